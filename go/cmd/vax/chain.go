@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/history"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// simulateEpoch 為模擬鏈第一個 action 的時間戳（unix ms）
+const simulateEpoch = 1700000000000
+
+// simulateActions 為模擬鏈輪流使用的 action type
+var simulateActions = []string{"deposit", "transfer", "withdraw"}
+
+func runChain(env *cli, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: vax chain simulate|verify [flags]")
+	}
+	switch args[0] {
+	case "simulate":
+		return runChainSimulate(env, args[1:])
+	case "verify":
+		return runChainVerify(env, args[1:])
+	}
+	return fmt.Errorf("unknown chain command %q (want simulate or verify)", args[0])
+}
+
+// runChainSimulate 以 seed 決定性地產生一條簽章鏈，輸出為 JSONL export
+func runChainSimulate(env *cli, args []string) error {
+	fs := newFlags(env, "chain simulate")
+	n := fs.Uint64("n", 10, "number of actions")
+	seed := fs.Uint64("seed", 1, "seed; the same seed always yields the same bytes")
+	actor := fs.String("actor", "sim", "actor id")
+	kid := fs.String("kid", "sim", "key id set on every envelope")
+	saltHex := fs.String("salt", "", "hex genesis salt (default: derived from the seed)")
+	pubOut := fs.String("pub-out", "", "write the signing key's public JWK to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	salt := simulateDigest("salt", *seed, 0)[:vax.GenesisSaltSize]
+	if *saltHex != "" {
+		var err error
+		if salt, err = hex.DecodeString(*saltHex); err != nil {
+			return fmt.Errorf("-salt: %w", err)
+		}
+	}
+	genesis, err := vax.ComputeGenesisSAI(*actor, salt)
+	if err != nil {
+		return fmt.Errorf("-salt must be %d bytes: %w", vax.GenesisSaltSize, err)
+	}
+	priv := ed25519.NewKeyFromSeed(simulateDigest("key", *seed, 0))
+	if *pubOut != "" {
+		if err := writePublicJWK(*pubOut, priv.Public(), *kid); err != nil {
+			return err
+		}
+	}
+
+	mem := history.NewMemory()
+	state := vax.ChainState{HeadSAI: genesis}
+	for i := uint64(1); i <= *n; i++ {
+		rec, err := simulateRecord(*actor, *kid, priv, state, *seed)
+		if err != nil {
+			return fmt.Errorf("action %d: %w", i, err)
+		}
+		if err := mem.Append(rec); err != nil {
+			return err
+		}
+		state = state.Advance(rec.SAI)
+	}
+	fmt.Fprintf(env.stderr, "actor %s, genesis salt %x, head %x\n", *actor, salt, state.HeadSAI)
+	return history.Export(env.stdout, mem, history.JSONL, history.Filter{Actors: []string{*actor}})
+}
+
+// simulateRecord 建立 state 之後的下一個 action；payload 與時間戳只取決於 seed 與 counter
+func simulateRecord(actor, kid string, priv ed25519.PrivateKey, state vax.ChainState, seed uint64) (history.Record, error) {
+	counter := state.Counter + 1
+	d := simulateDigest("action", seed, counter)
+	at := int64(simulateEpoch + counter*1000)
+	e := sae.NewEnvelope(simulateActions[int(d[0])%len(simulateActions)],
+		map[string]any{"amount": binary.BigEndian.Uint32(d[1:5]) % 100000, "ref": hex.EncodeToString(d[5:13])},
+		sae.WithChain(counter, state.HeadSAI), sae.WithNonce(hex.EncodeToString(d[13:29])))
+	e.Timestamp = at
+	e.Kid = kid
+	if err := e.Sign(priv); err != nil {
+		return history.Record{}, err
+	}
+	b, err := jcs.Marshal(e)
+	if err != nil {
+		return history.Record{}, err
+	}
+	sai, err := vax.ComputeSAI(state.HeadSAI, b)
+	if err != nil {
+		return history.Record{}, err
+	}
+	return history.Record{Actor: actor, Counter: counter, ActionType: e.ActionType,
+		PrevSAI: state.HeadSAI, SAE: b, SAI: sai, AcceptedAt: at}, nil
+}
+
+// simulateDigest = SHA256("vax-sim/" || label || "/" || seed || "/" || counter)，十進位
+func simulateDigest(label string, seed, counter uint64) []byte {
+	sum := sha256.Sum256([]byte("vax-sim/" + label + "/" + strconv.FormatUint(seed, 10) + "/" + strconv.FormatUint(counter, 10)))
+	return sum[:]
+}
+
+// runChainVerify 以 ImportAndVerify 重新驗證一份 JSONL export，並印出第一個失敗的連結
+func runChainVerify(env *cli, args []string) error {
+	fs := newFlags(env, "chain verify")
+	pubFile := fs.String("pub", "", "public key file: SPKI PEM, JWK or JWK Set (without it signatures are not checked)")
+	kid := fs.String("kid", "", "key id of a PEM public key (default: any)")
+	saltHex := fs.String("salt", "", "hex genesis salt; each chain must then start at counter 1 from its actor's genesis SAI")
+	genesisHex := fs.String("genesis", "", "hex genesis SAI shared by every chain (instead of -salt)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	input, err := readInput(env, fs)
+	if err != nil {
+		return err
+	}
+
+	var genesis history.GenesisFunc // nil: 以每條鏈的第一行為錨點
+	switch {
+	case *saltHex != "" && *genesisHex != "":
+		return errors.New("-salt and -genesis are mutually exclusive")
+	case *saltHex != "":
+		salt, err := hex.DecodeString(*saltHex)
+		if err != nil {
+			return fmt.Errorf("-salt: %w", err)
+		}
+		genesis = func(actor string) ([]byte, error) { return vax.ComputeGenesisSAI(actor, salt) }
+	case *genesisHex != "":
+		sai, err := hex.DecodeString(*genesisHex)
+		if err != nil || len(sai) != vax.SAISize {
+			return errors.New("-genesis must be a hex SAI")
+		}
+		genesis = func(string) ([]byte, error) { return sai, nil }
+	}
+	var keys sae.KeyResolver
+	if *pubFile != "" {
+		static, err := loadKeys(*pubFile, *kid)
+		if err != nil {
+			return err
+		}
+		keys = resolverFor(static)
+	}
+
+	report, err := history.ImportAndVerify(bytes.NewReader(input), genesis, keys, nil)
+	if errors.Is(err, history.ErrDiscrepancy) {
+		d := report.Discrepancies[0]
+		return fmt.Errorf("first failing link: %s (%d of %d records failed)", d, len(report.Discrepancies), report.Lines)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Lines == 0 {
+		return errors.New("no records")
+	}
+	fmt.Fprintf(env.stdout, "ok: %d records verified (%d redacted, %d pruned)%s\n",
+		report.Verified, report.Redacted, report.Pruned, signatureNote(keys != nil))
+	return nil
+}
+
+// anyKid 以同一把金鑰回應任何 kid（PEM 公鑰未指定 kid 時）
+type anyKid struct{ pub crypto.PublicKey }
+
+func (a anyKid) Resolve(string) (crypto.PublicKey, error) { return a.pub, nil }
+
+func resolverFor(keys sae.StaticResolver) sae.KeyResolver {
+	if pub, ok := keys[""]; ok && len(keys) == 1 {
+		return anyKid{pub}
+	}
+	return keys
+}
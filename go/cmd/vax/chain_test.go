@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	dir := t.TempDir()
+	pub := filepath.Join(dir, "sim.jwk")
+	code, export, info := vaxRun(t, "", "chain", "simulate", "-n", "5", "-seed", "7", "-pub-out", pub)
+	if code != 0 || strings.Count(export, "\n") != 5 {
+		t.Fatalf("simulate = %d %q %s", code, export, info)
+	}
+	salt := regexp.MustCompile(`genesis salt ([0-9a-f]+)`).FindStringSubmatch(info)[1]
+
+	t.Run("simulate is deterministic", func(t *testing.T) {
+		_, again, _ := vaxRun(t, "", "chain", "simulate", "-n", "5", "-seed", "7")
+		_, other, _ := vaxRun(t, "", "chain", "simulate", "-n", "5", "-seed", "8")
+		if again != export || other == export {
+			t.Error("output does not depend on the seed alone")
+		}
+	})
+
+	t.Run("verify accepts the simulated chain", func(t *testing.T) {
+		code, out, errOut := vaxRun(t, export, "chain", "verify", "-pub", pub, "-salt", salt)
+		if code != 0 || !strings.HasPrefix(out, "ok: 5 records verified") || !strings.Contains(out, "signature valid") {
+			t.Errorf("verify = %d %q %s", code, out, errOut)
+		}
+		// 部分 export 以第一行為錨點
+		tail := strings.SplitAfterN(export, "\n", 3)[2]
+		if code, out, errOut := vaxRun(t, tail, "chain", "verify"); code != 0 || !strings.HasPrefix(out, "ok: 3 records") {
+			t.Errorf("partial = %d %q %s", code, out, errOut)
+		}
+	})
+
+	t.Run("error: prints the first failing link", func(t *testing.T) {
+		lines := strings.SplitAfter(export, "\n")
+		lines[2] = regexp.MustCompile(`"amount":\d+`).ReplaceAllString(lines[2], `"amount":0`)
+		code, _, errOut := vaxRun(t, strings.Join(lines, ""), "chain", "verify", "-salt", salt)
+		if code != 1 || !strings.Contains(errOut, "first failing link: line 3 (sim/3)") {
+			t.Errorf("exit %d: %s", code, errOut)
+		}
+	})
+
+	t.Run("error: wrong genesis salt", func(t *testing.T) {
+		code, _, errOut := vaxRun(t, export, "chain", "verify", "-salt", strings.Repeat("00", 16))
+		if code != 1 || !strings.Contains(errOut, "line 1 (sim/1)") {
+			t.Errorf("exit %d: %s", code, errOut)
+		}
+	})
+
+	t.Run("error: unknown subcommand", func(t *testing.T) {
+		if code, _, _ := vaxRun(t, "", "chain", "replay"); code != 1 {
+			t.Errorf("exit %d", code)
+		}
+	})
+}
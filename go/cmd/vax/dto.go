@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net"
+	"net/netip"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// basicTypes 為 DTO 原始碼中可直接對應的內建型別
+var basicTypes = map[string]reflect.Type{
+	"string":  reflect.TypeFor[string](),
+	"bool":    reflect.TypeFor[bool](),
+	"int":     reflect.TypeFor[int](),
+	"int8":    reflect.TypeFor[int8](),
+	"int16":   reflect.TypeFor[int16](),
+	"int32":   reflect.TypeFor[int32](),
+	"int64":   reflect.TypeFor[int64](),
+	"uint":    reflect.TypeFor[uint](),
+	"uint8":   reflect.TypeFor[uint8](),
+	"uint16":  reflect.TypeFor[uint16](),
+	"uint32":  reflect.TypeFor[uint32](),
+	"uint64":  reflect.TypeFor[uint64](),
+	"float32": reflect.TypeFor[float32](),
+	"float64": reflect.TypeFor[float64](),
+	"byte":    reflect.TypeFor[byte](),
+	"rune":    reflect.TypeFor[rune](),
+	"any":     reflect.TypeFor[any](),
+}
+
+// knownTypes 為 schema 套件認得的標準庫型別（import path + 名稱）
+var knownTypes = map[string]reflect.Type{
+	"time.Time":                reflect.TypeFor[time.Time](),
+	"time.Duration":            reflect.TypeFor[time.Duration](),
+	"encoding/json.Number":     reflect.TypeFor[json.Number](),
+	"encoding/json.RawMessage": reflect.TypeFor[json.RawMessage](),
+	"net.IP":                   reflect.TypeFor[net.IP](),
+	"net/netip.Addr":           reflect.TypeFor[netip.Addr](),
+	"net/url.URL":              reflect.TypeFor[url.URL](),
+}
+
+// errRecursiveDTO：reflect.StructOf 無法建立自我參照的型別
+var errRecursiveDTO = errors.New("recursive DTOs need the compiled type: use schema.Generate from Go code")
+
+// dtoParser 把 Go 原始碼中的 struct 宣告轉成等價的 reflect.Type（欄位與 tag 相同），
+// 讓 schema 套件不必編譯 DTO 也能產生 schema
+type dtoParser struct {
+	decls    map[string]ast.Expr          // 型別名稱 → 定義
+	imports  map[string]map[string]string // 宣告所在檔案的 import 名稱 → path
+	declFile map[string]string            // 型別名稱 → 檔案
+	built    map[string]reflect.Type
+	building map[string]bool
+}
+
+// parseDTOs 讀取 Go 原始檔，回傳其中的 struct 型別（依名稱排序）
+func parseDTOs(files []string) ([]string, map[string]reflect.Type, error) {
+	p := &dtoParser{decls: map[string]ast.Expr{}, imports: map[string]map[string]string{},
+		declFile: map[string]string{}, built: map[string]reflect.Type{}, building: map[string]bool{}}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, nil, err
+		}
+		imports := map[string]string{}
+		for _, imp := range f.Imports {
+			ipath, _ := strconv.Unquote(imp.Path.Value)
+			name := path.Base(ipath)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			imports[name] = ipath
+		}
+		p.imports[file] = imports
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.TypeParams != nil {
+					continue
+				}
+				if _, dup := p.decls[ts.Name.Name]; dup {
+					return nil, nil, fmt.Errorf("%s: type %s declared twice", file, ts.Name.Name)
+				}
+				p.decls[ts.Name.Name] = ts.Type
+				p.declFile[ts.Name.Name] = file
+			}
+		}
+	}
+
+	var names []string
+	types := map[string]reflect.Type{}
+	for name, expr := range p.decls {
+		if _, ok := expr.(*ast.StructType); !ok || !ast.IsExported(name) {
+			continue
+		}
+		t, err := p.named(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		names = append(names, name)
+		types[name] = t
+	}
+	sort.Strings(names)
+	return names, types, nil
+}
+
+func (p *dtoParser) named(name string) (reflect.Type, error) {
+	if t, ok := p.built[name]; ok {
+		return t, nil
+	}
+	if p.building[name] {
+		return nil, fmt.Errorf("%s: %w", name, errRecursiveDTO)
+	}
+	p.building[name] = true
+	defer delete(p.building, name)
+	t, err := p.typeOf(p.declFile[name], p.decls[name])
+	if err != nil {
+		return nil, err
+	}
+	p.built[name] = t
+	return t, nil
+}
+
+func (p *dtoParser) typeOf(file string, expr ast.Expr) (reflect.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if _, ok := p.decls[e.Name]; ok {
+			return p.named(e.Name)
+		}
+		if t, ok := basicTypes[e.Name]; ok {
+			return t, nil
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok {
+			if t, ok := knownTypes[p.imports[file][pkg.Name]+"."+e.Sel.Name]; ok {
+				return t, nil
+			}
+		}
+	case *ast.StarExpr:
+		t, err := p.typeOf(file, e.X)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(t), nil
+	case *ast.ArrayType:
+		elem, err := p.typeOf(file, e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len == nil {
+			return reflect.SliceOf(elem), nil
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if n, err := strconv.Atoi(litValue(lit)); ok && err == nil {
+			return reflect.ArrayOf(n, elem), nil
+		}
+	case *ast.MapType:
+		key, err := p.typeOf(file, e.Key)
+		if err != nil {
+			return nil, err
+		}
+		val, err := p.typeOf(file, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, val), nil
+	case *ast.InterfaceType:
+		if len(e.Methods.List) == 0 {
+			return reflect.TypeFor[any](), nil
+		}
+	case *ast.StructType:
+		return p.structOf(file, e)
+	}
+	return nil, fmt.Errorf("unsupported type %s", exprString(expr))
+}
+
+func (p *dtoParser) structOf(file string, st *ast.StructType) (reflect.Type, error) {
+	var fields []reflect.StructField
+	for _, f := range st.Fields.List {
+		if len(f.Names) > 0 && !slices.ContainsFunc(f.Names, (*ast.Ident).IsExported) {
+			continue // encoding/json 忽略未 export 的欄位
+		}
+		t, err := p.typeOf(file, f.Type)
+		if err != nil {
+			return nil, err
+		}
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		if len(f.Names) == 0 {
+			// 內嵌欄位：StructOf 需要 exported 名稱，未加 tag 時名稱不影響 JSON
+			name := strings.TrimPrefix(exprString(f.Type), "*")
+			name = name[strings.LastIndex(name, ".")+1:]
+			fields = append(fields, reflect.StructField{Name: exported(name), Type: t, Tag: tag, Anonymous: true})
+			continue
+		}
+		for _, n := range f.Names {
+			if n.IsExported() {
+				fields = append(fields, reflect.StructField{Name: n.Name, Type: t, Tag: tag})
+			}
+		}
+	}
+	return reflect.StructOf(fields), nil
+}
+
+func exported(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func litValue(lit *ast.BasicLit) string {
+	if lit == nil {
+		return ""
+	}
+	return lit.Value
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.ArrayType:
+		return "[]" + exprString(e.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(e.Key) + "]" + exprString(e.Value)
+	}
+	return fmt.Sprintf("%T", expr)
+}
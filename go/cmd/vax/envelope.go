@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/api"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+func runJCS(env *cli, args []string) error {
+	fs := newFlags(env, "jcs")
+	check := fs.Bool("check", false, "only report whether the input is already canonical (exit 1 if not)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	input, err := readInput(env, fs)
+	if err != nil {
+		return err
+	}
+	input = bytes.TrimSuffix(input, []byte("\n"))
+	if *check {
+		if !jcs.IsCanonical(input) {
+			return errors.New("input is not VAX-JCS canonical")
+		}
+		fmt.Fprintln(env.stdout, "canonical")
+		return nil
+	}
+	out, err := jcs.CanonicalizeJSON(input)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(env.stdout, "%s\n", out)
+	return err
+}
+
+func runSign(env *cli, args []string) error {
+	fs := newFlags(env, "sign")
+	keyFile := fs.String("key", "", "private key file: PKCS#8 PEM, encrypted PEM or JWK (required)")
+	kid := fs.String("kid", "", "key id to set on the envelope")
+	passEnv := fs.String("passphrase-env", "", "environment variable holding the passphrase of an encrypted key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return errors.New("-key is required")
+	}
+	signer, err := loadSigner(env, *keyFile, *passEnv)
+	if err != nil {
+		return err
+	}
+	input, err := readInput(env, fs)
+	if err != nil {
+		return err
+	}
+	e, err := sae.Parse(bytes.TrimSpace(input))
+	if err != nil {
+		return fmt.Errorf("envelope: %w", err)
+	}
+	if *kid != "" {
+		e.Kid = *kid
+	}
+	if err := e.SignWith(signer, nil); err != nil {
+		return err
+	}
+	out, err := jcs.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(env.stdout, "%s\n", out)
+	return err
+}
+
+// runVerify 驗證 api.SubmitRequest（SAI、chain binding、簽章）或單獨的 SAE（簽章）
+func runVerify(env *cli, args []string) error {
+	fs := newFlags(env, "verify")
+	pubFile := fs.String("pub", "", "public key file: SPKI PEM, JWK or JWK Set (without it signatures are not checked)")
+	kid := fs.String("kid", "", "key id of a PEM public key (default: the envelope's kid)")
+	head := fs.String("head", "", "hex SAI the verifier holds as the actor's head; prev_sai must equal it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	input, err := readInput(env, fs)
+	if err != nil {
+		return err
+	}
+	var keys sae.StaticResolver
+	if *pubFile != "" {
+		if keys, err = loadKeys(*pubFile, *kid); err != nil {
+			return err
+		}
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(input, &members); err != nil {
+		return fmt.Errorf("input: %w", err)
+	}
+	if _, ok := members["action_type"]; ok {
+		e, err := sae.Parse(bytes.TrimSpace(input))
+		if err != nil {
+			return fmt.Errorf("envelope: %w", err)
+		}
+		if err := verifySignature(e, keys); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.stdout, "ok: %s envelope%s\n", e.ActionType, signatureNote(keys != nil))
+		return nil
+	}
+
+	var req api.SubmitRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return fmt.Errorf("submission: %w", err)
+	}
+	prev, err := hex.DecodeString(req.PrevSAI)
+	if err != nil || len(prev) != vax.SAISize {
+		return errors.New("submission: prev_sai must be a hex SAI")
+	}
+	if *head != "" && *head != req.PrevSAI {
+		return fmt.Errorf("%w: prev_sai is not the verifier's head", vax.ErrInvalidPrevSAI)
+	}
+	canonical, err := sae.Decompress(req.SAE)
+	if err != nil {
+		return fmt.Errorf("submission: sae: %w", err)
+	}
+	e, err := sae.Parse(canonical)
+	if err != nil {
+		return fmt.Errorf("submission: sae: %w", err)
+	}
+	if req.Counter == 0 || e.Counter != req.Counter {
+		return fmt.Errorf("%w: counter %d, envelope %d", vax.ErrInvalidCounter, req.Counter, e.Counter)
+	}
+	if err := vax.VerifyChainBinding(e, vax.ChainState{Counter: req.Counter - 1, HeadSAI: prev}); err != nil {
+		return err
+	}
+	sai, err := vax.ComputeSAI(prev, canonical)
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(sai); got != req.SAI {
+		return fmt.Errorf("%w: computed %s", vax.ErrSAIMismatch, got)
+	}
+	if err := verifySignature(e, keys); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "ok: %s counter %d sai %s%s\n", req.Actor, req.Counter, req.SAI, signatureNote(keys != nil))
+	return nil
+}
+
+func verifySignature(e *sae.Envelope, keys sae.StaticResolver) error {
+	if keys == nil {
+		return nil
+	}
+	if len(keys) == 1 {
+		// PEM 未指定 kid，或 envelope 沒有 kid：以唯一的金鑰驗證
+		for id, pub := range keys {
+			if id == "" || e.Kid == "" {
+				return e.Verify(pub)
+			}
+		}
+	}
+	return e.VerifyWithResolver(keys)
+}
+
+func signatureNote(checked bool) string {
+	if !checked {
+		return " (signature not checked)"
+	}
+	return ", signature valid"
+}
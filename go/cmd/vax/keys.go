@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+func runKeygen(env *cli, args []string) error {
+	fs := newFlags(env, "keygen")
+	alg := fs.String("alg", "ed25519", "key algorithm: ed25519, p256 or rsa")
+	format := fs.String("format", "pem", "output format: pem or jwk")
+	kid := fs.String("kid", "", "key id (written into JWKs)")
+	out := fs.String("out", "", "write the private key to this file (0600) and the public key to <file>.pub instead of stdout")
+	passEnv := fs.String("passphrase-env", "", "encrypt the PEM private key with the passphrase in this environment variable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	priv, err := generateKey(*alg)
+	if err != nil {
+		return err
+	}
+	var privOut, pubOut []byte
+	switch *format {
+	case "pem":
+		if *passEnv != "" {
+			pass := env.getenv(*passEnv)
+			if pass == "" {
+				return fmt.Errorf("$%s is empty", *passEnv)
+			}
+			privOut, err = sae.EncryptPrivateKey(priv, []byte(pass))
+		} else {
+			privOut, err = sae.MarshalPrivateKeyPEM(priv)
+		}
+		if err != nil {
+			return err
+		}
+		if pubOut, err = sae.MarshalPublicKeyPEM(priv.Public()); err != nil {
+			return err
+		}
+	case "jwk":
+		if *passEnv != "" {
+			return errors.New("-passphrase-env needs -format pem")
+		}
+		pj, err := sae.PrivateJWK(priv, *kid)
+		if err != nil {
+			return err
+		}
+		pub, err := sae.PublicJWK(priv.Public(), *kid)
+		if err != nil {
+			return err
+		}
+		if privOut, err = jcs.Marshal(pj); err != nil {
+			return err
+		}
+		if pubOut, err = jcs.Marshal(pub); err != nil {
+			return err
+		}
+		privOut, pubOut = append(privOut, '\n'), append(pubOut, '\n')
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if *out == "" {
+		if _, err := env.stdout.Write(privOut); err != nil {
+			return err
+		}
+		_, err := env.stdout.Write(pubOut)
+		return err
+	}
+	if err := os.WriteFile(*out, privOut, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".pub", pubOut, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "wrote %s and %s.pub\n", *out, *out)
+	return nil
+}
+
+func generateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "ed25519":
+		_, priv, err := sae.GenerateKeyPair()
+		return priv, err
+	case "p256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", alg)
+}
+
+// loadSigner 讀取 PKCS#8 PEM、加密 PEM（passphrase 取自環境變數）或私鑰 JWK
+func loadSigner(env *cli, path, passEnv string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var k sae.JWK
+		if err := json.Unmarshal(trimmed, &k); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return k.PrivateKey()
+	}
+	if bytes.Contains(data, []byte(sae.PEMEncryptedPrivateKey)) {
+		if passEnv == "" || env.getenv(passEnv) == "" {
+			return nil, fmt.Errorf("%s is encrypted: pass -passphrase-env", path)
+		}
+		return sae.DecryptPrivateKey(data, []byte(env.getenv(passEnv)))
+	}
+	signer, err := sae.ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// loadKeys 讀取 SPKI PEM 公鑰（對應 kid）、單一 JWK 或 JWK Set
+func loadKeys(path, kid string) (sae.StaticResolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		pub, err := sae.ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return sae.StaticResolver{kid: pub}, nil
+	}
+	var set sae.JWKSet
+	if err := json.Unmarshal(trimmed, &set); err != nil || set.Keys == nil {
+		var k sae.JWK
+		if err := json.Unmarshal(trimmed, &k); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.Keys = []sae.JWK{k}
+	}
+	keys := sae.StaticResolver{}
+	for _, k := range set.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, k.Kid, err)
+		}
+		id := k.Kid
+		if id == "" {
+			id = kid
+		}
+		keys[id] = pub
+	}
+	return keys, nil
+}
+
+// writePublicJWK 將公鑰以 JWK 寫入 path
+func writePublicJWK(path string, pub crypto.PublicKey, kid string) error {
+	k, err := sae.PublicJWK(pub, kid)
+	if err != nil {
+		return err
+	}
+	b, err := jcs.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,113 @@
+// Command vax is the VAX toolbox for support and partner onboarding: it
+// generates keys, canonicalizes JSON, signs envelopes and verifies
+// submissions without writing a Go program.
+//
+//	vax keygen     [-alg ed25519|p256|rsa] [-format pem|jwk] [-kid id] [-out file]
+//	vax jcs        < input.json
+//	vax sign       -key file [-kid id] < envelope.json
+//	vax verify     [-pub file] [-kid id] [-head hex] submission.json
+//	vax chain simulate [-n 10] [-seed 1] [-actor id] [-pub-out file]
+//	vax chain verify   [-pub file] [-salt hex | -genesis hex] export.jsonl
+//	vax vectors    [-out file | -check file]
+//	vax schema gen [-format jsonschema|openapi|typescript|go] dto.go... | fieldspec.json
+//
+// chain simulate derives every byte from the seed (see simulateDigest), so
+// its output doubles as a conformance fixture for other implementations:
+// they must reproduce it exactly, and chain verify must accept what they
+// produce.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command 是一個子命令；回傳的錯誤印到 stderr 並以 1 結束
+type command struct {
+	summary string
+	run     func(env *cli, args []string) error
+}
+
+// cli 讓子命令不直接碰 os，方便測試
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	getenv         func(string) string
+}
+
+var commands = map[string]command{}
+
+// commandOrder 為 usage 的顯示順序
+var commandOrder []string
+
+func register(name, summary string, run func(env *cli, args []string) error) {
+	commands[name] = command{summary: summary, run: run}
+	commandOrder = append(commandOrder, name)
+}
+
+func init() {
+	register("keygen", "generate a signing key pair (PEM or JWK)", runKeygen)
+	register("jcs", "canonicalize JSON from stdin (VAX-JCS)", runJCS)
+	register("sign", "sign an envelope from stdin with a key file", runSign)
+	register("verify", "verify a submission or SAE file", runVerify)
+	register("chain", "simulate a deterministic chain or verify a JSONL export", runChain)
+	register("vectors", "emit the cross-language test-vector file", runVectors)
+	register("schema", "generate JSON Schema, OpenAPI, TypeScript or Go from DTOs or FieldSpec JSON", runSchema)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, getenv: os.Getenv}))
+}
+
+func run(args []string, env *cli) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(env.stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "vax: unknown command %q\n", args[0])
+		usage(env.stderr)
+		return 2
+	}
+	if err := cmd.run(env, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(env.stderr, "vax %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: vax <command> [flags]")
+	fmt.Fprintln(w)
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+// newFlags 建立子命令的 FlagSet，錯誤輸出導向 stderr
+func newFlags(env *cli, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("vax "+name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	return fs
+}
+
+// readInput 讀取檔案參數，沒有（或為 "-"）時讀 stdin
+func readInput(env *cli, fs *flag.FlagSet) ([]byte, error) {
+	switch fs.NArg() {
+	case 0:
+		return io.ReadAll(env.stdin)
+	case 1:
+		if fs.Arg(0) == "-" {
+			return io.ReadAll(env.stdin)
+		}
+		return os.ReadFile(fs.Arg(0))
+	}
+	return nil, fmt.Errorf("expected at most one input file, got %d", fs.NArg())
+}
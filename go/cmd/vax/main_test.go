@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/api"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// vaxRun 執行子命令，回傳 exit code、stdout 與 stderr
+func vaxRun(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr,
+		getenv: func(k string) string { return map[string]string{"VAX_PASS": "hunter2"}[k] }}
+	code := run(args, env)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("jcs", func(t *testing.T) {
+		code, out, _ := vaxRun(t, `{"b": 1.50, "a": "x"}`, "jcs")
+		if code != 0 || out != `{"a":"x","b":1.5}`+"\n" {
+			t.Errorf("jcs = %d %q", code, out)
+		}
+		if code, _, _ := vaxRun(t, `{"b":1,"a":2}`, "jcs", "-check"); code != 1 {
+			t.Errorf("-check exit %d", code)
+		}
+	})
+
+	for _, tc := range []struct{ alg, format string }{{"ed25519", "pem"}, {"p256", "jwk"}} {
+		t.Run("keygen, sign and verify "+tc.alg+" "+tc.format, func(t *testing.T) {
+			key := filepath.Join(dir, tc.alg+"."+tc.format)
+			if code, _, errOut := vaxRun(t, "", "keygen", "-alg", tc.alg, "-format", tc.format, "-kid", "k1", "-out", key); code != 0 {
+				t.Fatalf("keygen: %s", errOut)
+			}
+			env := sae.NewEnvelope("transfer", map[string]any{"amount": 5}, sae.WithTimestamp(time.UnixMilli(1700000000000)))
+			unsigned, _ := jcs.Marshal(env)
+			code, signed, errOut := vaxRun(t, string(unsigned), "sign", "-key", key, "-kid", "k1")
+			if code != 0 {
+				t.Fatalf("sign: %s", errOut)
+			}
+			path := writeFile(t, dir, "signed.json", []byte(signed))
+			if code, out, errOut := vaxRun(t, "", "verify", "-pub", key+".pub", path); code != 0 || !strings.Contains(out, "signature valid") {
+				t.Errorf("verify = %d %q %s", code, out, errOut)
+			}
+		})
+	}
+
+	t.Run("encrypted key file", func(t *testing.T) {
+		key := filepath.Join(dir, "enc.pem")
+		if code, _, errOut := vaxRun(t, "", "keygen", "-passphrase-env", "VAX_PASS", "-out", key); code != 0 {
+			t.Fatalf("keygen: %s", errOut)
+		}
+		if code, _, _ := vaxRun(t, `{"action_type":"a","sdto":{},"timestamp":1}`, "sign", "-key", key); code != 1 {
+			t.Errorf("signed without the passphrase")
+		}
+		if code, _, errOut := vaxRun(t, `{"action_type":"a","sdto":{},"timestamp":1}`, "sign", "-key", key, "-passphrase-env", "VAX_PASS"); code != 0 {
+			t.Errorf("sign: %s", errOut)
+		}
+	})
+
+	t.Run("verifies a submission", func(t *testing.T) {
+		pub, priv, _ := sae.GenerateKeyPair()
+		pem, _ := sae.MarshalPublicKeyPEM(pub)
+		pubFile := writeFile(t, dir, "sub.pub", pem)
+		genesis := bytes.Repeat([]byte{7}, vax.SAISize)
+		env := sae.NewEnvelope("transfer", map[string]any{"amount": 1}, sae.WithChain(1, genesis))
+		env.Kid = "k1"
+		_ = env.Sign(priv)
+		b, _ := jcs.Marshal(env)
+		sai, _ := vax.ComputeSAI(genesis, b)
+		req := api.SubmitRequest{Actor: "alice", Counter: 1, PrevSAI: hex.EncodeToString(genesis), SAE: b, SAI: hex.EncodeToString(sai)}
+		raw, _ := json.Marshal(req)
+		path := writeFile(t, dir, "sub.json", raw)
+		if code, out, errOut := vaxRun(t, "", "verify", "-pub", pubFile, "-head", req.PrevSAI, path); code != 0 || !strings.HasPrefix(out, "ok: alice counter 1") {
+			t.Errorf("verify = %d %q %s", code, out, errOut)
+		}
+
+		req.SAI = strings.Repeat("0", 64)
+		raw, _ = json.Marshal(req)
+		if code, _, errOut := vaxRun(t, string(raw), "verify"); code != 1 || !strings.Contains(errOut, "SAI") {
+			t.Errorf("tampered SAI: %d %s", code, errOut)
+		}
+		if code, _, errOut := vaxRun(t, "", "verify", "-head", strings.Repeat("1", 64), path); code != 1 {
+			t.Errorf("stale head: %d %s", code, errOut)
+		}
+	})
+
+	t.Run("error: unknown command", func(t *testing.T) {
+		if code, _, errOut := vaxRun(t, "", "frobnicate"); code != 2 || !strings.Contains(errOut, "usage") {
+			t.Errorf("exit %d: %s", code, errOut)
+		}
+	})
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vax/pkg/vax/schema"
+	"vax/pkg/vax/sdto"
+)
+
+// schemaFormats 為 schema gen 的輸出格式
+var schemaFormats = []string{"jsonschema", "openapi", "typescript", "go"}
+
+func runSchema(env *cli, args []string) error {
+	if len(args) == 0 || args[0] != "gen" {
+		return errors.New("usage: vax schema gen [flags] dto.go... | fieldspec.json")
+	}
+	return runSchemaGen(env, args[1:])
+}
+
+// schemaInput 是一次 schema gen 的輸入：Go DTO（reflect.Type）或 FieldSpec schema，擇一
+type schemaInput struct {
+	names  []string
+	dtos   map[string]reflect.Type
+	fields map[string]map[string]sdto.FieldSpec
+}
+
+// fieldSpecs 回傳每個輸入的 FieldSpec schema（Go DTO 以 schema.GenerateFieldSpecType 轉換）
+func (in schemaInput) fieldSpecs() (map[string]map[string]sdto.FieldSpec, error) {
+	if in.fields != nil {
+		return in.fields, nil
+	}
+	out := map[string]map[string]sdto.FieldSpec{}
+	for _, name := range in.names {
+		fs, err := schema.GenerateFieldSpecType(in.dtos[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = fs
+	}
+	return out, nil
+}
+
+func runSchemaGen(env *cli, args []string) error {
+	fs := newFlags(env, "schema gen")
+	formatName := fs.String("format", "jsonschema", "output: "+strings.Join(schemaFormats, ", "))
+	types := fs.String("type", "", "comma-separated DTO or action names to emit (default: all)")
+	draft := fs.String("draft", "07", "JSON Schema draft for Go DTOs: 07 or 2020-12")
+	strict := fs.Bool("strict", false, "closed schemas for Go DTOs (schema.WithStrict)")
+	title := fs.String("title", "", "emit a full OpenAPI document with this title instead of components")
+	pkg := fs.String("package", "schemas", "package name of -format go output")
+	out := fs.String("out", "", "write here instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains(schemaFormats, *formatName) {
+		return fmt.Errorf("unknown format %q (want %s)", *formatName, strings.Join(schemaFormats, ", "))
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no input files")
+	}
+	in, err := loadSchemaInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *types != "" {
+		if err := in.selectNames(strings.Split(*types, ",")); err != nil {
+			return err
+		}
+	}
+	if len(in.names) == 0 {
+		return errors.New("no exported struct types or schemas in the input")
+	}
+	var opts []schema.Option
+	switch *draft {
+	case "07":
+	case "2020-12":
+		opts = append(opts, schema.WithDraft(schema.Draft202012))
+	default:
+		return fmt.Errorf("unknown draft %q", *draft)
+	}
+	if *strict {
+		opts = append(opts, schema.WithStrict())
+	}
+
+	var b []byte
+	switch *formatName {
+	case "jsonschema":
+		b, err = genJSONSchema(in, opts)
+	case "openapi":
+		b, err = genOpenAPI(in, *title, opts)
+	case "typescript":
+		var all map[string]map[string]sdto.FieldSpec
+		if all, err = in.fieldSpecs(); err == nil {
+			b = []byte(sdto.TypeScriptModule(all))
+		}
+	case "go":
+		var all map[string]map[string]sdto.FieldSpec
+		if all, err = in.fieldSpecs(); err == nil {
+			b, err = goConstructors(*pkg, in.names, all)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, b, 0o644)
+	}
+	_, err = env.stdout.Write(b)
+	return err
+}
+
+// loadSchemaInput 讀取 .go 檔（DTO struct）或單一 .json 檔（action type → FieldSpec schema）
+func loadSchemaInput(files []string) (schemaInput, error) {
+	var goFiles []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") {
+			goFiles = append(goFiles, f)
+		}
+	}
+	if len(goFiles) == len(files) {
+		names, dtos, err := parseDTOs(goFiles)
+		return schemaInput{names: names, dtos: dtos}, err
+	}
+	if len(files) != 1 {
+		return schemaInput{}, errors.New("pass Go DTO files or a single FieldSpec JSON file")
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return schemaInput{}, err
+	}
+	var raw map[string]map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return schemaInput{}, fmt.Errorf("%s: want {\"<action type>\": {\"<field>\": FieldSpec}}: %w", files[0], err)
+	}
+	in := schemaInput{fields: map[string]map[string]sdto.FieldSpec{}}
+	for action, fields := range raw {
+		s, err := sdto.ParseSchemaStrict(fields)
+		if err != nil {
+			return schemaInput{}, fmt.Errorf("%s: %s: %w", files[0], action, err)
+		}
+		in.names = append(in.names, action)
+		in.fields[action] = s
+	}
+	sort.Strings(in.names)
+	return in, nil
+}
+
+func (in *schemaInput) selectNames(want []string) error {
+	for _, name := range want {
+		if !slices.Contains(in.names, name) {
+			return fmt.Errorf("no type or action named %q", name)
+		}
+	}
+	in.names = want
+	for name := range in.dtos {
+		if !slices.Contains(want, name) {
+			delete(in.dtos, name)
+		}
+	}
+	for name := range in.fields {
+		if !slices.Contains(want, name) {
+			delete(in.fields, name)
+		}
+	}
+	return nil
+}
+
+// genJSONSchema：單一輸入輸出其 schema，多個時以名稱為 key
+func genJSONSchema(in schemaInput, opts []schema.Option) ([]byte, error) {
+	all := map[string]any{}
+	for _, name := range in.names {
+		if in.fields != nil {
+			all[name] = sdto.JSONSchema(in.fields[name])
+			continue
+		}
+		s, err := schema.GenerateType(in.dtos[name], opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		all[name] = s
+	}
+	if len(in.names) == 1 {
+		return indentJSON(all[in.names[0]])
+	}
+	return indentJSON(all)
+}
+
+func genOpenAPI(in schemaInput, title string, opts []schema.Option) ([]byte, error) {
+	var components map[string]any
+	if in.fields != nil {
+		schemas := map[string]any{}
+		for _, name := range in.names {
+			s := sdto.JSONSchema(in.fields[name])
+			delete(s, "$schema") // OpenAPI 3.1 的方言即 2020-12
+			schemas[name] = s
+		}
+		components = map[string]any{"schemas": schemas}
+	} else {
+		var err error
+		if components, err = schema.OpenAPIComponents(in.dtos, opts...); err != nil {
+			return nil, err
+		}
+	}
+	if title == "" {
+		return indentJSON(map[string]any{"components": components})
+	}
+	return indentJSON(map[string]any{
+		"openapi":    schema.OpenAPIVersion,
+		"info":       map[string]any{"title": title, "version": "1.0.0"},
+		"components": components,
+	})
+}
+
+func indentJSON(v any) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// goConstructors 產生 Go 原始碼：每個 action 一個 <Name>Schema 變數與 New<Name> 建構函式
+func goConstructors(pkg string, names []string, all map[string]map[string]sdto.FieldSpec) ([]byte, error) {
+	var body bytes.Buffer
+	usesJSON := false
+	for _, name := range names {
+		raw, err := json.Marshal(all[name])
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]any
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&fields); err != nil {
+			return nil, err
+		}
+		ident := goIdent(name)
+		fmt.Fprintf(&body, "\n// %sSchema is the sdto schema of the %q action.\n", ident, name)
+		fmt.Fprintf(&body, "var %sSchema = sdto.ParseSchema(", ident)
+		usesJSON = goLiteral(&body, fields, "") || usesJSON
+		body.WriteString(")\n")
+		fmt.Fprintf(&body, "\n// New%s starts a %q action validated against %sSchema.\n", ident, name, ident)
+		fmt.Fprintf(&body, "func New%s() *sdto.FluentAction {\n\treturn sdto.NewAction(%q, %sSchema)\n}\n", ident, name, ident)
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by vax schema gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkg)
+	if usesJSON {
+		src.WriteString("\t\"encoding/json\"\n\n")
+	}
+	src.WriteString("\t\"vax/pkg/vax/sdto\"\n)\n")
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// goLiteral 以 Go 字面量寫出 JSON 值；key 為 precision / scale 的數字寫成 int，
+// 其他數字寫成 json.Number（回傳是否用到 encoding/json）
+func goLiteral(w *bytes.Buffer, v any, key string) bool {
+	usesJSON := false
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.WriteString("map[string]any{\n")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%q: ", k)
+			usesJSON = goLiteral(w, v[k], k) || usesJSON
+			w.WriteString(",\n")
+		}
+		w.WriteString("}")
+	case []any:
+		w.WriteString("[]any{")
+		for i, e := range v {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			usesJSON = goLiteral(w, e, "") || usesJSON
+		}
+		w.WriteString("}")
+	case json.Number:
+		if key == "precision" || key == "scale" {
+			w.WriteString(v.String())
+		} else {
+			fmt.Fprintf(w, "json.Number(%q)", v.String())
+			usesJSON = true
+		}
+	case string:
+		w.WriteString(strconv.Quote(v))
+	case bool:
+		w.WriteString(strconv.FormatBool(v))
+	case nil:
+		w.WriteString("nil")
+	}
+	return usesJSON
+}
+
+// goIdent 把 action type（"transfer_funds"、"user.login"）轉成 exported 識別字
+func goIdent(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ' || r == ':' || r == '/':
+			upper = true
+		case upper:
+			sb.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	s := sb.String()
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "Action" + s
+	}
+	return s
+}
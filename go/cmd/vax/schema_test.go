@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/schema"
+)
+
+const dtoSource = `package dto
+
+import (
+	j "encoding/json"
+	"time"
+)
+
+type Currency string
+
+type base struct {
+	RequestID string ` + "`" + `json:"request_id" validate:"required,uuid"` + "`" + `
+}
+
+type Transfer struct {
+	base
+	Amount   int64        ` + "`" + `json:"amount" validate:"gte=1,lte=1000000"` + "`" + `
+	Currency Currency     ` + "`" + `json:"currency" validate:"oneof=USD EUR" default:"USD"` + "`" + `
+	At       time.Time    ` + "`" + `json:"at"` + "`" + `
+	Memo     *string      ` + "`" + `json:"memo,omitempty" validate:"max=140"` + "`" + `
+	Extra    j.RawMessage ` + "`" + `json:"extra,omitempty"` + "`" + `
+	internal chan int
+}
+
+type Login struct {
+	User string ` + "`" + `json:"user" validate:"required,min=3"` + "`" + `
+	MFA  bool   ` + "`" + `json:"mfa" default:"false"` + "`" + `
+}
+`
+
+// transferDTO 為 dtoSource 中 Transfer 的編譯版本
+type transferDTO struct {
+	RequestID string          `json:"request_id" validate:"required,uuid"`
+	Amount    int64           `json:"amount" validate:"gte=1,lte=1000000"`
+	Currency  string          `json:"currency" validate:"oneof=USD EUR" default:"USD"`
+	At        time.Time       `json:"at"`
+	Memo      *string         `json:"memo,omitempty" validate:"max=140"`
+	Extra     json.RawMessage `json:"extra,omitempty"`
+}
+
+const fieldSpecSource = `{
+  "transfer_funds": {
+    "amount": {"type": "decimal", "min": "0.01", "precision": 12, "scale": 2},
+    "fee": {"type": "integer", "default": 0}
+  },
+  "user.login": {"user": {"type": "string", "min": "3"}}
+}`
+
+func TestSchemaGen(t *testing.T) {
+	dir := t.TempDir()
+	dto := writeFile(t, dir, "dto.go", []byte(dtoSource))
+	specs := writeFile(t, dir, "actions.json", []byte(fieldSpecSource))
+
+	t.Run("JSON Schema from Go source matches the compiled DTO", func(t *testing.T) {
+		code, out, errOut := vaxRun(t, "", "schema", "gen", "-type", "Transfer", dto)
+		if code != 0 {
+			t.Fatalf("gen: %s", errOut)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := schema.Generate[transferDTO]()
+		b, _ := json.Marshal(want)
+		var wantJSON map[string]any
+		_ = json.Unmarshal(b, &wantJSON)
+		if !reflect.DeepEqual(got, wantJSON) {
+			t.Errorf("got  %v\nwant %v", got, wantJSON)
+		}
+	})
+
+	t.Run("OpenAPI components", func(t *testing.T) {
+		for _, in := range []string{dto, specs} {
+			code, out, errOut := vaxRun(t, "", "schema", "gen", "-format", "openapi", "-title", "Actions", in)
+			if code != 0 || !strings.Contains(out, `"openapi": "3.1.0"`) || !strings.Contains(out, `"schemas"`) {
+				t.Errorf("%s: %d %s", in, code, errOut)
+			}
+		}
+	})
+
+	t.Run("TypeScript", func(t *testing.T) {
+		code, out, _ := vaxRun(t, "", "schema", "gen", "-format", "typescript", "-type", "Login", dto)
+		if code != 0 || !strings.Contains(out, "export interface Login {") {
+			t.Errorf("%d\n%s", code, out)
+		}
+		// sdto 欄位是扁平的：time.Time、沒有 default 的選填欄位無法表達
+		if code, _, errOut := vaxRun(t, "", "schema", "gen", "-format", "typescript", dto); code != 1 || !strings.Contains(errOut, "Transfer") {
+			t.Errorf("Transfer: %d %s", code, errOut)
+		}
+	})
+
+	t.Run("Go constructors from FieldSpec JSON", func(t *testing.T) {
+		code, out, errOut := vaxRun(t, "", "schema", "gen", "-format", "go", "-package", "actions", specs)
+		if code != 0 {
+			t.Fatalf("gen: %s", errOut)
+		}
+		for _, want := range []string{
+			"package actions",
+			`var TransferFundsSchema = sdto.ParseSchema(map[string]any{`,
+			`"precision": 12,`,
+			`"default": json.Number("0"),`,
+			"func NewUserLogin() *sdto.FluentAction {\n\treturn sdto.NewAction(\"user.login\", UserLoginSchema)\n}",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("missing %q in\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("error: recursive DTO", func(t *testing.T) {
+		src := writeFile(t, dir, "node.go", []byte("package dto\n\ntype Node struct {\n\tNext *Node `json:\"next\"`\n}\n"))
+		_, _, err := parseDTOs([]string{src})
+		if !errors.Is(err, errRecursiveDTO) {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	t.Run("error: invalid FieldSpec", func(t *testing.T) {
+		bad := writeFile(t, dir, "bad.json", []byte(`{"a": {"x": {"type": "uuid"}}}`))
+		if code, _, errOut := vaxRun(t, "", "schema", "gen", bad); code != 1 || !strings.Contains(errOut, "unknown type") {
+			t.Errorf("exit %d: %s", code, errOut)
+		}
+	})
+}
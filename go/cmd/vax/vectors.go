@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"vax/pkg/vax/vectors"
+)
+
+// runVectors 輸出跨語言測試向量；-check 比對既有檔案是否為最新
+func runVectors(env *cli, args []string) error {
+	fs := newFlags(env, "vectors")
+	out := fs.String("out", "", "write the vector file here instead of stdout")
+	check := fs.String("check", "", "compare this vector file with the generated one (exit 1 if stale)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	b, err := vectors.Write()
+	if err != nil {
+		return err
+	}
+	switch {
+	case *check != "":
+		have, err := os.ReadFile(*check)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(have, b) {
+			return errors.New(*check + " is stale: regenerate it with vax vectors -out")
+		}
+		fmt.Fprintf(env.stdout, "ok: %s is up to date\n", *check)
+		return nil
+	case *out != "":
+		return os.WriteFile(*out, b, 0o644)
+	}
+	_, err = env.stdout.Write(b)
+	return err
+}
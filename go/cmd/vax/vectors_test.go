@@ -0,0 +1,21 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	if code, _, errOut := vaxRun(t, "", "vectors", "-out", path); code != 0 {
+		t.Fatalf("vectors: %s", errOut)
+	}
+	if code, out, _ := vaxRun(t, "", "vectors", "-check", path); code != 0 || !strings.HasPrefix(out, "ok") {
+		t.Errorf("check = %d %q", code, out)
+	}
+	writeFile(t, filepath.Dir(path), "vectors.json", []byte("{}"))
+	if code, _, errOut := vaxRun(t, "", "vectors", "-check", path); code != 1 || !strings.Contains(errOut, "stale") {
+		t.Errorf("stale check = %d %s", code, errOut)
+	}
+}
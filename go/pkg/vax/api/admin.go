@@ -0,0 +1,149 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vax/pkg/vax"
+)
+
+// ErrUnauthorized is returned by Authorize funcs to reject a request.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Checkpoint pins an actor's chain head at a point in time, e.g. before a
+// support intervention, so later audits can start from a known state.
+type Checkpoint struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`
+	HeadSAI string `json:"head_sai"` // hex
+	At      int64  `json:"at"`       // unix ms
+}
+
+// CheckpointStore persists checkpoints.
+type CheckpointStore interface {
+	SaveCheckpoint(cp Checkpoint) error
+}
+
+// MemoryCheckpoints is an in-memory CheckpointStore.
+type MemoryCheckpoints struct {
+	mu  sync.Mutex
+	cps map[string][]Checkpoint
+}
+
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{cps: make(map[string][]Checkpoint)}
+}
+
+func (m *MemoryCheckpoints) SaveCheckpoint(cp Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cps[cp.Actor] = append(m.cps[cp.Actor], cp)
+	return nil
+}
+
+// Checkpoints returns the actor's checkpoints, oldest first.
+func (m *MemoryCheckpoints) Checkpoints(actor string) []Checkpoint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Checkpoint(nil), m.cps[actor]...)
+}
+
+// AdminConfig wires HandleAdmin. Store and Authorize are required;
+// without Failures or Checkpoints the matching routes answer 404.
+type AdminConfig struct {
+	Store       vax.ChainStore
+	Failures    *FailureLog
+	Checkpoints CheckpointStore
+	// Authorize returns nil for requests allowed to use the admin API
+	// (see BearerToken); any error answers 401.
+	Authorize func(r *http.Request) error
+}
+
+// ActorState is the response of GET /actors/{actor}.
+type ActorState struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`
+	HeadSAI string `json:"head_sai"` // hex
+}
+
+// HandleAdmin serves the support API (mount it with http.StripPrefix):
+//
+//	GET  /actors/{actor}             → ActorState
+//	GET  /failures?actor=&limit=     → {"failures": [Failure, newest first]}
+//	POST /actors/{actor}/checkpoint  → 201 Checkpoint of the current head
+//
+// Every request must pass cfg.Authorize; errors use ErrorResponse.
+func HandleAdmin(cfg AdminConfig) http.Handler {
+	if cfg.Store == nil || cfg.Authorize == nil {
+		panic("api: HandleAdmin needs a store and an Authorize func")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /actors/{actor}", func(w http.ResponseWriter, r *http.Request) {
+		actor := r.PathValue("actor")
+		state, err := vax.HeadContext(r.Context(), cfg.Store, actor)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		respond(w, r, http.StatusOK, ActorState{Actor: actor, Counter: state.Counter, HeadSAI: hex.EncodeToString(state.HeadSAI)})
+	})
+	mux.HandleFunc("GET /failures", func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Failures == nil {
+			http.NotFound(w, r)
+			return
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			limit = 100
+		}
+		respond(w, r, http.StatusOK, map[string]any{"failures": cfg.Failures.Recent(r.URL.Query().Get("actor"), limit)})
+	})
+	mux.HandleFunc("POST /actors/{actor}/checkpoint", func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Checkpoints == nil {
+			http.NotFound(w, r)
+			return
+		}
+		actor := r.PathValue("actor")
+		state, err := vax.HeadContext(r.Context(), cfg.Store, actor)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		cp := Checkpoint{Actor: actor, Counter: state.Counter, HeadSAI: hex.EncodeToString(state.HeadSAI), At: time.Now().UnixMilli()}
+		if err := cfg.Checkpoints.SaveCheckpoint(cp); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		respond(w, r, http.StatusCreated, cp)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := cfg.Authorize(r); err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respond(w, r, http.StatusUnauthorized, ErrorResponse{Code: CodeUnauthenticated, Message: err.Error()})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// BearerToken returns an Authorize func accepting "Authorization: Bearer
+// <token>" (compared in constant time).
+func BearerToken(token string) func(*http.Request) error {
+	if token == "" {
+		panic("api: BearerToken needs a non-empty token")
+	}
+	return func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+}
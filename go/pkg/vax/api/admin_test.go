@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vax/pkg/vax"
+)
+
+func TestHandleAdmin(t *testing.T) {
+	failures := NewFailureLog(10)
+	f := newFixture(t, WithFailureLog(failures))
+	checkpoints := NewMemoryCheckpoints()
+	admin := httptest.NewServer(HandleAdmin(AdminConfig{
+		Store: f.store, Failures: failures, Checkpoints: checkpoints, Authorize: BearerToken("s3cret"),
+	}))
+	defer admin.Close()
+
+	call := func(t *testing.T, method, path, token string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, admin.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	// 一筆成功、一筆重放失敗
+	req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+	f.post(t, req)
+	f.post(t, req)
+
+	t.Run("actor head", func(t *testing.T) {
+		status, out := call(t, http.MethodGet, "/actors/"+testActor, "s3cret")
+		if status != http.StatusOK || out["counter"] != float64(1) || out["head_sai"] != req.SAI {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("recent failures", func(t *testing.T) {
+		status, out := call(t, http.MethodGet, "/failures?actor="+testActor, "s3cret")
+		list, _ := out["failures"].([]any)
+		if status != http.StatusOK || len(list) != 1 || list[0].(map[string]any)["code"] != CodeChainConflict {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("force checkpoint", func(t *testing.T) {
+		status, out := call(t, http.MethodPost, "/actors/"+testActor+"/checkpoint", "s3cret")
+		if status != http.StatusCreated || out["counter"] != float64(1) {
+			t.Fatalf("got %d %v", status, out)
+		}
+		if cps := checkpoints.Checkpoints(testActor); len(cps) != 1 || cps[0].HeadSAI != req.SAI {
+			t.Errorf("checkpoints = %+v", cps)
+		}
+	})
+
+	t.Run("error: unknown actor", func(t *testing.T) {
+		if status, out := call(t, http.MethodGet, "/actors/nobody", "s3cret"); status != http.StatusNotFound || out["code"] != CodeUnknownActor {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: missing or wrong token", func(t *testing.T) {
+		for _, token := range []string{"", "wrong"} {
+			if status, out := call(t, http.MethodGet, "/actors/"+testActor, token); status != http.StatusUnauthorized || out["code"] != CodeUnauthenticated {
+				t.Errorf("token %q: got %d %v", token, status, out)
+			}
+		}
+	})
+
+	t.Run("error: wrong method", func(t *testing.T) {
+		if status, _ := call(t, http.MethodDelete, "/actors/"+testActor, "s3cret"); status != http.StatusMethodNotAllowed {
+			t.Errorf("got %d", status)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+// Package api exposes the VAX server flow over net/http: action submission
+// (parse → schema validation → SAI → signature → chain advance) with
+// structured receipts and errors. Bodies are JSON, or deterministic CBOR
+// (package cbor) when sent with Content-Type / Accept application/cbor.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// Machine-readable error codes in ErrorResponse.Code
+const (
+	CodeInvalidInput        = "invalid_input"
+	CodeUnknownActor        = "unknown_actor"
+	CodeUnknownSchema       = "unknown_schema"
+	CodeInvalidSDTO         = "invalid_sdto"
+	CodeSAIMismatch         = "sai_mismatch"
+	CodeChainConflict       = "chain_conflict"
+	CodeInvalidSignature    = "invalid_signature"
+	CodeRateLimited         = "rate_limited"
+	CodeClockSkew           = "clock_skew"
+	CodeStaleTimestamp      = "stale_timestamp"
+	CodeTooLarge            = "too_large"
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeInternal            = "internal"
+)
+
+// ErrorResponse is the JSON body of every non-2xx response.
+type ErrorResponse struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Fields  sdto.ValidationErrors `json:"fields,omitempty"` // CodeInvalidSDTO only
+}
+
+// Classify maps a pipeline error to its HTTP status and ErrorResponse.
+// Other transports (see package rpc) derive their status from the code.
+func Classify(err error) (int, ErrorResponse) {
+	resp := ErrorResponse{Message: err.Error()}
+
+	var verrs sdto.ValidationErrors
+	var fe *sdto.FieldError
+	switch {
+	case errors.As(err, &verrs):
+		resp.Code, resp.Fields = CodeInvalidSDTO, verrs
+		return http.StatusUnprocessableEntity, resp
+	case errors.As(err, &fe):
+		resp.Code, resp.Fields = CodeInvalidSDTO, sdto.ValidationErrors{*fe}
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, vax.ErrEncrypted), errors.Is(err, sae.ErrDecrypt):
+		resp.Code = CodeInvalidSDTO
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, sdto.ErrUnknownSchema):
+		resp.Code = CodeUnknownSchema
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, vax.ErrUnknownActor):
+		resp.Code = CodeUnknownActor
+		return http.StatusNotFound, resp
+	case errors.Is(err, vax.ErrInvalidCounter), errors.Is(err, vax.ErrInvalidPrevSAI),
+		errors.Is(err, vax.ErrStaleHead), errors.Is(err, vax.ErrCounterOverflow), errors.Is(err, vax.ErrInvalidRegenesis):
+		resp.Code = CodeChainConflict
+		return http.StatusConflict, resp
+	case errors.Is(err, sae.ErrClockSkew):
+		resp.Code = CodeClockSkew
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, sae.ErrTimestampRegression):
+		resp.Code = CodeStaleTimestamp
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, sae.ErrEnvelopeTooLarge), errors.Is(err, sae.ErrTooManyFields), errors.Is(err, sae.ErrStringTooLong):
+		resp.Code = CodeTooLarge
+		return http.StatusRequestEntityTooLarge, resp
+	case errors.Is(err, ErrIdempotencyConflict):
+		resp.Code = CodeIdempotencyConflict
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, vax.ErrSAIMismatch):
+		resp.Code = CodeSAIMismatch
+		return http.StatusUnprocessableEntity, resp
+	case errors.Is(err, sae.ErrNotSigned), errors.Is(err, sae.ErrInvalidSignature),
+		errors.Is(err, sae.ErrInvalidKey), errors.Is(err, sae.ErrMissingKid),
+		errors.Is(err, sae.ErrUnknownKey), errors.Is(err, sae.ErrUnsupportedAlg),
+		errors.Is(err, sae.ErrKeyNotOwned):
+		resp.Code = CodeInvalidSignature
+		return http.StatusUnauthorized, resp
+	case errors.Is(err, vax.ErrInvalidInput):
+		resp.Code = CodeInvalidInput
+		return http.StatusBadRequest, resp
+	default:
+		// 內部錯誤不外洩細節
+		return http.StatusInternalServerError, ErrorResponse{Code: CodeInternal, Message: "internal error"}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// respond 依 Accept 以 deterministic CBOR 或 JSON 回應
+func respond(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Add("Vary", "Accept")
+	if !cbor.Accepts(r.Header.Get("Accept")) {
+		writeJSON(w, status, v)
+		return
+	}
+	b, err := cbor.Marshal(v)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Code: CodeInternal, Message: "internal error"})
+		return
+	}
+	w.Header().Set("Content-Type", cbor.MediaType)
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := Classify(err)
+	respond(w, r, status, resp)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// Failure is one rejected request, as recorded by WithFailureLog.
+type Failure struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter,omitempty"` // claimed chain position, if known
+	Code    string `json:"code"`              // ErrorResponse.Code
+	Message string `json:"message"`
+	At      int64  `json:"at"` // unix ms
+}
+
+// FailureLog keeps the most recent failures in a fixed-size ring.
+// Safe for concurrent use.
+type FailureLog struct {
+	mu   sync.Mutex
+	ring []Failure
+	next int
+	full bool
+	now  func() time.Time
+}
+
+// NewFailureLog keeps the last size failures (size must be positive).
+func NewFailureLog(size int) *FailureLog {
+	if size <= 0 {
+		panic("api: FailureLog size must be positive")
+	}
+	return &FailureLog{ring: make([]Failure, size), now: time.Now}
+}
+
+// Record appends f, stamping At if it is zero.
+func (l *FailureLog) Record(f Failure) {
+	if f.At == 0 {
+		f.At = l.now().UnixMilli()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ring[l.next] = f
+	l.next = (l.next + 1) % len(l.ring)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Recent returns up to limit failures, newest first, optionally only for
+// actor ("" = all actors). limit <= 0 means all retained failures.
+func (l *FailureLog) Recent(actor string, limit int) []Failure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.ring)
+	}
+	out := []Failure{}
+	for i := 0; i < n && (limit <= 0 || len(out) < limit); i++ {
+		f := l.ring[(l.next-1-i+len(l.ring))%len(l.ring)]
+		if actor == "" || f.Actor == actor {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package api
+
+import "testing"
+
+func TestFailureLog(t *testing.T) {
+	t.Run("keeps the newest entries", func(t *testing.T) {
+		l := NewFailureLog(3)
+		for i := 1; i <= 5; i++ {
+			l.Record(Failure{Actor: "a", Counter: uint64(i), Code: CodeSAIMismatch})
+		}
+		got := l.Recent("", 0)
+		if len(got) != 3 || got[0].Counter != 5 || got[2].Counter != 3 {
+			t.Fatalf("Recent = %+v", got)
+		}
+		if got[0].At == 0 {
+			t.Error("At not stamped")
+		}
+	})
+
+	t.Run("filters by actor and limit", func(t *testing.T) {
+		l := NewFailureLog(10)
+		l.Record(Failure{Actor: "a", Counter: 1})
+		l.Record(Failure{Actor: "b", Counter: 1})
+		l.Record(Failure{Actor: "a", Counter: 2})
+		if got := l.Recent("a", 0); len(got) != 2 || got[0].Counter != 2 {
+			t.Errorf("Recent(a) = %+v", got)
+		}
+		if got := l.Recent("", 1); len(got) != 1 || got[0].Actor != "a" {
+			t.Errorf("Recent limit 1 = %+v", got)
+		}
+		if got := NewFailureLog(1).Recent("", 0); got == nil || len(got) != 0 {
+			t.Errorf("empty log = %#v, want empty slice", got)
+		}
+	})
+}
@@ -0,0 +1,443 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/sae"
+)
+
+// Headers of an RFC 9421 HTTP message signature and its RFC 9530 body digest.
+const (
+	HeaderSignatureInput = "Signature-Input"
+	HeaderHTTPSignature  = "Signature"
+	HeaderContentDigest  = "Content-Digest"
+)
+
+// RFC 9421 signature algorithms accepted by VerifyHTTPSignature.
+const (
+	HTTPSigEd25519         = "ed25519"
+	HTTPSigECDSAP256SHA256 = "ecdsa-p256-sha256"
+	HTTPSigRSAPSSSHA512    = "rsa-pss-sha512"
+	HTTPSigRSAv15SHA256    = "rsa-v1_5-sha256"
+)
+
+// DefaultMaxSignatureAge is how old an HTTP signature's created parameter
+// may be (and how far in the future it may lie) unless
+// WithMaxSignatureAge says otherwise.
+const DefaultMaxSignatureAge = 5 * time.Minute
+
+// defaultSignatureComponents 是 SignHTTPRequest 未指定 components 時涵蓋的元件
+var defaultSignatureComponents = []string{"@method", "@target-uri"}
+
+// ErrMissingSignature is returned when a request carries no HTTP signature.
+var ErrMissingSignature = errors.New("missing HTTP message signature")
+
+// VerifyHTTPSignature authenticates requests signed at the HTTP layer
+// (RFC 9421) for partners that cannot sign inside the envelope. keyid
+// is resolved through the same KeyResolver as SAE signatures and becomes
+// the caller's Actor and Kid in the injected Identity (Counter and SAI are
+// zero: the request is not a chain position).
+//
+// The first signature in Signature-Input is verified. It must cover
+// @method and @target-uri (or @path), carry created and keyid parameters,
+// and, when the request has a body, cover content-digest, whose sha-256 or
+// sha-512 value is checked against the body bytes; with WithCanonicalBody,
+// JSON bodies must also be canonical, so the digest covers the canonical
+// form. alg is optional and otherwise follows from the key type (RSA keys
+// default to rsa-pss-sha512). created must lie within
+// DefaultMaxSignatureAge of server time (see WithMaxSignatureAge). Failures are answered like VerifyMiddleware's
+// and next is not called.
+func VerifyHTTPSignature(keys sae.KeyResolver, opts ...Option) func(http.Handler) http.Handler {
+	if keys == nil {
+		panic("api: VerifyHTTPSignature needs a key resolver")
+	}
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sig, err := parseHTTPSignature(r.Header)
+			if errors.Is(err, ErrMissingSignature) {
+				respond(w, r, http.StatusUnauthorized, ErrorResponse{Code: CodeUnauthenticated, Message: err.Error()})
+				return
+			}
+			if sig != nil && cfg.rateLimited(w, r, sig.keyid) {
+				return
+			}
+			if err == nil {
+				err = verifyHTTPSignature(w, r, sig, keys, cfg)
+			}
+			if err != nil {
+				if sig != nil {
+					cfg.recordFailure(sig.keyid, 0, err)
+				}
+				writeError(w, r, err)
+				return
+			}
+			id := &Identity{Actor: sig.keyid, Kid: sig.keyid}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+		})
+	}
+}
+
+// httpSignature 是 Signature-Input 中選定的一組簽章
+type httpSignature struct {
+	label      string
+	components []sfItem
+	params     []sfParam
+	keyid      string
+	alg        string
+	created    int64
+	expires    int64 // 0 = 無
+	value      []byte
+}
+
+func parseHTTPSignature(h http.Header) (*httpSignature, error) {
+	input, value := h.Values(HeaderSignatureInput), h.Values(HeaderHTTPSignature)
+	if len(input) == 0 || len(value) == 0 {
+		return nil, ErrMissingSignature
+	}
+	inputs, err := parseDictionary(strings.Join(input, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature-Input: %v", vax.ErrInvalidInput, err)
+	}
+	sigs, err := parseDictionary(strings.Join(value, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature: %v", vax.ErrInvalidInput, err)
+	}
+	if len(inputs) == 0 {
+		return nil, ErrMissingSignature
+	}
+
+	m := inputs[0]
+	s := &httpSignature{label: m.key, components: m.list, params: m.params}
+	if !m.inner {
+		return nil, fmt.Errorf("%w: Signature-Input %q is not an inner list", vax.ErrInvalidInput, m.key)
+	}
+	for _, sm := range sigs {
+		if sm.key == m.key {
+			s.value, _ = sm.item.([]byte)
+		}
+	}
+	if s.value == nil {
+		return nil, fmt.Errorf("%w: no signature value for %q", vax.ErrInvalidInput, m.key)
+	}
+	keyid, _ := m.param("keyid")
+	s.keyid, _ = keyid.(string)
+	alg, _ := m.param("alg")
+	s.alg, _ = alg.(string)
+	created, _ := m.param("created")
+	s.created, _ = created.(int64)
+	expires, _ := m.param("expires")
+	s.expires, _ = expires.(int64)
+	if s.keyid == "" {
+		return nil, fmt.Errorf("%w: signature %q has no keyid", sae.ErrMissingKid, m.key)
+	}
+	return s, nil
+}
+
+func verifyHTTPSignature(w http.ResponseWriter, r *http.Request, s *httpSignature, keys sae.KeyResolver, cfg config) error {
+	now := cfg.now()
+	if s.created == 0 {
+		return fmt.Errorf("%w: signature has no created parameter", sae.ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(s.created, 0)); age > cfg.maxSignatureAge || age < -cfg.maxSignatureAge {
+		return fmt.Errorf("%w: signature created %s ago", sae.ErrInvalidSignature, age.Round(time.Second))
+	}
+	if s.expires != 0 && now.Unix() >= s.expires {
+		return fmt.Errorf("%w: signature expired", sae.ErrInvalidSignature)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		return fmt.Errorf("%w: body: %v", vax.ErrInvalidInput, err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if cfg.canonicalBody && isJSON(r.Header.Get("Content-Type")) && len(body) > 0 {
+		if err := requireCanonical(body, false); err != nil {
+			return err
+		}
+	}
+
+	covered := map[string]bool{}
+	for _, c := range s.components {
+		name, _ := c.val.(string)
+		covered[name] = true
+	}
+	switch {
+	case !covered["@method"]:
+		return fmt.Errorf("%w: signature must cover @method", sae.ErrInvalidSignature)
+	case !covered["@target-uri"] && !covered["@path"]:
+		return fmt.Errorf("%w: signature must cover @target-uri or @path", sae.ErrInvalidSignature)
+	case len(body) > 0 && !covered["content-digest"]:
+		return fmt.Errorf("%w: signature must cover content-digest", sae.ErrInvalidSignature)
+	}
+	if covered["content-digest"] {
+		if err := checkContentDigest(r.Header.Values(HeaderContentDigest), body); err != nil {
+			return err
+		}
+	}
+
+	base, err := signatureBase(r, s.components, s.params)
+	if err != nil {
+		return err
+	}
+	pub, err := keys.Resolve(s.keyid)
+	if err != nil {
+		return err
+	}
+	return verifyHTTPSig(pub, s.alg, base, s.value)
+}
+
+// checkContentDigest 依 RFC 9530：至少一個支援的演算法，且所有支援的值都須相符
+func checkContentDigest(values []string, body []byte) error {
+	members, err := parseDictionary(strings.Join(values, ", "))
+	if err != nil {
+		return fmt.Errorf("%w: Content-Digest: %v", vax.ErrInvalidInput, err)
+	}
+	checked := false
+	for _, m := range members {
+		var sum []byte
+		switch m.key {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+		got, _ := m.item.([]byte)
+		if subtle.ConstantTimeCompare(got, sum) != 1 {
+			return fmt.Errorf("%w: Content-Digest %s does not match the body", vax.ErrInvalidInput, m.key)
+		}
+		checked = true
+	}
+	if !checked {
+		return fmt.Errorf("%w: Content-Digest has no sha-256 or sha-512 value", vax.ErrInvalidInput)
+	}
+	return nil
+}
+
+// signatureBase 依 RFC 9421 §2.5 組出簽章基底；只支援無參數的 component
+func signatureBase(r *http.Request, components []sfItem, params []sfParam) ([]byte, error) {
+	var b bytes.Buffer
+	seen := map[string]bool{}
+	for _, c := range components {
+		name, ok := c.val.(string)
+		if !ok || len(c.params) > 0 {
+			return nil, fmt.Errorf("%w: unsupported component %s", vax.ErrInvalidInput, serializeInnerList([]sfItem{c}, nil))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate component %q", vax.ErrInvalidInput, name)
+		}
+		seen[name] = true
+		value, err := componentValue(r, name)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%q: %s\n", name, value)
+	}
+	fmt.Fprintf(&b, "\"@signature-params\": %s", serializeInnerList(components, params))
+	return b.Bytes(), nil
+}
+
+func componentValue(r *http.Request, name string) (string, error) {
+	scheme := "http"
+	if r.TLS != nil || r.URL.Scheme == "https" {
+		scheme = "https"
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	switch name {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		return scheme + "://" + strings.ToLower(host) + r.URL.RequestURI(), nil
+	case "@authority":
+		return strings.ToLower(host), nil
+	case "@scheme":
+		return scheme, nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(name, "@") || name != strings.ToLower(name) {
+		return "", fmt.Errorf("%w: unsupported component %q", vax.ErrInvalidInput, name)
+	}
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: covered header %q is missing", vax.ErrInvalidInput, name)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// httpSigAlg 未指定 alg 時由金鑰型別決定；指定時須與金鑰相符
+func httpSigAlg(pub crypto.PublicKey, alg string) (string, error) {
+	var want []string
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		want = []string{HTTPSigEd25519}
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			want = []string{HTTPSigECDSAP256SHA256}
+		}
+	case *rsa.PublicKey:
+		want = []string{HTTPSigRSAPSSSHA512, HTTPSigRSAv15SHA256}
+	}
+	if len(want) == 0 {
+		return "", sae.ErrUnsupportedAlg
+	}
+	if alg == "" {
+		return want[0], nil
+	}
+	for _, a := range want {
+		if a == alg {
+			return alg, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q for %T", sae.ErrUnsupportedAlg, alg, pub)
+}
+
+func verifyHTTPSig(pub crypto.PublicKey, alg string, base, sig []byte) error {
+	alg, err := httpSigAlg(pub, alg)
+	if err != nil {
+		return err
+	}
+	ok := false
+	switch alg {
+	case HTTPSigEd25519:
+		ok = ed25519.Verify(pub.(ed25519.PublicKey), base, sig)
+	case HTTPSigECDSAP256SHA256:
+		// RFC 9421 §3.3.4：r || s 各 32 bytes，而非 ASN.1
+		if len(sig) == 64 {
+			h := sha256.Sum256(base)
+			ok = ecdsa.Verify(pub.(*ecdsa.PublicKey), h[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+		}
+	case HTTPSigRSAPSSSHA512:
+		h := sha512.Sum512(base)
+		ok = rsa.VerifyPSS(pub.(*rsa.PublicKey), crypto.SHA512, h[:], sig, &rsa.PSSOptions{SaltLength: 64}) == nil
+	case HTTPSigRSAv15SHA256:
+		h := sha256.Sum256(base)
+		ok = rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, h[:], sig) == nil
+	}
+	if !ok {
+		return sae.ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignHTTPRequest is the client side of VerifyHTTPSignature: it sets
+// Content-Digest (sha-256) for requests with a body and an RFC 9421
+// signature labelled "sig1" over components (@method and @target-uri
+// when empty, plus content-digest when there is a body), with created,
+// keyid and alg parameters. The body must be rewindable via GetBody.
+// Ed25519, ECDSA P-256 and RSA (rsa-pss-sha512) signers are supported.
+func SignHTTPRequest(req *http.Request, keyid string, signer crypto.Signer, components ...string) error {
+	alg, err := httpSigAlg(signer.Public(), "")
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if body, err = io.ReadAll(rc); err != nil {
+			return err
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return errors.New("api: SignHTTPRequest needs a request with GetBody")
+	}
+
+	if len(components) == 0 {
+		components = defaultSignatureComponents
+	}
+	var items []sfItem
+	hasDigest := false
+	for _, c := range components {
+		items = append(items, sfItem{val: c})
+		hasDigest = hasDigest || c == "content-digest"
+	}
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		req.Header.Set(HeaderContentDigest, "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		if !hasDigest {
+			items = append(items, sfItem{val: "content-digest"})
+		}
+	}
+	params := []sfParam{
+		{"created", time.Now().Unix()},
+		{"keyid", keyid},
+		{"alg", alg},
+	}
+
+	base, err := signatureBase(req, items, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signHTTPSig(signer, alg, base)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderSignatureInput, "sig1="+serializeInnerList(items, params))
+	req.Header.Set(HeaderHTTPSignature, "sig1=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+func signHTTPSig(signer crypto.Signer, alg string, base []byte) ([]byte, error) {
+	switch alg {
+	case HTTPSigEd25519:
+		return signer.Sign(rand.Reader, base, crypto.Hash(0))
+	case HTTPSigECDSAP256SHA256:
+		h := sha256.Sum256(base)
+		der, err := signer.Sign(rand.Reader, h[:], crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &rs); err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64)
+		rs.R.FillBytes(sig[:32])
+		rs.S.FillBytes(sig[32:])
+		return sig, nil
+	case HTTPSigRSAPSSSHA512:
+		h := sha512.Sum512(base)
+		return signer.Sign(rand.Reader, h[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	}
+	return nil, sae.ErrUnsupportedAlg
+}
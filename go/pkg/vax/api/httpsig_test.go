@@ -0,0 +1,202 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/sae"
+)
+
+func newHTTPSigServer(t *testing.T, keys sae.KeyResolver, opts ...Option) *httptest.Server {
+	t.Helper()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFrom(r.Context())
+		if !ok {
+			t.Error("no identity in context")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		writeJSON(w, http.StatusOK, map[string]any{"actor": id.Actor, "body": string(body)})
+	})
+	srv := httptest.NewServer(VerifyHTTPSignature(keys, opts...)(next))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func signedHTTPRequest(t *testing.T, url, body, keyid string, signer crypto.Signer, components ...string) *http.Request {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if err := SignHTTPRequest(req, keyid, signer, components...); err != nil {
+		t.Fatalf("SignHTTPRequest failed: %v", err)
+	}
+	return req
+}
+
+func TestVerifyHTTPSignature(t *testing.T) {
+	edPub, edPriv, _ := sae.GenerateKeyPair()
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaPriv, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := sae.StaticResolver{"partner-ed": edPub, "partner-ec": ecPriv.Public(), "partner-rsa": rsaPriv.Public()}
+	srv := newHTTPSigServer(t, keys)
+
+	t.Run("passes signed requests for every key type", func(t *testing.T) {
+		for kid, signer := range map[string]crypto.Signer{"partner-ed": edPriv, "partner-ec": ecPriv, "partner-rsa": rsaPriv} {
+			req := signedHTTPRequest(t, srv.URL+"/orders?dry=1", `{"a":1}`, kid, signer)
+			status, out := do(t, req)
+			if status != http.StatusOK || out["actor"] != kid || out["body"] != `{"a":1}` {
+				t.Errorf("%s: got %d %v", kid, status, out)
+			}
+		}
+	})
+
+	t.Run("covers content-digest automatically", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", `{"a":1}`, "partner-ed", edPriv)
+		if !strings.Contains(req.Header.Get(HeaderSignatureInput), `"content-digest"`) {
+			t.Errorf("Signature-Input = %q", req.Header.Get(HeaderSignatureInput))
+		}
+	})
+
+	t.Run("error: unsigned request", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orders", nil)
+		if status, out := do(t, req); status != http.StatusUnauthorized || out["code"] != CodeUnauthenticated {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: tampered body", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", `{"a":1}`, "partner-ed", edPriv)
+		req.Body = io.NopCloser(strings.NewReader(`{"a":2}`))
+		if status, out := do(t, req); status != http.StatusBadRequest || out["code"] != CodeInvalidInput {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: tampered digest", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", `{"a":1}`, "partner-ed", edPriv)
+		req.Body = io.NopCloser(strings.NewReader(`{"a":2}`))
+		sum := base64.StdEncoding.EncodeToString(make([]byte, 32))
+		req.Header.Set(HeaderContentDigest, "sha-256=:"+sum+":")
+		if status, _ := do(t, req); status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+	})
+
+	t.Run("error: tampered path", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", `{"a":1}`, "partner-ed", edPriv)
+		req.URL.Path = "/admin"
+		if status, out := do(t, req); status != http.StatusUnauthorized || out["code"] != CodeInvalidSignature {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: body not covered", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv)
+		req.Body = io.NopCloser(strings.NewReader(`{"a":1}`))
+		req.ContentLength = 7
+		if status, out := do(t, req); status != http.StatusUnauthorized || out["code"] != CodeInvalidSignature {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: method not covered", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv, "@path")
+		if status, _ := do(t, req); status != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", status)
+		}
+	})
+
+	t.Run("error: unknown keyid", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "nobody", edPriv)
+		if status, out := do(t, req); status != http.StatusUnauthorized || out["code"] != CodeInvalidSignature {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: alg does not match key", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv)
+		in := strings.Replace(req.Header.Get(HeaderSignatureInput), `alg="ed25519"`, `alg="rsa-pss-sha512"`, 1)
+		req.Header.Set(HeaderSignatureInput, in)
+		if status, _ := do(t, req); status != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", status)
+		}
+	})
+
+	late := func(c *config) { c.now = func() time.Time { return time.Now().Add(time.Hour) } }
+	t.Run("WithMaxSignatureAge", func(t *testing.T) {
+		srv := newHTTPSigServer(t, keys, late, WithMaxSignatureAge(2*time.Hour))
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv)
+		if status, body := do(t, req); status != http.StatusOK {
+			t.Errorf("expected 200, got %d %s", status, body)
+		}
+	})
+
+	t.Run("error: stale signature", func(t *testing.T) {
+		log := NewFailureLog(4)
+		srv := newHTTPSigServer(t, keys, late, WithFailureLog(log))
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv)
+		if status, _ := do(t, req); status != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", status)
+		}
+		if got := log.Recent("partner-ed", 0); len(got) != 1 {
+			t.Errorf("expected 1 recorded failure, got %v", got)
+		}
+	})
+
+	t.Run("error: malformed Signature-Input", func(t *testing.T) {
+		req := signedHTTPRequest(t, srv.URL+"/orders", "", "partner-ed", edPriv)
+		req.Header.Set(HeaderSignatureInput, `sig1=("@method"`)
+		if status, _ := do(t, req); status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+	})
+}
+
+// RFC 9421 附錄 B.2.6（ed25519）
+func TestSignatureBaseRFC9421(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", "18")
+	sig, err := parseHTTPSignature(http.Header{
+		HeaderSignatureInput: {`sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`},
+		HeaderHTTPSignature:  {`sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`},
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	base, err := signatureBase(req, sig.components, sig.params)
+	if err != nil {
+		t.Fatalf("signatureBase failed: %v", err)
+	}
+	want := `"date": Tue, 20 Apr 2021 02:07:55 GMT
+"@method": POST
+"@path": /foo
+"@authority": example.com
+"content-type": application/json
+"content-length": 18
+"@signature-params": ("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`
+	if string(base) != want {
+		t.Fatalf("base mismatch:\n%s\nwant:\n%s", base, want)
+	}
+
+	der, _ := base64.StdEncoding.DecodeString("MCowBQYDK2VwAyEAJrQLj5P/89iXES9+vFgrIy29clF9CC/oPPsw3c5D0bs=")
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyHTTPSig(pub, "", base, sig.value); err != nil {
+		t.Errorf("RFC vector did not verify: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrIdempotencyConflict is returned when an explicit idempotency key is
+// reused for a different action.
+var ErrIdempotencyConflict = errors.New("idempotency key reused for a different action")
+
+// IdempotencyHeader carries an explicit idempotency key on HTTP submissions
+// (SubmitRequest.IdempotencyKey takes precedence).
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyStore keeps the receipts of accepted submissions by
+// idempotency key (see WithIdempotency). Implementations must be safe for
+// concurrent use; MemoryIdempotency is the in-process one.
+type IdempotencyStore interface {
+	// Get returns the receipt stored under key (ok false if none).
+	Get(key string) (r *Receipt, ok bool, err error)
+	// Put stores r under key.
+	Put(key string, r *Receipt) error
+}
+
+// WithIdempotency answers a retried submission with the receipt of the
+// original one (Receipt.Replayed set) instead of 409 chain_conflict.
+// Submissions are keyed by actor and SubmitRequest.IdempotencyKey (or the
+// Idempotency-Key header), else by their SAI, so a retry of the same
+// bytes is recognized without client changes. An explicit key reused for
+// another SAI fails with 422 idempotency_conflict. A failing store lets
+// submissions through unchanged.
+func WithIdempotency(s IdempotencyStore) Option {
+	return func(c *config) {
+		c.idempotency = s
+	}
+}
+
+// idempotencyKey 以 actor 區隔；未指定 key 時以 SAI 為 key
+func idempotencyKey(req SubmitRequest) string {
+	if req.IdempotencyKey != "" {
+		return "key:" + req.Actor + ":" + req.IdempotencyKey
+	}
+	return "sai:" + req.Actor + ":" + strings.ToLower(req.SAI)
+}
+
+// replay 回傳 key 已存的 receipt（沒有或 store 錯誤時為 nil）
+func (c config) replay(key string, req SubmitRequest) (*Receipt, error) {
+	if c.idempotency == nil {
+		return nil, nil
+	}
+	r, ok, err := c.idempotency.Get(key)
+	if err != nil || !ok {
+		return nil, nil
+	}
+	if !strings.EqualFold(r.SAI, req.SAI) {
+		return nil, ErrIdempotencyConflict
+	}
+	replayed := *r
+	replayed.Replayed = true
+	return &replayed, nil
+}
+
+// MemoryIdempotency is an in-memory IdempotencyStore whose entries expire
+// after a fixed TTL.
+type MemoryIdempotency struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]idempotencyEntry
+	swept   time.Time
+	now     func() time.Time
+}
+
+type idempotencyEntry struct {
+	receipt Receipt
+	expires time.Time
+}
+
+// NewMemoryIdempotency keeps receipts for ttl (must be positive), which
+// should cover the clients' retry window.
+func NewMemoryIdempotency(ttl time.Duration) *MemoryIdempotency {
+	if ttl <= 0 {
+		panic("api: MemoryIdempotency ttl must be positive")
+	}
+	return &MemoryIdempotency{ttl: ttl, entries: map[string]idempotencyEntry{}, now: time.Now}
+}
+
+// Get implements IdempotencyStore.
+func (m *MemoryIdempotency) Get(key string) (*Receipt, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expires) {
+		return nil, false, nil
+	}
+	r := e.receipt
+	return &r, true, nil
+}
+
+// Put implements IdempotencyStore.
+func (m *MemoryIdempotency) Put(key string, r *Receipt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	// 每個 TTL 週期清一次過期項目
+	if now.Sub(m.swept) >= m.ttl {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.swept = now
+	}
+	m.entries[key] = idempotencyEntry{receipt: *r, expires: now.Add(m.ttl)}
+	return nil
+}
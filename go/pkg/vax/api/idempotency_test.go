@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// missOnce 第一次 Get 一律查無，模擬並行重送時 receipt 還沒存好
+type missOnce struct {
+	*MemoryIdempotency
+	missed bool
+}
+
+func (m *missOnce) Get(key string) (*Receipt, bool, error) {
+	if !m.missed {
+		m.missed = true
+		return nil, false, nil
+	}
+	return m.MemoryIdempotency.Get(key)
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("retry returns the original receipt", func(t *testing.T) {
+		f := newFixture(t, WithIdempotency(NewMemoryIdempotency(time.Minute)))
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+		_, first := f.post(t, req)
+		status, retry := f.post(t, req)
+		if status != http.StatusOK || retry["replayed"] != true || retry["accepted_at"] != first["accepted_at"] || retry["sai"] != req.SAI {
+			t.Errorf("retry: %d %v (first %v)", status, retry, first)
+		}
+		if head, _ := f.store.Head(testActor); head.Counter != 1 {
+			t.Errorf("retry advanced the chain: %+v", head)
+		}
+	})
+
+	t.Run("Idempotency-Key header", func(t *testing.T) {
+		f := newFixture(t, WithIdempotency(NewMemoryIdempotency(time.Minute)))
+		post := func(req SubmitRequest) (int, map[string]any) {
+			b, _ := json.Marshal(req)
+			hr, _ := http.NewRequest(http.MethodPost, f.srv.URL, bytes.NewReader(b))
+			hr.Header.Set(IdempotencyHeader, "upload-42")
+			resp, err := http.DefaultClient.Do(hr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var out map[string]any
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+		state := vax.ChainState{HeadSAI: f.genesis}
+		req := signedRequest(t, state, f.priv, "k1", map[string]any{"amount": 1})
+		if status, out := post(req); status != http.StatusOK {
+			t.Fatalf("%d %v", status, out)
+		}
+		if status, out := post(req); status != http.StatusOK || out["replayed"] != true {
+			t.Errorf("retry: %d %v", status, out)
+		}
+
+		sai, _ := vax.ComputeSAI(state.HeadSAI, req.SAE)
+		other := signedRequest(t, state.Advance(sai), f.priv, "k1", map[string]any{"amount": 2})
+		if status, out := post(other); status != http.StatusUnprocessableEntity || out["code"] != CodeIdempotencyConflict {
+			t.Errorf("reused key: %d %v", status, out)
+		}
+	})
+
+	t.Run("concurrent retry finds the receipt after the conflict", func(t *testing.T) {
+		genesis, _ := vax.ComputeGenesisSAI(testActor, testGenesisSalt)
+		store := vax.NewMemoryStore()
+		_ = store.Init(testActor, genesis)
+		pub, priv, _ := sae.GenerateKeyPair()
+		schemas := sdto.NewRegistry().Register("transfer", "",
+			sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema())
+		keys := sae.ActorResolver{testActor: {"k1": pub}}
+		req := signedRequest(t, vax.ChainState{HeadSAI: genesis}, priv, "k1", map[string]any{"amount": 1})
+
+		idem := &missOnce{MemoryIdempotency: NewMemoryIdempotency(time.Minute), missed: true}
+		if _, err := Submit(store, schemas, keys, req, WithIdempotency(idem)); err != nil {
+			t.Fatal(err)
+		}
+		idem.missed = false
+		r, err := Submit(store, schemas, keys, req, WithIdempotency(idem))
+		if err != nil || !r.Replayed || r.Counter != 1 {
+			t.Errorf("receipt = %+v, %v", r, err)
+		}
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		m := NewMemoryIdempotency(time.Minute)
+		now := time.Now()
+		m.now = func() time.Time { return now }
+		_ = m.Put("a", &Receipt{SAI: "00"})
+		if _, ok, _ := m.Get("a"); !ok {
+			t.Fatal("entry missing")
+		}
+		now = now.Add(time.Minute)
+		if _, ok, _ := m.Get("a"); ok {
+			t.Error("expired entry returned")
+		}
+		_ = m.Put("b", &Receipt{SAI: "01"})
+		if len(m.entries) != 1 {
+			t.Errorf("expired entries not swept: %d", len(m.entries))
+		}
+	})
+
+	t.Run("error: without the option a retry conflicts", func(t *testing.T) {
+		f := newFixture(t)
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+		f.post(t, req)
+		if status, out := f.post(t, req); status != http.StatusConflict {
+			t.Errorf("retry: %d %v", status, out)
+		}
+	})
+
+	t.Run("error: explicit key reused for another SAI", func(t *testing.T) {
+		m := NewMemoryIdempotency(time.Minute)
+		req := SubmitRequest{Actor: testActor, SAI: "aa", IdempotencyKey: "k"}
+		_ = m.Put(idempotencyKey(req), &Receipt{SAI: "bb"})
+		if _, err := (config{idempotency: m}).replay(idempotencyKey(req), req); !errors.Is(err, ErrIdempotencyConflict) {
+			t.Errorf("got %v", err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// Request headers of a chain-authenticated HTTP request (see SignRequest).
+const (
+	HeaderActor     = "VAX-Actor"
+	HeaderCounter   = "VAX-Counter"
+	HeaderTimestamp = "VAX-Timestamp" // unix ms
+	HeaderSAI       = "VAX-SAI"       // hex
+	HeaderAlg       = "VAX-Alg"
+	HeaderKid       = "VAX-Kid"
+	HeaderSignature = "VAX-Signature" // base64 (std)
+)
+
+// RequestActionType is the action_type of the envelope a request stands for.
+const RequestActionType = "http.request"
+
+// CodeUnauthenticated marks requests without (complete) VAX headers.
+const CodeUnauthenticated = "unauthenticated"
+
+// MaxBodyBytes caps the body VerifyMiddleware reads to hash.
+var MaxBodyBytes int64 = 1 << 20
+
+// ErrMissingHeaders is returned when a request lacks VAX headers.
+var ErrMissingHeaders = errors.New("missing VAX headers")
+
+// Identity is the verified caller VerifyMiddleware puts in the request context.
+type Identity struct {
+	Actor   string
+	Kid     string
+	Counter uint64 // chain position of this request
+	SAI     []byte // the actor's new chain head
+}
+
+type identityKey struct{}
+
+// IdentityFrom returns the Identity injected by VerifyMiddleware.
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// VerifyMiddleware authenticates every request as the next action in the
+// caller's chain, so existing handlers can be protected without changes.
+//
+// The request stands for an SAE that both sides build from the request
+// itself (see SignRequest): action_type "http.request", meta.actor, the
+// chain binding and an sdto of method, path, query and body_sha256 (SHA-256
+// of the JCS-canonicalized body for JSON content types, of the raw bytes
+// otherwise). The middleware recomputes the SAI from the stored head, checks
+// it against VAX-SAI, verifies the signature via keys, advances store and
+// passes the request on with an Identity in its context. The body is
+// restored for next; with WithCanonicalBody, JSON bodies must already be
+// canonical. Failures are answered with an ErrorResponse (401
+// unauthenticated / invalid_signature, 404 unknown_actor, 409
+// chain_conflict, 422 clock_skew / stale_timestamp under
+// WithTimestampPolicy, 429 rate_limited) and next is not called.
+func VerifyMiddleware(store vax.ChainStore, keys sae.KeyResolver, opts ...Option) func(http.Handler) http.Handler {
+	if store == nil || keys == nil {
+		panic("api: VerifyMiddleware needs a store and a key resolver")
+	}
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if actor := r.Header.Get(HeaderActor); actor != "" && cfg.rateLimited(w, r, actor) {
+				return
+			}
+			id, err := verifyRequest(w, r, store, keys, cfg)
+			if err != nil {
+				if errors.Is(err, ErrMissingHeaders) {
+					respond(w, r, http.StatusUnauthorized, ErrorResponse{Code: CodeUnauthenticated, Message: err.Error()})
+					return
+				}
+				counter, _ := strconv.ParseUint(r.Header.Get(HeaderCounter), 10, 64)
+				cfg.recordFailure(r.Header.Get(HeaderActor), counter, err)
+				writeError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+		})
+	}
+}
+
+func verifyRequest(w http.ResponseWriter, r *http.Request, store vax.ChainStore, keys sae.KeyResolver, cfg config) (*Identity, error) {
+	h := r.Header
+	actor := h.Get(HeaderActor)
+	if actor == "" || h.Get(HeaderSAI) == "" || h.Get(HeaderSignature) == "" {
+		return nil, ErrMissingHeaders
+	}
+	counter, err1 := strconv.ParseUint(h.Get(HeaderCounter), 10, 64)
+	ts, err2 := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	sai, err3 := hex.DecodeString(h.Get(HeaderSAI))
+	sig, err4 := base64.StdEncoding.DecodeString(h.Get(HeaderSignature))
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return nil, fmt.Errorf("%w: malformed VAX header: %v", vax.ErrInvalidInput, err)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: body: %v", vax.ErrInvalidInput, err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if cfg.canonicalBody && isJSON(r.Header.Get("Content-Type")) && len(body) > 0 {
+		if err := requireCanonical(body, false); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := vax.HeadContext(r.Context(), store, actor)
+	if err != nil {
+		return nil, err
+	}
+	env, err := requestEnvelope(r, body, actor, counter, state.HeadSAI, time.UnixMilli(ts))
+	if err != nil {
+		return nil, err
+	}
+	env.Alg, env.Kid, env.Signature = h.Get(HeaderAlg), h.Get(HeaderKid), sig
+
+	if err := vax.VerifyChainBinding(env, state); err != nil {
+		return nil, err
+	}
+	saeBytes, err := jcs.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	want, err := vax.ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(want, sai) {
+		return nil, vax.ErrSAIMismatch
+	}
+	if err := cfg.timePolicy.CheckTimestamp(env, state.Timestamp); err != nil {
+		return nil, err
+	}
+	if err := env.VerifyWithResolverContext(r.Context(), keys); err != nil {
+		return nil, err
+	}
+	next := state.Advance(sai)
+	next.Timestamp = env.Timestamp
+	if err := vax.CompareAndAdvanceContext(r.Context(), store, actor, state, next); err != nil {
+		return nil, err
+	}
+	return &Identity{Actor: actor, Kid: env.Kid, Counter: counter, SAI: sai}, nil
+}
+
+// SignRequest is the client side of VerifyMiddleware: it binds req (whose
+// body must be rewindable via GetBody, as http.NewRequest sets for byte
+// and string readers) to the next position after state, signs it and sets
+// the VAX headers. It returns the state to use for the next request.
+func SignRequest(req *http.Request, state vax.ChainState, actor, kid string, signer crypto.Signer) (vax.ChainState, error) {
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return state, err
+		}
+		defer rc.Close()
+		if body, err = io.ReadAll(rc); err != nil {
+			return state, err
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return state, errors.New("api: SignRequest needs a request with GetBody")
+	}
+
+	env, err := requestEnvelope(req, body, actor, state.Counter+1, state.HeadSAI, time.Now())
+	if err != nil {
+		return state, err
+	}
+	env.Kid = kid
+	if err := env.SignWith(signer, nil); err != nil {
+		return state, err
+	}
+	saeBytes, err := jcs.Marshal(env)
+	if err != nil {
+		return state, err
+	}
+	sai, err := vax.ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return state, err
+	}
+
+	req.Header.Set(HeaderActor, actor)
+	req.Header.Set(HeaderCounter, strconv.FormatUint(state.Counter+1, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(env.Timestamp, 10))
+	req.Header.Set(HeaderSAI, hex.EncodeToString(sai))
+	req.Header.Set(HeaderAlg, env.Alg)
+	req.Header.Set(HeaderKid, kid)
+	req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(env.Signature))
+	return state.Advance(sai), nil
+}
+
+// requestEnvelope 由 HTTP request 建立雙方共同的 SAE（不含 alg / kid / signature）
+func requestEnvelope(r *http.Request, body []byte, actor string, counter uint64, prevSAI []byte, at time.Time) (*sae.Envelope, error) {
+	digest, err := bodyDigest(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, err
+	}
+	sdto := map[string]any{
+		"method":      r.Method,
+		"path":        r.URL.EscapedPath(),
+		"query":       r.URL.RawQuery,
+		"body_sha256": digest,
+	}
+	return sae.NewEnvelope(RequestActionType, sdto,
+		sae.WithTimestamp(at),
+		sae.WithMetadata(actor, "", ""),
+		sae.WithChain(counter, prevSAI),
+	), nil
+}
+
+// bodyDigest JSON body 先以 JCS 正規化（空白、key 順序不影響簽章），其他內容直接雜湊
+func bodyDigest(contentType string, body []byte) (string, error) {
+	if isJSON(contentType) && len(body) > 0 {
+		canonical, err := jcs.CanonicalizeJSON(body)
+		if err != nil {
+			return "", fmt.Errorf("%w: body is not canonicalizable JSON: %v", vax.ErrInvalidInput, err)
+		}
+		body = canonical
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
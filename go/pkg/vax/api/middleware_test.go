@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/sae"
+)
+
+type middlewareFixture struct {
+	srv   *httptest.Server
+	store *vax.MemoryStore
+	state vax.ChainState
+	priv  ed25519.PrivateKey
+	seen  bytes.Buffer // next 收到的 body
+}
+
+func newMiddlewareFixture(t *testing.T, opts ...Option) *middlewareFixture {
+	t.Helper()
+	genesis, _ := vax.ComputeGenesisSAI(testActor, testGenesisSalt)
+	store := vax.NewMemoryStore()
+	_ = store.Init(testActor, genesis)
+	pub, priv, _ := sae.GenerateKeyPair()
+	f := &middlewareFixture{store: store, state: vax.ChainState{HeadSAI: genesis}, priv: priv}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFrom(r.Context())
+		if !ok {
+			t.Error("no identity in context")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.seen.Write(body)
+		writeJSON(w, http.StatusOK, id)
+	})
+	f.srv = httptest.NewServer(VerifyMiddleware(store, sae.StaticResolver{"k1": pub}, opts...)(next))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *middlewareFixture) request(t *testing.T, path, body string) *http.Request {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, f.srv.URL+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func (f *middlewareFixture) sign(t *testing.T, req *http.Request) vax.ChainState {
+	t.Helper()
+	next, err := SignRequest(req, f.state, testActor, "k1", f.priv)
+	if err != nil {
+		t.Fatalf("SignRequest failed: %v", err)
+	}
+	return next
+}
+
+func do(t *testing.T, req *http.Request) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestVerifyMiddleware(t *testing.T) {
+	t.Run("passes verified requests with identity and body", func(t *testing.T) {
+		f := newMiddlewareFixture(t)
+		for i := 1; i <= 2; i++ {
+			req := f.request(t, "/orders?dry=1", `{"b": 2, "a": 1}`)
+			f.state = f.sign(t, req)
+			status, out := do(t, req)
+			if status != http.StatusOK || out["Actor"] != testActor || out["Counter"] != float64(i) || out["Kid"] != "k1" {
+				t.Fatalf("request %d: got %d %v", i, status, out)
+			}
+		}
+		if f.seen.String() != `{"b": 2, "a": 1}{"b": 2, "a": 1}` {
+			t.Errorf("next saw body %q", f.seen.String())
+		}
+		head, _ := f.store.Head(testActor)
+		if head.Counter != 2 || !bytes.Equal(head.HeadSAI, f.state.HeadSAI) {
+			t.Errorf("store head = %+v, want %+v", head, f.state)
+		}
+	})
+
+	t.Run("JSON body is compared canonically", func(t *testing.T) {
+		f := newMiddlewareFixture(t)
+		req := f.request(t, "/orders", `{"b":2,"a":1}`)
+		f.sign(t, req)
+		// 重新排版不影響簽章
+		reformatted := "{\n  \"a\": 1,\n  \"b\": 2\n}"
+		req.Body, req.ContentLength = io.NopCloser(strings.NewReader(reformatted)), int64(len(reformatted))
+		if status, out := do(t, req); status != http.StatusOK {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	cases := []struct {
+		name   string
+		mutate func(req *http.Request)
+		status int
+		code   string
+	}{
+		{"error: missing headers", func(req *http.Request) { req.Header.Del(HeaderSignature) },
+			http.StatusUnauthorized, CodeUnauthenticated},
+		{"error: malformed counter", func(req *http.Request) { req.Header.Set(HeaderCounter, "x") },
+			http.StatusBadRequest, CodeInvalidInput},
+		{"error: unknown actor", func(req *http.Request) { req.Header.Set(HeaderActor, "nobody") },
+			http.StatusNotFound, CodeUnknownActor},
+		{"error: stale counter", func(req *http.Request) { req.Header.Set(HeaderCounter, "5") },
+			http.StatusConflict, CodeChainConflict},
+		{"error: tampered body", func(req *http.Request) {
+			req.Body = io.NopCloser(strings.NewReader(`{"a":1,"b":3}`))
+		}, http.StatusUnprocessableEntity, CodeSAIMismatch},
+		{"error: tampered path", func(req *http.Request) { req.URL.Path = "/admin" },
+			http.StatusUnprocessableEntity, CodeSAIMismatch},
+		{"error: swapped kid", func(req *http.Request) { req.Header.Set(HeaderKid, "k2") },
+			http.StatusUnprocessableEntity, CodeSAIMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMiddlewareFixture(t)
+			req := f.request(t, "/orders", `{"a":1,"b":2}`)
+			f.sign(t, req)
+			tc.mutate(req)
+			status, out := do(t, req)
+			if status != tc.status || out["code"] != tc.code {
+				t.Fatalf("got %d %v, want %d %s", status, out, tc.status, tc.code)
+			}
+			if f.seen.Len() != 0 {
+				t.Error("next must not be called")
+			}
+			if head, _ := f.store.Head(testActor); head.Counter != 0 {
+				t.Errorf("store advanced to %d", head.Counter)
+			}
+		})
+	}
+
+	t.Run("error: unknown kid", func(t *testing.T) {
+		f := newMiddlewareFixture(t)
+		req := f.request(t, "/orders", `{}`)
+		if _, err := SignRequest(req, f.state, testActor, "k2", f.priv); err != nil {
+			t.Fatalf("SignRequest failed: %v", err)
+		}
+		if status, out := do(t, req); status != http.StatusUnauthorized || out["code"] != CodeInvalidSignature {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("error: non-canonical body with WithCanonicalBody", func(t *testing.T) {
+		f := newMiddlewareFixture(t, WithCanonicalBody())
+		req := f.request(t, "/orders", `{"b":2,"a":1}`)
+		f.sign(t, req)
+		if status, out := do(t, req); status != http.StatusBadRequest || out["code"] != CodeInvalidInput {
+			t.Fatalf("got %d %v", status, out)
+		}
+		if head, _ := f.store.Head(testActor); head.Counter != 0 {
+			t.Errorf("store advanced to %d", head.Counter)
+		}
+	})
+
+	t.Run("error: replayed request", func(t *testing.T) {
+		f := newMiddlewareFixture(t)
+		req := f.request(t, "/orders", `{}`)
+		f.sign(t, req)
+		if status, _ := do(t, req); status != http.StatusOK {
+			t.Fatalf("first request: got %d", status)
+		}
+		replay := f.request(t, "/orders", `{}`)
+		replay.Header = req.Header.Clone()
+		if status, out := do(t, replay); status != http.StatusConflict || out["code"] != CodeChainConflict {
+			t.Fatalf("replay: got %d %v", status, out)
+		}
+	})
+}
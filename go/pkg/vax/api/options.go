@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/history"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/ratelimit"
+	"vax/pkg/vax/sae"
+)
+
+// Option configures HandleSubmitAction and VerifyMiddleware.
+type Option func(*config)
+
+type config struct {
+	canonicalBody bool
+	limiter       ratelimit.Limiter
+	failures      *FailureLog
+	notifier      FailureNotifier
+	history       history.Store
+	idempotency   IdempotencyStore
+	decryptionKey *ecdh.PrivateKey
+	keyOwner      func(kid string) (string, error)
+	timePolicy    sae.TimePolicy
+	logger        *slog.Logger
+	// HTTP 簽章（VerifyHTTPSignature）created 參數的容許誤差
+	maxSignatureAge time.Duration
+	now             func() time.Time // HTTP 簽章的 created / expires 檢查（測試可替換）
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{now: time.Now, maxSignatureAge: DefaultMaxSignatureAge}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithCanonicalBody rejects JSON request bodies that are not already VAX-JCS
+// canonical (jcs.IsCanonical) with 400 invalid_input and a hint at the first
+// differing byte (CBOR bodies must be deterministic, cbor.IsDeterministic), instead of re-canonicalizing them on the server. Clients
+// then sign and send the exact same bytes, so there is no ambiguity about
+// which form a signature or hash covers.
+func WithCanonicalBody() Option {
+	return func(c *config) {
+		c.canonicalBody = true
+	}
+}
+
+// WithRateLimit checks l, keyed by actor ID, before any signature or hash
+// work. Rejected requests get 429 rate_limited with a Retry-After header.
+// A failing limiter (e.g. Redis down) lets requests through; wrap it with
+// ratelimit.Instrument to count such errors.
+func WithRateLimit(l ratelimit.Limiter) Option {
+	return func(c *config) {
+		c.limiter = l
+	}
+}
+
+// WithFailureLog records every rejected verification (with the actor and
+// error code) in l, for the admin API (see HandleAdmin).
+func WithFailureLog(l *FailureLog) Option {
+	return func(c *config) {
+		c.failures = l
+	}
+}
+
+// FailureNotifier is told about every rejected verification;
+// *notify.Notifier implements it (alerting on SAI mismatches and forks).
+type FailureNotifier interface {
+	VerificationFailed(actor string, counter uint64, err error)
+}
+
+// WithNotifier passes rejected verifications to n (see package notify).
+func WithNotifier(n FailureNotifier) Option {
+	return func(c *config) {
+		c.notifier = n
+	}
+}
+
+// WithHistory appends every accepted submission (counter, exact SAE bytes,
+// SAI) to h after the chain store has advanced. If the append fails the
+// submission is answered 500 internal although the chain moved; compare
+// h's Head with the chain store to reconcile.
+func WithHistory(h history.Store) Option {
+	return func(c *config) {
+		c.history = h
+	}
+}
+
+// WithDecryptionKey accepts encrypted submissions addressed to key: their
+// payload is decrypted and validated against the schema (see
+// vax.WithDecryptionKey). Without it encrypted submissions are answered
+// 422 invalid_sdto.
+func WithDecryptionKey(key *ecdh.PrivateKey) Option {
+	return func(c *config) {
+		c.decryptionKey = key
+	}
+}
+
+// WithKeyOwner tells submissions which actor each kid belongs to, for key
+// resolvers that do not implement sae.KeyOwner (e.g. sae.JWKSResolver).
+// A submission whose kid belongs to another actor is answered 401
+// invalid_signature.
+func WithKeyOwner(owner func(kid string) (actor string, err error)) Option {
+	return func(c *config) {
+		c.keyOwner = owner
+	}
+}
+
+// WithTimestampPolicy checks envelope timestamps against p, for
+// submissions (see vax.WithTimestampPolicy) and VerifyMiddleware alike.
+// Violations are answered 422 clock_skew / stale_timestamp. Without it
+// timestamps are not checked.
+func WithTimestampPolicy(p sae.TimePolicy) Option {
+	return func(c *config) {
+		c.timePolicy = p
+	}
+}
+
+// WithLogger passes l to the verification of every submission (see
+// vax.WithLogger).
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithMaxSignatureAge bounds how old (or how far in the future) the created
+// parameter of a VerifyHTTPSignature request may be; the default is
+// DefaultMaxSignatureAge.
+func WithMaxSignatureAge(d time.Duration) Option {
+	return func(c *config) {
+		c.maxSignatureAge = d
+	}
+}
+
+// owner 回傳 kid → actor 的查詢：WithKeyOwner 優先，其次 keys 本身（sae.KeyOwner）
+func (c config) owner(keys sae.KeyResolver) func(string) (string, error) {
+	if c.keyOwner != nil {
+		return c.keyOwner
+	}
+	if o, ok := keys.(sae.KeyOwner); ok {
+		return o.Owner
+	}
+	return nil
+}
+
+// verifyOptions 轉成 vax.VerifyAndAdvance 的選項
+func (c config) verifyOptions() []vax.Option {
+	var opts []vax.Option
+	if c.decryptionKey != nil {
+		opts = append(opts, vax.WithDecryptionKey(c.decryptionKey))
+	}
+	return append(opts, vax.WithTimestampPolicy(c.timePolicy), vax.WithLogger(c.logger))
+}
+
+// recordFailure 記錄驗證失敗並通知（未設定時略過）
+func (c config) recordFailure(actor string, counter uint64, err error) {
+	if c.notifier != nil {
+		c.notifier.VerificationFailed(actor, counter, err)
+	}
+	if c.failures == nil {
+		return
+	}
+	_, resp := Classify(err)
+	c.failures.Record(Failure{Actor: actor, Counter: counter, Code: resp.Code, Message: resp.Message})
+}
+
+// rateLimited 超出額度時寫出 429 並回傳 true；limiter 錯誤時放行
+func (c config) rateLimited(w http.ResponseWriter, r *http.Request, actor string) bool {
+	if c.limiter == nil {
+		return false
+	}
+	ok, retryAfter, err := c.limiter.Allow(actor)
+	if err != nil || ok {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	respond(w, r, http.StatusTooManyRequests, ErrorResponse{Code: CodeRateLimited, Message: "rate limit exceeded"})
+	return true
+}
+
+// hintWindow 是 hint 中差異前後各顯示的位元組數
+const hintWindow = 16
+
+// requireCanonical body 非 canonical（CBOR 則為非 deterministic）時回傳附差異位置的 ErrInvalidInput
+func requireCanonical(body []byte, isCBOR bool) error {
+	if isCBOR {
+		if cbor.IsDeterministic(body) {
+			return nil
+		}
+		return fmt.Errorf("%w: body is not deterministic CBOR (RFC 8949 §4.2.1)", vax.ErrInvalidInput)
+	}
+	if jcs.IsCanonical(body) {
+		return nil
+	}
+	canonical, err := jcs.CanonicalizeJSON(body)
+	if err != nil {
+		return fmt.Errorf("%w: body is not valid JSON: %v", vax.ErrInvalidInput, err)
+	}
+	i := firstDifference(body, canonical)
+	return fmt.Errorf("%w: body is not VAX-JCS canonical at byte %d: got %q, want %q",
+		vax.ErrInvalidInput, i, excerpt(body, i), excerpt(canonical, i))
+}
+
+func firstDifference(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// excerpt 取 b 在 i 附近的片段，被截斷的一側以 "…" 標示
+func excerpt(b []byte, i int) string {
+	start, end := max(i-hintWindow, 0), min(i+hintWindow, len(b))
+	var s bytes.Buffer
+	if start > 0 {
+		s.WriteString("…")
+	}
+	s.Write(b[start:end])
+	if end < len(b) {
+		s.WriteString("…")
+	}
+	return s.String()
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/history"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/ratelimit"
+)
+
+func TestWithCanonicalBody(t *testing.T) {
+	post := func(t *testing.T, url string, body []byte) (int, ErrorResponse) {
+		t.Helper()
+		resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	t.Run("accepts canonical bodies", func(t *testing.T) {
+		f := newFixture(t, WithCanonicalBody())
+		body, _ := jcs.Marshal(signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 10}))
+		if status, out := post(t, f.srv.URL, body); status != http.StatusOK {
+			t.Fatalf("got %d %+v", status, out)
+		}
+	})
+
+	t.Run("error: non-canonical body with hint", func(t *testing.T) {
+		f := newFixture(t, WithCanonicalBody())
+		body, _ := json.MarshalIndent(signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 10}), "", "  ")
+		status, out := post(t, f.srv.URL, body)
+		if status != http.StatusBadRequest || out.Code != CodeInvalidInput || !strings.Contains(out.Message, "not VAX-JCS canonical at byte 1") {
+			t.Fatalf("got %d %+v", status, out)
+		}
+	})
+
+	t.Run("error: invalid JSON", func(t *testing.T) {
+		f := newFixture(t, WithCanonicalBody())
+		status, out := post(t, f.srv.URL, []byte(`{"actor":`))
+		if status != http.StatusBadRequest || !strings.Contains(out.Message, "not valid JSON") {
+			t.Fatalf("got %d %+v", status, out)
+		}
+	})
+}
+
+func TestRequireCanonical(t *testing.T) {
+	t.Run("hint shows both sides of the first difference", func(t *testing.T) {
+		err := requireCanonical([]byte(`{"counter":1,"actor":"a"}`), false)
+		want := `at byte 2: got "{\"counter\":1,\"acto…", want "{\"actor\":\"a\",\"coun…"`
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("got %v, want hint %s", err, want)
+		}
+	})
+
+	t.Run("canonical body passes", func(t *testing.T) {
+		if err := requireCanonical([]byte(`{"actor":"a","counter":1}`), false); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(string) (bool, time.Duration, error) { return false, 0, errors.New("down") }
+
+func TestWithRateLimit(t *testing.T) {
+	t.Run("rejects over budget before verification", func(t *testing.T) {
+		var metrics ratelimit.Metrics
+		limiter := ratelimit.Instrument(ratelimit.NewMemory(ratelimit.Limit{Every: time.Minute, Burst: 1}), &metrics)
+		f := newFixture(t, WithRateLimit(limiter))
+		state := vax.ChainState{HeadSAI: f.genesis}
+		if status, out := f.post(t, signedRequest(t, state, f.priv, "k1", map[string]any{"amount": 1})); status != http.StatusOK {
+			t.Fatalf("first request: got %d %v", status, out)
+		}
+
+		b, _ := json.Marshal(signedRequest(t, state, f.priv, "k1", map[string]any{"amount": 2}))
+		resp, err := http.Post(f.srv.URL, "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || out.Code != CodeRateLimited || resp.Header.Get("Retry-After") != "60" {
+			t.Fatalf("got %d %+v Retry-After=%q", resp.StatusCode, out, resp.Header.Get("Retry-After"))
+		}
+		if metrics.Allowed.Load() != 1 || metrics.Rejected.Load() != 1 {
+			t.Errorf("metrics = %s", metrics.String())
+		}
+	})
+
+	t.Run("failing limiter lets requests through", func(t *testing.T) {
+		f := newFixture(t, WithRateLimit(failingLimiter{}))
+		if status, out := f.post(t, signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})); status != http.StatusOK {
+			t.Fatalf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("middleware limits by VAX-Actor", func(t *testing.T) {
+		f := newMiddlewareFixture(t, WithRateLimit(ratelimit.NewMemory(ratelimit.Limit{Every: time.Minute, Burst: 1})))
+		for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			req := f.request(t, "/orders", `{}`)
+			f.state = f.sign(t, req)
+			if status, out := do(t, req); status != want {
+				t.Fatalf("request %d: got %d %v, want %d", i, status, out, want)
+			}
+		}
+	})
+}
+
+type recordingNotifier struct {
+	errs []error
+}
+
+func (n *recordingNotifier) VerificationFailed(actor string, counter uint64, err error) {
+	n.errs = append(n.errs, err)
+}
+
+func TestWithNotifier(t *testing.T) {
+	n := &recordingNotifier{}
+	f := newFixture(t, WithNotifier(n))
+	req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+	req.SAI = strings.Repeat("00", vax.SAISize)
+	f.post(t, req)
+	if len(n.errs) != 1 || !errors.Is(n.errs[0], vax.ErrSAIMismatch) {
+		t.Fatalf("notified %v", n.errs)
+	}
+}
+
+type failingHistory struct{ history.Store }
+
+func (failingHistory) Append(history.Record) error { return errors.New("disk full") }
+
+func TestWithHistory(t *testing.T) {
+	t.Run("appends every accepted submission", func(t *testing.T) {
+		h := history.NewMemory()
+		f := newFixture(t, WithHistory(h))
+		state := vax.ChainState{HeadSAI: f.genesis}
+		for i := 1; i <= 2; i++ {
+			req := signedRequest(t, state, f.priv, "k1", map[string]any{"amount": i})
+			if status, out := f.post(t, req); status != http.StatusOK {
+				t.Fatalf("step %d: status %d: %v", i, status, out)
+			}
+			sai, _ := hex.DecodeString(req.SAI)
+			state = state.Advance(sai)
+
+			rec, err := h.GetByCounter(testActor, uint64(i))
+			if err != nil {
+				t.Fatalf("step %d: %v", i, err)
+			}
+			if !bytes.Equal(rec.SAE, req.SAE) || !bytes.Equal(rec.SAI, sai) || rec.ActionType != "transfer" || rec.AcceptedAt == 0 {
+				t.Errorf("step %d: unexpected record %+v", i, rec)
+			}
+		}
+	})
+
+	t.Run("error: rejected submissions are not recorded", func(t *testing.T) {
+		h := history.NewMemory()
+		f := newFixture(t, WithHistory(h))
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 5000})
+		if status, _ := f.post(t, req); status != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d", status)
+		}
+		if _, err := h.Head(testActor); !errors.Is(err, history.ErrNotFound) {
+			t.Errorf("expected no record, got %v", err)
+		}
+	})
+
+	t.Run("error: append failure is internal", func(t *testing.T) {
+		f := newFixture(t, WithHistory(failingHistory{}))
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+		if status, out := f.post(t, req); status != http.StatusInternalServerError || out["code"] != CodeInternal {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+}
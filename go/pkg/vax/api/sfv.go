@@ -0,0 +1,294 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RFC 8941 structured field 的最小實作：只涵蓋 Signature / Signature-Input /
+// Content-Digest 需要的 dictionary、inner list 與 item 型別
+
+type sfParam struct {
+	key string
+	val any // int64, string, sfToken, bool, []byte
+}
+
+type sfToken string
+
+type sfMember struct {
+	key    string
+	item   any      // 非 inner list 時
+	list   []sfItem // inner list 時
+	inner  bool
+	params []sfParam
+}
+
+type sfItem struct {
+	val    any
+	params []sfParam
+}
+
+func (m sfMember) param(key string) (any, bool) {
+	for _, p := range m.params {
+		if p.key == key {
+			return p.val, true
+		}
+	}
+	return nil, false
+}
+
+type sfParser struct {
+	s string
+	i int
+}
+
+func parseDictionary(s string) ([]sfMember, error) {
+	p := &sfParser{s: s}
+	var members []sfMember
+	p.skipSP()
+	for p.i < len(p.s) {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		m := sfMember{key: key}
+		if p.peek() == '=' {
+			p.i++
+			if p.peek() == '(' {
+				if m.list, err = p.innerList(); err != nil {
+					return nil, err
+				}
+				m.inner = true
+			} else if m.item, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		} else {
+			m.item = true
+		}
+		if m.params, err = p.params(); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+		p.skipOWS()
+		if p.i == len(p.s) {
+			break
+		}
+		if p.s[p.i] != ',' {
+			return nil, p.errorf("expected ','")
+		}
+		p.i++
+		p.skipOWS()
+		if p.i == len(p.s) {
+			return nil, p.errorf("trailing ','")
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) innerList() ([]sfItem, error) {
+	p.i++ // '('
+	var items []sfItem
+	for {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.i++
+			return items, nil
+		}
+		val, err := p.bareItem()
+		if err != nil {
+			return nil, err
+		}
+		params, err := p.params()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, sfItem{val, params})
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, p.errorf("expected ' ' or ')'")
+		}
+	}
+}
+
+func (p *sfParser) params() ([]sfParam, error) {
+	var params []sfParam
+	for p.peek() == ';' {
+		p.i++
+		p.skipSP()
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var val any = true
+		if p.peek() == '=' {
+			p.i++
+			if val, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, sfParam{key, val})
+	}
+	return params, nil
+}
+
+func (p *sfParser) bareItem() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '"':
+		return p.str()
+	case c == ':':
+		end := strings.IndexByte(p.s[p.i+1:], ':')
+		if end < 0 {
+			return nil, p.errorf("unterminated byte sequence")
+		}
+		b, err := base64.StdEncoding.DecodeString(p.s[p.i+1 : p.i+1+end])
+		if err != nil {
+			return nil, p.errorf("byte sequence: %v", err)
+		}
+		p.i += end + 2
+		return b, nil
+	case c == '?':
+		if p.i+1 < len(p.s) && (p.s[p.i+1] == '0' || p.s[p.i+1] == '1') {
+			p.i += 2
+			return p.s[p.i-1] == '1', nil
+		}
+		return nil, p.errorf("invalid boolean")
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.i
+		p.i++
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		if p.peek() == '.' {
+			return nil, p.errorf("decimals are not supported")
+		}
+		n, err := strconv.ParseInt(p.s[start:p.i], 10, 64)
+		if err != nil || p.i-start > 16 {
+			return nil, p.errorf("invalid integer")
+		}
+		return n, nil
+	case c == '*' || isAlpha(c):
+		start := p.i
+		for p.i < len(p.s) && isTokenChar(p.s[p.i]) {
+			p.i++
+		}
+		return sfToken(p.s[start:p.i]), nil
+	}
+	return nil, p.errorf("unexpected character")
+}
+
+func (p *sfParser) str() (string, error) {
+	var sb strings.Builder
+	for p.i++; p.i < len(p.s); p.i++ {
+		switch c := p.s[p.i]; {
+		case c == '"':
+			p.i++
+			return sb.String(), nil
+		case c == '\\':
+			p.i++
+			if p.i == len(p.s) || (p.s[p.i] != '"' && p.s[p.i] != '\\') {
+				return "", p.errorf("invalid escape")
+			}
+			sb.WriteByte(p.s[p.i])
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid string character")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *sfParser) key() (string, error) {
+	start := p.i
+	if c := p.peek(); !(c == '*' || (c >= 'a' && c <= 'z')) {
+		return "", p.errorf("invalid key")
+	}
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *sfParser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *sfParser) skipSP() {
+	for p.peek() == ' ' {
+		p.i++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.i++
+	}
+}
+
+func (p *sfParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("structured field at %d: "+format, append([]any{p.i}, args...)...)
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isTokenChar(c byte) bool {
+	return isAlpha(c) || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
+
+// serializeInnerList 依 RFC 8941 §4.1.1.1 序列化（@signature-params 的值）
+func serializeInnerList(items []sfItem, params []sfParam) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for i, item := range items {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(serializeBareItem(item.val))
+		sb.WriteString(serializeParams(item.params))
+	}
+	sb.WriteByte(')')
+	sb.WriteString(serializeParams(params))
+	return sb.String()
+}
+
+func serializeParams(params []sfParam) string {
+	var sb strings.Builder
+	for _, p := range params {
+		sb.WriteString(";" + p.key)
+		if b, ok := p.val.(bool); ok && b {
+			continue
+		}
+		sb.WriteString("=" + serializeBareItem(p.val))
+	}
+	return sb.String()
+}
+
+func serializeBareItem(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	case sfToken:
+		return string(v)
+	case bool:
+		if v {
+			return "?1"
+		}
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	}
+	return ""
+}
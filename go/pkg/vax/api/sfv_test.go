@@ -0,0 +1,34 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseDictionary(t *testing.T) {
+	t.Run("inner lists, items and parameters round-trip", func(t *testing.T) {
+		in := `sig1=("@method" "content-digest");created=1618884473;keyid="k\"1";tag=app, flag, sig2=:AAE=:`
+		m, err := parseDictionary(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != 3 || !m[0].inner || m[1].item != true || !bytes.Equal(m[2].item.([]byte), []byte{0, 1}) {
+			t.Fatalf("unexpected members: %+v", m)
+		}
+		if keyid, _ := m[0].param("keyid"); keyid != `k"1` {
+			t.Errorf("keyid = %v", keyid)
+		}
+		want := `("@method" "content-digest");created=1618884473;keyid="k\"1";tag=app`
+		if got := serializeInnerList(m[0].list, m[0].params); got != want {
+			t.Errorf("serialized %q, want %q", got, want)
+		}
+	})
+
+	for _, in := range []string{`sig1=("a"`, `Sig1=1`, `a=1,`, `a="x`, `a=?2`, `a=1.5`, `a=:!!:`, `a=1 b=2`} {
+		t.Run("error: "+in, func(t *testing.T) {
+			if _, err := parseDictionary(in); err == nil {
+				t.Errorf("expected error for %q", in)
+			}
+		})
+	}
+}
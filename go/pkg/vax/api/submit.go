@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/history"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// MaxSubmitBytes caps the request body of HandleSubmitAction.
+var MaxSubmitBytes int64 = 1 << 20
+
+// ErrNoKeyOwner is returned by Submit when neither the key resolver
+// (sae.KeyOwner) nor WithKeyOwner says which actor a kid belongs to.
+var ErrNoKeyOwner = errors.New("api: key resolver does not report key owners")
+
+// SubmitRequest is the wire format of one action submission.
+type SubmitRequest struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`  // must equal the envelope's in-band counter
+	PrevSAI string `json:"prev_sai"` // hex
+	SAE     []byte `json:"sae"`      // base64 of the exact SAE bytes (canonical or Compress form)
+	SAI     string `json:"sai"`      // hex
+	// IdempotencyKey optionally names the submission for WithIdempotency
+	// (default: the SAI).
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// Receipt is returned for an accepted action.
+type Receipt struct {
+	Actor      string `json:"actor"`
+	ActionType string `json:"action_type"`
+	Counter    uint64 `json:"counter"`
+	SAI        string `json:"sai"`         // hex; the actor's new chain head
+	PrevSAI    string `json:"prev_sai"`    // hex
+	AcceptedAt int64  `json:"accepted_at"` // unix ms
+	// Replayed marks the original receipt returned for a retried
+	// submission (see WithIdempotency).
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// HandleSubmitAction serves POST submissions: it parses the SubmitRequest
+// (JSON, or CBOR with sae as a byte string),
+// looks up the schema registered for the envelope's (action_type,
+// schema_version), and runs vax.VerifyAndAdvance against store with keys
+// resolving the envelope's kid. The kid must belong to the submitting actor:
+// keys must implement sae.KeyOwner (e.g. sae.ActorResolver), or pass
+// WithKeyOwner. The store only advances when every check passes; the
+// response is a Receipt (200) or an ErrorResponse:
+//
+//	400 invalid_input      malformed request, counter / actor mismatch
+//	401 invalid_signature  unsigned, unknown kid, another actor's kid, bad signature
+//	404 unknown_actor      no chain for the actor
+//	409 chain_conflict     stale counter / prev_sai (replay, concurrent submit)
+//	413 too_large          envelope beyond sae.Limits (bytes, sdto fields, string length)
+//	422 invalid_sdto       schema violations, with per-field errors; encrypted
+//	                       payloads without WithDecryptionKey or that do not decrypt
+//	422 unknown_schema     no schema for the action type / version
+//	422 sai_mismatch       SAI does not hash the submitted bytes
+//	422 clock_skew         timestamp too far from server time (WithTimestampPolicy)
+//	422 stale_timestamp    timestamp earlier than the previous action
+//	422 idempotency_conflict  idempotency key reused for another action (WithIdempotency)
+//	429 rate_limited       see WithRateLimit
+//
+// See WithCanonicalBody for requiring canonical request bodies and
+// WithHistory for keeping every accepted record.
+func HandleSubmitAction(store vax.ChainStore, schemas *sdto.Registry, keys sae.KeyResolver, opts ...Option) http.Handler {
+	if store == nil || schemas == nil || keys == nil {
+		panic("api: HandleSubmitAction needs a store, a schema registry and a key resolver")
+	}
+	cfg := newConfig(opts)
+	if cfg.owner(keys) == nil {
+		panic("api: HandleSubmitAction needs a key resolver implementing sae.KeyOwner, or WithKeyOwner")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			respond(w, r, http.StatusMethodNotAllowed, ErrorResponse{Code: CodeInvalidInput, Message: "method not allowed"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSubmitBytes))
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: %v", vax.ErrInvalidInput, err))
+			return
+		}
+		isCBOR := cbor.IsCBOR(r.Header.Get("Content-Type"))
+		if cfg.canonicalBody {
+			if err := requireCanonical(body, isCBOR); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+		if isCBOR {
+			// 轉成 JSON 後走同一個嚴格的 decoder（sae 可為 byte string）
+			if body, err = cbor.ToJSON(body); err != nil {
+				writeError(w, r, fmt.Errorf("%w: %v", vax.ErrInvalidInput, err))
+				return
+			}
+		}
+		var req SubmitRequest
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, r, fmt.Errorf("%w: %v", vax.ErrInvalidInput, err))
+			return
+		}
+		if cfg.rateLimited(w, r, req.Actor) {
+			return
+		}
+		if req.IdempotencyKey == "" {
+			req.IdempotencyKey = r.Header.Get(IdempotencyHeader)
+		}
+		receipt, err := SubmitContext(r.Context(), store, schemas, keys, req, opts...)
+		if err != nil {
+			cfg.recordFailure(req.Actor, req.Counter, err)
+			writeError(w, r, err)
+			return
+		}
+		respond(w, r, http.StatusOK, receipt)
+	})
+}
+
+// Submit runs the submission pipeline of HandleSubmitAction on an already
+// decoded request, for transports other than HTTP. Only WithHistory,
+// WithIdempotency, WithDecryptionKey, WithKeyOwner, WithTimestampPolicy and
+// WithLogger apply.
+func Submit(store vax.ChainStore, schemas *sdto.Registry, keys sae.KeyResolver, req SubmitRequest, opts ...Option) (*Receipt, error) {
+	return SubmitContext(context.Background(), store, schemas, keys, req, opts...)
+}
+
+// SubmitContext is Submit with ctx passed to the chain store, the key
+// resolver and the history store (see vax.VerifyAndAdvanceContext).
+func SubmitContext(ctx context.Context, store vax.ChainStore, schemas *sdto.Registry, keys sae.KeyResolver, req SubmitRequest, opts ...Option) (*Receipt, error) {
+	cfg := newConfig(opts)
+	prevSAI, err1 := hex.DecodeString(req.PrevSAI)
+	sai, err2 := hex.DecodeString(req.SAI)
+	if err1 != nil || err2 != nil || req.Actor == "" || len(req.SAE) == 0 {
+		return nil, fmt.Errorf("%w: actor, prev_sai, sae and sai are required", vax.ErrInvalidInput)
+	}
+	key := idempotencyKey(req)
+	if r, err := cfg.replay(key, req); r != nil || err != nil {
+		return r, err
+	}
+
+	// 先解析一次取得 action_type / schema_version 以查 schema（完整驗證在 VerifyAndAdvance）
+	env, err := sae.Parse(req.SAE)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sae: %w", vax.ErrInvalidInput, err)
+	}
+	if env.Counter != req.Counter {
+		return nil, fmt.Errorf("%w: counter %d does not match envelope counter %d", vax.ErrInvalidInput, req.Counter, env.Counter)
+	}
+	if env.Meta != nil && env.Meta.Actor != "" && env.Meta.Actor != req.Actor {
+		return nil, fmt.Errorf("%w: actor does not match envelope meta.actor", vax.ErrInvalidInput)
+	}
+	schema, err := schemas.Lookup(env.ActionType, env.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyOwner(cfg.owner(keys), env.Kid, req.Actor); err != nil {
+		return nil, err
+	}
+
+	env, next, err := vax.VerifyAndAdvanceContext(ctx, store, req.Actor, prevSAI, req.SAE, sai, schema, keys, cfg.verifyOptions()...)
+	if err != nil {
+		// 同一動作的並行重送：先到的那次可能剛存好 receipt
+		if r, rerr := cfg.replay(key, req); r != nil || rerr != nil {
+			return r, rerr
+		}
+		return nil, err
+	}
+	acceptedAt := time.Now().UnixMilli()
+	if cfg.history != nil {
+		err := history.AppendContext(ctx, cfg.history, history.Record{
+			Actor:      req.Actor,
+			Counter:    next.Counter,
+			ActionType: env.ActionType,
+			PrevSAI:    prevSAI,
+			SAE:        req.SAE,
+			SAI:        next.HeadSAI,
+			AcceptedAt: acceptedAt,
+		})
+		if err != nil {
+			// chain 已前進：回報內部錯誤，由維運依 Head 比對補齊 history
+			return nil, fmt.Errorf("api: history append for %s counter %d: %w", req.Actor, next.Counter, err)
+		}
+	}
+	receipt := &Receipt{
+		Actor:      req.Actor,
+		ActionType: env.ActionType,
+		Counter:    next.Counter,
+		SAI:        hex.EncodeToString(next.HeadSAI),
+		PrevSAI:    req.PrevSAI,
+		AcceptedAt: acceptedAt,
+	}
+	if cfg.idempotency != nil {
+		// 存不進去只代表之後的重送拿到 409，不影響這次的結果
+		_ = cfg.idempotency.Put(key, receipt)
+	}
+	return receipt, nil
+}
+
+// checkKeyOwner 確認 kid 屬於提交的 actor（未簽名的交給 VerifyAndAdvance 拒絕）
+func checkKeyOwner(owner func(string) (string, error), kid, actor string) error {
+	if owner == nil {
+		return ErrNoKeyOwner
+	}
+	if kid == "" {
+		return nil
+	}
+	got, err := owner(kid)
+	if err != nil {
+		return err
+	}
+	if got != actor {
+		return fmt.Errorf("%w: kid %q is not a key of %s", sae.ErrKeyNotOwned, kid, actor)
+	}
+	return nil
+}
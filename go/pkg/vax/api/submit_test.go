@@ -0,0 +1,345 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+const testActor = "user123:device456"
+
+var testGenesisSalt = bytes.Repeat([]byte{0x42}, vax.GenesisSaltSize)
+
+// signedRequest 建立綁定在 state 下一個位置、以 kid 簽章的 SubmitRequest
+func signedRequest(t *testing.T, state vax.ChainState, priv ed25519.PrivateKey, kid string, data map[string]any) SubmitRequest {
+	t.Helper()
+	unsigned, _, err := vax.BuildChainedSAE(state, "transfer", data, sae.WithMetadata(testActor, "", ""))
+	if err != nil {
+		t.Fatalf("BuildChainedSAE failed: %v", err)
+	}
+	env, _ := sae.Parse(unsigned)
+	env.Kid = kid
+	if err := env.Sign(priv); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	saeBytes, _ := jcs.Marshal(env)
+	sai, _ := vax.ComputeSAI(state.HeadSAI, saeBytes)
+	return SubmitRequest{
+		Actor:   testActor,
+		Counter: state.Counter + 1,
+		PrevSAI: hex.EncodeToString(state.HeadSAI),
+		SAE:     saeBytes,
+		SAI:     hex.EncodeToString(sai),
+	}
+}
+
+type fixture struct {
+	srv     *httptest.Server
+	store   *vax.MemoryStore
+	genesis []byte
+	priv    ed25519.PrivateKey
+	mallory ed25519.PrivateKey // 另一個 actor 的 key（kid m1）
+}
+
+func newFixture(t *testing.T, opts ...Option) *fixture {
+	t.Helper()
+	genesis, _ := vax.ComputeGenesisSAI(testActor, testGenesisSalt)
+	store := vax.NewMemoryStore()
+	_ = store.Init(testActor, genesis)
+	pub, priv, _ := sae.GenerateKeyPair()
+	mpub, mpriv, _ := sae.GenerateKeyPair()
+	schemas := sdto.NewRegistry().Register("transfer", "",
+		sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema())
+
+	keys := sae.ActorResolver{testActor: {"k1": pub}, "mallory": {"m1": mpub}}
+	srv := httptest.NewServer(HandleSubmitAction(store, schemas, keys, opts...))
+	t.Cleanup(srv.Close)
+	return &fixture{srv: srv, store: store, genesis: genesis, priv: priv, mallory: mpriv}
+}
+
+func (f *fixture) post(t *testing.T, body any) (int, map[string]any) {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	resp, err := http.Post(f.srv.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestHandleSubmitAction(t *testing.T) {
+	t.Run("accepts and returns a receipt", func(t *testing.T) {
+		f := newFixture(t)
+		state := vax.ChainState{HeadSAI: f.genesis}
+		for i := 1; i <= 2; i++ {
+			req := signedRequest(t, state, f.priv, "k1", map[string]any{"amount": 10 * i})
+			status, out := f.post(t, req)
+			if status != http.StatusOK {
+				t.Fatalf("step %d: status %d: %v", i, status, out)
+			}
+			if out["counter"] != float64(i) || out["sai"] != req.SAI || out["action_type"] != "transfer" {
+				t.Fatalf("step %d: unexpected receipt %v", i, out)
+			}
+			sai, _ := hex.DecodeString(req.SAI)
+			state = state.Advance(sai)
+		}
+	})
+
+	cases := []struct {
+		name   string
+		mutate func(f *fixture, req *SubmitRequest)
+		status int
+		code   string
+	}{
+		{"replay", func(f *fixture, req *SubmitRequest) {
+			f.post(t, *req) // 第一次成功，第二次重送
+		}, http.StatusConflict, CodeChainConflict},
+		{"invalid sdto", func(f *fixture, req *SubmitRequest) {
+			*req = signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 5000})
+		}, http.StatusUnprocessableEntity, CodeInvalidSDTO},
+		{"unknown kid", func(f *fixture, req *SubmitRequest) {
+			*req = signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k2", map[string]any{"amount": 1})
+		}, http.StatusUnauthorized, CodeInvalidSignature},
+		{"signed with another actor's key", func(f *fixture, req *SubmitRequest) {
+			*req = signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.mallory, "m1", map[string]any{"amount": 1})
+		}, http.StatusUnauthorized, CodeInvalidSignature},
+		{"wrong SAI", func(f *fixture, req *SubmitRequest) {
+			req.SAI = hex.EncodeToString(make([]byte, vax.SAISize))
+		}, http.StatusUnprocessableEntity, CodeSAIMismatch},
+		{"counter mismatch", func(f *fixture, req *SubmitRequest) {
+			req.Counter = 7
+		}, http.StatusBadRequest, CodeInvalidInput},
+		{"actor does not match meta.actor", func(f *fixture, req *SubmitRequest) {
+			req.Actor = "someone-else"
+		}, http.StatusBadRequest, CodeInvalidInput},
+		{"malformed hex", func(f *fixture, req *SubmitRequest) {
+			req.PrevSAI = "zz"
+		}, http.StatusBadRequest, CodeInvalidInput},
+		{"oversized sdto string", func(f *fixture, req *SubmitRequest) {
+			req.SAE = []byte(`{"action_type":"transfer","sdto":{"memo":"` + strings.Repeat("x", 70<<10) + `"},"timestamp":1}`)
+		}, http.StatusRequestEntityTooLarge, CodeTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run("error: "+tc.name, func(t *testing.T) {
+			if tc.name == "oversized sdto string" {
+				old := sae.Limits
+				sae.Limits.MaxString = 64 << 10
+				t.Cleanup(func() { sae.Limits = old })
+			}
+			f := newFixture(t)
+			req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+			tc.mutate(f, &req)
+			status, out := f.post(t, req)
+			if status != tc.status || out["code"] != tc.code {
+				t.Errorf("got %d %v, want %d %s", status, out, tc.status, tc.code)
+			}
+			if tc.name == "invalid sdto" {
+				if fields, _ := out["fields"].([]any); len(fields) != 1 {
+					t.Errorf("expected one field error, got %v", out["fields"])
+				}
+			}
+			if tc.name != "replay" {
+				if head, _ := f.store.Head(testActor); head.Counter != 0 {
+					t.Errorf("store advanced on failure: %+v", head)
+				}
+			}
+		})
+	}
+
+	t.Run("error: WithTimestampPolicy rejects skewed clocks", func(t *testing.T) {
+		now := time.Now().Add(time.Hour)
+		f := newFixture(t, WithTimestampPolicy(sae.TimePolicy{MaxSkew: time.Minute, Now: func() time.Time { return now }}))
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1})
+		if status, out := f.post(t, req); status != http.StatusUnprocessableEntity || out["code"] != CodeClockSkew {
+			t.Errorf("got %d %v", status, out)
+		}
+	})
+
+	t.Run("encrypted submissions need WithDecryptionKey", func(t *testing.T) {
+		recipient, _ := sae.GenerateEncryptionKey()
+		encrypted := func(f *fixture) SubmitRequest {
+			state := vax.ChainState{HeadSAI: f.genesis}
+			unsigned, _, _ := vax.BuildChainedSAE(state, "transfer", map[string]any{"amount": 1}, sae.WithMetadata(testActor, "", ""))
+			plain, _ := sae.Parse(unsigned)
+			env, _ := sae.Encrypt(plain, recipient.PublicKey())
+			env.Kid = "k1"
+			_ = env.Sign(f.priv)
+			saeBytes, _ := jcs.Marshal(env)
+			sai, _ := vax.ComputeSAI(state.HeadSAI, saeBytes)
+			return SubmitRequest{Actor: testActor, Counter: 1, PrevSAI: hex.EncodeToString(state.HeadSAI), SAE: saeBytes, SAI: hex.EncodeToString(sai)}
+		}
+
+		f := newFixture(t)
+		if status, out := f.post(t, encrypted(f)); status != http.StatusUnprocessableEntity || out["code"] != CodeInvalidSDTO {
+			t.Errorf("without key: %d %v", status, out)
+		}
+		f = newFixture(t, WithDecryptionKey(recipient))
+		if status, out := f.post(t, encrypted(f)); status != http.StatusOK {
+			t.Errorf("with key: %d %v", status, out)
+		}
+	})
+
+	t.Run("error: unknown fields and wrong method", func(t *testing.T) {
+		f := newFixture(t)
+		if status, _ := f.post(t, map[string]any{"actor": testActor, "extra": 1}); status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+		resp, _ := http.Get(f.srv.URL)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", resp.StatusCode)
+		}
+	})
+	t.Run("error: cancelled request context does not advance", func(t *testing.T) {
+		f := newFixture(t)
+		pub := f.priv.Public()
+		schemas := sdto.NewRegistry().Register("transfer", "",
+			sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema())
+		h := HandleSubmitAction(f.store, schemas, sae.ActorResolver{testActor: {"k1": pub}})
+
+		b, _ := json.Marshal(signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": 1}))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code == http.StatusOK {
+			t.Errorf("accepted with a cancelled context: %s", w.Body)
+		}
+		if head, _ := f.store.Head(testActor); head.Counter != 0 {
+			t.Errorf("store advanced after cancellation: %+v", head)
+		}
+	})
+}
+
+func TestSubmitKeyOwner(t *testing.T) {
+	setup := func() (*vax.MemoryStore, *sdto.Registry, sae.StaticResolver, SubmitRequest) {
+		genesis, _ := vax.ComputeGenesisSAI(testActor, testGenesisSalt)
+		store := vax.NewMemoryStore()
+		_ = store.Init(testActor, genesis)
+		pub, priv, _ := sae.GenerateKeyPair()
+		schemas := sdto.NewRegistry().Register("transfer", "",
+			sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema())
+		return store, schemas, sae.StaticResolver{"k1": pub}, signedRequest(t, vax.ChainState{HeadSAI: genesis}, priv, "k1", map[string]any{"amount": 1})
+	}
+
+	t.Run("WithKeyOwner for resolvers without owners", func(t *testing.T) {
+		store, schemas, keys, req := setup()
+		owner := func(kid string) (string, error) { return testActor, nil }
+		if _, err := Submit(store, schemas, keys, req, WithKeyOwner(owner)); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	})
+
+	t.Run("error: WithKeyOwner names another actor", func(t *testing.T) {
+		store, schemas, keys, req := setup()
+		owner := func(kid string) (string, error) { return "mallory", nil }
+		if _, err := Submit(store, schemas, keys, req, WithKeyOwner(owner)); !errors.Is(err, sae.ErrKeyNotOwned) {
+			t.Errorf("expected ErrKeyNotOwned, got %v", err)
+		}
+		if head, _ := store.Head(testActor); head.Counter != 0 {
+			t.Errorf("store advanced: %+v", head)
+		}
+	})
+
+	t.Run("error: no owner source", func(t *testing.T) {
+		store, schemas, keys, req := setup()
+		if _, err := Submit(store, schemas, keys, req); !errors.Is(err, ErrNoKeyOwner) {
+			t.Errorf("expected ErrNoKeyOwner, got %v", err)
+		}
+		defer func() {
+			if recover() == nil {
+				t.Error("HandleSubmitAction accepted a resolver without owners")
+			}
+		}()
+		HandleSubmitAction(store, schemas, keys)
+	})
+}
+
+func TestHandleSubmitActionCBOR(t *testing.T) {
+	postCBOR := func(t *testing.T, f *fixture, body []byte, accept string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, f.srv.URL, bytes.NewReader(body))
+		req.Header.Set("Content-Type", cbor.MediaType)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp, out
+	}
+	cborRequest := func(t *testing.T, f *fixture, amount int) []byte {
+		t.Helper()
+		req := signedRequest(t, vax.ChainState{HeadSAI: f.genesis}, f.priv, "k1", map[string]any{"amount": amount})
+		// sae 以 byte string 傳送，SAI 仍是對原始 JCS bytes 計算
+		b, err := cbor.Encode(map[string]any{
+			"actor": req.Actor, "counter": req.Counter, "prev_sai": req.PrevSAI, "sae": req.SAE, "sai": req.SAI,
+		})
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		return b
+	}
+
+	t.Run("CBOR request and receipt", func(t *testing.T) {
+		f := newFixture(t, WithCanonicalBody())
+		resp, body := postCBOR(t, f, cborRequest(t, f, 10), cbor.MediaType)
+		var receipt Receipt
+		if err := cbor.Unmarshal(body, &receipt); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("got %d %x (%v)", resp.StatusCode, body, err)
+		}
+		if resp.Header.Get("Content-Type") != cbor.MediaType || receipt.Counter != 1 || receipt.ActionType != "transfer" {
+			t.Errorf("unexpected receipt %+v", receipt)
+		}
+	})
+
+	t.Run("error: CBOR error response with fields", func(t *testing.T) {
+		f := newFixture(t)
+		resp, body := postCBOR(t, f, cborRequest(t, f, 5000), "application/cbor, application/json;q=0.5")
+		var out ErrorResponse
+		if err := cbor.Unmarshal(body, &out); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if resp.StatusCode != http.StatusUnprocessableEntity || out.Code != CodeInvalidSDTO || len(out.Fields) != 1 {
+			t.Fatalf("got %d %+v", resp.StatusCode, out)
+		}
+	})
+
+	t.Run("CBOR request with JSON response by default", func(t *testing.T) {
+		f := newFixture(t)
+		resp, body := postCBOR(t, f, cborRequest(t, f, 10), "")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("got %d %s %s", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		}
+	})
+
+	t.Run("error: non-deterministic CBOR with WithCanonicalBody", func(t *testing.T) {
+		f := newFixture(t, WithCanonicalBody())
+		body := cborRequest(t, f, 10)
+		body = append([]byte{0xbf}, append(body[1:], 0xff)...) // 改為 indefinite-length map
+		resp, _ := postCBOR(t, f, body, "")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("got %d", resp.StatusCode)
+		}
+	})
+}
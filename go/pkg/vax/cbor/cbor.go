@@ -0,0 +1,134 @@
+// Package cbor implements deterministic CBOR (RFC 8949 §4.2.1: shortest
+// integer / float forms, definite lengths, map keys sorted by their encoded
+// bytes) for the JSON data model used across VAX. It is a transport
+// encoding only: SAE and SAI hashing stay on VAX-JCS bytes, which travel
+// inside CBOR as byte strings.
+//
+// Values map as in encoding/json, with one addition: CBOR byte strings
+// decode into []byte fields directly (and into base64 text elsewhere),
+// while []byte encodes as base64 text exactly as JSON would.
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// MediaType is the Content-Type of CBOR bodies.
+const MediaType = "application/cbor"
+
+// maxDepth 限制巢狀深度，避免惡意輸入耗盡 stack
+const maxDepth = 64
+
+// Error codes
+var (
+	ErrInvalid     = errors.New("cbor: invalid data")
+	ErrUnsupported = errors.New("cbor: unsupported item")
+)
+
+// Marshal encodes v deterministically. v goes through encoding/json first,
+// so struct tags, omitempty and json.Marshaler apply as usual.
+func Marshal(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return Encode(generic)
+}
+
+// Unmarshal decodes CBOR data into v with encoding/json semantics (unknown
+// fields are ignored; use Decode for full control). Byte strings become
+// base64 text, so they fill []byte fields.
+func Unmarshal(data []byte, v any) error {
+	raw, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// ToJSON converts a CBOR item to JSON bytes (byte strings as base64 text),
+// for callers that decode with their own json.Decoder settings.
+func ToJSON(data []byte) ([]byte, error) {
+	generic, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(toJSON(generic))
+}
+
+// IsDeterministic reports whether data is one CBOR item in deterministic
+// form, i.e. re-encoding its decoded value yields the same bytes.
+func IsDeterministic(data []byte) bool {
+	v, err := Decode(data)
+	if err != nil {
+		return false
+	}
+	again, err := Encode(v)
+	return err == nil && bytes.Equal(again, data)
+}
+
+// toJSON 把 byte string 轉為 base64 文字，其餘型別 encoding/json 可直接處理
+func toJSON(v any) any {
+	switch x := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	case []any:
+		for i := range x {
+			x[i] = toJSON(x[i])
+		}
+	case map[string]any:
+		for k := range x {
+			x[k] = toJSON(x[k])
+		}
+	}
+	return v
+}
+
+// Accepts reports whether an Accept header prefers CBOR to JSON: CBOR must
+// be listed with a q-value above zero and not below that of JSON.
+// Wildcards count for JSON, so "*/*" alone keeps the JSON default.
+func Accepts(accept string) bool {
+	cborQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case MediaType:
+			cborQ = max(cborQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return cborQ > 0 && cborQ >= jsonQ
+}
+
+// IsCBOR reports whether a Content-Type header names CBOR.
+func IsCBOR(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == MediaType
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...)
+}
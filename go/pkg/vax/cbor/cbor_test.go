@@ -0,0 +1,102 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+type sample struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`
+	SAE     []byte `json:"sae"`
+	Note    string `json:"note,omitempty"`
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	t.Run("round trip through struct tags", func(t *testing.T) {
+		in := sample{Actor: "a", Counter: 18446744073709551615, SAE: []byte(`{"x":1}`)}
+		b, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var out sample
+		if err := Unmarshal(b, &out); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if out.Actor != in.Actor || out.Counter != in.Counter || !bytes.Equal(out.SAE, in.SAE) {
+			t.Errorf("round trip = %+v", out)
+		}
+	})
+
+	t.Run("byte strings fill []byte fields", func(t *testing.T) {
+		b, _ := Encode(map[string]any{"actor": "a", "sae": []byte{0xde, 0xad}})
+		var out sample
+		if err := Unmarshal(b, &out); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if !bytes.Equal(out.SAE, []byte{0xde, 0xad}) {
+			t.Errorf("sae = %x", out.SAE)
+		}
+	})
+
+	t.Run("deterministic output", func(t *testing.T) {
+		a, _ := Marshal(map[string]any{"z": 1, "a": []any{1.5, "x"}})
+		b, _ := Marshal(map[string]any{"a": []any{1.5, "x"}, "z": 1})
+		if !bytes.Equal(a, b) {
+			t.Errorf("%x != %x", a, b)
+		}
+	})
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"application/cbor", true},
+		{"application/cbor, application/json;q=0.5", true},
+		{"application/json, application/cbor", true},
+		{"application/json, application/cbor;q=0.9", false},
+		{"application/cbor;q=0", false},
+		{"*/*", false},
+		{"", false},
+		{"text/html, */*;q=0.1, application/cbor;q=0.2", true},
+	}
+	for _, tt := range tests {
+		if got := Accepts(tt.accept); got != tt.want {
+			t.Errorf("Accepts(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+	if !IsCBOR("application/cbor; charset=binary") || IsCBOR("application/json") {
+		t.Error("IsCBOR misclassifies")
+	}
+}
+
+func TestIsDeterministic(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"a2616101616202", true},
+		{"a2616201616102", false},     // key 順序
+		{"1800", false},               // 非最短整數
+		{"fa3fc00000", false},         // 1.5 應為 half
+		{"5f42010243030405ff", false}, // indefinite length
+		{"", false},
+	}
+	for _, tt := range tests {
+		in, _ := hex.DecodeString(tt.in)
+		if got := IsDeterministic(in); got != tt.want {
+			t.Errorf("IsDeterministic(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	in, _ := hex.DecodeString("a2616142dead616281f93e00") // {"a": h'dead', "b": [1.5]}
+	got, err := ToJSON(in)
+	if err != nil || string(got) != `{"a":"3q0=","b":[1.5]}` {
+		t.Errorf("ToJSON = %s, %v", got, err)
+	}
+}
@@ -0,0 +1,189 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Decode parses one CBOR item into generic values: nil, bool, string,
+// []byte, json.Number (integers, exact at any 64-bit magnitude), float64,
+// []any and map[string]any. Only the JSON data model is accepted: map keys
+// must be text and unique, tags and indefinite lengths are rejected, and
+// trailing bytes are an error. Input need not be deterministic.
+func Decode(data []byte) (any, error) {
+	d := decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, invalid("%d trailing bytes", len(d.data)-d.off)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, invalid("nesting deeper than %d", maxDepth)
+	}
+	if d.off >= len(d.data) {
+		return nil, invalid("unexpected end of data")
+	}
+	ib := d.data[d.off]
+	d.off++
+	major, info := ib>>5, ib&0x1f
+
+	if major == majorSimple {
+		return d.simple(info)
+	}
+	n, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case majorNegint:
+		neg := new(big.Int).Neg(new(big.Int).SetUint64(n))
+		return json.Number(neg.Sub(neg, big.NewInt(1)).String()), nil
+	case majorBytes:
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case majorText:
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, invalid("text string is not UTF-8")
+		}
+		return string(b), nil
+	case majorArray:
+		if n > uint64(len(d.data)-d.off) { // 每個元素至少 1 byte
+			return nil, invalid("array length %d exceeds data", n)
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case majorMap:
+		if n > uint64(len(d.data)-d.off)/2 {
+			return nil, invalid("map length %d exceeds data", n)
+		}
+		m := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, invalid("map key is %T, want text", k)
+			}
+			if _, dup := m[key]; dup {
+				return nil, invalid("duplicate map key %q", key)
+			}
+			if m[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default: // majorTag
+		return nil, invalid("tag %d: %v", n, ErrUnsupported)
+	}
+}
+
+// argument 讀取 head 的引數；indefinite length（31）不支援
+func (d *decoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		b, err := d.take(1 << (info - 24))
+		if err != nil {
+			return 0, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, nil
+	case info == 31:
+		return 0, invalid("indefinite length: %v", ErrUnsupported)
+	default:
+		return 0, invalid("reserved additional info %d", info)
+	}
+}
+
+func (d *decoder) simple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		b, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		return fromHalf(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	default:
+		return nil, invalid("simple value %d: %v", info, ErrUnsupported)
+	}
+}
+
+func (d *decoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, invalid("unexpected end of data")
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func fromHalf(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
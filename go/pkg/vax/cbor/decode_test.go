@@ -0,0 +1,79 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{"uint", "1903e8", json.Number("1000")},
+		{"max uint64", "1bffffffffffffffff", json.Number("18446744073709551615")},
+		{"min negint", "3bffffffffffffffff", json.Number("-18446744073709551616")},
+		{"non-shortest int", "1800", json.Number("0")},
+		{"half", "f93e00", 1.5},
+		{"half subnormal", "f90001", 5.960464477539063e-8},
+		{"half -inf", "f9fc00", math.Inf(-1)},
+		{"single", "fa47c35000", 100000.0},
+		{"double", "fb3ff199999999999a", 1.1},
+		{"undefined", "f7", nil},
+		{"bytes", "4401020304", []byte{1, 2, 3, 4}},
+		{"text", "63e6b0b4", "水"},
+		{"map", "a26161016162820203", map[string]any{"a": json.Number("1"), "b": []any{json.Number("2"), json.Number("3")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, _ := hex.DecodeString(tt.in)
+			got, err := Decode(in)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	errTests := []struct {
+		name string
+		in   string
+	}{
+		{"error: empty", ""},
+		{"error: truncated", "1903"},
+		{"error: trailing bytes", "0000"},
+		{"error: indefinite length", "9f01ff"},
+		{"error: tag", "c11a514b67b0"},
+		{"error: non-text map key", "a10102"},
+		{"error: duplicate map key", "a2616101616102"},
+		{"error: invalid UTF-8", "61ff"},
+		{"error: huge array length", "9bffffffffffffffff"},
+		{"error: reserved info", "1c"},
+		{"error: simple value", "f0"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, _ := hex.DecodeString(tt.in)
+			if _, err := Decode(in); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode(%s) err = %v, want ErrInvalid", tt.in, err)
+			}
+		})
+	}
+
+	t.Run("error: nesting too deep", func(t *testing.T) {
+		in := make([]byte, maxDepth+2)
+		for i := range in {
+			in[i] = 0x81 // [ [ [ ...
+		}
+		if _, err := Decode(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("got %v", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// CBOR major types
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// Encode writes a generic value (nil, bool, string, []byte, json.Number,
+// float64, Go integers, []any, map[string]any) as deterministic CBOR.
+// json.Number literals that are integers encode as CBOR integers (up to
+// 64 bits), the rest as the shortest exact float.
+func Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v any, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: nesting deeper than %d", ErrUnsupported, maxDepth)
+	}
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if x {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case string:
+		writeHead(buf, majorText, uint64(len(x)))
+		buf.WriteString(x)
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(x)))
+		buf.Write(x)
+	case json.Number:
+		return encodeNumber(buf, x)
+	case float64:
+		encodeFloat(buf, x)
+	case float32:
+		encodeFloat(buf, float64(x))
+	case int:
+		encodeInt(buf, int64(x))
+	case int64:
+		encodeInt(buf, x)
+	case int32:
+		encodeInt(buf, int64(x))
+	case uint64:
+		writeHead(buf, majorUint, x)
+	case uint:
+		writeHead(buf, majorUint, uint64(x))
+	case uint32:
+		writeHead(buf, majorUint, uint64(x))
+	case []any:
+		writeHead(buf, majorArray, uint64(len(x)))
+		for _, item := range x {
+			if err := encode(buf, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		return encodeMap(buf, x, depth)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupported, v)
+	}
+	return nil
+}
+
+// encodeMap 依 RFC 8949 §4.2.1 以編碼後的 key bytes 排序
+// （text key 的 head 含長度，所以等同先比長度、再逐位元組比較）
+func encodeMap(buf *bytes.Buffer, m map[string]any, depth int) error {
+	type pair struct {
+		key []byte
+		val any
+	}
+	pairs := make([]pair, 0, len(m))
+	for k, v := range m {
+		var kb bytes.Buffer
+		writeHead(&kb, majorText, uint64(len(k)))
+		kb.WriteString(k)
+		pairs = append(pairs, pair{kb.Bytes(), v})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i].key, pairs[j].key) < 0 })
+
+	writeHead(buf, majorMap, uint64(len(pairs)))
+	for _, p := range pairs {
+		buf.Write(p.key)
+		if err := encode(buf, p.val, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		switch {
+		case i.IsUint64():
+			writeHead(buf, majorUint, i.Uint64())
+			return nil
+		case i.Sign() < 0:
+			// -1 - n 形式，n 需在 uint64 範圍內
+			neg := new(big.Int).Sub(new(big.Int).Neg(i), big.NewInt(1))
+			if neg.IsUint64() {
+				writeHead(buf, majorNegint, neg.Uint64())
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: integer %s exceeds 64 bits", ErrUnsupported, n)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("%w: number %s: %v", ErrUnsupported, n, err)
+	}
+	encodeFloat(buf, f)
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	if i >= 0 {
+		writeHead(buf, majorUint, uint64(i))
+	} else {
+		writeHead(buf, majorNegint, uint64(-(i + 1)))
+	}
+}
+
+// encodeFloat 取能精確表示該值的最短形式（half → single → double）
+func encodeFloat(buf *bytes.Buffer, f float64) {
+	if h, ok := toHalf(f); ok {
+		buf.WriteByte(majorSimple<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, h))
+		return
+	}
+	if f32 := float32(f); float64(f32) == f {
+		buf.WriteByte(majorSimple<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(f32)))
+		return
+	}
+	buf.WriteByte(majorSimple<<5 | 27)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+// toHalf 回傳 f 的 IEEE 754 half 表示（僅在可精確表示時）；NaN 一律為 0x7e00
+func toHalf(f float64) (uint16, bool) {
+	switch {
+	case math.IsNaN(f):
+		return 0x7e00, true
+	case math.IsInf(f, 1):
+		return 0x7c00, true
+	case math.IsInf(f, -1):
+		return 0xfc00, true
+	}
+	bits := math.Float64bits(f)
+	sign := uint16(bits>>48) & 0x8000
+	if f == 0 {
+		return sign, true
+	}
+	frac, exp := math.Frexp(math.Abs(f)) // |f| = frac × 2^exp，frac ∈ [0.5, 1)
+	e := exp - 1                         // 正規化指數：|f| = 1.m × 2^e
+	switch {
+	case e >= -14 && e <= 15:
+		// normal：10 位尾數
+		m := (frac*2 - 1) * 1024
+		if m != math.Trunc(m) {
+			return 0, false
+		}
+		return sign | uint16(e+15)<<10 | uint16(m), true
+	case e >= -24 && e < -14:
+		// subnormal：|f| = m × 2^-24
+		m := math.Abs(f) * (1 << 24)
+		if m != math.Trunc(m) {
+			return 0, false
+		}
+		return sign | uint16(m), true
+	}
+	return 0, false
+}
+
+// writeHead 以最短長度寫出 major type 與引數
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(m | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(m | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
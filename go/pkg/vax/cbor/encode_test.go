@@ -0,0 +1,84 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// RFC 8949 Appendix A 的範例（僅 JSON 資料模型可表達者）
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"0", json.Number("0"), "00"},
+		{"23", json.Number("23"), "17"},
+		{"24", json.Number("24"), "1818"},
+		{"1000", json.Number("1000"), "1903e8"},
+		{"1000000", json.Number("1000000"), "1a000f4240"},
+		{"max uint64", json.Number("18446744073709551615"), "1bffffffffffffffff"},
+		{"-1", json.Number("-1"), "20"},
+		{"-1000", json.Number("-1000"), "3903e7"},
+		{"min negint", json.Number("-18446744073709551616"), "3bffffffffffffffff"},
+		{"int", -100, "3863"},
+		{"uint64", uint64(500), "1901f4"},
+		{"0.0 half", 0.0, "f90000"},
+		{"-0.0 half", math.Copysign(0, -1), "f98000"},
+		{"1.5 half", json.Number("1.5"), "f93e00"},
+		{"65504 half", 65504.0, "f97bff"},
+		{"100000 single", 100000.0, "fa47c35000"},
+		{"5.960464477539063e-8 subnormal", 5.960464477539063e-8, "f90001"},
+		{"0.00006103515625 half", 0.00006103515625, "f90400"},
+		{"1.1 double", json.Number("1.1"), "fb3ff199999999999a"},
+		{"-4.1 double", -4.1, "fbc010666666666666"},
+		{"3.4028234663852886e+38 single", 3.4028234663852886e+38, "fa7f7fffff"},
+		{"infinity", math.Inf(1), "f97c00"},
+		{"NaN", math.NaN(), "f97e00"},
+		{"false", false, "f4"},
+		{"true", true, "f5"},
+		{"null", nil, "f6"},
+		{"empty bytes", []byte{}, "40"},
+		{"bytes", []byte{1, 2, 3, 4}, "4401020304"},
+		{"empty text", "", "60"},
+		{"text", "IETF", "6449455446"},
+		{"escaped text", "\"\\", "62225c"},
+		{"unicode text", "水", "63e6b0b4"},
+		{"empty array", []any{}, "80"},
+		{"nested array", []any{json.Number("1"), []any{json.Number("2"), json.Number("3")}}, "8201820203"},
+		{"empty map", map[string]any{}, "a0"},
+		{"map", map[string]any{"a": json.Number("1"), "b": []any{json.Number("2")}}, "a261610161628102"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.in)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Encode = %x, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("map keys sort by encoded bytes (length first)", func(t *testing.T) {
+		got, _ := Encode(map[string]any{"aa": nil, "b": nil, "a": nil})
+		if want := "a3" + "6161f6" + "6162f6" + "626161f6"; hex.EncodeToString(got) != want {
+			t.Errorf("Encode = %x, want %s", got, want)
+		}
+	})
+
+	t.Run("error: integer beyond 64 bits", func(t *testing.T) {
+		if _, err := Encode(json.Number("18446744073709551616")); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("error: unsupported type", func(t *testing.T) {
+		if _, err := Encode(struct{}{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package vax
+
+import (
+	"encoding/hex"
+	"math"
+
+	"vax/pkg/vax/sae"
+)
+
+// ChainState is an actor's current position in its chain.
+type ChainState struct {
+	Counter uint64 // counter of the last accepted action (0 = genesis)
+	HeadSAI []byte // SAI of the last accepted action (genesis SAI at counter 0)
+	// Timestamp is the envelope timestamp of the last accepted action (unix
+	// ms, 0 at genesis or when the store does not keep it); see
+	// WithTimestampPolicy.
+	Timestamp int64
+}
+
+// Advance returns the state after accepting an action with the given SAI.
+// Timestamp is cleared; the verifier sets it from the accepted envelope.
+func (s ChainState) Advance(sai []byte) ChainState {
+	return ChainState{Counter: s.Counter + 1, HeadSAI: sai}
+}
+
+// BuildChainedSAE builds an SAE bound to the next chain position
+// (counter = state.Counter+1, prev_sai = state.HeadSAI) and computes its SAI.
+func BuildChainedSAE(
+	state ChainState,
+	actionType string,
+	sdto map[string]any,
+	opts ...sae.Option,
+) (saeBytes []byte, sai []byte, err error) {
+	if len(state.HeadSAI) != SAISize {
+		return nil, nil, ErrInvalidInput
+	}
+	if state.Counter == math.MaxUint64 {
+		return nil, nil, ErrCounterOverflow
+	}
+
+	opts = append(opts, sae.WithChain(state.Counter+1, state.HeadSAI))
+	saeBytes, err = sae.BuildSAE(actionType, sdto, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sai, err = ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return saeBytes, sai, nil
+}
+
+// VerifyChainBinding checks the envelope's in-band chain position against
+// the verifier's state. Envelopes without binding fields are rejected.
+func VerifyChainBinding(env *sae.Envelope, state ChainState) error {
+	if env.PrevSAI == "" || env.Counter == 0 {
+		return ErrInvalidInput
+	}
+	if state.Counter == math.MaxUint64 {
+		return ErrCounterOverflow
+	}
+	if env.Counter != state.Counter+1 {
+		return ErrInvalidCounter
+	}
+	if env.PrevSAI != hex.EncodeToString(state.HeadSAI) {
+		return ErrInvalidPrevSAI
+	}
+	return nil
+}
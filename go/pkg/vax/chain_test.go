@@ -0,0 +1,79 @@
+package vax
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+func TestBuildChainedSAE(t *testing.T) {
+	genesis, _ := ComputeGenesisSAI("user123:device456", testGenesisSalt)
+	state := ChainState{HeadSAI: genesis}
+	pinned := sae.WithTimestamp(time.UnixMilli(1704672000000))
+
+	t.Run("binds counter and prev_sai", func(t *testing.T) {
+		saeBytes, sai, err := BuildChainedSAE(state, "transfer", map[string]any{"amount": 1}, pinned)
+		if err != nil {
+			t.Fatalf("BuildChainedSAE failed: %v", err)
+		}
+
+		env, _ := sae.Parse(saeBytes)
+		if err := VerifyChainBinding(env, state); err != nil {
+			t.Errorf("VerifyChainBinding failed: %v", err)
+		}
+
+		want, _ := ComputeSAI(genesis, saeBytes)
+		if !bytes.Equal(sai, want) {
+			t.Errorf("SAI = %x, want %x", sai, want)
+		}
+	})
+
+	t.Run("chain of three", func(t *testing.T) {
+		s := state
+		for i := 0; i < 3; i++ {
+			saeBytes, sai, err := BuildChainedSAE(s, "transfer", map[string]any{"i": i}, pinned)
+			if err != nil {
+				t.Fatalf("step %d: %v", i, err)
+			}
+			env, _ := sae.Parse(saeBytes)
+			if err := VerifyChainBinding(env, s); err != nil {
+				t.Fatalf("step %d: VerifyChainBinding failed: %v", i, err)
+			}
+			s = s.Advance(sai)
+		}
+		if s.Counter != 3 {
+			t.Errorf("counter = %d, want 3", s.Counter)
+		}
+	})
+
+	t.Run("error: replayed at another position", func(t *testing.T) {
+		saeBytes, sai, _ := BuildChainedSAE(state, "transfer", map[string]any{}, pinned)
+		env, _ := sae.Parse(saeBytes)
+		if err := VerifyChainBinding(env, state.Advance(sai)); err != ErrInvalidCounter {
+			t.Errorf("expected ErrInvalidCounter, got %v", err)
+		}
+	})
+
+	t.Run("error: counter overflow", func(t *testing.T) {
+		_, _, err := BuildChainedSAE(ChainState{Counter: math.MaxUint64, HeadSAI: genesis}, "transfer", nil)
+		if err != ErrCounterOverflow {
+			t.Errorf("expected ErrCounterOverflow, got %v", err)
+		}
+	})
+
+	t.Run("VerifyAction rejects mismatched in-band prev_sai", func(t *testing.T) {
+		schema := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "10").MustBuildSchema()
+		saeBytes, _, _ := BuildChainedSAE(state, "transfer", map[string]any{"amount": 1}, pinned)
+
+		other := make([]byte, SAISize)
+		clientSAI, _ := ComputeSAI(other, saeBytes)
+		_, err := VerifyAction(other, other, saeBytes, clientSAI, schema)
+		if err != ErrInvalidPrevSAI {
+			t.Errorf("expected ErrInvalidPrevSAI, got %v", err)
+		}
+	})
+}
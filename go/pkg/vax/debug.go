@@ -0,0 +1,303 @@
+package vax
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// DebugBundle is a diagnostic report on one submission, built by Debug for
+// triaging ErrSAIMismatch and signature failures. It can be attached to a
+// ticket as is: SDTO values are redacted, and all digests are hex.
+type DebugBundle struct {
+	ActionType      string `json:"action_type,omitempty"`
+	Counter         uint64 `json:"counter"`          // in-band counter of the envelope
+	ExpectedCounter uint64 `json:"expected_counter"` // state.Counter + 1
+	// Canonical is the verifier's JCS re-encoding of the envelope with every
+	// SDTO value replaced by its type ("<string:5>", "<number>", ...), so
+	// its offsets do not line up with Divergence.Offset.
+	Canonical       string `json:"canonical"`
+	SAELength       int    `json:"sae_length"`
+	SAESHA256       string `json:"sae_sha256"`       // SHA256 of the submitted bytes
+	CanonicalSHA256 string `json:"canonical_sha256"` // SHA256 of the unredacted re-encoding
+	SigningSHA256   string `json:"signing_sha256,omitempty"`
+	// Divergence is the first byte where the submitted bytes differ from
+	// their canonical form (nil when they are canonical).
+	Divergence *Divergence `json:"divergence,omitempty"`
+
+	PrevSAI         string `json:"prev_sai"`          // submitted
+	ExpectedPrevSAI string `json:"expected_prev_sai"` // state.HeadSAI
+	EnvelopePrevSAI string `json:"envelope_prev_sai,omitempty"`
+	SAI             string `json:"sai"`            // submitted
+	RecomputedSAI   string `json:"recomputed_sai"` // over the submitted bytes from the expected head
+	CanonicalSAI    string `json:"canonical_sai"`  // over the canonical bytes from the expected head
+	Signature       string `json:"signature"`      // "valid", "unsigned", "not checked" or the error
+
+	// Findings lists every check that fails, in verification order; it is
+	// empty when the submission would be accepted.
+	Findings []string `json:"findings"`
+}
+
+// Divergence locates the first differing byte of two encodings.
+type Divergence struct {
+	Offset int    `json:"offset"`
+	Path   string `json:"path,omitempty"` // JSON path of the token at Offset, e.g. "sdto.amount"
+}
+
+// Debug re-runs the checks of VerifyAndAdvance on sub against state and
+// reports every intermediate value instead of stopping at the first error:
+// the canonical encoding and where the submitted bytes leave it, the SAE
+// digest, the SAI recomputed from the expected head and over the canonical
+// bytes, the chain binding and, with keys, the signature. Only sub.SAE,
+// sub.SAI and sub.PrevSAI are used. This module has no per-chain key or gi
+// input to the SAI, so there is nothing else to recompute.
+//
+// An unparseable envelope still yields a bundle with its digest and the
+// parse error as a finding; err is only for unusable input.
+func Debug(sub *Submission, state ChainState, keys sae.KeyResolver) (*DebugBundle, error) {
+	if sub == nil || len(sub.SAE) == 0 || len(state.HeadSAI) != SAISize {
+		return nil, ErrInvalidInput
+	}
+	b := &DebugBundle{
+		ExpectedCounter: state.Counter + 1,
+		PrevSAI:         hex.EncodeToString(sub.PrevSAI),
+		ExpectedPrevSAI: hex.EncodeToString(state.HeadSAI),
+		SAI:             hex.EncodeToString(sub.SAI),
+		Signature:       "not checked",
+	}
+	finding := func(err error, format string, args ...any) {
+		b.Findings = append(b.Findings, fmt.Sprintf("%v: %s", err, fmt.Sprintf(format, args...)))
+	}
+
+	// SAI 以解壓後的 bytes 計算（同 VerifyAction）
+	submitted, err := sae.Decompress(sub.SAE)
+	if err != nil {
+		finding(ErrInvalidInput, "sae does not decompress: %v", err)
+		submitted = sub.SAE
+	}
+	digest := sha256.Sum256(submitted)
+	b.SAELength, b.SAESHA256 = len(submitted), hex.EncodeToString(digest[:])
+	recomputed, _ := ComputeSAI(state.HeadSAI, submitted)
+	b.RecomputedSAI = hex.EncodeToString(recomputed)
+
+	if !bytes.Equal(sub.PrevSAI, state.HeadSAI) {
+		finding(ErrInvalidPrevSAI, "prev_sai is not the chain head")
+	}
+
+	env, err := sae.Parse(submitted)
+	if err != nil {
+		finding(ErrInvalidInput, "sae does not parse: %v", err)
+		return b, nil
+	}
+	b.ActionType, b.Counter, b.EnvelopePrevSAI = env.ActionType, env.Counter, env.PrevSAI
+	if err := VerifyChainBinding(env, state); err != nil {
+		finding(err, "envelope is bound to counter %d, prev_sai %q", env.Counter, env.PrevSAI)
+	}
+
+	canonical, err := jcs.Marshal(env)
+	if err != nil {
+		finding(ErrInvalidInput, "sae does not canonicalize: %v", err)
+		return b, nil
+	}
+	canonicalDigest := sha256.Sum256(canonical)
+	canonicalSAI, _ := ComputeSAI(state.HeadSAI, canonical)
+	b.CanonicalSHA256, b.CanonicalSAI = hex.EncodeToString(canonicalDigest[:]), hex.EncodeToString(canonicalSAI)
+	if redacted, err := jcs.Marshal(redactEnvelope(env)); err == nil {
+		b.Canonical = string(redacted)
+	}
+	if i := divergeAt(submitted, canonical); i >= 0 {
+		b.Divergence = &Divergence{Offset: i, Path: jsonPathAt(submitted, i)}
+		finding(ErrInvalidInput, "sae is not canonical from byte %d (%s)", i, b.Divergence.Path)
+	}
+
+	switch {
+	case len(sub.SAI) != SAISize:
+		finding(ErrInvalidInput, "sai is %d bytes, want %d", len(sub.SAI), SAISize)
+	case bytes.Equal(sub.SAI, recomputed):
+	case bytes.Equal(sub.SAI, canonicalSAI):
+		finding(ErrSAIMismatch, "sai covers the canonical form, not the submitted bytes")
+	case len(sub.PrevSAI) == SAISize && bytes.Equal(sub.SAI, saiFrom(sub.PrevSAI, submitted)):
+		finding(ErrSAIMismatch, "sai was computed from the submitted prev_sai, not the chain head")
+	default:
+		finding(ErrSAIMismatch, "sai matches neither the submitted nor the canonical bytes")
+	}
+
+	if signing, err := env.SigningBytes(); err == nil {
+		sum := sha256.Sum256(signing)
+		b.SigningSHA256 = hex.EncodeToString(sum[:])
+	}
+	if len(env.Signature) == 0 {
+		b.Signature = "unsigned"
+	}
+	if keys != nil {
+		if err := env.VerifyWithResolver(keys); err != nil {
+			if len(env.Signature) > 0 {
+				b.Signature = err.Error()
+			}
+			finding(err, "kid %q, alg %q", env.Kid, env.Alg)
+		} else {
+			b.Signature = "valid"
+		}
+	}
+	return b, nil
+}
+
+// Err returns the findings joined as one error, nil when there are none.
+func (b *DebugBundle) Err() error {
+	if len(b.Findings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(b.Findings, "; "))
+}
+
+// String renders a plain-text report.
+func (b *DebugBundle) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "action_type:       %s (counter %d, expected %d)\n", b.ActionType, b.Counter, b.ExpectedCounter)
+	fmt.Fprintf(&s, "sae (%d bytes):     sha256 %s\n", b.SAELength, b.SAESHA256)
+	fmt.Fprintf(&s, "canonical:         sha256 %s\n", b.CanonicalSHA256)
+	if d := b.Divergence; d != nil {
+		fmt.Fprintf(&s, "diverges at byte:  %d (%s)\n", d.Offset, d.Path)
+	}
+	fmt.Fprintf(&s, "prev_sai:          %s\n", b.PrevSAI)
+	fmt.Fprintf(&s, "expected prev_sai: %s\n", b.ExpectedPrevSAI)
+	fmt.Fprintf(&s, "sai:               %s\n", b.SAI)
+	fmt.Fprintf(&s, "recomputed sai:    %s\n", b.RecomputedSAI)
+	fmt.Fprintf(&s, "canonical sai:     %s\n", b.CanonicalSAI)
+	fmt.Fprintf(&s, "signature:         %s\n", b.Signature)
+	for _, f := range b.Findings {
+		fmt.Fprintf(&s, "- %s\n", f)
+	}
+	fmt.Fprintf(&s, "%s\n", b.Canonical)
+	return s.String()
+}
+
+func saiFrom(prevSAI, saeBytes []byte) []byte {
+	sai, _ := ComputeSAI(prevSAI, saeBytes)
+	return sai
+}
+
+// divergeAt 回傳 a、b 第一個不同的 byte 位置（相同時為 -1）
+func divergeAt(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) == len(b) {
+		return -1
+	}
+	return n
+}
+
+// redactEnvelope 複製 env，SDTO 的值只保留型別
+func redactEnvelope(env *sae.Envelope) *sae.Envelope {
+	red := *env
+	red.SDTO, _ = redactValue(env.SDTO).(map[string]any)
+	return &red
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			out[k] = redactValue(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			out[i] = redactValue(x)
+		}
+		return out
+	case string:
+		return "<string:" + strconv.Itoa(utf8.RuneCountInString(v)) + ">"
+	case json.Number, float64:
+		return "<number>"
+	case bool:
+		return "<bool>"
+	case nil:
+		return nil
+	}
+	return fmt.Sprintf("<%T>", v)
+}
+
+// jsonPathAt 回傳 data 中涵蓋 offset 的 token 所在的 JSON 路徑（例如 sdto.items[2]）
+func jsonPathAt(data []byte, offset int) string {
+	type frame struct {
+		object  bool
+		key     string
+		index   int
+		wantKey bool
+	}
+	var stack []frame
+	path := func() string {
+		var p strings.Builder
+		for _, f := range stack {
+			if !f.object {
+				fmt.Fprintf(&p, "[%d]", f.index)
+				continue
+			}
+			if f.key == "" {
+				continue
+			}
+			if p.Len() > 0 {
+				p.WriteByte('.')
+			}
+			p.WriteString(f.key)
+		}
+		return p.String()
+	}
+	// 一個值結束：物件改等下一個 key，陣列前進到下一個元素
+	valueDone := func() {
+		if n := len(stack); n > 0 {
+			if stack[n-1].object {
+				stack[n-1].wantKey = true
+			} else {
+				stack[n-1].index++
+			}
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return path()
+		}
+		n := len(stack)
+		if s, ok := tok.(string); ok && n > 0 && stack[n-1].object && stack[n-1].wantKey {
+			stack[n-1].key, stack[n-1].wantKey = s, false
+			if dec.InputOffset() > int64(offset) {
+				return path()
+			}
+			continue
+		}
+		if dec.InputOffset() > int64(offset) {
+			return path()
+		}
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, frame{object: true, wantKey: true})
+		case json.Delim('['):
+			stack = append(stack, frame{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:n-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package vax
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+func TestDebug(t *testing.T) {
+	const actor = "user123:device456"
+	genesis, _ := ComputeGenesisSAI(actor, testGenesisSalt)
+	pub, priv, _ := sae.GenerateKeyPair()
+	keys := sae.StaticResolver{"k1": pub}
+	schema := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+	state := ChainState{HeadSAI: genesis}
+
+	sub, err := SignedAction("transfer", schema, map[string]any{"amount": 777}, priv, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Envelope.Kid = "k1"
+	sub = resign(t, sub, priv, state)
+
+	t.Run("accepted submission has no findings", func(t *testing.T) {
+		b, err := Debug(sub, state, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Err() != nil || b.Divergence != nil || b.Signature != "valid" || b.RecomputedSAI != b.SAI || b.Counter != 1 {
+			t.Errorf("bundle:\n%s", b)
+		}
+	})
+
+	t.Run("error: non-canonical bytes are located and values redacted", func(t *testing.T) {
+		bad := *sub
+		bad.SAE = bytes.Replace(sub.SAE, []byte(`"amount":777`), []byte(`"amount": 777`), 1)
+		b, err := Debug(&bad, state, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := bytes.Index(bad.SAE, []byte(" 777"))
+		if b.Divergence == nil || b.Divergence.Offset != want || b.Divergence.Path != "sdto.amount" {
+			t.Errorf("divergence = %+v, want offset %d at sdto.amount", b.Divergence, want)
+		}
+		if !strings.Contains(b.Err().Error(), "sai covers the canonical form") {
+			t.Errorf("findings: %v", b.Findings)
+		}
+		out, _ := json.Marshal(b)
+		// 只比對值的位置：digest 與簽章的 hex / base64 可能碰巧含有 777
+		if strings.Contains(string(out), ":777") || strings.Contains(string(out), " 777") || !strings.Contains(b.Canonical, `"amount":"<number>"`) {
+			t.Errorf("value not redacted: %s", out)
+		}
+	})
+
+	t.Run("error: stale head and wrong key", func(t *testing.T) {
+		moved := state.Advance(bytes.Repeat([]byte{7}, SAISize))
+		other, _, _ := sae.GenerateKeyPair()
+		b, err := Debug(sub, moved, sae.StaticResolver{"k1": other})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(b.Findings, "\n")
+		for _, want := range []string{"prev_sai is not the chain head", "bound to counter 1", "computed from the submitted prev_sai", `kid "k1"`} {
+			if !strings.Contains(got, want) {
+				t.Errorf("missing %q in\n%s", want, got)
+			}
+		}
+		if b.Signature == "valid" || b.ExpectedCounter != 2 {
+			t.Errorf("bundle:\n%s", b)
+		}
+	})
+
+	t.Run("error: unparseable envelope", func(t *testing.T) {
+		b, err := Debug(&Submission{SAE: []byte(`{"action_type":`), SAI: sub.SAI, PrevSAI: genesis}, state, nil)
+		if err != nil || len(b.Findings) != 1 || !strings.Contains(b.Findings[0], "does not parse") || b.SAESHA256 == "" {
+			t.Errorf("bundle %+v, %v", b, err)
+		}
+		if _, err := Debug(nil, state, nil); err != ErrInvalidInput {
+			t.Errorf("nil submission: %v", err)
+		}
+	})
+}
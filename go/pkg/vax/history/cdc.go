@@ -0,0 +1,188 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"vax/pkg/vax/jcs"
+)
+
+// Observer is notified of every record a store has durably appended, in
+// append order per actor. OnAppend runs on the appending goroutine and
+// must not block for long; stream through an outbox (SQLStore WithOutbox)
+// when delivery must survive crashes.
+type Observer interface {
+	OnAppend(rec Record)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(rec Record)
+
+func (f ObserverFunc) OnAppend(rec Record) { f(rec) }
+
+// ObservedStore is a Store that notifies observers after each append.
+type ObservedStore struct {
+	Store
+	observers []Observer
+}
+
+var (
+	_ ContextStore    = (*ObservedStore)(nil)
+	_ Lister          = (*ObservedStore)(nil)
+	_ Pruner          = (*ObservedStore)(nil)
+	_ CheckpointStore = (*ObservedStore)(nil)
+)
+
+// Observe wraps s so every successful Append notifies obs.
+func Observe(s Store, obs ...Observer) *ObservedStore {
+	if s == nil {
+		panic("history: Observe needs a store")
+	}
+	return &ObservedStore{Store: s, observers: obs}
+}
+
+func (o *ObservedStore) Append(rec Record) error {
+	return o.AppendContext(context.Background(), rec)
+}
+
+func (o *ObservedStore) AppendContext(ctx context.Context, rec Record) error {
+	if err := AppendContext(ctx, o.Store, rec); err != nil {
+		return err
+	}
+	for _, obs := range o.observers {
+		obs.OnAppend(clone(rec))
+	}
+	return nil
+}
+
+func (o *ObservedStore) GetByCounterContext(ctx context.Context, actor string, counter uint64) (Record, error) {
+	return GetByCounterContext(ctx, o.Store, actor, counter)
+}
+
+func (o *ObservedStore) RangeContext(ctx context.Context, actor string, from, to uint64, fn func(Record) error) error {
+	return RangeContext(ctx, o.Store, actor, from, to, fn)
+}
+
+func (o *ObservedStore) HeadContext(ctx context.Context, actor string) (Record, error) {
+	return HeadContext(ctx, o.Store, actor)
+}
+
+func (o *ObservedStore) Actors() ([]string, error) {
+	l, ok := o.Store.(Lister)
+	if !ok {
+		return nil, errors.New("history: inner store cannot list actors")
+	}
+	return l.Actors()
+}
+
+func (o *ObservedStore) Prune(actor string, from, to uint64) (int, error) {
+	p, ok := o.Store.(Pruner)
+	if !ok {
+		return 0, errors.New("history: inner store cannot prune")
+	}
+	return p.Prune(actor, from, to)
+}
+
+func (o *ObservedStore) SaveCheckpoint(cp Checkpoint) error {
+	cs, ok := o.Store.(CheckpointStore)
+	if !ok {
+		return errors.New("history: store cannot save checkpoints")
+	}
+	return cs.SaveCheckpoint(cp)
+}
+
+func (o *ObservedStore) LastCheckpoint(actor string) (Checkpoint, error) {
+	cs, ok := o.Store.(CheckpointStore)
+	if !ok {
+		return Checkpoint{}, ErrNotFound
+	}
+	return cs.LastCheckpoint(actor)
+}
+
+// Message is a record as streamed to a Sink.
+type Message struct {
+	// Key is the actor: partitioning by it keeps each chain in order.
+	Key []byte
+	// ID is "<actor>/<counter>", unique per record, for broker-side or
+	// consumer-side deduplication of redeliveries.
+	ID string
+	// Value is the record's export Line, VAX-JCS canonical.
+	Value []byte
+}
+
+// NewMessage builds the Message for rec.
+func NewMessage(rec Record) (Message, error) {
+	line, err := Filter{}.line(rec)
+	if err != nil {
+		return Message{}, err
+	}
+	value, err := jcs.Marshal(line)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Key: []byte(rec.Actor), ID: rec.Actor + "/" + strconv.FormatUint(rec.Counter, 10), Value: value}, nil
+}
+
+// Sink publishes messages to a downstream system.
+type Sink interface {
+	Publish(msg Message) error
+}
+
+// SinkObserver publishes every appended record to sink, reporting
+// failures to onError (which may be nil). Delivery is best effort; use an
+// outbox for at-least-once delivery.
+func SinkObserver(sink Sink, onError func(Record, error)) Observer {
+	return ObserverFunc(func(rec Record) {
+		msg, err := NewMessage(rec)
+		if err == nil {
+			err = sink.Publish(msg)
+		}
+		if err != nil && onError != nil {
+			onError(rec, err)
+		}
+	})
+}
+
+// KafkaProducer is the part of a Kafka client KafkaSink needs (e.g. a
+// franz-go or confluent-kafka-go producer behind a small adapter). Enable
+// the client's idempotent producer so retries do not duplicate messages.
+type KafkaProducer interface {
+	Produce(topic string, key, value []byte, headers map[string]string) error
+}
+
+// KafkaSink publishes to one Kafka topic, keyed by actor, with the
+// message ID in the "vax-message-id" header.
+type KafkaSink struct {
+	Producer KafkaProducer
+	Topic    string
+}
+
+func (k KafkaSink) Publish(msg Message) error {
+	if err := k.Producer.Produce(k.Topic, msg.Key, msg.Value, map[string]string{"vax-message-id": msg.ID}); err != nil {
+		return fmt.Errorf("history: kafka %s: %w", k.Topic, err)
+	}
+	return nil
+}
+
+// NATSPublisher is the part of a NATS client NATSSink needs (a JetStream
+// context's PublishMsg behind a small adapter).
+type NATSPublisher interface {
+	Publish(subject string, data []byte, headers map[string]string) error
+}
+
+// NATSSink publishes to one NATS subject with the message ID in the
+// Nats-Msg-Id header, which JetStream uses to drop duplicates within its
+// deduplication window.
+type NATSSink struct {
+	Publisher NATSPublisher
+	Subject   string
+}
+
+func (n NATSSink) Publish(msg Message) error {
+	if err := n.Publisher.Publish(n.Subject, msg.Value, map[string]string{"Nats-Msg-Id": msg.ID}); err != nil {
+		return fmt.Errorf("history: nats %s: %w", n.Subject, err)
+	}
+	return nil
+}
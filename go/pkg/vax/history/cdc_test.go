@@ -0,0 +1,101 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fakeProducer struct {
+	sent []map[string]string
+	fail int // 第 fail 次（1 起算）呼叫失敗
+}
+
+func (p *fakeProducer) Produce(topic string, key, value []byte, headers map[string]string) error {
+	if len(p.sent)+1 == p.fail {
+		p.fail = 0
+		return errors.New("broker unavailable")
+	}
+	var line Line
+	if err := json.Unmarshal(value, &line); err != nil {
+		return err
+	}
+	p.sent = append(p.sent, map[string]string{"topic": topic, "key": string(key), "actor": line.Actor, "id": headers["vax-message-id"], "nats": headers["Nats-Msg-Id"]})
+	return nil
+}
+
+func (p *fakeProducer) Publish(subject string, data []byte, headers map[string]string) error {
+	return p.Produce(subject, nil, data, headers)
+}
+
+func TestObserve(t *testing.T) {
+	t.Run("notifies after each append", func(t *testing.T) {
+		var seen []uint64
+		s := Observe(NewMemory(), ObserverFunc(func(r Record) { seen = append(seen, r.Counter) }))
+		recs := chain("alice", 3)
+		appendAll(t, s, recs[:2])
+		if err := s.Append(recs[0]); err == nil {
+			t.Fatal("duplicate accepted")
+		}
+		if len(seen) != 2 || seen[1] != 2 {
+			t.Errorf("seen %v", seen)
+		}
+		if actors, err := s.Actors(); err != nil || len(actors) != 1 {
+			t.Errorf("Actors = %v, %v", actors, err)
+		}
+	})
+
+	t.Run("streams to Kafka and NATS sinks", func(t *testing.T) {
+		kafka, nats := &fakeProducer{}, &fakeProducer{}
+		var failed []error
+		onError := func(_ Record, err error) { failed = append(failed, err) }
+		nats.fail = 2
+		s := Observe(NewMemory(),
+			SinkObserver(KafkaSink{Producer: kafka, Topic: "vax.actions"}, onError),
+			SinkObserver(NATSSink{Publisher: nats, Subject: "vax.actions"}, onError))
+		appendAll(t, s, envelopeChain(t, "alice", 2))
+		if len(kafka.sent) != 2 || kafka.sent[1]["key"] != "alice" || kafka.sent[1]["id"] != "alice/2" {
+			t.Errorf("kafka = %v", kafka.sent)
+		}
+		if len(nats.sent) != 1 || nats.sent[0]["nats"] != "alice/1" || len(failed) != 1 {
+			t.Errorf("nats = %v, failures %v", nats.sent, failed)
+		}
+	})
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run("relays committed records in order", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite, WithOutbox())
+		appendAll(t, s, envelopeChain(t, "bob", 1))
+		appendAll(t, s, envelopeChain(t, "alice", 2))
+		if len(fake.outbox) != 3 {
+			t.Fatalf("outbox = %v", fake.outbox)
+		}
+		p := &fakeProducer{fail: 2}
+		sink := KafkaSink{Producer: p, Topic: "t"}
+		if n, err := s.RelayOutbox(sink, 10); err == nil || n != 1 {
+			t.Fatalf("RelayOutbox = %d, %v", n, err)
+		}
+		if n, err := s.RelayOutbox(sink, 10); err != nil || n != 2 {
+			t.Fatalf("RelayOutbox = %d, %v", n, err)
+		}
+		var ids []string
+		for _, m := range p.sent {
+			ids = append(ids, m["id"])
+		}
+		if len(ids) != 3 || ids[0] != "alice/1" || ids[1] != "alice/2" || ids[2] != "bob/1" || len(fake.outbox) != 0 {
+			t.Errorf("published %v, %d pending", ids, len(fake.outbox))
+		}
+	})
+
+	t.Run("error: rolled back appends leave no outbox entry", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite, WithOutbox())
+		fake.failOn = "_outbox (actor, counter) VALUES"
+		if err := s.Append(chain("alice", 1)[0]); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(fake.rows) != 0 || len(fake.outbox) != 0 {
+			t.Errorf("%d rows, %d outbox entries", len(fake.rows), len(fake.outbox))
+		}
+	})
+}
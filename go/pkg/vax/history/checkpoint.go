@@ -0,0 +1,333 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// CheckpointActionType is the action_type of a checkpoint attestation.
+const CheckpointActionType = "vax.history.checkpoint"
+
+// ErrBadCheckpoint is returned when a stored checkpoint's attestation does
+// not verify or it no longer matches the records it covers.
+var ErrBadCheckpoint = errors.New("history: checkpoint does not verify")
+
+// Checkpoint records that an actor's chain verified up to Counter.
+//
+// MerkleRoot is the RFC 6962 root over SAIs 1..Counter and Frontier the
+// tree state that lets the next verification extend it from the tail
+// alone. Attestation, when present, is an SAE (action_type
+// CheckpointActionType) signed by the verifier over actor, counter, sai
+// and merkle_root.
+type Checkpoint struct {
+	Actor       string   `json:"actor"`
+	Counter     uint64   `json:"counter"`
+	SAI         []byte   `json:"sai"`
+	MerkleRoot  []byte   `json:"merkle_root"`
+	Frontier    Frontier `json:"frontier"`
+	VerifiedAt  int64    `json:"verified_at"` // unix ms
+	Verifier    string   `json:"verifier,omitempty"`
+	Attestation []byte   `json:"attestation,omitempty"`
+}
+
+// CheckpointStore is implemented by stores that keep checkpoints beside
+// the records (Memory, SQLStore, SegmentStore).
+type CheckpointStore interface {
+	SaveCheckpoint(cp Checkpoint) error
+	// LastCheckpoint returns the actor's checkpoint with the highest
+	// counter (ErrNotFound if none).
+	LastCheckpoint(actor string) (Checkpoint, error)
+}
+
+// ChainError locates the first record of a chain that failed verification.
+type ChainError struct {
+	Actor   string
+	Counter uint64
+	Err     error
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("history: %s counter %d: %v", e.Actor, e.Counter, e.Err)
+}
+
+func (e *ChainError) Unwrap() error { return e.Err }
+
+// VerifyOption configures VerifyChain and VerifyFromLastCheckpoint.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	ctx    context.Context
+	keys   sae.KeyResolver
+	signer crypto.Signer
+	kid    string
+	every  uint64
+	now    func() time.Time
+}
+
+// WithKeys verifies every envelope's signature, and the attestation of
+// the checkpoint verification starts from, through keys.
+func WithKeys(keys sae.KeyResolver) VerifyOption {
+	return func(c *verifyConfig) {
+		c.keys = keys
+	}
+}
+
+// WithContext bounds the verification by ctx: the scan stops between
+// records with ctx.Err() once ctx is done, and stores implementing
+// ContextStore receive ctx for their queries.
+func WithContext(ctx context.Context) VerifyOption {
+	return func(c *verifyConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithAttestation signs the checkpoints a verification produces with
+// signer under kid and saves them in the store (which must implement
+// CheckpointStore). Without it checkpoints are returned but not saved.
+func WithAttestation(kid string, signer crypto.Signer) VerifyOption {
+	return func(c *verifyConfig) {
+		c.kid, c.signer = kid, signer
+	}
+}
+
+// WithCheckpointEvery also saves a checkpoint every n verified records
+// during the scan, so an interrupted audit resumes close to where it
+// stopped. Requires WithAttestation.
+func WithCheckpointEvery(n uint64) VerifyOption {
+	return func(c *verifyConfig) {
+		c.every = n
+	}
+}
+
+// VerifyChain re-verifies the actor's whole chain from genesis: counters,
+// prev SAI links, each SAI over its canonical envelope, the envelopes'
+// in-band chain binding and (WithKeys) signatures; pruned records are
+// verified through their SAEHash. It returns the
+// checkpoint at the head; the first failure is a *ChainError.
+func VerifyChain(s Store, actor string, genesis []byte, opts ...VerifyOption) (Checkpoint, error) {
+	return verifyFrom(s, actor, genesisCheckpoint(actor, genesis), newVerifyConfig(opts))
+}
+
+// VerifyFromLastCheckpoint is VerifyChain starting at the actor's last
+// stored checkpoint, so routine audits only re-verify the records after
+// it. The checkpoint must still match the stored record at its counter
+// and carry an attestation by its Verifier that verifies through WithKeys
+// (ErrBadCheckpoint otherwise). Without WithKeys a stored checkpoint cannot
+// be authenticated, so, as without any checkpoint, the chain is verified
+// from genesis.
+func VerifyFromLastCheckpoint(s Store, actor string, genesis []byte, opts ...VerifyOption) (Checkpoint, error) {
+	cfg := newVerifyConfig(opts)
+	cs, ok := s.(CheckpointStore)
+	if !ok || cfg.keys == nil {
+		return verifyFrom(s, actor, genesisCheckpoint(actor, genesis), cfg)
+	}
+	cp, err := cs.LastCheckpoint(actor)
+	if errors.Is(err, ErrNotFound) {
+		return verifyFrom(s, actor, genesisCheckpoint(actor, genesis), cfg)
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if err := checkCheckpoint(cfg.ctx, s, cp, cfg.keys); err != nil {
+		return Checkpoint{}, err
+	}
+	return verifyFrom(s, actor, cp, cfg)
+}
+
+func genesisCheckpoint(actor string, genesis []byte) Checkpoint {
+	return Checkpoint{Actor: actor, SAI: genesis, MerkleRoot: Frontier{}.Root()}
+}
+
+func newVerifyConfig(opts []VerifyOption) verifyConfig {
+	cfg := verifyConfig{ctx: context.Background(), now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// checkCheckpoint 檢查 checkpoint 仍對應 store 中的 record，並驗證 attestation
+func checkCheckpoint(ctx context.Context, s Store, cp Checkpoint, keys sae.KeyResolver) error {
+	rec, err := GetByCounterContext(ctx, s, cp.Actor, cp.Counter)
+	if err != nil {
+		return fmt.Errorf("%w: counter %d: %v", ErrBadCheckpoint, cp.Counter, err)
+	}
+	if !bytes.Equal(rec.SAI, cp.SAI) || cp.Frontier.Size != cp.Counter || !bytes.Equal(cp.Frontier.Root(), cp.MerkleRoot) {
+		return fmt.Errorf("%w: counter %d does not match the stored chain", ErrBadCheckpoint, cp.Counter)
+	}
+	kid, err := verifyAttestation(ctx, cp.Attestation, cp, keys)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadCheckpoint, err)
+	}
+	if kid != cp.Verifier {
+		return fmt.Errorf("%w: attestation signed by %q, not the checkpoint verifier %q", ErrBadCheckpoint, kid, cp.Verifier)
+	}
+	return nil
+}
+
+// verifyAttestation 驗證 att 是 keys 可驗證、內容與 cp 相符的 checkpoint 聲明，回傳簽署者 kid
+func verifyAttestation(ctx context.Context, att []byte, cp Checkpoint, keys sae.KeyResolver) (string, error) {
+	env, err := sae.Parse(att)
+	if err != nil {
+		return "", fmt.Errorf("attestation: %v", err)
+	}
+	if err := env.VerifyWithResolverContext(ctx, keys); err != nil {
+		return "", fmt.Errorf("attestation: %v", err)
+	}
+	if env.ActionType != CheckpointActionType {
+		return "", errors.New("attestation is not a checkpoint statement")
+	}
+	// 以 canonical bytes 比對整份聲明（多出或型別不同的欄位都不算相符）
+	want, err := jcs.Marshal(checkpointStatement(cp))
+	if err != nil {
+		return "", err
+	}
+	got, err := jcs.Marshal(env.SDTO)
+	if err != nil || !bytes.Equal(got, want) {
+		return "", errors.New("attestation statement does not match the checkpoint")
+	}
+	return env.Kid, nil
+}
+
+func checkpointStatement(cp Checkpoint) map[string]any {
+	return map[string]any{
+		"actor":       cp.Actor,
+		"counter":     cp.Counter,
+		"sai":         hex.EncodeToString(cp.SAI),
+		"merkle_root": hex.EncodeToString(cp.MerkleRoot),
+	}
+}
+
+func verifyFrom(s Store, actor string, cp Checkpoint, cfg verifyConfig) (Checkpoint, error) {
+	if cfg.every > 0 && cfg.signer == nil {
+		return Checkpoint{}, errors.New("history: WithCheckpointEvery needs WithAttestation")
+	}
+	if len(cp.SAI) != vax.SAISize {
+		return Checkpoint{}, fmt.Errorf("%w: genesis SAI must be %d bytes", ErrInvalid, vax.SAISize)
+	}
+	state := vax.ChainState{Counter: cp.Counter, HeadSAI: cp.SAI}
+	frontier := cp.Frontier.clone()
+	last := cp
+
+	err := RangeContext(cfg.ctx, s, actor, cp.Counter+1, ^uint64(0), func(rec Record) error {
+		if err := verifyRecord(rec, state, cfg.keys); err != nil {
+			return &ChainError{Actor: actor, Counter: rec.Counter, Err: err}
+		}
+		state = state.Advance(rec.SAI)
+		frontier.Push(rec.SAI)
+		if cfg.every > 0 && state.Counter%cfg.every == 0 {
+			var err error
+			last, err = saveCheckpoint(s, actor, state, frontier, cfg)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	if state.Counter == last.Counter {
+		return last, nil
+	}
+	if cfg.signer == nil {
+		return Checkpoint{Actor: actor, Counter: state.Counter, SAI: state.HeadSAI, MerkleRoot: frontier.Root(),
+			Frontier: frontier, VerifiedAt: cfg.now().UnixMilli()}, nil
+	}
+	return saveCheckpoint(s, actor, state, frontier, cfg)
+}
+
+func saveCheckpoint(s Store, actor string, state vax.ChainState, frontier Frontier, cfg verifyConfig) (Checkpoint, error) {
+	cs, ok := s.(CheckpointStore)
+	if !ok {
+		return Checkpoint{}, errors.New("history: store cannot save checkpoints")
+	}
+	cp := Checkpoint{Actor: actor, Counter: state.Counter, SAI: state.HeadSAI, MerkleRoot: frontier.Root(),
+		Frontier: frontier.clone(), VerifiedAt: cfg.now().UnixMilli(), Verifier: cfg.kid}
+	b, err := attest(cp, cfg.kid, cfg.signer)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	cp.Attestation = b
+	if err := cs.SaveCheckpoint(cp); err != nil {
+		return Checkpoint{}, fmt.Errorf("history: save checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// attest 以 signer 簽署 cp 的 checkpoint 聲明（時間戳為 cp.VerifiedAt）
+func attest(cp Checkpoint, kid string, signer crypto.Signer) ([]byte, error) {
+	env := sae.NewEnvelope(CheckpointActionType, checkpointStatement(cp), sae.WithTimestamp(time.UnixMilli(cp.VerifiedAt)))
+	env.Kid = kid
+	if err := env.SignWith(signer, nil); err != nil {
+		return nil, fmt.Errorf("history: attest checkpoint: %w", err)
+	}
+	return jcs.Marshal(env)
+}
+
+func cloneCheckpoint(cp Checkpoint) Checkpoint {
+	cp.SAI, cp.MerkleRoot, cp.Attestation = bytes.Clone(cp.SAI), bytes.Clone(cp.MerkleRoot), bytes.Clone(cp.Attestation)
+	cp.Frontier = cp.Frontier.clone()
+	return cp
+}
+
+// verifyRecord 檢查 record 是否接在 state 之後（SAI 以 canonical envelope 計算）
+func verifyRecord(rec Record, state vax.ChainState, keys sae.KeyResolver) error {
+	if rec.Counter != state.Counter+1 {
+		return fmt.Errorf("%w: counter %d after %d", vax.ErrInvalidCounter, rec.Counter, state.Counter)
+	}
+	if !bytes.Equal(rec.PrevSAI, state.HeadSAI) {
+		return fmt.Errorf("%w: prev_sai is not the previous SAI", vax.ErrInvalidPrevSAI)
+	}
+	if rec.Pruned() {
+		// 內容已裁剪：只能以雜湊驗證 SAI
+		sai, err := vax.ComputeSAIFromDigest(state.HeadSAI, rec.SAEHash)
+		if err != nil {
+			return fmt.Errorf("%w: sae hash: %v", ErrInvalid, err)
+		}
+		if !bytes.Equal(sai, rec.SAI) {
+			return vax.ErrSAIMismatch
+		}
+		return nil
+	}
+	canonical, err := sae.Decompress(rec.SAE)
+	if err != nil {
+		return fmt.Errorf("%w: sae: %v", ErrInvalid, err)
+	}
+	sai, err := vax.ComputeSAI(state.HeadSAI, canonical)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sai, rec.SAI) {
+		return vax.ErrSAIMismatch
+	}
+	return verifyEnvelope(canonical, rec.ActionType, state, keys)
+}
+
+// verifyEnvelope 檢查 envelope 的 action_type、in-band chain binding 與簽章（有 keys 時）
+func verifyEnvelope(canonical []byte, actionType string, state vax.ChainState, keys sae.KeyResolver) error {
+	env, err := sae.Parse(canonical)
+	if err != nil {
+		return fmt.Errorf("%w: sae: %v", ErrInvalid, err)
+	}
+	if env.ActionType != actionType {
+		return fmt.Errorf("%w: action_type does not match the envelope", ErrInvalid)
+	}
+	if err := vax.VerifyChainBinding(env, state); err != nil {
+		return fmt.Errorf("envelope chain binding: %w", err)
+	}
+	if keys != nil {
+		if err := env.VerifyWithResolver(keys); err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+	}
+	return nil
+}
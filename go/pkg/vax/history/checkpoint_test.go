@@ -0,0 +1,211 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// signedChain 把 n 筆以 k1 簽章的 envelope 接在 actor 目前的 head 之後
+func signedChain(t *testing.T, s Store, actor string, n int, priv ed25519.PrivateKey) {
+	t.Helper()
+	state := vax.ChainState{HeadSAI: testGenesis}
+	if head, err := s.Head(actor); err == nil {
+		state = vax.ChainState{Counter: head.Counter, HeadSAI: head.SAI}
+	}
+	for i := 0; i < n; i++ {
+		env := sae.NewEnvelope("transfer", map[string]any{"amount": i},
+			sae.WithTimestamp(time.UnixMilli(1700000000000)), sae.WithChain(state.Counter+1, state.HeadSAI))
+		env.Kid = "k1"
+		if err := env.Sign(priv); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := jcs.Marshal(env)
+		sai, _ := vax.ComputeSAI(state.HeadSAI, b)
+		appendAll(t, s, []Record{{Actor: actor, Counter: state.Counter + 1, ActionType: "transfer", PrevSAI: state.HeadSAI, SAE: b, SAI: sai}})
+		state = state.Advance(sai)
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	t.Run("returns the head checkpoint", func(t *testing.T) {
+		s := NewMemory()
+		recs := envelopeChain(t, "alice", 5)
+		appendAll(t, s, recs)
+		cp, err := VerifyChain(s, "alice", testGenesis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, _ := MerkleRoot(s, "alice", 5)
+		if cp.Counter != 5 || !bytes.Equal(cp.SAI, recs[4].SAI) || !bytes.Equal(cp.MerkleRoot, root) || cp.Attestation != nil {
+			t.Errorf("checkpoint = %+v", cp)
+		}
+	})
+
+	t.Run("error: tampered envelope", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 4))
+		s.chains["alice"][2].SAE = bytes.Replace(s.chains["alice"][2].SAE, []byte(`"amount":3`), []byte(`"amount":4`), 1)
+		_, err := VerifyChain(s, "alice", testGenesis)
+		var ce *ChainError
+		if !errors.As(err, &ce) || ce.Counter != 3 || !errors.Is(err, vax.ErrSAIMismatch) {
+			t.Errorf("expected a SAI mismatch at counter 3, got %v", err)
+		}
+	})
+
+	t.Run("error: wrong genesis", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 1))
+		if _, err := VerifyChain(s, "alice", make([]byte, vax.SAISize)); !errors.Is(err, vax.ErrInvalidPrevSAI) {
+			t.Errorf("expected ErrInvalidPrevSAI, got %v", err)
+		}
+	})
+}
+
+func TestVerifyFromLastCheckpoint(t *testing.T) {
+	pub, priv, _ := sae.GenerateKeyPair()
+	auditorPub, auditor, _ := sae.GenerateKeyPair()
+	keys := sae.StaticResolver{"k1": pub, "auditor": auditorPub}
+
+	t.Run("verifies only the tail", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 3, priv)
+		cp, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys), WithAttestation("auditor", auditor))
+		if err != nil || cp.Counter != 3 || cp.Verifier != "auditor" || len(cp.Attestation) == 0 {
+			t.Fatalf("checkpoint = %+v, %v", cp, err)
+		}
+		if last, err := s.LastCheckpoint("alice"); err != nil || last.Counter != 3 {
+			t.Fatalf("LastCheckpoint = %+v, %v", last, err)
+		}
+
+		// 已查核的 record 不再重驗：竄改其簽章只有完整驗證看得到
+		s.chains["alice"][1].SAE = bytes.Replace(s.chains["alice"][1].SAE, []byte(`"kid":"k1"`), []byte(`"kid":"k2"`), 1)
+		signedChain(t, s, "alice", 2, priv)
+		cp, err = VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys), WithAttestation("auditor", auditor))
+		if err != nil || cp.Counter != 5 {
+			t.Fatalf("checkpoint = %+v, %v", cp, err)
+		}
+		root, _ := MerkleRoot(s, "alice", 5)
+		if !bytes.Equal(cp.MerkleRoot, root) {
+			t.Errorf("root %x, want %x", cp.MerkleRoot, root)
+		}
+		if _, err := VerifyChain(s, "alice", testGenesis, WithKeys(keys)); err == nil {
+			t.Error("full verification missed the tampered record")
+		}
+	})
+
+	t.Run("saves periodic checkpoints", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 5, priv)
+		s.chains["alice"][4].SAI[0] ^= 1
+		_, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys), WithAttestation("auditor", auditor), WithCheckpointEvery(2))
+		if !errors.Is(err, vax.ErrSAIMismatch) {
+			t.Fatalf("expected ErrSAIMismatch, got %v", err)
+		}
+		if last, err := s.LastCheckpoint("alice"); err != nil || last.Counter != 4 {
+			t.Errorf("LastCheckpoint = %+v, %v", last, err)
+		}
+	})
+
+	t.Run("persists in a segment store", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir, WithoutSync())
+		signedChain(t, s, "alice", 3, priv)
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithAttestation("auditor", auditor)); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		s = openSegments(t, dir)
+		cp, err := s.LastCheckpoint("alice")
+		if err != nil || cp.Counter != 3 || cp.Frontier.Size != 3 {
+			t.Fatalf("LastCheckpoint = %+v, %v", cp, err)
+		}
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error: attestation by another key", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 2, priv)
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithAttestation("auditor", auditor)); err != nil {
+			t.Fatal(err)
+		}
+		forged := sae.StaticResolver{"k1": pub, "auditor": pub}
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(forged)); !errors.Is(err, ErrBadCheckpoint) {
+			t.Errorf("expected ErrBadCheckpoint, got %v", err)
+		}
+	})
+
+	t.Run("error: checkpoint no longer matches the chain", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 2, priv)
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithAttestation("auditor", auditor)); err != nil {
+			t.Fatal(err)
+		}
+		s.chains["alice"][1].SAI[0] ^= 1
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys)); !errors.Is(err, ErrBadCheckpoint) {
+			t.Errorf("expected ErrBadCheckpoint, got %v", err)
+		}
+	})
+
+	t.Run("error: without keys the checkpoint is not trusted", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 3, priv)
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithAttestation("auditor", auditor)); err != nil {
+			t.Fatal(err)
+		}
+		// checkpoint 之前的 record 被竄改：沒有 keys 時必須從 genesis 重驗才看得到
+		s.chains["alice"][0].SAE = bytes.Replace(s.chains["alice"][0].SAE, []byte(`"amount":0`), []byte(`"amount":9`), 1)
+		_, err := VerifyFromLastCheckpoint(s, "alice", testGenesis)
+		var ce *ChainError
+		if !errors.As(err, &ce) || ce.Counter != 1 {
+			t.Errorf("expected a ChainError at counter 1, got %v", err)
+		}
+	})
+
+	t.Run("error: attestation by a key other than the verifier", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 2, priv)
+		cp, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithAttestation("auditor", auditor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp.Verifier = "k1"
+		_ = s.SaveCheckpoint(cp)
+		_, err = VerifyFromLastCheckpoint(s, "alice", testGenesis, WithKeys(keys))
+		if !errors.Is(err, ErrBadCheckpoint) || !strings.Contains(err.Error(), `not the checkpoint verifier "k1"`) {
+			t.Errorf("expected a verifier mismatch, got %v", err)
+		}
+	})
+
+	t.Run("error: attestation statement with extra fields", func(t *testing.T) {
+		cp := Checkpoint{Actor: "alice", Counter: 1, SAI: testGenesis, MerkleRoot: Frontier{}.Root()}
+		stmt := checkpointStatement(cp)
+		stmt["counter"] = "1" // fmt.Sprint 相同但型別不同
+		stmt["extra"] = true
+		env := sae.NewEnvelope(CheckpointActionType, stmt)
+		env.Kid = "auditor"
+		_ = env.Sign(auditor)
+		att, _ := jcs.Marshal(env)
+		if _, err := verifyAttestation(context.Background(), att, cp, keys); err == nil {
+			t.Error("accepted a statement that does not match canonically")
+		}
+	})
+
+	t.Run("error: periodic checkpoints need a signer", func(t *testing.T) {
+		s := NewMemory()
+		signedChain(t, s, "alice", 1, priv)
+		if _, err := VerifyFromLastCheckpoint(s, "alice", testGenesis, WithCheckpointEvery(1)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package history
+
+import "context"
+
+// ContextStore is a Store whose operations take a context, so request
+// deadlines and cancellation reach the backend (SQLStore). The package
+// functions AppendContext, GetByCounterContext, RangeContext and
+// HeadContext accept any Store and use these methods when available.
+type ContextStore interface {
+	Store
+	AppendContext(ctx context.Context, rec Record) error
+	GetByCounterContext(ctx context.Context, actor string, counter uint64) (Record, error)
+	RangeContext(ctx context.Context, actor string, from, to uint64, fn func(Record) error) error
+	HeadContext(ctx context.Context, actor string) (Record, error)
+}
+
+// AppendContext appends rec to s, failing with ctx.Err() once ctx is done.
+func AppendContext(ctx context.Context, s Store, rec Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cs, ok := s.(ContextStore); ok {
+		return cs.AppendContext(ctx, rec)
+	}
+	return s.Append(rec)
+}
+
+// GetByCounterContext is the context-aware form of s.GetByCounter.
+func GetByCounterContext(ctx context.Context, s Store, actor string, counter uint64) (Record, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, err
+	}
+	if cs, ok := s.(ContextStore); ok {
+		return cs.GetByCounterContext(ctx, actor, counter)
+	}
+	return s.GetByCounter(actor, counter)
+}
+
+// RangeContext is s.Range that stops with ctx.Err() once ctx is done. For
+// stores without a RangeContext method, ctx is checked before every record,
+// so long scans can be abandoned between records.
+func RangeContext(ctx context.Context, s Store, actor string, from, to uint64, fn func(Record) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cs, ok := s.(ContextStore); ok {
+		return cs.RangeContext(ctx, actor, from, to, fn)
+	}
+	return s.Range(actor, from, to, func(rec Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(rec)
+	})
+}
+
+// HeadContext is the context-aware form of s.Head.
+func HeadContext(ctx context.Context, s Store, actor string) (Record, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, err
+	}
+	if cs, ok := s.(ContextStore); ok {
+		return cs.HeadContext(ctx, actor)
+	}
+	return s.Head(actor)
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	sqlStore, _ := newSQLStore(t, SQLite)
+	stores := map[string]Store{"memory": NewMemory(), "sql": sqlStore, "encrypted": NewEncrypted(NewMemory(), testKeyer())}
+
+	for name, s := range stores {
+		appendAll(t, s, chain("alice", 5))
+
+		t.Run(name+": range stops once the context is cancelled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			calls := 0
+			err := RangeContext(ctx, s, "alice", 1, 5, func(Record) error {
+				if calls++; calls == 2 {
+					cancel()
+				}
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) || calls != 2 {
+				t.Errorf("err = %v after %d calls", err, calls)
+			}
+		})
+
+		t.Run(name+": error: done context", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := HeadContext(ctx, s, "alice"); !errors.Is(err, context.Canceled) {
+				t.Errorf("HeadContext: %v", err)
+			}
+			if err := AppendContext(ctx, s, chain("alice", 6)[5]); !errors.Is(err, context.Canceled) {
+				t.Errorf("AppendContext: %v", err)
+			}
+			if head, _ := s.Head("alice"); head.Counter != 5 {
+				t.Errorf("appended after cancellation: counter %d", head.Counter)
+			}
+		})
+	}
+
+	t.Run("error: verification and export stop with the context", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 3))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := VerifyChain(s, "alice", testGenesis, WithContext(ctx)); !errors.Is(err, context.Canceled) {
+			t.Errorf("VerifyChain: %v", err)
+		}
+		if err := ExportContext(ctx, io.Discard, s, JSONL, Filter{Actors: []string{"alice"}}); !errors.Is(err, context.Canceled) {
+			t.Errorf("ExportContext: %v", err)
+		}
+		if _, err := SearchContext(ctx, s, Query{Filter: Filter{Actors: []string{"alice"}}}); !errors.Is(err, context.Canceled) {
+			t.Errorf("SearchContext: %v", err)
+		}
+	})
+}
@@ -0,0 +1,466 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// CredentialFormat selects the securing mechanism of a segment credential.
+type CredentialFormat string
+
+const (
+	// CredentialJSONLD embeds a W3C Data Integrity proof (cryptosuite
+	// eddsa-jcs-2022 for Ed25519 keys, ecdsa-jcs-2019 for P-256).
+	CredentialJSONLD CredentialFormat = "jsonld"
+	// CredentialJWT secures the credential as a compact JWS of type vc+jwt
+	// (W3C VC-JOSE-COSE; EdDSA, ES256 or PS256).
+	CredentialJWT CredentialFormat = "jwt"
+)
+
+// Credential constants (W3C VC Data Model 2.0).
+const (
+	CredentialContext     = "https://www.w3.org/ns/credentials/v2"
+	SegmentCredentialType = "VAXChainSegmentCredential"
+)
+
+// ErrInvalidCredential is returned when a credential does not parse, is not
+// a segment credential or its proof does not verify.
+var ErrInvalidCredential = errors.New("history: invalid credential")
+
+// Credential is a W3C Verifiable Credential in which a verifier states that
+// a segment of an actor's chain is part of a head it verified.
+type Credential struct {
+	Context           []string         `json:"@context"`
+	Type              []string         `json:"type"`
+	Issuer            string           `json:"issuer"`
+	ValidFrom         string           `json:"validFrom"` // RFC 3339
+	CredentialSubject SegmentSubject   `json:"credentialSubject"`
+	Proof             *CredentialProof `json:"proof,omitempty"` // JSON-LD form only
+}
+
+// SegmentSubject is the claim of a segment credential: records From..To of
+// Actor, hash-linked from PrevSAI, under the verified head (HeadCounter,
+// HeadSAI, MerkleRoot over SAIs 1..HeadCounter). Digests are hex.
+type SegmentSubject struct {
+	ID          string          `json:"id,omitempty"`
+	Actor       string          `json:"actor"`
+	From        uint64          `json:"from"`
+	To          uint64          `json:"to"`
+	PrevSAI     string          `json:"prev_sai"`
+	Records     []SegmentRecord `json:"records"`
+	HeadCounter uint64          `json:"head_counter"`
+	HeadSAI     string          `json:"head_sai"`
+	MerkleRoot  string          `json:"merkle_root"`
+}
+
+// SegmentRecord is one action of a segment. Envelopes are not included;
+// their SAIs commit to them.
+type SegmentRecord struct {
+	Counter    uint64 `json:"counter"`
+	ActionType string `json:"action_type"`
+	SAI        string `json:"sai"`
+}
+
+// CredentialProof is a Data Integrity proof.
+type CredentialProof struct {
+	Context            []string `json:"@context,omitempty"`
+	Type               string   `json:"type"` // DataIntegrityProof
+	Cryptosuite        string   `json:"cryptosuite"`
+	Created            string   `json:"created"`
+	VerificationMethod string   `json:"verificationMethod"` // issuer#kid
+	ProofPurpose       string   `json:"proofPurpose"`       // assertionMethod
+	ProofValue         string   `json:"proofValue,omitempty"`
+}
+
+// CredentialIssuer signs segment credentials as a verifier.
+type CredentialIssuer struct {
+	ID        string        // issuer URI, e.g. did:web:verifier.example
+	Kid       string        // key id; the verification method is ID + "#" + Kid
+	Signer    crypto.Signer // Ed25519, ECDSA P-256 or RSA (JWT only)
+	SubjectID string        // optional credentialSubject.id, e.g. the actor's DID
+	// Now dates the credential (time.Now when nil).
+	Now func() time.Time
+}
+
+// Segment issues a credential for the records from..to (to 0: the head) of
+// cp's actor. cp is the verifier's checkpoint of the chain, typically
+// from VerifyChain or a FinalizedHead; the segment is read from s and
+// must be hash-linked and, when it ends at cp's counter, end at cp's SAI.
+func (iss CredentialIssuer) Segment(s Store, cp Checkpoint, from, to uint64, format CredentialFormat) ([]byte, error) {
+	return iss.SegmentContext(context.Background(), s, cp, from, to, format)
+}
+
+// SegmentContext is Segment with the scan bound to ctx (see RangeContext).
+func (iss CredentialIssuer) SegmentContext(ctx context.Context, s Store, cp Checkpoint, from, to uint64, format CredentialFormat) ([]byte, error) {
+	if format != CredentialJSONLD && format != CredentialJWT {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	if iss.ID == "" || iss.Kid == "" || iss.Signer == nil {
+		return nil, errors.New("history: a credential issuer needs an id, a kid and a signer")
+	}
+	if to == 0 {
+		to = cp.Counter
+	}
+	if from == 0 || from > to || to > cp.Counter {
+		return nil, fmt.Errorf("%w: segment %d..%d of a chain verified to %d", ErrInvalid, from, to, cp.Counter)
+	}
+
+	subject := SegmentSubject{ID: iss.SubjectID, Actor: cp.Actor, From: from, To: to, HeadCounter: cp.Counter,
+		HeadSAI: hex.EncodeToString(cp.SAI), MerkleRoot: hex.EncodeToString(cp.MerkleRoot)}
+	var prev []byte
+	err := RangeContext(ctx, s, cp.Actor, from, to, func(rec Record) error {
+		if rec.Counter != from+uint64(len(subject.Records)) || (prev != nil && !bytes.Equal(rec.PrevSAI, prev)) {
+			return fmt.Errorf("%w: %s counter %d does not extend the segment", ErrOutOfOrder, cp.Actor, rec.Counter)
+		}
+		if prev == nil {
+			subject.PrevSAI = hex.EncodeToString(rec.PrevSAI)
+		}
+		prev = rec.SAI
+		subject.Records = append(subject.Records, SegmentRecord{Counter: rec.Counter, ActionType: rec.ActionType, SAI: hex.EncodeToString(rec.SAI)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(subject.Records)) != to-from+1 {
+		return nil, fmt.Errorf("%w: %s has %d of records %d..%d", ErrNotFound, cp.Actor, len(subject.Records), from, to)
+	}
+	if to == cp.Counter && !bytes.Equal(prev, cp.SAI) {
+		return nil, fmt.Errorf("%w: segment does not end at the checkpoint SAI", ErrBadCheckpoint)
+	}
+
+	now := time.Now
+	if iss.Now != nil {
+		now = iss.Now
+	}
+	created := now().UTC().Format(time.RFC3339)
+	vc := &Credential{
+		Context:           []string{CredentialContext},
+		Type:              []string{"VerifiableCredential", SegmentCredentialType},
+		Issuer:            iss.ID,
+		ValidFrom:         created,
+		CredentialSubject: subject,
+	}
+	if format == CredentialJWT {
+		return iss.signJWT(vc)
+	}
+	return iss.signDataIntegrity(vc, created)
+}
+
+// VerifyCredential verifies a segment credential in either form, resolving
+// the issuer's kid (from the JWS header or the proof's verification
+// method) through keys, and returns it. It checks the proof and that the
+// subject's records are consecutive; whether the issuer is trusted and
+// the head is the one expected is up to the caller.
+func VerifyCredential(data []byte, keys sae.KeyResolver) (*Credential, error) {
+	return VerifyCredentialContext(context.Background(), data, keys)
+}
+
+// VerifyCredentialContext is VerifyCredential with ctx passed to keys
+// (see sae.ResolveKey).
+func VerifyCredentialContext(ctx context.Context, data []byte, keys sae.KeyResolver) (*Credential, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("%w: no key resolver", ErrInvalidCredential)
+	}
+	var (
+		vc  *Credential
+		err error
+	)
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		vc, err = verifyDataIntegrity(ctx, data, keys)
+	} else {
+		vc, err = verifyJWT(ctx, data, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub := vc.CredentialSubject
+	if len(vc.Type) != 2 || vc.Type[1] != SegmentCredentialType {
+		return nil, fmt.Errorf("%w: not a %s", ErrInvalidCredential, SegmentCredentialType)
+	}
+	if sub.From == 0 || sub.To < sub.From || sub.To > sub.HeadCounter || uint64(len(sub.Records)) != sub.To-sub.From+1 {
+		return nil, fmt.Errorf("%w: segment %d..%d has %d records", ErrInvalidCredential, sub.From, sub.To, len(sub.Records))
+	}
+	for i, rec := range sub.Records {
+		if rec.Counter != sub.From+uint64(i) {
+			return nil, fmt.Errorf("%w: record %d has counter %d", ErrInvalidCredential, i, rec.Counter)
+		}
+	}
+	if sub.To == sub.HeadCounter && sub.Records[len(sub.Records)-1].SAI != sub.HeadSAI {
+		return nil, fmt.Errorf("%w: segment does not end at the head SAI", ErrInvalidCredential)
+	}
+	return vc, nil
+}
+
+// ======== Data Integrity (eddsa-jcs-2022 / ecdsa-jcs-2019) ========
+
+func (iss CredentialIssuer) signDataIntegrity(vc *Credential, created string) ([]byte, error) {
+	suite, err := cryptosuiteFor(iss.Signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	proof := &CredentialProof{Context: vc.Context, Type: "DataIntegrityProof", Cryptosuite: suite, Created: created,
+		VerificationMethod: iss.ID + "#" + iss.Kid, ProofPurpose: "assertionMethod"}
+	msg, err := dataIntegrityHash(vc, proof)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := credentialSign(iss.Signer, msg)
+	if err != nil {
+		return nil, err
+	}
+	proof.ProofValue = "z" + base58Encode(sig)
+	vc.Proof = proof
+	return jcs.Marshal(vc)
+}
+
+func verifyDataIntegrity(ctx context.Context, data []byte, keys sae.KeyResolver) (*Credential, error) {
+	var vc Credential
+	if err := json.Unmarshal(data, &vc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	proof := vc.Proof
+	if proof == nil || proof.Type != "DataIntegrityProof" || proof.ProofPurpose != "assertionMethod" || !strings.HasPrefix(proof.ProofValue, "z") {
+		return nil, fmt.Errorf("%w: no assertionMethod Data Integrity proof", ErrInvalidCredential)
+	}
+	issuer, kid, ok := strings.Cut(proof.VerificationMethod, "#")
+	if !ok || issuer != vc.Issuer {
+		return nil, fmt.Errorf("%w: verification method %q is not the issuer's", ErrInvalidCredential, proof.VerificationMethod)
+	}
+	pub, err := sae.ResolveKey(ctx, keys, kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if suite, err := cryptosuiteFor(pub); err != nil || suite != proof.Cryptosuite {
+		return nil, fmt.Errorf("%w: cryptosuite %q does not match the key", ErrInvalidCredential, proof.Cryptosuite)
+	}
+	sig, err := base58Decode(proof.ProofValue[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: proofValue: %v", ErrInvalidCredential, err)
+	}
+
+	// 簽章涵蓋收到的文件本身（不經 struct 來回），未知成員也受保護
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	config, _ := doc["proof"].(map[string]any)
+	delete(doc, "proof")
+	delete(config, "proofValue")
+	msg, err := dataIntegrityHash(doc, config)
+	if err != nil || !credentialVerify(pub, msg, sig) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, sae.ErrInvalidSignature)
+	}
+	return &vc, nil
+}
+
+// dataIntegrityHash 回傳 SHA256(JCS(proof config)) || SHA256(JCS(document))
+func dataIntegrityHash(document, config any) ([]byte, error) {
+	doc, err := jcs.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := jcs.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	a, b := sha256.Sum256(cfg), sha256.Sum256(doc)
+	return append(a[:], b[:]...), nil
+}
+
+func cryptosuiteFor(pub crypto.PublicKey) (string, error) {
+	switch credentialAlg(pub) {
+	case "EdDSA":
+		return "eddsa-jcs-2022", nil
+	case "ES256":
+		return "ecdsa-jcs-2019", nil
+	}
+	return "", sae.ErrUnsupportedAlg
+}
+
+// ======== JWT (vc+jwt) ========
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+	Cty string `json:"cty,omitempty"`
+}
+
+func (iss CredentialIssuer) signJWT(vc *Credential) ([]byte, error) {
+	alg := credentialAlg(iss.Signer.Public())
+	if alg == "" {
+		return nil, sae.ErrUnsupportedAlg
+	}
+	header, err := json.Marshal(jwsHeader{Alg: alg, Kid: iss.Kid, Typ: "vc+jwt", Cty: "vc"})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := jcs.Marshal(vc)
+	if err != nil {
+		return nil, err
+	}
+	input := b64url.EncodeToString(header) + "." + b64url.EncodeToString(payload)
+	sig, err := credentialSign(iss.Signer, []byte(input))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(input + "." + b64url.EncodeToString(sig)), nil
+}
+
+func verifyJWT(ctx context.Context, data []byte, keys sae.KeyResolver) (*Credential, error) {
+	parts := strings.Split(string(data), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a compact JWS", ErrInvalidCredential)
+	}
+	var header jwsHeader
+	rawHeader, err := b64url.DecodeString(parts[0])
+	if err == nil {
+		err = json.Unmarshal(rawHeader, &header)
+	}
+	if err != nil || header.Typ != "vc+jwt" {
+		return nil, fmt.Errorf("%w: header is not a vc+jwt header", ErrInvalidCredential)
+	}
+	pub, err := sae.ResolveKey(ctx, keys, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	sig, err := b64url.DecodeString(parts[2])
+	// alg 必須與 key 型別相符，避免換 alg 攻擊
+	if err != nil || header.Alg != credentialAlg(pub) || !credentialVerify(pub, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, sae.ErrInvalidSignature)
+	}
+	payload, err := b64url.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidCredential, err)
+	}
+	var vc Credential
+	if err := json.Unmarshal(payload, &vc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if vc.Proof != nil {
+		return nil, fmt.Errorf("%w: a vc+jwt payload has no embedded proof", ErrInvalidCredential)
+	}
+	return &vc, nil
+}
+
+// ======== signatures ========
+
+var b64url = base64.RawURLEncoding
+
+// credentialAlg 回傳 key 對應的 JOSE alg（不支援時為空字串）
+func credentialAlg(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return "EdDSA"
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return "ES256"
+		}
+	case *rsa.PublicKey:
+		if k.N.BitLen() >= sae.MinRSABits {
+			return "PS256"
+		}
+	}
+	return ""
+}
+
+// credentialSign 簽署 msg：Ed25519 直接簽，ES256 / PS256 簽 SHA256(msg)；ECDSA 簽章為 JOSE 的 r||s
+func credentialSign(signer crypto.Signer, msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	switch credentialAlg(signer.Public()) {
+	case "EdDSA":
+		return signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	case "ES256":
+		der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &rs); err != nil {
+			return nil, err
+		}
+		return append(rs.R.FillBytes(make([]byte, 32)), rs.S.FillBytes(make([]byte, 32))...), nil
+	case "PS256":
+		return signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	}
+	return nil, sae.ErrUnsupportedAlg
+}
+
+func credentialVerify(pub crypto.PublicKey, msg, sig []byte) bool {
+	digest := sha256.Sum256(msg)
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, sig)
+	case *ecdsa.PublicKey:
+		if len(sig) != 64 {
+			return false
+		}
+		return ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+	case *rsa.PublicKey:
+		return rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+	}
+	return false
+}
+
+// ======== base58btc (multibase "z") ========
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	var out []byte
+	for mod, base := new(big.Int), big.NewInt(58); n.Sign() > 0; {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// 前導 0x00 各編為 '1'
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, '1')
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n, base := new(big.Int), big.NewInt(58)
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	for _, c := range []byte(s) {
+		i := strings.IndexByte(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(i)))
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
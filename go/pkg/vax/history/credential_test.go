@@ -0,0 +1,137 @@
+package history
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/sae"
+)
+
+func TestCredential(t *testing.T) {
+	_, priv, _ := sae.GenerateKeyPair()
+	vpub, vpriv, _ := sae.GenerateKeyPair()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := sae.StaticResolver{"v1": vpub, "v2": &ecKey.PublicKey}
+
+	s := NewMemory()
+	signedChain(t, s, "alice", 5, priv)
+	cp, err := VerifyChain(s, "alice", testGenesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	iss := CredentialIssuer{ID: "did:web:verifier.example", Kid: "v1", Signer: vpriv, SubjectID: "did:example:alice", Now: now}
+
+	for _, format := range []CredentialFormat{CredentialJSONLD, CredentialJWT} {
+		t.Run(string(format)+" round trip", func(t *testing.T) {
+			b, err := iss.Segment(s, cp, 2, 4, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vc, err := VerifyCredential(b, keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub := vc.CredentialSubject
+			rec, _ := s.GetByCounter("alice", 2)
+			if vc.Issuer != iss.ID || vc.ValidFrom != "2026-01-02T03:04:05Z" || sub.ID != "did:example:alice" ||
+				sub.From != 2 || sub.To != 4 || len(sub.Records) != 3 || sub.PrevSAI != hex.EncodeToString(rec.PrevSAI) ||
+				sub.Records[0].SAI != hex.EncodeToString(rec.SAI) || sub.HeadCounter != 5 || sub.MerkleRoot != hex.EncodeToString(cp.MerkleRoot) {
+				t.Errorf("credential = %+v", vc)
+			}
+		})
+	}
+
+	t.Run("json-ld proof", func(t *testing.T) {
+		b, _ := iss.Segment(s, cp, 1, 0, CredentialJSONLD)
+		vc, err := VerifyCredential(b, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := vc.Proof
+		if p.Cryptosuite != "eddsa-jcs-2022" || p.VerificationMethod != "did:web:verifier.example#v1" || !strings.HasPrefix(p.ProofValue, "z") ||
+			vc.CredentialSubject.To != 5 || vc.CredentialSubject.Records[4].SAI != vc.CredentialSubject.HeadSAI {
+			t.Errorf("credential = %+v, proof = %+v", vc, p)
+		}
+
+		ec := CredentialIssuer{ID: iss.ID, Kid: "v2", Signer: ecKey}
+		b, _ = ec.Segment(s, cp, 1, 0, CredentialJSONLD)
+		if vc, err := VerifyCredential(b, keys); err != nil || vc.Proof.Cryptosuite != "ecdsa-jcs-2019" {
+			t.Errorf("ecdsa: %v", err)
+		}
+	})
+
+	t.Run("jwt header", func(t *testing.T) {
+		b, _ := iss.Segment(s, cp, 1, 0, CredentialJWT)
+		header, _ := b64url.DecodeString(strings.Split(string(b), ".")[0])
+		if string(header) != `{"alg":"EdDSA","kid":"v1","typ":"vc+jwt","cty":"vc"}` {
+			t.Errorf("header = %s", header)
+		}
+	})
+
+	t.Run("base58", func(t *testing.T) {
+		for _, in := range [][]byte{{}, {0, 0, 1}, []byte("hello world"), bytes.Repeat([]byte{0xff}, 64)} {
+			out, err := base58Decode(base58Encode(in))
+			if err != nil || !bytes.Equal(out, in) {
+				t.Errorf("%x -> %q -> %x, %v", in, base58Encode(in), out, err)
+			}
+		}
+		if base58Encode([]byte("hello world")) != "StV1DL6CwTryKyV" {
+			t.Error(base58Encode([]byte("hello world")))
+		}
+	})
+
+	t.Run("error: tampered credentials", func(t *testing.T) {
+		ld, _ := iss.Segment(s, cp, 2, 4, CredentialJSONLD)
+		jwt, _ := iss.Segment(s, cp, 2, 4, CredentialJWT)
+		parts := strings.Split(string(jwt), ".")
+		payload, _ := b64url.DecodeString(parts[1])
+		for name, b := range map[string][]byte{
+			"json-ld subject": bytes.Replace(ld, []byte(`"to":4`), []byte(`"to":3`), 1),
+			"json-ld extra":   bytes.Replace(ld, []byte(`"issuer"`), []byte(`"extra":1,"issuer"`), 1),
+			"json-ld method":  bytes.Replace(ld, []byte("#v1"), []byte("#v2"), 1),
+			"jwt payload":     []byte(parts[0] + "." + b64url.EncodeToString(bytes.Replace(payload, []byte(`"to":4`), []byte(`"to":3`), 1)) + "." + parts[2]),
+			"jwt signature":   []byte(parts[0] + "." + parts[1] + "." + parts[0]),
+			"not a jws":       []byte("a.b"),
+		} {
+			if _, err := VerifyCredential(b, keys); !errors.Is(err, ErrInvalidCredential) {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		if _, err := VerifyCredential(ld, sae.StaticResolver{"v1": &ecKey.PublicKey}); !errors.Is(err, ErrInvalidCredential) {
+			t.Errorf("wrong key: %v", err)
+		}
+	})
+
+	t.Run("error: bad segments and issuers", func(t *testing.T) {
+		if _, err := iss.Segment(s, cp, 0, 3, CredentialJWT); !errors.Is(err, ErrInvalid) {
+			t.Errorf("from 0: %v", err)
+		}
+		if _, err := iss.Segment(s, cp, 2, 6, CredentialJWT); !errors.Is(err, ErrInvalid) {
+			t.Errorf("past the head: %v", err)
+		}
+		if _, err := iss.Segment(s, cp, 1, 0, "pdf"); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("format: %v", err)
+		}
+		stale := cp
+		stale.SAI = testGenesis
+		if _, err := iss.Segment(s, stale, 1, 0, CredentialJWT); !errors.Is(err, ErrBadCheckpoint) {
+			t.Errorf("stale checkpoint: %v", err)
+		}
+		if _, err := iss.Segment(NewMemory(), cp, 1, 0, CredentialJWT); !errors.Is(err, ErrNotFound) {
+			t.Errorf("missing records: %v", err)
+		}
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		if _, err := (CredentialIssuer{ID: iss.ID, Kid: "r", Signer: rsaKey}).Segment(s, cp, 1, 0, CredentialJSONLD); !errors.Is(err, sae.ErrUnsupportedAlg) {
+			t.Errorf("rsa json-ld: %v", err)
+		}
+	})
+}
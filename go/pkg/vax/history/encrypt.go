@@ -0,0 +1,275 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"vax/pkg/vax/sae"
+)
+
+// ErrDecrypt is returned when a sealed envelope cannot be opened: its KEK
+// is unknown to the Keyer, the caller may not unwrap it, or the data was
+// altered.
+var ErrDecrypt = errors.New("history: cannot decrypt record")
+
+// Keyer wraps and unwraps data keys with a key-encryption key, typically
+// held by a KMS or HSM. Implementations must be safe for concurrent use.
+type Keyer interface {
+	// WrapKey encrypts dek under the current KEK and returns that KEK's id.
+	WrapKey(dek []byte) (kekID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key wrapped under kekID.
+	UnwrapKey(kekID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyer is a Keyer over AES-256 KEKs held in memory: Current wraps
+// new keys and every KEK in Keys unwraps, so KEKs can be rotated without
+// re-encrypting the history. Prefer a KMS-backed Keyer in production.
+type LocalKeyer struct {
+	Current string
+	Keys    map[string][]byte // KEK id → 32-byte key
+}
+
+func (k LocalKeyer) WrapKey(dek []byte) (string, []byte, error) {
+	aead, err := k.aead(k.Current)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.Current, aead.Seal(nonce, nonce, dek, []byte(k.Current)), nil
+}
+
+func (k LocalKeyer) UnwrapKey(kekID string, wrapped []byte) ([]byte, error) {
+	aead, err := k.aead(kekID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(kekID))
+}
+
+func (k LocalKeyer) aead(kekID string) (cipher.AEAD, error) {
+	kek, ok := k.Keys[kekID]
+	if !ok || len(kek) != 32 {
+		return nil, fmt.Errorf("%w: no 32-byte KEK %q", ErrDecrypt, kekID)
+	}
+	return newGCM(kek)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealedMagic 開頭的 SAE 欄位為加密後的 envelope：
+// magic | sha256(canonical) 32 | kek id (u16 長度) | wrapped DEK (u16 長度) | nonce 12 | AES-256-GCM 密文
+var sealedMagic = []byte("VAXENC\x01")
+
+// sealedDigest 回傳加密 envelope 記錄的明文雜湊，讓沒有金鑰的 store 也能裁剪
+func sealedDigest(b []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(b, sealedMagic) || len(b) < len(sealedMagic)+sha256.Size {
+		return nil, false
+	}
+	return b[len(sealedMagic) : len(sealedMagic)+sha256.Size], true
+}
+
+// EncryptedStore encrypts envelopes at rest in another Store. Each
+// record's canonical SAE is sealed with a fresh AES-256-GCM data key,
+// wrapped by the Keyer's KEK and bound to the record's actor, counter and
+// SAI. Reads through the EncryptedStore decrypt transparently; the inner
+// store keeps counters, SAIs, prev SAIs and accept times in the clear, so
+// chains and Merkle proofs can be checked and pruning works without keys.
+type EncryptedStore struct {
+	inner Store
+	keys  Keyer
+}
+
+var (
+	_ ContextStore    = (*EncryptedStore)(nil)
+	_ Lister          = (*EncryptedStore)(nil)
+	_ Pruner          = (*EncryptedStore)(nil)
+	_ CheckpointStore = (*EncryptedStore)(nil)
+)
+
+// NewEncrypted panics if inner or keys is nil.
+func NewEncrypted(inner Store, keys Keyer) *EncryptedStore {
+	if inner == nil || keys == nil {
+		panic("history: NewEncrypted needs a store and a Keyer")
+	}
+	return &EncryptedStore{inner: inner, keys: keys}
+}
+
+func (e *EncryptedStore) Append(rec Record) error {
+	return e.AppendContext(context.Background(), rec)
+}
+
+func (e *EncryptedStore) AppendContext(ctx context.Context, rec Record) error {
+	if rec.Pruned() {
+		return AppendContext(ctx, e.inner, rec)
+	}
+	sealed, err := e.seal(rec)
+	if err != nil {
+		return err
+	}
+	rec.SAE = sealed
+	return AppendContext(ctx, e.inner, rec)
+}
+
+func (e *EncryptedStore) GetByCounter(actor string, counter uint64) (Record, error) {
+	return e.GetByCounterContext(context.Background(), actor, counter)
+}
+
+func (e *EncryptedStore) GetByCounterContext(ctx context.Context, actor string, counter uint64) (Record, error) {
+	rec, err := GetByCounterContext(ctx, e.inner, actor, counter)
+	if err != nil {
+		return Record{}, err
+	}
+	return e.open(rec)
+}
+
+func (e *EncryptedStore) Range(actor string, from, to uint64, fn func(Record) error) error {
+	return e.RangeContext(context.Background(), actor, from, to, fn)
+}
+
+func (e *EncryptedStore) RangeContext(ctx context.Context, actor string, from, to uint64, fn func(Record) error) error {
+	return RangeContext(ctx, e.inner, actor, from, to, func(rec Record) error {
+		rec, err := e.open(rec)
+		if err != nil {
+			return err
+		}
+		return fn(rec)
+	})
+}
+
+func (e *EncryptedStore) Head(actor string) (Record, error) {
+	return e.HeadContext(context.Background(), actor)
+}
+
+func (e *EncryptedStore) HeadContext(ctx context.Context, actor string) (Record, error) {
+	rec, err := HeadContext(ctx, e.inner, actor)
+	if err != nil {
+		return Record{}, err
+	}
+	return e.open(rec)
+}
+
+func (e *EncryptedStore) Actors() ([]string, error) {
+	l, ok := e.inner.(Lister)
+	if !ok {
+		return nil, errors.New("history: inner store cannot list actors")
+	}
+	return l.Actors()
+}
+
+func (e *EncryptedStore) Prune(actor string, from, to uint64) (int, error) {
+	p, ok := e.inner.(Pruner)
+	if !ok {
+		return 0, errors.New("history: inner store cannot prune")
+	}
+	return p.Prune(actor, from, to)
+}
+
+func (e *EncryptedStore) SaveCheckpoint(cp Checkpoint) error {
+	cs, ok := e.inner.(CheckpointStore)
+	if !ok {
+		return errors.New("history: store cannot save checkpoints")
+	}
+	return cs.SaveCheckpoint(cp)
+}
+
+func (e *EncryptedStore) LastCheckpoint(actor string) (Checkpoint, error) {
+	cs, ok := e.inner.(CheckpointStore)
+	if !ok {
+		return Checkpoint{}, ErrNotFound
+	}
+	return cs.LastCheckpoint(actor)
+}
+
+func (e *EncryptedStore) seal(rec Record) ([]byte, error) {
+	canonical, err := sae.Decompress(rec.SAE)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sae: %v", ErrInvalid, err)
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	kekID, wrapped, err := e.keys.WrapKey(dek)
+	if err != nil {
+		return nil, fmt.Errorf("history: wrap data key: %w", err)
+	}
+	if len(kekID) > math.MaxUint16 || len(wrapped) > math.MaxUint16 {
+		return nil, errors.New("history: wrapped data key too long")
+	}
+	sum := sha256.Sum256(canonical)
+	header := append(bytes.Clone(sealedMagic), sum[:]...)
+	header = appendString16(header, []byte(kekID))
+	header = appendString16(header, wrapped)
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, canonical, sealedAAD(header, rec)), nil
+}
+
+func (e *EncryptedStore) open(rec Record) (Record, error) {
+	if rec.Pruned() {
+		return rec, nil
+	}
+	digest, ok := sealedDigest(rec.SAE)
+	if !ok {
+		return Record{}, fmt.Errorf("%w: %s/%d is not sealed", ErrDecrypt, rec.Actor, rec.Counter)
+	}
+	r := frameReader{b: rec.SAE[len(sealedMagic)+sha256.Size:]}
+	kekID, wrapped := string(r.bytes16()), r.bytes16()
+	if r.err || len(r.b) < 12 {
+		return Record{}, fmt.Errorf("%w: %s/%d: malformed", ErrDecrypt, rec.Actor, rec.Counter)
+	}
+	header := rec.SAE[:len(rec.SAE)-len(r.b)]
+	dek, err := e.keys.UnwrapKey(kekID, wrapped)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: %s/%d: %v", ErrDecrypt, rec.Actor, rec.Counter, err)
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: %s/%d: %v", ErrDecrypt, rec.Actor, rec.Counter, err)
+	}
+	nonce, ciphertext := r.b[:aead.NonceSize()], r.b[aead.NonceSize():]
+	canonical, err := aead.Open(nil, nonce, ciphertext, sealedAAD(header, rec))
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: %s/%d: %v", ErrDecrypt, rec.Actor, rec.Counter, err)
+	}
+	if sum := sha256.Sum256(canonical); !bytes.Equal(sum[:], digest) {
+		return Record{}, fmt.Errorf("%w: %s/%d: digest mismatch", ErrDecrypt, rec.Actor, rec.Counter)
+	}
+	rec.SAE = canonical
+	return rec, nil
+}
+
+// sealedAAD 把密文綁定到 header 與 record 在鏈上的位置
+func sealedAAD(header []byte, rec Record) []byte {
+	aad := appendString16(bytes.Clone(header), []byte(rec.Actor))
+	aad = binary.BigEndian.AppendUint64(aad, rec.Counter)
+	return append(aad, rec.SAI...)
+}
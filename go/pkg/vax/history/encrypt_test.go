@@ -0,0 +1,82 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyer() LocalKeyer {
+	return LocalKeyer{Current: "kek-1", Keys: map[string][]byte{"kek-1": bytes.Repeat([]byte{1}, 32)}}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	t.Run("seals at rest and opens on read", func(t *testing.T) {
+		inner := NewMemory()
+		s := NewEncrypted(inner, testKeyer())
+		recs := envelopeChain(t, "alice", 3)
+		appendAll(t, s, recs)
+
+		raw, _ := inner.GetByCounter("alice", 2)
+		if bytes.Contains(raw.SAE, []byte("DE01")) || !bytes.Equal(raw.SAI, recs[1].SAI) {
+			t.Errorf("stored record = %q", raw.SAE)
+		}
+		rec, err := s.GetByCounter("alice", 2)
+		if err != nil || !bytes.Equal(rec.SAE, recs[1].SAE) {
+			t.Fatalf("GetByCounter = %q, %v", rec.SAE, err)
+		}
+		if _, err := VerifyChain(s, "alice", testGenesis); err != nil {
+			t.Error(err)
+		}
+		lines := exportLines(t, s, Filter{})
+		if len(lines) != 3 || !strings.Contains(lines[0], `"iban":"DE00"`) {
+			t.Errorf("export = %v", lines)
+		}
+	})
+
+	t.Run("rotated KEKs still open old records", func(t *testing.T) {
+		inner := NewMemory()
+		k := testKeyer()
+		appendAll(t, NewEncrypted(inner, k), envelopeChain(t, "alice", 1))
+		k.Keys["kek-2"], k.Current = bytes.Repeat([]byte{2}, 32), "kek-2"
+		s := NewEncrypted(inner, k)
+		appendAll(t, s, envelopeChain(t, "alice", 2)[1:])
+		if got := counters(t, s, "alice"); len(got) != 2 {
+			t.Errorf("counters = %v", got)
+		}
+	})
+
+	t.Run("pruning needs no keys", func(t *testing.T) {
+		inner := NewMemory()
+		appendAll(t, NewEncrypted(inner, testKeyer()), envelopeChain(t, "alice", 2))
+		if n, err := inner.Prune("alice", 1, 1); err != nil || n != 1 {
+			t.Fatalf("Prune = %d, %v", n, err)
+		}
+		if _, err := VerifyChain(inner, "alice", testGenesis); err == nil {
+			t.Error("sealed record 2 verified without keys")
+		}
+		if _, err := VerifyChain(NewEncrypted(inner, testKeyer()), "alice", testGenesis); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error: unknown KEK", func(t *testing.T) {
+		inner := NewMemory()
+		appendAll(t, NewEncrypted(inner, testKeyer()), envelopeChain(t, "alice", 1))
+		other := LocalKeyer{Current: "kek-9", Keys: map[string][]byte{"kek-9": make([]byte, 32)}}
+		if _, err := NewEncrypted(inner, other).Head("alice"); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("expected ErrDecrypt, got %v", err)
+		}
+	})
+
+	t.Run("error: ciphertext moved to another position", func(t *testing.T) {
+		inner := NewMemory()
+		s := NewEncrypted(inner, testKeyer())
+		appendAll(t, s, envelopeChain(t, "alice", 2))
+		inner.chains["alice"][1].SAE = inner.chains["alice"][0].SAE
+		if _, err := s.GetByCounter("alice", 2); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("expected ErrDecrypt, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// Format selects the output of Export.
+type Format string
+
+const (
+	// JSONL writes one VAX-JCS canonical Line per record.
+	JSONL Format = "jsonl"
+	// CSV writes a header and one summary row per record (no payloads).
+	CSV Format = "csv"
+)
+
+// ErrUnsupportedFormat is returned by Export for an unknown Format.
+var ErrUnsupportedFormat = errors.New("history: unsupported export format")
+
+// Lister is implemented by stores that can enumerate their actors; Export
+// uses it when the Filter names none. Memory, SQLStore and SegmentStore
+// implement it.
+type Lister interface {
+	Actors() ([]string, error)
+}
+
+// Filter selects the records Export writes.
+type Filter struct {
+	Actors      []string  // empty: every actor of a Lister store
+	From, To    uint64    // counter range, inclusive (To 0: to the head)
+	ActionTypes []string  // empty: all
+	Since       time.Time // accepted at or after (zero: no bound)
+	Until       time.Time // accepted before (zero: no bound)
+
+	// Redact, when set, masks SDTO values in JSONL output with sdto.Redact
+	// using the schema the registry holds for each envelope's action type
+	// and version (every value, if it has none).
+	Redact *sdto.Registry
+}
+
+// Line is one line of a JSONL export. Every line is VAX-JCS canonical, so
+// re-serializing a parsed line reproduces it byte for byte.
+//
+// SAE is the canonical envelope the SAI covers (a compressed transport
+// form is expanded) and SAESHA256 its digest, which still binds the chain
+// when the SDTO has been redacted: SAI = SHA256("VAX-SAI" || prev_sai ||
+// sae_sha256). For a pruned record only the digest is left.
+type Line struct {
+	Actor      string          `json:"actor"`
+	Counter    uint64          `json:"counter"`
+	ActionType string          `json:"action_type"`
+	PrevSAI    string          `json:"prev_sai"` // hex
+	SAI        string          `json:"sai"`      // hex
+	SAESHA256  string          `json:"sae_sha256"`
+	SAE        json.RawMessage `json:"sae"`
+	AcceptedAt int64           `json:"accepted_at"` // unix ms
+	Redacted   bool            `json:"redacted,omitempty"`
+	Pruned     bool            `json:"pruned,omitempty"` // sae is null
+}
+
+// csvHeader 為 CSV 摘要欄位
+var csvHeader = []string{"actor", "counter", "action_type", "accepted_at", "prev_sai", "sai", "sae_sha256"}
+
+// Export writes the records of s selected by f to w, actor by actor in
+// counter order, streaming them through Store.Range.
+func Export(w io.Writer, s Store, format Format, f Filter) error {
+	return ExportContext(context.Background(), w, s, format, f)
+}
+
+// ExportContext is Export with the scan bound to ctx (see RangeContext);
+// records already written stay written when ctx ends.
+func ExportContext(ctx context.Context, w io.Writer, s Store, format Format, f Filter) error {
+	if format != JSONL && format != CSV {
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	actors := f.Actors
+	if len(actors) == 0 {
+		l, ok := s.(Lister)
+		if !ok {
+			return errors.New("history: export needs Filter.Actors for a store that cannot list actors")
+		}
+		var err error
+		if actors, err = l.Actors(); err != nil {
+			return fmt.Errorf("history: export: %w", err)
+		}
+	}
+	to := f.To
+	if to == 0 {
+		to = ^uint64(0)
+	}
+
+	var cw *csv.Writer
+	if format == CSV {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, actor := range actors {
+		err := RangeContext(ctx, s, actor, f.From, to, func(rec Record) error {
+			if !f.match(rec) {
+				return nil
+			}
+			line, err := f.line(rec)
+			if err != nil {
+				return fmt.Errorf("history: export %s/%d: %w", rec.Actor, rec.Counter, err)
+			}
+			if cw != nil {
+				return cw.Write([]string{line.Actor, strconv.FormatUint(line.Counter, 10), line.ActionType,
+					time.UnixMilli(line.AcceptedAt).UTC().Format("2006-01-02T15:04:05.000Z"),
+					line.PrevSAI, line.SAI, line.SAESHA256})
+			}
+			b, err := jcs.Marshal(line)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(append(b, '\n'))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+func (f Filter) match(rec Record) bool {
+	at := time.UnixMilli(rec.AcceptedAt)
+	switch {
+	case len(f.ActionTypes) > 0 && !slices.Contains(f.ActionTypes, rec.ActionType):
+		return false
+	case !f.Since.IsZero() && at.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !at.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+func (f Filter) line(rec Record) (Line, error) {
+	if rec.Pruned() {
+		return Line{
+			Actor:      rec.Actor,
+			Counter:    rec.Counter,
+			ActionType: rec.ActionType,
+			PrevSAI:    hex.EncodeToString(rec.PrevSAI),
+			SAI:        hex.EncodeToString(rec.SAI),
+			SAESHA256:  hex.EncodeToString(rec.SAEHash),
+			SAE:        json.RawMessage("null"),
+			AcceptedAt: rec.AcceptedAt,
+			Pruned:     true,
+		}, nil
+	}
+	canonical, err := sae.Decompress(rec.SAE)
+	if err != nil {
+		return Line{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	line := Line{
+		Actor:      rec.Actor,
+		Counter:    rec.Counter,
+		ActionType: rec.ActionType,
+		PrevSAI:    hex.EncodeToString(rec.PrevSAI),
+		SAI:        hex.EncodeToString(rec.SAI),
+		SAESHA256:  hex.EncodeToString(sum[:]),
+		SAE:        canonical,
+		AcceptedAt: rec.AcceptedAt,
+	}
+	if f.Redact != nil {
+		redacted, ok, err := redactSAE(canonical, f.Redact)
+		if err != nil {
+			return Line{}, err
+		}
+		line.SAE, line.Redacted = redacted, ok
+	}
+	return line, nil
+}
+
+// redactSAE 遮蔽 sdto 中的值；sdto 不是 object（例如加密的 envelope）時原樣保留
+func redactSAE(canonical []byte, schemas *sdto.Registry) ([]byte, bool, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(canonical, &members); err != nil {
+		return nil, false, err
+	}
+	raw := members["sdto"]
+	if len(raw) == 0 || raw[0] != '{' {
+		return canonical, false, nil
+	}
+	var data map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, false, err
+	}
+	var actionType, version string
+	_ = json.Unmarshal(members["action_type"], &actionType)
+	_ = json.Unmarshal(members["schema_version"], &version)
+	schema, _ := schemas.Lookup(actionType, version) // 查無 schema 時全部遮蔽
+
+	masked, err := json.Marshal(sdto.Redact(data, schema))
+	if err != nil {
+		return nil, false, err
+	}
+	members["sdto"] = masked
+	out, err := jcs.Marshal(members)
+	return out, true, err
+}
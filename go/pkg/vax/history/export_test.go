@@ -0,0 +1,160 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+var testGenesis = bytes.Repeat([]byte{0x42}, vax.SAISize)
+
+// envelopeChain 產生以真正的 canonical SAE 與 SAI 相連的 record
+func envelopeChain(t *testing.T, actor string, n int) []Record {
+	t.Helper()
+	prev := testGenesis
+	recs := make([]Record, n)
+	for i := range recs {
+		b, err := jcs.Marshal(map[string]any{
+			"action_type": "transfer",
+			"timestamp":   1700000000000 + i,
+			"counter":     i + 1,
+			"prev_sai":    hex.EncodeToString(prev),
+			"sdto":        map[string]any{"amount": i + 1, "iban": fmt.Sprintf("DE%02d", i)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sai, _ := vax.ComputeSAI(prev, b)
+		recs[i] = Record{Actor: actor, Counter: uint64(i + 1), ActionType: "transfer", PrevSAI: prev, SAE: b, SAI: sai,
+			AcceptedAt: time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC).UnixMilli()}
+		prev = sai
+	}
+	return recs
+}
+
+func testSchemas() *sdto.Registry {
+	return sdto.NewRegistry().Register("transfer", "", sdto.NewSchemaBuilder().
+		SetActionNumberRange("amount", "0", "100").
+		SetActionStringLength("iban", "1", "34").SetSensitive("iban").MustBuildSchema())
+}
+
+func TestExport(t *testing.T) {
+	m := NewMemory()
+	appendAll(t, m, envelopeChain(t, "bob", 2))
+	appendAll(t, m, envelopeChain(t, "alice", 3))
+
+	t.Run("jsonl lines are canonical and bind the SAI", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, m, JSONL, Filter{}); err != nil {
+			t.Fatal(err)
+		}
+		sc := bufio.NewScanner(&buf)
+		var lines []Line
+		for sc.Scan() {
+			if !jcs.IsCanonical(sc.Bytes()) {
+				t.Errorf("line is not canonical: %s", sc.Bytes())
+			}
+			var l Line
+			if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(l.SAE)
+			prev, _ := hex.DecodeString(l.PrevSAI)
+			sai, _ := vax.ComputeSAI(prev, l.SAE)
+			if hex.EncodeToString(sum[:]) != l.SAESHA256 || hex.EncodeToString(sai) != l.SAI {
+				t.Errorf("%s/%d: digest does not bind the SAI", l.Actor, l.Counter)
+			}
+			lines = append(lines, l)
+		}
+		if len(lines) != 5 || lines[0].Actor != "alice" || lines[3].Actor != "bob" {
+			t.Errorf("unexpected order: %+v", lines)
+		}
+	})
+
+	t.Run("expands compressed envelopes", func(t *testing.T) {
+		rec := envelopeChain(t, "carol", 1)[0]
+		canonical := rec.SAE
+		rec.SAE, _ = sae.Compress(rec.SAE)
+		c := NewMemory()
+		appendAll(t, c, []Record{rec})
+		var buf bytes.Buffer
+		_ = Export(&buf, c, JSONL, Filter{})
+		var l Line
+		_ = json.Unmarshal(buf.Bytes(), &l)
+		if !bytes.Equal(l.SAE, canonical) {
+			t.Errorf("sae = %s", l.SAE)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := Filter{Actors: []string{"alice"}, From: 2, Since: time.Date(2026, 1, 1, 0, 0, 2, 0, time.UTC)}
+		if err := Export(&buf, m, JSONL, f); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(buf.String(), "\n"); n != 1 {
+			t.Errorf("expected 1 line, got %d", n)
+		}
+		buf.Reset()
+		_ = Export(&buf, m, JSONL, Filter{ActionTypes: []string{"login"}})
+		if buf.Len() != 0 {
+			t.Errorf("expected no lines, got %s", buf.String())
+		}
+	})
+
+	t.Run("redacts sensitive fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, m, JSONL, Filter{Actors: []string{"alice"}, To: 1, Redact: testSchemas()}); err != nil {
+			t.Fatal(err)
+		}
+		var l Line
+		_ = json.Unmarshal(buf.Bytes(), &l)
+		if !l.Redacted || !strings.Contains(string(l.SAE), `"iban":"[REDACTED]"`) || !strings.Contains(string(l.SAE), `"amount":1`) {
+			t.Errorf("unexpected line %s", buf.Bytes())
+		}
+		if l.SAESHA256 != hex.EncodeToString(sha256Sum(envelopeChain(t, "alice", 1)[0].SAE)) {
+			t.Error("digest must cover the original envelope")
+		}
+	})
+
+	t.Run("csv summary", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, m, CSV, Filter{Actors: []string{"bob"}}); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil || len(rows) != 3 || rows[0][0] != "actor" || rows[2][1] != "2" || rows[1][3] != "2026-01-01T00:00:00.000Z" {
+			t.Errorf("rows = %v, %v", rows, err)
+		}
+	})
+
+	t.Run("error: unknown format", func(t *testing.T) {
+		if err := Export(&bytes.Buffer{}, m, "xml", Filter{}); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+		}
+	})
+
+	t.Run("error: store without actor listing", func(t *testing.T) {
+		if err := Export(&bytes.Buffer{}, struct{ Store }{m}, JSONL, Filter{}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
@@ -0,0 +1,181 @@
+// Package history keeps the accepted actions of every actor's chain. The
+// verifier checks an action once; a Store keeps the exact (counter, SAE,
+// SAI) it accepted so the chain can be audited, exported and replayed later.
+package history
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Error codes
+var (
+	ErrNotFound   = errors.New("history: record not found")
+	ErrOutOfOrder = errors.New("history: record does not extend the actor's chain")
+	ErrInvalid    = errors.New("history: invalid record")
+)
+
+// Record is one accepted action.
+type Record struct {
+	Actor      string
+	Counter    uint64 // chain position (1 = first action after genesis)
+	ActionType string
+	PrevSAI    []byte // chain head the action was built on
+	SAE        []byte // the exact envelope bytes the SAI covers
+	SAI        []byte
+	AcceptedAt int64 // unix ms
+	// SAEHash is the SHA-256 of the canonical envelope once retention has
+	// pruned it (SAE is then nil); the SAI still verifies against it.
+	SAEHash []byte
+}
+
+// Pruned reports whether the record's envelope body has been pruned.
+func (r Record) Pruned() bool { return len(r.SAEHash) > 0 }
+
+// Store is an append-only log of accepted actions, one chain per actor.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Append adds the next record of rec.Actor: its counter must follow the
+	// actor's head (1 for a new actor) and its PrevSAI must be the head's
+	// SAI, otherwise ErrOutOfOrder.
+	Append(rec Record) error
+	// GetByCounter returns one record (ErrNotFound if absent).
+	GetByCounter(actor string, counter uint64) (Record, error)
+	// Range calls fn for the actor's records with from <= counter <= to in
+	// counter order, stopping at the first error fn returns.
+	Range(actor string, from, to uint64, fn func(Record) error) error
+	// Head returns the actor's latest record (ErrNotFound if none).
+	Head(actor string) (Record, error)
+}
+
+// CheckNext reports whether rec may follow head (nil for an actor without
+// records); it is the ordering rule of Store.Append.
+func CheckNext(head *Record, rec Record) error {
+	if rec.Actor == "" || (len(rec.SAE) == 0 && !rec.Pruned()) || len(rec.SAI) == 0 {
+		return fmt.Errorf("%w: actor, sae and sai are required", ErrInvalid)
+	}
+	if rec.Pruned() && (len(rec.SAE) != 0 || len(rec.SAEHash) != sha256.Size) {
+		return fmt.Errorf("%w: a pruned record has a SHA-256 sae hash and no sae", ErrInvalid)
+	}
+	if head == nil {
+		if rec.Counter != 1 {
+			return fmt.Errorf("%w: %s starts at counter %d, want 1", ErrOutOfOrder, rec.Actor, rec.Counter)
+		}
+		return nil
+	}
+	if rec.Counter != head.Counter+1 {
+		return fmt.Errorf("%w: %s counter %d after %d", ErrOutOfOrder, rec.Actor, rec.Counter, head.Counter)
+	}
+	if !bytes.Equal(rec.PrevSAI, head.SAI) {
+		return fmt.Errorf("%w: %s counter %d prev_sai is not the head SAI", ErrOutOfOrder, rec.Actor, rec.Counter)
+	}
+	return nil
+}
+
+// Memory is an in-memory Store (tests, single-process servers).
+type Memory struct {
+	mu     sync.RWMutex
+	chains map[string][]Record // chains[actor][i].Counter == i+1
+	cps    map[string]Checkpoint
+}
+
+func NewMemory() *Memory {
+	return &Memory{chains: make(map[string][]Record), cps: make(map[string]Checkpoint)}
+}
+
+func (m *Memory) Append(rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chain := m.chains[rec.Actor]
+	var head *Record
+	if len(chain) > 0 {
+		head = &chain[len(chain)-1]
+	}
+	if err := CheckNext(head, rec); err != nil {
+		return err
+	}
+	m.chains[rec.Actor] = append(chain, clone(rec))
+	return nil
+}
+
+func (m *Memory) GetByCounter(actor string, counter uint64) (Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chain := m.chains[actor]
+	if counter == 0 || counter > uint64(len(chain)) {
+		return Record{}, ErrNotFound
+	}
+	return clone(chain[counter-1]), nil
+}
+
+func (m *Memory) Range(actor string, from, to uint64, fn func(Record) error) error {
+	// 先複製再回呼，fn 內可再呼叫 Store 而不會死鎖
+	m.mu.RLock()
+	chain := m.chains[actor]
+	from = max(from, 1)
+	to = min(to, uint64(len(chain)))
+	var recs []Record
+	if from <= to {
+		recs = chain[from-1 : to]
+	}
+	m.mu.RUnlock()
+
+	for _, rec := range recs {
+		if err := fn(clone(rec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Head(actor string) (Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chain := m.chains[actor]
+	if len(chain) == 0 {
+		return Record{}, ErrNotFound
+	}
+	return clone(chain[len(chain)-1]), nil
+}
+
+// Actors lists the actors with records (sorted).
+func (m *Memory) Actors() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	actors := make([]string, 0, len(m.chains))
+	for actor := range m.chains {
+		actors = append(actors, actor)
+	}
+	sort.Strings(actors)
+	return actors, nil
+}
+
+var _ CheckpointStore = (*Memory)(nil)
+
+func (m *Memory) SaveCheckpoint(cp Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if last, ok := m.cps[cp.Actor]; !ok || cp.Counter >= last.Counter {
+		m.cps[cp.Actor] = cloneCheckpoint(cp)
+	}
+	return nil
+}
+
+func (m *Memory) LastCheckpoint(actor string) (Checkpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cp, ok := m.cps[actor]
+	if !ok {
+		return Checkpoint{}, ErrNotFound
+	}
+	return cloneCheckpoint(cp), nil
+}
+
+func clone(r Record) Record {
+	r.PrevSAI, r.SAE, r.SAI, r.SAEHash = bytes.Clone(r.PrevSAI), bytes.Clone(r.SAE), bytes.Clone(r.SAI), bytes.Clone(r.SAEHash)
+	return r
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// chain 產生 actor 的 n 筆相連 record（SAI 僅需相異）
+func chain(actor string, n int) []Record {
+	prev := bytes.Repeat([]byte{0}, 32)
+	recs := make([]Record, n)
+	for i := range recs {
+		sai := bytes.Repeat([]byte{byte(i + 1)}, 32)
+		recs[i] = Record{Actor: actor, Counter: uint64(i + 1), ActionType: "transfer", PrevSAI: prev, SAE: []byte(`{"n":1}`), SAI: sai}
+		prev = sai
+	}
+	return recs
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	for _, rec := range append(chain("alice", 5), chain("bob", 2)...) {
+		if err := m.Append(rec); err != nil {
+			t.Fatalf("Append %s/%d: %v", rec.Actor, rec.Counter, err)
+		}
+	}
+
+	t.Run("head and lookup", func(t *testing.T) {
+		head, err := m.Head("alice")
+		if err != nil || head.Counter != 5 {
+			t.Fatalf("Head = %+v, %v", head, err)
+		}
+		rec, err := m.GetByCounter("bob", 2)
+		if err != nil || !bytes.Equal(rec.SAI, chain("bob", 2)[1].SAI) {
+			t.Errorf("GetByCounter = %+v, %v", rec, err)
+		}
+	})
+
+	t.Run("range is inclusive and clamped", func(t *testing.T) {
+		var got []uint64
+		_ = m.Range("alice", 2, 100, func(r Record) error {
+			got = append(got, r.Counter)
+			return nil
+		})
+		if len(got) != 4 || got[0] != 2 || got[3] != 5 {
+			t.Errorf("Range = %v", got)
+		}
+	})
+
+	t.Run("range stops at callback error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := m.Range("alice", 0, 5, func(Record) error {
+			calls++
+			return stop
+		})
+		if err != stop || calls != 1 {
+			t.Errorf("err = %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("records are copies", func(t *testing.T) {
+		rec, _ := m.GetByCounter("alice", 1)
+		rec.SAI[0] = 0xff
+		again, _ := m.GetByCounter("alice", 1)
+		if again.SAI[0] == 0xff {
+			t.Error("stored record was modified through a returned copy")
+		}
+	})
+
+	t.Run("error: unknown records", func(t *testing.T) {
+		if _, err := m.Head("carol"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Head: %v", err)
+		}
+		for _, c := range []uint64{0, 6} {
+			if _, err := m.GetByCounter("alice", c); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByCounter(%d): %v", c, err)
+			}
+		}
+	})
+
+	t.Run("error: out of order", func(t *testing.T) {
+		recs := chain("alice", 7)
+		for _, rec := range []Record{recs[4], recs[6], chain("carol", 2)[1]} {
+			if err := m.Append(rec); !errors.Is(err, ErrOutOfOrder) {
+				t.Errorf("Append %s/%d: %v", rec.Actor, rec.Counter, err)
+			}
+		}
+		fork := recs[5]
+		fork.PrevSAI = recs[3].SAI
+		if err := m.Append(fork); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("fork: %v", err)
+		}
+	})
+
+	t.Run("error: incomplete record", func(t *testing.T) {
+		if err := m.Append(Record{Actor: "dave", Counter: 1}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// ErrDiscrepancy is returned by ImportAndVerify when any line fails
+// verification; the report lists them.
+var ErrDiscrepancy = errors.New("history: import found discrepancies")
+
+// GenesisFunc returns the genesis SAI of an actor's chain.
+type GenesisFunc func(actor string) ([]byte, error)
+
+// Discrepancy is one line of an import that did not verify.
+type Discrepancy struct {
+	Line    int // 1-based line number in the input
+	Actor   string
+	Counter uint64
+	Err     error
+}
+
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("line %d (%s/%d): %v", d.Line, d.Actor, d.Counter, d.Err)
+}
+
+// ImportReport summarizes an ImportAndVerify run.
+type ImportReport struct {
+	Lines         int // records read
+	Verified      int // records whose chain link verified
+	Imported      int // records appended to dst
+	Redacted      int // verified through sae_sha256 only, never imported
+	Pruned        int // verified through sae_sha256 only, imported pruned
+	Discrepancies []Discrepancy
+}
+
+// ImportAndVerify reads a JSONL export (see Export) and re-verifies every
+// actor's chain as it goes: counters are consecutive, each prev_sai is the
+// previous SAI (the genesis SAI for counter 1), sae_sha256 is the digest of
+// sae, and SAI = SHA256("VAX-SAI" || prev_sai || sae_sha256). Unredacted
+// envelopes must carry the matching in-band chain binding and, with keys,
+// a valid signature. Pruned lines verify through sae_sha256 and are
+// imported as pruned records. Lines must be VAX-JCS canonical, as Export writes them.
+//
+// With a nil genesis each actor's chain is anchored at its first line
+// (partial exports); otherwise it must start at counter 1. Verified records
+// are appended to dst when it is not nil, until an actor's first
+// discrepancy; redacted records are never imported. Discrepancies do not
+// stop the run: they are reported with their line numbers and the call
+// returns ErrDiscrepancy. Other errors (reading r, appending to dst) stop it.
+func ImportAndVerify(r io.Reader, genesis GenesisFunc, keys sae.KeyResolver, dst Store) (*ImportReport, error) {
+	type chainState struct {
+		state      vax.ChainState
+		genesisErr error
+		tainted    bool // 已有 discrepancy，後續不再匯入
+	}
+	report := &ImportReport{}
+	chains := map[string]*chainState{}
+	br := bufio.NewReader(r)
+
+	for n := 1; ; n++ {
+		raw, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return report, fmt.Errorf("history: import: %w", err)
+		}
+		raw = bytes.TrimSuffix(raw, []byte("\n"))
+		if len(raw) == 0 {
+			if err == io.EOF {
+				break
+			}
+			continue
+		}
+		report.Lines++
+
+		var line Line
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if derr := dec.Decode(&line); derr != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{Line: n, Err: fmt.Errorf("%w: %v", ErrInvalid, derr)})
+			if err == io.EOF {
+				break
+			}
+			continue
+		}
+
+		c := chains[line.Actor]
+		if c == nil {
+			c = &chainState{}
+			chains[line.Actor] = c
+			if genesis == nil {
+				// 以第一行為錨點
+				prev, _ := hex.DecodeString(line.PrevSAI)
+				c.state = vax.ChainState{Counter: line.Counter - 1, HeadSAI: prev}
+			} else {
+				var gerr error
+				if c.state.HeadSAI, gerr = genesis(line.Actor); gerr != nil {
+					c.genesisErr = fmt.Errorf("genesis: %w", gerr)
+				}
+			}
+		}
+
+		verr := c.genesisErr
+		if verr == nil {
+			verr = verifyLine(raw, line, c.state, keys)
+		}
+		if verr != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{Line: n, Actor: line.Actor, Counter: line.Counter, Err: verr})
+			c.tainted = true
+		} else {
+			report.Verified++
+		}
+		if line.Redacted {
+			report.Redacted++
+		}
+		if line.Pruned {
+			report.Pruned++
+		}
+
+		sai, _ := hex.DecodeString(line.SAI)
+		if verr == nil && !line.Redacted && !c.tainted && dst != nil {
+			rec := Record{Actor: line.Actor, Counter: line.Counter, ActionType: line.ActionType,
+				PrevSAI: c.state.HeadSAI, SAE: line.SAE, SAI: sai, AcceptedAt: line.AcceptedAt}
+			if line.Pruned {
+				rec.SAE, rec.SAEHash = nil, digestOf(line)
+			}
+			if err := dst.Append(rec); err != nil {
+				return report, fmt.Errorf("history: import line %d: %w", n, err)
+			}
+			report.Imported++
+		}
+		// 以宣稱的 SAI 繼續，單一竄改只在該行（與下一行的 prev_sai）出現
+		c.state = vax.ChainState{Counter: line.Counter, HeadSAI: sai}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(report.Discrepancies) > 0 {
+		return report, fmt.Errorf("%w: %d of %d records", ErrDiscrepancy, len(report.Discrepancies), report.Lines)
+	}
+	return report, nil
+}
+
+func digestOf(line Line) []byte {
+	b, _ := hex.DecodeString(line.SAESHA256)
+	return b
+}
+
+// verifyLine 檢查一行與前一個狀態的連結
+func verifyLine(raw []byte, line Line, state vax.ChainState, keys sae.KeyResolver) error {
+	if len(state.HeadSAI) != vax.SAISize {
+		return fmt.Errorf("%w: no genesis SAI for %s", ErrInvalid, line.Actor)
+	}
+	if !jcs.IsCanonical(raw) {
+		return fmt.Errorf("%w: line is not VAX-JCS canonical", ErrInvalid)
+	}
+	if line.Counter == 0 || line.Counter != state.Counter+1 {
+		return fmt.Errorf("%w: counter %d after %d", vax.ErrInvalidCounter, line.Counter, state.Counter)
+	}
+	if line.PrevSAI != hex.EncodeToString(state.HeadSAI) {
+		return fmt.Errorf("%w: prev_sai is not the previous SAI", vax.ErrInvalidPrevSAI)
+	}
+	digest, err := hex.DecodeString(line.SAESHA256)
+	if err != nil {
+		return fmt.Errorf("%w: sae_sha256: %v", ErrInvalid, err)
+	}
+	if line.Pruned && string(line.SAE) != "null" {
+		return fmt.Errorf("%w: pruned line carries an sae", ErrInvalid)
+	}
+	if !line.Redacted && !line.Pruned {
+		if sum := sha256.Sum256(line.SAE); !bytes.Equal(sum[:], digest) {
+			return fmt.Errorf("%w: sae does not hash to sae_sha256", vax.ErrSAIMismatch)
+		}
+	}
+	sai, err := vax.ComputeSAIFromDigest(state.HeadSAI, digest)
+	if err != nil {
+		return fmt.Errorf("%w: sae_sha256 is not a SHA-256 digest", ErrInvalid)
+	}
+	if line.SAI != hex.EncodeToString(sai) {
+		return fmt.Errorf("%w: sai does not chain prev_sai and sae_sha256", vax.ErrSAIMismatch)
+	}
+	if line.Redacted || line.Pruned {
+		return nil
+	}
+	return verifyEnvelope(line.SAE, line.ActionType, state, keys)
+}
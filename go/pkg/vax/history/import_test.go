@@ -0,0 +1,132 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+func genesisOf(actor string) ([]byte, error) {
+	if actor == "mallory" {
+		return nil, errors.New("no such actor")
+	}
+	return testGenesis, nil
+}
+
+func exportLines(t *testing.T, s Store, f Filter) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Export(&buf, s, JSONL, f); err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestImportAndVerify(t *testing.T) {
+	src := NewMemory()
+	appendAll(t, src, envelopeChain(t, "alice", 4))
+	appendAll(t, src, envelopeChain(t, "bob", 2))
+	lines := exportLines(t, src, Filter{})
+
+	t.Run("rebuilds a replica", func(t *testing.T) {
+		dst := NewMemory()
+		report, err := ImportAndVerify(strings.NewReader(strings.Join(lines, "")), genesisOf, nil, dst)
+		if err != nil || report.Lines != 6 || report.Imported != 6 {
+			t.Fatalf("report = %+v, %v", report, err)
+		}
+		want, _ := src.Head("alice")
+		got, _ := dst.Head("alice")
+		if !bytes.Equal(got.SAI, want.SAI) || !bytes.Equal(got.SAE, want.SAE) || got.AcceptedAt != want.AcceptedAt {
+			t.Errorf("head = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("verifies signatures", func(t *testing.T) {
+		pub, priv, _ := sae.GenerateKeyPair()
+		s := NewMemory()
+		state := vax.ChainState{HeadSAI: testGenesis}
+		for i := 1; i <= 2; i++ {
+			env := sae.NewEnvelope("transfer", map[string]any{"amount": i},
+				sae.WithTimestamp(time.UnixMilli(1700000000000)), sae.WithChain(state.Counter+1, state.HeadSAI))
+			env.Kid = "k1"
+			if err := env.Sign(priv); err != nil {
+				t.Fatal(err)
+			}
+			b, _ := jcs.Marshal(env)
+			sai, _ := vax.ComputeSAI(state.HeadSAI, b)
+			appendAll(t, s, []Record{{Actor: "alice", Counter: uint64(i), ActionType: "transfer", PrevSAI: state.HeadSAI, SAE: b, SAI: sai}})
+			state = state.Advance(sai)
+		}
+		in := strings.Join(exportLines(t, s, Filter{}), "")
+		if report, err := ImportAndVerify(strings.NewReader(in), genesisOf, sae.StaticResolver{"k1": pub}, nil); err != nil {
+			t.Errorf("report = %+v, %v", report, err)
+		}
+		other, _, _ := sae.GenerateKeyPair()
+		report, err := ImportAndVerify(strings.NewReader(in), genesisOf, sae.StaticResolver{"k1": other}, nil)
+		if !errors.Is(err, ErrDiscrepancy) || len(report.Discrepancies) != 2 || !errors.Is(report.Discrepancies[0].Err, sae.ErrInvalidSignature) {
+			t.Errorf("report = %+v, %v", report, err)
+		}
+	})
+
+	t.Run("redacted exports verify without importing", func(t *testing.T) {
+		redacted := exportLines(t, src, Filter{Redact: testSchemas()})
+		report, err := ImportAndVerify(strings.NewReader(strings.Join(redacted, "")), genesisOf, nil, NewMemory())
+		if err != nil || report.Verified != 6 || report.Redacted != 6 || report.Imported != 0 {
+			t.Errorf("report = %+v, %v", report, err)
+		}
+	})
+
+	t.Run("partial exports anchor at their first line", func(t *testing.T) {
+		tail := exportLines(t, src, Filter{Actors: []string{"alice"}, From: 3})
+		if report, err := ImportAndVerify(strings.NewReader(strings.Join(tail, "")), nil, nil, nil); err != nil || report.Verified != 2 {
+			t.Errorf("report = %+v, %v", report, err)
+		}
+		if _, err := ImportAndVerify(strings.NewReader(strings.Join(tail, "")), genesisOf, nil, nil); !errors.Is(err, ErrDiscrepancy) {
+			t.Errorf("expected ErrDiscrepancy with genesis, got %v", err)
+		}
+	})
+
+	t.Run("error: altered payload", func(t *testing.T) {
+		altered := append([]string(nil), lines...)
+		altered[1] = strings.Replace(altered[1], `"amount":2`, `"amount":9`, 1)
+		dst := NewMemory()
+		report, err := ImportAndVerify(strings.NewReader(strings.Join(altered, "")), genesisOf, nil, dst)
+		if !errors.Is(err, ErrDiscrepancy) || len(report.Discrepancies) != 1 {
+			t.Fatalf("report = %+v, %v", report, err)
+		}
+		d := report.Discrepancies[0]
+		if d.Line != 2 || d.Actor != "alice" || d.Counter != 2 || !errors.Is(d.Err, vax.ErrSAIMismatch) {
+			t.Errorf("discrepancy = %v", d)
+		}
+		// alice 停在竄改前，bob 不受影響
+		if head, _ := dst.Head("alice"); head.Counter != 1 {
+			t.Errorf("alice imported up to %d", head.Counter)
+		}
+		if head, _ := dst.Head("bob"); head.Counter != 2 {
+			t.Errorf("bob imported up to %d", head.Counter)
+		}
+	})
+
+	t.Run("error: missing and reordered records", func(t *testing.T) {
+		in := lines[0] + lines[2] + lines[1] + lines[3]
+		report, _ := ImportAndVerify(strings.NewReader(in), genesisOf, nil, nil)
+		if len(report.Discrepancies) != 3 || !errors.Is(report.Discrepancies[0].Err, vax.ErrInvalidCounter) || report.Discrepancies[0].Line != 2 {
+			t.Errorf("discrepancies = %v", report.Discrepancies)
+		}
+	})
+
+	t.Run("error: malformed, non-canonical and unknown actors", func(t *testing.T) {
+		spaced := strings.Replace(lines[0], `"actor":"alice",`, `"actor": "alice",`, 1)
+		in := "not json\n" + spaced + strings.ReplaceAll(lines[4], "bob", "mallory")
+		report, _ := ImportAndVerify(strings.NewReader(in), genesisOf, nil, nil)
+		if len(report.Discrepancies) != 3 || report.Discrepancies[2].Line != 3 {
+			t.Errorf("discrepancies = %v", report.Discrepancies)
+		}
+	})
+}
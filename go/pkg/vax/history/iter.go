@@ -0,0 +1,80 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"vax/pkg/vax"
+)
+
+// errStop 由 yield 回傳 false 時中止 Range，不對外回報
+var errStop = errors.New("history: iteration stopped")
+
+// All returns the actor's records with from <= counter <= to, in counter
+// order, as an iterator over Store.Range: records are read as the loop
+// advances, so a scan of any length runs in constant memory, and breaking
+// out of the loop stops the scan. A read error is yielded once, with a
+// zero Record, and ends the sequence.
+//
+//	for rec, err := range history.All(s, actor, 1, math.MaxUint64) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func All(s Store, actor string, from, to uint64) iter.Seq2[Record, error] {
+	return AllContext(context.Background(), s, actor, from, to)
+}
+
+// AllContext is All with the scan bound to ctx (see RangeContext).
+func AllContext(ctx context.Context, s Store, actor string, from, to uint64) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		err := RangeContext(ctx, s, actor, from, to, func(rec Record) error {
+			if !yield(rec, nil) {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStop) {
+			yield(Record{}, err)
+		}
+	}
+}
+
+// Verified passes records through the checks of VerifyChain as they are
+// consumed, starting after start (ChainState{HeadSAI: genesis} for a whole
+// chain, or a checkpoint's counter and SAI): each record is yielded once
+// it extends the chain verified so far. The first record that does not is
+// yielded with a *ChainError, and an error from records is passed on;
+// either ends the sequence. records can come from All, an import or any
+// other stream. Only WithKeys applies.
+func Verified(records iter.Seq2[Record, error], start vax.ChainState, opts ...VerifyOption) iter.Seq2[Record, error] {
+	cfg := newVerifyConfig(opts)
+	return func(yield func(Record, error) bool) {
+		state := start
+		for rec, err := range records {
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			if err := verifyRecord(rec, state, cfg.keys); err != nil {
+				yield(rec, &ChainError{Actor: rec.Actor, Counter: rec.Counter, Err: err})
+				return
+			}
+			state = state.Advance(rec.SAI)
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// VerifyAll is the iterator form of VerifyChain: it yields the actor's
+// records from genesis as they verify, for audits that also process each
+// record. Breaking out of the loop stops the scan; WithKeys and
+// WithContext apply, and no checkpoint is produced.
+func VerifyAll(s Store, actor string, genesis []byte, opts ...VerifyOption) iter.Seq2[Record, error] {
+	cfg := newVerifyConfig(opts)
+	return Verified(AllContext(cfg.ctx, s, actor, 1, ^uint64(0)), vax.ChainState{HeadSAI: genesis}, opts...)
+}
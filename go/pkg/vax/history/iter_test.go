@@ -0,0 +1,128 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/sae"
+)
+
+func TestIter(t *testing.T) {
+	s := NewMemory()
+	recs := envelopeChain(t, "alice", 5)
+	appendAll(t, s, recs)
+
+	t.Run("All yields the range in order", func(t *testing.T) {
+		var got []uint64
+		for rec, err := range All(s, "alice", 2, 4) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, rec.Counter)
+		}
+		if len(got) != 3 || got[0] != 2 || got[2] != 4 {
+			t.Errorf("counters = %v", got)
+		}
+	})
+
+	t.Run("break stops the scan", func(t *testing.T) {
+		n := 0
+		for range All(s, "alice", 1, math.MaxUint64) {
+			if n++; n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("read %d records", n)
+		}
+	})
+
+	t.Run("VerifyAll and Verified from a checkpoint", func(t *testing.T) {
+		n := 0
+		for _, err := range VerifyAll(s, "alice", testGenesis) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			n++
+		}
+		if n != 5 {
+			t.Errorf("verified %d records", n)
+		}
+
+		start := vax.ChainState{Counter: 2, HeadSAI: recs[1].SAI}
+		var last Record
+		for rec, err := range Verified(All(s, "alice", 3, math.MaxUint64), start) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			last = rec
+		}
+		if last.Counter != 5 {
+			t.Errorf("last = %d", last.Counter)
+		}
+	})
+
+	t.Run("signatures with WithKeys", func(t *testing.T) {
+		pub, priv, _ := sae.GenerateKeyPair()
+		signed := NewMemory()
+		signedChain(t, signed, "bob", 3, priv)
+		for _, err := range VerifyAll(signed, "bob", testGenesis, WithKeys(sae.StaticResolver{"k1": pub})) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		other, _, _ := sae.GenerateKeyPair()
+		var failed error
+		for _, err := range VerifyAll(signed, "bob", testGenesis, WithKeys(sae.StaticResolver{"k1": other})) {
+			failed = err
+		}
+		var ce *ChainError
+		if !errors.As(failed, &ce) || ce.Counter != 1 || !errors.Is(failed, sae.ErrInvalidSignature) {
+			t.Errorf("got %v", failed)
+		}
+	})
+
+	t.Run("error: a broken record ends the sequence", func(t *testing.T) {
+		bad := make([]Record, len(recs))
+		copy(bad, recs)
+		bad[2].SAI = bad[1].SAI
+		source := func(yield func(Record, error) bool) {
+			for _, rec := range bad {
+				if !yield(rec, nil) {
+					return
+				}
+			}
+		}
+		var counters []uint64
+		var failed error
+		for rec, err := range Verified(source, vax.ChainState{HeadSAI: testGenesis}) {
+			if err != nil {
+				failed = err
+				break
+			}
+			counters = append(counters, rec.Counter)
+		}
+		var ce *ChainError
+		if len(counters) != 2 || !errors.As(failed, &ce) || ce.Counter != 3 || !errors.Is(failed, vax.ErrSAIMismatch) {
+			t.Errorf("counters %v, err %v", counters, failed)
+		}
+	})
+
+	t.Run("error: read errors are yielded once", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		n := 0
+		for rec, err := range AllContext(ctx, s, "alice", 1, math.MaxUint64) {
+			n++
+			if !errors.Is(err, context.Canceled) || rec.Counter != 0 {
+				t.Errorf("got %+v, %v", rec, err)
+			}
+		}
+		if n != 1 {
+			t.Errorf("yielded %d times", n)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package history
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+)
+
+// Merkle trees over an actor's SAIs follow RFC 6962 §2.1: leaf i is
+// SHA256(0x00 || SAI_i), an interior node SHA256(0x01 || left || right),
+// and a tree of n leaves splits at the largest power of two below n.
+
+// ErrInvalidProof is returned by VerifyInclusion for a proof that does not
+// lead to the root.
+var ErrInvalidProof = errors.New("history: invalid Merkle proof")
+
+func leafHash(sai []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(sai)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Frontier is the compact state of a growing Merkle tree: the roots of its
+// perfect subtrees, largest first (one per set bit of the leaf count). It
+// extends the tree leaf by leaf without keeping the leaves.
+type Frontier struct {
+	Size  uint64   `json:"size"`
+	Nodes [][]byte `json:"nodes"`
+}
+
+// Push appends the leaf for sai.
+func (f *Frontier) Push(sai []byte) {
+	h := leafHash(sai)
+	// 每個結尾的 1 bit 代表一棵同大小的子樹，與新節點合併
+	for n := f.Size; n&1 == 1; n >>= 1 {
+		h = nodeHash(f.Nodes[len(f.Nodes)-1], h)
+		f.Nodes = f.Nodes[:len(f.Nodes)-1]
+	}
+	f.Nodes = append(f.Nodes, h)
+	f.Size++
+}
+
+// Root returns the tree's root hash (SHA256("") for an empty tree).
+func (f Frontier) Root() []byte {
+	if len(f.Nodes) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	root := f.Nodes[len(f.Nodes)-1]
+	for i := len(f.Nodes) - 2; i >= 0; i-- {
+		root = nodeHash(f.Nodes[i], root)
+	}
+	return root
+}
+
+func (f Frontier) clone() Frontier {
+	nodes := make([][]byte, len(f.Nodes))
+	for i, n := range f.Nodes {
+		nodes[i] = bytes.Clone(n)
+	}
+	return Frontier{Size: f.Size, Nodes: nodes}
+}
+
+// MerkleRoot returns the root over the actor's SAIs 1..size.
+func MerkleRoot(s Store, actor string, size uint64) ([]byte, error) {
+	var f Frontier
+	err := s.Range(actor, 1, size, func(rec Record) error {
+		f.Push(rec.SAI)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if f.Size != size {
+		return nil, ErrNotFound
+	}
+	return f.Root(), nil
+}
+
+// InclusionProof returns the audit path (RFC 6962 §2.1.1) proving that the
+// SAI at counter is leaf counter-1 of the tree over SAIs 1..size. It reads
+// the actor's first size records.
+func InclusionProof(s Store, actor string, counter, size uint64) ([][]byte, error) {
+	if counter == 0 || counter > size {
+		return nil, ErrNotFound
+	}
+	leaves := make([][]byte, 0, size)
+	err := s.Range(actor, 1, size, func(rec Record) error {
+		leaves = append(leaves, leafHash(rec.SAI))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(leaves)) != size {
+		return nil, ErrNotFound
+	}
+	return auditPath(leaves, counter-1), nil
+}
+
+func auditPath(leaves [][]byte, m uint64) [][]byte {
+	n := uint64(len(leaves))
+	if n <= 1 {
+		return nil
+	}
+	k := splitPoint(n)
+	if m < k {
+		return append(auditPath(leaves[:k], m), subtreeRoot(leaves[k:]))
+	}
+	return append(auditPath(leaves[k:], m-k), subtreeRoot(leaves[:k]))
+}
+
+func subtreeRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := splitPoint(uint64(len(leaves)))
+	return nodeHash(subtreeRoot(leaves[:k]), subtreeRoot(leaves[k:]))
+}
+
+// splitPoint 小於 n 的最大 2 的冪次
+func splitPoint(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// VerifyInclusion checks an InclusionProof for the SAI at counter against
+// the root of the tree over size SAIs (RFC 9162 §2.1.3.2).
+func VerifyInclusion(sai []byte, counter, size uint64, proof [][]byte, root []byte) error {
+	if counter == 0 || counter > size {
+		return ErrInvalidProof
+	}
+	fn, sn := counter-1, size-1
+	r := leafHash(sai)
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(r, root) {
+		return ErrInvalidProof
+	}
+	return nil
+}
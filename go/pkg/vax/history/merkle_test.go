@@ -0,0 +1,91 @@
+package history
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+// mth 是 RFC 6962 §2.1 的遞迴定義，作為對照
+func mth(sais [][]byte) []byte {
+	switch len(sais) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leafHash(sais[0])
+	}
+	k := 1
+	for k*2 < len(sais) {
+		k *= 2
+	}
+	return nodeHash(mth(sais[:k]), mth(sais[k:]))
+}
+
+func TestMerkle(t *testing.T) {
+	recs := chain("alice", 21)
+	s := NewMemory()
+	appendAll(t, s, recs)
+	var sais [][]byte
+	for _, r := range recs {
+		sais = append(sais, r.SAI)
+	}
+
+	t.Run("frontier root matches the recursive definition", func(t *testing.T) {
+		var f Frontier
+		for n := 0; n <= len(sais); n++ {
+			if !bytes.Equal(f.Root(), mth(sais[:n])) {
+				t.Fatalf("size %d: root differs", n)
+			}
+			if n < len(sais) {
+				f.Push(sais[n])
+			}
+		}
+		root, err := MerkleRoot(s, "alice", 13)
+		if err != nil || !bytes.Equal(root, mth(sais[:13])) {
+			t.Errorf("MerkleRoot = %x, %v", root, err)
+		}
+	})
+
+	t.Run("inclusion proofs verify", func(t *testing.T) {
+		for size := uint64(1); size <= uint64(len(sais)); size++ {
+			root := mth(sais[:size])
+			for c := uint64(1); c <= size; c++ {
+				proof, err := InclusionProof(s, "alice", c, size)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := VerifyInclusion(sais[c-1], c, size, proof, root); err != nil {
+					t.Fatalf("counter %d of %d: %v", c, size, err)
+				}
+			}
+		}
+	})
+
+	t.Run("error: wrong leaf, position or root", func(t *testing.T) {
+		root := mth(sais[:10])
+		proof, _ := InclusionProof(s, "alice", 4, 10)
+		if err := VerifyInclusion(sais[4], 4, 10, proof, root); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("wrong leaf: %v", err)
+		}
+		if err := VerifyInclusion(sais[3], 5, 10, proof, root); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("wrong counter: %v", err)
+		}
+		if err := VerifyInclusion(sais[3], 4, 11, proof, mth(sais[:11])); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("wrong size: %v", err)
+		}
+		if err := VerifyInclusion(sais[3], 4, 10, proof[:len(proof)-1], root); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("short proof: %v", err)
+		}
+	})
+
+	t.Run("error: beyond the chain", func(t *testing.T) {
+		if _, err := MerkleRoot(s, "alice", 22); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MerkleRoot: %v", err)
+		}
+		if _, err := InclusionProof(s, "alice", 5, 4); !errors.Is(err, ErrNotFound) {
+			t.Errorf("InclusionProof: %v", err)
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// DefaultQueryLimit is the page size of Search when Query.Limit is 0.
+const DefaultQueryLimit = 100
+
+// Error codes
+var (
+	ErrInvalidQuery  = errors.New("history: invalid query")
+	ErrInvalidCursor = errors.New("history: invalid cursor")
+)
+
+// Op is the comparison of a Predicate.
+type Op string
+
+const (
+	OpEq     Op = "="
+	OpNe     Op = "!="
+	OpLt     Op = "<"
+	OpLte    Op = "<="
+	OpGt     Op = ">"
+	OpGte    Op = ">="
+	OpPrefix Op = "^=" // strings only
+)
+
+// Predicate compares an SDTO field with Value, parsed as the type the
+// envelope's schema gives the field: number, integer and decimal fields
+// compare numerically, boolean fields as true/false, string fields
+// lexically. A record matches only if its schema defines the field as one
+// of those types and the field is present.
+type Predicate struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// ParsePredicate parses "field<op>value", e.g. "amount>=100" or
+// "currency=EUR".
+func ParsePredicate(s string) (Predicate, error) {
+	// 取最前面的運算子；同位置時兩字元的優先
+	i := strings.IndexAny(s, "=!<>^")
+	if i <= 0 {
+		return Predicate{}, fmt.Errorf("%w: predicate %q", ErrInvalidQuery, s)
+	}
+	for _, op := range []Op{OpLte, OpGte, OpNe, OpPrefix, OpLt, OpGt, OpEq} {
+		if strings.HasPrefix(s[i:], string(op)) {
+			return Predicate{Field: s[:i], Op: op, Value: s[i+len(op):]}, nil
+		}
+	}
+	return Predicate{}, fmt.Errorf("%w: predicate %q", ErrInvalidQuery, s)
+}
+
+// Query selects records for Search. Its Filter narrows actors, counters,
+// action types and accept times as for Export (Filter.Redact is ignored).
+type Query struct {
+	Filter
+	ActorPrefix string
+	// Where predicates must all hold; they need Schemas.
+	Where   []Predicate
+	Schemas *sdto.Registry
+	Limit   int    // page size (0: DefaultQueryLimit)
+	Cursor  string // Page.Next of the previous page ("" for the first)
+}
+
+// Page is one page of Search results.
+type Page struct {
+	Records []Record
+	// Next is the cursor of the following page ("" after the last one).
+	Next string
+}
+
+// cursor 為最後一筆傳回的位置；以 base64url(JCS) 傳給呼叫端
+type cursor struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`
+}
+
+// Search returns the records of s matching q, actor by actor in counter
+// order, one page at a time. Without Filter.Actors the actors come from s,
+// which must then implement Lister. Records are read through Store.Range,
+// so a query scans the selected actors' chains.
+func Search(s Store, q Query) (Page, error) {
+	return SearchContext(context.Background(), s, q)
+}
+
+// SearchContext is Search with the scan bound to ctx (see RangeContext).
+func SearchContext(ctx context.Context, s Store, q Query) (Page, error) {
+	if len(q.Where) > 0 && q.Schemas == nil {
+		return Page{}, fmt.Errorf("%w: field predicates need a schema registry", ErrInvalidQuery)
+	}
+	for _, p := range q.Where {
+		if p.Field == "" || !validOp(p.Op) {
+			return Page{}, fmt.Errorf("%w: predicate %q %q", ErrInvalidQuery, p.Field, p.Op)
+		}
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	var after cursor
+	if q.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+		if err != nil || json.Unmarshal(b, &after) != nil || after.Actor == "" {
+			return Page{}, ErrInvalidCursor
+		}
+	}
+
+	actors := q.Actors
+	if len(actors) == 0 {
+		l, ok := s.(Lister)
+		if !ok {
+			return Page{}, errors.New("history: query needs Filter.Actors for a store that cannot list actors")
+		}
+		var err error
+		if actors, err = l.Actors(); err != nil {
+			return Page{}, fmt.Errorf("history: query: %w", err)
+		}
+	}
+	actors = append([]string(nil), actors...)
+	sort.Strings(actors)
+	to := q.To
+	if to == 0 {
+		to = ^uint64(0)
+	}
+
+	var page Page
+	full := errors.New("page full")
+	for _, actor := range actors {
+		if !strings.HasPrefix(actor, q.ActorPrefix) || actor < after.Actor {
+			continue
+		}
+		from := q.From
+		if actor == after.Actor {
+			from = max(from, after.Counter+1)
+		}
+		err := RangeContext(ctx, s, actor, from, to, func(rec Record) error {
+			if !q.match(rec) || !q.where(rec) {
+				return nil
+			}
+			if len(page.Records) == limit {
+				// 還有下一筆才給 Next
+				last := page.Records[limit-1]
+				b, _ := jcs.Marshal(cursor{Actor: last.Actor, Counter: last.Counter})
+				page.Next = base64.RawURLEncoding.EncodeToString(b)
+				return full
+			}
+			page.Records = append(page.Records, rec)
+			return nil
+		})
+		if err == full {
+			break
+		}
+		if err != nil {
+			return Page{}, fmt.Errorf("history: query %s: %w", actor, err)
+		}
+	}
+	return page, nil
+}
+
+func validOp(op Op) bool {
+	switch op {
+	case OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpPrefix:
+		return true
+	}
+	return false
+}
+
+// where 以 envelope 的 schema 型別評估所有 predicate；裁剪或 sdto 非 object 的 record 不符合
+func (q Query) where(rec Record) bool {
+	if len(q.Where) == 0 {
+		return true
+	}
+	if rec.Pruned() {
+		return false
+	}
+	canonical, err := sae.Decompress(rec.SAE)
+	if err != nil {
+		return false
+	}
+	var members struct {
+		ActionType    string          `json:"action_type"`
+		SchemaVersion string          `json:"schema_version"`
+		SDTO          json.RawMessage `json:"sdto"`
+	}
+	if json.Unmarshal(canonical, &members) != nil || len(members.SDTO) == 0 || members.SDTO[0] != '{' {
+		return false
+	}
+	schema, err := q.Schemas.Lookup(members.ActionType, members.SchemaVersion)
+	if err != nil {
+		return false
+	}
+	var data map[string]any
+	dec := json.NewDecoder(bytes.NewReader(members.SDTO))
+	dec.UseNumber()
+	if dec.Decode(&data) != nil {
+		return false
+	}
+	for _, p := range q.Where {
+		spec, ok := schema[p.Field]
+		value, present := data[p.Field]
+		if !ok || !present || !p.holds(spec.Type, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds 比較欄位值與 p.Value；無法以該型別解讀的值視為不符合
+func (p Predicate) holds(fieldType string, value any) bool {
+	var cmp int
+	switch fieldType {
+	case "number", "integer", "decimal":
+		var text string
+		switch v := value.(type) {
+		case json.Number:
+			text = v.String()
+		case string: // decimal 以字串傳送
+			text = v
+		default:
+			return false
+		}
+		x, ok1 := new(big.Rat).SetString(text)
+		y, ok2 := new(big.Rat).SetString(p.Value)
+		if !ok1 || !ok2 || p.Op == OpPrefix {
+			return false
+		}
+		cmp = x.Cmp(y)
+	case "boolean":
+		x, ok := value.(bool)
+		y, err := strconv.ParseBool(p.Value)
+		if !ok || err != nil || (p.Op != OpEq && p.Op != OpNe) {
+			return false
+		}
+		if x != y {
+			cmp = 1
+		}
+	case "string":
+		x, ok := value.(string)
+		if !ok {
+			return false
+		}
+		if p.Op == OpPrefix {
+			return strings.HasPrefix(x, p.Value)
+		}
+		cmp = strings.Compare(x, p.Value)
+	default:
+		return false
+	}
+	switch p.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	}
+	return false
+}
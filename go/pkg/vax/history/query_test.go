@@ -0,0 +1,107 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParsePredicate(t *testing.T) {
+	for in, want := range map[string]Predicate{
+		"amount>=10":  {Field: "amount", Op: OpGte, Value: "10"},
+		"amount<3":    {Field: "amount", Op: OpLt, Value: "3"},
+		"iban!=DE01":  {Field: "iban", Op: OpNe, Value: "DE01"},
+		"iban^=DE":    {Field: "iban", Op: OpPrefix, Value: "DE"},
+		"note=a<=b":   {Field: "note", Op: OpEq, Value: "a<=b"},
+		"flag=":       {Field: "flag", Op: OpEq, Value: ""},
+		"amount<=1.5": {Field: "amount", Op: OpLte, Value: "1.5"},
+	} {
+		if got, err := ParsePredicate(in); err != nil || got != want {
+			t.Errorf("ParsePredicate(%q) = %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"amount", "=10", ""} {
+		if _, err := ParsePredicate(in); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParsePredicate(%q): %v", in, err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := NewMemory()
+	for _, actor := range []string{"acme/alice", "acme/bob", "zed"} {
+		appendAll(t, s, envelopeChain(t, actor, 5))
+	}
+	ids := func(recs []Record) []string {
+		var out []string
+		for _, r := range recs {
+			out = append(out, fmt.Sprintf("%s/%d", r.Actor, r.Counter))
+		}
+		return out
+	}
+
+	t.Run("filters by actor prefix, time and payload", func(t *testing.T) {
+		page, err := Search(s, Query{
+			Filter:      Filter{Since: time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC)},
+			ActorPrefix: "acme/",
+			Where:       []Predicate{{Field: "amount", Op: OpLte, Value: "3.5"}, {Field: "iban", Op: OpPrefix, Value: "DE0"}},
+			Schemas:     testSchemas(),
+		})
+		got := ids(page.Records)
+		if err != nil || fmt.Sprint(got) != "[acme/alice/2 acme/alice/3 acme/bob/2 acme/bob/3]" || page.Next != "" {
+			t.Errorf("Search = %v, %q, %v", got, page.Next, err)
+		}
+	})
+
+	t.Run("pages with a cursor", func(t *testing.T) {
+		q := Query{Filter: Filter{ActionTypes: []string{"transfer"}}, Limit: 4}
+		var all []string
+		for pages := 0; ; pages++ {
+			page, err := Search(s, q)
+			if err != nil || pages > 4 {
+				t.Fatalf("page %d: %v", pages, err)
+			}
+			all = append(all, ids(page.Records)...)
+			if page.Next == "" {
+				break
+			}
+			q.Cursor = page.Next
+		}
+		if len(all) != 15 || all[5] != "acme/bob/1" || all[14] != "zed/5" {
+			t.Errorf("pages = %v", all)
+		}
+	})
+
+	t.Run("pruned records do not match payload predicates", func(t *testing.T) {
+		m := NewMemory()
+		appendAll(t, m, envelopeChain(t, "alice", 3))
+		_, _ = m.Prune("alice", 1, 1)
+		page, err := Search(m, Query{Where: []Predicate{{Field: "amount", Op: OpGt, Value: "0"}}, Schemas: testSchemas()})
+		if err != nil || fmt.Sprint(ids(page.Records)) != "[alice/2 alice/3]" {
+			t.Errorf("Search = %v, %v", ids(page.Records), err)
+		}
+	})
+
+	t.Run("fields the schema does not type never match", func(t *testing.T) {
+		page, err := Search(s, Query{Where: []Predicate{{Field: "memo", Op: OpNe, Value: "x"}}, Schemas: testSchemas()})
+		if err != nil || len(page.Records) != 0 {
+			t.Errorf("Search = %v, %v", ids(page.Records), err)
+		}
+	})
+
+	t.Run("error: predicates without schemas", func(t *testing.T) {
+		if _, err := Search(s, Query{Where: []Predicate{{Field: "amount", Op: OpGt, Value: "1"}}}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+		if _, err := Search(s, Query{Where: []Predicate{{Field: "amount", Op: "~", Value: "1"}}, Schemas: testSchemas()}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+	})
+
+	t.Run("error: bad cursor", func(t *testing.T) {
+		if _, err := Search(s, Query{Cursor: "!!"}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
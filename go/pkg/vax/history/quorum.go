@@ -0,0 +1,119 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"vax/pkg/vax/sae"
+)
+
+// Error codes
+var (
+	ErrQuorum        = errors.New("history: attestation quorum not met")
+	ErrInvalidQuorum = errors.New("history: invalid quorum")
+)
+
+// Quorum is an M-of-N policy over verifier attestations: a chain head is
+// final once Threshold of the Verifiers have independently signed it, so
+// no single verifier has to be trusted.
+type Quorum struct {
+	Threshold int             // M: distinct verifiers required
+	Verifiers []string        // N: kids allowed to attest
+	Keys      sae.KeyResolver // resolves the verifiers' kids
+}
+
+// FinalizedHead is a chain head attested by a quorum of verifiers.
+// Checkpoint carries the attested statement (actor, counter, sai,
+// merkle_root); its own Verifier and Attestation are empty.
+type FinalizedHead struct {
+	Checkpoint   Checkpoint `json:"checkpoint"`
+	Verifiers    []string   `json:"verifiers"`    // kids that counted, sorted
+	Attestations [][]byte   `json:"attestations"` // in Verifiers order
+}
+
+// AttestHead signs cp's head as verifier kid: an SAE of action_type
+// CheckpointActionType over actor, counter, sai and merkle_root, the same
+// statement WithAttestation produces. Each verifier runs VerifyChain on its
+// own copy of the history and attests the checkpoint it gets; a
+// checkpoint's Attestation from WithAttestation can be used directly.
+func AttestHead(cp Checkpoint, kid string, signer crypto.Signer) ([]byte, error) {
+	if kid == "" || signer == nil {
+		return nil, errors.New("history: AttestHead needs a kid and a signer")
+	}
+	return attest(cp, kid, signer)
+}
+
+// Combine checks attestations against cp's head and returns the finalized
+// head once at least Threshold distinct verifiers of q have validly signed
+// it. Attestations from kids outside Verifiers, with a bad signature or
+// over a different head do not count, and a verifier counts once however
+// many attestations it sent. Below the threshold the error wraps ErrQuorum
+// and says why each attestation was rejected.
+func (q Quorum) Combine(cp Checkpoint, attestations ...[]byte) (*FinalizedHead, error) {
+	return q.CombineContext(context.Background(), cp, attestations...)
+}
+
+// CombineContext is Combine with ctx passed to q.Keys (see sae.ResolveKey).
+func (q Quorum) CombineContext(ctx context.Context, cp Checkpoint, attestations ...[]byte) (*FinalizedHead, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	head := Checkpoint{Actor: cp.Actor, Counter: cp.Counter, SAI: bytes.Clone(cp.SAI),
+		MerkleRoot: bytes.Clone(cp.MerkleRoot), Frontier: cp.Frontier.clone(), VerifiedAt: cp.VerifiedAt}
+
+	signed := map[string][]byte{}
+	var rejected []string
+	for i, att := range attestations {
+		kid, err := verifyAttestation(ctx, att, head, q.Keys)
+		switch {
+		case err != nil:
+			rejected = append(rejected, fmt.Sprintf("#%d: %v", i, err))
+		case !slices.Contains(q.Verifiers, kid):
+			rejected = append(rejected, fmt.Sprintf("#%d: %q is not a quorum verifier", i, kid))
+		case signed[kid] == nil:
+			signed[kid] = bytes.Clone(att)
+		}
+	}
+	if len(signed) < q.Threshold {
+		msg := fmt.Sprintf("%d of %d verifiers", len(signed), q.Threshold)
+		if len(rejected) > 0 {
+			msg += " (" + strings.Join(rejected, "; ") + ")"
+		}
+		return nil, fmt.Errorf("%w: %s", ErrQuorum, msg)
+	}
+
+	f := &FinalizedHead{Checkpoint: head}
+	for kid := range signed {
+		f.Verifiers = append(f.Verifiers, kid)
+	}
+	slices.Sort(f.Verifiers)
+	for _, kid := range f.Verifiers {
+		f.Attestations = append(f.Attestations, signed[kid])
+	}
+	return f, nil
+}
+
+// Verify re-checks a finalized head against q, e.g. after loading it or
+// when q's verifier set has changed.
+func (q Quorum) Verify(f *FinalizedHead) error {
+	if f == nil {
+		return fmt.Errorf("%w: no finalized head", ErrQuorum)
+	}
+	_, err := q.Combine(f.Checkpoint, f.Attestations...)
+	return err
+}
+
+func (q Quorum) validate() error {
+	if q.Keys == nil {
+		return fmt.Errorf("%w: no key resolver", ErrInvalidQuorum)
+	}
+	if q.Threshold < 1 || q.Threshold > len(q.Verifiers) {
+		return fmt.Errorf("%w: threshold %d of %d verifiers", ErrInvalidQuorum, q.Threshold, len(q.Verifiers))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax/sae"
+)
+
+func TestQuorum(t *testing.T) {
+	_, priv, _ := sae.GenerateKeyPair()
+	keys := sae.StaticResolver{}
+	signers := map[string]ed25519.PrivateKey{}
+	for _, kid := range []string{"v1", "v2", "v3", "outsider"} {
+		pub, key, _ := sae.GenerateKeyPair()
+		keys[kid], signers[kid] = pub, key
+	}
+	q := Quorum{Threshold: 2, Verifiers: []string{"v1", "v2", "v3"}, Keys: keys}
+
+	s := NewMemory()
+	signedChain(t, s, "alice", 3, priv)
+	// 每個 verifier 各自驗證同一條 chain
+	var cps []Checkpoint
+	for _, kid := range []string{"v1", "v2", "v3"} {
+		cp, err := VerifyChain(s, "alice", testGenesis, WithAttestation(kid, signers[kid]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cps = append(cps, cp)
+	}
+
+	t.Run("two of three finalize the head", func(t *testing.T) {
+		f, err := q.Combine(cps[0], cps[2].Attestation, cps[0].Attestation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(f.Verifiers, ",") != "v1,v3" || len(f.Attestations) != 2 || f.Checkpoint.Counter != 3 || f.Checkpoint.Attestation != nil {
+			t.Errorf("finalized = %+v", f)
+		}
+		if err := q.Verify(f); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("AttestHead matches checkpoint attestations", func(t *testing.T) {
+		att, err := AttestHead(cps[0], "v2", signers["v2"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.Combine(cps[0], cps[0].Attestation, att); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error: duplicates and outsiders do not count", func(t *testing.T) {
+		outsider, _ := AttestHead(cps[0], "outsider", signers["outsider"])
+		_, err := q.Combine(cps[0], cps[0].Attestation, cps[0].Attestation, outsider)
+		if !errors.Is(err, ErrQuorum) || !strings.Contains(err.Error(), `"outsider" is not a quorum verifier`) {
+			t.Errorf("expected ErrQuorum, got %v", err)
+		}
+	})
+
+	t.Run("error: attestation over another head", func(t *testing.T) {
+		signedChain(t, s, "alice", 1, priv)
+		moved, _ := VerifyChain(s, "alice", testGenesis, WithAttestation("v2", signers["v2"]))
+		_, err := q.Combine(cps[0], cps[0].Attestation, moved.Attestation)
+		if !errors.Is(err, ErrQuorum) || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("expected ErrQuorum, got %v", err)
+		}
+	})
+
+	t.Run("error: forged signature", func(t *testing.T) {
+		forged, _ := AttestHead(cps[0], "v2", signers["outsider"])
+		if _, err := q.Combine(cps[0], cps[0].Attestation, forged); !errors.Is(err, ErrQuorum) {
+			t.Errorf("expected ErrQuorum, got %v", err)
+		}
+	})
+
+	t.Run("error: invalid policy", func(t *testing.T) {
+		for _, bad := range []Quorum{{Threshold: 4, Verifiers: q.Verifiers, Keys: keys}, {Threshold: 0, Verifiers: q.Verifiers, Keys: keys}, {Threshold: 1, Verifiers: q.Verifiers}} {
+			if _, err := bad.Combine(cps[0], cps[0].Attestation); !errors.Is(err, ErrInvalidQuorum) {
+				t.Errorf("%+v: %v", bad, err)
+			}
+		}
+	})
+}
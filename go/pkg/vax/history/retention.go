@@ -0,0 +1,136 @@
+package history
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"vax/pkg/vax/sae"
+)
+
+// Pruner is implemented by stores that can drop envelope bodies while
+// keeping the chain verifiable (Memory, SQLStore, SegmentStore).
+//
+// Pruning replaces a record's SAE by the SHA-256 of its canonical form
+// (Record.SAEHash): counters, SAIs and accept times stay, so the chain,
+// Merkle roots, inclusion proofs and checkpoints still verify, but the
+// SDTO is gone for good. Use it for retention and erasure requests.
+type Pruner interface {
+	// Prune prunes the actor's records with from <= counter <= to and
+	// returns how many it pruned (already pruned records are skipped).
+	Prune(actor string, from, to uint64) (int, error)
+}
+
+// Retention says which envelope bodies ApplyRetention prunes. A record is
+// pruned when either limit applies to it; zero disables a limit.
+type Retention struct {
+	// MaxAge prunes records accepted longer than MaxAge ago (the chain's
+	// prefix up to the first younger record).
+	MaxAge time.Duration
+	// KeepLast keeps the bodies of each actor's latest KeepLast records.
+	KeepLast uint64
+}
+
+// ApplyRetention prunes the bodies r selects for every actor of s, which
+// must implement Lister and Pruner, and returns the number of records
+// pruned.
+func ApplyRetention(s Store, r Retention, now time.Time) (int, error) {
+	l, lok := s.(Lister)
+	p, pok := s.(Pruner)
+	if !lok || !pok {
+		return 0, errors.New("history: retention needs a store that lists actors and prunes")
+	}
+	actors, err := l.Actors()
+	if err != nil {
+		return 0, fmt.Errorf("history: retention: %w", err)
+	}
+	total := 0
+	for _, actor := range actors {
+		to, err := retentionCutoff(s, actor, r, now)
+		if err != nil {
+			return total, err
+		}
+		if to == 0 {
+			continue
+		}
+		n, err := p.Prune(actor, 1, to)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("history: retention %s: %w", actor, err)
+		}
+	}
+	return total, nil
+}
+
+// retentionCutoff 回傳應裁剪到的 counter（0：不裁剪）
+func retentionCutoff(s Store, actor string, r Retention, now time.Time) (uint64, error) {
+	head, err := s.Head(actor)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var to uint64
+	if r.KeepLast > 0 && head.Counter > r.KeepLast {
+		to = head.Counter - r.KeepLast
+	}
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge).UnixMilli()
+		stop := errors.New("stop")
+		err := s.Range(actor, to+1, head.Counter, func(rec Record) error {
+			if rec.AcceptedAt >= cutoff {
+				return stop
+			}
+			to = rec.Counter
+			return nil
+		})
+		if err != nil && err != stop {
+			return 0, err
+		}
+	}
+	return to, nil
+}
+
+// pruned 回傳以 canonical envelope 雜湊取代 SAE 的 record（加密的 envelope 用其記錄的明文雜湊）
+func pruned(rec Record) (Record, error) {
+	if rec.Pruned() {
+		return rec, nil
+	}
+	if digest, ok := sealedDigest(rec.SAE); ok {
+		rec.SAE, rec.SAEHash = nil, bytes.Clone(digest)
+		return rec, nil
+	}
+	canonical, err := sae.Decompress(rec.SAE)
+	if err != nil {
+		return Record{}, fmt.Errorf("history: prune %s/%d: %w", rec.Actor, rec.Counter, err)
+	}
+	sum := sha256.Sum256(canonical)
+	rec.SAE, rec.SAEHash = nil, sum[:]
+	return rec, nil
+}
+
+var _ Pruner = (*Memory)(nil)
+
+func (m *Memory) Prune(actor string, from, to uint64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chain := m.chains[actor]
+	from = max(from, 1)
+	to = min(to, uint64(len(chain)))
+	n := 0
+	for c := from; c <= to; c++ {
+		if chain[c-1].Pruned() {
+			continue
+		}
+		rec, err := pruned(chain[c-1])
+		if err != nil {
+			return n, err
+		}
+		chain[c-1] = rec
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory":  func(t *testing.T) Store { return NewMemory() },
+		"segment": func(t *testing.T) Store { return openSegments(t, t.TempDir(), WithSegmentSize(1500)) },
+		"sql":     func(t *testing.T) Store { s, _ := newSQLStore(t, SQLite); return s },
+	}
+	for name, open := range stores {
+		t.Run(name+" keeps the chain verifiable", func(t *testing.T) {
+			s := open(t)
+			appendAll(t, s, envelopeChain(t, "alice", 6))
+			appendAll(t, s, envelopeChain(t, "bob", 2))
+			before, _ := MerkleRoot(s, "alice", 6)
+
+			n, err := s.(Pruner).Prune("alice", 2, 4)
+			if err != nil || n != 3 {
+				t.Fatalf("Prune = %d, %v", n, err)
+			}
+			if n, _ := s.(Pruner).Prune("alice", 1, 4); n != 1 {
+				t.Errorf("pruned %d again", n)
+			}
+			rec, err := s.GetByCounter("alice", 3)
+			if err != nil || !rec.Pruned() || rec.SAE != nil || len(rec.SAEHash) != 32 {
+				t.Fatalf("record 3 = %+v, %v", rec, err)
+			}
+			if rec, _ := s.GetByCounter("alice", 5); rec.Pruned() || len(rec.SAE) == 0 {
+				t.Errorf("record 5 pruned: %+v", rec)
+			}
+			if _, err := VerifyChain(s, "alice", testGenesis); err != nil {
+				t.Error(err)
+			}
+			after, _ := MerkleRoot(s, "alice", 6)
+			if !bytes.Equal(before, after) {
+				t.Error("Merkle root changed")
+			}
+			if got := counters(t, s, "bob"); len(got) != 2 {
+				t.Errorf("bob = %v", got)
+			}
+			appendAll(t, s, envelopeChain(t, "alice", 7)[6:])
+		})
+	}
+
+	t.Run("segment compaction survives reopening", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir, WithSegmentSize(1500))
+		appendAll(t, s, envelopeChain(t, "alice", 8))
+		if _, err := s.Prune("alice", 1, 8); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		s = openSegments(t, dir)
+		if _, err := VerifyChain(s, "alice", testGenesis); err != nil {
+			t.Error(err)
+		}
+		if rec, _ := s.Head("alice"); !rec.Pruned() {
+			t.Error("head not pruned")
+		}
+	})
+
+	t.Run("exports and imports pruned records", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 3))
+		_, _ = s.Prune("alice", 1, 2)
+		lines := exportLines(t, s, Filter{})
+		if !strings.Contains(lines[0], `"pruned":true`) || !strings.Contains(lines[0], `"sae":null`) {
+			t.Errorf("line = %s", lines[0])
+		}
+		dst := NewMemory()
+		report, err := ImportAndVerify(strings.NewReader(strings.Join(lines, "")), genesisOf, nil, dst)
+		if err != nil || report.Pruned != 2 || report.Imported != 3 {
+			t.Fatalf("report = %+v, %v", report, err)
+		}
+		if rec, _ := dst.GetByCounter("alice", 2); !rec.Pruned() {
+			t.Errorf("imported %+v", rec)
+		}
+	})
+
+	t.Run("error: invalid pruned record", func(t *testing.T) {
+		rec := envelopeChain(t, "alice", 1)[0]
+		rec.SAEHash = []byte{1, 2, 3}
+		if err := NewMemory().Append(rec); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 10, 0, time.UTC) // envelopeChain: record i 於第 i-1 秒
+	pruned := func(s Store, actor string) []uint64 {
+		var got []uint64
+		_ = s.Range(actor, 1, ^uint64(0), func(r Record) error {
+			if r.Pruned() {
+				got = append(got, r.Counter)
+			}
+			return nil
+		})
+		return got
+	}
+
+	t.Run("by count", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 5))
+		appendAll(t, s, envelopeChain(t, "bob", 2))
+		n, err := ApplyRetention(s, Retention{KeepLast: 2}, now)
+		if err != nil || n != 3 || len(pruned(s, "alice")) != 3 || len(pruned(s, "bob")) != 0 {
+			t.Errorf("ApplyRetention = %d, %v", n, err)
+		}
+	})
+
+	t.Run("by age or count", func(t *testing.T) {
+		s := NewMemory()
+		appendAll(t, s, envelopeChain(t, "alice", 8))
+		n, err := ApplyRetention(s, Retention{MaxAge: 5 * time.Second, KeepLast: 6}, now)
+		// accepted_at 0..4 秒早於 cutoff (5 秒)
+		if got := pruned(s, "alice"); err != nil || n != 5 || len(got) != 5 || got[4] != 5 {
+			t.Errorf("ApplyRetention = %d, %v; pruned %v", n, err, got)
+		}
+		if _, err := VerifyChain(s, "alice", testGenesis); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error: store cannot prune", func(t *testing.T) {
+		if _, err := ApplyRetention(struct{ Store }{NewMemory()}, Retention{KeepLast: 1}, now); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
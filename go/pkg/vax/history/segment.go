@@ -0,0 +1,734 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrCorrupt is returned for segment data that fails its CRC or framing
+// outside the recoverable tail.
+var ErrCorrupt = errors.New("history: corrupt segment")
+
+var errClosed = errors.New("history: store is closed")
+
+// DefaultSegmentSize is the size after which SegmentStore starts a new
+// segment file.
+const DefaultSegmentSize = 64 << 20
+
+// frameHeader = payload 長度 (u32) + CRC32-C (u32)
+const frameHeader = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// SegmentStore is a Store in a directory of rolling segment files, for
+// deployments without a database. Each record is one frame (length,
+// CRC32-C, payload) appended to NNNNNN.log; NNNNNN.idx beside it lists
+// the frames' offsets, counters and actors so opening the store does not
+// read the records themselves.
+//
+// OpenSegments recovers from a crash: index entries the log does not back
+// are dropped, frames missing from the index are re-indexed, and a torn
+// frame at the end of the last segment is truncated. Damage anywhere else
+// is ErrCorrupt. Reads check each frame's CRC.
+type SegmentStore struct {
+	mu      sync.RWMutex
+	dir     string
+	maxSize int64
+	sync    bool
+
+	segs   []*segment
+	actors map[string]*actorLog
+	cps    map[string]Checkpoint
+}
+
+type segment struct {
+	id   int
+	log  *os.File
+	idx  *os.File
+	size int64
+}
+
+type location struct {
+	seg *segment
+	off int64
+	n   uint32 // frame 長度（含 header）
+}
+
+type actorLog struct {
+	locs    []location // locs[i] 為 counter i+1
+	headSAI []byte
+}
+
+// SegmentOption configures OpenSegments.
+type SegmentOption func(*SegmentStore)
+
+// WithSegmentSize sets the size at which a new segment is started.
+func WithSegmentSize(n int64) SegmentOption {
+	return func(s *SegmentStore) {
+		s.maxSize = max(n, 1)
+	}
+}
+
+// WithoutSync skips the fsync after each append (faster, but a crash may
+// lose recently acknowledged records).
+func WithoutSync() SegmentOption {
+	return func(s *SegmentStore) {
+		s.sync = false
+	}
+}
+
+var _ Store = (*SegmentStore)(nil)
+
+// OpenSegments opens (creating if needed) the store in dir and recovers it.
+func OpenSegments(dir string, opts ...SegmentOption) (*SegmentStore, error) {
+	s := &SegmentStore{dir: dir, maxSize: DefaultSegmentSize, sync: true, actors: map[string]*actorLog{}, cps: map[string]Checkpoint{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	var ids []int
+	for _, name := range names {
+		var id int
+		if _, err := fmt.Sscanf(filepath.Base(name), "%06d.log", &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for i, id := range ids {
+		if err := s.openSegment(id, i == len(ids)-1); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	for actor, a := range s.actors {
+		rec, err := s.read(a.locs[len(a.locs)-1])
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("history: head of %s: %w", actor, err)
+		}
+		a.headSAI = rec.SAI
+	}
+	if err := s.loadCheckpoints(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openSegment 以 idx 為準載入，再以 log 補齊或截斷；只有最後一個 segment 可截斷
+func (s *SegmentStore) openSegment(id int, last bool) error {
+	seg, err := s.createSegment(id)
+	if err != nil {
+		return err
+	}
+	s.segs = append(s.segs, seg)
+	info, err := seg.log.Stat()
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	logSize := info.Size()
+
+	entries, clean := readIndex(seg.idx)
+	// 去掉 log 未涵蓋或不相連的 entry
+	var end int64
+	for i, e := range entries {
+		if e.off != end || e.off+int64(e.n) > logSize {
+			entries, clean = entries[:i], false
+			break
+		}
+		end = e.off + int64(e.n)
+	}
+	// 補上 idx 之後的 frame
+	if end < logSize {
+		clean = false
+		tail, good, err := scanFrames(seg.log, end, logSize)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, tail...)
+		if good < logSize {
+			if !last {
+				return fmt.Errorf("%w: %s at offset %d", ErrCorrupt, seg.log.Name(), good)
+			}
+			if err := seg.log.Truncate(good); err != nil {
+				return fmt.Errorf("history: %w", err)
+			}
+		}
+		end = good
+	}
+	seg.size = end
+	if clean {
+		if _, err := seg.idx.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+	} else if err := rewriteIndex(seg.idx, entries); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		a := s.actors[e.actor]
+		if a == nil {
+			a = &actorLog{}
+			s.actors[e.actor] = a
+		}
+		if e.counter != uint64(len(a.locs))+1 {
+			return fmt.Errorf("%w: %s counter %d after %d in %s", ErrCorrupt, e.actor, e.counter, len(a.locs), seg.log.Name())
+		}
+		a.locs = append(a.locs, location{seg: seg, off: e.off, n: e.n})
+	}
+	return nil
+}
+
+func (s *SegmentStore) createSegment(id int) (*segment, error) {
+	base := filepath.Join(s.dir, fmt.Sprintf("%06d", id))
+	log, err := os.OpenFile(base+".log", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	idx, err := os.OpenFile(base+".idx", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	return &segment{id: id, log: log, idx: idx}, nil
+}
+
+func (s *SegmentStore) Append(rec Record) error {
+	if err := validateSizes(rec); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.segs == nil && s.actors == nil {
+		return errClosed
+	}
+	a := s.actors[rec.Actor]
+	var head *Record
+	if a != nil {
+		head = &Record{Counter: uint64(len(a.locs)), SAI: a.headSAI}
+	}
+	if err := CheckNext(head, rec); err != nil {
+		return err
+	}
+
+	frame := encodeFrame(rec)
+	seg, err := s.activeSegment(int64(len(frame)))
+	if err != nil {
+		return err
+	}
+	if _, err := seg.log.WriteAt(frame, seg.size); err != nil {
+		// 寫了一半的 frame 由下次 OpenSegments 截斷；這裡先還原長度
+		_ = seg.log.Truncate(seg.size)
+		return fmt.Errorf("history: append: %w", err)
+	}
+	if s.sync {
+		if err := seg.log.Sync(); err != nil {
+			_ = seg.log.Truncate(seg.size)
+			return fmt.Errorf("history: append: %w", err)
+		}
+	}
+	e := indexEntry{off: seg.size, n: uint32(len(frame)), counter: rec.Counter, actor: rec.Actor}
+	// idx 可由 log 重建，寫入失敗不影響已持久化的 record
+	_, _ = seg.idx.Write(e.encode())
+	seg.size += int64(len(frame))
+
+	if a == nil {
+		a = &actorLog{}
+		s.actors[rec.Actor] = a
+	}
+	a.locs = append(a.locs, location{seg: seg, off: e.off, n: e.n})
+	a.headSAI = bytes.Clone(rec.SAI)
+	return nil
+}
+
+// activeSegment 目前 segment 放不下時開新的（空 segment 一律可寫）
+func (s *SegmentStore) activeSegment(n int64) (*segment, error) {
+	if len(s.segs) > 0 {
+		seg := s.segs[len(s.segs)-1]
+		if seg.size == 0 || seg.size+n <= s.maxSize {
+			return seg, nil
+		}
+	}
+	id := 1
+	if len(s.segs) > 0 {
+		id = s.segs[len(s.segs)-1].id + 1
+	}
+	seg, err := s.createSegment(id)
+	if err != nil {
+		return nil, err
+	}
+	s.segs = append(s.segs, seg)
+	return seg, nil
+}
+
+func (s *SegmentStore) GetByCounter(actor string, counter uint64) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.actors[actor]
+	if a == nil || counter == 0 || counter > uint64(len(a.locs)) {
+		return Record{}, ErrNotFound
+	}
+	return s.read(a.locs[counter-1])
+}
+
+// Range reads one frame at a time, so memory use does not grow with the
+// size of the range.
+func (s *SegmentStore) Range(actor string, from, to uint64, fn func(Record) error) error {
+	s.mu.RLock()
+	a := s.actors[actor]
+	var locs []location
+	if a != nil {
+		from, to = max(from, 1), min(to, uint64(len(a.locs)))
+		if from <= to {
+			locs = a.locs[from-1 : to]
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, loc := range locs {
+		rec, err := s.read(loc)
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SegmentStore) Head(actor string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.actors[actor]
+	if a == nil {
+		return Record{}, ErrNotFound
+	}
+	return s.read(a.locs[len(a.locs)-1])
+}
+
+// Actors lists the actors with records (sorted).
+func (s *SegmentStore) Actors() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	actors := make([]string, 0, len(s.actors))
+	for actor := range s.actors {
+		actors = append(actors, actor)
+	}
+	sort.Strings(actors)
+	return actors, nil
+}
+
+var _ Pruner = (*SegmentStore)(nil)
+
+// Prune compacts every segment holding selected records: the segment is
+// rewritten with their envelopes replaced by digests, then swapped in by
+// rename. Its index is emptied first, so a crash in between leaves a log
+// that OpenSegments re-indexes.
+func (s *SegmentStore) Prune(actor string, from, to uint64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.segs == nil && s.actors == nil {
+		return 0, errClosed
+	}
+	a := s.actors[actor]
+	if a == nil {
+		return 0, nil
+	}
+	from = max(from, 1)
+	to = min(to, uint64(len(a.locs)))
+	var segs []*segment
+	for c := from; c <= to; c++ {
+		if seg := a.locs[c-1].seg; len(segs) == 0 || segs[len(segs)-1] != seg {
+			segs = append(segs, seg)
+		}
+	}
+	total := 0
+	for _, seg := range segs {
+		n, err := s.compact(seg, func(rec Record) bool {
+			return rec.Actor == actor && rec.Counter >= from && rec.Counter <= to
+		})
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("history: prune %s: %w", seg.log.Name(), err)
+		}
+	}
+	return total, nil
+}
+
+// compact 重寫 segment，裁剪 prune 選中的 record 並更新所有指向它的位置
+func (s *SegmentStore) compact(seg *segment, prune func(Record) bool) (int, error) {
+	data := make([]byte, seg.size)
+	if _, err := seg.log.ReadAt(data, 0); err != nil {
+		return 0, err
+	}
+	out := make([]byte, 0, len(data))
+	moved := map[int64]location{}
+	var entries []indexEntry
+	n := 0
+	for off := int64(0); off < seg.size; {
+		size := frameHeader + int64(binary.BigEndian.Uint32(data[off:off+4]))
+		frame := data[off : off+size]
+		rec, err := decodeFrame(frame)
+		if err != nil {
+			return 0, fmt.Errorf("%w: offset %d: %v", ErrCorrupt, off, err)
+		}
+		if !rec.Pruned() && prune(rec) {
+			if rec, err = pruned(rec); err != nil {
+				return 0, err
+			}
+			frame = encodeFrame(rec)
+			n++
+		}
+		e := indexEntry{off: int64(len(out)), n: uint32(len(frame)), counter: rec.Counter, actor: rec.Actor}
+		entries = append(entries, e)
+		moved[off] = location{seg: seg, off: e.off, n: e.n}
+		out = append(out, frame...)
+		off += size
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	name := seg.log.Name()
+	if err := writeSynced(name+".tmp", out); err != nil {
+		return 0, err
+	}
+	if err := seg.idx.Truncate(0); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return 0, err
+	}
+	log, err := os.OpenFile(name, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	seg.log.Close()
+	seg.log, seg.size = log, int64(len(out))
+	for _, a := range s.actors {
+		for i, loc := range a.locs {
+			if loc.seg == seg {
+				a.locs[i] = moved[loc.off]
+			}
+		}
+	}
+	// idx 可由 log 重建，寫入失敗不影響已完成的壓縮
+	_ = rewriteIndex(seg.idx, entries)
+	return n, nil
+}
+
+func writeSynced(name string, data []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkpointFile 每行一個 JSON checkpoint，只附加
+const checkpointFile = "checkpoints.jsonl"
+
+var _ CheckpointStore = (*SegmentStore)(nil)
+
+// SaveCheckpoint appends cp to checkpoints.jsonl in the store's directory.
+func (s *SegmentStore) SaveCheckpoint(cp Checkpoint) error {
+	line, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.segs == nil && s.actors == nil {
+		return errClosed
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, checkpointFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("history: save checkpoint: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("history: save checkpoint: %w", err)
+	}
+	if s.sync {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("history: save checkpoint: %w", err)
+		}
+	}
+	if last, ok := s.cps[cp.Actor]; !ok || cp.Counter >= last.Counter {
+		s.cps[cp.Actor] = cloneCheckpoint(cp)
+	}
+	return nil
+}
+
+func (s *SegmentStore) LastCheckpoint(actor string) (Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp, ok := s.cps[actor]
+	if !ok {
+		return Checkpoint{}, ErrNotFound
+	}
+	return cloneCheckpoint(cp), nil
+}
+
+// loadCheckpoints 讀入每個 actor 最新的 checkpoint；最後一行寫了一半時略過
+func (s *SegmentStore) loadCheckpoints() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(line, &cp); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("%w: %s line %d", ErrCorrupt, checkpointFile, i+1)
+		}
+		if last, ok := s.cps[cp.Actor]; !ok || cp.Counter >= last.Counter {
+			s.cps[cp.Actor] = cp
+		}
+	}
+	return nil
+}
+
+// Close closes the segment files; the store cannot be used afterwards.
+func (s *SegmentStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, seg := range s.segs {
+		errs = append(errs, seg.log.Close(), seg.idx.Close())
+	}
+	s.segs, s.actors = nil, nil
+	return errors.Join(errs...)
+}
+
+func (s *SegmentStore) read(loc location) (Record, error) {
+	frame := make([]byte, loc.n)
+	if _, err := loc.seg.log.ReadAt(frame, loc.off); err != nil {
+		return Record{}, fmt.Errorf("history: read: %w", err)
+	}
+	rec, err := decodeFrame(frame)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: %s at offset %d: %v", ErrCorrupt, loc.seg.log.Name(), loc.off, err)
+	}
+	return rec, nil
+}
+
+// prunedBit 設在 SAE 長度欄位時，該欄位存的是裁剪後的 SAE 雜湊
+const prunedBit = 1 << 31
+
+// validateSizes 長度欄位為 u16（SAE 為 u32，最高位元為 prunedBit）
+func validateSizes(rec Record) error {
+	for _, n := range []int{len(rec.Actor), len(rec.ActionType), len(rec.PrevSAI), len(rec.SAI)} {
+		if n > math.MaxUint16 {
+			return fmt.Errorf("%w: field too long for a segment frame", ErrInvalid)
+		}
+	}
+	if int64(len(rec.SAE)) > math.MaxUint32/2 {
+		return fmt.Errorf("%w: sae too long for a segment frame", ErrInvalid)
+	}
+	return nil
+}
+
+// encodeFrame: [len u32][crc32c u32] + payload
+// payload: actor, counter u64, action_type, prev_sai, sae (u32 長度；裁剪後為雜湊), sai, accepted_at i64
+func encodeFrame(rec Record) []byte {
+	body, flag := rec.SAE, uint32(0)
+	if rec.Pruned() {
+		body, flag = rec.SAEHash, prunedBit
+	}
+	b := make([]byte, frameHeader, frameHeader+64+len(rec.Actor)+len(rec.ActionType)+len(rec.PrevSAI)+len(body)+len(rec.SAI))
+	b = appendString16(b, []byte(rec.Actor))
+	b = binary.BigEndian.AppendUint64(b, rec.Counter)
+	b = appendString16(b, []byte(rec.ActionType))
+	b = appendString16(b, rec.PrevSAI)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body))|flag)
+	b = append(b, body...)
+	b = appendString16(b, rec.SAI)
+	b = binary.BigEndian.AppendUint64(b, uint64(rec.AcceptedAt))
+	payload := b[frameHeader:]
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.Checksum(payload, castagnoli))
+	return b
+}
+
+func appendString16(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func decodeFrame(frame []byte) (Record, error) {
+	if len(frame) < frameHeader {
+		return Record{}, errors.New("short frame")
+	}
+	payload := frame[frameHeader:]
+	if int(binary.BigEndian.Uint32(frame[0:4])) != len(payload) {
+		return Record{}, errors.New("length mismatch")
+	}
+	if crc32.Checksum(payload, castagnoli) != binary.BigEndian.Uint32(frame[4:8]) {
+		return Record{}, errors.New("CRC mismatch")
+	}
+	r := frameReader{b: payload}
+	var rec Record
+	rec.Actor = string(r.bytes16())
+	rec.Counter = r.uint64()
+	rec.ActionType = string(r.bytes16())
+	rec.PrevSAI = r.bytes16()
+	if n := r.uint32(); n&prunedBit != 0 {
+		rec.SAEHash = r.bytesN(int(n &^ prunedBit))
+	} else {
+		rec.SAE = r.bytesN(int(n))
+	}
+	rec.SAI = r.bytes16()
+	rec.AcceptedAt = int64(r.uint64())
+	if r.err || len(r.b) != 0 {
+		return Record{}, errors.New("malformed payload")
+	}
+	return rec, nil
+}
+
+type frameReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *frameReader) bytesN(n int) []byte {
+	if r.err || n > len(r.b) {
+		r.err = true
+		return nil
+	}
+	out := bytes.Clone(r.b[:n])
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *frameReader) bytes16() []byte {
+	if b := r.bytesN(2); b != nil {
+		return r.bytesN(int(binary.BigEndian.Uint16(b)))
+	}
+	return nil
+}
+
+func (r *frameReader) uint32() uint32 {
+	if b := r.bytesN(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *frameReader) uint64() uint64 {
+	if b := r.bytesN(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// scanFrames 從 off 讀到第一個不完整或 CRC 錯誤的 frame，回傳其 entry 與最後完好的位置
+func scanFrames(f *os.File, off, size int64) ([]indexEntry, int64, error) {
+	br := bufio.NewReader(io.NewSectionReader(f, off, size-off))
+	var entries []indexEntry
+	header := make([]byte, frameHeader)
+	for off < size {
+		if _, err := io.ReadFull(br, header); err != nil {
+			break
+		}
+		n := int64(binary.BigEndian.Uint32(header[0:4]))
+		if off+frameHeader+n > size {
+			break
+		}
+		frame := make([]byte, frameHeader+n)
+		copy(frame, header)
+		if _, err := io.ReadFull(br, frame[frameHeader:]); err != nil {
+			return nil, 0, fmt.Errorf("history: %w", err)
+		}
+		rec, err := decodeFrame(frame)
+		if err != nil {
+			break
+		}
+		entries = append(entries, indexEntry{off: off, n: uint32(len(frame)), counter: rec.Counter, actor: rec.Actor})
+		off += int64(len(frame))
+	}
+	return entries, off, nil
+}
+
+// indexEntry: [offset u64][frame 長度 u32][counter u64][actor (u16 長度)]
+type indexEntry struct {
+	off     int64
+	n       uint32
+	counter uint64
+	actor   string
+}
+
+func (e indexEntry) encode() []byte {
+	b := make([]byte, 0, 22+len(e.actor))
+	b = binary.BigEndian.AppendUint64(b, uint64(e.off))
+	b = binary.BigEndian.AppendUint32(b, e.n)
+	b = binary.BigEndian.AppendUint64(b, e.counter)
+	return appendString16(b, []byte(e.actor))
+}
+
+// readIndex 讀到第一個不完整的 entry 為止；clean 表示整個檔案都已讀完
+func readIndex(f *os.File) (entries []indexEntry, clean bool) {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, math.MaxInt64))
+	if err != nil {
+		return nil, false
+	}
+	r := frameReader{b: b}
+	for len(r.b) > 0 {
+		e := indexEntry{off: int64(r.uint64()), n: r.uint32(), counter: r.uint64(), actor: string(r.bytes16())}
+		if r.err {
+			return entries, false
+		}
+		entries = append(entries, e)
+	}
+	return entries, true
+}
+
+func rewriteIndex(f *os.File, entries []indexEntry) error {
+	var b []byte
+	for _, e := range entries {
+		b = append(b, e.encode()...)
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if _, err := f.WriteAt(b, 0); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	_, err := f.Seek(int64(len(b)), io.SeekStart)
+	return err
+}
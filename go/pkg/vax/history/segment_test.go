@@ -0,0 +1,136 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openSegments(t *testing.T, dir string, opts ...SegmentOption) *SegmentStore {
+	t.Helper()
+	s, err := OpenSegments(dir, opts...)
+	if err != nil {
+		t.Fatalf("OpenSegments: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func appendAll(t *testing.T, s Store, recs []Record) {
+	t.Helper()
+	for _, rec := range recs {
+		if err := s.Append(rec); err != nil {
+			t.Fatalf("Append %s/%d: %v", rec.Actor, rec.Counter, err)
+		}
+	}
+}
+
+func counters(t *testing.T, s Store, actor string) []uint64 {
+	t.Helper()
+	var got []uint64
+	if err := s.Range(actor, 0, ^uint64(0), func(r Record) error {
+		got = append(got, r.Counter)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	return got
+}
+
+func TestSegmentStore(t *testing.T) {
+	t.Run("survives reopen across rolled segments", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir, WithSegmentSize(300))
+		appendAll(t, s, chain("alice", 6))
+		appendAll(t, s, chain("bob", 2))
+		s.Close()
+		if logs, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(logs) < 3 {
+			t.Errorf("expected rolled segments, got %v", logs)
+		}
+
+		s = openSegments(t, dir, WithSegmentSize(300))
+		if got := counters(t, s, "alice"); len(got) != 6 {
+			t.Fatalf("alice = %v", got)
+		}
+		want := chain("bob", 3)
+		head, err := s.Head("bob")
+		if err != nil || head.Counter != 2 || !bytes.Equal(head.SAE, want[1].SAE) {
+			t.Fatalf("Head = %+v, %v", head, err)
+		}
+		// 重開後仍以磁碟上的 head 檢查順序
+		if err := s.Append(want[2]); err != nil {
+			t.Errorf("Append after reopen: %v", err)
+		}
+		if err := s.Append(chain("alice", 6)[5]); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("expected ErrOutOfOrder, got %v", err)
+		}
+	})
+
+	t.Run("truncates a torn tail", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir, WithoutSync())
+		appendAll(t, s, chain("alice", 3))
+		s.Close()
+		log := filepath.Join(dir, "000001.log")
+		info, _ := os.Stat(log)
+		_ = os.Truncate(log, info.Size()-5)
+
+		s = openSegments(t, dir)
+		if got := counters(t, s, "alice"); len(got) != 2 {
+			t.Fatalf("alice = %v", got)
+		}
+		appendAll(t, s, chain("alice", 3)[2:])
+	})
+
+	t.Run("rebuilds a lost index", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir)
+		appendAll(t, s, chain("alice", 3))
+		s.Close()
+		_ = os.Remove(filepath.Join(dir, "000001.idx"))
+
+		s = openSegments(t, dir)
+		if got := counters(t, s, "alice"); len(got) != 3 {
+			t.Fatalf("alice = %v", got)
+		}
+		s.Close()
+		if info, err := os.Stat(filepath.Join(dir, "000001.idx")); err != nil || info.Size() == 0 {
+			t.Errorf("index not rewritten: %v", err)
+		}
+	})
+
+	t.Run("error: bad CRC on read", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir)
+		appendAll(t, s, chain("alice", 2))
+		f, _ := os.OpenFile(filepath.Join(dir, "000001.log"), os.O_RDWR, 0)
+		_, _ = f.WriteAt([]byte{0xff}, 20)
+		f.Close()
+		if _, err := s.GetByCounter("alice", 1); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt, got %v", err)
+		}
+	})
+
+	t.Run("error: damaged sealed segment", func(t *testing.T) {
+		dir := t.TempDir()
+		s := openSegments(t, dir, WithSegmentSize(100))
+		appendAll(t, s, chain("alice", 3))
+		s.Close()
+		_ = os.Remove(filepath.Join(dir, "000001.idx"))
+		info, _ := os.Stat(filepath.Join(dir, "000001.log"))
+		_ = os.Truncate(filepath.Join(dir, "000001.log"), info.Size()-1)
+		if _, err := OpenSegments(dir); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt, got %v", err)
+		}
+	})
+
+	t.Run("error: closed store", func(t *testing.T) {
+		s := openSegments(t, t.TempDir())
+		s.Close()
+		if err := s.Append(chain("alice", 1)[0]); err == nil {
+			t.Error("expected an error after Close")
+		}
+	})
+}
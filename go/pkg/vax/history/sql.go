@@ -0,0 +1,492 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Dialect adapts SQLStore's statements to one database.
+type Dialect struct {
+	Name      string
+	BlobType  string // column type of byte columns
+	ForUpdate string // suffix locking the head row inside a transaction
+	// Bind rewrites the statement's ? placeholders (nil: leave them).
+	Bind func(query string) string
+}
+
+// SQLite and Postgres are the supported dialects; register any
+// database/sql driver for them (e.g. modernc.org/sqlite, pgx/stdlib).
+var (
+	SQLite   = Dialect{Name: "sqlite", BlobType: "BLOB"}
+	Postgres = Dialect{Name: "postgres", BlobType: "BYTEA", ForUpdate: " FOR UPDATE", Bind: dollarPlaceholders}
+)
+
+// DefaultBatchSize is the number of rows per INSERT in AppendBatch (8
+// parameters each, below SQLite's default limit of 999).
+const DefaultBatchSize = 100
+
+// SQLStore is a Store in a SQL database, one row per record with a primary
+// key on (actor, counter) and an index on accepted_at; checkpoints live in
+// <table>_checkpoints. Call Migrate once at startup.
+type SQLStore struct {
+	db        *sql.DB
+	d         Dialect
+	table     string
+	batchSize int
+	outbox    bool
+}
+
+// SQLOption configures NewSQL.
+type SQLOption func(*SQLStore)
+
+// WithTable sets the table name (default "vax_history"); its migration
+// bookkeeping lives in <table>_migrations.
+func WithTable(name string) SQLOption {
+	return func(s *SQLStore) {
+		s.table = name
+	}
+}
+
+// WithBatchSize sets the rows per INSERT statement of AppendBatch.
+func WithBatchSize(n int) SQLOption {
+	return func(s *SQLStore) {
+		s.batchSize = max(n, 1)
+	}
+}
+
+// WithOutbox records every append in <table>_outbox within the same
+// transaction; RelayOutbox then streams the records to a Sink, so each
+// committed record is published at least once even across crashes.
+func WithOutbox() SQLOption {
+	return func(s *SQLStore) {
+		s.outbox = true
+	}
+}
+
+var _ ContextStore = (*SQLStore)(nil)
+
+// NewSQL panics if db is nil.
+func NewSQL(db *sql.DB, d Dialect, opts ...SQLOption) *SQLStore {
+	if db == nil {
+		panic("history: NewSQL needs a database")
+	}
+	s := &SQLStore{db: db, d: d, table: "vax_history", batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// migrations 依序套用，版本號即索引 + 1；已發布的項目不可修改
+func (s *SQLStore) migrations() []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	action_type TEXT NOT NULL,
+	prev_sai    %[2]s NOT NULL,
+	sae         %[2]s NOT NULL,
+	sai         %[2]s NOT NULL,
+	accepted_at BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`, s.table, s.d.BlobType),
+		fmt.Sprintf(`CREATE INDEX %[1]s_accepted_at ON %[1]s (accepted_at)`, s.table),
+		fmt.Sprintf(`CREATE TABLE %s_checkpoints (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	sai         %[2]s NOT NULL,
+	merkle_root %[2]s NOT NULL,
+	frontier    %[2]s NOT NULL,
+	verified_at BIGINT NOT NULL,
+	verifier    TEXT NOT NULL,
+	attestation %[2]s NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`, s.table, s.d.BlobType),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN sae_sha256 %s`, s.table, s.d.BlobType),
+		fmt.Sprintf(`CREATE TABLE %s_outbox (
+	actor   TEXT NOT NULL,
+	counter BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`, s.table),
+	}
+}
+
+// Migrate brings the schema to the latest version, applying each pending
+// migration in its own transaction. It is safe to call on every start.
+func (s *SQLStore) Migrate() error {
+	ctx := context.Background()
+	meta := s.table + "_migrations"
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version INTEGER PRIMARY KEY)`, meta)); err != nil {
+		return fmt.Errorf("history: migrate: %w", err)
+	}
+	var current int
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s`, meta)).Scan(&current); err != nil {
+		return fmt.Errorf("history: migrate: %w", err)
+	}
+	for i, stmt := range s.migrations() {
+		version := i + 1
+		if version <= current {
+			continue
+		}
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, s.bind(fmt.Sprintf(`INSERT INTO %s (version) VALUES (?)`, meta)), version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("history: migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+const columns = "actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256"
+
+func (s *SQLStore) Append(rec Record) error {
+	return s.AppendContext(context.Background(), rec)
+}
+
+func (s *SQLStore) AppendContext(ctx context.Context, rec Record) error {
+	return s.AppendBatchContext(ctx, []Record{rec})
+}
+
+// AppendBatch appends consecutive records of one or more actors in a
+// single transaction, batchSize rows per INSERT: either all are stored or
+// none. Ordering is checked as for Append.
+func (s *SQLStore) AppendBatch(recs []Record) error {
+	return s.AppendBatchContext(context.Background(), recs)
+}
+
+// AppendBatchContext is AppendBatch within ctx; a cancelled context rolls
+// the transaction back.
+func (s *SQLStore) AppendBatchContext(ctx context.Context, recs []Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		heads := map[string]*Record{}
+		for _, rec := range recs {
+			head, seen := heads[rec.Actor]
+			if !seen {
+				h, err := s.head(ctx, tx, rec.Actor, s.d.ForUpdate)
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					return err
+				}
+				if err == nil {
+					head = &h
+				}
+			}
+			if err := CheckNext(head, rec); err != nil {
+				return err
+			}
+			heads[rec.Actor] = &rec
+		}
+		for start := 0; start < len(recs); start += s.batchSize {
+			chunk := recs[start:min(start+s.batchSize, len(recs))]
+			rows := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?), ", len(chunk)), ", ")
+			args := make([]any, 0, 8*len(chunk))
+			for _, r := range chunk {
+				var hash, body any = nil, r.SAE
+				if r.Pruned() {
+					hash, body = r.SAEHash, []byte{}
+				}
+				args = append(args, r.Actor, int64(r.Counter), r.ActionType, r.PrevSAI, body, r.SAI, r.AcceptedAt, hash)
+			}
+			query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s`, s.table, columns, rows)
+			if _, err := tx.ExecContext(ctx, s.bind(query), args...); err != nil {
+				return err
+			}
+			if s.outbox {
+				rows := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(chunk)), ", ")
+				args := make([]any, 0, 2*len(chunk))
+				for _, r := range chunk {
+					args = append(args, r.Actor, int64(r.Counter))
+				}
+				query := fmt.Sprintf(`INSERT INTO %s_outbox (actor, counter) VALUES %s`, s.table, rows)
+				if _, err := tx.ExecContext(ctx, s.bind(query), args...); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil || errors.Is(err, ErrOutOfOrder) || errors.Is(err, ErrInvalid) || ctx.Err() != nil {
+		return err
+	}
+	// 並行寫入同一位置時主鍵衝突（錯誤型別依 driver 而異）：重讀 head 判斷
+	if head, herr := s.HeadContext(ctx, recs[0].Actor); herr == nil && head.Counter >= recs[0].Counter {
+		return fmt.Errorf("%w: %s counter %d already stored", ErrOutOfOrder, recs[0].Actor, recs[0].Counter)
+	}
+	return fmt.Errorf("history: append: %w", err)
+}
+
+func (s *SQLStore) GetByCounter(actor string, counter uint64) (Record, error) {
+	return s.GetByCounterContext(context.Background(), actor, counter)
+}
+
+func (s *SQLStore) GetByCounterContext(ctx context.Context, actor string, counter uint64) (Record, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE actor = ? AND counter = ?`, columns, s.table)
+	rec, err := scanRecord(s.db.QueryRowContext(ctx, s.bind(query), actor, int64(counter)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// Range streams the rows from the database cursor: records are not
+// loaded all at once, so full-chain audits run in constant memory.
+func (s *SQLStore) Range(actor string, from, to uint64, fn func(Record) error) error {
+	return s.RangeContext(context.Background(), actor, from, to, fn)
+}
+
+// RangeContext is Range on a cursor bound to ctx: cancelling ctx closes the
+// rows and the scan returns ctx.Err().
+func (s *SQLStore) RangeContext(ctx context.Context, actor string, from, to uint64, fn func(Record) error) error {
+	if to > 1<<63-1 {
+		to = 1<<63 - 1
+	}
+	if from > to {
+		return nil
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE actor = ? AND counter >= ? AND counter <= ? ORDER BY counter`, columns, s.table)
+	rows, err := s.db.QueryContext(ctx, s.bind(query), actor, int64(from), int64(to))
+	if err != nil {
+		return fmt.Errorf("history: range: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *SQLStore) Head(actor string) (Record, error) {
+	return s.HeadContext(context.Background(), actor)
+}
+
+func (s *SQLStore) HeadContext(ctx context.Context, actor string) (Record, error) {
+	return s.head(ctx, s.db, actor, "")
+}
+
+// Actors lists the actors with records (sorted).
+func (s *SQLStore) Actors() ([]string, error) {
+	rows, err := s.db.QueryContext(context.Background(), fmt.Sprintf(`SELECT DISTINCT actor FROM %s ORDER BY actor`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("history: actors: %w", err)
+	}
+	defer rows.Close()
+	var actors []string
+	for rows.Next() {
+		var actor string
+		if err := rows.Scan(&actor); err != nil {
+			return nil, err
+		}
+		actors = append(actors, actor)
+	}
+	return actors, rows.Err()
+}
+
+var _ Pruner = (*SQLStore)(nil)
+
+// Prune empties the sae column of the selected rows and stores their
+// digest in sae_sha256, in one transaction.
+func (s *SQLStore) Prune(actor string, from, to uint64) (int, error) {
+	var recs []Record
+	err := s.Range(actor, from, to, func(rec Record) error {
+		if rec.Pruned() {
+			return nil
+		}
+		p, err := pruned(rec)
+		recs = append(recs, p)
+		return err
+	})
+	if err != nil || len(recs) == 0 {
+		return 0, err
+	}
+	ctx := context.Background()
+	query := s.bind(fmt.Sprintf(`UPDATE %s SET sae = ?, sae_sha256 = ? WHERE actor = ? AND counter = ?`, s.table))
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, rec := range recs {
+			if _, err := tx.ExecContext(ctx, query, []byte{}, rec.SAEHash, actor, int64(rec.Counter)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("history: prune: %w", err)
+	}
+	return len(recs), nil
+}
+
+// RelayOutbox publishes up to limit pending outbox entries to sink, each
+// actor's in counter order, deleting each entry once published. It stops
+// at the first failed publish, which is retried by the next call; a crash
+// between publishing and deleting redelivers the message, so consumers
+// deduplicate by Message.ID. It returns the number of messages published.
+func (s *SQLStore) RelayOutbox(sink Sink, limit int) (int, error) {
+	ctx := context.Background()
+	query := fmt.Sprintf(`SELECT actor, counter FROM %s_outbox ORDER BY actor, counter LIMIT ?`, s.table)
+	rows, err := s.db.QueryContext(ctx, s.bind(query), max(limit, 1))
+	if err != nil {
+		return 0, fmt.Errorf("history: outbox: %w", err)
+	}
+	type entry struct {
+		actor   string
+		counter int64
+	}
+	var pending []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.actor, &e.counter); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("history: outbox: %w", err)
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("history: outbox: %w", err)
+	}
+
+	del := s.bind(fmt.Sprintf(`DELETE FROM %s_outbox WHERE actor = ? AND counter = ?`, s.table))
+	for i, e := range pending {
+		rec, err := s.GetByCounter(e.actor, uint64(e.counter))
+		if err != nil {
+			return i, fmt.Errorf("history: outbox %s/%d: %w", e.actor, e.counter, err)
+		}
+		msg, err := NewMessage(rec)
+		if err != nil {
+			return i, fmt.Errorf("history: outbox %s/%d: %w", e.actor, e.counter, err)
+		}
+		if err := sink.Publish(msg); err != nil {
+			return i, err
+		}
+		if _, err := s.db.ExecContext(ctx, del, e.actor, e.counter); err != nil {
+			return i + 1, fmt.Errorf("history: outbox: %w", err)
+		}
+	}
+	return len(pending), nil
+}
+
+const checkpointColumns = "actor, counter, sai, merkle_root, frontier, verified_at, verifier, attestation"
+
+var _ CheckpointStore = (*SQLStore)(nil)
+
+// SaveCheckpoint stores cp in <table>_checkpoints; the Frontier is kept as
+// its concatenated node hashes (its size is the counter).
+func (s *SQLStore) SaveCheckpoint(cp Checkpoint) error {
+	if cp.Frontier.Size != cp.Counter {
+		return fmt.Errorf("%w: checkpoint frontier size %d at counter %d", ErrInvalid, cp.Frontier.Size, cp.Counter)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s_checkpoints (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (actor, counter) DO NOTHING`, s.table, checkpointColumns)
+	_, err := s.db.ExecContext(context.Background(), s.bind(query), cp.Actor, int64(cp.Counter), cp.SAI, cp.MerkleRoot,
+		bytes.Join(cp.Frontier.Nodes, nil), cp.VerifiedAt, cp.Verifier, cp.Attestation)
+	if err != nil {
+		return fmt.Errorf("history: save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) LastCheckpoint(actor string) (Checkpoint, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s_checkpoints WHERE actor = ? ORDER BY counter DESC LIMIT 1`, checkpointColumns, s.table)
+	var cp Checkpoint
+	var counter int64
+	var nodes []byte
+	err := s.db.QueryRowContext(context.Background(), s.bind(query), actor).
+		Scan(&cp.Actor, &counter, &cp.SAI, &cp.MerkleRoot, &nodes, &cp.VerifiedAt, &cp.Verifier, &cp.Attestation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Checkpoint{}, ErrNotFound
+	}
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("history: last checkpoint: %w", err)
+	}
+	if len(nodes)%sha256.Size != 0 || len(nodes)/sha256.Size != bits.OnesCount64(uint64(counter)) {
+		return Checkpoint{}, fmt.Errorf("%w: checkpoint frontier of %s", ErrInvalid, actor)
+	}
+	cp.Counter = uint64(counter)
+	cp.Frontier.Size = cp.Counter
+	for len(nodes) > 0 {
+		cp.Frontier.Nodes = append(cp.Frontier.Nodes, nodes[:sha256.Size:sha256.Size])
+		nodes = nodes[sha256.Size:]
+	}
+	return cp, nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *SQLStore) head(ctx context.Context, q querier, actor, suffix string) (Record, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE actor = ? ORDER BY counter DESC LIMIT 1%s`, columns, s.table, suffix)
+	rec, err := scanRecord(q.QueryRowContext(ctx, s.bind(query), actor))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+func (s *SQLStore) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) bind(query string) string {
+	if s.d.Bind == nil {
+		return query
+	}
+	return s.d.Bind(query)
+}
+
+func scanRecord(row interface{ Scan(...any) error }) (Record, error) {
+	var rec Record
+	var counter int64
+	err := row.Scan(&rec.Actor, &counter, &rec.ActionType, &rec.PrevSAI, &rec.SAE, &rec.SAI, &rec.AcceptedAt, &rec.SAEHash)
+	rec.Counter = uint64(counter)
+	if rec.Pruned() {
+		rec.SAE = nil
+	} else {
+		rec.SAEHash = nil
+	}
+	return rec, err
+}
+
+// dollarPlaceholders 把 ? 換成 $1, $2, ...（語句中不含字串常值）
+func dollarPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,459 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeDB 是只認得 SQLStore 語句的 database/sql driver（沙箱內沒有真正的 SQLite / Postgres）
+type fakeDB struct {
+	version int
+	rows    map[string][]driver.Value // key: actor/counter
+	cps     map[string][]driver.Value // checkpoints, key: actor/counter
+	outbox  map[string][]driver.Value // key: actor/counter
+	log     []string
+	snap    *fakeDB
+	failOn  string // 執行含此字串的語句時回傳錯誤
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	snap := &fakeDB{version: c.db.version, rows: maps.Clone(c.db.rows), outbox: maps.Clone(c.db.outbox)}
+	c.db.snap = snap
+	return c, nil
+}
+func (c fakeConn) Commit() error { c.db.snap = nil; return nil }
+func (c fakeConn) Rollback() error {
+	c.db.version, c.db.rows, c.db.outbox, c.db.snap = c.db.snap.version, c.db.snap.rows, c.db.snap.outbox, nil
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+var placeholders = regexp.MustCompile(`\$\d+`)
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.log = append(s.db.log, s.query)
+	q := placeholders.ReplaceAllString(s.query, "?")
+	if s.db.failOn != "" && strings.Contains(q, s.db.failOn) {
+		return nil, errors.New("fake: disk I/O error")
+	}
+	switch {
+	case strings.HasPrefix(q, "CREATE"), strings.HasPrefix(q, "ALTER"):
+	case strings.Contains(q, "_migrations (version) VALUES"):
+		s.db.version = int(args[0].(int64))
+	case strings.Contains(q, "_outbox (actor, counter) VALUES"):
+		for i := 0; i < len(args); i += 2 {
+			s.db.outbox[fmt.Sprint(args[i], "/", args[i+1])] = args[i : i+2]
+		}
+	case strings.HasPrefix(q, "DELETE FROM"):
+		delete(s.db.outbox, fmt.Sprint(args[0], "/", args[1]))
+	case strings.Contains(q, "_checkpoints (actor"):
+		key := fmt.Sprint(args[0], "/", args[1])
+		if _, dup := s.db.cps[key]; !dup {
+			s.db.cps[key] = args
+		}
+	case strings.HasPrefix(q, "INSERT INTO"):
+		for i := 0; i < len(args); i += 8 {
+			key := fmt.Sprint(args[i], "/", args[i+1])
+			if _, dup := s.db.rows[key]; dup {
+				return nil, errors.New("UNIQUE constraint failed")
+			}
+			s.db.rows[key] = args[i : i+8]
+		}
+	case strings.HasPrefix(q, "UPDATE"):
+		key := fmt.Sprint(args[2], "/", args[3])
+		row := slices.Clone(s.db.rows[key])
+		row[4], row[7] = args[0], args[1]
+		s.db.rows[key] = row
+	default:
+		return nil, fmt.Errorf("fake: unexpected exec %q", q)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.log = append(s.db.log, s.query)
+	q := placeholders.ReplaceAllString(s.query, "?")
+	if strings.Contains(q, "MAX(version)") {
+		return &fakeRows{cols: []string{"v"}, rows: [][]driver.Value{{int64(s.db.version)}}}, nil
+	}
+	if strings.Contains(q, "DISTINCT actor") {
+		seen := map[string]bool{}
+		var out [][]driver.Value
+		for _, r := range s.db.rows {
+			if a := r[0].(string); !seen[a] {
+				seen[a] = true
+				out = append(out, []driver.Value{a})
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i][0].(string) < out[j][0].(string) })
+		return &fakeRows{cols: []string{"actor"}, rows: out}, nil
+	}
+	if strings.Contains(q, "_outbox ORDER BY") {
+		var out [][]driver.Value
+		for _, e := range s.db.outbox {
+			out = append(out, e)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i][0] != out[j][0] {
+				return out[i][0].(string) < out[j][0].(string)
+			}
+			return out[i][1].(int64) < out[j][1].(int64)
+		})
+		return &fakeRows{cols: []string{"actor", "counter"}, rows: out[:min(len(out), int(args[0].(int64)))]}, nil
+	}
+	actor := args[0].(string)
+	if strings.Contains(q, "_checkpoints WHERE") {
+		var last []driver.Value
+		for _, c := range s.db.cps {
+			if c[0] == actor && (last == nil || c[1].(int64) > last[1].(int64)) {
+				last = c
+			}
+		}
+		rows := &fakeRows{cols: strings.Split(checkpointColumns, ", ")}
+		if last != nil {
+			rows.rows = [][]driver.Value{last}
+		}
+		return rows, nil
+	}
+	var recs [][]driver.Value
+	for _, r := range s.db.rows {
+		if r[0] == actor {
+			recs = append(recs, r)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i][1].(int64) < recs[j][1].(int64) })
+	switch {
+	case strings.Contains(q, "counter = ?"):
+		recs = filter(recs, func(c int64) bool { return c == args[1].(int64) })
+	case strings.Contains(q, "counter >= ?"):
+		recs = filter(recs, func(c int64) bool { return c >= args[1].(int64) && c <= args[2].(int64) })
+	case strings.Contains(q, "DESC LIMIT 1"):
+		if len(recs) > 0 {
+			recs = recs[len(recs)-1:]
+		}
+	default:
+		return nil, fmt.Errorf("fake: unexpected query %q", q)
+	}
+	return &fakeRows{cols: strings.Split(columns, ", "), rows: recs}, nil
+}
+
+func filter(recs [][]driver.Value, keep func(int64) bool) [][]driver.Value {
+	var out [][]driver.Value
+	for _, r := range recs {
+		if keep(r[1].(int64)) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func newSQLStore(t *testing.T, d Dialect, opts ...SQLOption) (*SQLStore, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{rows: map[string][]driver.Value{}, cps: map[string][]driver.Value{}, outbox: map[string][]driver.Value{}}
+	db := sql.OpenDB(fake)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	s := NewSQL(db, d, opts...)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return s, fake
+}
+
+func TestSQLStore(t *testing.T) {
+	t.Run("migrates once", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite)
+		if fake.version != 5 {
+			t.Fatalf("version = %d", fake.version)
+		}
+		n := len(fake.log)
+		if err := s.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range fake.log[n:] {
+			if strings.HasPrefix(q, "CREATE TABLE vax_history") || strings.HasPrefix(q, "CREATE INDEX") {
+				t.Errorf("migration re-applied: %s", q)
+			}
+		}
+	})
+
+	t.Run("append, lookup and stream", func(t *testing.T) {
+		s, _ := newSQLStore(t, SQLite)
+		for _, rec := range chain("alice", 3) {
+			if err := s.Append(rec); err != nil {
+				t.Fatalf("Append %d: %v", rec.Counter, err)
+			}
+		}
+		head, err := s.Head("alice")
+		if err != nil || head.Counter != 3 || !bytes.Equal(head.SAI, chain("alice", 3)[2].SAI) {
+			t.Fatalf("Head = %+v, %v", head, err)
+		}
+		rec, err := s.GetByCounter("alice", 2)
+		if err != nil || rec.ActionType != "transfer" || !bytes.Equal(rec.PrevSAI, chain("alice", 1)[0].SAI) {
+			t.Errorf("GetByCounter = %+v, %v", rec, err)
+		}
+		var got []uint64
+		err = s.Range("alice", 2, ^uint64(0), func(r Record) error {
+			got = append(got, r.Counter)
+			return nil
+		})
+		if err != nil || len(got) != 2 || got[0] != 2 {
+			t.Errorf("Range = %v, %v", got, err)
+		}
+	})
+
+	t.Run("lists actors", func(t *testing.T) {
+		s, _ := newSQLStore(t, SQLite)
+		appendAll(t, s, chain("bob", 1))
+		appendAll(t, s, chain("alice", 2))
+		if got, err := s.Actors(); err != nil || strings.Join(got, ",") != "alice,bob" {
+			t.Errorf("Actors = %v, %v", got, err)
+		}
+	})
+
+	t.Run("batched inserts in one transaction", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite, WithBatchSize(2))
+		n := len(fake.log)
+		if err := s.AppendBatch(append(chain("alice", 5), chain("bob", 1)...)); err != nil {
+			t.Fatal(err)
+		}
+		inserts := 0
+		for _, q := range fake.log[n:] {
+			if strings.HasPrefix(q, "INSERT INTO vax_history ") {
+				inserts++
+			}
+		}
+		if inserts != 3 || len(fake.rows) != 6 {
+			t.Errorf("%d INSERTs for %d rows", inserts, len(fake.rows))
+		}
+	})
+
+	t.Run("postgres placeholders and row lock", func(t *testing.T) {
+		s, fake := newSQLStore(t, Postgres, WithTable("audit"))
+		if err := s.Append(chain("alice", 1)[0]); err != nil {
+			t.Fatal(err)
+		}
+		joined := strings.Join(fake.log, "\n")
+		for _, want := range []string{"BYTEA", "actor = $1 ORDER BY counter DESC LIMIT 1 FOR UPDATE", "VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", "audit_migrations"} {
+			if !strings.Contains(joined, want) {
+				t.Errorf("statements lack %q:\n%s", want, joined)
+			}
+		}
+	})
+
+	t.Run("checkpoints", func(t *testing.T) {
+		s, _ := newSQLStore(t, Postgres)
+		if _, err := s.LastCheckpoint("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("LastCheckpoint: %v", err)
+		}
+		appendAll(t, s, envelopeChain(t, "alice", 3))
+		for _, n := range []uint64{2, 3} {
+			var f Frontier
+			_ = s.Range("alice", 1, n, func(r Record) error { f.Push(r.SAI); return nil })
+			cp := Checkpoint{Actor: "alice", Counter: n, SAI: []byte{byte(n)}, MerkleRoot: f.Root(), Frontier: f, Verifier: "auditor", Attestation: []byte("{}")}
+			if err := s.SaveCheckpoint(cp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cp, err := s.LastCheckpoint("alice")
+		if err != nil || cp.Counter != 3 || cp.Frontier.Size != 3 || !bytes.Equal(cp.Frontier.Root(), cp.MerkleRoot) {
+			t.Errorf("LastCheckpoint = %+v, %v", cp, err)
+		}
+	})
+
+	t.Run("error: out of order batch stores nothing", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite)
+		recs := chain("alice", 3)
+		if err := s.AppendBatch([]Record{recs[0], recs[2]}); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("expected ErrOutOfOrder, got %v", err)
+		}
+		if len(fake.rows) != 0 {
+			t.Errorf("%d rows stored", len(fake.rows))
+		}
+	})
+
+	t.Run("error: failed insert rolls back the batch", func(t *testing.T) {
+		s, fake := newSQLStore(t, SQLite, WithBatchSize(1))
+		fake.failOn = "VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+		err := s.AppendBatch(chain("alice", 2))
+		if err == nil || errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("expected a database error, got %v", err)
+		}
+		if len(fake.rows) != 0 {
+			t.Errorf("%d rows stored", len(fake.rows))
+		}
+	})
+
+	t.Run("error: duplicate position", func(t *testing.T) {
+		s, _ := newSQLStore(t, SQLite)
+		rec := chain("alice", 1)[0]
+		_ = s.Append(rec)
+		if err := s.Append(rec); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("expected ErrOutOfOrder, got %v", err)
+		}
+	})
+
+	t.Run("error: unknown records", func(t *testing.T) {
+		s, _ := newSQLStore(t, SQLite)
+		if _, err := s.Head("nobody"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Head: %v", err)
+		}
+		if _, err := s.GetByCounter("nobody", 1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetByCounter: %v", err)
+		}
+	})
+}
+
+// TestSQLDialects 逐字比對各 dialect 產生的語句（DDL、批次 INSERT、FOR UPDATE、placeholder）
+func TestSQLDialects(t *testing.T) {
+	want := map[string][]string{
+		"sqlite": {
+			"CREATE TABLE IF NOT EXISTS vax_history_migrations (version INTEGER PRIMARY KEY)",
+			`CREATE TABLE vax_history (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	action_type TEXT NOT NULL,
+	prev_sai    BLOB NOT NULL,
+	sae         BLOB NOT NULL,
+	sai         BLOB NOT NULL,
+	accepted_at BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"CREATE INDEX vax_history_accepted_at ON vax_history (accepted_at)",
+			`CREATE TABLE vax_history_checkpoints (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	sai         BLOB NOT NULL,
+	merkle_root BLOB NOT NULL,
+	frontier    BLOB NOT NULL,
+	verified_at BIGINT NOT NULL,
+	verifier    TEXT NOT NULL,
+	attestation BLOB NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"ALTER TABLE vax_history ADD COLUMN sae_sha256 BLOB",
+			`CREATE TABLE vax_history_outbox (
+	actor   TEXT NOT NULL,
+	counter BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"INSERT INTO vax_history_migrations (version) VALUES (?)",
+			"SELECT actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256 FROM vax_history WHERE actor = ? ORDER BY counter DESC LIMIT 1",
+			"INSERT INTO vax_history (actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256) VALUES (?, ?, ?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?, ?, ?)",
+			"INSERT INTO vax_history_outbox (actor, counter) VALUES (?, ?), (?, ?)",
+			"SELECT actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256 FROM vax_history WHERE actor = ? ORDER BY counter DESC LIMIT 1",
+			"INSERT INTO vax_history_checkpoints (actor, counter, sai, merkle_root, frontier, verified_at, verifier, attestation) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (actor, counter) DO NOTHING",
+		},
+		"postgres": {
+			"CREATE TABLE IF NOT EXISTS vax_history_migrations (version INTEGER PRIMARY KEY)",
+			`CREATE TABLE vax_history (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	action_type TEXT NOT NULL,
+	prev_sai    BYTEA NOT NULL,
+	sae         BYTEA NOT NULL,
+	sai         BYTEA NOT NULL,
+	accepted_at BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"CREATE INDEX vax_history_accepted_at ON vax_history (accepted_at)",
+			`CREATE TABLE vax_history_checkpoints (
+	actor       TEXT NOT NULL,
+	counter     BIGINT NOT NULL,
+	sai         BYTEA NOT NULL,
+	merkle_root BYTEA NOT NULL,
+	frontier    BYTEA NOT NULL,
+	verified_at BIGINT NOT NULL,
+	verifier    TEXT NOT NULL,
+	attestation BYTEA NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"ALTER TABLE vax_history ADD COLUMN sae_sha256 BYTEA",
+			`CREATE TABLE vax_history_outbox (
+	actor   TEXT NOT NULL,
+	counter BIGINT NOT NULL,
+	PRIMARY KEY (actor, counter)
+)`,
+			"INSERT INTO vax_history_migrations (version) VALUES ($1)",
+			"SELECT actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256 FROM vax_history WHERE actor = $1 ORDER BY counter DESC LIMIT 1 FOR UPDATE",
+			"INSERT INTO vax_history (actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16)",
+			"INSERT INTO vax_history_outbox (actor, counter) VALUES ($1, $2), ($3, $4)",
+			"SELECT actor, counter, action_type, prev_sai, sae, sai, accepted_at, sae_sha256 FROM vax_history WHERE actor = $1 ORDER BY counter DESC LIMIT 1",
+			"INSERT INTO vax_history_checkpoints (actor, counter, sai, merkle_root, frontier, verified_at, verifier, attestation) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (actor, counter) DO NOTHING",
+		},
+	}
+	for _, d := range []Dialect{SQLite, Postgres} {
+		t.Run(d.Name, func(t *testing.T) {
+			s, fake := newSQLStore(t, d, WithBatchSize(2), WithOutbox())
+			if err := s.AppendBatch(chain("alice", 2)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Head("alice"); err != nil {
+				t.Fatal(err)
+			}
+			var f Frontier
+			f.Push(chain("alice", 1)[0].SAI)
+			if err := s.SaveCheckpoint(Checkpoint{Actor: "alice", Counter: 1, Frontier: f}); err != nil {
+				t.Fatal(err)
+			}
+			for _, stmt := range want[d.Name] {
+				if !slices.Contains(fake.log, stmt) {
+					t.Errorf("missing statement:\n%s", stmt)
+				}
+			}
+			other := "$"
+			if d.Bind != nil {
+				other = "?"
+			}
+			for _, q := range fake.log {
+				if strings.Contains(q, other) {
+					t.Errorf("%s placeholder in %s statement: %s", other, d.Name, q)
+				}
+				if d.ForUpdate == "" && strings.Contains(q, "FOR UPDATE") {
+					t.Errorf("row lock in %s statement: %s", d.Name, q)
+				}
+			}
+		})
+	}
+}
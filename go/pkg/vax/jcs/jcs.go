@@ -28,6 +28,13 @@ func CanonicalizeJSON(input []byte) ([]byte, error) {
 	return CanonicalizeValue(v)
 }
 
+// IsCanonical 回報 input 是否已是 VAX-JCS bytes（重新正規化後逐位元組相同）。
+// 無法解析的 JSON 一律不是 canonical。
+func IsCanonical(input []byte) bool {
+	canonical, err := CanonicalizeJSON(input)
+	return err == nil && bytes.Equal(canonical, input)
+}
+
 // CanonicalizeValue 入口 2：直接接受已經建好的物件 (map / struct 轉 map 等)。
 func CanonicalizeValue(v any) ([]byte, error) {
 	var buf bytes.Buffer
@@ -845,3 +845,26 @@ func TestToUint64_PanicOnUnsupported(t *testing.T) {
 	}()
 	toUint64("not-uint")
 }
+
+func TestIsCanonical(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`{"a":1,"b":[true,null,"x"]}`, true},
+		{`{"b":1,"a":2}`, false},  // key 順序
+		{`{"a": 1}`, false},       // 空白
+		{`{"a":1.0}`, false},      // 數字表示
+		{`{"a":"\u00e9"}`, true},  // ASCII-only escape
+		{`{"a":"é"}`, false},      // 未 escape 的非 ASCII
+		{`{"a":1}` + "\n", false}, // 結尾換行
+		{`{"a":1}{"b":2}`, false}, // 多個值
+		{`{"a":`, false},          // 無法解析
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := IsCanonical([]byte(tt.input)); got != tt.want {
+			t.Errorf("IsCanonical(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
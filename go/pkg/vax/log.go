@@ -0,0 +1,53 @@
+package vax
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+
+	"vax/pkg/vax/sae"
+)
+
+func debugEnabled(l *slog.Logger) bool {
+	return l != nil && l.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// saiPrefix 為 SAI 前 8 bytes 的 hex（長度不足時整個輸出）
+func saiPrefix(sai []byte) string {
+	if len(sai) > 8 {
+		sai = sai[:8]
+	}
+	return hex.EncodeToString(sai)
+}
+
+func logVerifyAction(l *slog.Logger, prevSAI, saeBytes, sai []byte, env *sae.Envelope, err error) {
+	if !debugEnabled(l) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("prev_sai", saiPrefix(prevSAI)),
+		slog.String("sai", saiPrefix(sai)),
+		slog.String("sae_sha256", sae.DigestPrefix(saeBytes)),
+	}
+	if env != nil {
+		attrs = append(attrs, slog.String("action_type", env.ActionType), slog.Uint64("counter", env.Counter))
+	}
+	if err != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "vax: action rejected", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "vax: action verified", attrs...)
+}
+
+func logAdvance(l *slog.Logger, actor string, state ChainState, next ChainState, err error) {
+	if !debugEnabled(l) {
+		return
+	}
+	attrs := []slog.Attr{slog.String("actor", actor), slog.Uint64("head_counter", state.Counter), slog.String("head_sai", saiPrefix(state.HeadSAI))}
+	if err != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "vax: advance rejected", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "vax: chain advanced",
+		append(attrs, slog.Uint64("counter", next.Counter), slog.String("sai", saiPrefix(next.HeadSAI)))...)
+}
@@ -0,0 +1,94 @@
+package vax
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// captureLogs 回傳以 debug 等級 JSON handler 寫入 buffer 的 logger
+func captureLogs() (*bytes.Buffer, *slog.Logger) {
+	var buf bytes.Buffer
+	return &buf, slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func records(buf *bytes.Buffer) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if json.Unmarshal([]byte(line), &m) == nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func TestLogger(t *testing.T) {
+	const actor = "user123:device456"
+	genesis, _ := ComputeGenesisSAI(actor, testGenesisSalt)
+	schema := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+	_, priv, _ := sae.GenerateKeyPair()
+
+	t.Run("logs build, validation and acceptance without values", func(t *testing.T) {
+		buf, l := captureLogs()
+		store := NewMemoryStore()
+		_ = store.Init(actor, genesis)
+		sub, err := SignedAction("transfer", schema, map[string]any{"amount": 777}, priv, ChainState{HeadSAI: genesis}, sae.WithLogger(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, nil, WithLogger(l)); err != nil {
+			t.Fatal(err)
+		}
+
+		var msgs []string
+		for _, r := range records(buf) {
+			msgs = append(msgs, r["msg"].(string))
+			if r["msg"] == "vax: chain advanced" && (r["actor"] != actor || r["counter"] != 1.0 || r["sai"] != hex.EncodeToString(sub.SAI[:8])) {
+				t.Errorf("advance record %v", r)
+			}
+		}
+		for _, want := range []string{"sdto: data valid", "sae: built envelope", "vax: action verified", "vax: chain advanced"} {
+			if !strings.Contains(strings.Join(msgs, "|"), want) {
+				t.Errorf("no %q record in %v", want, msgs)
+			}
+		}
+		if strings.Contains(buf.String(), ":777") {
+			t.Error("an SDTO value was logged")
+		}
+	})
+
+	t.Run("error: rejection reasons", func(t *testing.T) {
+		buf, l := captureLogs()
+		if _, err := VerifyAction(genesis, genesis, []byte(`{"action_type":"t","sdto":{"amount":5000},"timestamp":1}`), genesis, schema, WithLogger(l)); err == nil {
+			t.Fatal("expected an error")
+		}
+		out := buf.String()
+		if !strings.Contains(out, `"msg":"sdto: validation failed"`) || !strings.Contains(out, `"codes":["max"]`) ||
+			!strings.Contains(out, `"msg":"vax: action rejected"`) || strings.Contains(out, ":5000") {
+			t.Errorf("logs:\n%s", out)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		old := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		t.Cleanup(func() { slog.SetDefault(old) })
+		store := NewMemoryStore()
+		_ = store.Init(actor, genesis)
+		sub, _ := SignedAction("transfer", schema, map[string]any{"amount": 1}, priv, ChainState{HeadSAI: genesis})
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, nil); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("logged without WithLogger: %s", buf.String())
+		}
+	})
+}
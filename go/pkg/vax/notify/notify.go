@@ -0,0 +1,203 @@
+// Package notify posts signed security events (SAI mismatches, chain
+// forks) to webhooks, so tampering is alerted on in real time instead of
+// being found by log scraping. Delivery is asynchronous with retries and
+// exponential backoff; see Notifier.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"vax/pkg/vax"
+)
+
+// Event types
+const (
+	EventSAIMismatch = "sai_mismatch" // SAI does not hash the submitted bytes
+	EventFork        = "fork"         // action built on a head that is not the actor's current one
+)
+
+// Webhook request headers. The signature is hex(HMAC-SHA256(secret,
+// timestamp + "." + body)); receivers should also reject stale timestamps.
+const (
+	HeaderSignature = "VAX-Webhook-Signature" // "sha256=<hex>"
+	HeaderTimestamp = "VAX-Webhook-Timestamp" // unix seconds
+)
+
+// Errors passed to the error handler when an event is dropped
+var (
+	ErrQueueFull = errors.New("notify: queue full")
+	ErrClosed    = errors.New("notify: notifier closed")
+)
+
+// Event is the JSON body POSTed to webhooks.
+type Event struct {
+	Type    string `json:"type"`
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter,omitempty"` // claimed chain position
+	Message string `json:"message"`
+	At      int64  `json:"at"` // unix ms
+}
+
+// Webhook is one delivery target.
+type Webhook struct {
+	URL    string
+	Secret []byte
+}
+
+// Sign returns the HeaderSignature value for body sent at timestamp.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received webhook in constant time; receivers use it
+// with their copy of the secret.
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+// Classify returns the event type of a verification error, if it is one
+// security operations should hear about.
+func Classify(err error) (string, bool) {
+	switch {
+	case errors.Is(err, vax.ErrSAIMismatch):
+		return EventSAIMismatch, true
+	case errors.Is(err, vax.ErrStaleHead), errors.Is(err, vax.ErrInvalidPrevSAI):
+		return EventFork, true
+	}
+	return "", false
+}
+
+// Notifier delivers events to every webhook from a background goroutine.
+// Safe for concurrent use; call Close to flush and stop it.
+type Notifier struct {
+	hooks []Webhook
+	cfg   config
+	queue chan Event
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex // 保護 closed 與 close(queue)
+	closed bool
+}
+
+// New starts a Notifier. It panics without webhooks.
+func New(hooks []Webhook, opts ...Option) *Notifier {
+	if len(hooks) == 0 {
+		panic("notify: New needs at least one webhook")
+	}
+	cfg := newConfig(opts)
+	n := &Notifier{hooks: hooks, cfg: cfg, queue: make(chan Event, cfg.queueSize)}
+	n.wg.Add(1)
+	go n.run()
+	return n
+}
+
+// VerificationFailed enqueues an event when err is a mismatch or fork
+// (see Classify) and ignores other errors.
+func (n *Notifier) VerificationFailed(actor string, counter uint64, err error) {
+	typ, ok := Classify(err)
+	if !ok {
+		return
+	}
+	n.Notify(Event{Type: typ, Actor: actor, Counter: counter, Message: err.Error()})
+}
+
+// Notify enqueues ev without blocking; when the queue is full the event is
+// dropped and reported to the error handler.
+func (n *Notifier) Notify(ev Event) {
+	if ev.At == 0 {
+		ev.At = n.cfg.now().UnixMilli()
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		n.cfg.onError(Webhook{}, ev, ErrClosed)
+		return
+	}
+	select {
+	case n.queue <- ev:
+	default:
+		n.cfg.onError(Webhook{}, ev, ErrQueueFull)
+	}
+}
+
+// Close stops accepting events and waits until queued ones are delivered
+// (or have exhausted their retries).
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+func (n *Notifier) run() {
+	defer n.wg.Done()
+	for ev := range n.queue {
+		body, err := json.Marshal(ev)
+		if err != nil {
+			n.cfg.onError(Webhook{}, ev, err)
+			continue
+		}
+		for _, hook := range n.hooks {
+			if err := n.deliver(hook, body); err != nil {
+				n.cfg.onError(hook, ev, err)
+			}
+		}
+	}
+}
+
+// deliver 以指數退避重試；4xx（除 429）視為永久失敗不重試
+func (n *Notifier) deliver(hook Webhook, body []byte) error {
+	var err error
+	delay := n.cfg.backoff
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		if retry, err = n.post(hook, body); err == nil || !retry || attempt == n.cfg.attempts {
+			return err
+		}
+		n.cfg.sleep(delay)
+		delay = min(delay*2, n.cfg.maxBackoff)
+	}
+}
+
+func (n *Notifier) post(hook Webhook, body []byte) (retry bool, err error) {
+	ts := n.cfg.now().Unix()
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, ts, body))
+	resp, err := n.cfg.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("notify: %s: status %d", hook.URL, resp.StatusCode)
+	default:
+		return false, fmt.Errorf("notify: %s: status %d", hook.URL, resp.StatusCode)
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+)
+
+// noSleep 讓重試不實際等待，並記錄退避時間
+func noSleep(delays *[]time.Duration) Option {
+	return func(c *config) {
+		c.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	secret := []byte("whsec")
+
+	t.Run("posts signed events", func(t *testing.T) {
+		var mu sync.Mutex
+		var got []Event
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if !Verify(secret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+				t.Error("bad signature")
+			}
+			var ev Event
+			_ = json.Unmarshal(body, &ev)
+			mu.Lock()
+			got = append(got, ev)
+			mu.Unlock()
+		}))
+		defer srv.Close()
+
+		n := New([]Webhook{{URL: srv.URL, Secret: secret}})
+		n.VerificationFailed("alice", 7, fmt.Errorf("submit: %w", vax.ErrSAIMismatch))
+		n.VerificationFailed("bob", 3, vax.ErrStaleHead)
+		n.VerificationFailed("carol", 1, vax.ErrInvalidInput) // 不通知
+		n.Close()
+
+		if len(got) != 2 || got[0].Type != EventSAIMismatch || got[0].Actor != "alice" || got[0].Counter != 7 || got[1].Type != EventFork {
+			t.Fatalf("delivered %+v", got)
+		}
+		if got[0].At == 0 {
+			t.Error("At not stamped")
+		}
+	})
+
+	t.Run("retries server errors with backoff", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		var delays []time.Duration
+		var failed error
+		n := New([]Webhook{{URL: srv.URL}}, WithRetry(5, 100*time.Millisecond), noSleep(&delays),
+			WithErrorHandler(func(_ Webhook, _ Event, err error) { failed = err }))
+		n.Notify(Event{Type: EventFork})
+		n.Close()
+
+		if calls.Load() != 3 || failed != nil {
+			t.Fatalf("calls = %d, err = %v", calls.Load(), failed)
+		}
+		if len(delays) != 2 || delays[0] != 100*time.Millisecond || delays[1] != 200*time.Millisecond {
+			t.Errorf("delays = %v", delays)
+		}
+	})
+
+	t.Run("error: gives up after the last attempt", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		var delays []time.Duration
+		var failed error
+		n := New([]Webhook{{URL: srv.URL}}, WithRetry(3, time.Second), noSleep(&delays),
+			WithErrorHandler(func(_ Webhook, _ Event, err error) { failed = err }))
+		n.Notify(Event{Type: EventFork})
+		n.Close()
+		if calls.Load() != 3 || failed == nil {
+			t.Errorf("calls = %d, err = %v", calls.Load(), failed)
+		}
+	})
+
+	t.Run("error: client errors are not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer srv.Close()
+
+		var delays []time.Duration
+		n := New([]Webhook{{URL: srv.URL}}, noSleep(&delays))
+		n.Notify(Event{Type: EventFork})
+		n.Close()
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d", calls.Load())
+		}
+	})
+
+	t.Run("error: events after Close are dropped", func(t *testing.T) {
+		var dropped error
+		n := New([]Webhook{{URL: "http://127.0.0.1:0"}}, WithErrorHandler(func(_ Webhook, _ Event, err error) { dropped = err }))
+		n.Close()
+		n.Notify(Event{Type: EventFork})
+		if !errors.Is(dropped, ErrClosed) {
+			t.Errorf("got %v", dropped)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"fork"}`)
+	sig := Sign([]byte("k"), 1700000000, body)
+	if !Verify([]byte("k"), "1700000000", body, sig) {
+		t.Error("valid signature rejected")
+	}
+	if Verify([]byte("k"), "1700000001", body, sig) || Verify([]byte("other"), "1700000000", body, sig) {
+		t.Error("invalid signature accepted")
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures New.
+type Option func(*config)
+
+type config struct {
+	client     *http.Client
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	queueSize  int
+	onError    func(Webhook, Event, error)
+	now        func() time.Time
+	sleep      func(time.Duration)
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		attempts:   5,
+		backoff:    500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		queueSize:  1024,
+		onError:    func(Webhook, Event, error) {},
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithHTTPClient replaces the default client (10s timeout).
+func WithHTTPClient(c *http.Client) Option {
+	return func(cfg *config) {
+		if c != nil {
+			cfg.client = c
+		}
+	}
+}
+
+// WithRetry sets the attempts per webhook (default 5) and the first delay
+// between them (default 500ms), doubled after each failure up to 30s.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(cfg *config) {
+		if attempts > 0 {
+			cfg.attempts = attempts
+		}
+		if backoff > 0 {
+			cfg.backoff = backoff
+		}
+	}
+}
+
+// WithQueueSize sets how many events may wait for delivery (default 1024).
+func WithQueueSize(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.queueSize = n
+		}
+	}
+}
+
+// WithErrorHandler is called for every event that could not be delivered
+// to a webhook (after the last retry) or was dropped (ErrQueueFull,
+// ErrClosed; the Webhook is then zero).
+func WithErrorHandler(fn func(Webhook, Event, error)) Option {
+	return func(cfg *config) {
+		if fn != nil {
+			cfg.onError = fn
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package vax
+
+import (
+	"crypto/ecdh"
+	"log/slog"
+
+	"vax/pkg/vax/sae"
+)
+
+// Option configures VerifyAction and VerifyAndAdvance.
+type Option func(*config)
+
+type config struct {
+	decryptionKey *ecdh.PrivateKey
+	timePolicy    sae.TimePolicy
+	logger        *slog.Logger
+}
+
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithDecryptionKey accepts encrypted envelopes (sae.Encrypt) addressed to
+// key: the sdto is decrypted and validated against the schema, and
+// envelopes that do not decrypt fail with sae.ErrDecrypt. Without it every
+// encrypted envelope is rejected with ErrEncrypted.
+func WithDecryptionKey(key *ecdh.PrivateKey) Option {
+	return func(c *config) {
+		c.decryptionKey = key
+	}
+}
+
+// WithTimestampPolicy applies p to every envelope VerifyAndAdvance accepts,
+// after the chain binding and before the signature: p.MaxSkew bounds the
+// distance from server time, and p.Monotonic rejects envelopes older than
+// the actor's previous action, using ChainState.Timestamp. Without it
+// timestamps are not checked. VerifyAction ignores it.
+func WithTimestampPolicy(p sae.TimePolicy) Option {
+	return func(c *config) {
+		c.timePolicy = p
+	}
+}
+
+// WithLogger sends debug records from VerifyAction and VerifyAndAdvance to
+// l: actor, counters, SAI and envelope digest prefixes (first 8 bytes, hex)
+// and the reason a submission was rejected, plus sdto.ValidateData's record
+// for the payload. SDTO values are never logged. Without it nothing is
+// logged; pass sae.WithLogger to BuildSAE / SignedAction for the client
+// side.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
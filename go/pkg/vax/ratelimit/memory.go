@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery 每處理這麼多次 Allow 清掉已回滿的 bucket，避免 key 無限增長
+const sweepEvery = 1024
+
+// Memory is an in-process Limiter. Safe for concurrent use.
+type Memory struct {
+	limit Limit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryOption configures NewMemory.
+type MemoryOption func(*Memory)
+
+// WithClock replaces time.Now as the time source.
+func WithClock(now func() time.Time) MemoryOption {
+	return func(m *Memory) {
+		if now != nil {
+			m.now = now
+		}
+	}
+}
+
+// NewMemory panics on a Limit with non-positive Every or Burst.
+func NewMemory(limit Limit, opts ...MemoryOption) *Memory {
+	if !limit.valid() {
+		panic("ratelimit: Limit needs positive Every and Burst")
+	}
+	m := &Memory{limit: limit, now: time.Now, buckets: make(map[string]*bucket)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *Memory) Allow(key string) (bool, time.Duration, error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.calls++; m.calls%sweepEvery == 0 {
+		m.sweep(now)
+	}
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(m.limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	m.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) * float64(m.limit.Every))
+	return false, wait, nil
+}
+
+func (m *Memory) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(m.limit.Burst), b.tokens+float64(elapsed)/float64(m.limit.Every))
+		b.last = now
+	}
+}
+
+// sweep 回滿的 bucket 與新建的等價，可直接刪除
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		m.refill(b, now)
+		if b.tokens >= float64(m.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestMemory(t *testing.T) {
+	t.Run("burst then refill", func(t *testing.T) {
+		clock := &fakeClock{t: time.Unix(0, 0)}
+		m := NewMemory(Limit{Every: time.Second, Burst: 3}, WithClock(clock.now))
+		for i := 0; i < 3; i++ {
+			if ok, _, _ := m.Allow("a"); !ok {
+				t.Fatalf("request %d rejected within burst", i)
+			}
+		}
+		ok, retryAfter, _ := m.Allow("a")
+		if ok || retryAfter != time.Second {
+			t.Fatalf("got ok=%v retryAfter=%v, want rejection for 1s", ok, retryAfter)
+		}
+		clock.advance(400 * time.Millisecond)
+		if _, retryAfter, _ := m.Allow("a"); retryAfter != 600*time.Millisecond {
+			t.Errorf("retryAfter = %v, want 600ms", retryAfter)
+		}
+		clock.advance(600 * time.Millisecond)
+		if ok, _, _ := m.Allow("a"); !ok {
+			t.Error("expected a token after refill")
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		m := NewMemory(Limit{Every: time.Hour, Burst: 1})
+		m.Allow("a")
+		if ok, _, _ := m.Allow("b"); !ok {
+			t.Error("key b limited by key a")
+		}
+	})
+
+	t.Run("full buckets are swept", func(t *testing.T) {
+		clock := &fakeClock{t: time.Unix(0, 0)}
+		m := NewMemory(Limit{Every: time.Millisecond, Burst: 1}, WithClock(clock.now))
+		for i := 0; i < sweepEvery-1; i++ {
+			m.Allow(fmt.Sprint(i))
+		}
+		clock.advance(time.Second)
+		m.Allow("last")
+		if len(m.buckets) != 1 { // 只剩 sweep 後建立的 "last"
+			t.Errorf("%d buckets left after sweep, want 1", len(m.buckets))
+		}
+	})
+
+	t.Run("error: invalid limit panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewMemory(Limit{})
+	})
+}
@@ -0,0 +1,69 @@
+// Package ratelimit limits requests per key (the actor ID on the VAX
+// server) with a token bucket, so one misbehaving device cannot saturate
+// the verifier with bogus submissions. Backends: Memory for a single
+// process, Redis for a fleet sharing one budget per actor.
+package ratelimit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Limit is a token bucket: one token is added every Every, up to Burst.
+type Limit struct {
+	Every time.Duration
+	Burst int
+}
+
+// PerSecond returns a Limit of n requests per second with the given burst.
+func PerSecond(n float64, burst int) Limit {
+	return Limit{Every: time.Duration(float64(time.Second) / n), Burst: burst}
+}
+
+func (l Limit) valid() bool {
+	return l.Every > 0 && l.Burst > 0
+}
+
+// Limiter decides whether the request for key may proceed. When it may
+// not, retryAfter is how long until a token is available. Backend failures
+// are returned as err; callers decide whether to fail open.
+type Limiter interface {
+	Allow(key string) (ok bool, retryAfter time.Duration, err error)
+}
+
+// Metrics counts decisions of an instrumented Limiter. It implements
+// expvar.Var, so it can be published with expvar.Publish.
+type Metrics struct {
+	Allowed  atomic.Uint64
+	Rejected atomic.Uint64
+	Errors   atomic.Uint64
+}
+
+func (m *Metrics) String() string {
+	return fmt.Sprintf(`{"allowed":%d,"rejected":%d,"errors":%d}`,
+		m.Allowed.Load(), m.Rejected.Load(), m.Errors.Load())
+}
+
+// Instrument returns l counting every decision in m.
+func Instrument(l Limiter, m *Metrics) Limiter {
+	return instrumented{l, m}
+}
+
+type instrumented struct {
+	Limiter
+	m *Metrics
+}
+
+func (i instrumented) Allow(key string) (bool, time.Duration, error) {
+	ok, retryAfter, err := i.Limiter.Allow(key)
+	switch {
+	case err != nil:
+		i.m.Errors.Add(1)
+	case ok:
+		i.m.Allowed.Add(1)
+	default:
+		i.m.Rejected.Add(1)
+	}
+	return ok, retryAfter, err
+}
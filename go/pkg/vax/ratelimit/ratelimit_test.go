@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type stubLimiter struct {
+	ok  bool
+	err error
+}
+
+func (s stubLimiter) Allow(string) (bool, time.Duration, error) { return s.ok, 0, s.err }
+
+func TestInstrument(t *testing.T) {
+	var m Metrics
+	Instrument(stubLimiter{ok: true}, &m).Allow("a")
+	Instrument(stubLimiter{ok: false}, &m).Allow("a")
+	Instrument(stubLimiter{ok: false}, &m).Allow("a")
+	Instrument(stubLimiter{err: errors.New("down")}, &m).Allow("a")
+
+	var got map[string]uint64
+	if err := json.Unmarshal([]byte(m.String()), &got); err != nil {
+		t.Fatalf("String() is not JSON: %v", err)
+	}
+	if got["allowed"] != 1 || got["rejected"] != 2 || got["errors"] != 1 {
+		t.Errorf("metrics = %v", got)
+	}
+}
+
+func TestPerSecond(t *testing.T) {
+	if l := PerSecond(4, 10); l.Every != 250*time.Millisecond || l.Burst != 10 {
+		t.Errorf("PerSecond(4, 10) = %+v", l)
+	}
+}
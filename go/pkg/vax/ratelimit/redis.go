@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Evaler runs a Lua script on Redis. Adapt your client with a few lines,
+// e.g. for go-redis: rdb.Eval(ctx, script, keys, args...).Result().
+type Evaler interface {
+	Eval(script string, keys []string, args ...any) (any, error)
+}
+
+// redisScript 在 Redis 內原子地補充並扣除 token（時間取自 Redis TIME，各節點時鐘不需同步）。
+// bucket 存為 hash {tokens, last(µs)}，閒置到回滿後過期。
+// 回傳 {allowed(0/1), retry_after(µs)}
+const redisScript = `
+local every = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(b[1]) or burst
+local last = tonumber(b[2]) or now
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) / every)
+end
+local allowed, wait = 0, math.ceil((1 - tokens) * every)
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed, wait = 1, 0
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * every / 1000) + 1000)
+return {allowed, wait}
+`
+
+// Redis is a Limiter whose buckets live in Redis, shared by every server.
+type Redis struct {
+	client Evaler
+	limit  Limit
+	prefix string
+}
+
+// NewRedis stores buckets under prefix+key. It panics on an invalid Limit.
+func NewRedis(client Evaler, limit Limit, prefix string) *Redis {
+	if !limit.valid() {
+		panic("ratelimit: Limit needs positive Every and Burst")
+	}
+	return &Redis{client: client, limit: limit, prefix: prefix}
+}
+
+func (r *Redis) Allow(key string) (bool, time.Duration, error) {
+	every := max(r.limit.Every.Microseconds(), 1)
+	res, err := r.client.Eval(redisScript, []string{r.prefix + key}, every, r.limit.Burst)
+	if err != nil {
+		return false, 0, fmt.Errorf("ratelimit: redis: %w", err)
+	}
+	allowed, wait, err := parseReply(res)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, time.Duration(wait) * time.Microsecond, nil
+}
+
+var errBadReply = errors.New("ratelimit: unexpected redis reply")
+
+// parseReply 接受 client 常見的整數陣列表示（[]any of int64）
+func parseReply(res any) (int64, int64, error) {
+	arr, ok := res.([]any)
+	if !ok || len(arr) != 2 {
+		return 0, 0, fmt.Errorf("%w: %v", errBadReply, res)
+	}
+	allowed, ok1 := arr[0].(int64)
+	wait, ok2 := arr[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("%w: %v", errBadReply, res)
+	}
+	return allowed, wait, nil
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeRedis struct {
+	reply any
+	err   error
+	keys  []string
+	args  []any
+}
+
+func (f *fakeRedis) Eval(_ string, keys []string, args ...any) (any, error) {
+	f.keys, f.args = keys, args
+	return f.reply, f.err
+}
+
+func TestRedis(t *testing.T) {
+	limit := Limit{Every: 250 * time.Millisecond, Burst: 5}
+
+	t.Run("passes key and limit to the script", func(t *testing.T) {
+		f := &fakeRedis{reply: []any{int64(1), int64(0)}}
+		ok, _, err := NewRedis(f, limit, "vax:rl:").Allow("actor1")
+		if err != nil || !ok {
+			t.Fatalf("got ok=%v err=%v", ok, err)
+		}
+		if f.keys[0] != "vax:rl:actor1" || f.args[0] != int64(250000) || f.args[1] != 5 {
+			t.Errorf("keys=%v args=%v", f.keys, f.args)
+		}
+	})
+
+	t.Run("rejection carries retry-after", func(t *testing.T) {
+		f := &fakeRedis{reply: []any{int64(0), int64(125000)}}
+		ok, retryAfter, _ := NewRedis(f, limit, "").Allow("a")
+		if ok || retryAfter != 125*time.Millisecond {
+			t.Errorf("got ok=%v retryAfter=%v", ok, retryAfter)
+		}
+	})
+
+	t.Run("error: backend failure", func(t *testing.T) {
+		_, _, err := NewRedis(&fakeRedis{err: errors.New("conn refused")}, limit, "").Allow("a")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("error: unexpected reply", func(t *testing.T) {
+		_, _, err := NewRedis(&fakeRedis{reply: "OK"}, limit, "").Allow("a")
+		if !errors.Is(err, errBadReply) {
+			t.Fatalf("got %v", err)
+		}
+	})
+}
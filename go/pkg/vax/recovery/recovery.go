@@ -0,0 +1,203 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// Error codes
+var (
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrInvalidThreshold = errors.New("invalid threshold")
+	ErrNotEnoughShares  = errors.New("not enough shares")
+	ErrDuplicateShare   = errors.New("duplicate share index")
+	ErrShareMismatch    = errors.New("shares belong to different splits")
+	ErrCorruptShare     = errors.New("corrupt share")
+	ErrIntegrity        = errors.New("secret integrity check failed")
+)
+
+// Constants
+const (
+	ShareVersion = 1
+	MaxShares    = 255
+	ChecksumSize = 4
+)
+
+// Share is one point of a Shamir split.
+// Value carries the secret bytes plus a checksum, so shares are
+// ChecksumSize bytes longer than the secret they protect.
+type Share struct {
+	Threshold byte
+	Index     byte // x coordinate, never 0
+	Value     []byte
+}
+
+// Split splits secret (typically a 32-byte k_chain) into n shares,
+// any k of which reconstruct it.
+//
+// A truncated SHA256 of the secret is appended before splitting, so
+// Combine can detect wrong or tampered shares without leaking the digest.
+func Split(secret []byte, n, k int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if k < 2 || n < k || n > MaxShares {
+		return nil, ErrInvalidThreshold
+	}
+
+	sum := sha256.Sum256(secret)
+	payload := make([]byte, 0, len(secret)+ChecksumSize)
+	payload = append(payload, secret...)
+	payload = append(payload, sum[:ChecksumSize]...)
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{
+			Threshold: byte(k),
+			Index:     byte(i + 1),
+			Value:     make([]byte, len(payload)),
+		}
+	}
+
+	// One random polynomial of degree k-1 per payload byte,
+	// constant term = payload byte.
+	coeffs := make([]byte, k)
+	for j, b := range payload {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Value[j] = evalPoly(coeffs, shares[i].Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine reconstructs the secret from at least Threshold shares.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	k := int(shares[0].Threshold)
+	size := len(shares[0].Value)
+	if k < 2 || size <= ChecksumSize {
+		return nil, ErrCorruptShare
+	}
+	if len(shares) < k {
+		return nil, ErrNotEnoughShares
+	}
+
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.Index == 0 {
+			return nil, ErrCorruptShare
+		}
+		if int(s.Threshold) != k || len(s.Value) != size {
+			return nil, ErrShareMismatch
+		}
+		if seen[s.Index] {
+			return nil, ErrDuplicateShare
+		}
+		seen[s.Index] = true
+	}
+
+	// Only k points are needed for interpolation
+	pts := shares[:k]
+
+	payload := make([]byte, size)
+	for j := range payload {
+		payload[j] = interpolateAtZero(pts, j)
+	}
+
+	secret := payload[:size-ChecksumSize]
+	sum := sha256.Sum256(secret)
+	if !bytes.Equal(sum[:ChecksumSize], payload[size-ChecksumSize:]) {
+		return nil, ErrIntegrity
+	}
+	return secret, nil
+}
+
+// Bytes serializes a share as
+// version || threshold || index || value || SHA256(...)[:4]
+func (s Share) Bytes() []byte {
+	out := make([]byte, 0, 3+len(s.Value)+ChecksumSize)
+	out = append(out, ShareVersion, s.Threshold, s.Index)
+	out = append(out, s.Value...)
+	sum := sha256.Sum256(out)
+	return append(out, sum[:ChecksumSize]...)
+}
+
+// ParseShare parses bytes produced by Share.Bytes and checks the share checksum.
+func ParseShare(b []byte) (Share, error) {
+	if len(b) < 3+ChecksumSize+1 {
+		return Share{}, ErrCorruptShare
+	}
+	body := b[:len(b)-ChecksumSize]
+	sum := sha256.Sum256(body)
+	if !bytes.Equal(sum[:ChecksumSize], b[len(b)-ChecksumSize:]) {
+		return Share{}, ErrCorruptShare
+	}
+	if body[0] != ShareVersion || body[1] < 2 || body[2] == 0 {
+		return Share{}, ErrCorruptShare
+	}
+
+	return Share{
+		Threshold: body[1],
+		Index:     body[2],
+		Value:     append([]byte(nil), body[3:]...),
+	}, nil
+}
+
+// ======== GF(2^8) arithmetic (AES polynomial x^8+x^4+x^3+x+1) ========
+
+func evalPoly(coeffs []byte, x byte) byte {
+	// Horner's method
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+func interpolateAtZero(pts []Share, j int) byte {
+	var result byte
+	for i, pi := range pts {
+		// Lagrange basis l_i(0) = Π x_m / (x_m - x_i), subtraction is XOR
+		num, den := byte(1), byte(1)
+		for m, pm := range pts {
+			if m == i {
+				continue
+			}
+			num = gfMul(num, pm.Index)
+			den = gfMul(den, pm.Index^pi.Index)
+		}
+		result ^= gfMul(pi.Value[j], gfMul(num, gfInv(den)))
+	}
+	return result
+}
+
+// gfMul multiplies without table lookups, so timing does not depend on secret bytes.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= a & -(b & 1)
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv computes a^254 = a^-1 (a != 0).
+func gfInv(a byte) byte {
+	r := a
+	for i := 0; i < 6; i++ {
+		r = gfMul(r, r)
+		r = gfMul(r, a)
+	}
+	return gfMul(r, r)
+}
@@ -0,0 +1,125 @@
+package recovery
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testSecret() []byte {
+	secret := make([]byte, 32)
+	for i := range secret {
+		secret[i] = byte(i * 7)
+	}
+	return secret
+}
+
+func TestSplitCombine(t *testing.T) {
+	t.Run("any k of n", func(t *testing.T) {
+		secret := testSecret()
+		shares, err := Split(secret, 5, 3)
+		if err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		if len(shares) != 5 {
+			t.Fatalf("got %d shares, want 5", len(shares))
+		}
+
+		subsets := [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}}
+		for _, idx := range subsets {
+			var picked []Share
+			for _, i := range idx {
+				picked = append(picked, shares[i])
+			}
+			got, err := Combine(picked)
+			if err != nil {
+				t.Fatalf("Combine(%v) failed: %v", idx, err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("Combine(%v) = %x, want %x", idx, got, secret)
+			}
+		}
+	})
+
+	t.Run("gf inverse", func(t *testing.T) {
+		for a := 1; a < 256; a++ {
+			if gfMul(byte(a), gfInv(byte(a))) != 1 {
+				t.Fatalf("gfInv(%d) is not an inverse", a)
+			}
+		}
+	})
+
+	t.Run("error: not enough shares", func(t *testing.T) {
+		shares, _ := Split(testSecret(), 5, 3)
+		_, err := Combine(shares[:2])
+		if err != ErrNotEnoughShares {
+			t.Errorf("expected ErrNotEnoughShares, got %v", err)
+		}
+	})
+
+	t.Run("error: duplicate share", func(t *testing.T) {
+		shares, _ := Split(testSecret(), 5, 3)
+		_, err := Combine([]Share{shares[0], shares[1], shares[1]})
+		if err != ErrDuplicateShare {
+			t.Errorf("expected ErrDuplicateShare, got %v", err)
+		}
+	})
+
+	t.Run("error: tampered share", func(t *testing.T) {
+		shares, _ := Split(testSecret(), 3, 3)
+		shares[1].Value[0] ^= 0x01
+		_, err := Combine(shares)
+		if err != ErrIntegrity {
+			t.Errorf("expected ErrIntegrity, got %v", err)
+		}
+	})
+
+	t.Run("error: mixed splits", func(t *testing.T) {
+		a, _ := Split(testSecret(), 3, 2)
+		b, _ := Split(testSecret(), 3, 3)
+		_, err := Combine([]Share{a[0], b[1], b[2]})
+		if err != ErrShareMismatch {
+			t.Errorf("expected ErrShareMismatch, got %v", err)
+		}
+	})
+
+	t.Run("error: invalid threshold", func(t *testing.T) {
+		for _, tc := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+			if _, err := Split(testSecret(), tc[0], tc[1]); err != ErrInvalidThreshold {
+				t.Errorf("Split(n=%d, k=%d): expected ErrInvalidThreshold, got %v", tc[0], tc[1], err)
+			}
+		}
+	})
+}
+
+func TestShareSerialization(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		secret := testSecret()
+		shares, _ := Split(secret, 4, 2)
+
+		var parsed []Share
+		for _, s := range shares[2:] {
+			p, err := ParseShare(s.Bytes())
+			if err != nil {
+				t.Fatalf("ParseShare failed: %v", err)
+			}
+			parsed = append(parsed, p)
+		}
+
+		got, err := Combine(parsed)
+		if err != nil {
+			t.Fatalf("Combine failed: %v", err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Error("secret mismatch after serialization round trip")
+		}
+	})
+
+	t.Run("error: corrupt bytes", func(t *testing.T) {
+		shares, _ := Split(testSecret(), 2, 2)
+		b := shares[0].Bytes()
+		b[5] ^= 0xFF
+		if _, err := ParseShare(b); err != ErrCorruptShare {
+			t.Errorf("expected ErrCorruptShare, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package vax
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// RegenesisActionType is the action_type of the record that rolls a chain
+// over at its counter ceiling.
+const RegenesisActionType = "vax.regenesis"
+
+// ErrInvalidRegenesis is returned for a re-genesis record that does not
+// continue the chain it claims to close.
+var ErrInvalidRegenesis = errors.New("invalid re-genesis record")
+
+// Regenesis builds and signs the record that continues a chain whose
+// counter has reached its ceiling (state.Counter == math.MaxUint64), where
+// every other action fails with ErrCounterOverflow.
+//
+// The record is an SAE of RegenesisActionType bound in-band to prev_sai =
+// state.HeadSAI (it has no counter); its sdto carries actor, the previous
+// head (previous_sai, previous_counter as a decimal string) and a fresh
+// genesis_salt (16 random bytes when salt is nil). Its SAI is computed from
+// the old head like any action's and is the genesis of the continued
+// chain: sub.Next is ChainState{Counter: 0, HeadSAI: sub.SAI}, so history
+// stays hash-linked across the rollover. VerifyAndAdvance accepts it at the
+// ceiling in place of a regular action.
+func Regenesis(state ChainState, actor string, salt []byte, signer crypto.Signer, opts ...sae.Option) (*Submission, error) {
+	if len(state.HeadSAI) != SAISize || actor == "" {
+		return nil, ErrInvalidInput
+	}
+	if state.Counter != math.MaxUint64 {
+		return nil, fmt.Errorf("%w: counter %d is below the ceiling", ErrInvalidCounter, state.Counter)
+	}
+	if salt == nil {
+		salt = make([]byte, GenesisSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+	if len(salt) != GenesisSaltSize {
+		return nil, ErrInvalidInput
+	}
+
+	data := map[string]any{
+		"actor":            actor,
+		"previous_sai":     hex.EncodeToString(state.HeadSAI),
+		"previous_counter": strconv.FormatUint(state.Counter, 10),
+		"genesis_salt":     hex.EncodeToString(salt),
+	}
+	// counter 0 不輸出：re-genesis 只以 prev_sai 綁定
+	env := sae.NewEnvelope(RegenesisActionType, data, append(opts, sae.WithChain(0, state.HeadSAI))...)
+	if err := env.SignWith(signer, nil); err != nil {
+		return nil, err
+	}
+	saeBytes, err := jcs.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	sai, err := ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &Submission{
+		Envelope: env,
+		SAE:      saeBytes,
+		SAI:      sai,
+		PrevSAI:  state.HeadSAI,
+		Next:     ChainState{HeadSAI: sai},
+	}, nil
+}
+
+// VerifyRegenesis checks that env is a re-genesis record closing the chain
+// at state: the chain is at its ceiling, the envelope has no counter, and
+// its in-band prev_sai and sdto name state's head and a genesis salt.
+// It does not check the signature or the SAI.
+func VerifyRegenesis(env *sae.Envelope, state ChainState) error {
+	if state.Counter != math.MaxUint64 {
+		return fmt.Errorf("%w: counter %d is below the ceiling", ErrInvalidRegenesis, state.Counter)
+	}
+	if env.ActionType != RegenesisActionType || env.Counter != 0 {
+		return fmt.Errorf("%w: not a re-genesis envelope", ErrInvalidRegenesis)
+	}
+	head := hex.EncodeToString(state.HeadSAI)
+	if env.PrevSAI != head || env.SDTO["previous_sai"] != head {
+		return fmt.Errorf("%w: previous head is not the chain head", ErrInvalidRegenesis)
+	}
+	if env.SDTO["previous_counter"] != strconv.FormatUint(state.Counter, 10) {
+		return fmt.Errorf("%w: previous counter is not %d", ErrInvalidRegenesis, state.Counter)
+	}
+	if actor, _ := env.SDTO["actor"].(string); actor == "" {
+		return fmt.Errorf("%w: actor is required", ErrInvalidRegenesis)
+	}
+	salt, _ := env.SDTO["genesis_salt"].(string)
+	if b, err := hex.DecodeString(salt); err != nil || len(b) != GenesisSaltSize {
+		return fmt.Errorf("%w: genesis_salt must be %d bytes of hex", ErrInvalidRegenesis, GenesisSaltSize)
+	}
+	if len(env.SDTO) != 4 {
+		return fmt.Errorf("%w: unexpected sdto fields", ErrInvalidRegenesis)
+	}
+	return nil
+}
+
+// advanceRegenesis 是 advance 在 counter 上限時的版本：只接受 re-genesis record，chain 從 counter 0 繼續
+func advanceRegenesis(
+	ctx context.Context,
+	store ChainStore,
+	actor string,
+	state ChainState,
+	prevSAI, saeBytes, sai []byte,
+	keys sae.KeyResolver,
+	cfg config,
+) (*sae.Envelope, ChainState, error) {
+	if !bytes.Equal(prevSAI, state.HeadSAI) {
+		return nil, ChainState{}, ErrInvalidPrevSAI
+	}
+	saeBytes, err := sae.Decompress(saeBytes)
+	if err != nil {
+		return nil, ChainState{}, ErrInvalidInput
+	}
+	env, err := sae.Parse(saeBytes)
+	if err != nil {
+		return nil, ChainState{}, ErrInvalidInput
+	}
+	if env.ActionType != RegenesisActionType {
+		return nil, ChainState{}, ErrCounterOverflow
+	}
+	if err := VerifyRegenesis(env, state); err != nil {
+		return nil, ChainState{}, err
+	}
+	if actor != env.SDTO["actor"] {
+		return nil, ChainState{}, fmt.Errorf("%w: record is for another actor", ErrInvalidRegenesis)
+	}
+	want, err := ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return nil, ChainState{}, err
+	}
+	if !bytes.Equal(want, sai) {
+		return nil, ChainState{}, ErrSAIMismatch
+	}
+	if err := cfg.timePolicy.CheckTimestamp(env, state.Timestamp); err != nil {
+		return nil, ChainState{}, err
+	}
+	if keys != nil {
+		if err := env.VerifyWithResolverContext(ctx, keys); err != nil {
+			return nil, ChainState{}, err
+		}
+	}
+
+	next := ChainState{HeadSAI: bytes.Clone(sai), Timestamp: env.Timestamp}
+	if err := CompareAndAdvanceContext(ctx, store, actor, state, next); err != nil {
+		return nil, ChainState{}, err
+	}
+	return env, next, nil
+}
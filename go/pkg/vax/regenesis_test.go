@@ -0,0 +1,91 @@
+package vax
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+func TestRegenesis(t *testing.T) {
+	const actor = "user123:device456"
+	pub, priv, _ := sae.GenerateKeyPair()
+	keys := sae.StaticResolver{"k1": pub}
+	schema := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+	ceiling := ChainState{Counter: math.MaxUint64, HeadSAI: bytes.Repeat([]byte{9}, SAISize)}
+
+	newStore := func(t *testing.T) *MemoryStore {
+		t.Helper()
+		store := NewMemoryStore()
+		store.states[actor] = ceiling
+		return store
+	}
+	regenesis := func(t *testing.T, salt []byte) *Submission {
+		t.Helper()
+		sub, err := Regenesis(ceiling, actor, salt, priv, sae.WithMetadata(actor, "", ""))
+		if err != nil {
+			t.Fatalf("Regenesis: %v", err)
+		}
+		sub.Envelope.Kid = "k1"
+		sub = resign(t, sub, priv, ceiling)
+		sub.Next = ChainState{HeadSAI: sub.SAI}
+		return sub
+	}
+
+	t.Run("chain continues from counter 0", func(t *testing.T) {
+		store := newStore(t)
+		sub := regenesis(t, nil)
+		env, next, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env.ActionType != RegenesisActionType || next.Counter != 0 || !bytes.Equal(next.HeadSAI, sub.SAI) {
+			t.Fatalf("next = %+v", next)
+		}
+
+		action, err := SignedAction("transfer", schema, map[string]any{"amount": 1}, priv, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, next, err = VerifyAndAdvance(store, actor, action.PrevSAI, action.SAE, action.SAI, schema, nil); err != nil || next.Counter != 1 {
+			t.Errorf("first action after rollover: %+v, %v", next, err)
+		}
+	})
+
+	t.Run("error: regular action at the ceiling", func(t *testing.T) {
+		if _, err := SignedAction("transfer", schema, map[string]any{"amount": 1}, priv, ceiling); !errors.Is(err, ErrCounterOverflow) {
+			t.Errorf("SignedAction: %v", err)
+		}
+		if _, err := Regenesis(ChainState{Counter: 5, HeadSAI: ceiling.HeadSAI}, actor, nil, priv); !errors.Is(err, ErrInvalidCounter) {
+			t.Errorf("Regenesis below the ceiling: %v", err)
+		}
+	})
+
+	t.Run("error: record for another head or actor", func(t *testing.T) {
+		sub := regenesis(t, bytes.Repeat([]byte{1}, GenesisSaltSize))
+		env, _ := sae.Parse(sub.SAE)
+		if err := VerifyRegenesis(env, ChainState{Counter: math.MaxUint64, HeadSAI: make([]byte, SAISize)}); !errors.Is(err, ErrInvalidRegenesis) {
+			t.Errorf("other head: %v", err)
+		}
+		store := newStore(t)
+		store.states["someone-else"] = ceiling
+		if _, _, err := VerifyAndAdvance(store, "someone-else", sub.PrevSAI, sub.SAE, sub.SAI, schema, keys); !errors.Is(err, ErrInvalidRegenesis) {
+			t.Errorf("other actor: %v", err)
+		}
+	})
+
+	t.Run("error: bad signature does not roll over", func(t *testing.T) {
+		store := newStore(t)
+		sub := regenesis(t, nil)
+		other, _, _ := sae.GenerateKeyPair()
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, sae.StaticResolver{"k1": other}); err == nil {
+			t.Fatal("expected a signature error")
+		}
+		if head, _ := store.Head(actor); head.Counter != math.MaxUint64 {
+			t.Errorf("store rolled over on failure: %+v", head)
+		}
+	})
+}
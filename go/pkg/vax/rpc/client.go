@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Invoker performs one unary call. A *grpc.ClientConn satisfies it through
+// a one-line adapter (its Invoke takes trailing CallOptions); LocalInvoker
+// calls a VAXServer in process.
+type Invoker interface {
+	Invoke(ctx context.Context, method string, args, reply any) error
+}
+
+// Client is the client API of the VAX service.
+type Client struct {
+	inv Invoker
+}
+
+func NewClient(inv Invoker) *Client {
+	return &Client{inv: inv}
+}
+
+func (c *Client) GetSchema(ctx context.Context, req *GetSchemaRequest) (*GetSchemaResponse, error) {
+	out := new(GetSchemaResponse)
+	if err := c.inv.Invoke(ctx, MethodGetSchema, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) SubmitAction(ctx context.Context, req *SubmitActionRequest) (*SubmitActionResponse, error) {
+	out := new(SubmitActionResponse)
+	if err := c.inv.Invoke(ctx, MethodSubmitAction, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) GetChainHead(ctx context.Context, req *GetChainHeadRequest) (*GetChainHeadResponse, error) {
+	out := new(GetChainHeadResponse)
+	if err := c.inv.Invoke(ctx, MethodGetChainHead, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LocalInvoker dispatches calls to srv in process (tests, services that
+// embed the verifier).
+func LocalInvoker(srv VAXServer) Invoker {
+	return localInvoker{srv}
+}
+
+type localInvoker struct {
+	srv VAXServer
+}
+
+func (l localInvoker) Invoke(ctx context.Context, method string, args, reply any) error {
+	var (
+		out any
+		err error
+	)
+	switch method {
+	case MethodGetSchema:
+		out, err = l.srv.GetSchema(ctx, args.(*GetSchemaRequest))
+	case MethodSubmitAction:
+		out, err = l.srv.SubmitAction(ctx, args.(*SubmitActionRequest))
+	case MethodGetChainHead:
+		out, err = l.srv.GetChainHead(ctx, args.(*GetChainHeadRequest))
+	default:
+		return &Status{Code: Unimplemented, Message: fmt.Sprintf("unknown method %s", method)}
+	}
+	if err != nil {
+		return err
+	}
+	copyReply(reply, out)
+	return nil
+}
+
+// copyReply 把 server 回傳的訊息複製到 caller 提供的 reply
+func copyReply(reply, out any) {
+	switch r := reply.(type) {
+	case *GetSchemaResponse:
+		*r = *out.(*GetSchemaResponse)
+	case *SubmitActionResponse:
+		*r = *out.(*SubmitActionResponse)
+	case *GetChainHeadResponse:
+		*r = *out.(*GetChainHeadResponse)
+	}
+}
@@ -0,0 +1,16 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLocalInvoker(t *testing.T) {
+	t.Run("error: unknown method", func(t *testing.T) {
+		inv := LocalInvoker(newFixture(t).srv)
+		err := inv.Invoke(context.Background(), "/vax.v1.VAX/Nope", &GetSchemaRequest{}, &GetSchemaResponse{})
+		if s := StatusOf(err); s == nil || s.Code != Unimplemented {
+			t.Fatalf("got %v", err)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+// Package rpc is the transport-neutral service behind the VAX RPC contract
+// (vax.proto): GetSchema, SubmitAction and GetChainHead over the same
+// verification pipeline as package api.
+//
+// It is not a gRPC server. This module depends only on the standard
+// library, so it ships neither generated protobuf code nor grpc-go. The
+// message types here mirror vax.proto field for field; a deployment that
+// serves gRPC generates stubs from vax.proto in its own module and
+// registers a thin adapter that converts the generated messages, calls
+// VAXServer and turns a *Status into status.Error(codes.Code(s.Code),
+// s.Message). Client does the reverse over any Invoker.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vax/pkg/vax/api"
+)
+
+// Full method names, as in the generated service descriptor.
+const (
+	ServiceName        = "vax.v1.VAX"
+	MethodGetSchema    = "/vax.v1.VAX/GetSchema"
+	MethodSubmitAction = "/vax.v1.VAX/SubmitAction"
+	MethodGetChainHead = "/vax.v1.VAX/GetChainHead"
+)
+
+type GetSchemaRequest struct {
+	Action string `json:"action"`
+}
+
+type GetSchemaResponse struct {
+	Action string `json:"action"`
+	Schema []byte `json:"schema"` // JSON Schema document
+}
+
+type SubmitActionRequest struct {
+	Actor          string `json:"actor"`
+	Counter        uint64 `json:"counter"`
+	PrevSai        []byte `json:"prev_sai"`
+	Sae            []byte `json:"sae"`
+	Sai            []byte `json:"sai"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // see api.SubmitRequest
+}
+
+type SubmitActionResponse struct {
+	Actor      string `json:"actor"`
+	ActionType string `json:"action_type"`
+	Counter    uint64 `json:"counter"`
+	Sai        []byte `json:"sai"`
+	PrevSai    []byte `json:"prev_sai"`
+	AcceptedAt int64  `json:"accepted_at"` // unix ms
+}
+
+type GetChainHeadRequest struct {
+	Actor string `json:"actor"`
+}
+
+type GetChainHeadResponse struct {
+	Actor   string `json:"actor"`
+	Counter uint64 `json:"counter"`
+	HeadSai []byte `json:"head_sai"`
+}
+
+// VAXServer is the server API of the VAX service (vax.proto).
+type VAXServer interface {
+	GetSchema(context.Context, *GetSchemaRequest) (*GetSchemaResponse, error)
+	SubmitAction(context.Context, *SubmitActionRequest) (*SubmitActionResponse, error)
+	GetChainHead(context.Context, *GetChainHeadRequest) (*GetChainHeadResponse, error)
+}
+
+// Code is a gRPC status code; the values equal google.golang.org/grpc/codes.
+type Code uint32
+
+const (
+	OK                 Code = 0
+	InvalidArgument    Code = 3
+	NotFound           Code = 5
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unauthenticated    Code = 16
+)
+
+// Status is the error returned by Server methods. Detail carries the same
+// code (and per-field errors) as the HTTP ErrorResponse.
+type Status struct {
+	Code    Code
+	Message string
+	Detail  api.ErrorResponse
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", s.Code, s.Message)
+}
+
+// StatusOf returns the *Status in err's chain, or nil.
+func StatusOf(err error) *Status {
+	var s *Status
+	if errors.As(err, &s) {
+		return s
+	}
+	return nil
+}
+
+// toStatus 以 api.Classify 分類，錯誤代碼與 HTTP 層一致
+func toStatus(err error) *Status {
+	_, resp := api.Classify(err)
+	return &Status{Code: codes[resp.Code], Message: resp.Message, Detail: resp}
+}
+
+var codes = map[string]Code{
+	api.CodeInvalidInput:        InvalidArgument,
+	api.CodeInvalidSDTO:         InvalidArgument,
+	api.CodeSAIMismatch:         InvalidArgument,
+	api.CodeClockSkew:           FailedPrecondition,
+	api.CodeStaleTimestamp:      FailedPrecondition,
+	api.CodeTooLarge:            InvalidArgument,
+	api.CodeIdempotencyConflict: InvalidArgument,
+	api.CodeUnknownActor:        NotFound,
+	api.CodeUnknownSchema:       FailedPrecondition,
+	api.CodeChainConflict:       Aborted,
+	api.CodeInvalidSignature:    Unauthenticated,
+	api.CodeInternal:            Internal,
+}
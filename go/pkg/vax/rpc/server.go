@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/api"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/schemaregistry"
+	"vax/pkg/vax/sdto"
+)
+
+// Server implements VAXServer on the same pipeline as api.HandleSubmitAction
+// (api.Submit) and serves documents from a schemaregistry.Registry.
+type Server struct {
+	store   vax.ChainStore
+	schemas *sdto.Registry
+	docs    *schemaregistry.Registry
+	keys    sae.KeyResolver
+	opts    []api.Option
+}
+
+var _ VAXServer = (*Server)(nil)
+
+// NewServer panics if any argument is nil, like api.HandleSubmitAction;
+// opts are passed to api.Submit (e.g. api.WithHistory).
+func NewServer(store vax.ChainStore, schemas *sdto.Registry, docs *schemaregistry.Registry, keys sae.KeyResolver, opts ...api.Option) *Server {
+	if store == nil || schemas == nil || docs == nil || keys == nil {
+		panic("rpc: NewServer needs a store, schema registries and a key resolver")
+	}
+	return &Server{store: store, schemas: schemas, docs: docs, keys: keys, opts: opts}
+}
+
+// GetSchema returns NotFound for unregistered actions.
+func (s *Server) GetSchema(_ context.Context, req *GetSchemaRequest) (*GetSchemaResponse, error) {
+	doc, err := s.docs.Document(req.Action)
+	if errors.Is(err, schemaregistry.ErrUnknownAction) {
+		return nil, &Status{Code: NotFound, Message: err.Error(), Detail: api.ErrorResponse{Code: api.CodeUnknownSchema, Message: err.Error()}}
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &GetSchemaResponse{Action: req.Action, Schema: doc}, nil
+}
+
+// SubmitAction verifies the SAE and advances the actor's chain; errors are
+// a *Status with the same Detail.Code as the HTTP endpoint. With
+// api.WithIdempotency, a retry under the same IdempotencyKey gets the
+// original response.
+func (s *Server) SubmitAction(ctx context.Context, req *SubmitActionRequest) (*SubmitActionResponse, error) {
+	receipt, err := api.SubmitContext(ctx, s.store, s.schemas, s.keys, api.SubmitRequest{
+		Actor:          req.Actor,
+		Counter:        req.Counter,
+		PrevSAI:        hex.EncodeToString(req.PrevSai),
+		SAE:            req.Sae,
+		SAI:            hex.EncodeToString(req.Sai),
+		IdempotencyKey: req.IdempotencyKey,
+	}, s.opts...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &SubmitActionResponse{
+		Actor:      receipt.Actor,
+		ActionType: receipt.ActionType,
+		Counter:    receipt.Counter,
+		Sai:        req.Sai,
+		PrevSai:    req.PrevSai,
+		AcceptedAt: receipt.AcceptedAt,
+	}, nil
+}
+
+// GetChainHead returns the actor's counter and head SAI, the prev_sai for
+// its next submission.
+func (s *Server) GetChainHead(ctx context.Context, req *GetChainHeadRequest) (*GetChainHeadResponse, error) {
+	if req.Actor == "" {
+		return nil, toStatus(fmt.Errorf("%w: actor is required", vax.ErrInvalidInput))
+	}
+	state, err := vax.HeadContext(ctx, s.store, req.Actor)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &GetChainHeadResponse{Actor: req.Actor, Counter: state.Counter, HeadSai: state.HeadSAI}, nil
+}
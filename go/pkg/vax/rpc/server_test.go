@@ -0,0 +1,159 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/api"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/schemaregistry"
+	"vax/pkg/vax/sdto"
+)
+
+const testActor = "user123:device456"
+
+type fixture struct {
+	srv     *Server
+	client  *Client
+	genesis []byte
+	priv    ed25519.PrivateKey
+}
+
+func newFixture(t *testing.T, opts ...api.Option) *fixture {
+	t.Helper()
+	genesis, _ := vax.ComputeGenesisSAI(testActor, bytes.Repeat([]byte{0x42}, vax.GenesisSaltSize))
+	store := vax.NewMemoryStore()
+	_ = store.Init(testActor, genesis)
+	pub, priv, _ := sae.GenerateKeyPair()
+
+	spec := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+	schemas := sdto.NewRegistry().Register("transfer", "", spec)
+	docs := schemaregistry.New()
+	if err := docs.RegisterFieldSpec("transfer", spec); err != nil {
+		t.Fatalf("RegisterFieldSpec failed: %v", err)
+	}
+	srv := NewServer(store, schemas, docs, sae.ActorResolver{testActor: {"k1": pub}}, opts...)
+	return &fixture{srv: srv, client: NewClient(LocalInvoker(srv)), genesis: genesis, priv: priv}
+}
+
+// submitRequest 建立綁定在 state 下一個位置、以 k1 簽章的 SubmitActionRequest
+func (f *fixture) submitRequest(t *testing.T, state vax.ChainState, data map[string]any) *SubmitActionRequest {
+	t.Helper()
+	unsigned, _, err := vax.BuildChainedSAE(state, "transfer", data, sae.WithMetadata(testActor, "", ""))
+	if err != nil {
+		t.Fatalf("BuildChainedSAE failed: %v", err)
+	}
+	env, _ := sae.Parse(unsigned)
+	env.Kid = "k1"
+	if err := env.Sign(f.priv); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	saeBytes, _ := jcs.Marshal(env)
+	sai, _ := vax.ComputeSAI(state.HeadSAI, saeBytes)
+	return &SubmitActionRequest{Actor: testActor, Counter: state.Counter + 1, PrevSai: state.HeadSAI, Sae: saeBytes, Sai: sai}
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetSchema returns the registered document", func(t *testing.T) {
+		f := newFixture(t)
+		resp, err := f.client.GetSchema(ctx, &GetSchemaRequest{Action: "transfer"})
+		if err != nil {
+			t.Fatalf("GetSchema failed: %v", err)
+		}
+		if resp.Action != "transfer" || !bytes.Contains(resp.Schema, []byte(`"amount"`)) {
+			t.Errorf("unexpected response %+v", resp)
+		}
+	})
+
+	t.Run("submit advances the head reported by GetChainHead", func(t *testing.T) {
+		f := newFixture(t)
+		head, err := f.client.GetChainHead(ctx, &GetChainHeadRequest{Actor: testActor})
+		if err != nil || head.Counter != 0 || !bytes.Equal(head.HeadSai, f.genesis) {
+			t.Fatalf("GetChainHead = %+v, %v", head, err)
+		}
+		req := f.submitRequest(t, vax.ChainState{Counter: head.Counter, HeadSAI: head.HeadSai}, map[string]any{"amount": 10})
+		resp, err := f.client.SubmitAction(ctx, req)
+		if err != nil {
+			t.Fatalf("SubmitAction failed: %v", err)
+		}
+		if resp.Counter != 1 || resp.ActionType != "transfer" || !bytes.Equal(resp.Sai, req.Sai) {
+			t.Errorf("unexpected response %+v", resp)
+		}
+		head, _ = f.client.GetChainHead(ctx, &GetChainHeadRequest{Actor: testActor})
+		if head.Counter != 1 || !bytes.Equal(head.HeadSai, req.Sai) {
+			t.Errorf("head after submit = %+v", head)
+		}
+	})
+
+	t.Run("error: replay is Aborted with chain_conflict", func(t *testing.T) {
+		f := newFixture(t)
+		req := f.submitRequest(t, vax.ChainState{HeadSAI: f.genesis}, map[string]any{"amount": 10})
+		if _, err := f.client.SubmitAction(ctx, req); err != nil {
+			t.Fatalf("first submit failed: %v", err)
+		}
+		_, err := f.client.SubmitAction(ctx, req)
+		if s := StatusOf(err); s == nil || s.Code != Aborted || s.Detail.Code != api.CodeChainConflict {
+			t.Fatalf("got %v", err)
+		}
+	})
+
+	t.Run("idempotency key is passed to api.Submit", func(t *testing.T) {
+		f := newFixture(t, api.WithIdempotency(api.NewMemoryIdempotency(time.Minute)))
+		req := f.submitRequest(t, vax.ChainState{HeadSAI: f.genesis}, map[string]any{"amount": 10})
+		req.IdempotencyKey = "order-1"
+		first, err := f.client.SubmitAction(ctx, req)
+		if err != nil {
+			t.Fatalf("first submit failed: %v", err)
+		}
+		retry, err := f.client.SubmitAction(ctx, req)
+		if err != nil || retry.AcceptedAt != first.AcceptedAt || !bytes.Equal(retry.Sai, first.Sai) {
+			t.Fatalf("retry = %+v, %v", retry, err)
+		}
+
+		other := f.submitRequest(t, vax.ChainState{Counter: 1, HeadSAI: first.Sai}, map[string]any{"amount": 20})
+		other.IdempotencyKey = "order-1"
+		_, err = f.client.SubmitAction(ctx, other)
+		if s := StatusOf(err); s == nil || s.Detail.Code != api.CodeIdempotencyConflict {
+			t.Errorf("reused key: %v", err)
+		}
+	})
+
+	errCases := []struct {
+		name   string
+		call   func(f *fixture) error
+		code   Code
+		detail string
+	}{
+		{"error: unknown schema document", func(f *fixture) error {
+			_, err := f.client.GetSchema(ctx, &GetSchemaRequest{Action: "nope"})
+			return err
+		}, NotFound, api.CodeUnknownSchema},
+		{"error: unknown actor", func(f *fixture) error {
+			_, err := f.client.GetChainHead(ctx, &GetChainHeadRequest{Actor: "nobody"})
+			return err
+		}, NotFound, api.CodeUnknownActor},
+		{"error: missing actor", func(f *fixture) error {
+			_, err := f.client.GetChainHead(ctx, &GetChainHeadRequest{})
+			return err
+		}, InvalidArgument, api.CodeInvalidInput},
+		{"error: invalid sdto", func(f *fixture) error {
+			_, err := f.client.SubmitAction(ctx, f.submitRequest(t, vax.ChainState{HeadSAI: f.genesis}, map[string]any{"amount": 5000}))
+			return err
+		}, InvalidArgument, api.CodeInvalidSDTO},
+	}
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call(newFixture(t))
+			if s := StatusOf(err); s == nil || s.Code != tc.code || s.Detail.Code != tc.detail {
+				t.Fatalf("got %v, want code %d / %s", err, tc.code, tc.detail)
+			}
+		})
+	}
+}
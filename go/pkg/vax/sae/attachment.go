@@ -0,0 +1,79 @@
+package sae
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Error codes
+var (
+	ErrAttachmentMissing   = errors.New("attachment missing")
+	ErrAttachmentMismatch  = errors.New("attachment does not match descriptor")
+	ErrDuplicateAttachment = errors.New("duplicate attachment name")
+)
+
+// Attachment binds an external blob to the envelope by content hash.
+// Only the descriptor is canonicalized; the blob travels out of band.
+type Attachment struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // hex
+}
+
+// NewAttachment streams r and returns its descriptor.
+func NewAttachment(name string, r io.Reader) (Attachment, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{
+		Name:   name,
+		Size:   n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// HashAttachment returns the descriptor of an in-memory blob.
+func HashAttachment(name string, data []byte) Attachment {
+	a, _ := NewAttachment(name, bytes.NewReader(data)) // bytes.Reader never fails
+	return a
+}
+
+// Verify checks that r has exactly the descriptor's size and SHA256.
+// At most Size+1 bytes are read.
+func (a Attachment) Verify(r io.Reader) error {
+	got, err := NewAttachment(a.Name, io.LimitReader(r, a.Size+1))
+	if err != nil {
+		return err
+	}
+	if got.Size != a.Size || got.SHA256 != a.SHA256 {
+		return fmt.Errorf("%w: %s", ErrAttachmentMismatch, a.Name)
+	}
+	return nil
+}
+
+// VerifyAttachments checks every descriptor against the blob of the same
+// name. Blobs without a descriptor are ignored; missing blobs are an error.
+func (e *Envelope) VerifyAttachments(blobs map[string]io.Reader) error {
+	seen := make(map[string]bool, len(e.Attachments))
+	for _, a := range e.Attachments {
+		if seen[a.Name] {
+			return fmt.Errorf("%w: %s", ErrDuplicateAttachment, a.Name)
+		}
+		seen[a.Name] = true
+
+		r, ok := blobs[a.Name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrAttachmentMissing, a.Name)
+		}
+		if err := a.Verify(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package sae
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachments(t *testing.T) {
+	scan := []byte("%PDF-1.7 fake scan")
+	photo := []byte{0x89, 'P', 'N', 'G'}
+
+	build := func() *Envelope {
+		return NewEnvelope("upload_claim", map[string]any{"claim": "c-1"},
+			WithTimestamp(time.UnixMilli(1704672000000)),
+			WithAttachments(HashAttachment("scan.pdf", scan), HashAttachment("photo.png", photo)),
+		)
+	}
+
+	t.Run("descriptor", func(t *testing.T) {
+		a := HashAttachment("empty", nil)
+		if a.Size != 0 || a.SHA256 != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+			t.Errorf("unexpected descriptor: %+v", a)
+		}
+	})
+
+	t.Run("verify blobs", func(t *testing.T) {
+		env := build()
+		err := env.VerifyAttachments(map[string]io.Reader{
+			"scan.pdf":  bytes.NewReader(scan),
+			"photo.png": bytes.NewReader(photo),
+		})
+		if err != nil {
+			t.Errorf("VerifyAttachments failed: %v", err)
+		}
+	})
+
+	t.Run("descriptors are signed", func(t *testing.T) {
+		pub, priv, _ := GenerateKeyPair()
+		env := build()
+		_ = env.Sign(priv)
+		env.Attachments[0].SHA256 = strings.Repeat("0", 64)
+		if err := env.Verify(pub); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("error: modified blob", func(t *testing.T) {
+		err := build().VerifyAttachments(map[string]io.Reader{
+			"scan.pdf":  bytes.NewReader(append(scan, '!')),
+			"photo.png": bytes.NewReader(photo),
+		})
+		if !errors.Is(err, ErrAttachmentMismatch) {
+			t.Errorf("expected ErrAttachmentMismatch, got %v", err)
+		}
+	})
+
+	t.Run("error: missing blob", func(t *testing.T) {
+		err := build().VerifyAttachments(map[string]io.Reader{
+			"scan.pdf": bytes.NewReader(scan),
+		})
+		if !errors.Is(err, ErrAttachmentMissing) {
+			t.Errorf("expected ErrAttachmentMissing, got %v", err)
+		}
+	})
+}
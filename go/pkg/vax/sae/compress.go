@@ -0,0 +1,123 @@
+package sae
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"vax/pkg/vax/jcs"
+)
+
+// Error codes
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported sdto encoding")
+	ErrPayloadTooLarge     = errors.New("decompressed sdto too large")
+)
+
+// EncodingGzipBase64 marks an sdto member carried as base64(gzip(JCS(sdto))).
+const EncodingGzipBase64 = "gzip+base64"
+
+// MaxDecompressedSDTO bounds Decompress output (decompression bomb guard).
+var MaxDecompressedSDTO int64 = 16 << 20
+
+// Compress returns the transport form of canonical SAE bytes: the sdto
+// member becomes a gzip+base64 string and "sdto_encoding" is added.
+//
+// The transport form is NOT canonical. SAI and signatures always cover the
+// uncompressed canonical bytes, which Decompress reproduces exactly.
+func Compress(saeBytes []byte) ([]byte, error) {
+	members, err := decodeMembers(saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := members["sdto_encoding"]; ok {
+		return nil, ErrUnsupportedEncoding // already compressed
+	}
+	if !isObject(members["sdto"]) {
+		return nil, ErrPayloadNotObject // e.g. encrypted envelopes
+	}
+
+	sdto, err := jcs.CanonicalizeJSON(members["sdto"])
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := zw.Write(sdto); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	members["sdto"] = encoded
+	members["sdto_encoding"] = json.RawMessage(`"` + EncodingGzipBase64 + `"`)
+	return jcs.Marshal(members)
+}
+
+// Decompress turns transport SAE bytes back into canonical SAE bytes.
+// Input without "sdto_encoding" is returned unchanged, so verifiers can
+// call it unconditionally before hashing.
+func Decompress(wire []byte) ([]byte, error) {
+	if !bytes.Contains(wire, []byte(`"sdto_encoding"`)) {
+		return wire, nil // fast path: nothing to undo
+	}
+	members, err := decodeMembers(wire)
+	if err != nil {
+		return nil, err
+	}
+
+	rawEnc, ok := members["sdto_encoding"]
+	if !ok {
+		return wire, nil
+	}
+	var enc string
+	if err := json.Unmarshal(rawEnc, &enc); err != nil || enc != EncodingGzipBase64 {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	var encoded string
+	if err := json.Unmarshal(members["sdto"], &encoded); err != nil {
+		return nil, ErrUnsupportedEncoding
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	sdto, err := io.ReadAll(io.LimitReader(zr, MaxDecompressedSDTO+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(sdto)) > MaxDecompressedSDTO {
+		return nil, ErrPayloadTooLarge
+	}
+	if !isObject(sdto) {
+		return nil, ErrPayloadNotObject
+	}
+
+	members["sdto"] = sdto
+	delete(members, "sdto_encoding")
+	return jcs.Marshal(members)
+}
+
+func decodeMembers(saeBytes []byte) (map[string]json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(saeBytes, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func isObject(raw []byte) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '{'
+}
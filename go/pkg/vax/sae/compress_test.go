@@ -0,0 +1,83 @@
+package sae
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/jcs"
+)
+
+func TestCompress(t *testing.T) {
+	samples := make([]any, 200)
+	for i := range samples {
+		samples[i] = map[string]any{"seq": i, "temp": 21.5, "unit": "celsius"}
+	}
+	saeBytes, err := BuildSAE("telemetry", map[string]any{"samples": samples},
+		WithTimestamp(time.UnixMilli(1704672000000)))
+	if err != nil {
+		t.Fatalf("BuildSAE failed: %v", err)
+	}
+
+	t.Run("round trip restores canonical bytes", func(t *testing.T) {
+		wire, err := Compress(saeBytes)
+		if err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		if len(wire)*4 > len(saeBytes) {
+			t.Errorf("wire form %d bytes, canonical %d: expected at least 4x smaller", len(wire), len(saeBytes))
+		}
+		if !strings.Contains(string(wire), `"sdto_encoding":"gzip+base64"`) {
+			t.Errorf("missing sdto_encoding: %s", wire)
+		}
+
+		got, err := Decompress(wire)
+		if err != nil {
+			t.Fatalf("Decompress failed: %v", err)
+		}
+		if !bytes.Equal(got, saeBytes) {
+			t.Error("Decompress did not reproduce the canonical bytes")
+		}
+	})
+
+	t.Run("signature survives transport", func(t *testing.T) {
+		pub, priv, _ := GenerateKeyPair()
+		env, _ := Parse(saeBytes)
+		_ = env.Sign(priv)
+		signed, _ := jcs.Marshal(env)
+
+		wire, _ := Compress(signed)
+		parsed, err := Parse(wire)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if err := parsed.Verify(pub); err != nil {
+			t.Errorf("Verify after transport failed: %v", err)
+		}
+	})
+
+	t.Run("uncompressed input passes through", func(t *testing.T) {
+		got, err := Decompress(saeBytes)
+		if err != nil || !bytes.Equal(got, saeBytes) {
+			t.Errorf("expected unchanged input, got err %v", err)
+		}
+	})
+
+	t.Run("error: unsupported encoding", func(t *testing.T) {
+		wire := []byte(`{"action_type":"a","sdto":"x","sdto_encoding":"brotli","timestamp":1}`)
+		if _, err := Decompress(wire); err != ErrUnsupportedEncoding {
+			t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+		}
+	})
+
+	t.Run("error: decompressed payload too large", func(t *testing.T) {
+		wire, _ := Compress(saeBytes)
+		old := MaxDecompressedSDTO
+		MaxDecompressedSDTO = 64
+		defer func() { MaxDecompressedSDTO = old }()
+		if _, err := Decompress(wire); err != ErrPayloadTooLarge {
+			t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package sae
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+
+	"vax/pkg/vax/jcs"
+)
+
+// Error codes
+var (
+	ErrNotEncrypted     = errors.New("envelope not encrypted")
+	ErrAlreadyEncrypted = errors.New("envelope already encrypted")
+	ErrDecrypt          = errors.New("decryption failed")
+	ErrMixedPayload     = errors.New("envelope has both sdto and encrypted")
+)
+
+// EncAlgX25519AESGCM is the payload encryption scheme (Encrypted.Alg):
+// ephemeral X25519 ECDH, HKDF-SHA256, then AES-256-GCM, all from the
+// standard library.
+const EncAlgX25519AESGCM = "X25519-HKDF-SHA256-A256GCM"
+
+const encInfo = "VAX-SAE-ENC-v1"
+
+// Encrypted replaces the sdto of a confidential envelope, which then
+// carries "sdto": null; an envelope with both is rejected (ErrMixedPayload).
+// The encrypted form is what gets canonicalized, signed and chained, so
+// relays can verify the chain without being able to read the payload.
+type Encrypted struct {
+	Alg        string `json:"alg"`
+	EPK        []byte `json:"epk"` // ephemeral X25519 public key
+	IV         []byte `json:"iv"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// GenerateEncryptionKey generates an X25519 recipient key pair.
+func GenerateEncryptionKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// Encrypt returns a copy of env whose sdto is encrypted to recipient.
+// The plaintext is the canonical sdto; action_type is bound as AAD.
+// Encrypt before signing and before computing the SAI.
+func Encrypt(env *Envelope, recipient *ecdh.PublicKey) (*Envelope, error) {
+	if env.Encrypted != nil {
+		return nil, ErrAlreadyEncrypted
+	}
+	if recipient == nil || recipient.Curve() != ecdh.X25519() {
+		return nil, ErrInvalidKey
+	}
+
+	plaintext, err := jcs.Marshal(env.SDTO)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	epk := ephemeral.PublicKey().Bytes()
+	aead, err := newPayloadAEAD(shared, epk, recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	out := *env
+	out.SDTO = nil
+	out.Signature = nil
+	out.Encrypted = &Encrypted{
+		Alg:        EncAlgX25519AESGCM,
+		EPK:        epk,
+		IV:         iv,
+		Ciphertext: aead.Seal(nil, iv, plaintext, []byte(env.ActionType)),
+	}
+	return &out, nil
+}
+
+// Decrypt returns a copy of env with the sdto restored.
+// The result is for reading only: the chain and signature cover the encrypted form.
+func Decrypt(env *Envelope, priv *ecdh.PrivateKey) (*Envelope, error) {
+	enc := env.Encrypted
+	if enc == nil {
+		return nil, ErrNotEncrypted
+	}
+	if env.SDTO != nil {
+		return nil, ErrMixedPayload
+	}
+	if enc.Alg != EncAlgX25519AESGCM {
+		return nil, ErrUnsupportedAlg
+	}
+	if priv == nil || priv.Curve() != ecdh.X25519() {
+		return nil, ErrInvalidKey
+	}
+
+	epk, err := ecdh.X25519().NewPublicKey(enc.EPK)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	shared, err := priv.ECDH(epk)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	aead, err := newPayloadAEAD(shared, enc.EPK, priv.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(enc.IV) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, enc.IV, enc.Ciphertext, []byte(env.ActionType))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	var sdto map[string]any
+	dec := json.NewDecoder(bytes.NewReader(plaintext))
+	dec.UseNumber()
+	if err := dec.Decode(&sdto); err != nil {
+		return nil, ErrDecrypt
+	}
+
+	out := *env
+	out.SDTO = sdto
+	out.Encrypted = nil
+	return &out, nil
+}
+
+// newPayloadAEAD derives the AES-256-GCM key:
+// HKDF-SHA256(ikm = shared, salt = epk || recipient, info = encInfo).
+func newPayloadAEAD(shared, epk, recipient []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, epk...), recipient...)
+	key, err := hkdf.Key(sha256.New, shared, salt, encInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
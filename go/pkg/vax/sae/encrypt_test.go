@@ -0,0 +1,99 @@
+package sae
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax/jcs"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	recipient, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatalf("GenerateEncryptionKey failed: %v", err)
+	}
+
+	newEnv := func() *Envelope {
+		return &Envelope{
+			ActionType: "record_vitals",
+			Timestamp:  1704672000000,
+			SDTO:       map[string]any{"patient": "alice", "bpm": 72},
+		}
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		enc, err := Encrypt(newEnv(), recipient.PublicKey())
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+
+		if enc.Encrypted.Alg != EncAlgX25519AESGCM || len(enc.Encrypted.IV) != 12 {
+			t.Errorf("alg %s, iv %d bytes", enc.Encrypted.Alg, len(enc.Encrypted.IV))
+		}
+
+		saeBytes, _ := jcs.Marshal(enc)
+		if strings.Contains(string(saeBytes), "alice") {
+			t.Errorf("plaintext leaked into canonical form: %s", saeBytes)
+		}
+
+		parsed, _ := Parse(saeBytes)
+		dec, err := Decrypt(parsed, recipient)
+		if err != nil {
+			t.Fatalf("Decrypt failed: %v", err)
+		}
+		got, _ := jcs.Marshal(dec.SDTO)
+		want, _ := jcs.Marshal(newEnv().SDTO)
+		if !bytes.Equal(got, want) {
+			t.Errorf("sdto = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("encrypted form is signable", func(t *testing.T) {
+		pub, priv, _ := GenerateKeyPair()
+		enc, _ := Encrypt(newEnv(), recipient.PublicKey())
+		_ = enc.Sign(priv)
+
+		saeBytes, _ := jcs.Marshal(enc)
+		parsed, _ := Parse(saeBytes)
+		if err := parsed.Verify(pub); err != nil {
+			t.Errorf("Verify of encrypted envelope failed: %v", err)
+		}
+	})
+
+	t.Run("error: wrong recipient", func(t *testing.T) {
+		other, _ := GenerateEncryptionKey()
+		enc, _ := Encrypt(newEnv(), recipient.PublicKey())
+		if _, err := Decrypt(enc, other); err != ErrDecrypt {
+			t.Errorf("expected ErrDecrypt, got %v", err)
+		}
+	})
+
+	t.Run("error: action_type is bound", func(t *testing.T) {
+		enc, _ := Encrypt(newEnv(), recipient.PublicKey())
+		enc.ActionType = "other"
+		if _, err := Decrypt(enc, recipient); err != ErrDecrypt {
+			t.Errorf("expected ErrDecrypt, got %v", err)
+		}
+	})
+
+	t.Run("error: sdto alongside encrypted", func(t *testing.T) {
+		enc, _ := Encrypt(newEnv(), recipient.PublicKey())
+		enc.SDTO = map[string]any{"patient": "mallory"}
+		if _, err := Decrypt(enc, recipient); err != ErrMixedPayload {
+			t.Errorf("Decrypt: expected ErrMixedPayload, got %v", err)
+		}
+		saeBytes, _ := jcs.Marshal(enc)
+		if _, err := Parse(saeBytes); err != ErrMixedPayload {
+			t.Errorf("Parse: expected ErrMixedPayload, got %v", err)
+		}
+	})
+
+	t.Run("error: unknown alg", func(t *testing.T) {
+		enc, _ := Encrypt(newEnv(), recipient.PublicKey())
+		enc.Encrypted.Alg = "X25519-HKDF-SHA256-XC20P"
+		if _, err := Decrypt(enc, recipient); err != ErrUnsupportedAlg {
+			t.Errorf("expected ErrUnsupportedAlg, got %v", err)
+		}
+	})
+}
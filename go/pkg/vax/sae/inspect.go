@@ -0,0 +1,71 @@
+package sae
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vax/pkg/vax/jcs"
+)
+
+// Inspection shows exactly which bytes an envelope produces, for debugging
+// cross-language hash and signature mismatches.
+type Inspection struct {
+	Canonical     []byte // SAE bytes hashed into the SAI
+	Digest        []byte // SHA256(Canonical)
+	SigningBytes  []byte // signature input: SigningContext || 0x00 || unsigned
+	SigningDigest []byte // SHA256(SigningBytes)
+	Pretty        string // indented view of Canonical (same key order)
+}
+
+// Inspect canonicalizes env and reports its hash and signing inputs.
+func Inspect(env *Envelope) (*Inspection, error) {
+	canonical, err := jcs.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	signing, err := env.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, canonical, "", "  "); err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(canonical)
+	signingDigest := sha256.Sum256(signing)
+	return &Inspection{
+		Canonical:     canonical,
+		Digest:        digest[:],
+		SigningBytes:  signing,
+		SigningDigest: signingDigest[:],
+		Pretty:        pretty.String(),
+	}, nil
+}
+
+// InspectBytes parses received SAE bytes and inspects them. If the bytes are
+// not already canonical, Canonical will differ from the input.
+func InspectBytes(saeBytes []byte) (*Inspection, error) {
+	env, err := Parse(saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	return Inspect(env)
+}
+
+// String renders a plain-text report.
+func (i *Inspection) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "canonical (%d bytes): %s\n", len(i.Canonical), i.Canonical)
+	fmt.Fprintf(&b, "sha256(canonical):   %s\n", hex.EncodeToString(i.Digest))
+	fmt.Fprintf(&b, "signing (%d bytes):   %q\n", len(i.SigningBytes), i.SigningBytes)
+	fmt.Fprintf(&b, "sha256(signing):     %s\n", hex.EncodeToString(i.SigningDigest))
+	b.WriteString(i.Pretty)
+	b.WriteByte('\n')
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package sae
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	env := &Envelope{
+		ActionType: "transfer",
+		Timestamp:  1704672000000,
+		SDTO:       map[string]any{"b": 2, "a": "你"},
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		in, err := Inspect(env)
+		if err != nil {
+			t.Fatalf("Inspect failed: %v", err)
+		}
+
+		want := `{"action_type":"transfer","sdto":{"a":"\u4f60","b":2},"timestamp":1704672000000}`
+		if string(in.Canonical) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", in.Canonical, want)
+		}
+		sum := sha256.Sum256([]byte(want))
+		if hex.EncodeToString(in.Digest) != hex.EncodeToString(sum[:]) {
+			t.Error("Digest is not SHA256(Canonical)")
+		}
+		if !strings.Contains(in.Pretty, "\n  \"sdto\": {") {
+			t.Errorf("unexpected Pretty output:\n%s", in.Pretty)
+		}
+	})
+
+	t.Run("signed envelope separates hash and signing input", func(t *testing.T) {
+		_, priv, _ := GenerateKeyPair()
+		signed := *env
+		_ = signed.Sign(priv)
+
+		in, _ := Inspect(&signed)
+		if strings.Contains(string(in.SigningBytes), `"signature"`) {
+			t.Error("SigningBytes must not contain the signature")
+		}
+		if !strings.Contains(string(in.Canonical), `"signature"`) {
+			t.Error("Canonical must contain the signature")
+		}
+	})
+
+	t.Run("non-canonical input", func(t *testing.T) {
+		in, err := InspectBytes([]byte(`{ "timestamp": 1, "sdto": {"x": 1.50}, "action_type": "t" }`))
+		if err != nil {
+			t.Fatalf("InspectBytes failed: %v", err)
+		}
+		if string(in.Canonical) != `{"action_type":"t","sdto":{"x":1.5},"timestamp":1}` {
+			t.Errorf("unexpected canonical form: %s", in.Canonical)
+		}
+		if !strings.HasPrefix(in.String(), "canonical (") {
+			t.Errorf("unexpected report:\n%s", in)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package sae
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is the subset of RFC 7517 / RFC 8037 JSON Web Keys used for envelope keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"` // private part (OKP / EC only)
+}
+
+// JWKSet is a JWKS document ({"keys":[...]}).
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var b64url = base64.RawURLEncoding
+
+// PublicKey decodes the JWK into an Ed25519, ECDSA P-256 or RSA public key.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrUnsupportedAlg
+		}
+		x, err := b64url.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKey
+		}
+		return ed25519.PublicKey(x), nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrUnsupportedAlg
+		}
+		x, errX := b64url.DecodeString(k.X)
+		y, errY := b64url.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, ErrInvalidKey
+		}
+		// crypto/ecdh rejects points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "RSA":
+		n, errN := b64url.DecodeString(k.N)
+		e, errE := b64url.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidKey
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if pub.N.BitLen() < MinRSABits || pub.E < 3 {
+			return nil, ErrInvalidKey
+		}
+		return pub, nil
+
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+}
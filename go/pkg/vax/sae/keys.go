@@ -0,0 +1,292 @@
+package sae
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+// ErrWrongPassphrase is returned when an encrypted key file cannot be opened.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupt key file")
+
+// PEM block types
+const (
+	PEMPublicKey           = "PUBLIC KEY"                // SPKI
+	PEMPrivateKey          = "PRIVATE KEY"               // PKCS#8
+	PEMEncryptedPrivateKey = "VAX ENCRYPTED PRIVATE KEY" // see EncryptPrivateKey
+)
+
+// ======== PEM ========
+
+// MarshalPublicKeyPEM encodes an envelope public key as SPKI PEM.
+func MarshalPublicKeyPEM(pub crypto.PublicKey) ([]byte, error) {
+	if _, err := AlgForKey(pub); err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMPublicKey, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM decodes an SPKI PEM public key usable with Envelope.Verify.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMPublicKey {
+		return nil, ErrInvalidKey
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	if _, err := AlgForKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// MarshalPrivateKeyPEM encodes a private key as unencrypted PKCS#8 PEM.
+func MarshalPrivateKeyPEM(priv crypto.Signer) ([]byte, error) {
+	der, err := marshalPKCS8(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMPrivateKey, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decodes an unencrypted PKCS#8 PEM private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMPrivateKey {
+		return nil, ErrInvalidKey
+	}
+	return parsePKCS8(block.Bytes)
+}
+
+func marshalPKCS8(priv crypto.Signer) ([]byte, error) {
+	if priv == nil {
+		return nil, ErrInvalidKey
+	}
+	if _, err := AlgForKey(priv.Public()); err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS8PrivateKey(priv)
+}
+
+func parsePKCS8(der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedAlg
+	}
+	if _, err := AlgForKey(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// ======== Encrypted key files ========
+
+// Key files use PBKDF2-HMAC-SHA256 (crypto/pbkdf2) with 600,000 iterations
+// by default. Version 1 files (scrypt) are no longer read.
+const (
+	keyFileVersion = 2
+	keyFileIter    = 600_000
+	keyFileSalt    = 16
+	keyFileHeader  = 1 + 4 + keyFileSalt
+)
+
+// 解密時接受的迭代次數上限：header 不受信任，避免被要求數秒以上的推導
+const maxKeyFileIter = 5_000_000
+
+// EncryptPrivateKey seals a private key with a passphrase:
+// PBKDF2-HMAC-SHA256 derives an AES-256-GCM key that encrypts the PKCS#8
+// DER.
+//
+// Block body: version(1) || iterations(4, big-endian) || salt(16) || iv(12) || ct
+// The 21-byte header (version..salt) is authenticated as AAD.
+func EncryptPrivateKey(priv crypto.Signer, passphrase []byte) ([]byte, error) {
+	der, err := marshalPKCS8(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, keyFileHeader)
+	header[0] = keyFileVersion
+	binary.BigEndian.PutUint32(header[1:5], keyFileIter)
+	if _, err := rand.Read(header[5:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := keyFileAEAD(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	body := append(append(header, iv...), aead.Seal(nil, iv, der, header)...)
+	return pem.EncodeToMemory(&pem.Block{Type: PEMEncryptedPrivateKey, Bytes: body}), nil
+}
+
+// DecryptPrivateKey opens a key file written by EncryptPrivateKey.
+// The iteration count comes from the file, so files asking for more than
+// 5,000,000 iterations are rejected with ErrInvalidKey before any key
+// derivation.
+func DecryptPrivateKey(data, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMEncryptedPrivateKey {
+		return nil, ErrInvalidKey
+	}
+
+	body := block.Bytes
+	if len(body) < keyFileHeader+12 || body[0] != keyFileVersion || !keyFileCostOK(body[1:5]) {
+		return nil, ErrInvalidKey
+	}
+	header := body[:keyFileHeader]
+
+	aead, err := keyFileAEAD(passphrase, header)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	rest := body[len(header):]
+	iv, ct := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	der, err := aead.Open(nil, iv, ct, header)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return parsePKCS8(der)
+}
+
+func keyFileCostOK(iter []byte) bool {
+	n := binary.BigEndian.Uint32(iter)
+	return n >= 1 && n <= maxKeyFileIter
+}
+
+func keyFileAEAD(passphrase, header []byte) (cipher.AEAD, error) {
+	iter := int(binary.BigEndian.Uint32(header[1:5]))
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), header[5:], iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ======== JWK ========
+
+// PublicJWK encodes an envelope public key as a JWK with the given kid.
+func PublicJWK(pub crypto.PublicKey, kid string) (JWK, error) {
+	alg, err := AlgForKey(pub)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	switch alg {
+	case AlgEd25519:
+		return JWK{Kty: "OKP", Crv: "Ed25519", Kid: kid, X: b64url.EncodeToString(pub.(ed25519.PublicKey))}, nil
+	case AlgECDSA:
+		k := pub.(*ecdsa.PublicKey)
+		return JWK{
+			Kty: "EC", Crv: "P-256", Kid: kid,
+			X: b64url.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+			Y: b64url.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+		}, nil
+	default: // AlgRSA
+		k := pub.(*rsa.PublicKey)
+		return JWK{
+			Kty: "RSA", Kid: kid,
+			N: b64url.EncodeToString(k.N.Bytes()),
+			E: b64url.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	}
+}
+
+// PrivateJWK encodes an Ed25519 or ECDSA P-256 private key as a JWK.
+// RSA private keys are only supported as PEM.
+func PrivateJWK(priv crypto.Signer, kid string) (JWK, error) {
+	if priv == nil {
+		return JWK{}, ErrInvalidKey
+	}
+	jwk, err := PublicJWK(priv.Public(), kid)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	switch k := priv.(type) {
+	case ed25519.PrivateKey:
+		jwk.D = b64url.EncodeToString(k.Seed())
+	case *ecdsa.PrivateKey:
+		jwk.D = b64url.EncodeToString(k.D.FillBytes(make([]byte, 32)))
+	default:
+		return JWK{}, ErrUnsupportedAlg
+	}
+	return jwk, nil
+}
+
+// PrivateKey decodes the private part of an OKP Ed25519 or EC P-256 JWK.
+func (k JWK) PrivateKey() (crypto.Signer, error) {
+	if k.D == "" {
+		return nil, ErrInvalidKey
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	d, err := b64url.DecodeString(k.D)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	switch p := pub.(type) {
+	case ed25519.PublicKey:
+		if len(d) != ed25519.SeedSize {
+			return nil, ErrInvalidKey
+		}
+		priv := ed25519.NewKeyFromSeed(d)
+		if !p.Equal(priv.Public()) {
+			return nil, ErrInvalidKey
+		}
+		return priv, nil
+
+	case *ecdsa.PublicKey:
+		if len(d) != 32 {
+			return nil, ErrInvalidKey
+		}
+		// Check d against (x, y) via crypto/ecdh
+		ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+		if err != nil {
+			return nil, ErrInvalidKey
+		}
+		point := append(append([]byte{4}, p.X.FillBytes(make([]byte, 32))...), p.Y.FillBytes(make([]byte, 32))...)
+		if string(ecdhKey.PublicKey().Bytes()) != string(point) {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsa.PrivateKey{PublicKey: *p, D: new(big.Int).SetBytes(d)}, nil
+
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+}
@@ -0,0 +1,159 @@
+package sae
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func testSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	_, ed, _ := GenerateKeyPair()
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rs, _ := rsa.GenerateKey(rand.Reader, 2048)
+	return map[string]crypto.Signer{AlgEd25519: ed, AlgECDSA: ec, AlgRSA: rs}
+}
+
+// signAndVerify checks that priv and pub are a working pair for envelopes.
+func signAndVerify(t *testing.T, priv crypto.Signer, pub crypto.PublicKey) {
+	t.Helper()
+	env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{"a": 1}}
+	if err := env.SignWith(priv, nil); err != nil {
+		t.Fatalf("SignWith failed: %v", err)
+	}
+	if err := env.Verify(pub); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestKeyPEM(t *testing.T) {
+	for alg, priv := range testSigners(t) {
+		t.Run(alg, func(t *testing.T) {
+			pubPEM, err := MarshalPublicKeyPEM(priv.Public())
+			if err != nil {
+				t.Fatalf("MarshalPublicKeyPEM failed: %v", err)
+			}
+			privPEM, err := MarshalPrivateKeyPEM(priv)
+			if err != nil {
+				t.Fatalf("MarshalPrivateKeyPEM failed: %v", err)
+			}
+
+			pub, err := ParsePublicKeyPEM(pubPEM)
+			if err != nil {
+				t.Fatalf("ParsePublicKeyPEM failed: %v", err)
+			}
+			priv2, err := ParsePrivateKeyPEM(privPEM)
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM failed: %v", err)
+			}
+			signAndVerify(t, priv2, pub)
+		})
+	}
+}
+
+func TestEncryptedKeyFile(t *testing.T) {
+	_, priv, _ := GenerateKeyPair()
+	pass := []byte("correct horse battery staple")
+
+	file, err := EncryptPrivateKey(priv, pass)
+	if err != nil {
+		t.Fatalf("EncryptPrivateKey failed: %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := DecryptPrivateKey(file, pass)
+		if err != nil {
+			t.Fatalf("DecryptPrivateKey failed: %v", err)
+		}
+		signAndVerify(t, got, priv.Public())
+	})
+
+	t.Run("error: wrong passphrase", func(t *testing.T) {
+		if _, err := DecryptPrivateKey(file, []byte("nope")); err != ErrWrongPassphrase {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+	})
+
+	t.Run("error: version 1 (scrypt) file", func(t *testing.T) {
+		block, _ := pem.Decode(file)
+		body := bytes.Clone(block.Bytes)
+		body[0] = 1
+		if _, err := DecryptPrivateKey(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: body}), pass); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("error: iteration count out of range", func(t *testing.T) {
+		block, _ := pem.Decode(file)
+		for name, iter := range map[string]uint32{
+			"too many": maxKeyFileIter + 1,
+			"max":      1<<32 - 1,
+			"zero":     0,
+		} {
+			body := bytes.Clone(block.Bytes)
+			binary.BigEndian.PutUint32(body[1:5], iter)
+			crafted := pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: body})
+			start := time.Now()
+			if _, err := DecryptPrivateKey(crafted, pass); err != ErrInvalidKey {
+				t.Errorf("%s: expected ErrInvalidKey, got %v", name, err)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("%s: rejected after %v", name, d)
+			}
+		}
+	})
+}
+
+func TestKeyJWK(t *testing.T) {
+	for alg, priv := range testSigners(t) {
+		t.Run(alg, func(t *testing.T) {
+			pubJWK, err := PublicJWK(priv.Public(), "k1")
+			if err != nil {
+				t.Fatalf("PublicJWK failed: %v", err)
+			}
+			raw, _ := json.Marshal(pubJWK)
+
+			var decoded JWK
+			_ = json.Unmarshal(raw, &decoded)
+			pub, err := decoded.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey failed: %v", err)
+			}
+
+			privJWK, err := PrivateJWK(priv, "k1")
+			if alg == AlgRSA {
+				if err != ErrUnsupportedAlg {
+					t.Errorf("expected ErrUnsupportedAlg for RSA private JWK, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrivateJWK failed: %v", err)
+			}
+			priv2, err := privJWK.PrivateKey()
+			if err != nil {
+				t.Fatalf("PrivateKey failed: %v", err)
+			}
+			signAndVerify(t, priv2, pub)
+		})
+	}
+
+	t.Run("error: d does not match public part", func(t *testing.T) {
+		_, a, _ := GenerateKeyPair()
+		_, b, _ := GenerateKeyPair()
+		ja, _ := PrivateJWK(a, "")
+		jb, _ := PrivateJWK(b, "")
+		ja.D = jb.D
+		if _, err := ja.PrivateKey(); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package sae
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes
+var (
+	ErrEnvelopeTooLarge = errors.New("envelope too large")
+	ErrTooManyFields    = errors.New("sdto has too many fields")
+	ErrStringTooLong    = errors.New("sdto string too long")
+)
+
+// EnvelopeLimits bounds the envelopes Parse and BuildSAE accept, so an
+// oversized submission is rejected before it is canonicalized or hashed.
+// A zero field disables that limit.
+type EnvelopeLimits struct {
+	// MaxBytes bounds the envelope bytes, both as received and once
+	// decompressed (see Decompress).
+	MaxBytes int
+	// MaxFields bounds the values in the sdto: every member of every
+	// object and every element of every array, at any depth.
+	MaxFields int
+	// MaxString bounds the length in bytes of every sdto string, keys
+	// included. It applies to bytes fields too (sdto type "bytes", carried
+	// as base64 strings), so it is off by default: set it only when no
+	// schema carries payloads larger than the limit.
+	MaxString int
+}
+
+// Limits is applied by Parse, BuildSAE and vax.VerifyAction; set it once
+// at startup. By default envelopes are bounded to 4 MiB and 1024 fields,
+// and strings only by MaxBytes.
+var Limits = EnvelopeLimits{MaxBytes: 4 << 20, MaxFields: 1024}
+
+// CheckSize fails with ErrEnvelopeTooLarge when n envelope bytes exceed
+// l.MaxBytes.
+func (l EnvelopeLimits) CheckSize(n int) error {
+	if l.MaxBytes > 0 && n > l.MaxBytes {
+		return fmt.Errorf("%w: %d bytes, max %d", ErrEnvelopeTooLarge, n, l.MaxBytes)
+	}
+	return nil
+}
+
+// CheckSDTO fails with ErrTooManyFields or ErrStringTooLong when sdto
+// exceeds l.
+func (l EnvelopeLimits) CheckSDTO(sdto map[string]any) error {
+	fields := 0
+	return l.walk(sdto, "sdto", &fields)
+}
+
+// walk 走訪 v，累計 fields 並檢查字串長度；超過 MaxFields 時立即停止
+func (l EnvelopeLimits) walk(v any, path string, fields *int) error {
+	count := func(n int) error {
+		*fields += n
+		if l.MaxFields > 0 && *fields > l.MaxFields {
+			return fmt.Errorf("%w: more than %d", ErrTooManyFields, l.MaxFields)
+		}
+		return nil
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if err := count(len(v)); err != nil {
+			return err
+		}
+		for k, x := range v {
+			if err := l.checkString(k, path); err != nil {
+				return err
+			}
+			if err := l.walk(x, path+"."+k, fields); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if err := count(len(v)); err != nil {
+			return err
+		}
+		for i, x := range v {
+			if err := l.walk(x, fmt.Sprintf("%s[%d]", path, i), fields); err != nil {
+				return err
+			}
+		}
+	case string:
+		return l.checkString(v, path)
+	}
+	return nil
+}
+
+func (l EnvelopeLimits) checkString(s, path string) error {
+	if l.MaxString > 0 && len(s) > l.MaxString {
+		return fmt.Errorf("%w: %s is %d bytes, max %d", ErrStringTooLong, path, len(s), l.MaxString)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package sae
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withLimits 於測試期間替換 Limits
+func withLimits(t *testing.T, l EnvelopeLimits) {
+	t.Helper()
+	old := Limits
+	Limits = l
+	t.Cleanup(func() { Limits = old })
+}
+
+func TestLimits(t *testing.T) {
+	t.Run("within limits", func(t *testing.T) {
+		withLimits(t, EnvelopeLimits{MaxBytes: 200, MaxFields: 4, MaxString: 5})
+		b, err := BuildSAE("transfer", map[string]any{"to": "bob", "tags": []any{"a", "b"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(b); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("zero disables", func(t *testing.T) {
+		withLimits(t, EnvelopeLimits{})
+		if _, err := BuildSAE("transfer", map[string]any{"memo": strings.Repeat("x", 1<<20)}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("default accepts large strings and bytes fields", func(t *testing.T) {
+		blob := base64.StdEncoding.EncodeToString(make([]byte, 1<<20))
+		b, err := BuildSAE("upload", map[string]any{"name": strings.Repeat("n", 100<<10), "file": blob})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(b); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error: envelope too large", func(t *testing.T) {
+		withLimits(t, EnvelopeLimits{MaxBytes: 64})
+		sdto := map[string]any{"memo": strings.Repeat("x", 64)}
+		if _, err := BuildSAE("transfer", sdto); !errors.Is(err, ErrEnvelopeTooLarge) {
+			t.Errorf("BuildSAE: %v", err)
+		}
+		Limits = EnvelopeLimits{}
+		b, _ := BuildSAE("transfer", sdto)
+		wire, _ := Compress(b)
+		Limits = EnvelopeLimits{MaxBytes: 64}
+		if _, err := Parse(b); !errors.Is(err, ErrEnvelopeTooLarge) {
+			t.Errorf("Parse: %v", err)
+		}
+		// 壓縮後可能在上限內，解壓後仍要檢查
+		if _, err := Parse(wire); !errors.Is(err, ErrEnvelopeTooLarge) {
+			t.Errorf("Parse compressed: %v", err)
+		}
+	})
+
+	t.Run("error: too many fields", func(t *testing.T) {
+		withLimits(t, EnvelopeLimits{MaxFields: 3})
+		for _, sdto := range []map[string]any{
+			{"a": 1, "b": 2, "c": 3, "d": 4},
+			{"a": map[string]any{"b": 1, "c": 2, "d": 3}},
+			{"a": []any{1, 2, 3}},
+		} {
+			if _, err := BuildSAE("t", sdto); !errors.Is(err, ErrTooManyFields) {
+				t.Errorf("%v: %v", sdto, err)
+			}
+		}
+		if _, err := Parse([]byte(`{"action_type":"t","sdto":{"a":[[1],[2]]},"timestamp":1}`)); !errors.Is(err, ErrTooManyFields) {
+			t.Errorf("Parse: %v", err)
+		}
+	})
+
+	t.Run("error: string too long", func(t *testing.T) {
+		withLimits(t, EnvelopeLimits{MaxString: 4})
+		_, err := BuildSAE("t", map[string]any{"a": map[string]any{"b": []any{"12345"}}})
+		if !errors.Is(err, ErrStringTooLong) || !strings.Contains(err.Error(), "sdto.a.b[0]") {
+			t.Errorf("value: %v", err)
+		}
+		if _, err := Parse([]byte(`{"action_type":"t","sdto":{"longkey":1},"timestamp":1}`)); !errors.Is(err, ErrStringTooLong) {
+			t.Errorf("key: %v", err)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package sae
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+)
+
+// DigestPrefix returns the first 8 bytes of SHA256(b) in hex, the form log
+// records use to identify an envelope without logging its contents.
+func DigestPrefix(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+func logBuild(l *slog.Logger, actionType string, counter uint64, canonical []byte, err error) {
+	if l == nil || !l.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if err != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "sae: build failed",
+			slog.String("action_type", actionType), slog.String("error", err.Error()))
+		return
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "sae: built envelope",
+		slog.String("action_type", actionType), slog.Uint64("counter", counter),
+		slog.Int("bytes", len(canonical)), slog.String("sae_sha256", DigestPrefix(canonical)))
+}
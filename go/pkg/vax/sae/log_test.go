@@ -0,0 +1,36 @@
+package sae
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	b, err := BuildSAE("transfer", map[string]any{"to": "secret-recipient"}, WithChain(4, make([]byte, 32)), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`msg="sae: built envelope"`, "action_type=transfer", "counter=4", "sae_sha256=" + DigestPrefix(b)} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret-recipient") {
+		t.Error("SDTO value logged")
+	}
+
+	t.Run("error: failed validation", func(t *testing.T) {
+		buf.Reset()
+		_, err := BuildSAE("transfer", nil, WithValidator(func(map[string]any) error { return errors.New("bad payload") }), logger)
+		if err == nil || !strings.Contains(buf.String(), `msg="sae: build failed"`) || !strings.Contains(buf.String(), "bad payload") {
+			t.Errorf("err %v, logs %s", err, buf.String())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package sae
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"time"
+)
+
+// Option configures BuildSAE / NewEnvelope.
+type Option func(*buildConfig)
+
+type buildConfig struct {
+	clock   func() time.Time
+	at      *time.Time
+	nonce   string
+	meta    *Metadata
+	counter uint64
+	prevSAI string
+
+	attachments   []Attachment
+	schemaVersion string
+	schemaFP      string
+	validate      func(map[string]any) error
+	logger        *slog.Logger
+}
+
+func newBuildConfig(opts []Option) *buildConfig {
+	cfg := &buildConfig{clock: time.Now}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func (c *buildConfig) timestamp() time.Time {
+	if c.at != nil {
+		return *c.at
+	}
+	return c.clock()
+}
+
+// WithTimestamp pins the envelope timestamp (takes precedence over WithClock).
+func WithTimestamp(t time.Time) Option {
+	return func(c *buildConfig) {
+		c.at = &t
+	}
+}
+
+// WithClock replaces time.Now as the timestamp source.
+func WithClock(clock func() time.Time) Option {
+	return func(c *buildConfig) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// WithNonce sets the envelope nonce (see NewNonce).
+func WithNonce(nonce string) Option {
+	return func(c *buildConfig) {
+		c.nonce = nonce
+	}
+}
+
+// WithMetadata attaches actor / device / session context.
+// Empty values are omitted from the canonical form.
+func WithMetadata(actor, device, session string) Option {
+	return func(c *buildConfig) {
+		c.meta = &Metadata{Actor: actor, Device: device, Session: session}
+	}
+}
+
+// WithChain binds the envelope to a chain position (counter, prev_sai).
+func WithChain(counter uint64, prevSAI []byte) Option {
+	return func(c *buildConfig) {
+		c.counter = counter
+		c.prevSAI = hex.EncodeToString(prevSAI)
+	}
+}
+
+// WithAttachments binds attachment descriptors (see NewAttachment).
+func WithAttachments(attachments ...Attachment) Option {
+	return func(c *buildConfig) {
+		c.attachments = append(c.attachments, attachments...)
+	}
+}
+
+// WithValidator runs validate on the sdto before canonicalization, e.g.
+//
+//	sae.WithValidator(func(m map[string]any) error { return sdto.ValidateData(m, schema) })
+func WithValidator(validate func(sdto map[string]any) error) Option {
+	return func(c *buildConfig) {
+		c.validate = validate
+	}
+}
+
+// WithSchemaVersion records which schema version the sdto was built against.
+func WithSchemaVersion(version string) Option {
+	return func(c *buildConfig) {
+		c.schemaVersion = version
+	}
+}
+
+// WithSchemaFingerprint records the fingerprint of the schema the sdto was
+// validated against (see sdto.Fingerprint).
+func WithSchemaFingerprint(fingerprint string) Option {
+	return func(c *buildConfig) {
+		c.schemaFP = fingerprint
+	}
+}
+
+// WithLogger sends BuildSAE a debug record to l for the envelope it builds:
+// the action type, chain counter, size and SHA-256 prefix of the canonical
+// envelope, or the error. SDTO values are never logged. Without it nothing
+// is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *buildConfig) {
+		c.logger = l
+	}
+}
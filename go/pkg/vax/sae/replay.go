@@ -0,0 +1,139 @@
+package sae
+
+import (
+	"container/heap"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Error codes
+var (
+	ErrMissingNonce = errors.New("envelope has no nonce")
+	ErrReplay       = errors.New("nonce already used")
+	ErrNotYetValid  = errors.New("envelope not yet valid")
+	ErrExpired      = errors.New("envelope expired")
+)
+
+// NonceSize is the number of random bytes in a generated nonce.
+const NonceSize = 16
+
+// NewNonce returns a random base64url nonce.
+func NewNonce() (string, error) {
+	b := make([]byte, NonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b64url.EncodeToString(b), nil
+}
+
+// SetValidity sets the not_before / expires_at window (unix ms).
+// A zero time leaves the corresponding bound unset.
+func (e *Envelope) SetValidity(notBefore, expiresAt time.Time) {
+	e.NotBefore, e.ExpiresAt = 0, 0
+	if !notBefore.IsZero() {
+		e.NotBefore = notBefore.UnixMilli()
+	}
+	if !expiresAt.IsZero() {
+		e.ExpiresAt = expiresAt.UnixMilli()
+	}
+}
+
+// CheckReplay checks the validity window against now and, when store is
+// non-nil, records the nonce so a second use fails with ErrReplay.
+//
+// These fields only protect against replay when the envelope is signed;
+// call Verify first.
+func (e *Envelope) CheckReplay(now time.Time, store NonceStore) error {
+	ms := now.UnixMilli()
+	if e.NotBefore != 0 && ms < e.NotBefore {
+		return ErrNotYetValid
+	}
+	if e.ExpiresAt != 0 && ms >= e.ExpiresAt {
+		return ErrExpired
+	}
+
+	if store == nil {
+		return nil
+	}
+	if e.Nonce == "" {
+		return ErrMissingNonce
+	}
+
+	var expiresAt time.Time
+	if e.ExpiresAt != 0 {
+		expiresAt = time.UnixMilli(e.ExpiresAt)
+	}
+	return store.Use(e.Nonce, expiresAt)
+}
+
+// NonceStore remembers used nonces.
+type NonceStore interface {
+	// Use records nonce and returns ErrReplay if it was seen before.
+	// expiresAt (zero = never) tells the store when it may forget the nonce.
+	Use(nonce string, expiresAt time.Time) error
+}
+
+// MemoryNonceStore is an in-process NonceStore.
+// Nonces without an expiry are kept forever; expired ones are dropped in
+// expiry order, so Use costs O(log n) however many nonces are live.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	expiry nonceHeap // 有期限的 nonce，依到期時間排序
+	now    func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) Use(nonce string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.prune(now)
+	if _, ok := s.seen[nonce]; ok {
+		return ErrReplay
+	}
+
+	s.seen[nonce] = expiresAt
+	if !expiresAt.IsZero() {
+		heap.Push(&s.expiry, nonceExpiry{nonce: nonce, at: expiresAt})
+	}
+	return nil
+}
+
+// prune 從 heap 頂端移除所有已到期的 nonce
+func (s *MemoryNonceStore) prune(now time.Time) {
+	for len(s.expiry) > 0 && !now.Before(s.expiry[0].at) {
+		e := heap.Pop(&s.expiry).(nonceExpiry)
+		if exp, ok := s.seen[e.nonce]; ok && exp.Equal(e.at) {
+			delete(s.seen, e.nonce)
+		}
+	}
+}
+
+type nonceExpiry struct {
+	nonce string
+	at    time.Time
+}
+
+// nonceHeap 實作 heap.Interface（最早到期者在頂端）
+type nonceHeap []nonceExpiry
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(nonceExpiry)) }
+func (h *nonceHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
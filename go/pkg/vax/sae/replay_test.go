@@ -0,0 +1,99 @@
+package sae
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckReplay(t *testing.T) {
+	now := time.UnixMilli(1704672000000)
+
+	newEnv := func() *Envelope {
+		nonce, err := NewNonce()
+		if err != nil {
+			t.Fatalf("NewNonce failed: %v", err)
+		}
+		env := &Envelope{ActionType: "transfer", Timestamp: now.UnixMilli(), SDTO: map[string]any{}, Nonce: nonce}
+		env.SetValidity(now.Add(-time.Minute), now.Add(time.Minute))
+		return env
+	}
+
+	t.Run("fields are signed", func(t *testing.T) {
+		pub, priv, _ := GenerateKeyPair()
+		env := newEnv()
+		_ = env.Sign(priv)
+		env.ExpiresAt += 3600_000
+		if err := env.Verify(pub); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature after extending expiry, got %v", err)
+		}
+	})
+
+	t.Run("replay rejected", func(t *testing.T) {
+		store := NewMemoryNonceStore()
+		store.now = func() time.Time { return now }
+		env := newEnv()
+		if err := env.CheckReplay(now, store); err != nil {
+			t.Fatalf("first use failed: %v", err)
+		}
+		if err := env.CheckReplay(now, store); err != ErrReplay {
+			t.Errorf("expected ErrReplay, got %v", err)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		env := newEnv()
+		if err := env.CheckReplay(now.Add(-2*time.Minute), nil); err != ErrNotYetValid {
+			t.Errorf("expected ErrNotYetValid, got %v", err)
+		}
+		if err := env.CheckReplay(now.Add(time.Minute), nil); err != ErrExpired {
+			t.Errorf("expected ErrExpired, got %v", err)
+		}
+	})
+
+	t.Run("expired nonces are pruned", func(t *testing.T) {
+		store := NewMemoryNonceStore()
+		clock := now
+		store.now = func() time.Time { return clock }
+
+		_ = store.Use("a", now.Add(time.Second))
+		clock = now.Add(2 * time.Second)
+		if err := store.Use("a", now.Add(time.Hour)); err != nil {
+			t.Errorf("expired nonce should be reusable, got %v", err)
+		}
+		if err := store.Use("a", time.Time{}); err != ErrReplay {
+			t.Errorf("re-recorded nonce: expected ErrReplay, got %v", err)
+		}
+	})
+
+	t.Run("pruning keeps only live nonces", func(t *testing.T) {
+		store := NewMemoryNonceStore()
+		clock := now
+		store.now = func() time.Time { return clock }
+
+		for i := 0; i < 1000; i++ {
+			_ = store.Use(strconv.Itoa(i), now.Add(time.Duration(i%10+1)*time.Second))
+		}
+		_ = store.Use("forever", time.Time{})
+		clock = now.Add(5 * time.Second)
+		_ = store.Use("late", now.Add(time.Hour))
+		// 到期時間 6s..10s 的 500 個、forever 與 late
+		if len(store.seen) != 502 || len(store.expiry) != 501 {
+			t.Errorf("seen %d, heap %d", len(store.seen), len(store.expiry))
+		}
+		if err := store.Use("999", time.Time{}); err != ErrReplay {
+			t.Errorf("live nonce: expected ErrReplay, got %v", err)
+		}
+		if err := store.Use("0", time.Time{}); err != nil {
+			t.Errorf("expired nonce: %v", err)
+		}
+	})
+
+	t.Run("error: missing nonce", func(t *testing.T) {
+		env := newEnv()
+		env.Nonce = ""
+		if err := env.CheckReplay(now, NewMemoryNonceStore()); err != ErrMissingNonce {
+			t.Errorf("expected ErrMissingNonce, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package sae
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Error codes
+var (
+	ErrMissingKid  = errors.New("envelope has no kid")
+	ErrUnknownKey  = errors.New("unknown key id")
+	ErrKeyNotOwned = errors.New("key does not belong to the actor")
+)
+
+// KeyResolver maps an envelope's kid to the public key that signed it.
+type KeyResolver interface {
+	Resolve(kid string) (crypto.PublicKey, error)
+}
+
+// ContextKeyResolver is a KeyResolver whose lookups can block (network,
+// KMS); ResolveKey passes the caller's context to it so deadlines and
+// cancellation reach the fetch.
+type ContextKeyResolver interface {
+	KeyResolver
+	ResolveContext(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// ResolveKey resolves kid through r, with ctx when r is a
+// ContextKeyResolver. It fails with ctx.Err() once ctx is done.
+func ResolveKey(ctx context.Context, r KeyResolver, kid string) (crypto.PublicKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cr, ok := r.(ContextKeyResolver); ok {
+		return cr.ResolveContext(ctx, kid)
+	}
+	return r.Resolve(kid)
+}
+
+// VerifyWithResolver looks up Envelope.Kid via the resolver and verifies the signature.
+func (e *Envelope) VerifyWithResolver(r KeyResolver, opts ...VerifyOption) error {
+	return e.VerifyWithResolverContext(context.Background(), r, opts...)
+}
+
+// VerifyWithResolverContext is VerifyWithResolver with the key lookup
+// bound to ctx (see ResolveKey).
+func (e *Envelope) VerifyWithResolverContext(ctx context.Context, r KeyResolver, opts ...VerifyOption) error {
+	if e.Kid == "" {
+		return ErrMissingKid
+	}
+	pub, err := ResolveKey(ctx, r, e.Kid)
+	if err != nil {
+		return err
+	}
+	return e.Verify(pub, opts...)
+}
+
+// StaticResolver is an in-memory kid → public key table.
+type StaticResolver map[string]crypto.PublicKey
+
+func (r StaticResolver) Resolve(kid string) (crypto.PublicKey, error) {
+	pub, ok := r[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return pub, nil
+}
+
+// KeyOwner is implemented by resolvers that know which actor each kid was
+// issued to. Servers use it so that a valid signature by one actor's key
+// cannot advance another actor's chain.
+type KeyOwner interface {
+	// Owner returns the actor kid belongs to (ErrUnknownKey if none).
+	Owner(kid string) (string, error)
+}
+
+var (
+	_ KeyResolver = ActorResolver(nil)
+	_ KeyOwner    = ActorResolver(nil)
+)
+
+// ActorResolver is an in-memory actor → kid → public key table. A kid
+// listed under more than one actor is ambiguous and does not resolve.
+type ActorResolver map[string]StaticResolver
+
+func (r ActorResolver) Resolve(kid string) (crypto.PublicKey, error) {
+	actor, err := r.Owner(kid)
+	if err != nil {
+		return nil, err
+	}
+	return r[actor].Resolve(kid)
+}
+
+func (r ActorResolver) Owner(kid string) (string, error) {
+	if kid == "" {
+		return "", ErrMissingKid
+	}
+	owner := ""
+	for actor, keys := range r {
+		if _, ok := keys[kid]; !ok {
+			continue
+		}
+		if owner != "" {
+			return "", ErrUnknownKey
+		}
+		owner = actor
+	}
+	if owner == "" {
+		return "", ErrUnknownKey
+	}
+	return owner, nil
+}
+
+// Defaults for JWKSResolver
+const (
+	DefaultJWKSTTL        = 5 * time.Minute
+	DefaultJWKSMinRefresh = 30 * time.Second
+)
+
+// maxJWKSBytes 限制 JWKS 回應大小，避免異常端點灌爆記憶體
+const maxJWKSBytes = 1 << 20
+
+var _ ContextKeyResolver = (*JWKSResolver)(nil)
+
+// JWKSResolver resolves kids from a JWKS document served over HTTP.
+//
+// Keys are cached for TTL. An unknown kid triggers a refetch, but at most
+// once per MinRefresh, so bogus kids cannot be used to hammer the endpoint.
+// Failed fetches count too: while the endpoint is down, lookups that miss
+// the cache get the last fetch error until MinRefresh has passed. Documents
+// larger than 1 MiB are rejected.
+type JWKSResolver struct {
+	URL        string
+	Client     *http.Client
+	TTL        time.Duration
+	MinRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time // 最後一次成功取得
+	attempted time.Time // 最後一次嘗試（含失敗）
+	fetchErr  error     // 最後一次嘗試的錯誤
+}
+
+// NewJWKSResolver creates a resolver for the JWKS at url with default cache settings.
+func NewJWKSResolver(url string) *JWKSResolver {
+	return &JWKSResolver{
+		URL:        url,
+		Client:     http.DefaultClient,
+		TTL:        DefaultJWKSTTL,
+		MinRefresh: DefaultJWKSMinRefresh,
+	}
+}
+
+func (r *JWKSResolver) Resolve(kid string) (crypto.PublicKey, error) {
+	return r.ResolveContext(context.Background(), kid)
+}
+
+// ResolveContext is Resolve with the JWKS fetch bound to ctx; a cancelled
+// fetch still falls back to a stale cached key.
+func (r *JWKSResolver) ResolveContext(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	age := time.Since(r.fetched)
+	pub, ok := r.keys[kid]
+	if ok && age < r.TTL {
+		return pub, nil
+	}
+
+	if time.Since(r.attempted) >= r.MinRefresh {
+		if err := r.refresh(ctx); err != nil {
+			// Serve stale keys rather than failing closed on a transient outage
+			if ok {
+				return pub, nil
+			}
+			return nil, err
+		}
+		pub, ok = r.keys[kid]
+	}
+	if !ok {
+		if r.fetchErr != nil {
+			return nil, r.fetchErr
+		}
+		return nil, ErrUnknownKey
+	}
+	return pub, nil
+}
+
+// refresh 取得 JWKS；失敗也記下嘗試時間，呼叫端自己取消的除外
+func (r *JWKSResolver) refresh(ctx context.Context) error {
+	err := r.fetch(ctx)
+	if err == nil || ctx.Err() == nil {
+		r.attempted = time.Now()
+		r.fetchErr = err
+	}
+	return err
+}
+
+func (r *JWKSResolver) fetch(ctx context.Context) error {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(&set); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kid == "" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			// Skip keys we cannot use instead of rejecting the whole set
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	r.keys = keys
+	r.fetched = time.Now()
+	return nil
+}
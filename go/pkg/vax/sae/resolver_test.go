@@ -0,0 +1,193 @@
+package sae
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaticResolver(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	resolver := StaticResolver{"device-1": pub}
+
+	t.Run("basic", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}, Kid: "device-1"}
+		_ = env.Sign(priv)
+		if err := env.VerifyWithResolver(resolver); err != nil {
+			t.Errorf("VerifyWithResolver failed: %v", err)
+		}
+	})
+
+	t.Run("error: unknown kid", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}, Kid: "device-2"}
+		_ = env.Sign(priv)
+		if err := env.VerifyWithResolver(resolver); err != ErrUnknownKey {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+	})
+
+	t.Run("error: missing kid", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		_ = env.Sign(priv)
+		if err := env.VerifyWithResolver(resolver); err != ErrMissingKid {
+			t.Errorf("expected ErrMissingKid, got %v", err)
+		}
+	})
+}
+
+func TestActorResolver(t *testing.T) {
+	alice, _, _ := GenerateKeyPair()
+	bob, _, _ := GenerateKeyPair()
+	resolver := ActorResolver{"alice": {"a1": alice}, "bob": {"b1": bob, "shared": bob}, "carol": {"shared": alice}}
+
+	t.Run("resolves kids and reports owners", func(t *testing.T) {
+		if key, err := resolver.Resolve("b1"); err != nil || !bob.Equal(key) {
+			t.Errorf("Resolve(b1) = %v, %v", key, err)
+		}
+		if owner, err := resolver.Owner("a1"); err != nil || owner != "alice" {
+			t.Errorf("Owner(a1) = %q, %v", owner, err)
+		}
+	})
+
+	t.Run("error: unknown, missing and ambiguous kids", func(t *testing.T) {
+		for kid, want := range map[string]error{"zz": ErrUnknownKey, "": ErrMissingKid, "shared": ErrUnknownKey} {
+			if _, err := resolver.Resolve(kid); !errors.Is(err, want) {
+				t.Errorf("Resolve(%q): expected %v, got %v", kid, want, err)
+			}
+			if _, err := resolver.Owner(kid); !errors.Is(err, want) {
+				t.Errorf("Owner(%q): expected %v, got %v", kid, want, err)
+			}
+		}
+	})
+}
+
+func TestJWKSResolver(t *testing.T) {
+	edPub, edPriv, _ := GenerateKeyPair()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	set := JWKSet{Keys: []JWK{
+		{Kty: "OKP", Crv: "Ed25519", Kid: "ed", X: b64url.EncodeToString(edPub)},
+		{
+			Kty: "EC", Crv: "P-256", Kid: "ec",
+			X: b64url.EncodeToString(ecKey.PublicKey.X.FillBytes(make([]byte, 32))),
+			Y: b64url.EncodeToString(ecKey.PublicKey.Y.FillBytes(make([]byte, 32))),
+		},
+		{Kty: "oct", Kid: "ignored"},
+	}}
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	resolver := NewJWKSResolver(srv.URL)
+
+	t.Run("ed25519 and ecdsa keys", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}, Kid: "ed"}
+		_ = env.Sign(edPriv)
+		if err := env.VerifyWithResolver(resolver); err != nil {
+			t.Errorf("ed25519: %v", err)
+		}
+
+		env = &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}, Kid: "ec"}
+		_ = env.SignWith(ecKey, nil)
+		if err := env.VerifyWithResolver(resolver); err != nil {
+			t.Errorf("ecdsa: %v", err)
+		}
+
+		if hits.Load() != 1 {
+			t.Errorf("JWKS fetched %d times, want 1 (cached)", hits.Load())
+		}
+	})
+
+	t.Run("unknown kid does not refetch within MinRefresh", func(t *testing.T) {
+		before := hits.Load()
+		if _, err := resolver.Resolve("nope"); err != ErrUnknownKey {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+		if hits.Load() != before {
+			t.Error("unknown kid should not trigger a refetch within MinRefresh")
+		}
+	})
+
+	t.Run("error: failed fetches back off for MinRefresh", func(t *testing.T) {
+		var down atomic.Int32
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			down.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer failing.Close()
+
+		r := NewJWKSResolver(failing.URL)
+		for i := 0; i < 3; i++ {
+			if _, err := r.Resolve("ed"); err == nil || errors.Is(err, ErrUnknownKey) {
+				t.Errorf("attempt %d: expected the fetch error, got %v", i, err)
+			}
+		}
+		if down.Load() != 1 {
+			t.Errorf("JWKS fetched %d times during the outage, want 1", down.Load())
+		}
+		r.MinRefresh = 0
+		_, _ = r.Resolve("ed")
+		if down.Load() != 2 {
+			t.Errorf("JWKS fetched %d times after MinRefresh, want 2", down.Load())
+		}
+	})
+
+	t.Run("error: oversized JWKS", func(t *testing.T) {
+		big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"keys":[`))
+			_, _ = w.Write(bytes.Repeat([]byte(" "), 2<<20))
+			_ = json.NewEncoder(w).Encode(set.Keys[0])
+			_, _ = w.Write([]byte(`]}`))
+		}))
+		defer big.Close()
+		if _, err := NewJWKSResolver(big.URL).Resolve("ed"); err == nil || !strings.Contains(err.Error(), "decode jwks") {
+			t.Errorf("expected a decode error, got %v", err)
+		}
+	})
+}
+
+func TestResolveKeyContext(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}, Kid: "device-1"}
+	_ = env.Sign(priv)
+
+	t.Run("error: cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := env.VerifyWithResolverContext(ctx, StaticResolver{"device-1": pub}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("error: JWKS fetch deadline", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer srv.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := env.VerifyWithResolverContext(ctx, NewJWKSResolver(srv.URL)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
@@ -1,33 +1,154 @@
 package sae
 
 import (
-	"time"
+	"bytes"
+	"encoding/json"
+	"errors"
 
 	"vax/pkg/vax/jcs"
 )
 
 type Envelope struct {
-	ActionType string         `json:"action_type"`
-	Timestamp  int64          `json:"timestamp"`
-	SDTO       map[string]any `json:"sdto"`
+	ActionType    string         `json:"action_type"`
+	Timestamp     int64          `json:"timestamp"`
+	SDTO          map[string]any `json:"sdto"`
+	SchemaVersion string         `json:"schema_version,omitempty"`
+	SchemaFP      string         `json:"schema_fingerprint,omitempty"` // hex SHA256 of the JCS schema
+	Encrypted     *Encrypted     `json:"encrypted,omitempty"`
+	Attachments   []Attachment   `json:"attachments,omitempty"`
+	Meta          *Metadata      `json:"meta,omitempty"`
+	Counter       uint64         `json:"counter,omitempty"`
+	PrevSAI       string         `json:"prev_sai,omitempty"` // hex
+	Nonce         string         `json:"nonce,omitempty"`
+	NotBefore     int64          `json:"not_before,omitempty"` // unix ms
+	ExpiresAt     int64          `json:"expires_at,omitempty"` // unix ms
+	Alg           string         `json:"alg,omitempty"`
+	Kid           string         `json:"kid,omitempty"`
+	Signature     []byte         `json:"signature,omitempty"`
+}
+
+// Metadata carries deployment-specific context about who produced the action.
+type Metadata struct {
+	Actor   string `json:"actor,omitempty"`
+	Device  string `json:"device,omitempty"`
+	Session string `json:"session,omitempty"`
 }
 
 // BuildSAE builds a Semantic Action Envelope using the project's JCS canonicalizer.
-func BuildSAE(actionType string, sdto map[string]any) ([]byte, error) {
-	env := Envelope{
-		ActionType: actionType,
-		Timestamp:  time.Now().UnixMilli(),
-		SDTO:       sdto,
+// The sdto and the result must be within Limits.
+// The result is logged with WithLogger.
+func BuildSAE(actionType string, sdto map[string]any, opts ...Option) ([]byte, error) {
+	cfg := newBuildConfig(opts)
+	if err := Limits.CheckSDTO(sdto); err != nil {
+		logBuild(cfg.logger, actionType, cfg.counter, nil, err)
+		return nil, err
 	}
+	if cfg.validate != nil {
+		if err := cfg.validate(sdto); err != nil {
+			logBuild(cfg.logger, actionType, cfg.counter, nil, err)
+			return nil, err
+		}
+	}
+	env := NewEnvelope(actionType, sdto, opts...)
 
 	// IMPORTANT:
 	// We do NOT use json.Marshal()
 	// We MUST ONLY use our own JCS canonicalizer.
 	canonical, err := jcs.Marshal(env)
+	if err == nil {
+		err = Limits.CheckSize(len(canonical))
+	}
+	logBuild(cfg.logger, actionType, cfg.counter, canonical, err)
 	if err != nil {
 		return nil, err
 	}
 	return canonical, nil
 }
 
+// NewEnvelope builds the Envelope value BuildSAE would canonicalize,
+// for callers that sign or inspect it before marshaling.
+// WithValidator is not applied here.
+func NewEnvelope(actionType string, sdto map[string]any, opts ...Option) *Envelope {
+	cfg := newBuildConfig(opts)
+
+	return &Envelope{
+		ActionType:    actionType,
+		Timestamp:     cfg.timestamp().UnixMilli(),
+		SDTO:          sdto,
+		SchemaVersion: cfg.schemaVersion,
+		SchemaFP:      cfg.schemaFP,
+		Meta:          cfg.meta,
+		Counter:       cfg.counter,
+		PrevSAI:       cfg.prevSAI,
+		Nonce:         cfg.nonce,
+		Attachments:   cfg.attachments,
+	}
+}
+
+// Parse decodes SAE bytes into an Envelope.
+// Numbers are kept as json.Number so re-canonicalization (Verify, SAI)
+// reproduces the exact bytes the client produced. Compressed transport
+// forms (see Compress) are decompressed first. Envelopes beyond Limits
+// are rejected before they are decoded, and encrypted envelopes that also
+// carry an sdto with ErrMixedPayload.
+func Parse(saeBytes []byte) (*Envelope, error) {
+	var env Envelope
+
+	if err := Limits.CheckSize(len(saeBytes)); err != nil {
+		return nil, err
+	}
+	saeBytes, err := Decompress(saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := Limits.CheckSize(len(saeBytes)); err != nil {
+		return nil, err
+	}
 
+	dec := json.NewDecoder(bytes.NewReader(saeBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&env); err != nil {
+		return nil, err
+	}
+	if env.Encrypted != nil && env.SDTO != nil {
+		return nil, ErrMixedPayload
+	}
+	if err := Limits.CheckSDTO(env.SDTO); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// ErrPayloadNotObject is returned by Build when T does not encode to a JSON object.
+var ErrPayloadNotObject = errors.New("payload is not a JSON object")
+
+// Build is the typed counterpart of BuildSAE: payload (usually a struct with
+// json tags) is turned into the sdto through the jcs reflection path, so
+// field names come from the type instead of hand-written map keys.
+func Build[T any](actionType string, payload T, opts ...Option) ([]byte, error) {
+	sdto, err := toSDTO(payload)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSAE(actionType, sdto, opts...)
+}
+
+func toSDTO(payload any) (map[string]any, error) {
+	canonical, err := jcs.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(canonical))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, ErrPayloadNotObject
+	}
+	return m, nil
+}
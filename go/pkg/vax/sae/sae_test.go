@@ -2,7 +2,9 @@ package sae
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestBuildSAE(t *testing.T) {
@@ -75,3 +77,83 @@ func BenchmarkBuildSAE(b *testing.B) {
 		_, _ = BuildSAE("transfer", sdto)
 	}
 }
+
+func TestBuildSAEOptions(t *testing.T) {
+	pinned := time.UnixMilli(1704672000000)
+
+	t.Run("pinned timestamp is deterministic", func(t *testing.T) {
+		sae1, _ := BuildSAE("test", map[string]any{"key": "value"}, WithTimestamp(pinned))
+		sae2, _ := BuildSAE("test", map[string]any{"key": "value"}, WithTimestamp(pinned))
+		if string(sae1) != string(sae2) {
+			t.Errorf("outputs differ:\n%s\n%s", sae1, sae2)
+		}
+
+		want := `{"action_type":"test","sdto":{"key":"value"},"timestamp":1704672000000}`
+		if string(sae1) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", sae1, want)
+		}
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		env := NewEnvelope("test", map[string]any{}, WithClock(func() time.Time { return pinned }))
+		if env.Timestamp != pinned.UnixMilli() {
+			t.Errorf("timestamp = %d, want %d", env.Timestamp, pinned.UnixMilli())
+		}
+	})
+
+	t.Run("nonce and metadata", func(t *testing.T) {
+		saeBytes, err := BuildSAE("test", map[string]any{},
+			WithTimestamp(pinned),
+			WithNonce("n-1"),
+			WithMetadata("alice", "laptop", ""),
+		)
+		if err != nil {
+			t.Fatalf("BuildSAE failed: %v", err)
+		}
+
+		want := `{"action_type":"test","meta":{"actor":"alice","device":"laptop"},"nonce":"n-1","sdto":{},"timestamp":1704672000000}`
+		if string(saeBytes) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", saeBytes, want)
+		}
+	})
+}
+
+type transferDTO struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int64  `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+func TestBuildTyped(t *testing.T) {
+	pinned := WithTimestamp(time.UnixMilli(1704672000000))
+
+	t.Run("matches map form", func(t *testing.T) {
+		typed, err := Build("transfer", transferDTO{From: "alice", To: "bob", Amount: 9007199254740993}, pinned)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		want := `{"action_type":"transfer","sdto":{"amount":9007199254740993,"from":"alice","to":"bob"},"timestamp":1704672000000}`
+		if string(typed) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", typed, want)
+		}
+	})
+
+	t.Run("validator", func(t *testing.T) {
+		reject := WithValidator(func(m map[string]any) error {
+			if _, ok := m["memo"]; !ok {
+				return errors.New("memo required")
+			}
+			return nil
+		})
+		if _, err := Build("transfer", transferDTO{From: "a", To: "b"}, reject); err == nil {
+			t.Error("expected validator error")
+		}
+	})
+
+	t.Run("error: not an object", func(t *testing.T) {
+		if _, err := Build("transfer", []int{1, 2}); err != ErrPayloadNotObject {
+			t.Errorf("expected ErrPayloadNotObject, got %v", err)
+		}
+	})
+}
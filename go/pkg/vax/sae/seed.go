@@ -0,0 +1,143 @@
+package sae
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrInvalidPath     = errors.New("invalid derivation path")
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+)
+
+const hardenedOffset = 0x80000000
+
+// KeyFromSeed deterministically derives an Ed25519 signing key from a seed
+// following SLIP-0010. path is "m" or "m/44'/0'/0'"; Ed25519 derivation only
+// supports hardened indexes (suffix ' or h).
+//
+// A device can regenerate its key from a backed-up seed (see SeedFromMnemonic)
+// instead of exporting the raw private key.
+func KeyFromSeed(seed []byte, path string) (ed25519.PrivateKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidKey
+	}
+	indexes, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+
+	for _, index := range indexes {
+		var data [37]byte // 0x00 || key || ser32(index)
+		copy(data[1:33], key)
+		binary.BigEndian.PutUint32(data[33:], index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data[:])
+		sum := mac.Sum(nil)
+		key, chain = sum[:32], sum[32:]
+	}
+
+	return ed25519.NewKeyFromSeed(key), nil
+}
+
+func parsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		trimmed := strings.TrimRight(p, "'hH")
+		if len(trimmed) != len(p)-1 {
+			return nil, ErrInvalidPath // non-hardened or malformed
+		}
+		n, err := strconv.ParseUint(trimmed, 10, 31)
+		if err != nil {
+			return nil, ErrInvalidPath
+		}
+		indexes = append(indexes, uint32(n)+hardenedOffset)
+	}
+	return indexes, nil
+}
+
+// SeedFromMnemonic turns a BIP39 mnemonic (and optional passphrase) into a
+// 64-byte seed: PBKDF2-HMAC-SHA512(mnemonic, "mnemonic"+passphrase, 2048).
+//
+// Every word must be in the BIP39 English wordlist and the mnemonic's
+// checksum bits must match its entropy, so a mistyped backup fails with
+// ErrInvalidMnemonic instead of silently deriving a different key. Only
+// ASCII input is accepted because NFKD normalization is not available in
+// the standard library.
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, ErrInvalidMnemonic
+	}
+	normalized := strings.Join(words, " ")
+	if !isASCII(normalized) || !isASCII(passphrase) {
+		return nil, ErrInvalidMnemonic
+	}
+	if err := checkMnemonic(words); err != nil {
+		return nil, err
+	}
+
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), 2048, 64)
+}
+
+// checkMnemonic 檢查每個字都在 BIP39 英文單字表中，且 checksum（SHA-256(entropy) 前 ENT/32 bits）相符
+func checkMnemonic(words []string) error {
+	index := bip39Index()
+	// 每字 11 bits：entropy || checksum
+	bits := make([]byte, 0, len(words)*11)
+	for i, w := range words {
+		n, ok := index[w]
+		if !ok {
+			// 不回報單字本身，避免助記詞進入 log
+			return fmt.Errorf("%w: word %d is not in the BIP39 English wordlist", ErrInvalidMnemonic, i+1)
+		}
+		for b := 10; b >= 0; b-- {
+			bits = append(bits, byte(n>>b)&1)
+		}
+	}
+	csBits := len(bits) / 33
+	entropy := make([]byte, (len(bits)-csBits)/8)
+	for i := range entropy {
+		for _, bit := range bits[i*8 : i*8+8] {
+			entropy[i] = entropy[i]<<1 | bit
+		}
+	}
+	sum := sha256.Sum256(entropy)
+	for i, bit := range bits[len(entropy)*8:] {
+		if sum[0]>>(7-i)&1 != bit {
+			return fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+		}
+	}
+	return nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
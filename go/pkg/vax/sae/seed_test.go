@@ -0,0 +1,97 @@
+package sae
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestKeyFromSeed(t *testing.T) {
+	// SLIP-0010 test vector 1 for ed25519
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	cases := []struct {
+		path string
+		want string // private key (seed half)
+	}{
+		{"m", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{"m/0'", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{"m/0h/1H", "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2"},
+	}
+	for _, tc := range cases {
+		key, err := KeyFromSeed(seed, tc.path)
+		if err != nil {
+			t.Fatalf("KeyFromSeed(%q) failed: %v", tc.path, err)
+		}
+		if got := hex.EncodeToString(key.Seed()); got != tc.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", tc.path, got, tc.want)
+		}
+	}
+
+	t.Run("error: non-hardened path", func(t *testing.T) {
+		for _, p := range []string{"m/0", "44'/0'", "m/x'", ""} {
+			if _, err := KeyFromSeed(seed, p); err != ErrInvalidPath {
+				t.Errorf("KeyFromSeed(%q): expected ErrInvalidPath, got %v", p, err)
+			}
+		}
+	})
+}
+
+func TestSeedFromMnemonic(t *testing.T) {
+	// BIP39 reference vector (passphrase "TREZOR")
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+
+	seed, err := SeedFromMnemonic(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatalf("SeedFromMnemonic failed: %v", err)
+	}
+	if got := hex.EncodeToString(seed); got != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+
+	// Same phrase, same key
+	k1, _ := KeyFromSeed(seed, "m/44'/0'")
+	k2, _ := KeyFromSeed(seed, "m/44'/0'")
+	if !k1.Equal(k2) {
+		t.Error("derivation should be deterministic")
+	}
+
+	t.Run("BIP39 vectors", func(t *testing.T) {
+		vectors := map[string]string{
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong": "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless": "c0c519bd0e91a2ed54357d9d1ebef6f5af218a153624cf4f2da911a0ed8f7a09e2ef61af0aca007096df430022f7a2b6fb91661a9589097069720d015e4e982f",
+		}
+		for mnemonic, want := range vectors {
+			seed, err := SeedFromMnemonic(mnemonic, "TREZOR")
+			if err != nil || hex.EncodeToString(seed) != want {
+				t.Errorf("%q: %x, %v", mnemonic, seed, err)
+			}
+		}
+	})
+
+	t.Run("wordlist", func(t *testing.T) {
+		sum := sha256.Sum256([]byte(bip39English + "\n"))
+		if got := hex.EncodeToString(sum[:]); got != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+			t.Errorf("wordlist SHA-256 = %s", got)
+		}
+		if len(bip39Index()) != 2048 {
+			t.Errorf("%d distinct words", len(bip39Index()))
+		}
+	})
+
+	for name, mnemonic := range map[string]string{
+		"too few words":  "abandon about",
+		"mistyped word":  "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandn about",
+		"wrong checksum": "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"swapped words":  "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about abandon",
+		"uppercase word": "Abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	} {
+		t.Run("error: "+name, func(t *testing.T) {
+			if _, err := SeedFromMnemonic(mnemonic, ""); !errors.Is(err, ErrInvalidMnemonic) {
+				t.Errorf("expected ErrInvalidMnemonic, got %v", err)
+			}
+		})
+	}
+}
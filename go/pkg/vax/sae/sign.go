@@ -0,0 +1,243 @@
+package sae
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+
+	"vax/pkg/vax/jcs"
+)
+
+// Error codes
+var (
+	ErrNotSigned        = errors.New("envelope not signed")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidKey       = errors.New("invalid key")
+	ErrUnsupportedAlg   = errors.New("unsupported signature algorithm")
+)
+
+// Signature algorithms (values of Envelope.Alg).
+// Names match sdto.SupportedSignTypes.
+const (
+	AlgEd25519 = "ed25519" // Ed25519 over the signing bytes
+	AlgECDSA   = "ecdsa"   // ECDSA P-256 over SHA256(signing bytes), ASN.1 DER
+	AlgRSA     = "rsa"     // RSA-PSS over SHA256(signing bytes), salt length = 32
+)
+
+// MinRSABits is the smallest RSA modulus accepted for signing and verification.
+const MinRSABits = 2048
+
+var pssOptions = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+
+// GenerateKeyPair generates an Ed25519 key pair for signing envelopes.
+func GenerateKeyPair() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// SigningContext domain-separates SAE signatures: the signed message is
+// SigningContext || 0x00 || UnsignedBytes(), so a key that also signs other
+// canonical JSON documents can never produce a signature valid as an SAE.
+const SigningContext = "VAX-SAE-v1"
+
+// VerifyOption configures Verify and VerifyWithResolver.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	legacy bool
+}
+
+// WithLegacySignatures also accepts signatures over the bare
+// UnsignedBytes() (no SigningContext prefix), as produced before the
+// context was introduced. Migration aid only: pass it from the code that
+// re-verifies old envelopes, never on the submission path.
+func WithLegacySignatures() VerifyOption {
+	return func(c *verifyConfig) {
+		c.legacy = true
+	}
+}
+
+// UnsignedBytes returns the canonical unsigned form of the envelope.
+//
+// Rule: the signature covers the JCS canonical envelope with the
+// "signature" member removed. Every other member is signed, including
+// "alg", so the algorithm cannot be swapped after signing.
+func (e *Envelope) UnsignedBytes() ([]byte, error) {
+	unsigned := *e
+	unsigned.Signature = nil // omitempty drops the member entirely
+	return jcs.Marshal(unsigned)
+}
+
+// SigningBytes returns the exact message passed to the signature algorithm:
+// SigningContext || 0x00 || UnsignedBytes().
+func (e *Envelope) SigningBytes() ([]byte, error) {
+	unsigned, err := e.UnsignedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return withSigningContext(unsigned), nil
+}
+
+func withSigningContext(unsigned []byte) []byte {
+	msg := make([]byte, 0, len(SigningContext)+1+len(unsigned))
+	msg = append(msg, SigningContext...)
+	msg = append(msg, 0x00)
+	return append(msg, unsigned...)
+}
+
+// Sign signs the envelope with an Ed25519 private key and stores the signature.
+func (e *Envelope) Sign(privateKey ed25519.PrivateKey) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return ErrInvalidKey
+	}
+	return e.SignWith(privateKey, crypto.Hash(0))
+}
+
+// SignWith signs the envelope with any crypto.Signer (HSM, KMS, smart card...).
+//
+// The algorithm is picked from signer.Public(): Ed25519, ECDSA P-256 or RSA
+// (always PSS). A nil opts selects the algorithm's default; explicit opts
+// must agree with it (crypto.Hash(0) for Ed25519, SHA256 otherwise,
+// *rsa.PSSOptions with SHA256 and a salt length equal to the hash for RSA,
+// since that is the only PSS form Verify accepts).
+func (e *Envelope) SignWith(signer crypto.Signer, opts crypto.SignerOpts) error {
+	if signer == nil {
+		return ErrInvalidKey
+	}
+
+	alg, err := AlgForKey(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	switch alg {
+	case AlgEd25519:
+		if opts == nil {
+			opts = crypto.Hash(0)
+		}
+		if opts.HashFunc() != 0 {
+			return ErrUnsupportedAlg
+		}
+	case AlgECDSA:
+		if opts == nil {
+			opts = crypto.SHA256
+		}
+		if opts.HashFunc() != crypto.SHA256 {
+			return ErrUnsupportedAlg
+		}
+	case AlgRSA:
+		if opts == nil {
+			opts = pssOptions
+		}
+		pss, ok := opts.(*rsa.PSSOptions)
+		if !ok || pss.HashFunc() != crypto.SHA256 || !pssSaltOK(pss.SaltLength) {
+			return ErrUnsupportedAlg
+		}
+		// 驗證端固定使用 pssOptions，簽名也一律用它
+		opts = pssOptions
+	}
+
+	e.Alg = alg
+	msg, err := e.SigningBytes()
+	if err != nil {
+		return err
+	}
+
+	digest := msg
+	if opts.HashFunc() != 0 {
+		sum := sha256.Sum256(msg)
+		digest = sum[:]
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return err
+	}
+	e.Signature = sig
+	return nil
+}
+
+// Verify checks the envelope signature against a public key.
+// The key type must match Envelope.Alg; an empty Alg means Ed25519.
+func (e *Envelope) Verify(publicKey crypto.PublicKey, opts ...VerifyOption) error {
+	var cfg verifyConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(e.Signature) == 0 {
+		return ErrNotSigned
+	}
+
+	alg := e.Alg
+	if alg == "" {
+		alg = AlgEd25519
+	}
+	keyAlg, err := AlgForKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if keyAlg != alg {
+		return ErrInvalidKey
+	}
+
+	unsigned, err := e.UnsignedBytes()
+	if err != nil {
+		return err
+	}
+
+	if verifySignature(publicKey, withSigningContext(unsigned), e.Signature) {
+		return nil
+	}
+	if cfg.legacy && verifySignature(publicKey, unsigned, e.Signature) {
+		return nil
+	}
+	return ErrInvalidSignature
+}
+
+// pssSaltOK 只接受與 pssOptions 相同的 salt 長度（= hash 長度）
+func pssSaltOK(n int) bool {
+	return n == rsa.PSSSaltLengthEqualsHash || n == sha256.Size
+}
+
+func verifySignature(publicKey crypto.PublicKey, msg, sig []byte) bool {
+	switch pub := publicKey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, msg, sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(msg)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(msg)
+		return rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, pssOptions) == nil
+	default:
+		return false
+	}
+}
+
+// AlgForKey maps a public key to the envelope algorithm SignWith would
+// use for it, failing with ErrUnsupportedAlg or ErrInvalidKey.
+func AlgForKey(publicKey crypto.PublicKey) (string, error) {
+	switch pub := publicKey.(type) {
+	case ed25519.PublicKey:
+		if len(pub) != ed25519.PublicKeySize {
+			return "", ErrInvalidKey
+		}
+		return AlgEd25519, nil
+	case *ecdsa.PublicKey:
+		if pub == nil || pub.Curve != elliptic.P256() {
+			return "", ErrUnsupportedAlg
+		}
+		return AlgECDSA, nil
+	case *rsa.PublicKey:
+		if pub == nil || pub.N.BitLen() < MinRSABits {
+			return "", ErrInvalidKey
+		}
+		return AlgRSA, nil
+	default:
+		return "", ErrUnsupportedAlg
+	}
+}
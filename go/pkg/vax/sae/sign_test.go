@@ -0,0 +1,256 @@
+package sae
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"vax/pkg/vax/jcs"
+)
+
+func TestEnvelopeSignVerify(t *testing.T) {
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	newEnv := func() *Envelope {
+		return &Envelope{
+			ActionType: "transfer",
+			Timestamp:  1704672000000,
+			SDTO:       map[string]any{"name": "alice", "amount": 100},
+		}
+	}
+
+	t.Run("basic", func(t *testing.T) {
+		env := newEnv()
+		if err := env.Sign(priv); err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		if err := env.Verify(pub); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+	})
+
+	t.Run("round trip through canonical bytes", func(t *testing.T) {
+		env := newEnv()
+		env.SDTO["id"] = int64(9007199254740993) // > 2^53
+		_ = env.Sign(priv)
+
+		saeBytes, err := jcs.Marshal(env)
+		if err != nil {
+			t.Fatalf("jcs.Marshal failed: %v", err)
+		}
+		parsed, err := Parse(saeBytes)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if err := parsed.Verify(pub); err != nil {
+			t.Errorf("Verify after Parse failed: %v", err)
+		}
+	})
+
+	t.Run("unsigned form excludes signature", func(t *testing.T) {
+		env := newEnv()
+		_ = env.Sign(priv)
+		msg, _ := env.UnsignedBytes()
+		want := `{"action_type":"transfer","alg":"ed25519","sdto":{"amount":100,"name":"alice"},"timestamp":1704672000000}`
+		if string(msg) != want {
+			t.Errorf("UnsignedBytes:\ngot:  %s\nwant: %s", msg, want)
+		}
+	})
+
+	t.Run("signing input is domain separated", func(t *testing.T) {
+		env := newEnv()
+		_ = env.Sign(priv)
+		unsigned, _ := env.UnsignedBytes()
+		msg, _ := env.SigningBytes()
+		want := append([]byte(SigningContext+"\x00"), unsigned...)
+		if !bytes.Equal(msg, want) {
+			t.Errorf("SigningBytes = %q, want %q", msg, want)
+		}
+	})
+
+	t.Run("legacy signature needs WithLegacySignatures", func(t *testing.T) {
+		env := newEnv()
+		env.Alg, env.Kid = AlgEd25519, "k1"
+		unsigned, _ := env.UnsignedBytes()
+		env.Signature = ed25519.Sign(priv, unsigned)
+
+		if err := env.Verify(pub); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+
+		if err := env.Verify(pub, WithLegacySignatures()); err != nil {
+			t.Errorf("legacy Verify failed: %v", err)
+		}
+		if err := env.VerifyWithResolver(StaticResolver{"k1": pub}, WithLegacySignatures()); err != nil {
+			t.Errorf("legacy VerifyWithResolver failed: %v", err)
+		}
+	})
+
+	t.Run("error: tampered sdto", func(t *testing.T) {
+		env := newEnv()
+		_ = env.Sign(priv)
+		env.SDTO["amount"] = 101
+		if err := env.Verify(pub); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("error: wrong key", func(t *testing.T) {
+		other, _, _ := GenerateKeyPair()
+		env := newEnv()
+		_ = env.Sign(priv)
+		if err := env.Verify(other); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("error: not signed", func(t *testing.T) {
+		if err := newEnv().Verify(pub); err != ErrNotSigned {
+			t.Errorf("expected ErrNotSigned, got %v", err)
+		}
+	})
+
+	t.Run("error: invalid key length", func(t *testing.T) {
+		if err := newEnv().Sign(priv[:10]); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+}
+
+// opaqueSigner hides the concrete key type, like an HSM or KMS handle.
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestEnvelopeSignWith(t *testing.T) {
+	newEnv := func() *Envelope {
+		return &Envelope{
+			ActionType: "transfer",
+			Timestamp:  1704672000000,
+			SDTO:       map[string]any{"amount": 100},
+		}
+	}
+
+	t.Run("ed25519 signer", func(t *testing.T) {
+		pub, priv, _ := GenerateKeyPair()
+		env := newEnv()
+		if err := env.SignWith(opaqueSigner{priv}, nil); err != nil {
+			t.Fatalf("SignWith failed: %v", err)
+		}
+		if err := env.Verify(pub); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+	})
+
+	t.Run("ecdsa signer hashes first", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		env := newEnv()
+		if err := env.SignWith(opaqueSigner{key}, crypto.SHA256); err != nil {
+			t.Fatalf("SignWith failed: %v", err)
+		}
+
+		msg, _ := env.SigningBytes()
+		digest := sha256.Sum256(msg)
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], env.Signature) {
+			t.Error("ECDSA signature does not verify over SHA256(SigningBytes)")
+		}
+	})
+
+	t.Run("error: nil signer", func(t *testing.T) {
+		if err := newEnv().SignWith(nil, nil); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+}
+
+func TestEnvelopeAlgorithms(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	edPub, edPriv, _ := GenerateKeyPair()
+
+	cases := []struct {
+		name   string
+		signer crypto.Signer
+		pub    crypto.PublicKey
+		alg    string
+	}{
+		{"ed25519", edPriv, edPub, AlgEd25519},
+		{"ecdsa p-256", ecKey, &ecKey.PublicKey, AlgECDSA},
+		{"rsa-pss", rsaKey, &rsaKey.PublicKey, AlgRSA},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{"a": 1}}
+			if err := env.SignWith(tc.signer, nil); err != nil {
+				t.Fatalf("SignWith failed: %v", err)
+			}
+			if env.Alg != tc.alg {
+				t.Errorf("Alg = %q, want %q", env.Alg, tc.alg)
+			}
+			if err := env.Verify(tc.pub); err != nil {
+				t.Errorf("Verify failed: %v", err)
+			}
+		})
+	}
+
+	t.Run("error: key does not match alg", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		_ = env.SignWith(ecKey, nil)
+		if err := env.Verify(edPub); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("error: alg swapped after signing", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		_ = env.SignWith(ecKey, nil)
+		env.Alg = AlgEd25519
+		if err := env.Verify(&ecKey.PublicKey); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("error: rsa pkcs1v15 opts", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		if err := env.SignWith(rsaKey, crypto.SHA256); err != ErrUnsupportedAlg {
+			t.Errorf("expected ErrUnsupportedAlg, got %v", err)
+		}
+	})
+
+	t.Run("rsa explicit PSS opts", func(t *testing.T) {
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		if err := env.SignWith(rsaKey, &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}); err != nil {
+			t.Fatalf("SignWith failed: %v", err)
+		}
+		if err := env.Verify(&rsaKey.PublicKey); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+	})
+
+	t.Run("error: rsa PSS salt length Verify cannot check", func(t *testing.T) {
+		for _, salt := range []int{rsa.PSSSaltLengthAuto, 20} {
+			env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+			if err := env.SignWith(rsaKey, &rsa.PSSOptions{SaltLength: salt, Hash: crypto.SHA256}); err != ErrUnsupportedAlg {
+				t.Errorf("salt %d: expected ErrUnsupportedAlg, got %v", salt, err)
+			}
+		}
+	})
+
+	t.Run("error: unsupported curve", func(t *testing.T) {
+		p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		env := &Envelope{ActionType: "transfer", Timestamp: 1, SDTO: map[string]any{}}
+		if err := env.SignWith(p384, nil); err != ErrUnsupportedAlg {
+			t.Errorf("expected ErrUnsupportedAlg, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package sae
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error codes
+var (
+	ErrClockSkew           = errors.New("envelope timestamp outside allowed clock skew")
+	ErrTimestampRegression = errors.New("envelope timestamp earlier than previous action")
+)
+
+// TimePolicy is a verification-time policy for Envelope.Timestamp. The
+// zero value accepts every timestamp.
+type TimePolicy struct {
+	// MaxSkew rejects timestamps further than this from the verifier's
+	// clock, in either direction (0 disables the check).
+	MaxSkew time.Duration
+	// Monotonic rejects timestamps earlier than the previous action's in
+	// the same chain; equal timestamps are allowed.
+	Monotonic bool
+	// Now is the verifier's clock (time.Now when nil).
+	Now func() time.Time
+}
+
+// CheckTimestamp applies p to env. prev is the timestamp (unix ms) of the
+// previous action in env's chain; 0 means unknown (genesis, or a store that
+// does not keep it) and skips the monotonicity check.
+//
+// Like CheckReplay, this only means something for signed envelopes.
+func (p TimePolicy) CheckTimestamp(env *Envelope, prev int64) error {
+	if p.MaxSkew > 0 {
+		now := time.Now
+		if p.Now != nil {
+			now = p.Now
+		}
+		skew := time.Duration(env.Timestamp-now().UnixMilli()) * time.Millisecond
+		if skew > p.MaxSkew || -skew > p.MaxSkew {
+			return fmt.Errorf("%w: %v from server time, max %v", ErrClockSkew, skew, p.MaxSkew)
+		}
+	}
+	if p.Monotonic && prev != 0 && env.Timestamp < prev {
+		return fmt.Errorf("%w: %d < %d", ErrTimestampRegression, env.Timestamp, prev)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sae
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimePolicy(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	p := TimePolicy{MaxSkew: time.Minute, Monotonic: true, Now: func() time.Time { return now }}
+	env := func(ts time.Time) *Envelope { return &Envelope{Timestamp: ts.UnixMilli()} }
+
+	t.Run("within skew and in order", func(t *testing.T) {
+		for _, ts := range []time.Time{now, now.Add(-time.Minute), now.Add(59 * time.Second)} {
+			if err := p.CheckTimestamp(env(ts), now.Add(-time.Hour).UnixMilli()); err != nil {
+				t.Errorf("%v: %v", ts, err)
+			}
+		}
+		if err := p.CheckTimestamp(env(now), now.UnixMilli()); err != nil {
+			t.Errorf("equal timestamps: %v", err)
+		}
+		if err := (TimePolicy{}).CheckTimestamp(env(time.UnixMilli(1)), now.UnixMilli()); err != nil {
+			t.Errorf("zero policy: %v", err)
+		}
+	})
+
+	t.Run("error: skew", func(t *testing.T) {
+		for _, ts := range []time.Time{now.Add(-2 * time.Minute), now.Add(time.Hour)} {
+			if err := p.CheckTimestamp(env(ts), 0); !errors.Is(err, ErrClockSkew) {
+				t.Errorf("%v: expected ErrClockSkew, got %v", ts, err)
+			}
+		}
+	})
+
+	t.Run("error: backdated within the chain", func(t *testing.T) {
+		if err := p.CheckTimestamp(env(now.Add(-time.Second)), now.UnixMilli()); !errors.Is(err, ErrTimestampRegression) {
+			t.Errorf("expected ErrTimestampRegression, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"reflect"
+	"sync"
+)
+
+// cacheKey 只快取內建命名策略的結果；自訂 namer 是任意函式，無法判斷兩次呼叫是否等價
+type cacheKey struct {
+	t         reflect.Type
+	strict    bool
+	qualified bool
+	draft     string
+}
+
+var cache sync.Map // cacheKey → map[string]any（不外流，回傳前複製）
+
+func (c *config) cacheKey(t reflect.Type) (cacheKey, bool) {
+	namer := reflect.ValueOf(c.defName).Pointer()
+	switch namer {
+	case reflect.ValueOf(TypeName).Pointer():
+		return cacheKey{t: t, strict: c.strict, draft: c.draft}, true
+	case reflect.ValueOf(QualifiedTypeName).Pointer():
+		return cacheKey{t: t, strict: c.strict, qualified: true, draft: c.draft}, true
+	default:
+		return cacheKey{}, false
+	}
+}
+
+// resetCache 在 Register* 修改全域表之後呼叫，讓已快取的 schema 重新產生
+func resetCache() {
+	cache.Clear()
+}
@@ -0,0 +1,24 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	a, _ := Generate[UpdateProfileDTO]()
+	a["properties"] = nil // 呼叫端修改不能影響快取
+	b, _ := Generate[UpdateProfileDTO]()
+	if b["properties"] == nil {
+		t.Fatal("cached schema was mutated through a returned copy")
+	}
+
+	if _, ok := newConfig([]Option{WithDefinitionNamer(func(t reflect.Type) string { return "x" })}).cacheKey(reflect.TypeFor[Node]()); ok {
+		t.Error("custom namer must bypass the cache")
+	}
+	strict, _ := newConfig([]Option{WithStrict()}).cacheKey(reflect.TypeFor[Node]())
+	loose, _ := newConfig(nil).cacheKey(reflect.TypeFor[Node]())
+	if strict == loose {
+		t.Error("options must be part of the cache key")
+	}
+}
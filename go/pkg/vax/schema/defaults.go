@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// applyValueTags 處理 default:"…" 與 example:"…"：依欄位型別轉成 JSON 值
+func applyValueTags(s map[string]any, f field) error {
+	if tag, ok := f.Tag.Lookup("default"); ok {
+		v, err := tagValue(tag, f.Type)
+		if err != nil {
+			return fmt.Errorf("default %q: %w", tag, err)
+		}
+		s["default"] = v
+	}
+	if tag, ok := f.Tag.Lookup("example"); ok {
+		v, err := tagValue(tag, f.Type)
+		if err != nil {
+			return fmt.Errorf("example %q: %w", tag, err)
+		}
+		s["examples"] = []any{v}
+	}
+	return nil
+}
+
+// tagValue 把 struct tag 的文字轉成欄位型別對應的 JSON 值：
+//   - 以字串傳輸的型別（string、[]byte、time.Time、net.IP、TextMarshaler、對應為 string 的型別）直接取文字
+//   - time.Duration 可寫 "1m30s" 或奈秒整數
+//   - 其餘（數字、布林、slice、map、struct）以 JSON 解析，例如 default:"[1,2]"
+//
+// 結果先解碼回欄位型別確認合法，數字保留原字面值（json.Number）。
+func tagValue(tag string, t reflect.Type) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var raw []byte
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		if d, err := time.ParseDuration(tag); err == nil {
+			raw = []byte(strconv.FormatInt(int64(d), 10))
+		} else {
+			raw = []byte(tag)
+		}
+	case stringLike(t):
+		raw, _ = json.Marshal(tag)
+	default:
+		raw = []byte(tag)
+	}
+
+	if checkable(t) {
+		if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+			return nil, fmt.Errorf("not a valid %s: %w", t, err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("not valid JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+func stringLike(t reflect.Type) bool {
+	if m, ok := lookupTypeMapping(t); ok {
+		return m["type"] == "string"
+	}
+	if t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) {
+		return true
+	}
+	return reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) ||
+		t == reflect.TypeFor[url.URL]()
+}
+
+// checkable 第三方對應型別與 url.URL 無法用 encoding/json 解碼驗證
+func checkable(t reflect.Type) bool {
+	if _, ok := lookupTypeMapping(t); ok {
+		return false
+	}
+	return t != reflect.TypeFor[url.URL]() && t.Kind() != reflect.Interface
+}
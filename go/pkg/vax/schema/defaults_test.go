@@ -0,0 +1,87 @@
+package schema
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/sdto"
+)
+
+func TestDefaultAndExample(t *testing.T) {
+	type Form struct {
+		Name     string         `json:"name" example:"Alice"`
+		Age      int            `json:"age" default:"18" example:"42"`
+		Ratio    float64        `json:"ratio" default:"0.25"`
+		Active   bool           `json:"active" default:"true"`
+		Tags     []string       `json:"tags,omitempty" default:"[\"a\",\"b\"]"`
+		Labels   map[string]int `json:"labels,omitempty" example:"{\"x\":1}"`
+		Timeout  time.Duration  `json:"timeout" default:"1m30s"`
+		Since    time.Time      `json:"since" example:"2026-01-02T03:04:05Z"`
+		Client   net.IP         `json:"client" example:"10.0.0.1"`
+		Status   Status         `json:"status" default:"pending"`
+		Priority Priority       `json:"priority" default:"2"`
+		Big      int64          `json:"big" example:"9007199254740993"`
+	}
+	s, err := Generate[Form]()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	props := s["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"name":     `{"examples":["Alice"],"type":"string"}`,
+		"age":      `{"default":18,"examples":[42],"type":"integer"}`,
+		"ratio":    `{"default":0.25,"type":"number"}`,
+		"active":   `{"default":true,"type":"boolean"}`,
+		"tags":     `{"default":["a","b"],"items":{"type":"string"},"type":"array"}`,
+		"labels":   `{"additionalProperties":{"type":"integer"},"examples":[{"x":1}],"type":"object"}`,
+		"timeout":  `{"default":90000000000,"description":"duration in nanoseconds","type":"integer"}`,
+		"since":    `{"examples":["2026-01-02T03:04:05Z"],"format":"date-time","type":"string"}`,
+		"client":   `{"anyOf":[{"format":"ipv4"},{"format":"ipv6"}],"examples":["10.0.0.1"],"type":"string"}`,
+		"status":   `{"default":"pending","enum":["pending","done"],"type":"string"}`,
+		"priority": `{"default":2,"enum":[1,2],"type":"integer"}`,
+		"big":      `{"examples":[9007199254740993],"type":"integer"}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	t.Run("FieldSpec default makes the field optional", func(t *testing.T) {
+		type Settings struct {
+			Theme string `json:"theme,omitempty" default:"light" validate:"oneof=light dark"`
+			Limit int    `json:"limit" default:"10" validate:"min=1,max=100"`
+		}
+		spec, err := GenerateFieldSpec[Settings]()
+		if err != nil {
+			t.Fatalf("GenerateFieldSpec failed: %v", err)
+		}
+		data := map[string]any{}
+		if err := sdto.ValidateData(sdto.ApplyDefaults(data, spec), spec); err != nil {
+			t.Errorf("defaults should satisfy the schema: %v", err)
+		}
+	})
+
+	t.Run("error: default does not fit the field", func(t *testing.T) {
+		cases := map[string]func(...Option) (map[string]any, error){
+			"int": Generate[struct {
+				N int `json:"n" default:"ten"`
+			}],
+			"bool": Generate[struct {
+				B bool `json:"b" default:"yes"`
+			}],
+			"time": Generate[struct {
+				T time.Time `json:"t" example:"yesterday"`
+			}],
+			"slice": Generate[struct {
+				S []int `json:"s" default:"[1,\"x\"]"`
+			}],
+		}
+		for name, gen := range cases {
+			if _, err := gen(); err == nil || !(strings.Contains(err.Error(), "default") || strings.Contains(err.Error(), "example")) {
+				t.Errorf("%s: expected tag error, got %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Enumer is implemented by named string types that know their values:
+//
+//	func (Status) EnumValues() []string { return []string{"pending", "done"} }
+type Enumer interface {
+	EnumValues() []string
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = map[reflect.Type][]any{}
+)
+
+// RegisterEnum records the allowed values of a named type, for types that
+// cannot implement Enumer (or whose values are not strings):
+//
+//	schema.RegisterEnum(StatusPending, StatusDone)
+//
+// Fields of type T (or *T, []T, map[string]T) then carry "enum". Like
+// RegisterFormat it is meant for init time and panics on misuse.
+func RegisterEnum[T comparable](values ...T) {
+	t := reflect.TypeFor[T]()
+	if t.PkgPath() == "" || (t.Kind() != reflect.String && !isNumeric(t.Kind()) && t.Kind() != reflect.Bool) {
+		panic(fmt.Sprintf("schema: RegisterEnum: %s is not a named string, numeric or bool type", t))
+	}
+	if len(values) == 0 {
+		panic(fmt.Sprintf("schema: RegisterEnum: no values for %s", t))
+	}
+	enum := make([]any, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	if _, dup := enums[t]; dup {
+		panic(fmt.Sprintf("schema: RegisterEnum called twice for %s", t))
+	}
+	enums[t] = enum
+	resetCache()
+}
+
+// enumOf 取得型別的 enum：RegisterEnum 優先，其次為 Enumer（value 或 pointer receiver 皆可）
+func enumOf(t reflect.Type) ([]any, bool, error) {
+	enumsMu.RLock()
+	enum, ok := enums[t]
+	enumsMu.RUnlock()
+	if ok {
+		return enum, true, nil
+	}
+
+	if t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface ||
+		!reflect.PointerTo(t).Implements(reflect.TypeFor[Enumer]()) {
+		return nil, false, nil
+	}
+	if t.Kind() != reflect.String {
+		// EnumValues 回傳字串，套在非字串型別上 JSON 型別會不一致
+		return nil, false, fmt.Errorf("%w: %s implements Enumer but is not a string type; use RegisterEnum", ErrUnsupportedType, t)
+	}
+	values := reflect.New(t).Interface().(Enumer).EnumValues()
+	enum = make([]any, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+	return enum, true, nil
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+type Status string
+
+func (Status) EnumValues() []string { return []string{"pending", "done"} }
+
+type Region string
+
+func (*Region) EnumValues() []string { return []string{"eu", "us"} }
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota + 1
+	PriorityHigh
+)
+
+type Color string
+
+type Level int
+
+func (Level) EnumValues() []string { return []string{"debug"} }
+
+func init() {
+	RegisterEnum(PriorityLow, PriorityHigh)
+	RegisterEnum[Color]("red", "green")
+}
+
+func TestEnum(t *testing.T) {
+	type Task struct {
+		Status   Status            `json:"status"`
+		Previous *Status           `json:"previous,omitempty"`
+		Region   Region            `json:"region"`
+		Priority Priority          `json:"priority"`
+		Colors   []Color           `json:"colors"`
+		ByRegion map[string]Status `json:"by_region"`
+		Override Status            `json:"override" validate:"oneof=done"`
+	}
+	s, err := Generate[Task]()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	props := s["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"status":    `{"enum":["pending","done"],"type":"string"}`,
+		"previous":  `{"enum":["pending","done"],"type":"string"}`,
+		"region":    `{"enum":["eu","us"],"type":"string"}`,
+		"priority":  `{"enum":[1,2],"type":"integer"}`,
+		"colors":    `{"items":{"enum":["red","green"],"type":"string"},"type":"array"}`,
+		"by_region": `{"additionalProperties":{"enum":["pending","done"],"type":"string"},"type":"object"}`,
+		"override":  `{"enum":["done"],"type":"string"}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	t.Run("error: Enumer on non-string type", func(t *testing.T) {
+		type Bad struct {
+			Level Level `json:"level"`
+		}
+		if _, err := Generate[Bad](); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("expected ErrUnsupportedType, got %v", err)
+		}
+	})
+
+	t.Run("error: RegisterEnum misuse panics", func(t *testing.T) {
+		for name, register := range map[string]func(){
+			"duplicate": func() { RegisterEnum(PriorityLow) },
+			"unnamed":   func() { RegisterEnum("a", "b") },
+			"empty":     func() { RegisterEnum[Region]() },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected panic", name)
+					}
+				}()
+				register()
+			}()
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"vax/pkg/vax/sdto"
+)
+
+// ErrNoFieldSpec is returned by GenerateFieldSpec for Go types or validate
+// rules that sdto.FieldSpec cannot express.
+var ErrNoFieldSpec = errors.New("schema: no sdto.FieldSpec equivalent")
+
+// GenerateFieldSpec converts struct T straight into an sdto schema, for
+// NewAction / ValidateData / Registry, without the lossy JSON Schema →
+// ParseSchema round trip. The same json / validate tags apply as for
+// Generate; string lengths count code points (LengthUnit runes), as
+// go-playground/validator does.
+//
+// sdto fields are flat and required unless they have a default, so nested
+// structs, slices, optional fields without a default:"…" tag (pointer /
+// omitempty / required_without), nullable, ne, patterns and custom formats
+// fail with ErrNoFieldSpec instead of being dropped. Sized integers (int8, uint16, ...) get their range as bounds.
+func GenerateFieldSpec[T any]() (map[string]sdto.FieldSpec, error) {
+	return GenerateFieldSpecType(reflect.TypeFor[T]())
+}
+
+// MustGenerateFieldSpec is GenerateFieldSpec that panics on error.
+func MustGenerateFieldSpec[T any]() map[string]sdto.FieldSpec {
+	s, err := GenerateFieldSpec[T]()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// GenerateFieldSpecType is GenerateFieldSpec for a reflect.Type known only at run time.
+func GenerateFieldSpecType(t reflect.Type) (map[string]sdto.FieldSpec, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w, got %s", ErrNotStruct, t)
+	}
+
+	out := map[string]sdto.FieldSpec{}
+	for _, f := range structFields(t) {
+		fieldPath := t.Name() + "." + f.Name
+		rules := tagRules(f.Tag.Get("validate"))
+		_, required := rules["required"]
+		_, hasDefault := f.Tag.Lookup("default")
+		if !required && !hasDefault && (f.Type.Kind() == reflect.Pointer || f.omitempty || f.viaPointer) {
+			return nil, fmt.Errorf("%w: %s is optional, sdto fields are required unless they have a default", ErrNoFieldSpec, fieldPath)
+		}
+		if _, ok := rules["required_without"]; ok {
+			return nil, fmt.Errorf("%w: %s: required_without", ErrNoFieldSpec, fieldPath)
+		}
+		if p, _ := fieldPattern(f.Tag.Get("pattern"), rules); p != "" {
+			return nil, fmt.Errorf("%w: %s: pattern", ErrNoFieldSpec, fieldPath)
+		}
+
+		spec, err := fieldSpec(f.Type, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyFieldRules(&spec, rules); err != nil {
+			return nil, fmt.Errorf("%s: %w", fieldPath, err)
+		}
+		if tag, ok := f.Tag.Lookup("default"); ok {
+			v, err := tagValue(tag, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: default %q: %w", fieldPath, tag, err)
+			}
+			spec.Default = &v // 有 default 的欄位在 sdto 為選填，Finalize 會補上
+		}
+		out[f.name] = spec
+	}
+
+	if err := sdto.ValidateSchema(out); err != nil {
+		return nil, fmt.Errorf("schema: %s: %w", t, err)
+	}
+	return out, nil
+}
+
+func fieldSpec(t reflect.Type, path string) (sdto.FieldSpec, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if mapped, ok := lookupTypeMapping(t); ok {
+		return mappedFieldSpec(mapped, path)
+	}
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return sdto.FieldSpec{Type: "string", Format: sdto.FormatDateTime}, nil
+	case reflect.TypeFor[time.Duration]():
+		return sdto.FieldSpec{Type: "integer"}, nil
+	case reflect.TypeFor[url.URL]():
+		return sdto.FieldSpec{Type: "string", Format: sdto.FormatURI}, nil
+	case reflect.TypeFor[net.IP](), reflect.TypeFor[netip.Addr]():
+		return sdto.FieldSpec{Type: "string"}, nil
+	case reflect.TypeFor[json.Number]():
+		return sdto.FieldSpec{Type: "number"}, nil
+	}
+
+	var spec sdto.FieldSpec
+	switch k := t.Kind(); {
+	case k == reflect.String:
+		spec.Type = "string"
+	case k == reflect.Bool:
+		spec.Type = "boolean"
+	case k == reflect.Float32 || k == reflect.Float64:
+		spec.Type = "number"
+	case isNumeric(k):
+		spec.Type = "integer"
+		spec.Min, spec.Max = intRange(t)
+	case k == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		spec.Type = "bytes"
+	case k == reflect.Map && t.Key().Kind() == reflect.String:
+		values, err := fieldSpec(t.Elem(), path+"[]")
+		if err != nil {
+			return spec, err
+		}
+		spec.Type = "map"
+		spec.Values = &values
+	default:
+		return spec, fmt.Errorf("%w: %s: %s", ErrNoFieldSpec, path, t)
+	}
+
+	enum, isEnum, err := enumOf(t)
+	if err != nil {
+		return spec, fmt.Errorf("%s: %w", path, err)
+	}
+	if isEnum {
+		if spec.Type == "boolean" {
+			return spec, fmt.Errorf("%w: %s: boolean enum", ErrNoFieldSpec, path)
+		}
+		spec.Min, spec.Max = nil, nil
+		for _, v := range enum {
+			spec.Enum = append(spec.Enum, fmt.Sprint(v))
+		}
+	}
+	return spec, nil
+}
+
+// mappedFieldSpec 把 RegisterTypeMapping 的 JSON Schema 轉成 FieldSpec，只接受 sdto 能表達的關鍵字
+func mappedFieldSpec(m map[string]any, path string) (sdto.FieldSpec, error) {
+	var spec sdto.FieldSpec
+	typ, _ := m["type"].(string)
+	switch typ {
+	case "string", "integer", "number", "boolean":
+		spec.Type = typ
+	default:
+		return spec, fmt.Errorf("%w: %s: mapped type %v", ErrNoFieldSpec, path, m["type"])
+	}
+	bound := func(v any) *string {
+		s := fmt.Sprint(v)
+		return &s
+	}
+	for k, v := range m {
+		switch k {
+		case "type":
+		case "format":
+			f, _ := v.(string)
+			switch f {
+			case sdto.FormatEmail, sdto.FormatURI, sdto.FormatUUID, sdto.FormatDateTime:
+				spec.Format = f
+			default:
+				return spec, fmt.Errorf("%w: %s: format %q", ErrNoFieldSpec, path, f)
+			}
+		case "minLength", "minimum":
+			spec.Min = bound(v)
+		case "maxLength", "maximum":
+			spec.Max = bound(v)
+		case "enum":
+			values, _ := v.([]any)
+			for _, e := range values {
+				spec.Enum = append(spec.Enum, fmt.Sprint(e))
+			}
+		case "description", "title", "examples":
+			// 註解性質，FieldSpec 沒有對應欄位
+		default:
+			return spec, fmt.Errorf("%w: %s: mapped keyword %q", ErrNoFieldSpec, path, k)
+		}
+	}
+	if spec.Type == "string" && (spec.Min != nil || spec.Max != nil) {
+		spec.LengthUnit = sdto.LengthRunes // JSON Schema 長度以 code point 計
+	}
+	return spec, nil
+}
+
+// intRange 固定大小整數（int8 / uint16 ...）以型別範圍為預設 bounds；int / int64 / uint64 不加
+func intRange(t reflect.Type) (min, max *string) {
+	str := func(s string) *string { return &s }
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits()
+		return str(strconv.FormatInt(-1<<(bits-1), 10)), str(strconv.FormatInt(1<<(bits-1)-1, 10))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return str("0"), str(strconv.FormatUint(1<<t.Bits()-1, 10))
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return str("0"), nil
+	default:
+		return nil, nil
+	}
+}
+
+// applyFieldRules 把 validate tag 套到 FieldSpec；無法表達的規則回傳 ErrNoFieldSpec
+func applyFieldRules(spec *sdto.FieldSpec, rules map[string]string) error {
+	numeric := spec.Type == "integer" || spec.Type == "number"
+	for key, value := range rules {
+		v := value
+		switch key {
+		case "required", "omitempty":
+		case "min", "gte", "max", "lte", "gt", "lt", "len":
+			if !numeric && spec.Type != "string" && spec.Type != "bytes" && spec.Type != "map" {
+				continue
+			}
+			if !numeric {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					return fmt.Errorf("validate %s=%s: not a non-negative integer", key, v)
+				}
+				switch key {
+				case "gt":
+					v = strconv.Itoa(n + 1)
+				case "lt":
+					if n == 0 {
+						return fmt.Errorf("validate lt=0: no value has a negative length")
+					}
+					v = strconv.Itoa(n - 1)
+				}
+				if spec.Type == "string" {
+					spec.LengthUnit = sdto.LengthRunes
+				}
+			}
+			switch key {
+			case "min", "gte":
+				spec.Min = &v
+			case "max", "lte":
+				spec.Max = &v
+			case "gt":
+				spec.Min, spec.ExclusiveMin = &v, numeric
+			case "lt":
+				spec.Max, spec.ExclusiveMax = &v, numeric
+			case "len":
+				if numeric {
+					c := any(json.Number(v))
+					spec.Const = &c
+				} else {
+					spec.Min, spec.Max = &v, &v
+				}
+			}
+		case "oneof":
+			if !numeric && spec.Type != "string" {
+				return fmt.Errorf("%w: validate oneof on %s", ErrNoFieldSpec, spec.Type)
+			}
+			spec.Enum = strings.Fields(v)
+		case "ne", "nullable":
+			return fmt.Errorf("%w: validate %s", ErrNoFieldSpec, key)
+		default:
+			f, ok := lookupFormat(key)
+			if !ok || spec.Type != "string" {
+				continue
+			}
+			switch f.Name {
+			case sdto.FormatEmail, sdto.FormatURI, sdto.FormatUUID, sdto.FormatDateTime:
+				if f.Pattern != "" {
+					return fmt.Errorf("%w: validate %s: pattern", ErrNoFieldSpec, key)
+				}
+				spec.Format = f.Name
+			default:
+				return fmt.Errorf("%w: validate %s: format %q", ErrNoFieldSpec, key, f.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/sdto"
+)
+
+type TransferDTO struct {
+	From     string            `json:"from" validate:"required,min=3,max=20"`
+	Amount   float64           `json:"amount" validate:"gt=0,lte=10000"`
+	Retries  uint8             `json:"retries"`
+	Currency string            `json:"currency" validate:"oneof=EUR USD"`
+	Memo     string            `json:"memo" validate:"max=140"`
+	Email    string            `json:"email" validate:"email"`
+	At       time.Time         `json:"at"`
+	Status   Status            `json:"status"`
+	Tags     map[string]string `json:"tags" validate:"max=5"`
+	Payload  []byte            `json:"payload" validate:"max=1024"`
+	Version  int               `json:"version" validate:"len=2"`
+}
+
+func TestGenerateFieldSpec(t *testing.T) {
+	spec, err := GenerateFieldSpec[TransferDTO]()
+	if err != nil {
+		t.Fatalf("GenerateFieldSpec failed: %v", err)
+	}
+	for name, want := range map[string]string{
+		"from":     `{"type":"string","min":"3","max":"20","length_unit":"runes"}`,
+		"amount":   `{"type":"number","min":"0","max":"10000","exclusive_min":true}`,
+		"retries":  `{"type":"integer","min":"0","max":"255"}`,
+		"currency": `{"type":"string","enum":["EUR","USD"]}`,
+		"email":    `{"type":"string","format":"email"}`,
+		"at":       `{"type":"string","format":"date-time"}`,
+		"status":   `{"type":"string","enum":["pending","done"]}`,
+		"tags":     `{"type":"map","max":"5","values":{"type":"string"}}`,
+		"payload":  `{"type":"bytes","max":"1024"}`,
+		"version":  `{"type":"integer","const":2}`,
+	} {
+		if got := mustJSON(t, spec[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	t.Run("feeds ValidateData", func(t *testing.T) {
+		data := map[string]any{
+			"from": "alice", "amount": 10.5, "retries": 3, "currency": "EUR", "memo": "",
+			"email": "a@example.com", "at": "2026-01-02T03:04:05Z", "status": "done",
+			"tags": map[string]any{"k": "v"}, "payload": "aGk=", "version": 2,
+		}
+		if err := sdto.ValidateData(data, spec); err != nil {
+			t.Fatalf("ValidateData failed: %v", err)
+		}
+
+		data["from"] = "al" // minLength 經 ParseSchema 往返時會遺失
+		var fe *sdto.FieldError
+		if err := sdto.ValidateData(data, spec); !errors.As(err, &fe) || fe.Field != "from" {
+			t.Errorf("expected error on from, got %v", err)
+		}
+	})
+
+	t.Run("error: not representable", func(t *testing.T) {
+		type Nested struct {
+			Inner struct {
+				A string `json:"a"`
+			} `json:"inner"`
+		}
+		type List struct {
+			Items []string `json:"items"`
+		}
+		type Optional struct {
+			Note *string `json:"note"`
+		}
+		type Omit struct {
+			Note string `json:"note,omitempty"`
+		}
+		type NotEqual struct {
+			N int `json:"n" validate:"ne=0"`
+		}
+		type Pattern struct {
+			Code string `json:"code" pattern:"^[A-Z]+$"`
+		}
+		type Any struct {
+			V any `json:"v"`
+		}
+		for name, gen := range map[string]func() (map[string]sdto.FieldSpec, error){
+			"nested":   GenerateFieldSpec[Nested],
+			"slice":    GenerateFieldSpec[List],
+			"pointer":  GenerateFieldSpec[Optional],
+			"omit":     GenerateFieldSpec[Omit],
+			"ne":       GenerateFieldSpec[NotEqual],
+			"pattern":  GenerateFieldSpec[Pattern],
+			"any":      GenerateFieldSpec[Any],
+			"order_id": GenerateFieldSpec[orderDTO],
+		} {
+			if _, err := gen(); !errors.Is(err, ErrNoFieldSpec) {
+				t.Errorf("%s: expected ErrNoFieldSpec, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("error: top-level not a struct", func(t *testing.T) {
+		if _, err := GenerateFieldSpec[string](); !errors.Is(err, ErrNotStruct) {
+			t.Errorf("expected ErrNotStruct, got %v", err)
+		}
+	})
+}
+
+type orderDTO struct {
+	Order string `json:"order" validate:"order_id"`
+}
@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Format is what a validate tag contributes to a string field's schema.
+type Format struct {
+	Name    string // "format" keyword (annotation only for most validators)
+	Pattern string // "pattern" keyword, enforced by every validator
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{
+		"email":    {Name: "email"},
+		"url":      {Name: "uri"},
+		"uri":      {Name: "uri"},
+		"uuid":     {Name: "uuid"},
+		"datetime": {Name: "date-time"},
+		"ipv4":     {Name: "ipv4"},
+		"ipv6":     {Name: "ipv6"},
+		"hostname": {Name: "hostname"},
+	}
+)
+
+// RegisterFormat maps a custom validate tag to a Format, so that
+// validate:"order_id" on a string field emits its format and pattern:
+//
+//	schema.RegisterFormat("order_id", schema.Format{Name: "order-id", Pattern: `^ORD-[0-9]{8}$`})
+//
+// Like sql.Register it is meant for init time and panics when the tag is
+// already registered or the pattern does not compile.
+func RegisterFormat(tag string, f Format) {
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			panic(fmt.Sprintf("schema: RegisterFormat %q: %v", tag, err))
+		}
+	}
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if _, dup := formats[tag]; dup {
+		panic(fmt.Sprintf("schema: RegisterFormat called twice for tag %q", tag))
+	}
+	formats[tag] = f
+	resetCache()
+}
+
+func lookupFormat(tag string) (Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	f, ok := formats[tag]
+	return f, ok
+}
+
+// fieldPattern 取得欄位的 pattern：專用 struct tag `pattern:"..."` 優先（內容不經逗號切割），
+// 其次為 validate:"regexp=..."（與 go-playground/validator 相同，逗號寫成 0x2C）
+func fieldPattern(patternTag string, rules map[string]string) (string, error) {
+	p := patternTag
+	if p == "" {
+		p = strings.ReplaceAll(rules["regexp"], "0x2C", ",")
+	}
+	if p == "" {
+		return "", nil
+	}
+	// Go 的 RE2 語法大致是 ECMA-262 的子集，能編譯的 pattern 在 JS 端也能用
+	if _, err := regexp.Compile(p); err != nil {
+		return "", fmt.Errorf("pattern %q: %w", p, err)
+	}
+	return p, nil
+}
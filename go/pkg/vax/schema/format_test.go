@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterFormat("order_id", Format{Name: "order-id", Pattern: `^ORD-[0-9]{8}$`})
+}
+
+func TestFormat(t *testing.T) {
+	type Shipment struct {
+		Order    string  `json:"order" validate:"required,order_id"`
+		Passport string  `json:"passport" validate:"regexp=^[A-Z]{2}\\d{6}$"`
+		Postcode string  `json:"postcode" pattern:"^[0-9]{3,5}$"`
+		Ref      *string `json:"ref" validate:"regexp=^[a-z]{10x2C3}$"`
+		Host     string  `json:"host" validate:"hostname"`
+	}
+	s, err := Generate[Shipment]()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	props := s["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"order":    `{"format":"order-id","pattern":"^ORD-[0-9]{8}$","type":"string"}`,
+		"passport": `{"pattern":"^[A-Z]{2}\\d{6}$","type":"string"}`,
+		"postcode": `{"pattern":"^[0-9]{3,5}$","type":"string"}`,
+		"ref":      `{"pattern":"^[a-z]{1,3}$","type":"string"}`,
+		"host":     `{"format":"hostname","type":"string"}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	t.Run("error: invalid pattern", func(t *testing.T) {
+		type Bad struct {
+			Code string `json:"code" pattern:"^[A-Z"`
+		}
+		if _, err := Generate[Bad](); err == nil || !strings.Contains(err.Error(), "Bad.Code") {
+			t.Errorf("expected pattern error naming the field, got %v", err)
+		}
+	})
+
+	t.Run("error: pattern on non-string", func(t *testing.T) {
+		type Bad struct {
+			Count int `json:"count" pattern:"^[0-9]+$"`
+		}
+		if _, err := Generate[Bad](); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("error: duplicate registration panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		RegisterFormat("email", Format{Name: "email"})
+	})
+}
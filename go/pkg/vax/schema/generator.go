@@ -0,0 +1,694 @@
+// Package schema generates JSON Schema documents from Go DTO structs, so the
+// schema a provider serves always matches the struct its handlers decode.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支援的 JSON Schema 版本（$schema 值），預設 Draft07，以 WithDraft 選擇
+const (
+	Draft07     = "http://json-schema.org/draft-07/schema#"
+	Draft202012 = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// Error codes
+var (
+	ErrNotStruct        = errors.New("schema: top-level type must be a struct")
+	ErrUnsupportedType  = errors.New("schema: unsupported type")
+	ErrStrict           = errors.New("schema: not allowed in strict mode")
+	ErrUnsupportedDraft = errors.New("schema: unsupported draft")
+)
+
+type generator struct {
+	cfg *config
+
+	expanding map[reflect.Type]bool   // 正在展開的具名 struct（偵測循環用）
+	recursive map[reflect.Type]bool   // 曾在展開途中被引用的型別，改放進 definitions / $defs
+	names     map[reflect.Type]string // 型別 → 定義名稱
+	taken     map[string]bool
+	defs      map[string]any
+}
+
+// Generate returns the JSON Schema (draft-07) for struct T:
+//
+//   - properties follow encoding/json field names (json tag, "-" skips)
+//   - a field is required when tagged validate:"required", or when it is
+//     neither a pointer nor omitempty
+//   - validate tags min / max / gte / lte / gt / lt / len / ne / oneof /
+//     email / url / uuid / datetime / nullable become the matching JSON
+//     Schema keywords; bounds compare values on numbers and lengths on
+//     strings (minLength), slices (minItems) and maps (minProperties)
+//   - a `pattern:"..."` struct tag or validate:"regexp=..." emits "pattern";
+//     custom validate tags registered with RegisterFormat emit their format
+//   - default:"…" / example:"…" tags become "default" / "examples", typed
+//     like the field (default:"3" on an int is 3, default:"[1,2]" on a
+//     slice is an array, durations may be written "1m30s")
+//   - named types registered with RegisterEnum or implementing Enumer
+//     carry "enum"
+//   - required_without=Other makes a field required only when Other is absent
+//
+// Embedded structs are flattened the way encoding/json promotes their fields
+// (fields reached through a nil-able *Embedded are never required).
+// Nested structs, slices, arrays and map[string]T are supported, as are
+// time.Time (date-time), time.Duration (integer nanoseconds, as encoding/json
+// writes it), net.IP / netip.Addr (ipv4 or ipv6), url.URL (uri),
+// json.Number and json.RawMessage; other types can be described with
+// RegisterTypeMapping.
+//
+// Self-referential types (Comment{Replies []Comment}, or A → B → A) are
+// emitted once under "definitions" ("$defs" with WithDraft(Draft202012))
+// and referenced with "$ref"; see WithDefinitionNamer. Types without cycles
+// are always inlined.
+func Generate[T any](opts ...Option) (map[string]any, error) {
+	return GenerateType(reflect.TypeFor[T](), opts...)
+}
+
+// MustGenerate is Generate that panics on error (for package-level schemas).
+func MustGenerate[T any](opts ...Option) map[string]any {
+	s, err := Generate[T](opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// GenerateType is Generate for a reflect.Type known only at run time.
+// Results are cached per type and options (custom WithDefinitionNamer
+// functions bypass the cache); every call returns its own copy.
+func GenerateType(t reflect.Type, opts ...Option) (map[string]any, error) {
+	cfg := newConfig(opts)
+	key, cacheable := cfg.cacheKey(t)
+	if cacheable {
+		if s, ok := cache.Load(key); ok {
+			return cloneValue(s).(map[string]any), nil
+		}
+	}
+	s, err := generate(t, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cacheable {
+		cache.Store(key, cloneValue(s))
+	}
+	return s, nil
+}
+
+func generate(t reflect.Type, cfg *config) (map[string]any, error) {
+	if cfg.draft != Draft07 && cfg.draft != Draft202012 {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDraft, cfg.draft)
+	}
+	g := &generator{
+		cfg:       cfg,
+		expanding: map[reflect.Type]bool{},
+		recursive: map[reflect.Type]bool{},
+		names:     map[reflect.Type]string{},
+		taken:     map[string]bool{},
+		defs:      map[string]any{},
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w, got %s", ErrNotStruct, t)
+	}
+
+	s, err := g.namedStruct(t, t.Name())
+	if err != nil {
+		return nil, err
+	}
+	if len(g.defs) > 0 {
+		s[g.defsKeyword()] = g.defs
+	}
+	s["$schema"] = g.cfg.draft
+	return s, nil
+}
+
+// ToJSON 輸出縮排後的 JSON（給人看或寫檔用）
+func ToJSON(schema map[string]any) ([]byte, error) {
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (g *generator) structSchema(t reflect.Type, path string) (map[string]any, error) {
+	props := map[string]any{}
+	required := []string{}
+	fields := structFields(t)
+	var conditional []any
+
+	for _, f := range fields {
+		fieldPath := path + "." + f.Name
+		if g.cfg.strict && !f.tagged {
+			return nil, fmt.Errorf("%w: %s has no json tag name", ErrStrict, fieldPath)
+		}
+		s, err := g.typeSchema(f.Type, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		rules := tagRules(f.Tag.Get("validate"))
+		if err := applyValidation(s, rules, f.Type); err != nil {
+			return nil, fmt.Errorf("%s: %w", fieldPath, err)
+		}
+		if err := applyPattern(s, f, rules); err != nil {
+			return nil, fmt.Errorf("%s: %w", fieldPath, err)
+		}
+		if err := applyValueTags(s, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", fieldPath, err)
+		}
+		props[f.name] = s
+
+		if other, ok := rules["required_without"]; ok {
+			// 另一欄位缺席時本欄位必填：{"anyOf":[{"required":[other]},{"required":[name]}]}
+			otherName, found := goFieldName(fields, other)
+			if !found {
+				return nil, fmt.Errorf("%s: validate required_without=%s: no such field", fieldPath, other)
+			}
+			conditional = append(conditional, map[string]any{"anyOf": []any{
+				map[string]any{"required": []string{otherName}},
+				map[string]any{"required": []string{f.name}},
+			}})
+			continue
+		}
+		if _, ok := rules["required"]; ok || (f.Type.Kind() != reflect.Pointer && !f.omitempty && !f.viaPointer) {
+			required = append(required, f.name)
+		}
+	}
+
+	s := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	if len(conditional) > 0 {
+		s["allOf"] = conditional
+	}
+	if g.cfg.strict {
+		s["additionalProperties"] = false
+	}
+	return s, nil
+}
+
+// goFieldName 以 Go 欄位名稱（validator tag 的寫法）找出對應的 JSON 名稱
+func goFieldName(fields []field, goName string) (string, bool) {
+	for _, f := range fields {
+		if f.Name == goName {
+			return f.name, true
+		}
+	}
+	return "", false
+}
+
+// namedStruct 展開具名 struct；展開途中再遇到自己時改輸出 $ref，
+// 展開完成後若確實有循環，把 schema 移到定義區並回傳 $ref
+func (g *generator) namedStruct(t reflect.Type, path string) (map[string]any, error) {
+	if t.Name() == "" {
+		return g.structSchema(t, path) // 匿名 struct 無法自我參照
+	}
+	if g.recursive[t] && !g.expanding[t] {
+		return g.ref(t), nil
+	}
+	if g.expanding[t] {
+		g.recursive[t] = true
+		return g.ref(t), nil
+	}
+
+	g.expanding[t] = true
+	s, err := g.structSchema(t, path)
+	delete(g.expanding, t)
+	if err != nil {
+		return nil, err
+	}
+	if !g.recursive[t] {
+		return s, nil
+	}
+	g.defs[g.defName(t)] = s
+	return g.ref(t), nil
+}
+
+func (g *generator) ref(t reflect.Type) map[string]any {
+	return map[string]any{"$ref": "#/" + g.defsKeyword() + "/" + escapePointer(g.defName(t))}
+}
+
+// defsKeyword draft-07 以 definitions 放共用定義，2019-09 起改為 $defs
+func (g *generator) defsKeyword() string {
+	if g.cfg.draft == Draft07 {
+		return "definitions"
+	}
+	return "$defs"
+}
+
+// defName 取得（必要時配置）型別的定義名稱，重名時加數字後綴
+func (g *generator) defName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	base := g.cfg.defName(t)
+	if base == "" {
+		base = "def"
+	}
+	name := base
+	for i := 2; g.taken[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	g.taken[name] = true
+	return name
+}
+
+// escapePointer 依 RFC 6901 跳脫 JSON Pointer 片段
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// field 是 encoding/json 實際會輸出的欄位（含嵌入 struct 提升上來的欄位）
+type field struct {
+	reflect.StructField
+	name       string
+	omitempty  bool
+	tagged     bool // json tag 有指定名稱
+	depth      int  // 嵌入層數，0 為 t 自身的欄位
+	viaPointer bool // 經由 *Embedded 提升；nil 時整組欄位不輸出，故不算 required
+}
+
+// structFields 依 encoding/json 的規則展開欄位：
+//   - 沒有 json 名稱的嵌入 struct（或 *struct）把欄位提升到外層，未匯出的嵌入型別也一樣
+//   - 有 json 名稱的嵌入 struct 視為一般具名欄位
+//   - 同名欄位取最淺的一層；同層中只有一個有 tag 名稱時取它，否則全部捨棄
+func structFields(t reflect.Type) []field {
+	var all []field
+	var walk func(t reflect.Type, depth int, viaPointer bool, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, depth int, viaPointer bool, visited map[reflect.Type]bool) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !f.IsExported() && ft.Kind() != reflect.Struct {
+					continue
+				}
+			} else if !f.IsExported() {
+				continue
+			}
+			name, omitempty, skip := jsonName(f)
+			if skip {
+				continue
+			}
+			tagName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			tagged := tagName != ""
+
+			if f.Anonymous && !tagged {
+				ft, ptr := f.Type, false
+				if ft.Kind() == reflect.Pointer {
+					ft, ptr = ft.Elem(), true
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, depth+1, viaPointer || ptr, visited)
+					continue
+				}
+			}
+			all = append(all, field{
+				StructField: f,
+				name:        name,
+				omitempty:   omitempty,
+				tagged:      tagged,
+				depth:       depth,
+				viaPointer:  viaPointer,
+			})
+		}
+	}
+	walk(t, 0, false, map[reflect.Type]bool{})
+
+	byName := map[string][]int{}
+	for i, f := range all {
+		byName[f.name] = append(byName[f.name], i)
+	}
+	out := make([]field, 0, len(all))
+	for i, f := range all {
+		if dominant(all, byName[f.name]) == i {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// dominant 回傳同名欄位中勝出者的索引，衝突無解時回傳 -1
+func dominant(all []field, idx []int) int {
+	minDepth := all[idx[0]].depth
+	for _, i := range idx {
+		minDepth = min(minDepth, all[i].depth)
+	}
+	winner, count, taggedCount := -1, 0, 0
+	for _, i := range idx {
+		if all[i].depth != minDepth {
+			continue
+		}
+		count++
+		if all[i].tagged {
+			taggedCount++
+			winner = i
+		}
+	}
+	switch {
+	case taggedCount == 1:
+		return winner
+	case count == 1 && taggedCount == 0:
+		for _, i := range idx {
+			if all[i].depth == minDepth {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// stdlibTypes 對應 encoding/json 的實際輸出，而非型別內部（多為未匯出欄位）的結構
+var stdlibTypes = map[reflect.Type]func() map[string]any{
+	// RFC 3339（MarshalJSON）
+	reflect.TypeFor[time.Time](): func() map[string]any {
+		return map[string]any{"type": "string", "format": "date-time"}
+	},
+	// encoding/json 把 Duration 當 int64 輸出，單位為奈秒
+	reflect.TypeFor[time.Duration](): func() map[string]any {
+		return map[string]any{"type": "integer", "description": "duration in nanoseconds"}
+	},
+	// MarshalText：IPv4 為點分十進位，其餘為 IPv6
+	reflect.TypeFor[net.IP]():     ipSchema,
+	reflect.TypeFor[netip.Addr](): ipSchema,
+	// url.URL 沒有 MarshalText；DTO 應搭配自訂 marshaller 以字串傳輸
+	reflect.TypeFor[url.URL](): func() map[string]any {
+		return map[string]any{"type": "string", "format": "uri"}
+	},
+	reflect.TypeFor[json.Number](): func() map[string]any {
+		return map[string]any{"type": "number"}
+	},
+	reflect.TypeFor[json.RawMessage](): func() map[string]any {
+		return map[string]any{}
+	},
+}
+
+func ipSchema() map[string]any {
+	return map[string]any{
+		"type":  "string",
+		"anyOf": []any{map[string]any{"format": "ipv4"}, map[string]any{"format": "ipv6"}},
+	}
+}
+
+func (g *generator) typeSchema(t reflect.Type, path string) (map[string]any, error) {
+	if g.cfg.strict && (t.Kind() == reflect.Interface || t == reflect.TypeFor[json.RawMessage]()) {
+		return nil, fmt.Errorf("%w: %s accepts any JSON value", ErrStrict, path)
+	}
+	if mapped, ok := lookupTypeMapping(t); ok {
+		return mapped, nil
+	}
+	if known, ok := stdlibTypes[t]; ok {
+		return known(), nil
+	}
+	enum, isEnum, err := enumOf(t)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if isEnum {
+		s, err := g.kindSchema(t, path)
+		if err != nil {
+			return nil, err
+		}
+		s["enum"] = append([]any(nil), enum...) // 複製，避免 nullable 改到登錄的值
+		return s, nil
+	}
+	return g.kindSchema(t, path)
+}
+
+func (g *generator) kindSchema(t reflect.Type, path string) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem(), path)
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Struct:
+		return g.namedStruct(t, path)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			// encoding/json 把 []byte 編成 base64 字串
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.typeSchema(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			// Go 陣列元素同型別、長度固定：items + 長度，而非 tuple（prefixItems / items 陣列）
+			s["minItems"], s["maxItems"] = t.Len(), t.Len()
+		}
+		return s, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s: map key %s is not a string (JSON object keys are strings)", ErrUnsupportedType, path, t.Key())
+		}
+		values, err := g.typeSchema(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil // 任意 JSON 值
+	default:
+		return nil, fmt.Errorf("%w: %s: %s", ErrUnsupportedType, path, t)
+	}
+}
+
+// jsonName 依 encoding/json 規則取得欄位名稱
+func jsonName(f reflect.StructField) (name string, omitempty, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// tagRules 解析 validate tag："required,min=1,max=50" → {required: "", min: "1", max: "50"}
+func tagRules(tag string) map[string]string {
+	rules := map[string]string{}
+	for _, part := range strings.Split(tag, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		rules[key] = value
+	}
+	return rules
+}
+
+// applyValidation 把 validate tag 轉成 JSON Schema 關鍵字：
+// 數值型別的 min / max / gt / lt / len / ne 比較數值，字串、slice、map 則比較長度
+func applyValidation(s map[string]any, rules map[string]string, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	numeric := isNumeric(t.Kind())
+	minLen, maxLen := lengthKeywords(t)
+
+	for key, value := range rules {
+		switch key {
+		case "min", "max", "gte", "lte", "gt", "lt", "len":
+			if numeric {
+				n, err := boundValue(value, true)
+				if err != nil {
+					return fmt.Errorf("validate %s=%s: %w", key, value, err)
+				}
+				switch key {
+				case "min", "gte":
+					s["minimum"] = n
+				case "max", "lte":
+					s["maximum"] = n
+				case "gt":
+					s["exclusiveMinimum"] = n // draft-07：數值形式
+				case "lt":
+					s["exclusiveMaximum"] = n
+				case "len":
+					s["const"] = n
+				}
+				continue
+			}
+			if minLen == "" {
+				continue // 例如 time.Time 的 gt（與現在時間比較），無對應關鍵字
+			}
+			n, err := boundValue(value, false)
+			if err != nil {
+				return fmt.Errorf("validate %s=%s: %w", key, value, err)
+			}
+			length := n.(int)
+			switch key {
+			case "min", "gte":
+				s[minLen] = length
+			case "max", "lte":
+				s[maxLen] = length
+			case "gt":
+				s[minLen] = length + 1
+			case "lt":
+				if length == 0 {
+					return fmt.Errorf("validate lt=0: no value has a negative length")
+				}
+				s[maxLen] = length - 1
+			case "len":
+				s[minLen], s[maxLen] = length, length
+			}
+		case "ne":
+			if numeric {
+				n, err := boundValue(value, true)
+				if err != nil {
+					return fmt.Errorf("validate ne=%s: %w", value, err)
+				}
+				s["not"] = map[string]any{"const": n}
+			} else if t.Kind() == reflect.String {
+				s["not"] = map[string]any{"const": value}
+			}
+		case "oneof":
+			enum, err := enumValues(strings.Fields(value), t.Kind())
+			if err != nil {
+				return fmt.Errorf("validate oneof=%s: %w", value, err)
+			}
+			s["enum"] = enum
+		default:
+			if f, ok := lookupFormat(key); ok && value == "" {
+				if f.Name != "" {
+					s["format"] = f.Name
+				}
+				if f.Pattern != "" {
+					s["pattern"] = f.Pattern
+				}
+			}
+		}
+	}
+	if _, ok := rules["nullable"]; ok {
+		makeNullable(s) // enum 可能在迴圈中較晚才設定，故最後處理
+	}
+	return nil
+}
+
+// makeNullable 讓 schema 也接受 null（JSON Schema 2020-12 / OpenAPI 3.1 沒有 nullable 關鍵字）：
+// type 加上 "null"、enum 加上 null，$ref 則包成 anyOf
+func makeNullable(s map[string]any) {
+	if ref, ok := s["$ref"]; ok {
+		delete(s, "$ref")
+		s["anyOf"] = []any{map[string]any{"$ref": ref}, map[string]any{"type": "null"}}
+		return
+	}
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		s["enum"] = append(enum, nil)
+	}
+}
+
+// applyPattern 套用 pattern；只允許字串欄位
+func applyPattern(s map[string]any, f field, rules map[string]string) error {
+	p, err := fieldPattern(f.Tag.Get("pattern"), rules)
+	if err != nil || p == "" {
+		return err
+	}
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.String {
+		return fmt.Errorf("pattern on %s: only string fields can have a pattern", t)
+	}
+	s["pattern"] = p
+	return nil
+}
+
+// lengthKeywords 回傳型別對應的長度關鍵字；[]byte 以 base64 傳輸，位元組長度無法精確換算成字元數
+func lengthKeywords(t reflect.Type) (minKey, maxKey string) {
+	switch t.Kind() {
+	case reflect.String:
+		return "minLength", "maxLength"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "", ""
+		}
+		return "minItems", "maxItems"
+	case reflect.Array:
+		return "minItems", "maxItems"
+	case reflect.Map:
+		return "minProperties", "maxProperties"
+	default:
+		return "", ""
+	}
+}
+
+// boundValue 數值邊界保留原字面值（json.Number），長度邊界為非負整數
+func boundValue(value string, numeric bool) (any, error) {
+	if numeric {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.New("not a number")
+		}
+		return json.Number(value), nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, errors.New("not a non-negative integer")
+	}
+	return n, nil
+}
+
+func enumValues(values []string, kind reflect.Kind) ([]any, error) {
+	enum := make([]any, len(values))
+	for i, v := range values {
+		if isNumeric(kind) {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			enum[i] = json.Number(v)
+			continue
+		}
+		enum[i] = v
+	}
+	return enum, nil
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
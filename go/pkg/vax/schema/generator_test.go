@@ -0,0 +1,374 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required,min=1,max=50"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type UpdateProfileDTO struct {
+	Name     string            `json:"name" validate:"required,min=1,max=50"`
+	Email    string            `json:"email" validate:"required,email"`
+	Age      int               `json:"age" validate:"gte=0,lte=150"`
+	Role     string            `json:"role" validate:"oneof=admin user"`
+	Nickname *string           `json:"nickname" validate:"nullable"`
+	Tags     []string          `json:"tags,omitempty"`
+	Address  Address           `json:"address"`
+	Avatar   []byte            `json:"avatar,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Scores   map[string][]int  `json:"scores,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+type Node struct {
+	Children map[string]*Node `json:"children,omitempty"`
+	Leaf     *Leaf            `json:"leaf,omitempty"`
+}
+
+type Leaf struct {
+	Parent *Node `json:"parent"`
+}
+
+type StrictDTO struct {
+	Root struct {
+		Body string `json:"body"`
+	} `json:"root"`
+	Meta map[string]string `json:"meta"`
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(b)
+}
+
+func sorted(s []string) []string {
+	sort.Strings(s)
+	return s
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := Generate[UpdateProfileDTO]()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	props := s["properties"].(map[string]any)
+
+	for name, want := range map[string]string{
+		"name":     `{"maxLength":50,"minLength":1,"type":"string"}`,
+		"email":    `{"format":"email","type":"string"}`,
+		"age":      `{"maximum":150,"minimum":0,"type":"integer"}`,
+		"role":     `{"enum":["admin","user"],"type":"string"}`,
+		"nickname": `{"type":["string","null"]}`,
+		"tags":     `{"items":{"type":"string"},"type":"array"}`,
+		"address":  `{"properties":{"city":{"maxLength":50,"minLength":1,"type":"string"},"zip":{"type":"string"}},"required":["city"],"type":"object"}`,
+		"avatar":   `{"contentEncoding":"base64","type":"string"}`,
+		"metadata": `{"additionalProperties":{"type":"string"},"type":"object"}`,
+		"scores":   `{"additionalProperties":{"items":{"type":"integer"},"type":"array"},"type":"object"}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+	if len(props) != 10 {
+		t.Errorf("expected 10 properties, got %d", len(props))
+	}
+	if got := mustJSON(t, s["required"]); got != `["name","email","age","role","address"]` {
+		t.Errorf("required = %s", got)
+	}
+	if s["$schema"] != Draft07 {
+		t.Errorf("$schema = %v", s["$schema"])
+	}
+
+	t.Run("ToJSON", func(t *testing.T) {
+		b, err := ToJSON(s)
+		if err != nil || !strings.Contains(string(b), "\n  \"$schema\"") {
+			t.Errorf("unexpected ToJSON output: %s, %v", b, err)
+		}
+	})
+
+	t.Run("stdlib types", func(t *testing.T) {
+		type Event struct {
+			At      time.Time       `json:"at"`
+			Expires *time.Time      `json:"expires,omitempty"`
+			TTL     time.Duration   `json:"ttl" validate:"gte=0"`
+			Client  net.IP          `json:"client"`
+			Link    url.URL         `json:"link"`
+			Amount  json.Number     `json:"amount"`
+			Extra   json.RawMessage `json:"extra"`
+		}
+		s, err := Generate[Event]()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		props := s["properties"].(map[string]any)
+		for name, want := range map[string]string{
+			"at":      `{"format":"date-time","type":"string"}`,
+			"expires": `{"format":"date-time","type":"string"}`,
+			"ttl":     `{"description":"duration in nanoseconds","minimum":0,"type":"integer"}`,
+			"client":  `{"anyOf":[{"format":"ipv4"},{"format":"ipv6"}],"type":"string"}`,
+			"link":    `{"format":"uri","type":"string"}`,
+			"amount":  `{"type":"number"}`,
+			"extra":   `{}`,
+		} {
+			if got := mustJSON(t, props[name]); got != want {
+				t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+			}
+		}
+	})
+
+	t.Run("embedded structs are flattened", func(t *testing.T) {
+		type Audit struct {
+			CreatedBy string    `json:"created_by"`
+			CreatedAt time.Time `json:"created_at"`
+			Note      string    `json:"note"`
+		}
+		type Versioned struct {
+			Version int `json:"version"`
+		}
+		type internal struct {
+			TraceID string `json:"trace_id,omitempty"`
+		}
+		type Doc struct {
+			Audit
+			*Versioned
+			internal
+			Owner Audit  `json:"owner"`
+			Note  string `json:"note,omitempty"`
+			Title string `json:"title"`
+		}
+		s, err := Generate[Doc]()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		props := s["properties"].(map[string]any)
+		var names []string
+		for name := range props {
+			names = append(names, name)
+		}
+		want := []string{"created_at", "created_by", "note", "owner", "title", "trace_id", "version"}
+		if got := mustJSON(t, sorted(names)); got != mustJSON(t, want) {
+			t.Errorf("properties = %s, want %s", got, mustJSON(t, want))
+		}
+		// 外層 note（omitempty）遮蔽嵌入的 note；*Versioned 可能為 nil，version 非必填
+		if got := mustJSON(t, s["required"]); got != `["created_by","created_at","owner","title"]` {
+			t.Errorf("required = %s", got)
+		}
+	})
+
+	t.Run("embedded conflicts follow encoding/json", func(t *testing.T) {
+		type A struct {
+			ID string `json:"ID"`
+			X  string
+		}
+		type B struct {
+			ID int
+			X  string
+		}
+		type C struct {
+			A
+			B
+		}
+		s, err := Generate[C]()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		// ID：只有 A 有 tag 名稱而勝出；X：同層皆無 tag，兩者都捨棄
+		if got := mustJSON(t, s["properties"]); got != `{"ID":{"type":"string"}}` {
+			t.Errorf("properties = %s", got)
+		}
+	})
+
+	t.Run("recursive types use definitions", func(t *testing.T) {
+		type Comment struct {
+			Body    string    `json:"body"`
+			Replies []Comment `json:"replies,omitempty"`
+		}
+		type Thread struct {
+			Root   Comment  `json:"root"`
+			Pinned *Comment `json:"pinned,omitempty"`
+		}
+		s, err := Generate[Thread]()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		props := s["properties"].(map[string]any)
+		if got := mustJSON(t, props["root"]); got != `{"$ref":"#/definitions/Comment"}` {
+			t.Errorf("root = %s", got)
+		}
+		if got := mustJSON(t, props["pinned"]); got != `{"$ref":"#/definitions/Comment"}` {
+			t.Errorf("pinned = %s", got)
+		}
+		want := `{"Comment":{"properties":{"body":{"type":"string"},"replies":{"items":{"$ref":"#/definitions/Comment"},"type":"array"}},"required":["body"],"type":"object"}}`
+		if got := mustJSON(t, s["definitions"]); got != want {
+			t.Errorf("definitions:\ngot:  %s\nwant: %s", got, want)
+		}
+	})
+
+	t.Run("recursive root and naming strategy", func(t *testing.T) {
+		s, err := Generate[Node](WithDefinitionNamer(QualifiedTypeName), WithDraft(Draft202012))
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if s["$ref"] != "#/$defs/schema.Node" || s["$schema"] != Draft202012 {
+			t.Errorf("unexpected root: %s", mustJSON(t, s))
+		}
+		// Node → Leaf → Node：只有 Node 需要定義，Leaf 照常內嵌
+		want := `{"schema.Node":{"properties":{"children":{"additionalProperties":{"$ref":"#/$defs/schema.Node"},"type":"object"},"leaf":{"properties":{"parent":{"$ref":"#/$defs/schema.Node"}},"type":"object"}},"type":"object"}}`
+		if got := mustJSON(t, s["$defs"]); got != want {
+			t.Errorf("$defs:\ngot:  %s\nwant: %s", got, want)
+		}
+	})
+
+	t.Run("non-recursive types stay inline", func(t *testing.T) {
+		s, _ := Generate[UpdateProfileDTO]()
+		if _, ok := s["definitions"]; ok {
+			t.Error("unexpected definitions")
+		}
+	})
+
+	t.Run("bounds by kind", func(t *testing.T) {
+		type Order struct {
+			Qty     int               `json:"qty" validate:"gt=0,lt=100"`
+			Price   float64           `json:"price" validate:"ne=0"`
+			Code    string            `json:"code" validate:"len=6,ne=000000"`
+			Note    string            `json:"note" validate:"gt=2,lt=10"`
+			Items   []string          `json:"items" validate:"min=1,max=5"`
+			Pair    [2]int            `json:"pair" validate:"len=2"`
+			Labels  map[string]string `json:"labels" validate:"max=3"`
+			Email   string            `json:"email,omitempty" validate:"required_without=Phone"`
+			Phone   string            `json:"phone,omitempty" validate:"required_without=Email"`
+			Version int               `json:"version" validate:"len=2"`
+		}
+		s, err := Generate[Order]()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		props := s["properties"].(map[string]any)
+		for name, want := range map[string]string{
+			"qty":     `{"exclusiveMaximum":100,"exclusiveMinimum":0,"type":"integer"}`,
+			"price":   `{"not":{"const":0},"type":"number"}`,
+			"code":    `{"maxLength":6,"minLength":6,"not":{"const":"000000"},"type":"string"}`,
+			"note":    `{"maxLength":9,"minLength":3,"type":"string"}`,
+			"items":   `{"items":{"type":"string"},"maxItems":5,"minItems":1,"type":"array"}`,
+			"pair":    `{"items":{"type":"integer"},"maxItems":2,"minItems":2,"type":"array"}`,
+			"labels":  `{"additionalProperties":{"type":"string"},"maxProperties":3,"type":"object"}`,
+			"version": `{"const":2,"type":"integer"}`,
+		} {
+			if got := mustJSON(t, props[name]); got != want {
+				t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+			}
+		}
+		if got := mustJSON(t, s["allOf"]); got != `[{"anyOf":[{"required":["phone"]},{"required":["email"]}]},{"anyOf":[{"required":["email"]},{"required":["phone"]}]}]` {
+			t.Errorf("allOf = %s", got)
+		}
+		if got := mustJSON(t, s["required"]); strings.Contains(got, "email") || strings.Contains(got, "phone") {
+			t.Errorf("required_without fields must not be unconditionally required: %s", got)
+		}
+	})
+
+	t.Run("error: required_without unknown field", func(t *testing.T) {
+		type Bad struct {
+			Email string `json:"email" validate:"required_without=Mobile"`
+		}
+		if _, err := Generate[Bad](); err == nil || !strings.Contains(err.Error(), "Mobile") {
+			t.Errorf("expected error naming Mobile, got %v", err)
+		}
+	})
+
+	t.Run("strict mode closes every object", func(t *testing.T) {
+		s, err := Generate[StrictDTO](WithStrict())
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		want := `{"$schema":"http://json-schema.org/draft-07/schema#","additionalProperties":false,"properties":{"meta":{"additionalProperties":{"type":"string"},"type":"object"},"root":{"additionalProperties":false,"properties":{"body":{"type":"string"}},"required":["body"],"type":"object"}},"required":["root","meta"],"type":"object"}`
+		if got := mustJSON(t, s); got != want {
+			t.Errorf("got:  %s\nwant: %s", got, want)
+		}
+	})
+
+	t.Run("error: strict mode rejects permissive fields", func(t *testing.T) {
+		type Untagged struct {
+			Name string
+		}
+		type Loose struct {
+			Extra any `json:"extra"`
+		}
+		type Raw struct {
+			Extra json.RawMessage `json:"extra"`
+		}
+		for name, gen := range map[string]func(...Option) (map[string]any, error){
+			"untagged":  Generate[Untagged],
+			"interface": Generate[Loose],
+			"raw":       Generate[Raw],
+		} {
+			if _, err := gen(WithStrict()); !errors.Is(err, ErrStrict) {
+				t.Errorf("%s: expected ErrStrict, got %v", name, err)
+			}
+			if _, err := gen(); err != nil {
+				t.Errorf("%s: non-strict Generate failed: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("fixed-size arrays", func(t *testing.T) {
+		type Point struct {
+			XY [2]float64 `json:"xy"`
+		}
+		for _, draft := range []string{Draft07, Draft202012} {
+			s, err := Generate[Point](WithDraft(draft))
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			got := mustJSON(t, s["properties"].(map[string]any)["xy"])
+			if got != `{"items":{"type":"number"},"maxItems":2,"minItems":2,"type":"array"}` {
+				t.Errorf("%s: xy = %s", draft, got)
+			}
+		}
+	})
+
+	t.Run("error: unsupported draft", func(t *testing.T) {
+		if _, err := Generate[Node](WithDraft("https://json-schema.org/draft/2019-09/schema")); !errors.Is(err, ErrUnsupportedDraft) {
+			t.Errorf("expected ErrUnsupportedDraft, got %v", err)
+		}
+	})
+
+	t.Run("error: non-string map key", func(t *testing.T) {
+		type Bad struct {
+			Counts map[int]string `json:"counts"`
+		}
+		_, err := Generate[Bad]()
+		if !errors.Is(err, ErrUnsupportedType) || !strings.Contains(err.Error(), "Bad.Counts") {
+			t.Errorf("expected ErrUnsupportedType naming the field, got %v", err)
+		}
+	})
+
+	t.Run("error: top-level not a struct", func(t *testing.T) {
+		if _, err := Generate[map[string]string](); !errors.Is(err, ErrNotStruct) {
+			t.Errorf("expected ErrNotStruct, got %v", err)
+		}
+	})
+
+	t.Run("error: bad tag value", func(t *testing.T) {
+		type Bad struct {
+			Age int `json:"age" validate:"gte=zero"`
+		}
+		if _, err := Generate[Bad](); err == nil {
+			t.Error("expected error for gte=zero")
+		}
+	})
+}
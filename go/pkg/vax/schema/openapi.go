@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OpenAPIVersion is the OpenAPI version written by OpenAPIDocument.
+const OpenAPIVersion = "3.1.0"
+
+const componentsRef = "#/components/schemas/"
+
+// OpenAPIComponents generates each DTO under its component name and returns
+// an OpenAPI 3.1 "components" object ({"schemas": {...}}). Recursive types
+// that Generate would put under $defs (draft 2020-12, the dialect of
+// OpenAPI 3.1) become shared components and every
+// "$ref" points at "#/components/schemas/<name>". Nullable fields use the
+// 3.1 form (type arrays / anyOf with "null"), never 3.0's "nullable: true".
+//
+// Two different schemas under the same component name (a DTO name reused as
+// a definition name, or definitions from different packages) are an error;
+// use WithDefinitionNamer(QualifiedTypeName) to disambiguate.
+func OpenAPIComponents(dtos map[string]reflect.Type, opts ...Option) (map[string]any, error) {
+	schemas := map[string]any{}
+	add := func(name string, s any) error {
+		if prev, ok := schemas[name]; ok {
+			a, _ := json.Marshal(prev)
+			b, _ := json.Marshal(s)
+			if string(a) != string(b) {
+				return fmt.Errorf("schema: component %q defined twice with different schemas", name)
+			}
+		}
+		schemas[name] = s
+		return nil
+	}
+
+	names := make([]string, 0, len(dtos))
+	for name := range dtos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// OpenAPI 3.1 的 schema 方言以 2020-12 為基礎
+		s, err := GenerateType(dtos[name], append(opts[:len(opts):len(opts)], WithDraft(Draft202012))...)
+		if err != nil {
+			return nil, fmt.Errorf("schema: component %q: %w", name, err)
+		}
+		defs, _ := s["$defs"].(map[string]any)
+		delete(s, "$defs")
+		delete(s, "$schema")
+
+		// 遞迴的根型別本身就是 $defs 中的定義：同名時直接用定義內容，避免自己指向自己
+		if ref, ok := s["$ref"].(string); ok && len(s) == 1 && ref == "#/$defs/"+escapePointer(name) {
+			s = defs[name].(map[string]any)
+			delete(defs, name)
+		}
+		if err := add(name, toComponentRefs(s)); err != nil {
+			return nil, err
+		}
+		for defName, def := range defs {
+			if err := add(defName, toComponentRefs(def)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return map[string]any{"schemas": schemas}, nil
+}
+
+// OpenAPIDocument wraps OpenAPIComponents in a minimal OpenAPI 3.1 document
+// (no paths), suitable as a shared components file for the gateway.
+func OpenAPIDocument(title, version string, dtos map[string]reflect.Type, opts ...Option) (map[string]any, error) {
+	components, err := OpenAPIComponents(dtos, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"openapi":    OpenAPIVersion,
+		"info":       map[string]any{"title": title, "version": version},
+		"components": components,
+	}, nil
+}
+
+// toComponentRefs 複製 schema 樹，把 "#/$defs/X" 改成 "#/components/schemas/X"
+func toComponentRefs(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if ref, ok := val.(string); ok && k == "$ref" && strings.HasPrefix(ref, "#/$defs/") {
+				out[k] = componentsRef + strings.TrimPrefix(ref, "#/$defs/")
+				continue
+			}
+			out[k] = toComponentRefs(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = toComponentRefs(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
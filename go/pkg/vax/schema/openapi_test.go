@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type forumThread struct {
+	Title  string  `json:"title"`
+	Root   Node    `json:"root"`
+	Status *Status `json:"status" validate:"nullable"`
+	Parent *Node   `json:"parent" validate:"nullable"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	dtos := map[string]reflect.Type{
+		"Node":          reflect.TypeFor[Node](),
+		"ForumThread":   reflect.TypeFor[forumThread](),
+		"UpdateProfile": reflect.TypeFor[UpdateProfileDTO](),
+	}
+	doc, err := OpenAPIDocument("vax actions", "1.0.0", dtos)
+	if err != nil {
+		t.Fatalf("OpenAPIDocument failed: %v", err)
+	}
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	if len(schemas) != 3 {
+		t.Fatalf("expected 3 components, got %s", mustJSON(t, schemas))
+	}
+
+	node := mustJSON(t, schemas["Node"])
+	if want := `{"properties":{"children":{"additionalProperties":{"$ref":"#/components/schemas/Node"},"type":"object"},"leaf":{"properties":{"parent":{"$ref":"#/components/schemas/Node"}},"type":"object"}},"type":"object"}`; node != want {
+		t.Errorf("Node:\ngot:  %s\nwant: %s", node, want)
+	}
+
+	props := schemas["ForumThread"].(map[string]any)["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"root":   `{"$ref":"#/components/schemas/Node"}`,
+		"status": `{"enum":["pending","done",null],"type":["string","null"]}`,
+		"parent": `{"anyOf":[{"$ref":"#/components/schemas/Node"},{"type":"null"}]}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	all := mustJSON(t, doc)
+	if strings.Contains(all, "$defs") || strings.Contains(all, "$schema") || strings.Contains(all, "nullable") {
+		t.Errorf("leftover draft-07 keywords: %s", all)
+	}
+
+	t.Run("error: conflicting component names", func(t *testing.T) {
+		_, err := OpenAPIComponents(map[string]reflect.Type{
+			"Node":  reflect.TypeFor[UpdateProfileDTO](),
+			"Other": reflect.TypeFor[forumThread](),
+		})
+		if err == nil || !strings.Contains(err.Error(), `"Node"`) {
+			t.Errorf("expected conflict on Node, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"path"
+	"reflect"
+)
+
+// Option configures Generate / GenerateType.
+type Option func(*config)
+
+type config struct {
+	defName func(reflect.Type) string
+	strict  bool
+	draft   string
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{defName: TypeName, draft: Draft07}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithDefinitionNamer sets how recursive types are named under
+// definitions / $defs
+// (default TypeName). Names that collide get a numeric suffix.
+func WithDefinitionNamer(name func(reflect.Type) string) Option {
+	return func(c *config) {
+		c.defName = name
+	}
+}
+
+// WithDraft selects the JSON Schema dialect: Draft07 (default) or
+// Draft202012. The dialect decides "$schema" and where recursive
+// definitions live ("definitions" vs "$defs"); nullable fields use type
+// arrays / anyOf with "null" in both, which is also what OpenAPI 3.1
+// expects. Any other value fails generation with ErrUnsupportedDraft.
+func WithDraft(draft string) Option {
+	return func(c *config) {
+		c.draft = draft
+	}
+}
+
+// WithStrict produces closed schemas: every struct object gets
+// "additionalProperties": false, and generation fails instead of falling
+// back to something permissive when a field has no json tag name (its wire
+// name would silently be the Go name) or is interface{} / json.RawMessage
+// (any value). map[string]T stays open: its keys are data, not fields.
+func WithStrict() Option {
+	return func(c *config) {
+		c.strict = true
+	}
+}
+
+// TypeName names a definition after the bare Go type name ("Comment").
+func TypeName(t reflect.Type) string {
+	return t.Name()
+}
+
+// QualifiedTypeName prefixes the package name ("forum.Comment"), for schemas
+// that mix same-named types from several packages.
+func QualifiedTypeName(t reflect.Type) string {
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ErrNotRegistered is returned when no DTO is registered under a name.
+var ErrNotRegistered = errors.New("schema: not registered")
+
+// Registry holds DTO schemas generated once at registration and served as
+// immutable, precomputed documents (no reflection per request).
+// Safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	opts    []Option
+	entries map[string]*entry
+}
+
+type entry struct {
+	t      reflect.Type
+	schema map[string]any // 不外流；Schema() 回傳副本
+	json   []byte
+}
+
+// NewRegistry returns an empty Registry; opts apply to every registered DTO.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{opts: opts, entries: map[string]*entry{}}
+}
+
+// Register generates T's schema and stores it under name (usually the
+// action type). Registering a name twice is an error.
+func Register[T any](r *Registry, name string) error {
+	return r.RegisterType(name, reflect.TypeFor[T]())
+}
+
+// MustRegister is Register that panics on error (for init-time registration).
+func MustRegister[T any](r *Registry, name string) {
+	if err := Register[T](r, name); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterType is Register for a reflect.Type known only at run time.
+func (r *Registry) RegisterType(name string, t reflect.Type) error {
+	s, err := GenerateType(t, r.opts...)
+	if err != nil {
+		return fmt.Errorf("schema: register %q: %w", name, err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("schema: register %q: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.entries[name]; dup {
+		return fmt.Errorf("schema: %q already registered", name)
+	}
+	r.entries[name] = &entry{t: t, schema: s, json: b}
+	return nil
+}
+
+// JSON returns the precomputed schema document for name. The slice is
+// shared: callers must not modify it.
+func (r *Registry) JSON(name string) ([]byte, error) {
+	e, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.json, nil
+}
+
+// Schema returns a copy of the schema registered under name.
+func (r *Registry) Schema(name string) (map[string]any, error) {
+	e, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return cloneValue(e.schema).(map[string]any), nil
+}
+
+// Type returns the Go type registered under name.
+func (r *Registry) Type(name string) (reflect.Type, error) {
+	e, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.t, nil
+}
+
+// Names lists the registered names (sorted).
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenAPI returns an OpenAPI 3.1 document with every registered DTO as a
+// component (see OpenAPIDocument).
+func (r *Registry) OpenAPI(title, version string) (map[string]any, error) {
+	r.mu.RLock()
+	dtos := make(map[string]reflect.Type, len(r.entries))
+	for name, e := range r.entries {
+		dtos[name] = e.t
+	}
+	r.mu.RUnlock()
+	return OpenAPIDocument(title, version, dtos, r.opts...)
+}
+
+func (r *Registry) lookup(name string) (*entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotRegistered, name)
+	}
+	return e, nil
+}
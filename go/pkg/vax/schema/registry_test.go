@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(WithStrict())
+	MustRegister[UpdateProfileDTO](r, "update_profile")
+	MustRegister[Node](r, "tree.update")
+
+	t.Run("serves precomputed documents", func(t *testing.T) {
+		b, err := r.JSON("update_profile")
+		if err != nil {
+			t.Fatalf("JSON failed: %v", err)
+		}
+		want, _ := Generate[UpdateProfileDTO](WithStrict())
+		if string(b) != mustJSON(t, want) {
+			t.Errorf("registry document differs from Generate:\n%s", b)
+		}
+		if got := r.Names(); !reflect.DeepEqual(got, []string{"tree.update", "update_profile"}) {
+			t.Errorf("Names = %v", got)
+		}
+		if typ, _ := r.Type("tree.update"); typ != reflect.TypeFor[Node]() {
+			t.Errorf("Type = %v", typ)
+		}
+	})
+
+	t.Run("Schema returns a copy", func(t *testing.T) {
+		s, _ := r.Schema("update_profile")
+		s["properties"].(map[string]any)["name"].(map[string]any)["maxLength"] = 1
+		again, _ := r.Schema("update_profile")
+		if again["properties"].(map[string]any)["name"].(map[string]any)["maxLength"] != 50 {
+			t.Error("caller mutation leaked into the registry")
+		}
+	})
+
+	t.Run("OpenAPI covers every registered DTO", func(t *testing.T) {
+		doc, err := r.OpenAPI("vax", "1")
+		if err != nil {
+			t.Fatalf("OpenAPI failed: %v", err)
+		}
+		schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+		for _, name := range []string{"update_profile", "tree.update", "Node"} {
+			if _, ok := schemas[name]; !ok {
+				t.Errorf("missing component %q", name)
+			}
+		}
+	})
+
+	t.Run("concurrent reads", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b, _ := r.JSON("update_profile")
+				var m map[string]any
+				if err := json.Unmarshal(b, &m); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("error: unknown name", func(t *testing.T) {
+		if _, err := r.JSON("nope"); !errors.Is(err, ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered, got %v", err)
+		}
+	})
+
+	t.Run("error: duplicate name", func(t *testing.T) {
+		if err := Register[Node](r, "update_profile"); err == nil {
+			t.Error("expected duplicate error")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	typeMappingsMu sync.RWMutex
+	typeMappings   = map[reflect.Type]map[string]any{}
+)
+
+// RegisterTypeMapping sets the schema emitted for t (and *t), for
+// third-party types whose JSON form reflection cannot see:
+//
+//	schema.RegisterTypeMapping(reflect.TypeFor[uuid.UUID](), map[string]any{"type": "string", "format": "uuid"})
+//	schema.RegisterTypeMapping(reflect.TypeFor[decimal.Decimal](), map[string]any{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?$`})
+//
+// A mapping takes precedence over the built-in time / net / url handling.
+// validate tags still apply on top of it. The map is copied on
+// registration and on every use. Like RegisterFormat it is meant for init
+// time and panics on duplicates.
+func RegisterTypeMapping(t reflect.Type, s map[string]any) {
+	if t == nil || s == nil {
+		panic("schema: RegisterTypeMapping with nil type or schema")
+	}
+	typeMappingsMu.Lock()
+	defer typeMappingsMu.Unlock()
+	if _, dup := typeMappings[t]; dup {
+		panic(fmt.Sprintf("schema: RegisterTypeMapping called twice for %s", t))
+	}
+	typeMappings[t] = cloneValue(s).(map[string]any)
+	resetCache()
+}
+
+func lookupTypeMapping(t reflect.Type) (map[string]any, bool) {
+	typeMappingsMu.RLock()
+	defer typeMappingsMu.RUnlock()
+	s, ok := typeMappings[t]
+	if !ok {
+		return nil, false
+	}
+	return cloneValue(s).(map[string]any), true
+}
+
+// cloneValue 深拷貝 schema 樹（map / slice），避免呼叫端或 applyValidation 改到共用的值
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = cloneValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = cloneValue(val)
+		}
+		return out
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
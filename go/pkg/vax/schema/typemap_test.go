@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// 模擬第三方型別：反射看到的結構與 JSON 輸出不同
+type fakeUUID [16]byte
+
+type fakeDecimal struct {
+	value string
+	exp   int32
+}
+
+type fakeDate struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+func init() {
+	RegisterTypeMapping(reflect.TypeFor[fakeUUID](), map[string]any{"type": "string", "format": "uuid"})
+	RegisterTypeMapping(reflect.TypeFor[fakeDecimal](), map[string]any{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?$`})
+	RegisterTypeMapping(reflect.TypeFor[fakeDate](), map[string]any{"type": "string", "format": "date"})
+}
+
+func TestTypeMapping(t *testing.T) {
+	type Invoice struct {
+		ID     fakeUUID      `json:"id"`
+		Amount fakeDecimal   `json:"amount"`
+		Due    fakeDate      `json:"due"`
+		Paid   *fakeDate     `json:"paid" validate:"nullable"`
+		Lines  []fakeDecimal `json:"lines"`
+	}
+	s, err := Generate[Invoice]()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	props := s["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"id":     `{"format":"uuid","type":"string"}`,
+		"amount": `{"pattern":"^-?[0-9]+(\\.[0-9]+)?$","type":"string"}`,
+		"due":    `{"format":"date","type":"string"}`,
+		"paid":   `{"format":"date","type":["string","null"]}`,
+		"lines":  `{"items":{"pattern":"^-?[0-9]+(\\.[0-9]+)?$","type":"string"},"type":"array"}`,
+	} {
+		if got := mustJSON(t, props[name]); got != want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", name, got, want)
+		}
+	}
+
+	t.Run("registered schema is not mutated", func(t *testing.T) {
+		m, _ := lookupTypeMapping(reflect.TypeFor[fakeDate]())
+		if got := mustJSON(t, m); got != `{"format":"date","type":"string"}` {
+			t.Errorf("mapping changed: %s", got)
+		}
+	})
+
+	t.Run("FieldSpec bridge", func(t *testing.T) {
+		type Ref struct {
+			ID fakeUUID `json:"id"`
+		}
+		spec, err := GenerateFieldSpec[Ref]()
+		if err != nil {
+			t.Fatalf("GenerateFieldSpec failed: %v", err)
+		}
+		if got := mustJSON(t, spec["id"]); got != `{"type":"string","format":"uuid"}` {
+			t.Errorf("id = %s", got)
+		}
+
+		type Money struct {
+			Amount fakeDecimal `json:"amount"`
+		}
+		if _, err := GenerateFieldSpec[Money](); !errors.Is(err, ErrNoFieldSpec) {
+			t.Errorf("expected ErrNoFieldSpec for pattern mapping, got %v", err)
+		}
+	})
+
+	t.Run("error: duplicate registration panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		RegisterTypeMapping(reflect.TypeFor[fakeUUID](), map[string]any{"type": "string"})
+	})
+}
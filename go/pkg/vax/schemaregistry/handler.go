@@ -0,0 +1,106 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"vax/pkg/vax/cbor"
+)
+
+// DefaultCacheControl is the Cache-Control of schema documents unless
+// WithCacheControl overrides it: caches may reuse a document briefly and
+// then revalidate it with If-None-Match, which costs a 304 and no body.
+const DefaultCacheControl = "public, max-age=300, must-revalidate"
+
+// HandlerOption configures Handler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	cacheControl string
+}
+
+// WithCacheControl sets the Cache-Control header of documents ("" omits it).
+func WithCacheControl(v string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.cacheControl = v
+	}
+}
+
+// Handler serves the registry over HTTP (mount it with http.StripPrefix):
+//
+//	GET /          → {"actions": ["a", "b"]}
+//	GET /{action}  → the action's JSON Schema document
+//	GET /?action=a → same, for clients of the old HandleGetSchema
+//
+// Unknown actions get 404 and other methods 405, both with a JSON
+// {"error": "..."} body. Documents are served from their precomputed JCS
+// bytes with a strong ETag (see Registry.ETag) and Cache-Control, or as
+// deterministic CBOR when the Accept header prefers application/cbor (the
+// ETag then carries a "+cbor" suffix); a matching If-None-Match gets 304
+// Not Modified. The listing changes as
+// actions register and is sent with Cache-Control: no-cache.
+func (r *Registry) Handler(opts ...HandlerOption) http.Handler {
+	cfg := handlerConfig{cacheControl: DefaultCacheControl}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		action := strings.Trim(req.URL.Path, "/")
+		if action == "" {
+			action = req.URL.Query().Get("action")
+		}
+		if action == "" {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"actions": r.Actions()})
+			return
+		}
+
+		e, err := r.lookup(action)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "unknown action "+action)
+			return
+		}
+		doc, etag, contentType := e.doc, e.etag, "application/schema+json"
+		if cbor.Accepts(req.Header.Get("Accept")) {
+			doc, etag, contentType = e.cbor, e.cborETag, cbor.MediaType
+		}
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Add("Vary", "Accept")
+		if cfg.cacheControl != "" {
+			h.Set("Cache-Control", cfg.cacheControl)
+		}
+		if etagMatch(req.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		h.Set("Content-Type", contentType)
+		_, _ = w.Write(doc)
+	})
+}
+
+// etagMatch 依 RFC 9110 §13.1.2 比對 If-None-Match：可為 "*" 或以逗號分隔的清單，
+// GET 採弱比較（忽略 W/ 前綴）
+func etagMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,154 @@
+package schemaregistry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/jcs"
+)
+
+func TestHandler(t *testing.T) {
+	r := newTestRegistry(t)
+	srv := httptest.NewServer(http.StripPrefix("/schemas", r.Handler()))
+	defer srv.Close()
+
+	get := func(path string, header ...string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("listing", func(t *testing.T) {
+		resp, body := get("/schemas/")
+		if resp.StatusCode != 200 || strings.TrimSpace(body) != `{"actions":["transfer","update_profile"]}` {
+			t.Errorf("unexpected listing %d %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("document by path and by query", func(t *testing.T) {
+		resp, body := get("/schemas/update_profile")
+		if resp.StatusCode != 200 || !strings.Contains(body, `"email"`) {
+			t.Fatalf("unexpected %d %s", resp.StatusCode, body)
+		}
+		etag := resp.Header.Get("ETag")
+		if _, legacy := get("/schemas?action=update_profile"); legacy != body {
+			t.Error("query form differs from path form")
+		}
+		if resp, _ := get("/schemas/update_profile", "If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+			t.Errorf("expected 304, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ETag is the JCS fingerprint and is cacheable", func(t *testing.T) {
+		resp, body := get("/schemas/transfer")
+		sum := sha256.Sum256([]byte(body))
+		if want := `"` + hex.EncodeToString(sum[:]) + `"`; resp.Header.Get("ETag") != want {
+			t.Errorf("ETag = %s, want %s", resp.Header.Get("ETag"), want)
+		}
+		if etag, _ := r.ETag("transfer"); etag != resp.Header.Get("ETag") {
+			t.Errorf("Registry.ETag = %s", etag)
+		}
+		if canonical, _ := jcs.CanonicalizeJSON([]byte(body)); string(canonical) != body {
+			t.Error("document is not served in JCS form")
+		}
+		if cc := resp.Header.Get("Cache-Control"); cc != DefaultCacheControl {
+			t.Errorf("Cache-Control = %q", cc)
+		}
+		if resp, _ := get("/schemas/"); resp.Header.Get("Cache-Control") != "no-cache" {
+			t.Error("listing must not be cached without revalidation")
+		}
+	})
+
+	t.Run("If-None-Match lists, wildcard and weak tags", func(t *testing.T) {
+		resp, _ := get("/schemas/transfer")
+		etag := resp.Header.Get("ETag")
+		for header, want := range map[string]int{
+			`"stale", ` + etag: http.StatusNotModified,
+			"*":                http.StatusNotModified,
+			"W/" + etag:        http.StatusNotModified,
+			`"stale"`:          http.StatusOK,
+		} {
+			resp, body := get("/schemas/transfer", "If-None-Match", header)
+			if resp.StatusCode != want {
+				t.Errorf("If-None-Match %s: got %d, want %d", header, resp.StatusCode, want)
+			}
+			if want == http.StatusNotModified && (body != "" || resp.Header.Get("ETag") != etag || resp.Header.Get("Cache-Control") == "") {
+				t.Errorf("If-None-Match %s: 304 must carry ETag and Cache-Control and no body", header)
+			}
+		}
+	})
+
+	t.Run("CBOR by content negotiation", func(t *testing.T) {
+		jsonResp, jsonBody := get("/schemas/transfer")
+		resp, body := get("/schemas/transfer", "Accept", "application/cbor")
+		if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != cbor.MediaType || !cbor.IsDeterministic([]byte(body)) {
+			t.Fatalf("unexpected %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if asJSON, _ := cbor.ToJSON([]byte(body)); !jsonEqual(asJSON, []byte(jsonBody)) {
+			t.Errorf("CBOR document differs from JSON: %s", asJSON)
+		}
+		etag := resp.Header.Get("ETag")
+		if etag != strings.TrimSuffix(jsonResp.Header.Get("ETag"), `"`)+`+cbor"` {
+			t.Errorf("CBOR ETag = %s", etag)
+		}
+		if resp.Header.Get("Vary") != "Accept" {
+			t.Error("missing Vary: Accept")
+		}
+		if resp, _ := get("/schemas/transfer", "If-None-Match", etag); resp.StatusCode != http.StatusOK {
+			t.Error("CBOR ETag must not validate the JSON representation")
+		}
+	})
+
+	t.Run("WithCacheControl", func(t *testing.T) {
+		srv := httptest.NewServer(r.Handler(WithCacheControl("no-store")))
+		defer srv.Close()
+		resp, err := http.Get(srv.URL + "/transfer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("Cache-Control = %q", cc)
+		}
+	})
+
+	t.Run("error: unknown action", func(t *testing.T) {
+		resp, body := get("/schemas/nope")
+		if resp.StatusCode != http.StatusNotFound || !strings.Contains(body, `"error"`) {
+			t.Errorf("expected 404 with JSON error, got %d %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("error: method not allowed", func(t *testing.T) {
+		resp, err := http.Post(srv.URL+"/schemas/transfer", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, HEAD" {
+			t.Errorf("expected 405, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func jsonEqual(a, b []byte) bool {
+	var x, y any
+	return json.Unmarshal(a, &x) == nil && json.Unmarshal(b, &y) == nil && reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,275 @@
+package schemaregistry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"vax/pkg/vax/api"
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/schema"
+)
+
+// OpenAPIInfo describes the API documented by Registry.OpenAPI. Empty paths
+// default to "/schemas" (where Handler is mounted) and "/actions" (where
+// api.HandleSubmitAction is mounted).
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	SchemasPath string
+	SubmitPath  string
+}
+
+const componentsRef = "#/components/schemas/"
+
+// OpenAPI returns an OpenAPI 3.1 document describing the schema endpoints
+// and the submission endpoint. Every registered action becomes a component
+// (its definitions as "<action>.<name>"), together with an "SAE.<action>"
+// envelope whose sdto is that component; "Envelope" selects among them by
+// action_type and is the contentSchema of SubmitRequest.sae.
+func (r *Registry) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	if info.SchemasPath == "" {
+		info.SchemasPath = "/schemas"
+	}
+	if info.SubmitPath == "" {
+		info.SubmitPath = "/actions"
+	}
+	components, err := schema.OpenAPIComponents(map[string]reflect.Type{
+		"SubmitRequest": reflect.TypeFor[api.SubmitRequest](),
+		"Receipt":       reflect.TypeFor[api.Receipt](),
+		"ErrorResponse": reflect.TypeFor[api.ErrorResponse](),
+		"BaseEnvelope":  reflect.TypeFor[sae.Envelope](),
+	})
+	if err != nil {
+		return nil, err
+	}
+	schemas := components["schemas"].(map[string]any)
+
+	actions := r.Actions()
+	variants := make([]any, 0, len(actions))
+	mapping := map[string]any{}
+	for _, action := range actions {
+		doc, err := r.Document(action)
+		if err != nil {
+			return nil, err // 與 Actions() 之間不會移除，保險起見
+		}
+		var s map[string]any
+		if err := json.Unmarshal(doc, &s); err != nil {
+			return nil, err
+		}
+		name := componentName(action)
+		envName := "SAE." + name
+		hoisted := hoistDefs(name, s)
+		hoisted[name] = s
+		hoisted[envName] = nil
+		for compName, def := range hoisted {
+			if _, dup := schemas[compName]; dup {
+				return nil, fmt.Errorf("schemaregistry: openapi: component %q of action %q is already defined", compName, action)
+			}
+			schemas[compName] = def
+		}
+		schemas[envName] = map[string]any{
+			"allOf": []any{
+				map[string]any{"$ref": componentsRef + "BaseEnvelope"},
+				map[string]any{
+					"properties": map[string]any{
+						"action_type": map[string]any{"const": action},
+						"sdto":        map[string]any{"$ref": componentsRef + name},
+					},
+				},
+			},
+		}
+		variants = append(variants, map[string]any{"$ref": componentsRef + envName})
+		mapping[action] = componentsRef + envName
+	}
+	envelope := map[string]any{"$ref": componentsRef + "BaseEnvelope"}
+	if len(variants) > 0 {
+		envelope = map[string]any{
+			"oneOf":         variants,
+			"discriminator": map[string]any{"propertyName": "action_type", "mapping": mapping},
+		}
+	}
+	schemas["Envelope"] = envelope
+
+	// sae 欄位是 base64 的 SAE bytes，內容即 Envelope
+	submit := schemas["SubmitRequest"].(map[string]any)
+	submit["properties"].(map[string]any)["sae"] = map[string]any{
+		"type":             "string",
+		"contentEncoding":  "base64",
+		"contentMediaType": "application/json",
+		"contentSchema":    map[string]any{"$ref": componentsRef + "Envelope"},
+	}
+
+	doc := map[string]any{
+		"openapi": schema.OpenAPIVersion,
+		"info":    map[string]any{"title": info.Title, "version": info.Version},
+		"paths": map[string]any{
+			info.SchemasPath + "/":         listingPath(),
+			info.SchemasPath + "/{action}": documentPath(actions),
+			info.SubmitPath:                submitPath(),
+		},
+		"components": components,
+	}
+	return jcs.Marshal(doc)
+}
+
+// OpenAPIHandler serves OpenAPI(info) (mount it at /openapi.json). The
+// document is rebuilt per request so later registrations show up; it has
+// an ETag and honours If-None-Match like Handler.
+func (r *Registry) OpenAPIHandler(info OpenAPIInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		doc, err := r.OpenAPI(info)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "openapi: "+err.Error())
+			return
+		}
+		sum := sha256.Sum256(doc)
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatch(req.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+}
+
+var invalidComponentChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// componentName OpenAPI 的 component 名稱只允許 [A-Za-z0-9._-]
+func componentName(action string) string {
+	return invalidComponentChars.ReplaceAllString(action, "_")
+}
+
+// hoistDefs 移除 s 的 $schema / definitions / $defs，回傳改名為 "<prefix>.<name>"
+// 的定義，並把 s 與定義中的本地 $ref 改指向 components
+func hoistDefs(prefix string, s map[string]any) map[string]any {
+	delete(s, "$schema")
+	hoisted := map[string]any{}
+	for _, keyword := range []string{"definitions", "$defs"} {
+		defs, _ := s[keyword].(map[string]any)
+		delete(s, keyword)
+		for name, def := range defs {
+			hoisted[prefix+"."+componentName(name)] = def
+		}
+	}
+	rewriteRefs(prefix, s)
+	for _, def := range hoisted {
+		rewriteRefs(prefix, def)
+	}
+	return hoisted
+}
+
+func rewriteRefs(prefix string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				for _, local := range []string{"#/definitions/", "#/$defs/"} {
+					if strings.HasPrefix(ref, local) {
+						name := unescapePointer(strings.TrimPrefix(ref, local))
+						v[k] = componentsRef + prefix + "." + componentName(name)
+					}
+				}
+				continue
+			}
+			rewriteRefs(prefix, val)
+		}
+	case []any:
+		for _, val := range v {
+			rewriteRefs(prefix, val)
+		}
+	}
+}
+
+func unescapePointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+func jsonContent(ref string) map[string]any {
+	return map[string]any{"schema": map[string]any{"$ref": componentsRef + ref}}
+}
+
+func errorResponse(desc string) map[string]any {
+	return map[string]any{
+		"description": desc,
+		"content":     map[string]any{"application/json": jsonContent("ErrorResponse"), cbor.MediaType: jsonContent("ErrorResponse")},
+	}
+}
+
+func listingPath() map[string]any {
+	return map[string]any{"get": map[string]any{
+		"operationId": "listSchemas",
+		"summary":     "List registered actions",
+		"responses": map[string]any{"200": map[string]any{
+			"description": "Registered action names",
+			"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"actions": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+			}}},
+		}},
+	}}
+}
+
+func documentPath(actions []string) map[string]any {
+	param := map[string]any{"type": "string"}
+	if len(actions) > 0 {
+		param["enum"] = actions
+	}
+	return map[string]any{"get": map[string]any{
+		"operationId": "getSchema",
+		"summary":     "JSON Schema of an action's sdto",
+		"parameters": []any{map[string]any{
+			"name": "action", "in": "path", "required": true, "schema": param,
+		}},
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "JSON Schema document (JCS bytes, or deterministic CBOR on Accept: application/cbor)",
+				"headers":     map[string]any{"ETag": map[string]any{"schema": map[string]any{"type": "string"}}},
+				"content": map[string]any{
+					"application/schema+json": map[string]any{"schema": map[string]any{"type": "object"}},
+					cbor.MediaType:            map[string]any{"schema": map[string]any{"type": "object"}},
+				},
+			},
+			"304": map[string]any{"description": "Not modified (If-None-Match)"},
+			"404": map[string]any{"description": "Unknown action"},
+		},
+	}}
+}
+
+func submitPath() map[string]any {
+	return map[string]any{"post": map[string]any{
+		"operationId": "submitAction",
+		"summary":     "Verify an SAE and advance the actor's chain",
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": jsonContent("SubmitRequest"), cbor.MediaType: jsonContent("SubmitRequest")},
+		},
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "Accepted",
+				"content":     map[string]any{"application/json": jsonContent("Receipt"), cbor.MediaType: jsonContent("Receipt")},
+			},
+			"400": errorResponse("invalid_input"),
+			"401": errorResponse("invalid_signature"),
+			"404": errorResponse("unknown_actor"),
+			"409": errorResponse("chain_conflict"),
+			"422": errorResponse("invalid_sdto, unknown_schema or sai_mismatch"),
+			"429": errorResponse("rate_limited"),
+		},
+	}}
+}
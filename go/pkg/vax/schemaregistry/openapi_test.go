@@ -0,0 +1,115 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Node struct {
+	Value    int     `json:"value"`
+	Children []*Node `json:"children"`
+}
+
+type TreeDTO struct {
+	Root Node `json:"root"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("paths and per-action components", func(t *testing.T) {
+		r := newTestRegistry(t)
+		b, err := r.OpenAPI(OpenAPIInfo{Title: "VAX", Version: "1.0.0", SubmitPath: "/v1/actions"})
+		if err != nil {
+			t.Fatalf("OpenAPI failed: %v", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatalf("not JSON: %v", err)
+		}
+		if doc["openapi"] != "3.1.0" {
+			t.Errorf("openapi = %v", doc["openapi"])
+		}
+		paths := doc["paths"].(map[string]any)
+		for _, p := range []string{"/schemas/", "/schemas/{action}", "/v1/actions"} {
+			if _, ok := paths[p]; !ok {
+				t.Errorf("missing path %s", p)
+			}
+		}
+		schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+		for _, name := range []string{"SubmitRequest", "Receipt", "ErrorResponse", "BaseEnvelope", "Envelope",
+			"transfer", "update_profile", "SAE.transfer", "SAE.update_profile"} {
+			if _, ok := schemas[name]; !ok {
+				t.Errorf("missing component %s", name)
+			}
+		}
+		mapping := schemas["Envelope"].(map[string]any)["discriminator"].(map[string]any)["mapping"].(map[string]any)
+		if mapping["transfer"] != "#/components/schemas/SAE.transfer" {
+			t.Errorf("discriminator mapping = %v", mapping)
+		}
+		sae := schemas["SubmitRequest"].(map[string]any)["properties"].(map[string]any)["sae"].(map[string]any)
+		if sae["contentSchema"].(map[string]any)["$ref"] != "#/components/schemas/Envelope" {
+			t.Errorf("sae = %v", sae)
+		}
+		if _, ok := schemas["transfer"].(map[string]any)["$schema"]; ok {
+			t.Error("$schema must be removed from components")
+		}
+	})
+
+	t.Run("definitions are hoisted with the action prefix", func(t *testing.T) {
+		r := New()
+		if err := RegisterDTO[TreeDTO](r, "tree.put"); err != nil {
+			t.Fatal(err)
+		}
+		b, err := r.OpenAPI(OpenAPIInfo{})
+		if err != nil {
+			t.Fatalf("OpenAPI failed: %v", err)
+		}
+		s := string(b)
+		if !strings.Contains(s, `"tree.put.Node":`) || !strings.Contains(s, `"$ref":"#/components/schemas/tree.put.Node"`) {
+			t.Errorf("recursive definition not hoisted: %s", s)
+		}
+		if strings.Contains(s, `"#/definitions/`) || strings.Contains(s, `"#/$defs/`) {
+			t.Error("local refs left in the document")
+		}
+	})
+
+	t.Run("error: action name collides with a fixed component", func(t *testing.T) {
+		r := New()
+		if err := RegisterDTO[UpdateProfileDTO](r, "Receipt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.OpenAPI(OpenAPIInfo{}); err == nil {
+			t.Error("expected collision error")
+		}
+	})
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := newTestRegistry(t)
+	srv := httptest.NewServer(r.OpenAPIHandler(OpenAPIInfo{Title: "VAX", Version: "1"}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" || !strings.Contains(string(body), `"openapi":"3.1.0"`) {
+		t.Fatalf("unexpected %d %s", resp.StatusCode, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,249 @@
+// Package schemaregistry serves the JSON Schema of every registered action.
+// Actions register a Go DTO type or an sdto FieldSpec schema at startup, or
+// are loaded in bulk (a directory of schema files, a database table) through
+// a Loader; Handler exposes them over HTTP.
+package schemaregistry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"vax/pkg/vax/cbor"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/schema"
+	"vax/pkg/vax/sdto"
+)
+
+// Error codes
+var (
+	ErrUnknownAction = errors.New("schemaregistry: unknown action")
+	ErrDuplicate     = errors.New("schemaregistry: action already registered")
+)
+
+// Loader supplies FieldSpec schemas keyed by action name, e.g. from a
+// database table. DirLoader reads them from files.
+type Loader interface {
+	LoadSchemas() (map[string]map[string]sdto.FieldSpec, error)
+}
+
+// Registry maps action names to precomputed JSON Schema documents.
+// Safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	opts    []schema.Option
+	actions map[string]*entry
+}
+
+type entry struct {
+	doc      []byte
+	etag     string
+	cbor     []byte // doc 的 deterministic CBOR 形式
+	cborETag string
+	spec     map[string]sdto.FieldSpec // 只有以 FieldSpec 註冊時才有
+}
+
+// New returns an empty Registry; opts apply to DTO registrations.
+func New(opts ...schema.Option) *Registry {
+	return &Registry{opts: opts, actions: map[string]*entry{}}
+}
+
+// RegisterDTO registers the schema generated from struct T under action.
+func RegisterDTO[T any](r *Registry, action string) error {
+	return r.RegisterType(action, reflect.TypeFor[T]())
+}
+
+// RegisterType is RegisterDTO for a reflect.Type known only at run time.
+func (r *Registry) RegisterType(action string, t reflect.Type) error {
+	s, err := schema.GenerateType(t, r.opts...)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: %s: %w", action, err)
+	}
+	return r.add(action, s, nil)
+}
+
+// RegisterFieldSpec registers an sdto schema under action; it is checked
+// with sdto.ValidateSchema and served as sdto.JSONSchema.
+func (r *Registry) RegisterFieldSpec(action string, spec map[string]sdto.FieldSpec) error {
+	if err := sdto.ValidateSchema(spec); err != nil {
+		return fmt.Errorf("schemaregistry: %s: %w", action, err)
+	}
+	return r.add(action, sdto.JSONSchema(spec), spec)
+}
+
+// Load registers every schema returned by l. Nothing is registered when
+// any of them is invalid or already registered.
+func (r *Registry) Load(l Loader) error {
+	all, err := l.LoadSchemas()
+	if err != nil {
+		return fmt.Errorf("schemaregistry: load: %w", err)
+	}
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// 先全部檢查，再一次寫入（避免載入一半）
+	var errs []error
+	for _, name := range names {
+		if err := sdto.ValidateSchema(all[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("schemaregistry: load: %w", errors.Join(errs...))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if _, dup := r.actions[name]; dup {
+			return fmt.Errorf("%w: %s", ErrDuplicate, name)
+		}
+	}
+	for _, name := range names {
+		e, err := newEntry(sdto.JSONSchema(all[name]), all[name])
+		if err != nil {
+			return fmt.Errorf("schemaregistry: %s: %w", name, err)
+		}
+		r.actions[name] = e
+	}
+	return nil
+}
+
+// Document returns the JSON Schema document of action in JCS form. The
+// slice is shared: callers must not modify it.
+func (r *Registry) Document(action string) ([]byte, error) {
+	e, err := r.lookup(action)
+	if err != nil {
+		return nil, err
+	}
+	return e.doc, nil
+}
+
+// ETag returns the strong entity tag of action's document (quoted hex
+// SHA-256 of its JCS bytes), as sent by Handler.
+func (r *Registry) ETag(action string) (string, error) {
+	e, err := r.lookup(action)
+	if err != nil {
+		return "", err
+	}
+	return e.etag, nil
+}
+
+// FieldSpec returns the sdto schema of an action registered with
+// RegisterFieldSpec or Load (nil for DTO registrations).
+func (r *Registry) FieldSpec(action string) (map[string]sdto.FieldSpec, error) {
+	e, err := r.lookup(action)
+	if err != nil {
+		return nil, err
+	}
+	return e.spec, nil
+}
+
+// Actions lists the registered action names (sorted).
+func (r *Registry) Actions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.actions))
+	for name := range r.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) add(action string, doc map[string]any, spec map[string]sdto.FieldSpec) error {
+	if action == "" {
+		return fmt.Errorf("schemaregistry: empty action name")
+	}
+	e, err := newEntry(doc, spec)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: %s: %w", action, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.actions[action]; dup {
+		return fmt.Errorf("%w: %s", ErrDuplicate, action)
+	}
+	r.actions[action] = e
+	return nil
+}
+
+func (r *Registry) lookup(action string) (*entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.actions[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAction, action)
+	}
+	return e, nil
+}
+
+// newEntry 文件以 JCS 與 deterministic CBOR 各序列化一次；ETag 為其指紋
+// hex(SHA256(JCS(doc)))，內容相同的文件不論註冊方式、重啟與否都得到相同 ETag
+func newEntry(doc map[string]any, spec map[string]sdto.FieldSpec) (*entry, error) {
+	b, err := jcs.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	fp := hex.EncodeToString(sum[:])
+	var generic any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	c, err := cbor.Encode(generic)
+	if err != nil {
+		return nil, err
+	}
+	// 兩種表示共用 JCS 指紋，CBOR 加後綴以區分（strong ETag 需依表示而異）
+	return &entry{doc: b, etag: `"` + fp + `"`, cbor: c, cborETag: `"` + fp + `+cbor"`, spec: spec}, nil
+}
+
+// DirLoader loads <action>.json files from the root of fsys (use
+// os.DirFS for a directory). Each file holds an sdto FieldSpec schema in
+// its wire form and is parsed with sdto.ParseSchemaStrict.
+func DirLoader(fsys fs.FS) Loader {
+	return dirLoader{fsys}
+}
+
+type dirLoader struct {
+	fsys fs.FS
+}
+
+func (d dirLoader) LoadSchemas() (map[string]map[string]sdto.FieldSpec, error) {
+	files, err := fs.Glob(d.fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]map[string]sdto.FieldSpec, len(files))
+	for _, file := range files {
+		b, err := fs.ReadFile(d.fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		spec, err := sdto.ParseSchemaStrict(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		out[strings.TrimSuffix(path.Base(file), ".json")] = spec
+	}
+	return out, nil
+}
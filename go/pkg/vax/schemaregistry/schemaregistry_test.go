@@ -0,0 +1,90 @@
+package schemaregistry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"vax/pkg/vax/sdto"
+)
+
+type UpdateProfileDTO struct {
+	Name  string `json:"name" validate:"required,min=1,max=50"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := New()
+	if err := RegisterDTO[UpdateProfileDTO](r, "update_profile"); err != nil {
+		t.Fatalf("RegisterDTO failed: %v", err)
+	}
+	spec := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+	if err := r.RegisterFieldSpec("transfer", spec); err != nil {
+		t.Fatalf("RegisterFieldSpec failed: %v", err)
+	}
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	r := newTestRegistry(t)
+
+	t.Run("documents by source", func(t *testing.T) {
+		doc, err := r.Document("update_profile")
+		if err != nil || !strings.Contains(string(doc), `"maxLength":50`) {
+			t.Errorf("unexpected DTO document %s, %v", doc, err)
+		}
+		doc, err = r.Document("transfer")
+		if err != nil || !strings.Contains(string(doc), `"maximum":1000`) {
+			t.Errorf("unexpected FieldSpec document %s, %v", doc, err)
+		}
+		if spec, _ := r.FieldSpec("transfer"); spec["amount"].Type != "number" {
+			t.Errorf("FieldSpec not kept: %+v", spec)
+		}
+		if got := strings.Join(r.Actions(), ","); got != "transfer,update_profile" {
+			t.Errorf("Actions = %s", got)
+		}
+	})
+
+	t.Run("directory loader", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"user.create.json": {Data: []byte(`{"name":{"type":"string","min":"1","max":"20"}}`)},
+			"notes.txt":        {Data: []byte("ignored")},
+		}
+		if err := r.Load(DirLoader(fsys)); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if _, err := r.Document("user.create"); err != nil {
+			t.Errorf("loaded action missing: %v", err)
+		}
+	})
+
+	t.Run("error: invalid file loads nothing", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"a.json": {Data: []byte(`{"n":{"type":"string"}}`)},
+			"b.json": {Data: []byte(`{"n":{"type":"nope"}}`)},
+		}
+		if err := r.Load(DirLoader(fsys)); err == nil {
+			t.Fatal("expected load error")
+		}
+		if _, err := r.Document("a"); !errors.Is(err, ErrUnknownAction) {
+			t.Errorf("partial load: %v", err)
+		}
+	})
+
+	t.Run("error: duplicates and unknown", func(t *testing.T) {
+		if err := RegisterDTO[UpdateProfileDTO](r, "transfer"); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("expected ErrDuplicate, got %v", err)
+		}
+		if _, err := r.Document("nope"); !errors.Is(err, ErrUnknownAction) {
+			t.Errorf("expected ErrUnknownAction, got %v", err)
+		}
+		bad := map[string]sdto.FieldSpec{"x": {Type: "string", Min: strPtr("5"), Max: strPtr("1")}}
+		if err := r.RegisterFieldSpec("bad", bad); err == nil {
+			t.Error("expected schema error")
+		}
+	})
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,180 @@
+package sdto
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ActionBuilder 定義單一 action type 的欄位，由 SchemaBuilder.Action 取得：
+//
+//	b := sdto.NewSchemaBuilder()
+//	b.Action("createUser").String("name", 1, 50).Number("age", 0, 150)
+//	b.Action("deleteUser").String("id", 36, 36)
+//	all, err := b.BuildAll()
+type ActionBuilder struct {
+	parent *SchemaBuilder
+	fields *SchemaBuilder
+}
+
+// Action 取得（或建立）actionType 的欄位定義
+func (b *SchemaBuilder) Action(actionType string) *ActionBuilder {
+	if b.actions == nil {
+		b.actions = make(map[string]*ActionBuilder)
+	}
+	a, ok := b.actions[actionType]
+	if !ok {
+		a = &ActionBuilder{parent: b, fields: NewSchemaBuilder()}
+		b.actions[actionType] = a
+	}
+	return a
+}
+
+// Action 切換到另一個 action type（方便連續鏈式定義）
+func (a *ActionBuilder) Action(actionType string) *ActionBuilder {
+	return a.parent.Action(actionType)
+}
+
+// String 字串欄位，長度 [min, max]
+func (a *ActionBuilder) String(field string, min, max int) *ActionBuilder {
+	a.fields.SetActionStringLength(field, strconv.Itoa(min), strconv.Itoa(max))
+	return a
+}
+
+// Number 數字欄位，範圍 [min, max]
+func (a *ActionBuilder) Number(field string, min, max float64) *ActionBuilder {
+	a.fields.SetActionNumberRange(field, formatBound(min), formatBound(max))
+	return a
+}
+
+// Integer 整數欄位，範圍 [min, max]
+func (a *ActionBuilder) Integer(field string, min, max int64) *ActionBuilder {
+	a.fields.SetActionIntegerRange(field, strconv.FormatInt(min, 10), strconv.FormatInt(max, 10))
+	return a
+}
+
+// Decimal 十進位字串欄位（金額），bounds 以字串表示避免精度損失
+func (a *ActionBuilder) Decimal(field string, min, max string, precision, scale int) *ActionBuilder {
+	a.fields.SetActionDecimal(field, min, max, precision, scale)
+	return a
+}
+
+// Bytes bytes 欄位（base64），解碼後大小 [min, max]
+func (a *ActionBuilder) Bytes(field string, min, max int) *ActionBuilder {
+	a.fields.SetActionBytes(field, strconv.Itoa(min), strconv.Itoa(max))
+	return a
+}
+
+// Map map 欄位，項目數 [min, max]
+func (a *ActionBuilder) Map(field string, keyPattern string, values FieldSpec, min, max int) *ActionBuilder {
+	a.fields.SetActionMap(field, keyPattern, values, strconv.Itoa(min), strconv.Itoa(max))
+	return a
+}
+
+// OneOf union 欄位，值符合任一替代型別即可
+func (a *ActionBuilder) OneOf(field string, alternatives ...FieldSpec) *ActionBuilder {
+	a.fields.SetActionOneOf(field, alternatives...)
+	return a
+}
+
+// Boolean 布林欄位
+func (a *ActionBuilder) Boolean(field string) *ActionBuilder {
+	a.fields.SetActionBoolean(field)
+	return a
+}
+
+// Enum 字串列舉欄位
+func (a *ActionBuilder) Enum(field string, values ...string) *ActionBuilder {
+	a.fields.SetActionEnum(field, values)
+	return a
+}
+
+// NumberEnum 數字列舉欄位
+func (a *ActionBuilder) NumberEnum(field string, values ...string) *ActionBuilder {
+	a.fields.SetActionNumberEnum(field, values)
+	return a
+}
+
+// Sign 簽名欄位，允許的演算法見 SupportedSignTypes
+func (a *ActionBuilder) Sign(field string, signTypes ...string) *ActionBuilder {
+	a.fields.SetActionSignMulti(field, signTypes)
+	return a
+}
+
+// LengthUnit 設定已定義字串欄位的長度單位
+func (a *ActionBuilder) LengthUnit(field string, unit string) *ActionBuilder {
+	a.fields.SetLengthUnit(field, unit)
+	return a
+}
+
+// Format 設定已定義字串欄位的格式
+func (a *ActionBuilder) Format(field string, format string) *ActionBuilder {
+	a.fields.SetFormat(field, format)
+	return a
+}
+
+// Exclusive 設定已定義數值欄位的開區間
+func (a *ActionBuilder) Exclusive(field string, exclusiveMin, exclusiveMax bool) *ActionBuilder {
+	a.fields.SetExclusiveBounds(field, exclusiveMin, exclusiveMax)
+	return a
+}
+
+// MultipleOf 設定已定義數值欄位的倍數限制
+func (a *ActionBuilder) MultipleOf(field string, multipleOf string) *ActionBuilder {
+	a.fields.SetMultipleOf(field, multipleOf)
+	return a
+}
+
+// Default 設定已定義欄位的預設值
+func (a *ActionBuilder) Default(field string, value any) *ActionBuilder {
+	a.fields.SetDefault(field, value)
+	return a
+}
+
+// Const 把已定義欄位固定為單一值
+func (a *ActionBuilder) Const(field string, value any) *ActionBuilder {
+	a.fields.SetConst(field, value)
+	return a
+}
+
+// Sensitive 標記已定義欄位為敏感資料
+func (a *ActionBuilder) Sensitive(field string) *ActionBuilder {
+	a.fields.SetSensitive(field)
+	return a
+}
+
+// BuildAll 驗證並回傳 actionType → schema
+func (b *SchemaBuilder) BuildAll() (map[string]map[string]FieldSpec, error) {
+	names := make([]string, 0, len(b.actions))
+	for name := range b.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := make(map[string]map[string]FieldSpec, len(names))
+	for _, name := range names {
+		schema, err := b.actions[name].fields.BuildSchema()
+		if err != nil {
+			return nil, fmt.Errorf("action %s: %w", name, err)
+		}
+		all[name] = schema
+	}
+	return all, nil
+}
+
+// NewAction 依 actionType 取得 schema 並建立 FluentAction
+func (b *SchemaBuilder) NewAction(actionType string) (*FluentAction, error) {
+	a, ok := b.actions[actionType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSchema, actionType)
+	}
+	schema, err := a.fields.BuildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("action %s: %w", actionType, err)
+	}
+	return NewAction(actionType, schema), nil
+}
+
+func formatBound(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
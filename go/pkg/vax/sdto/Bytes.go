@@ -0,0 +1,65 @@
+package sdto
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// validateBytes 驗證 bytes 欄位：值為標準 base64（含 padding）字串，
+// Min / Max 為解碼後的位元組數。
+func validateBytes(value any, c FieldSpec) error {
+	v, ok := value.(string)
+	if !ok {
+		return ruleError(CodeType, jsonType(value), "bytes", "expected base64 string")
+	}
+
+	raw, err := base64.StdEncoding.Strict().DecodeString(v)
+	if err != nil {
+		return ruleError(CodeFormat, v, "base64", "value is not valid base64")
+	}
+
+	if c.Min != nil {
+		if n, err := strconv.Atoi(*c.Min); err == nil && len(raw) < n {
+			return ruleError(CodeMin, len(raw), n, "bytes size %d < min %d", len(raw), n)
+		}
+	}
+	if c.Max != nil {
+		if n, err := strconv.Atoi(*c.Max); err == nil && len(raw) > n {
+			return ruleError(CodeMax, len(raw), n, "bytes size %d > max %d", len(raw), n)
+		}
+	}
+	return nil
+}
+
+// SetBytes 以 base64 編碼後設定 bytes 欄位
+func (f *FluentAction) SetBytes(key string, value []byte) *FluentAction {
+	return f.Set(key, base64.StdEncoding.EncodeToString(value))
+}
+
+// GetBytes 取回已設定的 bytes 欄位原始內容
+func (f *FluentAction) GetBytes(key string) ([]byte, bool) {
+	f.mu.Lock()
+	v, ok := f.data[key].(string)
+	f.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
+
+// DecodeBytes 伺服器端讀取 bytes 欄位（data 通常為已驗證的 env.SDTO）
+func DecodeBytes(data map[string]any, key string) ([]byte, bool) {
+	v, ok := data[key].(string)
+	if !ok {
+		return nil, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
@@ -0,0 +1,129 @@
+package sdto
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Coercion 記錄 CoerceData 做過的一次轉換
+type Coercion struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// jsonNumberPattern 是 JSON 的數字語法（拒絕 "0x10"、"1/2"、"Inf" 等）
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// CoerceData returns a copy of data with representation-only differences
+// converted to what schema expects, plus the list of conversions made:
+//
+//	integer: "42", json.Number("42"), 42.0  → int64
+//	number:  "1.5", json.Number("1.5")      → int64 when integral, else float64
+//	boolean: "true"/"false", "1"/"0", 1/0   → bool
+//	decimal: json.Number / Go numbers       → decimal string
+//
+// Values that cannot be converted are left as-is so validation reports them.
+// Unknown fields, map / union fields and already-correct values are untouched.
+//
+// Coercion is opt-in and meant for input that has not been signed yet (HTML
+// forms, query parameters). Never coerce a received SDTO before hashing: the
+// SAI covers the values as sent.
+func CoerceData(data map[string]any, schema map[string]FieldSpec) (map[string]any, []Coercion) {
+	out := make(map[string]any, len(data))
+	var log []Coercion
+	for key, value := range data {
+		out[key] = value
+		spec, ok := schema[key]
+		if !ok {
+			continue
+		}
+		if to, ok := coerceValue(value, spec.Type); ok {
+			out[key] = to
+			log = append(log, Coercion{Field: key, From: value, To: to})
+		}
+	}
+	sort.Slice(log, func(i, j int) bool { return log[i].Field < log[j].Field })
+	return out, log
+}
+
+func coerceValue(value any, fieldType string) (any, bool) {
+	switch fieldType {
+	case "integer":
+		switch v := value.(type) {
+		case string, json.Number:
+			return parseInteger(numberText(v))
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), true
+			}
+		}
+	case "number":
+		switch v := value.(type) {
+		case string, json.Number:
+			s := numberText(v)
+			if n, ok := parseInteger(s); ok {
+				return n, true
+			}
+			if jsonNumberPattern.MatchString(s) {
+				if f, err := strconv.ParseFloat(s, 64); err == nil {
+					return f, true
+				}
+			}
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case string:
+			switch strings.TrimSpace(v) {
+			case "true", "1":
+				return true, true
+			case "false", "0":
+				return false, true
+			}
+		default:
+			if r, ok := toRat(value); ok && r.IsInt() && (r.Sign() == 0 || r.Num().IsInt64() && r.Num().Int64() == 1) {
+				return r.Sign() != 0, true
+			}
+		}
+	case "decimal":
+		switch v := value.(type) {
+		case json.Number:
+			return v.String(), true
+		case float64:
+			// 最短十進位表示（與 JCS 一致），不帶指數
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				return strconv.FormatFloat(v, 'f', -1, 64), true
+			}
+		case int, int32, int64, uint, uint32, uint64:
+			if r, ok := toRat(v); ok {
+				return r.RatString(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func numberText(v any) string {
+	if n, ok := v.(json.Number); ok {
+		return n.String()
+	}
+	return strings.TrimSpace(v.(string))
+}
+
+func parseInteger(s string) (int64, bool) {
+	if !jsonNumberPattern.MatchString(s) {
+		return 0, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, true
+	}
+	// "42.0" / "4.2e1"
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f), true
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package sdto
+
+// Define 登記可重用的欄位定義（例如 "money"、"email"），之後以 Use 套用。
+// 在 SchemaBuilder 上定義的名稱，其下所有 Action 都能使用。
+//
+//	b := sdto.NewSchemaBuilder().
+//		Define("money", sdto.FieldSpec{Type: "decimal", Min: &zero, Scale: &two})
+//	b.Action("transfer").Use("amount", "money")
+//	b.Action("refund").Use("amount", "money")
+func (b *SchemaBuilder) Define(name string, spec FieldSpec) *SchemaBuilder {
+	if b.defs == nil {
+		b.defs = make(map[string]FieldSpec)
+	}
+	b.defs[name] = spec
+	return b
+}
+
+// Use 以 Define 登記的定義設定欄位；名稱不存在時 BuildSchema 回報錯誤
+func (b *SchemaBuilder) Use(field string, definition string) *SchemaBuilder {
+	b.use(field, definition, b.defs)
+	return b
+}
+
+func (b *SchemaBuilder) use(field, definition string, scopes ...map[string]FieldSpec) {
+	for _, defs := range scopes {
+		if spec, ok := defs[definition]; ok {
+			b.Actions[field] = spec
+			return
+		}
+	}
+	b.fail(field, "unknown definition %q", definition)
+}
+
+// Include 併入另一個 builder 的欄位與定義（共用欄位組，例如簽名 + 時間戳）。
+// 同名欄位不會被覆蓋，BuildSchema 時回報衝突。
+func (b *SchemaBuilder) Include(other *SchemaBuilder) *SchemaBuilder {
+	for name, spec := range other.defs {
+		if _, exists := b.defs[name]; !exists {
+			b.Define(name, spec)
+		}
+	}
+	for field, spec := range other.Actions {
+		if _, exists := b.Actions[field]; exists {
+			b.fail(field, "field already defined, not overwritten by Include")
+			continue
+		}
+		b.Actions[field] = spec
+	}
+	b.errs = append(b.errs, other.errs...)
+	return b
+}
+
+func (b *SchemaBuilder) fail(field, format string, args ...any) {
+	fe := ruleError(CodeSchema, nil, nil, format, args...)
+	fe.Field = field
+	b.errs = append(b.errs, *fe)
+}
+
+// Use 以定義設定欄位：先找 Include 帶進此 action 的定義，再找上層 SchemaBuilder.Define
+func (a *ActionBuilder) Use(field string, definition string) *ActionBuilder {
+	a.fields.use(field, definition, a.fields.defs, a.parent.defs)
+	return a
+}
+
+// Include 併入另一個 builder 的欄位（見 SchemaBuilder.Include）
+func (a *ActionBuilder) Include(other *SchemaBuilder) *ActionBuilder {
+	a.fields.Include(other)
+	return a
+}
@@ -0,0 +1,46 @@
+package sdto
+
+import (
+	"bytes"
+
+	"vax/pkg/vax/jcs"
+)
+
+// validateConst 先以欄位本身的型別規則驗證，再要求值等於 Const。
+// 數字依數值比較（1 == 1.0 == json.Number("1")），其餘依 JCS 位元組比較。
+func validateConst(value any, c FieldSpec) error {
+	want := *c.Const
+	c.Const = nil
+	if err := validateValue(value, c); err != nil {
+		return err
+	}
+	if !sameValue(value, want) {
+		return ruleError(CodeConst, value, want, "value must be %v", want)
+	}
+	return nil
+}
+
+func sameValue(a, b any) bool {
+	if ra, ok := toRat(a); ok {
+		rb, ok := toRat(b)
+		return ok && ra.Cmp(rb) == 0
+	}
+	ca, err := jcs.Marshal(a)
+	if err != nil {
+		return false
+	}
+	cb, err := jcs.Marshal(b)
+	return err == nil && bytes.Equal(ca, cb)
+}
+
+// implicitValue 回傳欄位未設定時的值：Const 優先，其次 Default
+func implicitValue(c FieldSpec) (any, bool) {
+	switch {
+	case c.Const != nil:
+		return *c.Const, true
+	case c.Default != nil:
+		return *c.Default, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,61 @@
+package sdto
+
+import (
+	"math/big"
+	"strings"
+)
+
+// validateDecimal 驗證十進位字串（金額用）：值保持字串，比較以 big.Rat 進行，
+// 驗證與 JCS 正規化都不經過 float64。
+//
+// 語法：-?(0|[1-9][0-9]*)(\.[0-9]+)?，不接受指數、正號與前導零。
+// Precision = 有效位數上限（整數位 + 小數位），Scale = 小數位數上限。
+func validateDecimal(value any, c FieldSpec) error {
+	v, ok := value.(string)
+	if !ok {
+		return ruleError(CodeType, jsonType(value), "decimal", "expected decimal string")
+	}
+
+	intPart, fracPart, ok := splitDecimal(v)
+	if !ok {
+		return ruleError(CodeFormat, v, "decimal", "value %q is not a valid decimal", v)
+	}
+
+	if c.Scale != nil && len(fracPart) > *c.Scale {
+		return ruleError(CodeScale, len(fracPart), *c.Scale, "decimal scale %d > max %d", len(fracPart), *c.Scale)
+	}
+	if c.Precision != nil {
+		digits := len(strings.TrimLeft(intPart, "0")) + len(fracPart)
+		if digits > *c.Precision {
+			return ruleError(CodePrecision, digits, *c.Precision, "decimal precision %d > max %d", digits, *c.Precision)
+		}
+	}
+
+	r, _ := new(big.Rat).SetString(v)
+	return checkRange(r, c, "decimal")
+}
+
+func splitDecimal(v string) (intPart, fracPart string, ok bool) {
+	s := strings.TrimPrefix(v, "-")
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+
+	if !isDigits(intPart) || (len(intPart) > 1 && intPart[0] == '0') {
+		return "", "", false
+	}
+	if hasDot && !isDigits(fracPart) {
+		return "", "", false
+	}
+	return intPart, fracPart, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package sdto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 驗證錯誤代碼（機器可讀，給前端標示欄位用）
+const (
+	CodeRequired     = "required"
+	CodeUnknownField = "unknown_field"
+	CodeType         = "type"
+	CodeEnum         = "enum"
+	CodeMin          = "min"
+	CodeMax          = "max"
+	CodeMultipleOf   = "multiple_of"
+	CodeConst        = "const"
+	CodeFormat       = "format"
+	CodeFractional   = "fractional"
+	CodeScale        = "scale"
+	CodePrecision    = "precision"
+	CodeSchema       = "schema" // schema 本身有誤（未知 type / format）
+)
+
+// FieldError 是單一欄位的驗證錯誤
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Got     any    `json:"got,omitempty"`
+	Want    any    `json:"want,omitempty"`
+
+	path []string // 巢狀值（map key）相對於欄位的位置，見 ValidateDataReport
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	switch e.Code {
+	case CodeRequired, CodeUnknownField:
+		return e.Message + ": " + e.Field
+	default:
+		return "field " + e.Field + ": " + e.Message
+	}
+}
+
+// ValidationErrors 是 Finalize / ValidateData 回傳的錯誤集合。
+// 支援 errors.As（*ValidationErrors 或個別 *FieldError）與 JSON 序列化。
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i := range v {
+		msgs[i] = v[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap 讓 errors.As(err, &fieldErr) 取得第一個相符的 *FieldError
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i := range v {
+		errs[i] = &v[i]
+	}
+	return errs
+}
+
+// ruleError 由各 validator 回傳，欄位名稱由呼叫端補上
+func ruleError(code string, got, want any, format string, args ...any) *FieldError {
+	return &FieldError{Code: code, Got: got, Want: want, Message: fmt.Sprintf(format, args...)}
+}
+
+// fieldError 把 validator 錯誤綁定到欄位
+func fieldError(field string, err error) FieldError {
+	if fe, ok := err.(*FieldError); ok {
+		out := *fe
+		out.Field = field
+		return out
+	}
+	return FieldError{Field: field, Code: CodeSchema, Message: err.Error()}
+}
+
+// sorted 依欄位名稱排序（map 走訪順序不固定）
+func (v ValidationErrors) sorted() ValidationErrors {
+	sort.SliceStable(v, func(i, j int) bool { return v[i].Field < v[j].Field })
+	return v
+}
+
+// jsonType 回傳值的 JSON 型別名稱，用於 type 錯誤的 Got
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		if _, ok := toRat(value); ok {
+			return "number"
+		}
+		return fmt.Sprintf("%T", value)
+	}
+}
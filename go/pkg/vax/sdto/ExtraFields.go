@@ -0,0 +1,67 @@
+package sdto
+
+import "sort"
+
+// ExtraFieldPolicy 決定 ValidateDataWithPolicy 如何處理 schema 沒定義的欄位
+type ExtraFieldPolicy int
+
+const (
+	// ExtraReject 回報 unknown_field 錯誤（ValidateData 的行為）
+	ExtraReject ExtraFieldPolicy = iota
+	// ExtraIgnore 接受並保留多出的欄位（新 client → 舊 server）
+	ExtraIgnore
+	// ExtraStrip 從 data 移除多出的欄位並回傳其名稱
+	ExtraStrip
+)
+
+// ValidateDataWithPolicy is ValidateData with a configurable policy for
+// fields the schema does not define. It returns the sorted names of the
+// extra fields it handled: removed from data under ExtraStrip (data is
+// modified in place), left in place under ExtraIgnore, and reported as
+// errors under ExtraReject.
+//
+// Stripping does not touch the signed envelope: SAI and signatures still
+// cover the SDTO as sent. Use the returned names to exclude the same fields
+// wherever the server re-hashes or stores its own canonical view.
+func ValidateDataWithPolicy(data map[string]any, schema map[string]FieldSpec, policy ExtraFieldPolicy) ([]string, error) {
+	var errs ValidationErrors
+
+	// Check all required fields in schema exist (const / defaulted fields are optional)
+	for key, spec := range schema {
+		value, exists := data[key]
+		if !exists {
+			if _, ok := implicitValue(spec); !ok {
+				errs = append(errs, FieldError{Field: key, Code: CodeRequired, Message: "missing required field"})
+			}
+			continue
+		}
+		if err := validateValue(value, spec); err != nil {
+			errs = append(errs, fieldError(key, err))
+		}
+	}
+
+	var extra []string
+	for key := range data {
+		if _, exists := schema[key]; !exists {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+
+	switch policy {
+	case ExtraIgnore:
+	case ExtraStrip:
+		for _, key := range extra {
+			delete(data, key)
+		}
+	default:
+		for _, key := range extra {
+			errs = append(errs, FieldError{Field: key, Code: CodeUnknownField, Message: "unknown field"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return extra, errs.sorted()
+	}
+	return extra, nil
+}
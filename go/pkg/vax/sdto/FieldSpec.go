@@ -1,10 +1,44 @@
 package sdto
 
+import "fmt"
+
 type FieldSpec struct {
-	Type string   `json:"type"` // string / number
+	Type string   `json:"type,omitempty"` // string / number / integer / decimal / boolean / bytes / map / sign；union 欄位留空
 	Min  *string  `json:"min,omitempty"`
 	Max  *string  `json:"max,omitempty"`
 	Enum []string `json:"enum,omitempty"`
+
+	// 數值型別（number / integer / decimal）：開區間與倍數限制
+	ExclusiveMin bool    `json:"exclusive_min,omitempty"`
+	ExclusiveMax bool    `json:"exclusive_max,omitempty"`
+	MultipleOf   *string `json:"multiple_of,omitempty"`
+
+	// decimal 專用：有效位數與小數位數上限
+	Precision *int `json:"precision,omitempty"`
+	Scale     *int `json:"scale,omitempty"`
+
+	// map 專用：key 的 regexp 與 value 的 schema（nil 表示任意值）；Min / Max 為項目數
+	KeyPattern string     `json:"key_pattern,omitempty"`
+	Values     *FieldSpec `json:"values,omitempty"`
+
+	// OneOf union 欄位：值符合任一替代型別即可（Type 留空，限制寫在各替代型別內）
+	OneOf []FieldSpec `json:"one_of,omitempty"`
+
+	// LengthUnit 字串長度單位：bytes（預設）/ runes / graphemes
+	LengthUnit string `json:"length_unit,omitempty"`
+
+	// Format 字串格式：email / uri / uuid / date-time
+	Format string `json:"format,omitempty"`
+
+	// Default 讓欄位變成選填：未設定時 Finalize 以此值補上
+	Default *any `json:"default,omitempty"`
+
+	// Const 把欄位固定為單一值（例如區域部署的 currency）：
+	// 其他值一律拒絕，未設定時 Finalize 自動補上
+	Const *any `json:"const,omitempty"`
+
+	// Sensitive 標記 PII 等敏感欄位：Redact 會遮蔽其值（log / history 匯出用），不影響驗證
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
 // ParseSchema converts map[string]any to map[string]FieldSpec
@@ -18,31 +52,202 @@ func ParseSchema(raw map[string]any) map[string]FieldSpec {
 			continue
 		}
 
-		spec := FieldSpec{}
+		result[key] = parseFieldSpec(m)
+	}
 
-		if t, ok := m["type"].(string); ok {
-			spec.Type = t
-		}
-		if min, ok := m["min"].(string); ok {
-			spec.Min = &min
-		}
-		if max, ok := m["max"].(string); ok {
-			spec.Max = &max
+	return result
+}
+
+func parseFieldSpec(m map[string]any) FieldSpec {
+	spec := FieldSpec{}
+
+	if t, ok := m["type"].(string); ok {
+		spec.Type = t
+	}
+	if min, ok := m["min"].(string); ok {
+		spec.Min = &min
+	}
+	if max, ok := m["max"].(string); ok {
+		spec.Max = &max
+	}
+	if enumRaw, ok := m["enum"].([]any); ok {
+		for _, e := range enumRaw {
+			if s, ok := e.(string); ok {
+				spec.Enum = append(spec.Enum, s)
+			}
 		}
-		if enumRaw, ok := m["enum"].([]any); ok {
-			for _, e := range enumRaw {
-				if s, ok := e.(string); ok {
-					spec.Enum = append(spec.Enum, s)
-				}
+	}
+	if b, ok := m["exclusive_min"].(bool); ok {
+		spec.ExclusiveMin = b
+	}
+	if b, ok := m["exclusive_max"].(bool); ok {
+		spec.ExclusiveMax = b
+	}
+	if mo, ok := m["multiple_of"].(string); ok {
+		spec.MultipleOf = &mo
+	}
+	if p, ok := toInt(m["precision"]); ok {
+		spec.Precision = &p
+	}
+	if sc, ok := toInt(m["scale"]); ok {
+		spec.Scale = &sc
+	}
+	if unit, ok := m["length_unit"].(string); ok {
+		spec.LengthUnit = unit
+	}
+	if format, ok := m["format"].(string); ok {
+		spec.Format = format
+	}
+	if def, ok := m["default"]; ok {
+		spec.Default = &def
+	}
+	if cv, ok := m["const"]; ok {
+		spec.Const = &cv
+	}
+	if b, ok := m["sensitive"].(bool); ok {
+		spec.Sensitive = b
+	}
+	// Support []string directly
+	if enumStr, ok := m["enum"].([]string); ok {
+		spec.Enum = enumStr
+	}
+	if kp, ok := m["key_pattern"].(string); ok {
+		spec.KeyPattern = kp
+	}
+	if vm, ok := m["values"].(map[string]any); ok {
+		values := parseFieldSpec(vm)
+		spec.Values = &values
+	}
+	if alts, ok := m["one_of"].([]any); ok {
+		for _, a := range alts {
+			if am, ok := a.(map[string]any); ok {
+				spec.OneOf = append(spec.OneOf, parseFieldSpec(am))
 			}
 		}
-		// Support []string directly
-		if enumStr, ok := m["enum"].([]string); ok {
-			spec.Enum = enumStr
+	}
+
+	return spec
+}
+
+// toInt 接受 JSON 解碼後的數字（float64 / json.Number）或 Go int
+func toInt(v any) (int, bool) {
+	r, ok := toRat(v)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return int(r.Num().Int64()), true
+}
+
+// SupportedTypes 是 FieldSpec.Type 可用的值
+var SupportedTypes = []string{"string", "number", "integer", "decimal", "boolean", "bytes", "map", "sign"}
+
+// ParseSchemaStrict is ParseSchema that reports problems instead of silently
+// dropping them: non-object entries, missing or unknown types, non-string
+// bounds, malformed enum / format / precision / scale / unknown keys, then
+// checks the result with ValidateSchema. Problems are returned as ValidationErrors with Code CodeSchema.
+func ParseSchemaStrict(raw map[string]any) (map[string]FieldSpec, error) {
+	var errs ValidationErrors
+	bad := func(field, format string, args ...any) {
+		fe := ruleError(CodeSchema, nil, nil, format, args...)
+		fe.Field = field
+		errs = append(errs, *fe)
+	}
+
+	for key, val := range raw {
+		checkRawField(key, val, bad)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs.sorted()
+	}
+
+	schema := ParseSchema(raw)
+	if err := ValidateSchema(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func checkRawField(key string, val any, bad func(field, format string, args ...any)) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		bad(key, "field definition must be an object, got %s", jsonType(val))
+		return
+	}
+
+	t, ok := m["type"].(string)
+	_, union := m["one_of"]
+	switch {
+	case union && !ok:
+		// union 欄位不需要 type
+	case !ok:
+		bad(key, "missing or non-string type")
+	case !contains(SupportedTypes, t):
+		bad(key, "unknown type %q", t)
+	}
+
+	for k, v := range m {
+		switch k {
+		case "type":
+		case "min", "max", "format", "multiple_of", "length_unit", "key_pattern":
+			if _, ok := v.(string); !ok {
+				bad(key, "%s must be a string, got %s", k, jsonType(v))
+			}
+		case "exclusive_min", "exclusive_max", "sensitive":
+			if _, ok := v.(bool); !ok {
+				bad(key, "%s must be a boolean, got %s", k, jsonType(v))
+			}
+		case "precision", "scale":
+			if n, ok := toInt(v); !ok || n < 0 {
+				bad(key, "%s must be a non-negative integer", k)
+			}
+		case "enum":
+			if !isStringList(v) {
+				bad(key, "enum must be an array of strings")
+			}
+		case "values":
+			checkRawField(key+".values", v, bad)
+		case "one_of":
+			alts, ok := v.([]any)
+			if !ok {
+				bad(key, "one_of must be an array of field definitions")
+				continue
+			}
+			for i, a := range alts {
+				checkRawField(fmt.Sprintf("%s.one_of[%d]", key, i), a, bad)
+			}
+		case "default", "const":
+		default:
+			bad(key, "unknown key %q", k)
 		}
+	}
 
-		result[key] = spec
+	if f, ok := m["format"].(string); ok && !contains(supportedFormats, f) {
+		bad(key, "unknown format %q", f)
 	}
+}
 
-	return result
+func isStringList(v any) bool {
+	switch list := v.(type) {
+	case []string:
+		return true
+	case []any:
+		for _, e := range list {
+			if _, ok := e.(string); !ok {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,40 @@
+package sdto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// ErrSchemaMismatch 表示 SAE 是依照不同的 schema 建立的
+var ErrSchemaMismatch = errors.New("schema fingerprint mismatch")
+
+// Fingerprint 回傳 schema 指紋：hex(SHA256(JCS(schema)))
+// 同一份 schema 不論欄位順序、跨語言，指紋都相同
+func Fingerprint(schema map[string]FieldSpec) (string, error) {
+	canonical, err := jcs.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyFingerprint 檢查 SAE 內嵌的 schema_fingerprint 是否與 schema 相符。
+// 沒有指紋的 SAE（舊版 client）直接通過，交由 ValidateData 判斷。
+func VerifyFingerprint(env *sae.Envelope, schema map[string]FieldSpec) error {
+	if env.SchemaFP == "" {
+		return nil
+	}
+	fp, err := Fingerprint(schema)
+	if err != nil {
+		return err
+	}
+	if env.SchemaFP != fp {
+		return ErrSchemaMismatch
+	}
+	return nil
+}
@@ -1,20 +1,27 @@
 package sdto
 
 import (
-	"errors"
-	"fmt"
+	"encoding/json"
+	"math"
 	"math/big"
+	"sort"
 	"strconv"
+	"sync"
 
+	"vax/pkg/vax/jcs"
 	"vax/pkg/vax/sae"
 )
 
-// FluentAction 是你給 Consumer 的「量尺」
+// FluentAction 是你給 Consumer 的「量尺」。
+// 所有方法都可在多個 goroutine 間同時呼叫（內部以 mutex 保護）；
+// 但同時填同一個 action 通常不是想要的結果，範本請用 Clone 各自複製一份。
 type FluentAction struct {
-	actionType string
-	schema     map[string]FieldSpec // 從後端拉回來的驗證規則
-	data       map[string]any
-	errs       []error
+	mu            sync.Mutex
+	actionType    string
+	schemaVersion string
+	schema        map[string]FieldSpec // 從後端拉回來的驗證規則
+	data          map[string]any
+	errs          ValidationErrors
 }
 
 func NewAction(actionType string, rules map[string]FieldSpec) *FluentAction {
@@ -25,33 +32,137 @@ func NewAction(actionType string, rules map[string]FieldSpec) *FluentAction {
 	}
 }
 
+// SetSchemaVersion 標記 schema 版本，Finalize 時寫入 SAE 的 schema_version
+func (f *FluentAction) SetSchemaVersion(version string) *FluentAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schemaVersion = version
+	return f
+}
+
+// Clone 複製出獨立的 action（schema / data / errs 各自一份），
+// 適合預先建好範本、每個 request 再 Clone 填入自己的欄位。
+// data 為淺層複製：map / slice 型的值仍與原本共用，請勿原地修改。
+func (f *FluentAction) Clone() *FluentAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := &FluentAction{
+		actionType:    f.actionType,
+		schemaVersion: f.schemaVersion,
+		schema:        make(map[string]FieldSpec, len(f.schema)),
+		data:          make(map[string]any, len(f.data)),
+		errs:          append(ValidationErrors(nil), f.errs...),
+	}
+	for k, v := range f.schema {
+		c.schema[k] = v
+	}
+	for k, v := range f.data {
+		c.data[k] = v
+	}
+	return c
+}
+
 // Set 在賦值的瞬間進行驗證
 func (f *FluentAction) Set(key string, value any) *FluentAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(key, value)
+	return f
+}
+
+func (f *FluentAction) set(key string, value any) {
 	spec, exists := f.schema[key]
 	if !exists {
-		f.errs = append(f.errs, fmt.Errorf("unknown field: %s", key))
-		return f
+		f.errs = append(f.errs, FieldError{Field: key, Code: CodeUnknownField, Message: "unknown field"})
+		return
 	}
 
 	if err := validateValue(value, spec); err != nil {
-		f.errs = append(f.errs, fmt.Errorf("field %s: %w", key, err))
-		return f
+		f.errs = append(f.errs, fieldError(key, err))
+		return
 	}
 
 	f.data[key] = value
+}
+
+// SetString 設定字串欄位（string / decimal / bytes 的編碼值）
+func (f *FluentAction) SetString(key string, value string) *FluentAction {
+	return f.Set(key, value)
+}
+
+// SetNumber 設定 number 欄位
+func (f *FluentAction) SetNumber(key string, value float64) *FluentAction {
+	return f.Set(key, value)
+}
+
+// SetInteger 設定 integer 欄位
+func (f *FluentAction) SetInteger(key string, value int64) *FluentAction {
+	return f.Set(key, value)
+}
+
+// SetBool 設定 boolean 欄位
+func (f *FluentAction) SetBool(key string, value bool) *FluentAction {
+	return f.Set(key, value)
+}
+
+// SetAll 依 key 排序逐一 Set（錯誤順序穩定），方便從 map 型的舊程式碼遷移
+func (f *FluentAction) SetAll(values map[string]any) *FluentAction {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range keys {
+		f.set(k, values[k])
+	}
+	return f
+}
+
+// Unset 移除欄位的值與先前 Set 累積的錯誤，可重新 Set
+func (f *FluentAction) Unset(key string) *FluentAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.data, key)
+	kept := f.errs[:0]
+	for _, fe := range f.errs {
+		if fe.Field != key {
+			kept = append(kept, fe)
+		}
+	}
+	f.errs = kept
 	return f
 }
 
 func validateValue(value any, c FieldSpec) error {
+	if c.Const != nil {
+		return validateConst(value, c)
+	}
+	if c.OneOf != nil {
+		return validateOneOf(value, c)
+	}
 	switch c.Type {
 	case "string":
 		return validateString(value, c)
 	case "number":
 		return validateNumber(value, c)
+	case "integer":
+		return validateInteger(value, c)
+	case "boolean":
+		return validateBoolean(value)
+	case "decimal":
+		return validateDecimal(value, c)
+	case "bytes":
+		return validateBytes(value, c)
+	case "map":
+		return validateMap(value, c)
 	case "sign":
 		return validateSign(value, c)
 	default:
-		return fmt.Errorf("unknown type %q", c.Type)
+		return ruleError(CodeSchema, nil, nil, "unknown type %q", c.Type)
 	}
 }
 
@@ -59,12 +170,12 @@ func validateSign(value any, c FieldSpec) error {
 	// 簽名值只能是 string（類型已在 schema 層定義）
 	v, ok := value.(string)
 	if !ok {
-		return errors.New("sign field expects string value")
+		return ruleError(CodeType, jsonType(value), "string", "sign field expects string value")
 	}
 
 	// 可擴展：根據 c.Enum[0] 做格式驗證（hex/base64 等）
 	if len(v) == 0 {
-		return errors.New("sign value cannot be empty")
+		return ruleError(CodeMin, 0, 1, "sign value cannot be empty")
 	}
 
 	return nil
@@ -73,7 +184,14 @@ func validateSign(value any, c FieldSpec) error {
 func validateString(value any, c FieldSpec) error {
 	v, ok := value.(string)
 	if !ok {
-		return errors.New("expected string")
+		return ruleError(CodeType, jsonType(value), "string", "expected string")
+	}
+
+	// format
+	if c.Format != "" {
+		if err := validateFormat(v, c.Format); err != nil {
+			return err
+		}
 	}
 
 	// enum
@@ -83,20 +201,21 @@ func validateString(value any, c FieldSpec) error {
 				return nil
 			}
 		}
-		return fmt.Errorf("value %q not in enum", v)
+		return ruleError(CodeEnum, v, c.Enum, "value %q not in enum", v)
 	}
 
-	// length boundary (數值解析)
+	// length boundary (數值解析，單位見 LengthUnit)
+	n := stringLength(v, c.LengthUnit)
 	if c.Min != nil {
 		minLen, err := strconv.Atoi(*c.Min)
-		if err == nil && len(v) < minLen {
-			return fmt.Errorf("string length %d < min %d", len(v), minLen)
+		if err == nil && n < minLen {
+			return ruleError(CodeMin, n, minLen, "string length %d < min %d", n, minLen)
 		}
 	}
 	if c.Max != nil {
 		maxLen, err := strconv.Atoi(*c.Max)
-		if err == nil && len(v) > maxLen {
-			return fmt.Errorf("string length %d > max %d", len(v), maxLen)
+		if err == nil && n > maxLen {
+			return ruleError(CodeMax, n, maxLen, "string length %d > max %d", n, maxLen)
 		}
 	}
 
@@ -104,38 +223,148 @@ func validateString(value any, c FieldSpec) error {
 }
 
 func validateNumber(value any, c FieldSpec) error {
-	var v float64
+	// 用 big.Rat 精確比較，避免大數或高精度金額經 float64 失真
+	v, ok := toRat(value)
+	if !ok {
+		return ruleError(CodeType, jsonType(value), "number", "expected number")
+	}
+	if err := checkExactNumber(value, v); err != nil {
+		return err
+	}
+	if err := checkNumericEnum(v, c); err != nil {
+		return err
+	}
+	return checkRange(v, c, "number")
+}
+
+func validateBoolean(value any) error {
+	if _, ok := value.(bool); !ok {
+		return ruleError(CodeType, jsonType(value), "boolean", "expected boolean")
+	}
+	return nil
+}
+
+func validateInteger(value any, c FieldSpec) error {
+	v, ok := toRat(value)
+	if !ok {
+		return ruleError(CodeType, jsonType(value), "integer", "expected integer")
+	}
+	if !v.IsInt() {
+		return ruleError(CodeFractional, v.RatString(), "integer", "expected integer, got fractional value")
+	}
+	if err := checkExactNumber(value, v); err != nil {
+		return err
+	}
+	if err := checkNumericEnum(v, c); err != nil {
+		return err
+	}
+	return checkRange(v, c, "integer")
+}
 
+// maxSafeFloat 是 float64 能無歧義表示整數的上限（2^53 - 1，同 JS Number.MAX_SAFE_INTEGER）
+const maxSafeFloat = 1<<53 - 1
+
+// checkExactNumber 確保寫進 SAE 的數字就是驗證過的數字：
+// float 超過 ±(2^53 - 1) 時可能早已失真（大數 ID 請用 int64 或 json.Number）；
+// json.Number 以原始字面值寫入，JCS 正規化後必須數值不變
+// （超過 64 位元的整數、超過 float64 精度的小數會被改寫，請改用 decimal 欄位）。
+func checkExactNumber(value any, v *big.Rat) error {
+	switch n := value.(type) {
+	case float32, float64:
+		if v.Cmp(big.NewRat(maxSafeFloat, 1)) > 0 || v.Cmp(big.NewRat(-maxSafeFloat, 1)) < 0 {
+			return ruleError(CodePrecision, v.RatString(), "int64 or json.Number",
+				"float value %s exceeds 2^53 - 1 and may have lost precision", v.RatString())
+		}
+	case json.Number:
+		canonical, err := jcs.CanonicalizeValue(n)
+		if err != nil {
+			return nil // 指數等寫法由 BuildSAE 回報
+		}
+		if r, ok := new(big.Rat).SetString(string(canonical)); !ok || r.Cmp(v) != 0 {
+			return ruleError(CodePrecision, n.String(), string(canonical),
+				"number %s would change to %s when canonicalized; use a decimal field", n, canonical)
+		}
+	}
+	return nil
+}
+
+// toRat 把 Go 數字型別或 json.Number（sae.Parse 解出的數字）精確轉成 big.Rat
+func toRat(value any) (*big.Rat, bool) {
+	v := new(big.Rat)
 	switch n := value.(type) {
 	case int:
-		v = float64(n)
+		return v.SetInt64(int64(n)), true
+	case int32:
+		return v.SetInt64(int64(n)), true
 	case int64:
-		v = float64(n)
+		return v.SetInt64(n), true
+	case uint:
+		return v.SetUint64(uint64(n)), true
+	case uint32:
+		return v.SetUint64(uint64(n)), true
+	case uint64:
+		return v.SetUint64(n), true
 	case float32:
-		v = float64(n)
+		return floatRat(float64(n), 32)
 	case float64:
-		v = n
+		return floatRat(n, 64)
+	case json.Number:
+		_, ok := v.SetString(n.String())
+		return v, ok
 	default:
-		return errors.New("expected number")
+		return nil, false
 	}
+}
 
+// checkNumericEnum 以數值相等比較 enum（"1" 與 1.0、json.Number("1.00") 相符）
+// floatRat 取浮點數的最短十進位表示（即 JCS 寫入 SAE 的值），
+// 使 client 端 Set(0.01) 與 server 端解出的 json.Number("0.01") 判斷一致
+func floatRat(f float64, bitSize int) (*big.Rat, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+func checkNumericEnum(v *big.Rat, c FieldSpec) error {
+	if len(c.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range c.Enum {
+		if e, ok := new(big.Rat).SetString(allowed); ok && v.Cmp(e) == 0 {
+			return nil
+		}
+	}
+	return ruleError(CodeEnum, v.RatString(), c.Enum, "value %s not in enum", v.RatString())
+}
+
+func checkRange(v *big.Rat, c FieldSpec, kind string) error {
 	if c.Min != nil {
+		if c.ExclusiveMin && !compareNumber(v, *c.Min, ">") {
+			return ruleError(CodeMin, v.RatString(), *c.Min, "%s <= exclusive min", kind)
+		}
 		if !compareNumber(v, *c.Min, ">=") {
-			return fmt.Errorf("number < min")
+			return ruleError(CodeMin, v.RatString(), *c.Min, "%s < min", kind)
 		}
 	}
 	if c.Max != nil {
+		if c.ExclusiveMax && !compareNumber(v, *c.Max, "<") {
+			return ruleError(CodeMax, v.RatString(), *c.Max, "%s >= exclusive max", kind)
+		}
 		if !compareNumber(v, *c.Max, "<=") {
-			return fmt.Errorf("number > max")
+			return ruleError(CodeMax, v.RatString(), *c.Max, "%s > max", kind)
+		}
+	}
+	if c.MultipleOf != nil {
+		m, ok := new(big.Rat).SetString(*c.MultipleOf)
+		if !ok || m.Sign() <= 0 || !new(big.Rat).Quo(v, m).IsInt() {
+			return ruleError(CodeMultipleOf, v.RatString(), *c.MultipleOf, "%s is not a multiple of %s", kind, *c.MultipleOf)
 		}
 	}
-
 	return nil
 }
 
-func compareNumber(value float64, bound string, op string) bool {
-	v := new(big.Rat).SetFloat64(value)
-
+func compareNumber(v *big.Rat, bound string, op string) bool {
 	b := new(big.Rat)
 	if _, ok := b.SetString(bound); !ok {
 		return false
@@ -146,68 +375,112 @@ func compareNumber(value float64, bound string, op string) bool {
 		return v.Cmp(b) >= 0
 	case "<=":
 		return v.Cmp(b) <= 0
+	case ">":
+		return v.Cmp(b) > 0
+	case "<":
+		return v.Cmp(b) < 0
 	default:
 		return false
 	}
 }
 
-// Finalize 最終產出 SAE
-func (f *FluentAction) Finalize() ([]byte, error) {
-	// Check for missing required fields (all schema fields are required)
-	for key := range f.schema {
-		if _, exists := f.data[key]; !exists {
-			f.errs = append(f.errs, fmt.Errorf("missing required field: %s", key))
+// Finalize 最終產出 SAE（opts 直接傳給 sae.BuildSAE，例如固定 timestamp）
+func (f *FluentAction) Finalize(opts ...sae.Option) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Fill unset const / defaulted fields, then check the rest are present
+	for key, spec := range f.schema {
+		if _, exists := f.data[key]; exists || f.rejected(key) {
+			continue
+		}
+		if v, ok := implicitValue(spec); ok {
+			f.set(key, v)
 		}
 	}
 
-	if len(f.errs) > 0 {
-		// Aggregate errors into a single message
-		msg := ""
-		for i, err := range f.errs {
-			if i > 0 {
-				msg += "; "
-			}
-			msg += err.Error()
-		}
-		return nil, errors.New(msg)
+	if errs := f.problems(); len(errs) > 0 {
+		return nil, errs
 	}
 	// 調用你剛剛寫好的 SAE.BuildSAE
-	return sae.BuildSAE(f.actionType, f.data)
+	fp, err := Fingerprint(f.schema)
+	if err != nil {
+		return nil, err
+	}
+	pre := []sae.Option{sae.WithSchemaFingerprint(fp)}
+	if f.schemaVersion != "" {
+		pre = append(pre, sae.WithSchemaVersion(f.schemaVersion))
+	}
+	opts = append(pre, opts...)
+	return sae.BuildSAE(f.actionType, f.data, opts...)
 }
 
-// ValidateData validates a map against schema (for server-side verification)
-func ValidateData(data map[string]any, schema map[string]FieldSpec) error {
-	var errs []error
+// Validate 回報目前累積的錯誤與尚未設定的必填欄位，不產生 SAE，
+// 讓 UI 在送出前就能顯示問題。回傳 ValidationErrors 或 nil。
+func (f *FluentAction) Validate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if errs := f.problems(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-	// Check all required fields in schema exist
-	for key, spec := range schema {
-		value, exists := data[key]
-		if !exists {
-			errs = append(errs, fmt.Errorf("missing field: %s", key))
+// Errors 回傳 Validate 的錯誤清單（每個元素為 *FieldError），沒有問題時為空
+func (f *FluentAction) Errors() []error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.problems().Unwrap()
+}
+
+// problems 是 Set 累積的錯誤加上缺少的必填欄位（已被 Set 拒絕的欄位
+// 不重複回報；const / default 欄位視為選填），已排序的副本
+func (f *FluentAction) problems() ValidationErrors {
+	errs := append(ValidationErrors(nil), f.errs...)
+	for key, spec := range f.schema {
+		if _, exists := f.data[key]; exists || f.rejected(key) {
 			continue
 		}
-		if err := validateValue(value, spec); err != nil {
-			errs = append(errs, fmt.Errorf("field %s: %w", key, err))
+		if _, ok := implicitValue(spec); !ok {
+			errs = append(errs, FieldError{Field: key, Code: CodeRequired, Message: "missing required field"})
 		}
 	}
+	return errs.sorted()
+}
 
-	// Check no extra fields
-	for key := range data {
-		if _, exists := schema[key]; !exists {
-			errs = append(errs, fmt.Errorf("unknown field: %s", key))
+func (f *FluentAction) rejected(key string) bool {
+	for _, fe := range f.errs {
+		if fe.Field == key {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(errs) > 0 {
-		// Aggregate errors into a single message
-		msg := ""
-		for i, err := range errs {
-			if i > 0 {
-				msg += "; "
-			}
-			msg += err.Error()
+// ApplyDefaults returns a copy of data with missing const and defaulted fields filled in.
+// Use it server-side to read the effective values of an older client's SDTO;
+// SAI and signatures still cover the data exactly as sent.
+func ApplyDefaults(data map[string]any, schema map[string]FieldSpec) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, v := range data {
+		out[k] = v
+	}
+	for key, spec := range schema {
+		if _, exists := out[key]; exists {
+			continue
+		}
+		if v, ok := implicitValue(spec); ok {
+			out[key] = v
 		}
-		return errors.New(msg)
 	}
-	return nil
+	return out
+}
+
+// ValidateData validates a map against schema (for server-side verification).
+// Unknown fields are rejected; see ValidateDataWithPolicy for rolling upgrades.
+// Outcomes are logged with WithLogger.
+func ValidateData(data map[string]any, schema map[string]FieldSpec, opts ...ValidateOption) error {
+	_, err := ValidateDataWithPolicy(data, schema, ExtraReject)
+	logValidation(newValidateConfig(opts).logger, data, err)
+	return err
 }
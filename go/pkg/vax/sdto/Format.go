@@ -0,0 +1,65 @@
+package sdto
+
+import (
+	"net/mail"
+	"net/url"
+	"time"
+)
+
+// 內建字串格式（與 JSON Schema format 名稱一致）
+const (
+	FormatEmail    = "email"
+	FormatURI      = "uri"
+	FormatUUID     = "uuid"
+	FormatDateTime = "date-time"
+)
+
+var supportedFormats = []string{FormatEmail, FormatURI, FormatUUID, FormatDateTime}
+
+func validateFormat(v string, format string) error {
+	switch format {
+	case FormatEmail:
+		addr, err := mail.ParseAddress(v)
+		if err != nil || addr.Name != "" || addr.Address != v {
+			return ruleError(CodeFormat, v, format, "value %q is not a valid email", v)
+		}
+	case FormatURI:
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme == "" {
+			return ruleError(CodeFormat, v, format, "value %q is not a valid uri", v)
+		}
+	case FormatUUID:
+		if !isUUID(v) {
+			return ruleError(CodeFormat, v, format, "value %q is not a valid uuid", v)
+		}
+	case FormatDateTime:
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return ruleError(CodeFormat, v, format, "value %q is not a valid date-time", v)
+		}
+	default:
+		return ruleError(CodeSchema, nil, nil, "unknown format %q", format)
+	}
+	return nil
+}
+
+// isUUID 檢查 8-4-4-4-12 十六進位格式（不限版本）
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			isHex := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+			if !isHex {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package sdto
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// JSONSchemaDraft 是匯出文件宣告的 JSON Schema 版本
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ExportJSONSchema 匯出標準 JSON Schema（給前端表單產生器用），
+// 與 Build() 的內部傳輸格式（min/max 為字串）不同。
+func (b *SchemaBuilder) ExportJSONSchema() map[string]any {
+	return JSONSchema(b.Actions)
+}
+
+// JSONSchema converts a FieldSpec schema to a JSON Schema document:
+// minLength/maxLength, minimum/maximum, enum, format, default, const and a
+// required array (fields without a default or const). Extra fields are rejected
+// (additionalProperties: false), matching ValidateData.
+func JSONSchema(schema map[string]FieldSpec) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+
+	for name, c := range schema {
+		props[name] = fieldJSONSchema(c)
+		if _, ok := implicitValue(c); !ok {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	return map[string]any{
+		"$schema":              JSONSchemaDraft,
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func fieldJSONSchema(c FieldSpec) map[string]any {
+	m := map[string]any{}
+
+	if c.OneOf != nil {
+		// 驗證採「任一符合」（替代型別可重疊，如 number / integer），對應 anyOf
+		alts := make([]any, len(c.OneOf))
+		for i, alt := range c.OneOf {
+			alts[i] = fieldJSONSchema(alt)
+		}
+		m["anyOf"] = alts
+		setAnnotations(m, c)
+		return m
+	}
+
+	switch c.Type {
+	case "string":
+		m["type"] = "string"
+		setIntBound(m, "minLength", c.Min)
+		setIntBound(m, "maxLength", c.Max)
+		// JSON Schema 以 code point 計長度；其他單位另外標註
+		if c.LengthUnit != "" && c.LengthUnit != LengthRunes {
+			m["x-length-unit"] = c.LengthUnit
+		}
+		if c.Format != "" {
+			m["format"] = c.Format
+		}
+	case "number", "integer":
+		m["type"] = c.Type
+		if c.ExclusiveMin {
+			setNumberBound(m, "exclusiveMinimum", c.Min)
+		} else {
+			setNumberBound(m, "minimum", c.Min)
+		}
+		if c.ExclusiveMax {
+			setNumberBound(m, "exclusiveMaximum", c.Max)
+		} else {
+			setNumberBound(m, "maximum", c.Max)
+		}
+		setNumberBound(m, "multipleOf", c.MultipleOf)
+	case "boolean":
+		m["type"] = "boolean"
+	case "map":
+		m["type"] = "object"
+		setIntBound(m, "minProperties", c.Min)
+		setIntBound(m, "maxProperties", c.Max)
+		if c.KeyPattern != "" {
+			m["propertyNames"] = map[string]any{"pattern": c.KeyPattern}
+		}
+		if c.Values != nil {
+			m["additionalProperties"] = fieldJSONSchema(*c.Values)
+		}
+	case "bytes":
+		// 大小限制針對解碼後內容，標準關鍵字無法表達
+		m["type"] = "string"
+		m["contentEncoding"] = "base64"
+	case "decimal":
+		// 金額以字串傳輸；範圍無法以標準關鍵字表達，只輸出語法
+		m["type"] = "string"
+		m["pattern"] = decimalPattern(c.Scale)
+	case "sign":
+		m["type"] = "string"
+		m["minLength"] = 1
+		m["x-sign-algs"] = c.Enum
+	default:
+		m["type"] = c.Type
+	}
+
+	switch {
+	case len(c.Enum) == 0 || c.Type == "sign":
+	case c.Type == "number" || c.Type == "integer":
+		values := make([]json.Number, len(c.Enum))
+		for i, e := range c.Enum {
+			values[i] = json.Number(e)
+		}
+		m["enum"] = values
+	default:
+		m["enum"] = c.Enum
+	}
+	setAnnotations(m, c)
+	return m
+}
+
+func setAnnotations(m map[string]any, c FieldSpec) {
+	if c.Default != nil {
+		m["default"] = *c.Default
+	}
+	if c.Const != nil {
+		m["const"] = *c.Const
+	}
+	if c.Sensitive {
+		m["x-sensitive"] = true
+	}
+}
+
+func setIntBound(m map[string]any, key string, bound *string) {
+	if bound == nil {
+		return
+	}
+	if n, err := strconv.Atoi(*bound); err == nil {
+		m[key] = n
+	}
+}
+
+func setNumberBound(m map[string]any, key string, bound *string) {
+	if bound == nil {
+		return
+	}
+	// json.Number 保留原始精度（不經 float64）
+	if _, ok := new(big.Rat).SetString(*bound); ok {
+		m[key] = json.Number(*bound)
+	}
+}
+
+func decimalPattern(scale *int) string {
+	frac := `[0-9]+`
+	if scale != nil {
+		frac = `[0-9]{1,` + strconv.Itoa(*scale) + `}`
+	}
+	return `^-?(0|[1-9][0-9]*)(\.` + frac + `)?$`
+}
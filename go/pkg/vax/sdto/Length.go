@@ -0,0 +1,116 @@
+package sdto
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// 字串長度單位（FieldSpec.LengthUnit），未設定時為 bytes（相容舊行為）
+const (
+	LengthBytes     = "bytes"
+	LengthRunes     = "runes"     // Unicode code points（同 JSON Schema minLength / maxLength）
+	LengthGraphemes = "graphemes" // 使用者感知的字元（extended grapheme clusters）
+)
+
+var supportedLengthUnits = []string{LengthBytes, LengthRunes, LengthGraphemes}
+
+// stringLength 依單位計算長度
+func stringLength(v string, unit string) int {
+	switch unit {
+	case LengthRunes:
+		return utf8.RuneCountInString(v)
+	case LengthGraphemes:
+		return graphemeCount(v)
+	default:
+		return len(v)
+	}
+}
+
+const (
+	zwj      = '\u200d'
+	riFirst  = '\U0001F1E6' // regional indicator A
+	riLast   = '\U0001F1FF'
+	emojiMod = '\U0001F3FB' // skin tone modifiers 1F3FB–1F3FF
+)
+
+// graphemeCount 以 UAX #29 的主要規則近似計算 grapheme cluster 數：
+// CR LF、結合符號（Mn / Me / Mc）、變體選擇符、膚色修飾、ZWJ 序列、
+// 國旗（regional indicator 成對）、韓文字母組合。只用標準庫，不含完整屬性表。
+func graphemeCount(s string) int {
+	count := 0
+	var prev rune = -1
+	riRun := 0 // 連續 regional indicator 數
+
+	for _, r := range s {
+		if prev >= 0 && !isGraphemeBreak(prev, r, riRun) {
+			if isRegionalIndicator(r) {
+				riRun++
+			}
+			prev = r
+			continue
+		}
+
+		count++
+		riRun = 0
+		if isRegionalIndicator(r) {
+			riRun = 1
+		}
+		prev = r
+	}
+	return count
+}
+
+func isGraphemeBreak(prev, r rune, riRun int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return false
+	case isControl(prev) || isControl(r):
+		return true
+	case isExtend(r) || r == zwj:
+		return false
+	case prev == zwj && unicode.Is(unicode.So, r):
+		return false // emoji ZWJ sequence
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		return riRun%2 == 0 // 國旗兩兩成對
+	case isHangulJamo(prev) && isHangulJamo(r):
+		return !hangulJoins(prev, r)
+	}
+	return true
+}
+
+func isControl(r rune) bool {
+	return r == '\r' || r == '\n' || (unicode.IsControl(r) && r != zwj)
+}
+
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		unicode.Is(unicode.Variation_Selector, r) ||
+		(r >= emojiMod && r <= emojiMod+4)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= riFirst && r <= riLast
+}
+
+// 韓文：L = 1100–115F，V = 1160–11A7，T = 11A8–11FF，LV/LVT 音節 = AC00–D7A3
+func isHangulJamo(r rune) bool {
+	return (r >= 0x1100 && r <= 0x11FF) || (r >= 0xAC00 && r <= 0xD7A3)
+}
+
+func hangulJoins(prev, r rune) bool {
+	isL := func(r rune) bool { return r >= 0x1100 && r <= 0x115F }
+	isV := func(r rune) bool { return r >= 0x1160 && r <= 0x11A7 }
+	isT := func(r rune) bool { return r >= 0x11A8 && r <= 0x11FF }
+	isSyl := func(r rune) bool { return r >= 0xAC00 && r <= 0xD7A3 }
+	isLV := func(r rune) bool { return isSyl(r) && (r-0xAC00)%28 == 0 }
+
+	switch {
+	case isL(prev):
+		return isL(r) || isV(r) || isSyl(r)
+	case isV(prev) || isLV(prev):
+		return isV(r) || isT(r)
+	case isT(prev) || isSyl(prev):
+		return isT(r)
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package sdto
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// keyPatterns 快取已編譯的 key_pattern（schema 通常長期重用）
+var keyPatterns sync.Map // string → *regexp.Regexp
+
+func compileKeyPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := keyPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	keyPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// validateMap 驗證 map 欄位（使用者自訂的 metadata 等）：
+// key 為字串並符合 KeyPattern，value 符合 Values，Min / Max 為項目數。
+func validateMap(value any, c FieldSpec) error {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return ruleError(CodeType, jsonType(value), "object", "expected object")
+	}
+
+	if c.Min != nil {
+		if n, err := strconv.Atoi(*c.Min); err == nil && len(m) < n {
+			return ruleError(CodeMin, len(m), n, "map entries %d < min %d", len(m), n)
+		}
+	}
+	if c.Max != nil {
+		if n, err := strconv.Atoi(*c.Max); err == nil && len(m) > n {
+			return ruleError(CodeMax, len(m), n, "map entries %d > max %d", len(m), n)
+		}
+	}
+
+	var re *regexp.Regexp
+	if c.KeyPattern != "" {
+		var err error
+		if re, err = compileKeyPattern(c.KeyPattern); err != nil {
+			return ruleError(CodeSchema, nil, nil, "invalid key_pattern: %v", err)
+		}
+	}
+
+	// 依 key 排序，錯誤訊息穩定
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if re != nil && !re.MatchString(k) {
+			return ruleError(CodeFormat, k, c.KeyPattern, "key %q does not match %s", k, c.KeyPattern)
+		}
+		if c.Values == nil {
+			continue
+		}
+		if err := validateValue(m[k], *c.Values); err != nil {
+			fe := fieldError("", err)
+			fe.Message = "key " + strconv.Quote(k) + ": " + fe.Message
+			fe.path = append([]string{k}, fe.path...)
+			return &fe
+		}
+	}
+	return nil
+}
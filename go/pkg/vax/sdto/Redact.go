@@ -0,0 +1,21 @@
+package sdto
+
+// RedactedValue 取代 Redact 遮蔽的值
+const RedactedValue = "[REDACTED]"
+
+// Redact returns a copy of data that is safe to log: values of Sensitive
+// fields, and of fields the schema does not define (their content is
+// unknown), are replaced with RedactedValue. Map values are masked as a
+// whole. data itself is not modified; never hash or sign the result.
+func Redact(data map[string]any, schema map[string]FieldSpec) map[string]any {
+	out := make(map[string]any, len(data))
+	for key, value := range data {
+		spec, known := schema[key]
+		if !known || spec.Sensitive {
+			out[key] = RedactedValue
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
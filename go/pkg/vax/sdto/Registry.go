@@ -0,0 +1,117 @@
+package sdto
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"vax/pkg/vax/sae"
+)
+
+// ErrUnknownSchema is returned when no schema is registered for (actionType, version).
+var ErrUnknownSchema = errors.New("unknown schema")
+
+type registryKey struct {
+	actionType string
+	version    string
+}
+
+// Registry maps (actionType, schema version) to a FieldSpec schema.
+// Verifiers look schemas up by the envelope's action_type / schema_version,
+// so an envelope is always validated against the schema it was built with.
+// Safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	schemas    map[registryKey]map[string]FieldSpec
+	current    map[string]string // actionType → current version
+	migrations map[registryKey]migrationStep
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas:    make(map[registryKey]map[string]FieldSpec),
+		current:    make(map[string]string),
+		migrations: make(map[registryKey]migrationStep),
+	}
+}
+
+// Register 註冊 (actionType, version) 對應的 schema；version 可為空字串（未版本化）
+func (r *Registry) Register(actionType, version string, schema map[string]FieldSpec) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[registryKey{actionType, version}] = schema
+	return r
+}
+
+// Lookup returns the schema registered for (actionType, version).
+func (r *Registry) Lookup(actionType, version string) (map[string]FieldSpec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schema, ok := r.schemas[registryKey{actionType, version}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@%s", ErrUnknownSchema, actionType, version)
+	}
+	return schema, nil
+}
+
+// Versions lists the registered versions of an action type (sorted).
+func (r *Registry) Versions(actionType string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var versions []string
+	for k := range r.schemas {
+		if k.actionType == actionType {
+			versions = append(versions, k.version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// NewAction 依 (actionType, version) 取得 schema 並建立 FluentAction，Finalize 時寫入 schema_version
+func (r *Registry) NewAction(actionType, version string) (*FluentAction, error) {
+	schema, err := r.Lookup(actionType, version)
+	if err != nil {
+		return nil, err
+	}
+	return NewAction(actionType, schema).SetSchemaVersion(version), nil
+}
+
+// ValidateEnvelope validates env.SDTO against the schema registered for
+// env.ActionType and env.SchemaVersion, and checks the embedded schema
+// fingerprint when present. Missing required fields and unknown fields
+// are rejected.
+func (r *Registry) ValidateEnvelope(env *sae.Envelope) error {
+	_, err := r.ValidateEnvelopeWithPolicy(env, ExtraReject)
+	return err
+}
+
+// ValidateEnvelopeWithPolicy is ValidateEnvelope with a configurable
+// policy for unknown fields (see ValidateDataWithPolicy). Required fields
+// are enforced under every policy. ExtraStrip removes fields from the parsed
+// env.SDTO only; the signed bytes and their SAI are unchanged.
+func (r *Registry) ValidateEnvelopeWithPolicy(env *sae.Envelope, policy ExtraFieldPolicy) ([]string, error) {
+	schema, err := r.Lookup(env.ActionType, env.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyFingerprint(env, schema); err != nil {
+		return nil, err
+	}
+	return ValidateDataWithPolicy(env.SDTO, schema, policy)
+}
+
+// ParseAndValidate parses SAE bytes and validates them via ValidateEnvelope.
+func (r *Registry) ParseAndValidate(saeBytes []byte) (*sae.Envelope, error) {
+	env, err := sae.Parse(saeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ValidateEnvelope(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
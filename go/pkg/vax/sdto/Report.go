@@ -0,0 +1,79 @@
+package sdto
+
+import (
+	"sort"
+	"strings"
+)
+
+// Report 是 ValidateDataReport 的結果，給 admin UI 解釋拒絕原因用
+type Report struct {
+	Valid    bool                   `json:"valid"`
+	Errors   []ReportError          `json:"errors"`
+	Fields   map[string]FieldStatus `json:"fields"`
+	Document map[string]any         `json:"document"`
+}
+
+// ReportError 是帶有 JSON Pointer（RFC 6901）路徑的 FieldError，
+// 例如 map 欄位內的值為 "/labels/env"
+type ReportError struct {
+	Path string `json:"path"`
+	FieldError
+}
+
+// FieldStatus 是單一欄位（含 schema 以外的欄位）的驗證結果
+type FieldStatus struct {
+	Valid     bool `json:"valid"`
+	Defaulted bool `json:"defaulted,omitempty"` // 未送出，以 const / default 補上
+	Unknown   bool `json:"unknown,omitempty"`   // schema 沒有定義
+}
+
+// ValidateDataReport runs the same checks as ValidateData but returns every
+// error with its JSON path, a pass/fail status per field and the effective
+// document (schema fields only, const / default values applied). Run
+// CoerceData first to report on coerced input.
+func ValidateDataReport(data map[string]any, schema map[string]FieldSpec) Report {
+	r := Report{
+		Valid:    true,
+		Errors:   []ReportError{},
+		Fields:   make(map[string]FieldStatus, len(schema)),
+		Document: make(map[string]any, len(schema)),
+	}
+
+	for key := range schema {
+		_, sent := data[key]
+		r.Fields[key] = FieldStatus{Valid: true, Defaulted: !sent}
+	}
+	for key, value := range ApplyDefaults(data, schema) {
+		if _, ok := schema[key]; ok {
+			r.Document[key] = value
+		} else {
+			r.Fields[key] = FieldStatus{Unknown: true}
+		}
+	}
+
+	if err := ValidateData(data, schema); err != nil {
+		r.Valid = false
+		for _, fe := range err.(ValidationErrors) {
+			r.Errors = append(r.Errors, ReportError{Path: jsonPointer(fe.Field, fe.path), FieldError: fe})
+			status := r.Fields[fe.Field]
+			status.Valid = false
+			if fe.Code == CodeRequired {
+				status.Defaulted = false
+			}
+			r.Fields[fe.Field] = status
+		}
+	}
+	sort.SliceStable(r.Errors, func(i, j int) bool { return r.Errors[i].Path < r.Errors[j].Path })
+	return r
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func jsonPointer(field string, path []string) string {
+	var sb strings.Builder
+	for _, seg := range append([]string{field}, path...) {
+		sb.WriteByte('/')
+		sb.WriteString(pointerEscaper.Replace(seg))
+	}
+	return sb.String()
+}
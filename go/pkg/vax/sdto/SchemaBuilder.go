@@ -1,7 +1,13 @@
 package sdto
 
+import "vax/pkg/vax/sae"
+
 type SchemaBuilder struct {
 	Actions map[string]FieldSpec
+
+	actions map[string]*ActionBuilder // 多 action 定義，見 Action()
+	defs    map[string]FieldSpec      // 可重用的欄位定義，見 Define()
+	errs    ValidationErrors          // Use / Include 的錯誤，BuildSchema 時回報
 }
 
 // 啟動點
@@ -31,6 +37,79 @@ func (b *SchemaBuilder) SetActionNumberRange(action string, min string, max stri
 	return b
 }
 
+// 設定行動整數範圍限制（拒絕小數）
+func (b *SchemaBuilder) SetActionIntegerRange(action string, min string, max string) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{
+		Type: "integer",
+		Min:  &min,
+		Max:  &max,
+	}
+	return b
+}
+
+// 設定十進位字串欄位（金額用），precision / scale 為 0 表示不限
+func (b *SchemaBuilder) SetActionDecimal(action string, min string, max string, precision int, scale int) *SchemaBuilder {
+	spec := FieldSpec{
+		Type: "decimal",
+		Min:  &min,
+		Max:  &max,
+	}
+	if precision > 0 {
+		spec.Precision = &precision
+	}
+	if scale > 0 {
+		spec.Scale = &scale
+	}
+	b.Actions[action] = spec
+	return b
+}
+
+// 設定 bytes 欄位（base64 字串），min / max 為解碼後位元組數
+func (b *SchemaBuilder) SetActionBytes(action string, min string, max string) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{
+		Type: "bytes",
+		Min:  &min,
+		Max:  &max,
+	}
+	return b
+}
+
+// 設定 map 欄位：key 需符合 keyPattern（空字串不限），value 需符合 values，
+// min / max 為項目數
+func (b *SchemaBuilder) SetActionMap(action string, keyPattern string, values FieldSpec, min string, max string) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{
+		Type:       "map",
+		Min:        &min,
+		Max:        &max,
+		KeyPattern: keyPattern,
+		Values:     &values,
+	}
+	return b
+}
+
+// 設定 union 欄位：值符合任一替代型別即可
+func (b *SchemaBuilder) SetActionOneOf(action string, alternatives ...FieldSpec) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{OneOf: alternatives}
+	return b
+}
+
+// 設定布林欄位
+func (b *SchemaBuilder) SetActionBoolean(action string) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{
+		Type: "boolean",
+	}
+	return b
+}
+
+// 設定數字列舉（以數值精確比較，"1" 與 1.0 相符）
+func (b *SchemaBuilder) SetActionNumberEnum(action string, values []string) *SchemaBuilder {
+	b.Actions[action] = FieldSpec{
+		Type: "number",
+		Enum: values,
+	}
+	return b
+}
+
 // 設定行動列舉限制
 func (b *SchemaBuilder) SetActionEnum(action string, values []string) *SchemaBuilder {
 	b.Actions[action] = FieldSpec{
@@ -40,8 +119,8 @@ func (b *SchemaBuilder) SetActionEnum(action string, values []string) *SchemaBui
 	return b
 }
 
-// 支援的簽名類型
-var SupportedSignTypes = []string{"ed25519", "rsa", "ecdsa"}
+// 支援的簽名類型（與 sae.Envelope.Alg 一致）
+var SupportedSignTypes = []string{sae.AlgEd25519, sae.AlgRSA, sae.AlgECDSA}
 
 // 設定簽名欄位，指定簽名演算法類型
 func (b *SchemaBuilder) SetActionSign(action string, signType string) *SchemaBuilder {
@@ -61,9 +140,85 @@ func (b *SchemaBuilder) SetActionSignMulti(action string, signTypes []string) *S
 	return b
 }
 
-// BuildSchema 回傳給 constructor 用的 FieldSpec map
-func (b *SchemaBuilder) BuildSchema() map[string]FieldSpec {
-	return b.Actions
+// 設定字串欄位格式（欄位需先定義），見 FormatEmail 等常數
+func (b *SchemaBuilder) SetFormat(action string, format string) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.Format = format
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 設定字串長度單位（欄位需先定義）：LengthBytes / LengthRunes / LengthGraphemes
+func (b *SchemaBuilder) SetLengthUnit(action string, unit string) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.LengthUnit = unit
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 設定開區間（欄位需先定義）：exclusiveMin 表示 > min，exclusiveMax 表示 < max
+func (b *SchemaBuilder) SetExclusiveBounds(action string, exclusiveMin bool, exclusiveMax bool) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.ExclusiveMin = exclusiveMin
+		spec.ExclusiveMax = exclusiveMax
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 設定倍數限制（欄位需先定義），例如 "0.01" 表示金額最小單位為分
+func (b *SchemaBuilder) SetMultipleOf(action string, multipleOf string) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.MultipleOf = &multipleOf
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 設定欄位預設值（欄位需先定義），有預設值的欄位為選填
+func (b *SchemaBuilder) SetDefault(action string, value any) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.Default = &value
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 設定欄位固定值（欄位需先定義），其他值一律拒絕，未設定時自動補上
+func (b *SchemaBuilder) SetConst(action string, value any) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.Const = &value
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// 標記欄位為敏感資料（欄位需先定義），Redact 時遮蔽
+func (b *SchemaBuilder) SetSensitive(action string) *SchemaBuilder {
+	if spec, ok := b.Actions[action]; ok {
+		spec.Sensitive = true
+		b.Actions[action] = spec
+	}
+	return b
+}
+
+// BuildSchema 驗證後回傳給 constructor 用的 FieldSpec map（見 Validate）
+func (b *SchemaBuilder) BuildSchema() (map[string]FieldSpec, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b.Actions, nil
+}
+
+// MustBuildSchema 同 BuildSchema，schema 無效時 panic（用於固定於程式碼中的 schema）
+func (b *SchemaBuilder) MustBuildSchema() map[string]FieldSpec {
+	schema, err := b.BuildSchema()
+	if err != nil {
+		panic("sdto: invalid schema: " + err.Error())
+	}
+	return schema
 }
 
 // Build 回傳 JSON 友善格式（跨語言傳輸用）
@@ -71,19 +226,7 @@ func (b *SchemaBuilder) Build() map[string]any {
 	props := map[string]any{}
 
 	for name, c := range b.Actions {
-		m := map[string]any{
-			"type": c.Type,
-		}
-		if c.Min != nil {
-			m["min"] = *c.Min
-		}
-		if c.Max != nil {
-			m["max"] = *c.Max
-		}
-		if len(c.Enum) > 0 {
-			m["enum"] = c.Enum
-		}
-		props[name] = m
+		props[name] = fieldSpecMap(c)
 	}
 
 	return map[string]any{
@@ -91,3 +234,63 @@ func (b *SchemaBuilder) Build() map[string]any {
 		"properties": props,
 	}
 }
+
+func fieldSpecMap(c FieldSpec) map[string]any {
+	m := map[string]any{}
+	if c.Type != "" {
+		m["type"] = c.Type
+	}
+	if c.Min != nil {
+		m["min"] = *c.Min
+	}
+	if c.Max != nil {
+		m["max"] = *c.Max
+	}
+	if len(c.Enum) > 0 {
+		m["enum"] = c.Enum
+	}
+	if c.ExclusiveMin {
+		m["exclusive_min"] = true
+	}
+	if c.ExclusiveMax {
+		m["exclusive_max"] = true
+	}
+	if c.MultipleOf != nil {
+		m["multiple_of"] = *c.MultipleOf
+	}
+	if c.Precision != nil {
+		m["precision"] = *c.Precision
+	}
+	if c.Scale != nil {
+		m["scale"] = *c.Scale
+	}
+	if c.LengthUnit != "" {
+		m["length_unit"] = c.LengthUnit
+	}
+	if c.Format != "" {
+		m["format"] = c.Format
+	}
+	if c.KeyPattern != "" {
+		m["key_pattern"] = c.KeyPattern
+	}
+	if c.Values != nil {
+		m["values"] = fieldSpecMap(*c.Values)
+	}
+	if c.OneOf != nil {
+		alts := make([]any, len(c.OneOf))
+		for i, alt := range c.OneOf {
+			alts[i] = fieldSpecMap(alt)
+		}
+		m["one_of"] = alts
+	}
+	if c.Default != nil {
+		m["default"] = *c.Default
+	}
+	if c.Const != nil {
+		m["const"] = *c.Const
+	}
+	if c.Sensitive {
+		m["sensitive"] = true
+	}
+	return m
+}
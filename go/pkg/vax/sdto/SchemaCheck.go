@@ -0,0 +1,177 @@
+package sdto
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+// Validate 檢查 builder 內 schema 的一致性（見 ValidateSchema）
+func (b *SchemaBuilder) Validate() error {
+	if len(b.errs) > 0 {
+		return append(ValidationErrors(nil), b.errs...).sorted()
+	}
+	return ValidateSchema(b.Actions)
+}
+
+// ValidateSchema rejects schemas that could never validate correctly:
+// unknown types or formats, non-numeric bounds, min > max, empty enums,
+// sign types outside SupportedSignTypes, inconsistent precision/scale and
+// defaults or consts that violate their own field. Problems are returned as
+// ValidationErrors with Code CodeSchema.
+func ValidateSchema(schema map[string]FieldSpec) error {
+	var errs ValidationErrors
+	bad := func(field, format string, args ...any) {
+		fe := ruleError(CodeSchema, nil, nil, format, args...)
+		fe.Field = field
+		errs = append(errs, *fe)
+	}
+
+	for name, c := range schema {
+		checkFieldSpec(name, c, bad)
+	}
+
+	if len(errs) > 0 {
+		return errs.sorted()
+	}
+	return nil
+}
+
+func parseBound(fieldType, bound string) (*big.Rat, bool) {
+	switch fieldType {
+	case "string", "bytes", "map":
+		n, err := strconv.Atoi(bound)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		return new(big.Rat).SetInt64(int64(n)), true
+	case "number", "integer", "decimal":
+		return new(big.Rat).SetString(bound)
+	default:
+		// boolean / sign 沒有範圍
+		return nil, false
+	}
+}
+
+func checkFieldSpec(name string, c FieldSpec, bad func(field, format string, args ...any)) {
+	if c.OneOf != nil {
+		checkUnionSpec(name, c, bad)
+		return
+	}
+	if !contains(SupportedTypes, c.Type) {
+		bad(name, "unknown type %q", c.Type)
+		return
+	}
+
+	// bounds：string 為長度、bytes 為解碼後大小、map 為項目數（非負整數），其餘數值型別為有理數
+	var lo, hi *big.Rat
+	for _, b := range []struct {
+		key   string
+		bound *string
+		out   **big.Rat
+	}{{"min", c.Min, &lo}, {"max", c.Max, &hi}} {
+		if b.bound == nil {
+			continue
+		}
+		r, ok := parseBound(c.Type, *b.bound)
+		if !ok {
+			bad(name, "%s %q is not a valid bound for %s", b.key, *b.bound, c.Type)
+			continue
+		}
+		*b.out = r
+	}
+	if lo != nil && hi != nil {
+		if lo.Cmp(hi) > 0 {
+			bad(name, "min %s > max %s", *c.Min, *c.Max)
+		} else if lo.Cmp(hi) == 0 && (c.ExclusiveMin || c.ExclusiveMax) {
+			bad(name, "exclusive bounds %s..%s admit no value", *c.Min, *c.Max)
+		}
+	}
+
+	numeric := c.Type == "number" || c.Type == "integer" || c.Type == "decimal"
+	if (c.ExclusiveMin || c.ExclusiveMax || c.MultipleOf != nil) && !numeric {
+		bad(name, "exclusive bounds and multiple_of apply to numeric types only")
+	}
+	if c.MultipleOf != nil {
+		if m, ok := new(big.Rat).SetString(*c.MultipleOf); !ok || m.Sign() <= 0 {
+			bad(name, "multiple_of %q must be a positive number", *c.MultipleOf)
+		}
+	}
+
+	if c.Enum != nil && len(c.Enum) == 0 {
+		bad(name, "enum is empty")
+	}
+	if c.Type == "number" || c.Type == "integer" {
+		for _, e := range c.Enum {
+			if _, ok := new(big.Rat).SetString(e); !ok {
+				bad(name, "enum value %q is not a number", e)
+			}
+		}
+	}
+	if c.Type == "sign" {
+		if len(c.Enum) == 0 {
+			bad(name, "sign field needs at least one algorithm")
+		}
+		for _, alg := range c.Enum {
+			if !contains(SupportedSignTypes, alg) {
+				bad(name, "unsupported sign type %q", alg)
+			}
+		}
+	}
+
+	if c.LengthUnit != "" && (c.Type != "string" || !contains(supportedLengthUnits, c.LengthUnit)) {
+		bad(name, "length_unit %q not supported for %s", c.LengthUnit, c.Type)
+	}
+	if c.Format != "" && !contains(supportedFormats, c.Format) {
+		bad(name, "unknown format %q", c.Format)
+	}
+	if (c.Precision != nil && *c.Precision <= 0) || (c.Scale != nil && *c.Scale < 0) {
+		bad(name, "precision must be positive and scale non-negative")
+	} else if c.Precision != nil && c.Scale != nil && *c.Scale > *c.Precision {
+		bad(name, "scale %d > precision %d", *c.Scale, *c.Precision)
+	}
+
+	checkImplicit(name, c, bad)
+
+	if c.KeyPattern != "" {
+		if _, err := regexp.Compile(c.KeyPattern); err != nil {
+			bad(name, "key_pattern: %v", err)
+		}
+	}
+	if (c.KeyPattern != "" || c.Values != nil) && c.Type != "map" {
+		bad(name, "key_pattern and values apply to map fields only")
+	}
+	if c.Values != nil {
+		checkFieldSpec(name+".values", *c.Values, bad)
+	}
+}
+
+func checkUnionSpec(name string, c FieldSpec, bad func(field, format string, args ...any)) {
+	if len(c.OneOf) < 2 {
+		bad(name, "one_of needs at least two alternatives")
+	}
+	if !unionOnly(c) {
+		bad(name, "union fields take no type or constraints besides one_of and default")
+	}
+	for i, alt := range c.OneOf {
+		checkFieldSpec(fmt.Sprintf("%s.one_of[%d]", name, i), alt, bad)
+	}
+	checkImplicit(name, c, bad)
+}
+
+// checkImplicit 要求 const 符合欄位規則、default 符合欄位規則（含 const）
+func checkImplicit(name string, c FieldSpec, bad func(field, format string, args ...any)) {
+	if c.Const != nil {
+		rules := c
+		rules.Const = nil
+		if err := validateValue(*c.Const, rules); err != nil {
+			bad(name, "invalid const: %v", err)
+		}
+	}
+	if c.Default != nil {
+		if err := validateValue(*c.Default, c); err != nil {
+			bad(name, "invalid default: %v", err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package sdto
+
+import (
+	"errors"
+	"fmt"
+
+	"vax/pkg/vax/sae"
+)
+
+// ErrNoMigration is returned when old-version data has no migration path
+// to the current version.
+var ErrNoMigration = errors.New("no migration path")
+
+// SchemaSet 是帶版本號的 schema（一個 action type 的某一版）
+type SchemaSet struct {
+	Version string               `json:"version"`
+	Fields  map[string]FieldSpec `json:"fields"`
+}
+
+// BuildVersion 以目前欄位產出一版 SchemaSet。
+// 欄位 map 為複本，之後可繼續修改 builder 再發佈下一版。
+func (b *SchemaBuilder) BuildVersion(version string) SchemaSet {
+	fields := make(map[string]FieldSpec, len(b.Actions))
+	for k, v := range b.Actions {
+		fields[k] = v
+	}
+	return SchemaSet{Version: version, Fields: fields}
+}
+
+// Migration 把某一版的 sdto 升級到下一版（回傳新 map，不應修改輸入）
+type Migration func(data map[string]any) (map[string]any, error)
+
+type migrationStep struct {
+	to string
+	fn Migration
+}
+
+// RegisterSet 依序註冊多個版本，最後一個成為 current 版本
+func (r *Registry) RegisterSet(actionType string, sets ...SchemaSet) *Registry {
+	for _, set := range sets {
+		r.Register(actionType, set.Version, set.Fields)
+	}
+	if len(sets) > 0 {
+		r.SetCurrent(actionType, sets[len(sets)-1].Version)
+	}
+	return r
+}
+
+// RegisterAll 以同一版本註冊 BuildAll 的所有 action
+func (r *Registry) RegisterAll(version string, schemas map[string]map[string]FieldSpec) *Registry {
+	for actionType, schema := range schemas {
+		r.Register(actionType, version, schema)
+	}
+	return r
+}
+
+// SetCurrent 指定 action type 的 current 版本（伺服器端實際處理的版本）
+func (r *Registry) SetCurrent(actionType, version string) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current[actionType] = version
+	return r
+}
+
+// Current returns the current SchemaSet of an action type.
+func (r *Registry) Current(actionType string) (SchemaSet, error) {
+	r.mu.RLock()
+	version, ok := r.current[actionType]
+	r.mu.RUnlock()
+	if !ok {
+		return SchemaSet{}, fmt.Errorf("%w: %s (no current version)", ErrUnknownSchema, actionType)
+	}
+	fields, err := r.Lookup(actionType, version)
+	if err != nil {
+		return SchemaSet{}, err
+	}
+	return SchemaSet{Version: version, Fields: fields}, nil
+}
+
+// RegisterMigration 註冊 from → to 的升級函式；多步升級會依序串接
+func (r *Registry) RegisterMigration(actionType, from, to string, m Migration) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrations[registryKey{actionType, from}] = migrationStep{to: to, fn: m}
+	return r
+}
+
+// Upgrade runs migrations from version up to the current version and returns
+// the upgraded data. Data already at the current version is returned as is.
+func (r *Registry) Upgrade(actionType, version string, data map[string]any) (map[string]any, error) {
+	current, err := r.Current(actionType)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// 每一步最多走一次，避免循環註冊造成無限迴圈
+	for steps := 0; version != current.Version; steps++ {
+		step, ok := r.migrations[registryKey{actionType, version}]
+		if !ok || steps > len(r.migrations) {
+			return nil, fmt.Errorf("%w: %s@%s → %s", ErrNoMigration, actionType, version, current.Version)
+		}
+		if data, err = step.fn(data); err != nil {
+			return nil, fmt.Errorf("migrate %s@%s → %s: %w", actionType, version, step.to, err)
+		}
+		version = step.to
+	}
+	return data, nil
+}
+
+// ValidateAndUpgrade 伺服器端驗證：先依 SAE 宣告的版本驗證（含指紋），
+// 再升級到 current 版本並驗證結果。回傳升級後的資料；
+// SAI 與簽章仍涵蓋原始送出的 sdto。
+func (r *Registry) ValidateAndUpgrade(env *sae.Envelope) (map[string]any, error) {
+	if err := r.ValidateEnvelope(env); err != nil {
+		return nil, err
+	}
+
+	data, err := r.Upgrade(env.ActionType, env.SchemaVersion, env.SDTO)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.Current(env.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateData(data, current.Fields); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,51 @@
+package sdto
+
+import (
+	"crypto"
+	"fmt"
+
+	"vax/pkg/vax/sae"
+)
+
+// AllowedSignAlgs 回傳 schema 內所有 sign 欄位允許的簽名演算法（去重）
+func AllowedSignAlgs(schema map[string]FieldSpec) []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, spec := range schema {
+		if spec.Type != "sign" {
+			continue
+		}
+		for _, alg := range spec.Enum {
+			if !seen[alg] {
+				seen[alg] = true
+				algs = append(algs, alg)
+			}
+		}
+	}
+	return algs
+}
+
+// VerifySigned verifies an envelope signature and checks that its algorithm
+// is one the schema's sign fields allow. Schemas without sign fields accept
+// any supported algorithm.
+func VerifySigned(env *sae.Envelope, publicKey crypto.PublicKey, schema map[string]FieldSpec) error {
+	alg := env.Alg
+	if alg == "" {
+		alg = sae.AlgEd25519
+	}
+
+	if allowed := AllowedSignAlgs(schema); len(allowed) > 0 {
+		ok := false
+		for _, a := range allowed {
+			if a == alg {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("signature algorithm %q not allowed by schema", alg)
+		}
+	}
+
+	return env.Verify(publicKey)
+}
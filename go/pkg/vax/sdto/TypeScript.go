@@ -0,0 +1,276 @@
+package sdto
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypeScriptModule 產生 TypeScript 模組：每個 action 一個 interface 與一個
+// Zod schema（import { z } from "zod"），讓 web client 以與 Go 相同的限制驗證。
+// 輸出依 action 名稱排序，可直接 commit 進前端 repo 並在 CI 比對。
+func TypeScriptModule(all map[string]map[string]FieldSpec) string {
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by sdto.TypeScriptModule. DO NOT EDIT.\n\n")
+	sb.WriteString("import { z } from \"zod\";\n")
+	for _, name := range names {
+		typeName := tsTypeName(name)
+		sb.WriteString("\n")
+		sb.WriteString(TypeScript(typeName, all[name]))
+		sb.WriteString("\n")
+		sb.WriteString(Zod(typeName+"Schema", all[name]))
+	}
+	return sb.String()
+}
+
+// TypeScript 產生 schema 對應的 TypeScript interface（有 default / const 的欄位為選填）
+func TypeScript(typeName string, schema map[string]FieldSpec) string {
+	var sb strings.Builder
+	sb.WriteString("export interface " + typeName + " {\n")
+	for _, name := range sortedFields(schema) {
+		c := schema[name]
+		opt := ""
+		if _, ok := implicitValue(c); ok {
+			opt = "?"
+		}
+		sb.WriteString("  " + tsKey(name) + opt + ": " + tsType(c) + ";\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Zod 產生 schema 對應的 Zod validator（.strict() 拒絕多餘欄位，同 ValidateData）
+func Zod(constName string, schema map[string]FieldSpec) string {
+	var sb strings.Builder
+	sb.WriteString("export const " + constName + " = z.object({\n")
+	for _, name := range sortedFields(schema) {
+		c := schema[name]
+		expr := zodExpr(c)
+		if _, ok := implicitValue(c); ok {
+			expr += ".optional()"
+		}
+		sb.WriteString("  " + tsKey(name) + ": " + expr + ",\n")
+	}
+	sb.WriteString("}).strict();\n")
+	return sb.String()
+}
+
+func tsType(c FieldSpec) string {
+	if c.Const != nil {
+		return jsLiteral(*c.Const)
+	}
+	if c.OneOf != nil {
+		alts := make([]string, len(c.OneOf))
+		for i, alt := range c.OneOf {
+			alts[i] = tsType(alt)
+		}
+		return strings.Join(alts, " | ")
+	}
+	switch c.Type {
+	case "number", "integer":
+		if len(c.Enum) > 0 {
+			return strings.Join(c.Enum, " | ")
+		}
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "map":
+		values := "unknown"
+		if c.Values != nil {
+			values = tsType(*c.Values)
+		}
+		return "Record<string, " + values + ">"
+	case "string":
+		if len(c.Enum) > 0 {
+			lits := make([]string, len(c.Enum))
+			for i, e := range c.Enum {
+				lits[i] = jsLiteral(e)
+			}
+			return strings.Join(lits, " | ")
+		}
+		return "string"
+	default:
+		// decimal / bytes / sign 皆以字串傳輸
+		return "string"
+	}
+}
+
+func zodExpr(c FieldSpec) string {
+	if c.Const != nil {
+		return "z.literal(" + jsLiteral(*c.Const) + ")"
+	}
+	if c.OneOf != nil {
+		alts := make([]string, len(c.OneOf))
+		for i, alt := range c.OneOf {
+			alts[i] = zodExpr(alt)
+		}
+		return "z.union([" + strings.Join(alts, ", ") + "])"
+	}
+
+	switch c.Type {
+	case "string":
+		if len(c.Enum) > 0 {
+			return "z.enum(" + jsLiteral(c.Enum) + ")"
+		}
+		expr := "z.string()"
+		switch c.Format {
+		case FormatEmail:
+			expr += ".email()"
+		case FormatURI:
+			expr += ".url()"
+		case FormatUUID:
+			expr += ".uuid()"
+		case FormatDateTime:
+			expr += ".datetime({ offset: true })"
+		}
+		return expr + zodLength(c)
+	case "number", "integer":
+		if len(c.Enum) > 0 {
+			lits := make([]string, len(c.Enum))
+			for i, e := range c.Enum {
+				lits[i] = "z.literal(" + e + ")"
+			}
+			if len(lits) == 1 {
+				return lits[0]
+			}
+			return "z.union([" + strings.Join(lits, ", ") + "])"
+		}
+		expr := "z.number()"
+		if c.Type == "integer" {
+			expr += ".int()"
+		}
+		if c.Min != nil {
+			if c.ExclusiveMin {
+				expr += ".gt(" + *c.Min + ")"
+			} else {
+				expr += ".gte(" + *c.Min + ")"
+			}
+		}
+		if c.Max != nil {
+			if c.ExclusiveMax {
+				expr += ".lt(" + *c.Max + ")"
+			} else {
+				expr += ".lte(" + *c.Max + ")"
+			}
+		}
+		if c.MultipleOf != nil {
+			expr += ".multipleOf(" + *c.MultipleOf + ")"
+		}
+		return expr
+	case "decimal":
+		// 範圍與精度以字串比較太複雜，前端只檢查語法，伺服器仍會完整驗證
+		return "z.string().regex(" + jsRegExp(decimalPattern(c.Scale)) + ")"
+	case "boolean":
+		return "z.boolean()"
+	case "bytes":
+		expr := "z.string().regex(/^[A-Za-z0-9+/]*={0,2}$/)"
+		return expr + zodRefineLength(c, "atob(v).length", "bytes")
+	case "map":
+		values := "z.unknown()"
+		if c.Values != nil {
+			values = zodExpr(*c.Values)
+		}
+		keys := "z.string()"
+		if c.KeyPattern != "" {
+			keys += ".regex(" + jsRegExp(c.KeyPattern) + ")"
+		}
+		return "z.record(" + keys + ", " + values + ")" + zodRefineLength(c, "Object.keys(v).length", "entries")
+	case "sign":
+		return "z.string().min(1)"
+	default:
+		return "z.unknown()"
+	}
+}
+
+// zodLength 依 LengthUnit 產生長度檢查（Zod 的 min / max 以 UTF-16 計算，不能直接用）
+func zodLength(c FieldSpec) string {
+	switch c.LengthUnit {
+	case LengthRunes:
+		return zodRefineLength(c, "[...v].length", "characters")
+	case LengthGraphemes:
+		return zodRefineLength(c, `[...new Intl.Segmenter().segment(v)].length`, "characters")
+	default:
+		return zodRefineLength(c, "new TextEncoder().encode(v).length", "bytes")
+	}
+}
+
+func zodRefineLength(c FieldSpec, length, unit string) string {
+	var conds, desc []string
+	if c.Min != nil {
+		conds = append(conds, length+" >= "+*c.Min)
+		desc = append(desc, "at least "+*c.Min)
+	}
+	if c.Max != nil {
+		conds = append(conds, length+" <= "+*c.Max)
+		desc = append(desc, "at most "+*c.Max)
+	}
+	if len(conds) == 0 {
+		return ""
+	}
+	msg := jsLiteral(strings.Join(desc, " and ") + " " + unit)
+	return ".refine((v) => " + strings.Join(conds, " && ") + ", { message: " + msg + " })"
+}
+
+func sortedFields(schema map[string]FieldSpec) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tsTypeName 把 action type 轉成 PascalCase（"user.create" → "UserCreate"）
+func tsTypeName(actionType string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range actionType {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "Action" + name
+	}
+	return name
+}
+
+// tsKey 合法識別字直接輸出，否則加引號
+func tsKey(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return jsLiteral(name)
+		}
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
+
+func jsLiteral(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "undefined"
+	}
+	return string(b)
+}
+
+// jsRegExp 以 RegExp 建構式輸出，避免 regex literal 的跳脫問題
+func jsRegExp(pattern string) string {
+	return "new RegExp(" + jsLiteral(pattern) + ")"
+}
@@ -0,0 +1,37 @@
+package sdto
+
+import "strings"
+
+// validateOneOf 依序嘗試每個替代型別，第一個通過即接受。
+// 全部失敗時回報最接近的錯誤：型別相符但限制不符者優先（例如
+// 字串太長），否則回報型別錯誤並列出所有可接受的型別。
+func validateOneOf(value any, c FieldSpec) error {
+	var best error
+	types := make([]string, 0, len(c.OneOf))
+	for _, alt := range c.OneOf {
+		err := validateValue(value, alt)
+		if err == nil {
+			return nil
+		}
+		types = append(types, alt.Type)
+		if fe, ok := err.(*FieldError); ok && fe.Code == CodeType {
+			continue
+		}
+		if best == nil {
+			best = err
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return ruleError(CodeType, jsonType(value), types, "expected one of %s", strings.Join(types, ", "))
+}
+
+// unionOnly 回報 union 欄位上是否只有 one_of（與 default）；
+// 其餘限制應寫在各替代型別內
+func unionOnly(c FieldSpec) bool {
+	return c.Type == "" && c.Min == nil && c.Max == nil && c.Enum == nil &&
+		!c.ExclusiveMin && !c.ExclusiveMax && c.MultipleOf == nil &&
+		c.Precision == nil && c.Scale == nil && c.KeyPattern == "" && c.Values == nil &&
+		c.LengthUnit == "" && c.Format == ""
+}
@@ -1,8 +1,16 @@
 package sdto
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 	"testing"
+	"time"
+
+	"vax/pkg/vax/sae"
 )
 
 func TestBuilderToConstructor_StringField(t *testing.T) {
@@ -12,7 +20,7 @@ func TestBuilderToConstructor_StringField(t *testing.T) {
 	schema.SetActionStringLength("email", "5", "100")
 
 	// Consumer uses schema to construct action
-	sae, err := NewAction("createUser", schema.BuildSchema()).
+	sae, err := NewAction("createUser", schema.MustBuildSchema()).
 		Set("name", "Alice").
 		Set("email", "alice@example.com").
 		Finalize()
@@ -30,7 +38,7 @@ func TestBuilderToConstructor_NumberField(t *testing.T) {
 	schema.SetActionNumberRange("amount", "0", "1000000")
 	schema.SetActionNumberRange("quantity", "1", "99")
 
-	sae, err := NewAction("purchase", schema.BuildSchema()).
+	sae, err := NewAction("purchase", schema.MustBuildSchema()).
 		Set("amount", 500.0).
 		Set("quantity", 3).
 		Finalize()
@@ -47,7 +55,7 @@ func TestBuilderToConstructor_EnumField(t *testing.T) {
 	schema := NewSchemaBuilder()
 	schema.SetActionEnum("status", []string{"pending", "completed", "cancelled"})
 
-	sae, err := NewAction("updateOrder", schema.BuildSchema()).
+	sae, err := NewAction("updateOrder", schema.MustBuildSchema()).
 		Set("status", "pending").
 		Finalize()
 
@@ -63,7 +71,7 @@ func TestBuilderToConstructor_ValidationError_UnknownField(t *testing.T) {
 	schema := NewSchemaBuilder()
 	schema.SetActionStringLength("name", "1", "50")
 
-	_, err := NewAction("createUser", schema.BuildSchema()).
+	_, err := NewAction("createUser", schema.MustBuildSchema()).
 		Set("name", "Alice").
 		Set("unknown", "value"). // undefined field
 		Finalize()
@@ -78,7 +86,7 @@ func TestBuilderToConstructor_ValidationError_MissingField(t *testing.T) {
 		SetActionStringLength("name", "1", "50").
 		SetActionStringLength("email", "5", "100").
 		SetActionNumberRange("age", "0", "150").
-		BuildSchema()
+		MustBuildSchema()
 
 	// Only set 'name', missing 'email' and 'age'
 	_, err := NewAction("createUser", schema).
@@ -100,7 +108,7 @@ func TestBuilderToConstructor_ValidationError_StringTooShort(t *testing.T) {
 	schema := NewSchemaBuilder()
 	schema.SetActionStringLength("name", "3", "50") // min length 3
 
-	_, err := NewAction("createUser", schema.BuildSchema()).
+	_, err := NewAction("createUser", schema.MustBuildSchema()).
 		Set("name", "AB"). // length 2, less than min
 		Finalize()
 
@@ -113,7 +121,7 @@ func TestBuilderToConstructor_ValidationError_NumberOutOfRange(t *testing.T) {
 	schema := NewSchemaBuilder()
 	schema.SetActionNumberRange("amount", "0", "100")
 
-	_, err := NewAction("purchase", schema.BuildSchema()).
+	_, err := NewAction("purchase", schema.MustBuildSchema()).
 		Set("amount", 150.0). // exceeds max
 		Finalize()
 
@@ -126,7 +134,7 @@ func TestBuilderToConstructor_ValidationError_EnumInvalid(t *testing.T) {
 	schema := NewSchemaBuilder()
 	schema.SetActionEnum("status", []string{"pending", "completed"})
 
-	_, err := NewAction("updateOrder", schema.BuildSchema()).
+	_, err := NewAction("updateOrder", schema.MustBuildSchema()).
 		Set("status", "invalid"). // not in enum
 		Finalize()
 
@@ -198,7 +206,7 @@ func TestValidateData_Valid(t *testing.T) {
 	builder := NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
 	builder.SetActionNumberRange("amount", "0", "1000")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	data := map[string]any{
 		"name":   "alice",
@@ -215,7 +223,7 @@ func TestValidateData_MissingField(t *testing.T) {
 	builder := NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
 	builder.SetActionNumberRange("amount", "0", "1000")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	data := map[string]any{
 		"name": "alice",
@@ -231,7 +239,7 @@ func TestValidateData_MissingField(t *testing.T) {
 func TestValidateData_ExtraField(t *testing.T) {
 	builder := NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	data := map[string]any{
 		"name":  "alice",
@@ -247,7 +255,7 @@ func TestValidateData_ExtraField(t *testing.T) {
 func TestValidateData_InvalidValue(t *testing.T) {
 	builder := NewSchemaBuilder()
 	builder.SetActionNumberRange("amount", "0", "100")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	data := map[string]any{
 		"amount": 999.0, // exceeds max
@@ -331,7 +339,7 @@ func TestBuild_RoundTrip(t *testing.T) {
 func TestValidateData_MissingRequiredField(t *testing.T) {
 	builder := NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	data := map[string]any{
 		"other": "value",
@@ -343,3 +351,1750 @@ func TestValidateData_MissingRequiredField(t *testing.T) {
 	}
 }
 
+func TestVerifySigned_SchemaAllowedAlgs(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "50").
+		SetActionSignMulti("sig", []string{"ecdsa"}).
+		MustBuildSchema()
+
+	pub, priv, _ := sae.GenerateKeyPair()
+	env := &sae.Envelope{ActionType: "createUser", Timestamp: 1, SDTO: map[string]any{"name": "Alice"}}
+	_ = env.Sign(priv)
+
+	err := VerifySigned(env, pub, schema)
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected algorithm rejection, got %v", err)
+	}
+
+	schema["sig"] = FieldSpec{Type: "sign", Enum: []string{"ed25519", "ecdsa"}}
+	if err := VerifySigned(env, pub, schema); err != nil {
+		t.Errorf("VerifySigned failed: %v", err)
+	}
+}
+
+func TestFinalize_WithOptions(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "50").
+		MustBuildSchema()
+
+	got, err := NewAction("createUser", schema).
+		Set("name", "Alice").
+		Finalize(sae.WithTimestamp(time.UnixMilli(1704672000000)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// fingerprint = SHA256(`{"name":{"max":"50","min":"1","type":"string"}}`)
+	want := `{"action_type":"createUser","schema_fingerprint":"ba89477fd0ae4f8fc1190db319fc479eb00eaf4714d21f60e2d48b710d942b24","sdto":{"name":"Alice"},"timestamp":1704672000000}`
+	if string(got) != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestRegistry_VersionBinding(t *testing.T) {
+	v1 := NewSchemaBuilder().SetActionNumberRange("amount", "0", "100").MustBuildSchema()
+	v2 := NewSchemaBuilder().
+		SetActionNumberRange("amount", "0", "1000").
+		SetActionEnum("currency", []string{"USD", "EUR"}).
+		MustBuildSchema()
+
+	reg := NewRegistry().
+		Register("transfer", "1", v1).
+		Register("transfer", "2", v2)
+
+	if got := reg.Versions("transfer"); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("Versions = %v, want [1 2]", got)
+	}
+
+	// Old client builds against v1
+	action, err := reg.NewAction("transfer", "1")
+	if err != nil {
+		t.Fatalf("NewAction failed: %v", err)
+	}
+	saeBytes, err := action.Set("amount", 50).Finalize()
+	if err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if !strings.Contains(string(saeBytes), `"schema_version":"1"`) {
+		t.Errorf("schema_version not embedded: %s", saeBytes)
+	}
+
+	// Server validates against the version the client declared
+	if _, err := reg.ParseAndValidate(saeBytes); err != nil {
+		t.Errorf("ParseAndValidate failed: %v", err)
+	}
+
+	// Same payload claimed as v2 fails (currency missing)
+	env, _ := sae.Parse(saeBytes)
+	env.SchemaVersion = "2"
+	if err := reg.ValidateEnvelope(env); err == nil {
+		t.Error("expected v2 validation error")
+	}
+
+	env.SchemaVersion = "3"
+	if err := reg.ValidateEnvelope(env); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("expected ErrUnknownSchema, got %v", err)
+	}
+}
+
+func TestBuilderToConstructor_BooleanAndInteger(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionIntegerRange("quantity", "1", "99").
+		SetActionBoolean("gift").
+		MustBuildSchema()
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := NewAction("purchase", schema).
+			Set("quantity", 3).
+			Set("gift", true).
+			Finalize()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("integral float and json.Number accepted", func(t *testing.T) {
+		data := map[string]any{"quantity": json.Number("42"), "gift": false}
+		if err := ValidateData(data, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		data["quantity"] = 5.0
+		if err := ValidateData(data, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error: fractional integer", func(t *testing.T) {
+		_, err := NewAction("purchase", schema).
+			Set("quantity", 2.5).
+			Set("gift", true).
+			Finalize()
+		if err == nil || !strings.Contains(err.Error(), "fractional") {
+			t.Errorf("expected fractional error, got %v", err)
+		}
+	})
+
+	t.Run("error: integer out of range", func(t *testing.T) {
+		data := map[string]any{"quantity": json.Number("100"), "gift": true}
+		if err := ValidateData(data, schema); err == nil {
+			t.Error("expected range error")
+		}
+	})
+
+	t.Run("error: boolean type", func(t *testing.T) {
+		data := map[string]any{"quantity": 1, "gift": "true"}
+		if err := ValidateData(data, schema); err == nil || !strings.Contains(err.Error(), "expected boolean") {
+			t.Errorf("expected boolean error, got %v", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		exported := NewSchemaBuilder().
+			SetActionIntegerRange("quantity", "1", "99").
+			SetActionBoolean("gift").
+			Build()
+		parsed := ParseSchema(exported["properties"].(map[string]any))
+		if parsed["quantity"].Type != "integer" || *parsed["quantity"].Max != "99" {
+			t.Errorf("quantity spec = %+v", parsed["quantity"])
+		}
+		if parsed["gift"].Type != "boolean" {
+			t.Errorf("gift spec = %+v", parsed["gift"])
+		}
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionNumberRange("amount", "0", "1000").
+		SetActionEnum("currency", []string{"USD", "EUR"}).
+		SetDefault("currency", "USD").
+		MustBuildSchema()
+	pinned := sae.WithTimestamp(time.UnixMilli(1704672000000))
+
+	t.Run("finalize fills unset field", func(t *testing.T) {
+		saeBytes, err := NewAction("transfer", schema).Set("amount", 5).Finalize(pinned)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(saeBytes), `"currency":"USD"`) {
+			t.Errorf("default not applied: %s", saeBytes)
+		}
+	})
+
+	t.Run("explicit value wins", func(t *testing.T) {
+		saeBytes, _ := NewAction("transfer", schema).Set("amount", 5).Set("currency", "EUR").Finalize(pinned)
+		if !strings.Contains(string(saeBytes), `"currency":"EUR"`) {
+			t.Errorf("explicit value overwritten: %s", saeBytes)
+		}
+	})
+
+	t.Run("old client data still validates", func(t *testing.T) {
+		data := map[string]any{"amount": 5}
+		if err := ValidateData(data, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if got := ApplyDefaults(data, schema)["currency"]; got != "USD" {
+			t.Errorf("ApplyDefaults currency = %v, want USD", got)
+		}
+		if _, ok := data["currency"]; ok {
+			t.Error("ApplyDefaults must not mutate its input")
+		}
+	})
+
+	t.Run("error: invalid default", func(t *testing.T) {
+		_, err := NewSchemaBuilder().
+			SetActionEnum("currency", []string{"USD"}).
+			SetDefault("currency", "JPY").
+			BuildSchema()
+		if err == nil || !strings.Contains(err.Error(), "invalid default") {
+			t.Errorf("expected invalid default error, got %v", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		exported := NewSchemaBuilder().
+			SetActionIntegerRange("retries", "0", "5").
+			SetDefault("retries", 3).
+			Build()
+		parsed := ParseSchema(exported["properties"].(map[string]any))
+		if parsed["retries"].Default == nil || *parsed["retries"].Default != 3 {
+			t.Errorf("retries default = %v", parsed["retries"].Default)
+		}
+	})
+}
+
+func TestFormats(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("email", "3", "100").SetFormat("email", FormatEmail).
+		SetActionStringLength("site", "1", "200").SetFormat("site", FormatURI).
+		SetActionStringLength("id", "36", "36").SetFormat("id", FormatUUID).
+		SetActionStringLength("at", "1", "40").SetFormat("at", FormatDateTime).
+		MustBuildSchema()
+
+	valid := map[string]any{
+		"email": "alice@example.com",
+		"site":  "https://example.com/a?b=c",
+		"id":    "123e4567-e89b-12d3-a456-426614174000",
+		"at":    "2024-01-08T00:00:00Z",
+	}
+
+	t.Run("valid values", func(t *testing.T) {
+		if err := ValidateData(valid, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		f := NewAction("signup", schema)
+		for k, v := range valid {
+			f.Set(k, v)
+		}
+		if _, err := f.Finalize(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	invalid := map[string]string{
+		"email": "Alice <alice@example.com>",
+		"site":  "example.com/no-scheme",
+		"id":    "123e4567-e89b-12d3-a456-42661417400g",
+		"at":    "2024-01-08 00:00:00",
+	}
+	for field, bad := range invalid {
+		t.Run("error: invalid "+field, func(t *testing.T) {
+			data := map[string]any{}
+			for k, v := range valid {
+				data[k] = v
+			}
+			data[field] = bad
+			err := ValidateData(data, schema)
+			if err == nil || !strings.Contains(err.Error(), "not a valid") {
+				t.Errorf("expected format error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		exported := NewSchemaBuilder().SetActionStringLength("id", "36", "36").SetFormat("id", FormatUUID).Build()
+		parsed := ParseSchema(exported["properties"].(map[string]any))
+		if parsed["id"].Format != FormatUUID {
+			t.Errorf("format = %q, want uuid", parsed["id"].Format)
+		}
+	})
+}
+
+func TestDecimal(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionDecimal("amount", "0.01", "99999999999999999.99", 21, 2).
+		MustBuildSchema()
+
+	t.Run("valid amounts", func(t *testing.T) {
+		for _, v := range []string{"0.01", "10", "10.5", "99999999999999999.99"} {
+			if err := ValidateData(map[string]any{"amount": v}, schema); err != nil {
+				t.Errorf("%s: unexpected error: %v", v, err)
+			}
+		}
+	})
+
+	t.Run("exact bounds beyond float64", func(t *testing.T) {
+		// 100000000000000000.00 and 99999999999999999.99 are the same float64
+		err := ValidateData(map[string]any{"amount": "100000000000000000.00"}, schema)
+		if err == nil || !strings.Contains(err.Error(), "decimal > max") {
+			t.Errorf("expected max error, got %v", err)
+		}
+	})
+
+	t.Run("canonical form keeps the string", func(t *testing.T) {
+		saeBytes, err := NewAction("transfer", schema).Set("amount", "12.30").Finalize()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(saeBytes), `"amount":"12.30"`) {
+			t.Errorf("amount not preserved: %s", saeBytes)
+		}
+	})
+
+	t.Run("number field compares exactly", func(t *testing.T) {
+		num := NewSchemaBuilder().SetActionNumberRange("n", "0", "9007199254740992").MustBuildSchema()
+		err := ValidateData(map[string]any{"n": json.Number("9007199254740993")}, num)
+		if err == nil {
+			t.Error("expected max error for 2^53+1")
+		}
+	})
+
+	for _, tc := range []struct {
+		value any
+		want  string
+	}{
+		{12.3, "expected decimal string"},
+		{"1e3", "not a valid decimal"},
+		{"01.5", "not a valid decimal"},
+		{"1.", "not a valid decimal"},
+		{"1.234", "scale 3 > max 2"},
+		{"0.001", "scale"},
+		{"0.00", "decimal < min"},
+	} {
+		t.Run(fmt.Sprintf("error: %v", tc.value), func(t *testing.T) {
+			err := ValidateData(map[string]any{"amount": tc.value}, schema)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %q error, got %v", tc.want, err)
+			}
+		})
+	}
+
+	t.Run("error: precision", func(t *testing.T) {
+		p := NewSchemaBuilder().SetActionDecimal("x", "-1000", "1000", 4, 2).MustBuildSchema()
+		if err := ValidateData(map[string]any{"x": "100.25"}, p); err == nil {
+			t.Error("expected precision error")
+		}
+		if err := ValidateData(map[string]any{"x": "-0.25"}, p); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		exported := NewSchemaBuilder().SetActionDecimal("amount", "0", "100", 5, 2).Build()
+		raw, _ := json.Marshal(exported["properties"])
+		var props map[string]any
+		_ = json.Unmarshal(raw, &props)
+		parsed := ParseSchema(props)
+		if s := parsed["amount"]; s.Type != "decimal" || *s.Precision != 5 || *s.Scale != 2 {
+			t.Errorf("amount spec = %+v", s)
+		}
+	})
+}
+
+func TestValidationErrors_Structured(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "3", "20").
+		SetActionNumberRange("amount", "0", "1000").
+		SetActionEnum("currency", []string{"USD", "EUR"}).
+		MustBuildSchema()
+
+	data := map[string]any{"name": "Al", "amount": "100", "extra": 1}
+	err := ValidateData(data, schema)
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %T", err)
+	}
+
+	got := map[string]string{}
+	for _, fe := range verrs {
+		got[fe.Field] = fe.Code
+	}
+	want := map[string]string{
+		"name":     CodeMin,
+		"amount":   CodeType,
+		"currency": CodeRequired,
+		"extra":    CodeUnknownField,
+	}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("%s: code = %q, want %q", field, got[field], code)
+		}
+	}
+
+	t.Run("sorted by field", func(t *testing.T) {
+		for i := 1; i < len(verrs); i++ {
+			if verrs[i-1].Field > verrs[i].Field {
+				t.Errorf("errors not sorted: %v", verrs)
+			}
+		}
+	})
+
+	t.Run("errors.As individual field", func(t *testing.T) {
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "amount" {
+			t.Errorf("expected first FieldError for amount, got %+v", fe)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		b, _ := json.Marshal(verrs)
+		if !strings.Contains(string(b), `{"field":"name","code":"min","message":"string length 2 \u003c min 3","got":2,"want":3}`) {
+			t.Errorf("unexpected JSON: %s", b)
+		}
+	})
+
+	t.Run("finalize", func(t *testing.T) {
+		_, err := NewAction("transfer", schema).Set("name", "Alice").Set("currency", "JPY").Finalize()
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 2 {
+			t.Fatalf("expected 2 ValidationErrors, got %v", err)
+		}
+		if verrs[0].Field != "amount" || verrs[0].Code != CodeRequired {
+			t.Errorf("unexpected first error: %+v", verrs[0])
+		}
+		if verrs[1].Field != "currency" || verrs[1].Code != CodeEnum || verrs[1].Got != "JPY" {
+			t.Errorf("unexpected second error: %+v", verrs[1])
+		}
+	})
+}
+
+func TestFingerprint_BoundIntoSAE(t *testing.T) {
+	v1 := NewSchemaBuilder().SetActionNumberRange("amount", "0", "100").MustBuildSchema()
+	v1b := NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+
+	saeBytes, err := NewAction("transfer", v1).Set("amount", 50).Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env, _ := sae.Parse(saeBytes)
+
+	t.Run("same schema", func(t *testing.T) {
+		if err := VerifyFingerprint(env, v1); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error: schema changed", func(t *testing.T) {
+		if err := VerifyFingerprint(env, v1b); err != ErrSchemaMismatch {
+			t.Errorf("expected ErrSchemaMismatch, got %v", err)
+		}
+	})
+
+	t.Run("error: registry rejects silently changed schema", func(t *testing.T) {
+		reg := NewRegistry().Register("transfer", "", v1b)
+		if _, err := reg.ParseAndValidate(saeBytes); err != ErrSchemaMismatch {
+			t.Errorf("expected ErrSchemaMismatch, got %v", err)
+		}
+	})
+
+	t.Run("envelope without fingerprint", func(t *testing.T) {
+		legacy, _ := sae.BuildSAE("transfer", map[string]any{"amount": 50})
+		env, _ := sae.Parse(legacy)
+		if err := VerifyFingerprint(env, v1); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSchemaSet_Migration(t *testing.T) {
+	b := NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000")
+	v1 := b.BuildVersion("1")
+	b.SetActionEnum("currency", []string{"USD", "EUR"})
+	v2 := b.BuildVersion("2")
+	b.SetActionDecimal("amount", "0", "1000", 0, 2)
+	v3 := b.BuildVersion("3")
+
+	if _, ok := v1.Fields["currency"]; ok {
+		t.Fatal("BuildVersion must snapshot the builder")
+	}
+
+	reg := NewRegistry().
+		RegisterSet("transfer", v1, v2, v3).
+		RegisterMigration("transfer", "1", "2", func(d map[string]any) (map[string]any, error) {
+			out := map[string]any{"currency": "USD"}
+			for k, v := range d {
+				out[k] = v
+			}
+			return out, nil
+		}).
+		RegisterMigration("transfer", "2", "3", func(d map[string]any) (map[string]any, error) {
+			n, ok := toRat(d["amount"])
+			if !ok {
+				return nil, errors.New("amount not numeric")
+			}
+			return map[string]any{"amount": n.FloatString(2), "currency": d["currency"]}, nil
+		})
+
+	if cur, _ := reg.Current("transfer"); cur.Version != "3" {
+		t.Fatalf("current = %q, want 3", cur.Version)
+	}
+
+	t.Run("old client upgraded through every step", func(t *testing.T) {
+		action, _ := reg.NewAction("transfer", "1")
+		saeBytes, err := action.Set("amount", 50).Finalize()
+		if err != nil {
+			t.Fatalf("Finalize failed: %v", err)
+		}
+		env, _ := sae.Parse(saeBytes)
+
+		data, err := reg.ValidateAndUpgrade(env)
+		if err != nil {
+			t.Fatalf("ValidateAndUpgrade failed: %v", err)
+		}
+		if data["amount"] != "50.00" || data["currency"] != "USD" {
+			t.Errorf("upgraded data = %v", data)
+		}
+		if _, ok := env.SDTO["currency"]; ok {
+			t.Error("migration must not modify the signed sdto")
+		}
+	})
+
+	t.Run("current client unchanged", func(t *testing.T) {
+		data, err := reg.Upgrade("transfer", "3", map[string]any{"amount": "1.00", "currency": "EUR"})
+		if err != nil || data["currency"] != "EUR" {
+			t.Errorf("unexpected result %v, %v", data, err)
+		}
+	})
+
+	t.Run("error: no migration path", func(t *testing.T) {
+		reg := NewRegistry().RegisterSet("transfer", v1, v2)
+		if _, err := reg.Upgrade("transfer", "1", map[string]any{"amount": 1}); !errors.Is(err, ErrNoMigration) {
+			t.Errorf("expected ErrNoMigration, got %v", err)
+		}
+	})
+
+	t.Run("error: migration cycle", func(t *testing.T) {
+		noop := func(d map[string]any) (map[string]any, error) { return d, nil }
+		reg := NewRegistry().RegisterSet("transfer", v1, v2, v3).
+			RegisterMigration("transfer", "1", "2", noop).
+			RegisterMigration("transfer", "2", "1", noop)
+		if _, err := reg.Upgrade("transfer", "1", map[string]any{}); !errors.Is(err, ErrNoMigration) {
+			t.Errorf("expected ErrNoMigration, got %v", err)
+		}
+	})
+}
+
+func TestExportJSONSchema(t *testing.T) {
+	doc := NewSchemaBuilder().
+		SetActionStringLength("email", "3", "100").SetFormat("email", FormatEmail).
+		SetActionNumberRange("amount", "0", "1000.5").
+		SetActionIntegerRange("quantity", "1", "99").
+		SetActionEnum("currency", []string{"USD", "EUR"}).SetDefault("currency", "USD").
+		SetActionDecimal("price", "0", "100", 0, 2).
+		SetActionBoolean("gift").
+		ExportJSONSchema()
+
+	got, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	want := `{"$schema":"https://json-schema.org/draft/2020-12/schema",` +
+		`"additionalProperties":false,` +
+		`"properties":{` +
+		`"amount":{"maximum":1000.5,"minimum":0,"type":"number"},` +
+		`"currency":{"default":"USD","enum":["USD","EUR"],"type":"string"},` +
+		`"email":{"format":"email","maxLength":100,"minLength":3,"type":"string"},` +
+		`"gift":{"type":"boolean"},` +
+		`"price":{"pattern":"^-?(0|[1-9][0-9]*)(\\.[0-9]{1,2})?$","type":"string"},` +
+		`"quantity":{"maximum":99,"minimum":1,"type":"integer"}},` +
+		`"required":["amount","email","gift","price","quantity"],` +
+		`"type":"object"}`
+	if string(got) != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestParseSchemaStrict(t *testing.T) {
+	t.Run("valid schema matches ParseSchema", func(t *testing.T) {
+		exported := NewSchemaBuilder().
+			SetActionStringLength("name", "1", "50").SetFormat("name", FormatEmail).
+			SetActionDecimal("price", "0", "10", 4, 2).
+			SetActionEnum("currency", []string{"USD"}).SetDefault("currency", "USD").
+			Build()
+		raw, _ := json.Marshal(exported["properties"])
+		var props map[string]any
+		_ = json.Unmarshal(raw, &props)
+
+		parsed, err := ParseSchemaStrict(props)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(parsed) != 3 || *parsed["price"].Scale != 2 {
+			t.Errorf("unexpected schema: %+v", parsed)
+		}
+	})
+
+	t.Run("error: every problem reported", func(t *testing.T) {
+		raw := map[string]any{
+			"a": "string",
+			"b": map[string]any{"type": "strnig"},
+			"c": map[string]any{"type": "number", "min": 0},
+			"d": map[string]any{"type": "string", "enum": []any{"x", 1}},
+			"e": map[string]any{"type": "string", "maxLen": "5"},
+			"f": map[string]any{"type": "string", "format": "phone"},
+			"g": map[string]any{"min": "1"},
+		}
+		_, err := ParseSchemaStrict(raw)
+
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) {
+			t.Fatalf("expected ValidationErrors, got %v", err)
+		}
+		fields := map[string]bool{}
+		for _, fe := range verrs {
+			if fe.Code != CodeSchema {
+				t.Errorf("%s: code = %q, want schema", fe.Field, fe.Code)
+			}
+			fields[fe.Field] = true
+		}
+		for _, f := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+			if !fields[f] {
+				t.Errorf("field %s not reported: %v", f, err)
+			}
+		}
+	})
+}
+
+func TestSchemaBuilder_Validate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		if err := NewSchemaBuilder().SetActionStringLength("name", "1", "50").Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	cases := map[string]*SchemaBuilder{
+		"min > max":         NewSchemaBuilder().SetActionNumberRange("f", "10", "1"),
+		"non-numeric bound": NewSchemaBuilder().SetActionNumberRange("f", "zero", "1"),
+		"negative length":   NewSchemaBuilder().SetActionStringLength("f", "-1", "5"),
+		"empty enum":        NewSchemaBuilder().SetActionEnum("f", []string{}),
+		"unsupported sign":  NewSchemaBuilder().SetActionSign("f", "md5"),
+		"scale > precision": NewSchemaBuilder().SetActionDecimal("f", "0", "1", 2, 4),
+		"unknown format":    NewSchemaBuilder().SetActionStringLength("f", "1", "5").SetFormat("f", "phone"),
+	}
+	for name, b := range cases {
+		t.Run("error: "+name, func(t *testing.T) {
+			_, err := b.BuildSchema()
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || verrs[0].Field != "f" || verrs[0].Code != CodeSchema {
+				t.Errorf("expected schema error for f, got %v", err)
+			}
+		})
+	}
+
+	t.Run("error: MustBuildSchema panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewSchemaBuilder().SetActionSign("sig", "md5").MustBuildSchema()
+	})
+}
+
+func TestSchemaBuilder_MultipleActions(t *testing.T) {
+	b := NewSchemaBuilder()
+	b.Action("createUser").String("name", 1, 50).Number("age", 0, 150).
+		Action("transfer").Decimal("amount", "0.01", "1000", 0, 2).Enum("currency", "USD", "EUR").Default("currency", "USD")
+	b.Action("createUser").Boolean("admin")
+
+	all, err := b.BuildAll()
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	if len(all) != 2 || len(all["createUser"]) != 3 || len(all["transfer"]) != 2 {
+		t.Fatalf("unexpected schemas: %v", all)
+	}
+	if *all["createUser"]["age"].Max != "150" {
+		t.Errorf("age max = %s", *all["createUser"]["age"].Max)
+	}
+
+	t.Run("NewAction by action type", func(t *testing.T) {
+		action, err := b.NewAction("transfer")
+		if err != nil {
+			t.Fatalf("NewAction failed: %v", err)
+		}
+		saeBytes, err := action.Set("amount", "9.99").Finalize()
+		if err != nil || !strings.Contains(string(saeBytes), `"action_type":"transfer"`) {
+			t.Errorf("unexpected result %s, %v", saeBytes, err)
+		}
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		reg := NewRegistry().RegisterAll("1", all)
+		if _, err := reg.NewAction("createUser", "1"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error: unknown action", func(t *testing.T) {
+		if _, err := b.NewAction("deleteUser"); !errors.Is(err, ErrUnknownSchema) {
+			t.Errorf("expected ErrUnknownSchema, got %v", err)
+		}
+	})
+
+	t.Run("error: invalid action schema", func(t *testing.T) {
+		bad := NewSchemaBuilder()
+		bad.Action("a").String("name", 10, 1)
+		_, err := bad.BuildAll()
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || !strings.Contains(err.Error(), "action a") {
+			t.Errorf("expected schema error for action a, got %v", err)
+		}
+	})
+}
+
+func TestBytesField(t *testing.T) {
+	schema := NewSchemaBuilder().SetActionBytes("blob", "1", "4").MustBuildSchema()
+
+	t.Run("set and get raw bytes", func(t *testing.T) {
+		action := NewAction("upload", schema).SetBytes("blob", []byte{0xde, 0xad, 0xbe, 0xef})
+		raw, ok := action.GetBytes("blob")
+		if !ok || !bytes.Equal(raw, []byte{0xde, 0xad, 0xbe, 0xef}) {
+			t.Errorf("GetBytes = %x, %v", raw, ok)
+		}
+		saeBytes, err := action.Finalize()
+		if err != nil || !strings.Contains(string(saeBytes), `"blob":"3q2+7w=="`) {
+			t.Errorf("unexpected result %s, %v", saeBytes, err)
+		}
+
+		env, _ := sae.Parse(saeBytes)
+		if raw, ok := DecodeBytes(env.SDTO, "blob"); !ok || len(raw) != 4 {
+			t.Errorf("DecodeBytes = %x, %v", raw, ok)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		value any
+		code  string
+	}{
+		"not a string": {42, CodeType},
+		"bad base64":   {"not base64!", CodeFormat},
+		"no padding":   {"3q2+7w", CodeFormat},
+		"too large":    {"AAAAAAA=", CodeMax},
+		"empty":        {"", CodeMin},
+	} {
+		t.Run("error: "+name, func(t *testing.T) {
+			err := ValidateData(map[string]any{"blob": tc.value}, schema)
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || verrs[0].Code != tc.code {
+				t.Errorf("expected %s error, got %v", tc.code, err)
+			}
+		})
+	}
+}
+
+func TestNumberEnum(t *testing.T) {
+	schema := NewSchemaBuilder().SetActionNumberEnum("priority", []string{"1", "2", "3.5"}).MustBuildSchema()
+
+	for _, v := range []any{1, 2.0, json.Number("3.50"), json.Number("1e0")} {
+		if err := ValidateData(map[string]any{"priority": v}, schema); err != nil {
+			t.Errorf("%v: unexpected error: %v", v, err)
+		}
+	}
+
+	t.Run("canonical number in SAE", func(t *testing.T) {
+		saeBytes, err := NewAction("ticket", schema).Set("priority", 2).Finalize()
+		if err != nil || !strings.Contains(string(saeBytes), `"priority":2`) {
+			t.Errorf("unexpected result %s, %v", saeBytes, err)
+		}
+	})
+
+	t.Run("error: not in enum", func(t *testing.T) {
+		err := ValidateData(map[string]any{"priority": 4}, schema)
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || verrs[0].Code != CodeEnum {
+			t.Errorf("expected enum error, got %v", err)
+		}
+	})
+
+	t.Run("error: string value", func(t *testing.T) {
+		if err := ValidateData(map[string]any{"priority": "1"}, schema); err == nil {
+			t.Error("expected type error")
+		}
+	})
+
+	t.Run("error: non-numeric enum value", func(t *testing.T) {
+		if _, err := NewSchemaBuilder().SetActionNumberEnum("p", []string{"high"}).BuildSchema(); err == nil {
+			t.Error("expected schema error")
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		b, _ := json.Marshal(JSONSchema(schema)["properties"])
+		if !strings.Contains(string(b), `"enum":[1,2,3.5]`) {
+			t.Errorf("unexpected JSON Schema: %s", b)
+		}
+	})
+}
+
+func TestExclusiveBoundsAndMultipleOf(t *testing.T) {
+	b := NewSchemaBuilder().
+		SetActionNumberRange("amount", "0", "1000").
+		SetExclusiveBounds("amount", true, false).
+		SetMultipleOf("amount", "0.01")
+	schema := b.MustBuildSchema()
+
+	for _, v := range []any{0.01, json.Number("999.99"), 1000, json.Number("12.30")} {
+		if err := ValidateData(map[string]any{"amount": v}, schema); err != nil {
+			t.Errorf("%v: unexpected error: %v", v, err)
+		}
+	}
+
+	for v, code := range map[string]string{"0": CodeMin, "-1": CodeMin, "0.015": CodeMultipleOf, "1000.01": CodeMax} {
+		t.Run("error: "+v, func(t *testing.T) {
+			err := ValidateData(map[string]any{"amount": json.Number(v)}, schema)
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || verrs[0].Code != code {
+				t.Errorf("expected %s error, got %v", code, err)
+			}
+		})
+	}
+
+	t.Run("decimal", func(t *testing.T) {
+		s := NewSchemaBuilder().SetActionDecimal("price", "0", "10", 0, 0).SetMultipleOf("price", "0.05").MustBuildSchema()
+		if err := ValidateData(map[string]any{"price": "1.15"}, s); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := ValidateData(map[string]any{"price": "1.17"}, s); err == nil {
+			t.Error("expected multiple_of error")
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		parsed, err := ParseSchemaStrict(b.Build()["properties"].(map[string]any))
+		if err != nil {
+			t.Fatalf("ParseSchemaStrict failed: %v", err)
+		}
+		if s := parsed["amount"]; !s.ExclusiveMin || s.ExclusiveMax || *s.MultipleOf != "0.01" {
+			t.Errorf("amount spec = %+v", s)
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		out, _ := json.Marshal(JSONSchema(schema)["properties"])
+		want := `{"amount":{"exclusiveMinimum":0,"maximum":1000,"multipleOf":0.01,"type":"number"}}`
+		if string(out) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", out, want)
+		}
+	})
+
+	t.Run("error: invalid constraints", func(t *testing.T) {
+		for name, b := range map[string]*SchemaBuilder{
+			"empty range":        NewSchemaBuilder().SetActionNumberRange("f", "1", "1").SetExclusiveBounds("f", false, true),
+			"zero multiple":      NewSchemaBuilder().SetActionNumberRange("f", "0", "1").SetMultipleOf("f", "0"),
+			"multiple on string": NewSchemaBuilder().SetActionStringLength("f", "0", "1").SetMultipleOf("f", "2"),
+		} {
+			if _, err := b.BuildSchema(); err == nil {
+				t.Errorf("%s: expected schema error", name)
+			}
+		}
+	})
+}
+
+func TestLengthUnit(t *testing.T) {
+	name := strings.Repeat("王", 50) // 50 runes, 150 bytes
+
+	t.Run("bytes is the default", func(t *testing.T) {
+		schema := NewSchemaBuilder().SetActionStringLength("name", "1", "50").MustBuildSchema()
+		if err := ValidateData(map[string]any{"name": name}, schema); err == nil {
+			t.Error("expected byte length error")
+		}
+	})
+
+	t.Run("runes", func(t *testing.T) {
+		schema := NewSchemaBuilder().SetActionStringLength("name", "1", "50").SetLengthUnit("name", LengthRunes).MustBuildSchema()
+		if err := ValidateData(map[string]any{"name": name}, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := NewAction("createUser", schema).Set("name", name+"王").Finalize(); err == nil {
+			t.Error("expected rune length error from Set")
+		}
+	})
+
+	t.Run("graphemes", func(t *testing.T) {
+		cases := map[string]int{
+			"abc":                3,
+			"e\u0301":            1, // e + combining acute
+			"\r\n":               1,
+			"🇹🇼🇯🇵":               2, // two flags
+			"👍\U0001F3FD":        1, // skin tone modifier
+			"👩\u200d👩\u200d👧":    1, // ZWJ family
+			"❤\ufe0f":            1, // variation selector
+			"한국어":                3,
+			"\u1100\u1161\u11a8": 1, // conjoining jamo 각
+		}
+		for s, want := range cases {
+			if got := stringLength(s, LengthGraphemes); got != want {
+				t.Errorf("graphemes(%q) = %d, want %d", s, got, want)
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		exported := NewSchemaBuilder().SetActionStringLength("name", "1", "50").SetLengthUnit("name", LengthGraphemes).Build()
+		parsed, err := ParseSchemaStrict(exported["properties"].(map[string]any))
+		if err != nil || parsed["name"].LengthUnit != LengthGraphemes {
+			t.Errorf("unexpected result %+v, %v", parsed, err)
+		}
+	})
+
+	t.Run("error: unknown unit", func(t *testing.T) {
+		if _, err := NewSchemaBuilder().SetActionStringLength("name", "1", "5").SetLengthUnit("name", "chars").BuildSchema(); err == nil {
+			t.Error("expected schema error")
+		}
+	})
+}
+
+func TestMapField(t *testing.T) {
+	b := NewSchemaBuilder().SetActionMap("labels", `^[a-z][a-z0-9_]*$`, FieldSpec{Type: "string", Max: strPtr("20")}, "0", "3")
+	schema := b.MustBuildSchema()
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := NewAction("tag", schema).Set("labels", map[string]any{"env": "prod", "team_id": "a1"}).Finalize()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		value any
+		code  string
+	}{
+		"not an object":  {[]any{"a"}, CodeType},
+		"bad key":        {map[string]any{"Env": "prod"}, CodeFormat},
+		"bad value":      {map[string]any{"env": 1}, CodeType},
+		"value too long": {map[string]any{"env": strings.Repeat("x", 21)}, CodeMax},
+		"too many":       {map[string]any{"a": "", "b": "", "c": "", "d": ""}, CodeMax},
+	} {
+		t.Run("error: "+name, func(t *testing.T) {
+			err := ValidateData(map[string]any{"labels": tc.value}, schema)
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || verrs[0].Code != tc.code || verrs[0].Field != "labels" {
+				t.Errorf("expected %s error on labels, got %v", tc.code, err)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		raw, _ := json.Marshal(b.Build()["properties"])
+		var props map[string]any
+		_ = json.Unmarshal(raw, &props)
+		parsed, err := ParseSchemaStrict(props)
+		if err != nil {
+			t.Fatalf("ParseSchemaStrict failed: %v", err)
+		}
+		if s := parsed["labels"]; s.KeyPattern == "" || s.Values == nil || *s.Values.Max != "20" {
+			t.Errorf("labels spec = %+v", s)
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		out, _ := json.Marshal(JSONSchema(schema)["properties"])
+		want := `{"labels":{"additionalProperties":{"maxLength":20,"type":"string"},"maxProperties":3,"minProperties":0,"propertyNames":{"pattern":"^[a-z][a-z0-9_]*$"},"type":"object"}}`
+		if string(out) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", out, want)
+		}
+	})
+
+	t.Run("error: invalid schema", func(t *testing.T) {
+		bad := NewSchemaBuilder().SetActionMap("m", "([", FieldSpec{Type: "strnig"}, "0", "1")
+		_, err := bad.BuildSchema()
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 2 {
+			t.Errorf("expected key_pattern and values errors, got %v", err)
+		}
+	})
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestOneOfField(t *testing.T) {
+	b := NewSchemaBuilder().SetActionOneOf("account",
+		FieldSpec{Type: "string", Min: strPtr("3"), Max: strPtr("8")},
+		FieldSpec{Type: "integer", Min: strPtr("1"), Max: strPtr("999999")},
+	)
+	schema := b.MustBuildSchema()
+
+	t.Run("either alternative", func(t *testing.T) {
+		for _, v := range []any{"acct42", 12345, json.Number("7")} {
+			if err := ValidateData(map[string]any{"account": v}, schema); err != nil {
+				t.Errorf("%v: unexpected error: %v", v, err)
+			}
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		value any
+		code  string
+	}{
+		"best match is the string constraint":  {"ab", CodeMin},
+		"best match is the integer constraint": {0, CodeMin},
+		"no alternative has the type":          {true, CodeType},
+	} {
+		t.Run("error: "+name, func(t *testing.T) {
+			_, err := NewAction("t", schema).Set("account", tc.value).Finalize()
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Code != tc.code || fe.Field != "account" {
+				t.Errorf("expected %s error on account, got %v", tc.code, err)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		raw, _ := json.Marshal(b.Build()["properties"])
+		var props map[string]any
+		_ = json.Unmarshal(raw, &props)
+		parsed, err := ParseSchemaStrict(props)
+		if err != nil {
+			t.Fatalf("ParseSchemaStrict failed: %v", err)
+		}
+		if alts := parsed["account"].OneOf; len(alts) != 2 || alts[1].Type != "integer" {
+			t.Errorf("account spec = %+v", parsed["account"])
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		out, _ := json.Marshal(JSONSchema(schema)["properties"])
+		want := `{"account":{"anyOf":[{"maxLength":8,"minLength":3,"type":"string"},{"maximum":999999,"minimum":1,"type":"integer"}]}}`
+		if string(out) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", out, want)
+		}
+	})
+
+	t.Run("error: invalid schema", func(t *testing.T) {
+		bad := map[string]FieldSpec{
+			"a": {OneOf: []FieldSpec{{Type: "string"}}},
+			"b": {Type: "string", OneOf: []FieldSpec{{Type: "string"}, {Type: "strnig"}}},
+		}
+		err := ValidateSchema(bad)
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 3 {
+			t.Errorf("expected 3 schema errors, got %v", err)
+		}
+	})
+}
+
+func TestConstField(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionNumberRange("amount", "0", "1000").
+		SetActionEnum("currency", []string{"USD", "EUR"}).
+		SetConst("currency", "USD").
+		SetActionIntegerRange("version", "1", "9").
+		SetConst("version", 2).
+		MustBuildSchema()
+
+	t.Run("finalize fills const fields", func(t *testing.T) {
+		saeBytes, err := NewAction("pay", schema).Set("amount", 10).Finalize()
+		if err != nil {
+			t.Fatalf("Finalize failed: %v", err)
+		}
+		env, _ := sae.Parse(saeBytes)
+		if env.SDTO["currency"] != "USD" || fmt.Sprint(env.SDTO["version"]) != "2" {
+			t.Errorf("const fields not filled: %v", env.SDTO)
+		}
+	})
+
+	t.Run("same value is accepted", func(t *testing.T) {
+		data := map[string]any{"amount": 10, "currency": "USD", "version": json.Number("2.0")}
+		if err := ValidateData(data, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if got := ApplyDefaults(map[string]any{"amount": 1}, schema)["currency"]; got != "USD" {
+			t.Errorf("ApplyDefaults currency = %v", got)
+		}
+	})
+
+	t.Run("error: other value", func(t *testing.T) {
+		_, err := NewAction("pay", schema).Set("amount", 10).Set("currency", "EUR").Finalize()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Code != CodeConst || fe.Field != "currency" {
+			t.Errorf("expected const error on currency, got %v", err)
+		}
+		err = ValidateData(map[string]any{"amount": 10, "version": 3}, schema)
+		if !errors.As(err, &fe) || fe.Code != CodeConst || fe.Field != "version" {
+			t.Errorf("expected const error on version, got %v", err)
+		}
+	})
+
+	t.Run("error: const violates field rules", func(t *testing.T) {
+		_, err := NewSchemaBuilder().
+			SetActionEnum("currency", []string{"USD", "EUR"}).
+			SetConst("currency", "JPY").
+			BuildSchema()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Code != CodeSchema {
+			t.Errorf("expected schema error, got %v", err)
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		doc := JSONSchema(schema)
+		out, _ := json.Marshal(doc["properties"].(map[string]any)["currency"])
+		if want := `{"const":"USD","enum":["USD","EUR"],"type":"string"}`; string(out) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", out, want)
+		}
+		if req := doc["required"].([]string); len(req) != 1 || req[0] != "amount" {
+			t.Errorf("required = %v", req)
+		}
+	})
+}
+
+func TestValidateDataWithPolicy(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "10").
+		MustBuildSchema()
+	newData := func() map[string]any {
+		return map[string]any{"name": "alice", "tags": []any{"x"}, "nickname": "al"}
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		extra, err := ValidateDataWithPolicy(newData(), schema, ExtraReject)
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 2 || verrs[0].Code != CodeUnknownField {
+			t.Errorf("expected 2 unknown_field errors, got %v", err)
+		}
+		if fmt.Sprint(extra) != "[nickname tags]" {
+			t.Errorf("extra = %v", extra)
+		}
+	})
+
+	t.Run("ignore keeps extra fields", func(t *testing.T) {
+		data := newData()
+		extra, err := ValidateDataWithPolicy(data, schema, ExtraIgnore)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(extra) != 2 || len(data) != 3 {
+			t.Errorf("extra = %v, data = %v", extra, data)
+		}
+	})
+
+	t.Run("strip removes and reports", func(t *testing.T) {
+		data := newData()
+		extra, err := ValidateDataWithPolicy(data, schema, ExtraStrip)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fmt.Sprint(extra) != "[nickname tags]" || len(data) != 1 || data["name"] != "alice" {
+			t.Errorf("extra = %v, data = %v", extra, data)
+		}
+	})
+
+	t.Run("error: known fields still validated", func(t *testing.T) {
+		data := map[string]any{"name": "", "extra": 1}
+		_, err := ValidateDataWithPolicy(data, schema, ExtraStrip)
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "name" || fe.Code != CodeMin {
+			t.Errorf("expected min error on name, got %v", err)
+		}
+	})
+}
+
+func TestCoerceData(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionIntegerRange("qty", "1", "100").
+		SetActionNumberRange("price", "0", "1000").
+		SetActionBoolean("gift").
+		SetActionDecimal("total", "0", "100000", 10, 2).
+		SetActionStringLength("note", "0", "20").
+		MustBuildSchema()
+
+	t.Run("form input validates after coercion", func(t *testing.T) {
+		form := map[string]any{"qty": "3", "price": "19.5", "gift": "1", "total": json.Number("58.50"), "note": "42"}
+		if err := ValidateData(form, schema); err == nil {
+			t.Fatal("expected raw form data to fail validation")
+		}
+
+		data, log := CoerceData(form, schema)
+		if err := ValidateData(data, schema); err != nil {
+			t.Fatalf("unexpected error after coercion: %v", err)
+		}
+		if data["qty"] != int64(3) || data["price"] != 19.5 || data["gift"] != true || data["total"] != "58.50" || data["note"] != "42" {
+			t.Errorf("coerced data = %v", data)
+		}
+		if len(log) != 4 || log[0].Field != "gift" || log[0].From != "1" || log[0].To != true {
+			t.Errorf("coercion log = %+v", log)
+		}
+		if form["qty"] != "3" {
+			t.Error("CoerceData modified its input")
+		}
+	})
+
+	t.Run("json numbers", func(t *testing.T) {
+		data, _ := CoerceData(map[string]any{"qty": json.Number("4.0"), "price": json.Number("2"), "gift": 0.0, "total": 12.25}, schema)
+		if data["qty"] != int64(4) || data["price"] != int64(2) || data["gift"] != false || data["total"] != "12.25" {
+			t.Errorf("coerced data = %v", data)
+		}
+	})
+
+	t.Run("unconvertible values are left for validation", func(t *testing.T) {
+		for _, v := range []any{"3.5", "0x10", "1/2", " ", "yes"} {
+			data, log := CoerceData(map[string]any{"qty": v, "gift": v}, schema)
+			if len(log) != 0 || data["qty"] != v {
+				t.Errorf("%q: unexpected coercion %+v", v, log)
+			}
+		}
+	})
+}
+
+func TestValidateDataReport(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "10").
+		SetActionNumberRange("amount", "0", "100").
+		SetActionStringLength("memo", "0", "20").
+		SetDefault("memo", "").
+		SetActionMap("labels", `^[a-z/]+$`, FieldSpec{Type: "string", Max: strPtr("3")}, "0", "5").
+		MustBuildSchema()
+
+	t.Run("valid document", func(t *testing.T) {
+		r := ValidateDataReport(map[string]any{"name": "alice", "amount": 5, "labels": map[string]any{}}, schema)
+		if !r.Valid || len(r.Errors) != 0 {
+			t.Fatalf("unexpected errors: %+v", r.Errors)
+		}
+		if !r.Fields["memo"].Defaulted || r.Document["memo"] != "" || !r.Fields["name"].Valid {
+			t.Errorf("fields = %+v, document = %v", r.Fields, r.Document)
+		}
+	})
+
+	t.Run("every error with its path", func(t *testing.T) {
+		r := ValidateDataReport(map[string]any{
+			"amount": 500,
+			"labels": map[string]any{"a/b": "long"},
+			"extra":  true,
+		}, schema)
+		if r.Valid {
+			t.Fatal("expected invalid report")
+		}
+
+		var got []string
+		for _, e := range r.Errors {
+			got = append(got, e.Path+" "+e.Code)
+		}
+		want := "[/amount max /extra unknown_field /labels/a~1b max /name required]"
+		if fmt.Sprint(got) != want {
+			t.Errorf("errors = %v, want %v", got, want)
+		}
+
+		if r.Fields["name"].Valid || r.Fields["name"].Defaulted || !r.Fields["extra"].Unknown || !r.Fields["memo"].Valid {
+			t.Errorf("fields = %+v", r.Fields)
+		}
+		if _, ok := r.Document["extra"]; ok {
+			t.Error("document should contain schema fields only")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		r := ValidateDataReport(map[string]any{"name": "", "amount": 1}, schema)
+		b, _ := json.Marshal(r.Errors[0])
+		if want := `{"path":"/labels","field":"labels","code":"required","message":"missing required field"}`; string(b) != want {
+			t.Errorf("\ngot:  %s\nwant: %s", b, want)
+		}
+	})
+}
+
+func TestFluentAction_TypedSetters(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "10").
+		SetActionNumberRange("price", "0", "100").
+		SetActionIntegerRange("qty", "1", "9").
+		SetActionBoolean("gift").
+		MustBuildSchema()
+
+	t.Run("typed setters", func(t *testing.T) {
+		_, err := NewAction("order", schema).
+			SetString("name", "alice").
+			SetNumber("price", 9.99).
+			SetInteger("qty", 2).
+			SetBool("gift", true).
+			Finalize()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("typed setters still validate", func(t *testing.T) {
+		_, err := NewAction("order", schema).
+			SetString("name", "alice").
+			SetNumber("price", 9.99).
+			SetInteger("qty", 20).
+			SetBool("gift", true).
+			Finalize()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "qty" || fe.Code != CodeMax {
+			t.Errorf("expected max error on qty, got %v", err)
+		}
+	})
+
+	t.Run("SetAll", func(t *testing.T) {
+		_, err := NewAction("order", schema).
+			SetAll(map[string]any{"name": "alice", "price": 1, "qty": 1, "gift": false}).
+			Finalize()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		_, err = NewAction("order", schema).
+			SetAll(map[string]any{"name": "", "price": 1, "qty": 0, "gift": false, "zzz": 1}).
+			Finalize()
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 3 || verrs[0].Field != "name" || verrs[2].Field != "zzz" {
+			t.Errorf("expected name, qty and zzz errors, got %v", err)
+		}
+	})
+
+	t.Run("Unset clears value and errors", func(t *testing.T) {
+		a := NewAction("order", schema).
+			SetAll(map[string]any{"name": "alice", "price": 1, "gift": false}).
+			SetInteger("qty", 99)
+		if _, err := a.Finalize(); err == nil {
+			t.Fatal("expected error before Unset")
+		}
+
+		a = NewAction("order", schema).
+			SetAll(map[string]any{"name": "alice", "price": 1, "gift": false}).
+			SetInteger("qty", 99).
+			Unset("qty").
+			SetInteger("qty", 3)
+		if _, err := a.Finalize(); err != nil {
+			t.Errorf("unexpected error after Unset: %v", err)
+		}
+
+		_, err := NewAction("order", schema).
+			SetAll(map[string]any{"name": "alice", "price": 1, "qty": 1, "gift": false}).
+			Unset("gift").
+			Finalize()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "gift" || fe.Code != CodeRequired {
+			t.Errorf("expected gift to be required again, got %v", err)
+		}
+	})
+}
+
+func TestFluentAction_Validate(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("name", "1", "10").
+		SetActionNumberRange("amount", "0", "100").
+		SetActionStringLength("memo", "0", "20").
+		SetDefault("memo", "").
+		MustBuildSchema()
+
+	t.Run("reports missing required fields before finalize", func(t *testing.T) {
+		a := NewAction("pay", schema).Set("amount", 500)
+		err := a.Validate()
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 2 ||
+			verrs[0].Field != "amount" || verrs[0].Code != CodeMax ||
+			verrs[1].Field != "name" || verrs[1].Code != CodeRequired {
+			t.Errorf("unexpected Validate result: %v", err)
+		}
+
+		errs := a.Errors()
+		var fe *FieldError
+		if len(errs) != 2 || !errors.As(errs[1], &fe) || fe.Field != "name" {
+			t.Errorf("unexpected Errors result: %v", errs)
+		}
+	})
+
+	t.Run("no side effects", func(t *testing.T) {
+		a := NewAction("pay", schema).Set("name", "alice")
+		_ = a.Validate()
+		_ = a.Validate()
+		a.Set("amount", 1)
+		if err := a.Validate(); err != nil || len(a.Errors()) != 0 {
+			t.Fatalf("expected valid action, got %v", err)
+		}
+		if _, err := a.Finalize(); err != nil {
+			t.Errorf("Finalize failed after Validate: %v", err)
+		}
+	})
+}
+
+func TestFluentAction_Clone(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionStringLength("merchant", "1", "20").
+		SetActionIntegerRange("amount", "1", "1000").
+		MustBuildSchema()
+	template := NewAction("charge", schema).SetSchemaVersion("v1").Set("merchant", "acme")
+
+	t.Run("clones are independent", func(t *testing.T) {
+		a := template.Clone().Set("amount", 5)
+		b := template.Clone().Set("amount", 5000)
+
+		if _, err := a.Finalize(); err != nil {
+			t.Errorf("a: unexpected error: %v", err)
+		}
+		if _, err := b.Finalize(); err == nil {
+			t.Error("b: expected max error")
+		}
+		if err := template.Validate(); err == nil || strings.Contains(err.Error(), "max") {
+			t.Errorf("template should only miss amount, got %v", err)
+		}
+	})
+
+	t.Run("concurrent fill from template", func(t *testing.T) {
+		done := make(chan error)
+		for i := 0; i < 16; i++ {
+			go func(i int) {
+				_, err := template.Clone().SetInteger("amount", int64(i+1)).Finalize()
+				done <- err
+			}(i)
+		}
+		for i := 0; i < 16; i++ {
+			if err := <-done; err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("concurrent use of one action", func(t *testing.T) {
+		a := NewAction("charge", schema)
+		done := make(chan struct{})
+		for i := 0; i < 8; i++ {
+			go func() {
+				a.Set("merchant", "acme").Set("amount", 1)
+				_ = a.Validate()
+				done <- struct{}{}
+			}()
+		}
+		for i := 0; i < 8; i++ {
+			<-done
+		}
+		if err := a.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	b := NewSchemaBuilder()
+	b.Action("signup").
+		String("email", 3, 100).Format("email", FormatEmail).Sensitive("email").
+		String("country", 2, 2).
+		Map("profile", "", FieldSpec{Type: "string"}, 0, 10).Sensitive("profile")
+	all, err := b.BuildAll()
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	schema := all["signup"]
+
+	data := map[string]any{
+		"email":   "alice@example.com",
+		"country": "TW",
+		"profile": map[string]any{"phone": "0912"},
+		"extra":   "who knows",
+	}
+
+	t.Run("masks sensitive and unknown fields", func(t *testing.T) {
+		got := Redact(data, schema)
+		want := map[string]any{"email": RedactedValue, "country": "TW", "profile": RedactedValue, "extra": RedactedValue}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Redact = %v, want %v", got, want)
+		}
+		if data["email"] != "alice@example.com" {
+			t.Error("Redact modified its input")
+		}
+	})
+
+	t.Run("sensitive does not affect validation", func(t *testing.T) {
+		delete(data, "extra")
+		if err := ValidateData(data, schema); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("round trip and json schema", func(t *testing.T) {
+		raw, _ := json.Marshal(schema)
+		var props map[string]any
+		_ = json.Unmarshal(raw, &props)
+		parsed, err := ParseSchemaStrict(props)
+		if err != nil || !parsed["email"].Sensitive || parsed["country"].Sensitive {
+			t.Errorf("ParseSchemaStrict = %+v, %v", parsed, err)
+		}
+		if p := JSONSchema(schema)["properties"].(map[string]any)["email"].(map[string]any); p["x-sensitive"] != true {
+			t.Errorf("email json schema = %v", p)
+		}
+	})
+}
+
+func TestSchemaComposition(t *testing.T) {
+	zero, two := "0", 2
+	money := FieldSpec{Type: "decimal", Min: &zero, Scale: &two}
+
+	common := NewSchemaBuilder().
+		SetActionSign("sig", sae.AlgEd25519).
+		SetActionStringLength("request_id", "36", "36").SetFormat("request_id", FormatUUID)
+
+	b := NewSchemaBuilder().
+		Define("money", money).
+		Define("email", FieldSpec{Type: "string", Max: strPtr("254"), Format: FormatEmail})
+	b.Action("transfer").Include(common).Use("amount", "money").Use("to", "email")
+	b.Action("refund").Include(common).Use("amount", "money")
+
+	t.Run("definitions and includes are shared", func(t *testing.T) {
+		all, err := b.BuildAll()
+		if err != nil {
+			t.Fatalf("BuildAll failed: %v", err)
+		}
+		if len(all["transfer"]) != 4 || len(all["refund"]) != 3 {
+			t.Fatalf("unexpected fields: %v", all)
+		}
+		if all["refund"]["amount"].Type != "decimal" || all["transfer"]["sig"].Type != "sign" {
+			t.Errorf("unexpected specs: %+v", all)
+		}
+	})
+
+	t.Run("actions stay independent", func(t *testing.T) {
+		b.Action("refund").Default("amount", "0")
+		all, _ := b.BuildAll()
+		if all["transfer"]["amount"].Default != nil || all["refund"]["amount"].Default == nil {
+			t.Error("Default on one action leaked into the shared definition")
+		}
+	})
+
+	t.Run("flat builder", func(t *testing.T) {
+		schema, err := NewSchemaBuilder().Define("money", money).Use("price", "money").Include(common).BuildSchema()
+		if err != nil || len(schema) != 3 {
+			t.Errorf("unexpected schema %v, err %v", schema, err)
+		}
+	})
+
+	t.Run("error: unknown definition", func(t *testing.T) {
+		_, err := NewSchemaBuilder().Use("amount", "monye").BuildSchema()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "amount" || fe.Code != CodeSchema {
+			t.Errorf("expected schema error on amount, got %v", err)
+		}
+	})
+
+	t.Run("error: include conflict", func(t *testing.T) {
+		_, err := NewSchemaBuilder().SetActionBoolean("sig").Include(common).BuildSchema()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != "sig" {
+			t.Errorf("expected conflict on sig, got %v", err)
+		}
+	})
+}
+
+func TestTypeScriptExport(t *testing.T) {
+	b := NewSchemaBuilder()
+	b.Action("transfer.create").
+		String("to", 1, 64).
+		Decimal("amount", "0", "1000000", 12, 2).
+		Integer("seq", 1, 100).Exclusive("seq", true, false).
+		Enum("currency", "USD", "EUR").Default("currency", "USD").
+		OneOf("account", FieldSpec{Type: "string", Format: FormatUUID}, FieldSpec{Type: "integer"})
+	b.Action("ping").Boolean("ok").Const("ok", true)
+	all := mustBuildAll(t, b)
+
+	want := `// Code generated by sdto.TypeScriptModule. DO NOT EDIT.
+
+import { z } from "zod";
+
+export interface Ping {
+  ok?: true;
+}
+
+export const PingSchema = z.object({
+  ok: z.literal(true).optional(),
+}).strict();
+
+export interface TransferCreate {
+  account: string | number;
+  amount: string;
+  currency?: "USD" | "EUR";
+  seq: number;
+  to: string;
+}
+
+export const TransferCreateSchema = z.object({
+  account: z.union([z.string().uuid(), z.number().int()]),
+  amount: z.string().regex(new RegExp("^-?(0|[1-9][0-9]*)(\\.[0-9]{1,2})?$")),
+  currency: z.enum(["USD","EUR"]).optional(),
+  seq: z.number().int().gt(1).lte(100),
+  to: z.string().refine((v) => new TextEncoder().encode(v).length >= 1 && new TextEncoder().encode(v).length <= 64, { message: "at least 1 and at most 64 bytes" }),
+}).strict();
+`
+	if got := TypeScriptModule(all); got != want {
+		t.Errorf("TypeScriptModule mismatch:\n%s", got)
+	}
+
+	t.Run("length units and maps", func(t *testing.T) {
+		schema := NewSchemaBuilder().
+			SetActionStringLength("name", "1", "20").SetLengthUnit("name", LengthGraphemes).
+			SetActionMap("tags", `^[a-z]+$`, FieldSpec{Type: "bytes", Max: strPtr("16")}, "0", "4").
+			MustBuildSchema()
+		got := Zod("S", schema)
+		for _, part := range []string{
+			`[...new Intl.Segmenter().segment(v)].length <= 20`,
+			`z.record(z.string().regex(new RegExp("^[a-z]+$")), z.string().regex(/^[A-Za-z0-9+/]*={0,2}$/).refine((v) => atob(v).length <= 16`,
+			`Object.keys(v).length <= 4`,
+		} {
+			if !strings.Contains(got, part) {
+				t.Errorf("missing %s in:\n%s", part, got)
+			}
+		}
+	})
+
+	t.Run("quoted keys and type names", func(t *testing.T) {
+		if got := tsKey("first-name"); got != `"first-name"` {
+			t.Errorf("tsKey = %s", got)
+		}
+		if got := tsTypeName("2fa_verify"); got != "Action2faVerify" {
+			t.Errorf("tsTypeName = %s", got)
+		}
+	})
+}
+
+func mustBuildAll(t *testing.T, b *SchemaBuilder) map[string]map[string]FieldSpec {
+	t.Helper()
+	all, err := b.BuildAll()
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	return all
+}
+
+// 舊 consumer 以 len(*c.Min) 比較（邊界字串的長度）；provider 與 consumer
+// 現在共用同一套驗證，邊界一律以數值解讀
+func TestStringBounds_Numeric(t *testing.T) {
+	schema := NewSchemaBuilder().SetActionStringLength("code", "10", "100").MustBuildSchema()
+
+	for _, tc := range []struct {
+		value string
+		code  string
+	}{
+		{"abc", CodeMin}, // len("10") == 2 would have let this pass
+		{strings.Repeat("x", 10), ""},
+		{strings.Repeat("x", 100), ""},
+		{strings.Repeat("x", 101), CodeMax},
+	} {
+		_, setErr := NewAction("a", schema).Set("code", tc.value).Finalize()
+		dataErr := ValidateData(map[string]any{"code": tc.value}, schema)
+		for _, err := range []error{setErr, dataErr} {
+			var fe *FieldError
+			switch {
+			case tc.code == "" && err != nil:
+				t.Errorf("len %d: unexpected error: %v", len(tc.value), err)
+			case tc.code != "" && (!errors.As(err, &fe) || fe.Code != tc.code):
+				t.Errorf("len %d: expected %s, got %v", len(tc.value), tc.code, err)
+			}
+		}
+	}
+}
+
+func TestRegistry_ValidateEnvelopeWithPolicy(t *testing.T) {
+	reg := NewRegistry().Register("transfer", "1", NewSchemaBuilder().
+		SetActionStringLength("to", "1", "20").
+		SetActionNumberRange("amount", "0", "100").
+		MustBuildSchema())
+	envelope := func(sdto map[string]any) *sae.Envelope {
+		saeBytes, err := sae.BuildSAE("transfer", sdto, sae.WithSchemaVersion("1"))
+		if err != nil {
+			t.Fatalf("BuildSAE failed: %v", err)
+		}
+		env, _ := sae.Parse(saeBytes)
+		return env
+	}
+
+	t.Run("incomplete envelope rejected under every policy", func(t *testing.T) {
+		for _, policy := range []ExtraFieldPolicy{ExtraReject, ExtraIgnore, ExtraStrip} {
+			_, err := reg.ValidateEnvelopeWithPolicy(envelope(map[string]any{"to": "bob"}), policy)
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "amount" || fe.Code != CodeRequired {
+				t.Errorf("policy %d: expected amount required, got %v", policy, err)
+			}
+		}
+	})
+
+	t.Run("unknown fields follow the policy", func(t *testing.T) {
+		data := map[string]any{"to": "bob", "amount": 5, "note": "new client"}
+		if err := reg.ValidateEnvelope(envelope(data)); err == nil {
+			t.Error("ValidateEnvelope: expected unknown_field error")
+		}
+		env := envelope(data)
+		extra, err := reg.ValidateEnvelopeWithPolicy(env, ExtraStrip)
+		if err != nil || len(extra) != 1 || extra[0] != "note" {
+			t.Errorf("ExtraStrip: extra %v, err %v", extra, err)
+		}
+		if _, ok := env.SDTO["note"]; ok {
+			t.Error("ExtraStrip: note still in env.SDTO")
+		}
+	})
+}
+
+func TestNumbers_ExactLexicalForm(t *testing.T) {
+	schema := NewSchemaBuilder().
+		SetActionIntegerRange("id", "0", "18446744073709551615").
+		SetActionNumberRange("ratio", "-1000", "1000").
+		MustBuildSchema()
+
+	t.Run("int64 and json.Number IDs above 2^53 survive the SAE", func(t *testing.T) {
+		for _, id := range []any{int64(9007199254740993), json.Number("9007199254740993"), uint64(18446744073709551615)} {
+			saeBytes, err := NewAction("a", schema).Set("id", id).Set("ratio", json.Number("0.5")).Finalize()
+			if err != nil {
+				t.Fatalf("%v: Finalize failed: %v", id, err)
+			}
+			if want := fmt.Sprintf(`"id":%v`, id); !strings.Contains(string(saeBytes), want) {
+				t.Errorf("%v: SAE altered the value: %s", id, saeBytes)
+			}
+
+			env, _ := sae.Parse(saeBytes)
+			if err := ValidateData(env.SDTO, schema); err != nil {
+				t.Errorf("%v: server validation failed: %v", id, err)
+			}
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		field string
+		value any
+	}{
+		"float64 above 2^53":        {"id", float64(9007199254740993)},
+		"negative float below 2^53": {"ratio", -1e16},
+		"integer beyond 64 bits":    {"id", json.Number("123456789012345678901234")},
+		"digits beyond float64":     {"ratio", json.Number("0.1000000000000000055511151231257827")},
+	} {
+		t.Run("error: "+name, func(t *testing.T) {
+			err := ValidateData(map[string]any{"id": 1, "ratio": 0, tc.field: tc.value}, schema)
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != tc.field || fe.Code != CodePrecision {
+				t.Errorf("expected precision error on %s, got %v", tc.field, err)
+			}
+		})
+	}
+}
+
+func TestValidateDataLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	schema := NewSchemaBuilder().SetActionStringLength("email", "3", "64").MustBuildSchema()
+
+	if err := ValidateData(map[string]any{"email": "a@example.com"}, schema, logger); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `msg="sdto: data valid" fields=1`) {
+		t.Errorf("logs: %s", buf.String())
+	}
+
+	t.Run("error: field names and codes only", func(t *testing.T) {
+		buf.Reset()
+		_ = ValidateData(map[string]any{"email": "x", "extra": "pii@example.com"}, schema, logger)
+		out := buf.String()
+		if !strings.Contains(out, `msg="sdto: validation failed"`) || !strings.Contains(out, "email") ||
+			!strings.Contains(out, CodeUnknownField) || strings.Contains(out, "pii@example.com") {
+			t.Errorf("logs: %s", out)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package sdto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// ValidateOption configures ValidateData.
+type ValidateOption func(*validateConfig)
+
+type validateConfig struct {
+	logger *slog.Logger
+}
+
+func newValidateConfig(opts []ValidateOption) validateConfig {
+	var cfg validateConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithLogger sends ValidateData a debug record to l for every call: the
+// number of fields checked and, on failure, the failing field names and
+// error codes. Field values are never logged, so PII stays out of logs.
+// Without it nothing is logged.
+func WithLogger(l *slog.Logger) ValidateOption {
+	return func(c *validateConfig) {
+		c.logger = l
+	}
+}
+
+// logValidation 記錄 ValidateData 的結果（只有欄位名稱與錯誤代碼）
+func logValidation(l *slog.Logger, data map[string]any, err error) {
+	if l == nil || !l.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if err == nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "sdto: data valid", slog.Int("fields", len(data)))
+		return
+	}
+	var fields, codes []string
+	var verrs ValidationErrors
+	var ferr *FieldError
+	switch {
+	case errors.As(err, &verrs):
+		for _, e := range verrs {
+			fields, codes = append(fields, e.Field), append(codes, e.Code)
+		}
+	case errors.As(err, &ferr):
+		fields, codes = []string{ferr.Field}, []string{ferr.Code}
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "sdto: validation failed",
+		slog.Int("fields", len(data)), slog.Any("failed", fields), slog.Any("codes", codes))
+}
@@ -0,0 +1,461 @@
+// Package sdtotest generates random SDTO data from FieldSpec schemas for
+// property-based tests and fuzzing of ValidateData and action handlers.
+package sdtotest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vax/pkg/vax/sdto"
+)
+
+// 沒有上下限時的預設範圍（保持值小而可讀）
+const (
+	defaultSpan   = 1000
+	maxStringLen  = 32
+	maxMapEntries = 5
+	attempts      = 20
+)
+
+// Mutation 描述 GenerateInvalid 對有效資料做的破壞
+type Mutation struct {
+	Kind  string // "missing" / "type" / "range" / "unknown"
+	Field string
+}
+
+func (m Mutation) String() string { return m.Kind + " " + m.Field }
+
+// Generate returns a random map that satisfies schema. Fields with a
+// default or const are included or left out at random. Deterministic for
+// a given rng seed.
+func Generate(schema map[string]sdto.FieldSpec, rng *rand.Rand) map[string]any {
+	data := make(map[string]any, len(schema))
+	for _, name := range sortedFields(schema) {
+		spec := schema[name]
+		if (spec.Default != nil || spec.Const != nil) && rng.Intn(2) == 0 {
+			continue
+		}
+		data[name] = Value(spec, rng)
+	}
+	return data
+}
+
+// GenerateInvalid returns a random map that schema rejects and the single
+// mutation that makes it invalid: a missing required field, a wrong JSON
+// type, an out-of-range value or an unknown field.
+func GenerateInvalid(schema map[string]sdto.FieldSpec, rng *rand.Rand) (map[string]any, Mutation) {
+	fields := sortedFields(schema)
+	for i := 0; i < attempts && len(fields) > 0; i++ {
+		data := Generate(schema, rng)
+		name := fields[rng.Intn(len(fields))]
+		spec := schema[name]
+
+		var m Mutation
+		switch rng.Intn(3) {
+		case 0:
+			if spec.Default != nil || spec.Const != nil {
+				continue
+			}
+			delete(data, name)
+			m = Mutation{"missing", name}
+		case 1:
+			data[name] = wrongType(spec, rng)
+			m = Mutation{"type", name}
+		default:
+			v, ok := outOfRange(spec, rng)
+			if !ok {
+				continue
+			}
+			data[name] = v
+			m = Mutation{"range", name}
+		}
+		if sdto.ValidateData(data, schema) != nil {
+			return data, m
+		}
+	}
+
+	// unknown field 一定無效
+	data := Generate(schema, rng)
+	name := "unknown_" + strconv.Itoa(rng.Intn(1e6))
+	for _, exists := schema[name]; exists; _, exists = schema[name] {
+		name += "_"
+	}
+	data[name] = randString(rng, 1+rng.Intn(8), false)
+	return data, Mutation{"unknown", name}
+}
+
+// Value returns a random value that satisfies a single FieldSpec.
+func Value(spec sdto.FieldSpec, rng *rand.Rand) any {
+	if spec.Const != nil {
+		return *spec.Const
+	}
+	if len(spec.OneOf) > 0 {
+		return Value(spec.OneOf[rng.Intn(len(spec.OneOf))], rng)
+	}
+
+	// 格式 / 長度組合不一定每次都中，產生後以 sdto 確認
+	var v any
+	for i := 0; i < attempts; i++ {
+		v = value(spec, rng)
+		if valid(v, spec) {
+			return v
+		}
+	}
+	return v
+}
+
+func value(spec sdto.FieldSpec, rng *rand.Rand) any {
+	switch spec.Type {
+	case "string":
+		return stringValue(spec, rng)
+	case "number", "integer", "decimal":
+		return numericValue(spec, rng)
+	case "boolean":
+		return rng.Intn(2) == 0
+	case "bytes":
+		lo, hi := intBounds(spec, maxStringLen)
+		b := make([]byte, lo+rng.Intn(hi-lo+1))
+		rng.Read(b)
+		return base64.StdEncoding.EncodeToString(b)
+	case "map":
+		return mapValue(spec, rng)
+	case "sign":
+		b := make([]byte, 64)
+		rng.Read(b)
+		return base64.StdEncoding.EncodeToString(b)
+	default:
+		return nil
+	}
+}
+
+func stringValue(spec sdto.FieldSpec, rng *rand.Rand) string {
+	if len(spec.Enum) > 0 {
+		return spec.Enum[rng.Intn(len(spec.Enum))]
+	}
+	lo, hi := intBounds(spec, maxStringLen)
+	n := lo + rng.Intn(hi-lo+1)
+
+	switch spec.Format {
+	case sdto.FormatEmail:
+		const domain = "@example.com"
+		return randString(rng, max(n-len(domain), 1), false) + domain
+	case sdto.FormatURI:
+		const base = "https://example.com/"
+		return base + randString(rng, max(n-len(base), 0), false)
+	case sdto.FormatUUID:
+		b := make([]byte, 16)
+		rng.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case sdto.FormatDateTime:
+		t := time.Unix(rng.Int63n(4e9), 0).UTC()
+		return t.Format(time.RFC3339)
+	}
+	// bytes 單位只用 ASCII；runes / graphemes 可混入多位元組字元
+	return randString(rng, n, spec.LengthUnit == sdto.LengthRunes || spec.LengthUnit == sdto.LengthGraphemes)
+}
+
+// numericValue 在 [min, max] 內挑 step 的整數倍：integer 為 1、decimal 為
+// 10^-scale、number 為 0.01，再與 multiple_of 取最小公倍數
+func numericValue(spec sdto.FieldSpec, rng *rand.Rand) any {
+	if len(spec.Enum) > 0 {
+		return numericResult(spec.Type, ratOf(spec.Enum[rng.Intn(len(spec.Enum))]))
+	}
+
+	scale := 2
+	switch {
+	case spec.Type == "integer":
+		scale = 0
+	case spec.Scale != nil:
+		scale = *spec.Scale
+	}
+	if spec.Precision != nil && scale > *spec.Precision {
+		scale = *spec.Precision
+	}
+	step := new(big.Rat).SetFrac(big.NewInt(1), pow10(scale))
+	if spec.MultipleOf != nil {
+		step = ratLCM(step, ratOf(*spec.MultipleOf))
+	}
+
+	var lo, hi *big.Rat
+	if spec.Min != nil {
+		lo = ratOf(*spec.Min)
+	}
+	if spec.Max != nil {
+		hi = ratOf(*spec.Max)
+	}
+	switch {
+	case lo == nil && hi == nil:
+		lo, hi = big.NewRat(-defaultSpan, 1), big.NewRat(defaultSpan, 1)
+	case lo == nil:
+		lo = new(big.Rat).Sub(hi, big.NewRat(defaultSpan, 1))
+	case hi == nil:
+		hi = new(big.Rat).Add(lo, big.NewRat(defaultSpan, 1))
+	}
+	if spec.Type == "decimal" && spec.Precision != nil {
+		// 整數位 + 小數位 <= precision
+		limit := new(big.Rat).SetFrac(pow10(*spec.Precision-scale), big.NewInt(1))
+		limit.Sub(limit, step)
+		if hi.Cmp(limit) > 0 {
+			hi = limit
+		}
+		if neg := new(big.Rat).Neg(limit); lo.Cmp(neg) < 0 {
+			lo = neg
+		}
+	}
+
+	kLo := ratCeil(new(big.Rat).Quo(lo, step))
+	if spec.ExclusiveMin && new(big.Rat).Mul(new(big.Rat).SetInt(kLo), step).Cmp(lo) == 0 {
+		kLo.Add(kLo, big.NewInt(1))
+	}
+	kHi := ratFloor(new(big.Rat).Quo(hi, step))
+	if spec.ExclusiveMax && new(big.Rat).Mul(new(big.Rat).SetInt(kHi), step).Cmp(hi) == 0 {
+		kHi.Sub(kHi, big.NewInt(1))
+	}
+	if kHi.Cmp(kLo) < 0 {
+		kHi.Set(kLo) // 範圍內沒有 step 的倍數，Value 會回傳無效值
+	}
+
+	span := new(big.Int).Sub(kHi, kLo)
+	k := new(big.Int).Set(kLo)
+	if span.IsInt64() && span.Int64() < 1<<62 {
+		k.Add(k, big.NewInt(rng.Int63n(span.Int64()+1)))
+	} else {
+		k.Add(k, big.NewInt(rng.Int63n(1<<62)))
+	}
+	v := new(big.Rat).Mul(new(big.Rat).SetInt(k), step)
+
+	if spec.Type == "decimal" {
+		return v.FloatString(scale)
+	}
+	return numericResult(spec.Type, v)
+}
+
+// numericResult 回傳 JSON 解碼後常見的 Go 型別：integer 為 int64、number 為 float64；
+// float64 無法精確表示時改用 json.Number
+func numericResult(fieldType string, v *big.Rat) any {
+	if fieldType == "integer" && v.IsInt() && v.Num().IsInt64() {
+		return v.Num().Int64()
+	}
+	f, _ := v.Float64()
+	if back, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64)); ok && back.Cmp(v) == 0 {
+		return f
+	}
+	return json.Number(v.FloatString(20))
+}
+
+func mapValue(spec sdto.FieldSpec, rng *rand.Rand) map[string]any {
+	lo, hi := intBounds(spec, maxMapEntries)
+	n := lo + rng.Intn(hi-lo+1)
+
+	var re *regexp.Regexp
+	var ast *syntax.Regexp
+	if spec.KeyPattern != "" {
+		re = regexp.MustCompile(spec.KeyPattern)
+		if parsed, err := syntax.Parse(spec.KeyPattern, syntax.Perl); err == nil {
+			ast = parsed.Simplify()
+		}
+	}
+
+	m := make(map[string]any, n)
+	for i := 0; i < n*attempts && len(m) < n; i++ {
+		key := randString(rng, 1+rng.Intn(8), false)
+		if ast != nil {
+			var sb strings.Builder
+			genRegexp(ast, rng, &sb)
+			key = sb.String()
+		}
+		if re != nil && !re.MatchString(key) {
+			continue
+		}
+		if _, dup := m[key]; dup {
+			continue
+		}
+		if spec.Values != nil {
+			m[key] = Value(*spec.Values, rng)
+		} else {
+			m[key] = randString(rng, rng.Intn(8), false)
+		}
+	}
+	return m
+}
+
+// genRegexp 產生符合（已 Simplify 的）regexp 的字串；錨點與字界不產生字元
+func genRegexp(re *syntax.Regexp, rng *rand.Rand, sb *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return
+		}
+		i := rng.Intn(len(re.Rune)/2) * 2
+		lo, hi := re.Rune[i], re.Rune[i+1]
+		if hi-lo > 94 {
+			hi = lo + 94 // 避免產生大量罕見字元
+		}
+		sb.WriteRune(lo + rune(rng.Intn(int(hi-lo)+1)))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteString(randString(rng, 1, false))
+	case syntax.OpCapture:
+		genRegexp(re.Sub[0], rng, sb)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			genRegexp(sub, rng, sb)
+		}
+	case syntax.OpAlternate:
+		genRegexp(re.Sub[rng.Intn(len(re.Sub))], rng, sb)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := 0, 3
+		switch re.Op {
+		case syntax.OpPlus:
+			lo = 1
+		case syntax.OpQuest:
+			hi = 1
+		case syntax.OpRepeat:
+			lo, hi = re.Min, re.Max
+			if hi < 0 {
+				hi = lo + 3
+			}
+		}
+		for n := lo + rng.Intn(hi-lo+1); n > 0; n-- {
+			genRegexp(re.Sub[0], rng, sb)
+		}
+	}
+}
+
+// wrongType 挑一個欄位不接受的 JSON 型別
+func wrongType(spec sdto.FieldSpec, rng *rand.Rand) any {
+	candidates := []any{true, "x", int64(12345), 1.5, []any{"x"}, map[string]any{"x": 1}, nil}
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	for _, c := range candidates {
+		if !valid(c, spec) {
+			return c
+		}
+	}
+	return candidates[0]
+}
+
+// outOfRange 產生超出 max（或低於 min）的值；沒有範圍時回傳 false
+func outOfRange(spec sdto.FieldSpec, rng *rand.Rand) (any, bool) {
+	switch spec.Type {
+	case "string", "bytes":
+		if spec.Max == nil {
+			return nil, false
+		}
+		n, _ := strconv.Atoi(*spec.Max)
+		if spec.Type == "bytes" {
+			b := make([]byte, n+1)
+			rng.Read(b)
+			return base64.StdEncoding.EncodeToString(b), true
+		}
+		return strings.Repeat("x", n+1), true
+	case "number", "integer", "decimal":
+		var v *big.Rat
+		switch {
+		case spec.Max != nil:
+			v = new(big.Rat).Add(ratOf(*spec.Max), big.NewRat(1+rng.Int63n(defaultSpan), 1))
+		case spec.Min != nil:
+			v = new(big.Rat).Sub(ratOf(*spec.Min), big.NewRat(1+rng.Int63n(defaultSpan), 1))
+		default:
+			return nil, false
+		}
+		if spec.Type == "decimal" {
+			return v.FloatString(0), true
+		}
+		return numericResult(spec.Type, v), true
+	default:
+		return nil, false
+	}
+}
+
+func valid(v any, spec sdto.FieldSpec) bool {
+	return sdto.ValidateData(map[string]any{"v": v}, map[string]sdto.FieldSpec{"v": spec}) == nil
+}
+
+// intBounds 讀取非負整數的 min / max（字串長度、bytes 大小、map 項目數），上限預設為 min+limit
+func intBounds(spec sdto.FieldSpec, limit int) (int, int) {
+	lo, hi := 0, -1
+	if spec.Min != nil {
+		lo, _ = strconv.Atoi(*spec.Min)
+	}
+	if spec.Max != nil {
+		hi, _ = strconv.Atoi(*spec.Max)
+	}
+	if hi < 0 || hi > lo+limit {
+		hi = lo + limit
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randString(rng *rand.Rand, n int, unicode bool) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if unicode && rng.Intn(4) == 0 {
+			sb.WriteRune([]rune("éßøñ中文")[rng.Intn(6)])
+			continue
+		}
+		sb.WriteByte(letters[rng.Intn(len(letters))])
+	}
+	return sb.String()
+}
+
+func sortedFields(schema map[string]sdto.FieldSpec) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names) // 同一個 seed 產生相同資料
+	return names
+}
+
+func ratOf(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// ratLCM 兩個正有理數的最小公倍數：lcm(a/b, c/d) = lcm(a, c) / gcd(b, d)
+func ratLCM(x, y *big.Rat) *big.Rat {
+	a, b, c, d := x.Num(), x.Denom(), y.Num(), y.Denom()
+	g := new(big.Int).GCD(nil, nil, a, c)
+	l := new(big.Int).Mul(a, c)
+	l.Quo(l, g)
+	return new(big.Rat).SetFrac(l, new(big.Int).GCD(nil, nil, b, d))
+}
+
+// ratFloor：big.Int.Div 為 Euclidean 除法，分母恆正時即 floor
+func ratFloor(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func ratCeil(r *big.Rat) *big.Int {
+	q := ratFloor(r)
+	if !r.IsInt() {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
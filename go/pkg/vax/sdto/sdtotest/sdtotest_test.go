@@ -0,0 +1,90 @@
+package sdtotest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+func testSchemas(t *testing.T) map[string]map[string]sdto.FieldSpec {
+	t.Helper()
+	b := sdto.NewSchemaBuilder()
+	b.Action("transfer").
+		String("to", 1, 64).
+		Decimal("amount", "0.01", "1000000", 12, 2).
+		Integer("seq", 1, 100).Exclusive("seq", true, false).MultipleOf("seq", "5").
+		Number("rate", 0, 1).MultipleOf("rate", "0.25").
+		Enum("currency", "USD", "EUR").Default("currency", "USD").
+		Sign("sig", sae.AlgEd25519)
+	b.Action("profile").
+		String("email", 5, 60).Format("email", sdto.FormatEmail).
+		String("id", 36, 36).Format("id", sdto.FormatUUID).
+		String("name", 1, 10).LengthUnit("name", sdto.LengthGraphemes).
+		String("at", 20, 20).Format("at", sdto.FormatDateTime).
+		Bytes("avatar", 0, 64).
+		Boolean("public").Const("public", true).
+		Map("labels", `^[a-z][a-z0-9_]{0,15}$`, sdto.FieldSpec{Type: "integer", Min: strPtr("0"), Max: strPtr("9")}, 1, 4).
+		NumberEnum("tier", "1", "2.5", "10").
+		OneOf("account", sdto.FieldSpec{Type: "string", Format: sdto.FormatUUID}, sdto.FieldSpec{Type: "integer", Min: strPtr("1")})
+	all, err := b.BuildAll()
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	return all
+}
+
+func TestGenerate(t *testing.T) {
+	for action, schema := range testSchemas(t) {
+		t.Run(action, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 500; i++ {
+				data := Generate(schema, rng)
+				if err := sdto.ValidateData(data, schema); err != nil {
+					t.Fatalf("iteration %d: generated invalid data %v: %v", i, data, err)
+				}
+			}
+		})
+	}
+
+	t.Run("deterministic for a seed", func(t *testing.T) {
+		schema := testSchemas(t)["profile"]
+		a := Generate(schema, rand.New(rand.NewSource(42)))
+		b := Generate(schema, rand.New(rand.NewSource(42)))
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("same seed, different data:\n%v\n%v", a, b)
+		}
+	})
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	for action, schema := range testSchemas(t) {
+		t.Run(action, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(2))
+			kinds := map[string]int{}
+			for i := 0; i < 500; i++ {
+				data, m := GenerateInvalid(schema, rng)
+				if sdto.ValidateData(data, schema) == nil {
+					t.Fatalf("iteration %d: %s produced valid data %v", i, m, data)
+				}
+				kinds[m.Kind]++
+			}
+			for _, kind := range []string{"missing", "type", "range"} {
+				if kinds[kind] == 0 {
+					t.Errorf("mutation %q never produced: %v", kind, kinds)
+				}
+			}
+		})
+	}
+
+	t.Run("empty schema", func(t *testing.T) {
+		data, m := GenerateInvalid(map[string]sdto.FieldSpec{}, rand.New(rand.NewSource(3)))
+		if m.Kind != "unknown" || len(data) != 1 {
+			t.Errorf("got %v, %s", data, m)
+		}
+	})
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,212 @@
+package vax
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// Error codes
+var (
+	ErrUnknownActor = errors.New("unknown actor")
+	ErrActorExists  = errors.New("actor already has a chain")
+	ErrStaleHead    = errors.New("chain head moved concurrently")
+)
+
+// ChainStore persists each actor's ChainState on the verifier side.
+// Implementations must make CompareAndAdvance atomic so that two concurrent
+// submissions for the same position cannot both be accepted.
+type ChainStore interface {
+	// Head returns the actor's current state (ErrUnknownActor if none).
+	Head(actor string) (ChainState, error)
+	// CompareAndAdvance moves the actor from prev to next, failing with
+	// ErrStaleHead when the stored head is no longer prev.
+	CompareAndAdvance(actor string, prev, next ChainState) error
+}
+
+// ContextChainStore is a ChainStore backed by a database or a remote
+// service. VerifyAndAdvanceContext, HeadContext and CompareAndAdvanceContext
+// pass the caller's context to it, so request deadlines and cancellation
+// reach the backend.
+type ContextChainStore interface {
+	ChainStore
+	HeadContext(ctx context.Context, actor string) (ChainState, error)
+	CompareAndAdvanceContext(ctx context.Context, actor string, prev, next ChainState) error
+}
+
+// HeadContext returns store's head for actor, with ctx when store is a
+// ContextChainStore. It fails with ctx.Err() once ctx is done.
+func HeadContext(ctx context.Context, store ChainStore, actor string) (ChainState, error) {
+	if err := ctx.Err(); err != nil {
+		return ChainState{}, err
+	}
+	if cs, ok := store.(ContextChainStore); ok {
+		return cs.HeadContext(ctx, actor)
+	}
+	return store.Head(actor)
+}
+
+// CompareAndAdvanceContext is the context-aware form of
+// store.CompareAndAdvance (see HeadContext).
+func CompareAndAdvanceContext(ctx context.Context, store ChainStore, actor string, prev, next ChainState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cs, ok := store.(ContextChainStore); ok {
+		return cs.CompareAndAdvanceContext(ctx, actor, prev, next)
+	}
+	return store.CompareAndAdvance(actor, prev, next)
+}
+
+// MemoryStore is an in-memory ChainStore (tests, single-process servers).
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]ChainState
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]ChainState)}
+}
+
+// Init starts an actor's chain at its genesis SAI (counter 0).
+func (m *MemoryStore) Init(actor string, genesisSAI []byte) error {
+	if len(genesisSAI) != SAISize {
+		return ErrInvalidInput
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.states[actor]; ok {
+		return ErrActorExists
+	}
+	m.states[actor] = ChainState{HeadSAI: bytes.Clone(genesisSAI)}
+	return nil
+}
+
+func (m *MemoryStore) Head(actor string) (ChainState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[actor]
+	if !ok {
+		return ChainState{}, ErrUnknownActor
+	}
+	return ChainState{Counter: s.Counter, HeadSAI: bytes.Clone(s.HeadSAI), Timestamp: s.Timestamp}, nil
+}
+
+func (m *MemoryStore) CompareAndAdvance(actor string, prev, next ChainState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[actor]
+	if !ok {
+		return ErrUnknownActor
+	}
+	if s.Counter != prev.Counter || !bytes.Equal(s.HeadSAI, prev.HeadSAI) {
+		return ErrStaleHead
+	}
+	m.states[actor] = ChainState{Counter: next.Counter, HeadSAI: bytes.Clone(next.HeadSAI), Timestamp: next.Timestamp}
+	return nil
+}
+
+// VerifyAndAdvance is the server-side acceptance step for one action:
+// it loads the actor's head from store, runs VerifyAction against it
+// (schema + SAI), checks the in-band chain binding, verifies the envelope
+// signature through keys, and only then advances the store.
+//
+// keys may be nil for deployments whose envelopes are not signed; with a
+// resolver, unsigned envelopes are rejected. Nothing is written unless every
+// check passes; a concurrent acceptance of the same position yields
+// ErrStaleHead. With WithTimestampPolicy the envelope timestamp must
+// satisfy the policy (sae.ErrClockSkew, sae.ErrTimestampRegression). At the
+// counter ceiling only a re-genesis record (see Regenesis) is accepted; it
+// restarts the actor at counter 0. The outcome is logged with WithLogger. opts are also passed to VerifyAction (see WithDecryptionKey).
+func VerifyAndAdvance(
+	store ChainStore,
+	actor string,
+	prevSAI []byte,
+	saeBytes []byte,
+	sai []byte,
+	schema map[string]sdto.FieldSpec,
+	keys sae.KeyResolver,
+	opts ...Option,
+) (*sae.Envelope, ChainState, error) {
+	return VerifyAndAdvanceContext(context.Background(), store, actor, prevSAI, saeBytes, sai, schema, keys, opts...)
+}
+
+// VerifyAndAdvanceContext is VerifyAndAdvance with ctx passed to the store
+// (see ContextChainStore) and the key resolver (see sae.ResolveKey). A
+// context that ends before the store is advanced leaves the chain as it was.
+func VerifyAndAdvanceContext(
+	ctx context.Context,
+	store ChainStore,
+	actor string,
+	prevSAI []byte,
+	saeBytes []byte,
+	sai []byte,
+	schema map[string]sdto.FieldSpec,
+	keys sae.KeyResolver,
+	opts ...Option,
+) (*sae.Envelope, ChainState, error) {
+	cfg := newConfig(opts)
+	state, err := HeadContext(ctx, store, actor)
+	if err != nil {
+		logAdvance(cfg.logger, actor, ChainState{}, ChainState{}, err)
+		return nil, ChainState{}, err
+	}
+	var env *sae.Envelope
+	var next ChainState
+	if state.Counter == math.MaxUint64 {
+		env, next, err = advanceRegenesis(ctx, store, actor, state, prevSAI, saeBytes, sai, keys, cfg)
+	} else {
+		env, next, err = advance(ctx, store, actor, state, prevSAI, saeBytes, sai, schema, keys, cfg)
+	}
+	logAdvance(cfg.logger, actor, state, next, err)
+	if err != nil {
+		return nil, ChainState{}, err
+	}
+	return env, next, nil
+}
+
+func advance(
+	ctx context.Context,
+	store ChainStore,
+	actor string,
+	state ChainState,
+	prevSAI, saeBytes, sai []byte,
+	schema map[string]sdto.FieldSpec,
+	keys sae.KeyResolver,
+	cfg config,
+) (*sae.Envelope, ChainState, error) {
+	verified, err := verifyAction(state.HeadSAI, prevSAI, saeBytes, sai, schema, cfg)
+	logVerifyAction(cfg.logger, prevSAI, saeBytes, sai, verified, err)
+	if err != nil {
+		return nil, ChainState{}, err
+	}
+
+	// Parse keeps numbers exact, so the signature is checked over the same bytes
+	env, err := sae.Parse(saeBytes)
+	if err != nil {
+		return nil, ChainState{}, ErrInvalidInput
+	}
+	if err := VerifyChainBinding(env, state); err != nil {
+		return nil, ChainState{}, err
+	}
+	if err := cfg.timePolicy.CheckTimestamp(env, state.Timestamp); err != nil {
+		return nil, ChainState{}, err
+	}
+	if keys != nil {
+		if err := env.VerifyWithResolverContext(ctx, keys); err != nil {
+			return nil, ChainState{}, err
+		}
+	}
+
+	next := state.Advance(bytes.Clone(sai))
+	next.Timestamp = env.Timestamp
+	if err := CompareAndAdvanceContext(ctx, store, actor, state, next); err != nil {
+		return nil, ChainState{}, err
+	}
+	return env, next, nil
+}
@@ -0,0 +1,246 @@
+package vax
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+func TestVerifyAndAdvance(t *testing.T) {
+	const actor = "user123:device456"
+	genesis, _ := ComputeGenesisSAI(actor, testGenesisSalt)
+	pub, priv, _ := sae.GenerateKeyPair()
+	keys := sae.StaticResolver{"k1": pub}
+	schema := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "0", "1000").MustBuildSchema()
+
+	newStore := func(t *testing.T) *MemoryStore {
+		t.Helper()
+		store := NewMemoryStore()
+		if err := store.Init(actor, genesis); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		return store
+	}
+	submit := func(t *testing.T, state ChainState, amount int) *Submission {
+		t.Helper()
+		sub, err := SignedAction("transfer", schema, map[string]any{"amount": amount}, priv, state)
+		if err != nil {
+			t.Fatalf("SignedAction failed: %v", err)
+		}
+		sub.Envelope.Kid = "k1"
+		return resign(t, sub, priv, state)
+	}
+
+	t.Run("accepts a chain of actions", func(t *testing.T) {
+		store := newStore(t)
+		state := ChainState{HeadSAI: genesis}
+		for i := 0; i < 3; i++ {
+			sub := submit(t, state, i)
+			env, next, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys)
+			if err != nil {
+				t.Fatalf("step %d: %v", i, err)
+			}
+			if env.ActionType != "transfer" || next.Counter != uint64(i+1) || !bytes.Equal(next.HeadSAI, sub.SAI) {
+				t.Fatalf("step %d: unexpected result %+v", i, next)
+			}
+			state = next
+		}
+		if head, _ := store.Head(actor); head.Counter != 3 {
+			t.Errorf("stored counter = %d, want 3", head.Counter)
+		}
+	})
+
+	t.Run("error: replay is rejected and store untouched", func(t *testing.T) {
+		store := newStore(t)
+		sub := submit(t, ChainState{HeadSAI: genesis}, 1)
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys); err != nil {
+			t.Fatalf("first submit failed: %v", err)
+		}
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys); err != ErrInvalidPrevSAI {
+			t.Errorf("expected ErrInvalidPrevSAI, got %v", err)
+		}
+	})
+
+	t.Run("error: bad signature does not advance", func(t *testing.T) {
+		store := newStore(t)
+		sub := submit(t, ChainState{HeadSAI: genesis}, 1)
+		other, _, _ := sae.GenerateKeyPair()
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, sae.StaticResolver{"k1": other}); err == nil {
+			t.Fatal("expected signature error")
+		}
+		if head, _ := store.Head(actor); head.Counter != 0 {
+			t.Errorf("store advanced on failure: %+v", head)
+		}
+	})
+
+	t.Run("error: timestamp policy", func(t *testing.T) {
+		now := time.Now()
+		policy := WithTimestampPolicy(sae.TimePolicy{MaxSkew: time.Minute, Monotonic: true, Now: func() time.Time { return now }})
+		at := func(state ChainState, ts time.Time) *Submission {
+			sub, err := SignedAction("transfer", schema, map[string]any{"amount": 1}, priv, state, sae.WithTimestamp(ts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub.Envelope.Kid = "k1"
+			return resign(t, sub, priv, state)
+		}
+
+		store := newStore(t)
+		sub := at(ChainState{HeadSAI: genesis}, now)
+		_, next, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys, policy)
+		if err != nil || next.Timestamp != now.UnixMilli() {
+			t.Fatalf("next = %+v, %v", next, err)
+		}
+		if head, _ := store.Head(actor); head.Timestamp != now.UnixMilli() {
+			t.Errorf("stored timestamp = %d", head.Timestamp)
+		}
+
+		backdated := at(next, now.Add(-time.Second))
+		if _, _, err := VerifyAndAdvance(store, actor, backdated.PrevSAI, backdated.SAE, backdated.SAI, schema, keys, policy); !errors.Is(err, sae.ErrTimestampRegression) {
+			t.Errorf("backdated: %v", err)
+		}
+		skewed := at(next, now.Add(time.Hour))
+		if _, _, err := VerifyAndAdvance(store, actor, skewed.PrevSAI, skewed.SAE, skewed.SAI, schema, keys, policy); !errors.Is(err, sae.ErrClockSkew) {
+			t.Errorf("skewed: %v", err)
+		}
+		if head, _ := store.Head(actor); head.Counter != 1 {
+			t.Errorf("store advanced on a rejected timestamp: %+v", head)
+		}
+		// 未設定 policy 時不檢查時間戳
+		if _, _, err := VerifyAndAdvance(store, actor, skewed.PrevSAI, skewed.SAE, skewed.SAI, schema, keys); err != nil {
+			t.Errorf("without WithTimestampPolicy: %v", err)
+		}
+	})
+
+	t.Run("error: context cancelled mid-verification does not advance", func(t *testing.T) {
+		store := newStore(t)
+		sub := submit(t, ChainState{HeadSAI: genesis}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// 在查 key 時取消：簽章仍有效，但 store 不可前進
+		resolver := cancelingResolver{StaticResolver: keys, cancel: cancel}
+		if _, _, err := VerifyAndAdvanceContext(ctx, store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, resolver); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if head, _ := store.Head(actor); head.Counter != 0 {
+			t.Errorf("store advanced after cancellation: %+v", head)
+		}
+	})
+
+	t.Run("error: schema violation", func(t *testing.T) {
+		store := newStore(t)
+		sub := submit(t, ChainState{HeadSAI: genesis}, 1)
+		strict := sdto.NewSchemaBuilder().SetActionNumberRange("amount", "5", "10").MustBuildSchema()
+		if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, strict, keys); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+
+	t.Run("encrypted actions need WithDecryptionKey", func(t *testing.T) {
+		recipient, _ := sae.GenerateEncryptionKey()
+		state := ChainState{HeadSAI: genesis}
+		encrypted := func(amount int) *Submission {
+			sub := submit(t, state, 1)
+			sub.Envelope.SDTO["amount"] = amount
+			enc, err := sae.Encrypt(sub.Envelope, recipient.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub.Envelope = enc
+			return resign(t, sub, priv, state)
+		}
+
+		store := newStore(t)
+		bad := encrypted(5000)
+		if _, _, err := VerifyAndAdvance(store, actor, bad.PrevSAI, bad.SAE, bad.SAI, schema, keys); !errors.Is(err, ErrEncrypted) {
+			t.Errorf("without key: expected ErrEncrypted, got %v", err)
+		}
+		if _, _, err := VerifyAndAdvance(store, actor, bad.PrevSAI, bad.SAE, bad.SAI, schema, keys, WithDecryptionKey(recipient)); err == nil {
+			t.Error("with key: schema-invalid payload accepted")
+		}
+		if head, _ := store.Head(actor); head.Counter != 0 {
+			t.Fatalf("store advanced: %+v", head)
+		}
+
+		good := encrypted(1)
+		env, next, err := VerifyAndAdvance(store, actor, good.PrevSAI, good.SAE, good.SAI, schema, keys, WithDecryptionKey(recipient))
+		if err != nil || env.Encrypted == nil || next.Counter != 1 {
+			t.Errorf("with key: %+v, %v", next, err)
+		}
+	})
+
+	t.Run("error: unknown actor", func(t *testing.T) {
+		sub := submit(t, ChainState{HeadSAI: genesis}, 1)
+		if _, _, err := VerifyAndAdvance(NewMemoryStore(), actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys); err != ErrUnknownActor {
+			t.Errorf("expected ErrUnknownActor, got %v", err)
+		}
+	})
+
+	t.Run("concurrent submissions: exactly one wins", func(t *testing.T) {
+		store := newStore(t)
+		subs := make([]*Submission, 8)
+		for i := range subs {
+			subs[i] = submit(t, ChainState{HeadSAI: genesis}, i)
+		}
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		accepted := 0
+		for _, sub := range subs {
+			wg.Add(1)
+			go func(sub *Submission) {
+				defer wg.Done()
+				if _, _, err := VerifyAndAdvance(store, actor, sub.PrevSAI, sub.SAE, sub.SAI, schema, keys); err == nil {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}(sub)
+		}
+		wg.Wait()
+		if accepted != 1 {
+			t.Errorf("accepted %d submissions for one position", accepted)
+		}
+	})
+
+	t.Run("error: Init twice", func(t *testing.T) {
+		if err := newStore(t).Init(actor, genesis); err != ErrActorExists {
+			t.Errorf("expected ErrActorExists, got %v", err)
+		}
+	})
+}
+
+// resign 重新簽章（設定 kid 後簽章內容改變）並重算 SAE / SAI
+func resign(t *testing.T, sub *Submission, priv ed25519.PrivateKey, state ChainState) *Submission {
+	t.Helper()
+	sub.Envelope.Signature = nil
+	if err := sub.Envelope.Sign(priv); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	saeBytes, err := jcs.Marshal(sub.Envelope)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	sai, _ := ComputeSAI(state.HeadSAI, saeBytes)
+	sub.SAE, sub.SAI, sub.Next = saeBytes, sai, state.Advance(sai)
+	return sub
+}
+
+// cancelingResolver 在解析 key 時取消呼叫端的 context
+type cancelingResolver struct {
+	sae.StaticResolver
+	cancel context.CancelFunc
+}
+
+func (r cancelingResolver) ResolveContext(_ context.Context, kid string) (crypto.PublicKey, error) {
+	r.cancel()
+	return r.Resolve(kid)
+}
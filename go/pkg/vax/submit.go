@@ -0,0 +1,93 @@
+package vax
+
+import (
+	"crypto"
+	"fmt"
+
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// Submission is everything a client sends for one action.
+type Submission struct {
+	Envelope *sae.Envelope
+	SAE      []byte     // canonical signed envelope (what the SAI covers)
+	SAI      []byte     // SHA256("VAX-SAI" || prevSAI || SHA256(SAE))
+	PrevSAI  []byte     // chain head the action was built on
+	Next     ChainState // state to use for the following action
+}
+
+// SignedAction validates data against schema, builds an SAE bound to the
+// next chain position, signs it and computes its SAI, in that order.
+//
+// Nothing is signed unless validation passes. The signer's algorithm must be
+// one the schema's sign fields allow (see sdto.AllowedSignAlgs); it is
+// checked from the public key before the signer is called.
+func SignedAction(
+	actionType string,
+	schema map[string]sdto.FieldSpec,
+	data map[string]any,
+	signer crypto.Signer,
+	state ChainState,
+	opts ...sae.Option,
+) (*Submission, error) {
+	if len(state.HeadSAI) != SAISize {
+		return nil, ErrInvalidInput
+	}
+	if err := sdto.ValidateData(data, schema); err != nil {
+		return nil, err
+	}
+
+	// BuildChainedSAE checks the counter and produces the unsigned bytes;
+	// parse them back so the signed envelope carries exactly those members.
+	unsigned, _, err := BuildChainedSAE(state, actionType, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	env, err := sae.Parse(unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先確認演算法，不允許的 signer 不會被呼叫
+	if signer == nil {
+		return nil, sae.ErrInvalidKey
+	}
+	alg, err := sae.AlgForKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if allowed := sdto.AllowedSignAlgs(schema); len(allowed) > 0 && !contains(allowed, alg) {
+		return nil, fmt.Errorf("signature algorithm %q not allowed by schema", alg)
+	}
+	if err := env.SignWith(signer, nil); err != nil {
+		return nil, err
+	}
+
+	saeBytes, err := jcs.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	sai, err := ComputeSAI(state.HeadSAI, saeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Submission{
+		Envelope: env,
+		SAE:      saeBytes,
+		SAI:      sai,
+		PrevSAI:  state.HeadSAI,
+		Next:     state.Advance(sai),
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
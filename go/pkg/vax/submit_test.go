@@ -0,0 +1,88 @@
+package vax
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"vax/pkg/vax/sae"
+	"vax/pkg/vax/sdto"
+)
+
+// countingSigner 記錄 Sign 被呼叫的次數（例如 HSM 的簽名次數）
+type countingSigner struct {
+	crypto.Signer
+	calls int
+}
+
+func (s *countingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.calls++
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestSignedAction(t *testing.T) {
+	genesis, _ := ComputeGenesisSAI("user123:device456", testGenesisSalt)
+	state := ChainState{HeadSAI: genesis}
+	pub, priv, _ := sae.GenerateKeyPair()
+	schema := sdto.NewSchemaBuilder().
+		SetActionStringLength("name", "1", "10").
+		SetActionNumberRange("amount", "0", "1000").
+		MustBuildSchema()
+	data := map[string]any{"name": "alice", "amount": 100}
+
+	t.Run("submission verifies end to end", func(t *testing.T) {
+		sub, err := SignedAction("transfer", schema, data, priv, state)
+		if err != nil {
+			t.Fatalf("SignedAction failed: %v", err)
+		}
+
+		env, err := VerifyAction(genesis, sub.PrevSAI, sub.SAE, sub.SAI, schema)
+		if err != nil {
+			t.Fatalf("VerifyAction failed: %v", err)
+		}
+		if err := VerifyChainBinding(env, state); err != nil {
+			t.Errorf("VerifyChainBinding failed: %v", err)
+		}
+		if err := env.Verify(pub); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+		if sub.Next.Counter != 1 || !bytes.Equal(sub.Next.HeadSAI, sub.SAI) {
+			t.Errorf("unexpected next state: %+v", sub.Next)
+		}
+	})
+
+	t.Run("error: invalid data is never signed", func(t *testing.T) {
+		bad := map[string]any{"name": "alice", "amount": 5000}
+		sub, err := SignedAction("transfer", schema, bad, priv, state)
+		if err == nil || sub != nil {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("error: signer not allowed by schema", func(t *testing.T) {
+		signed := sdto.NewSchemaBuilder().
+			SetActionNumberRange("amount", "0", "1000").
+			SetActionSign("sig", sae.AlgEd25519).
+			MustBuildSchema()
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		signer := &countingSigner{Signer: ecKey}
+		_, err := SignedAction("transfer", signed, map[string]any{"amount": 1, "sig": "x"}, signer, state)
+		if err == nil {
+			t.Error("expected algorithm error")
+		}
+		if signer.calls != 0 {
+			t.Errorf("disallowed signer called %d times", signer.calls)
+		}
+	})
+
+	t.Run("error: invalid chain state", func(t *testing.T) {
+		_, err := SignedAction("transfer", schema, data, priv, ChainState{})
+		if err != ErrInvalidInput {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	})
+}
@@ -2,8 +2,10 @@ package vax
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"vax/pkg/vax/sae"
 	"vax/pkg/vax/sdto"
 )
@@ -16,6 +18,7 @@ var (
 	ErrOutOfMemory     = errors.New("out of memory")
 	ErrInvalidInput    = errors.New("invalid input")
 	ErrCounterOverflow = errors.New("counter overflow")
+	ErrEncrypted       = errors.New("encrypted payload without a decryption key")
 )
 
 // Constants
@@ -35,13 +38,22 @@ func ComputeSAI(prevSAI, saeBytes []byte) ([]byte, error) {
 
 	// Two-stage hash
 	saeHash := sha256.Sum256(saeBytes)
+	return ComputeSAIFromDigest(prevSAI, saeHash[:])
+}
+
+// ComputeSAIFromDigest is ComputeSAI for callers that only hold SHA256(SAE),
+// e.g. an export whose payload has been redacted.
+func ComputeSAIFromDigest(prevSAI, saeHash []byte) ([]byte, error) {
+	if len(prevSAI) != SAISize || len(saeHash) != sha256.Size {
+		return nil, ErrInvalidInput
+	}
 
 	// vax sai = 11
-	// message = "VAX-SAI" || prevSAI || saeHash || gi
+	// message = "VAX-SAI" || prevSAI || saeHash
 	message := make([]byte, 0, 7+SAISize+SAISize)
 	message = append(message, "VAX-SAI"...)
 	message = append(message, prevSAI...)
-	message = append(message, saeHash[:]...)
+	message = append(message, saeHash...)
 
 	hash := sha256.Sum256(message)
 	return hash[:], nil
@@ -65,12 +77,30 @@ func ComputeGenesisSAI(actorID string, genesisSalt []byte) ([]byte, error) {
 
 // VerifyAction verifies an action submission (crypto + schema validation)
 // saeBytes: canonical JSON bytes from client (already JCS-marshaled by Finalize)
+// Encrypted envelopes are rejected with ErrEncrypted unless
+// WithDecryptionKey is given; the returned envelope keeps the encrypted form.
+// The outcome is logged with WithLogger.
 func VerifyAction(
 	expectedPrevSAI []byte,
 	prevSAI []byte,
 	saeBytes []byte,
 	clientProvidedSAI []byte,
 	schema map[string]sdto.FieldSpec,
+	opts ...Option,
+) (*sae.Envelope, error) {
+	cfg := newConfig(opts)
+	env, err := verifyAction(expectedPrevSAI, prevSAI, saeBytes, clientProvidedSAI, schema, cfg)
+	logVerifyAction(cfg.logger, prevSAI, saeBytes, clientProvidedSAI, env, err)
+	return env, err
+}
+
+func verifyAction(
+	expectedPrevSAI []byte,
+	prevSAI []byte,
+	saeBytes []byte,
+	clientProvidedSAI []byte,
+	schema map[string]sdto.FieldSpec,
+	cfg config,
 ) (*sae.Envelope, error) {
 
 	// Input validation
@@ -83,20 +113,41 @@ func VerifyAction(
 	if len(saeBytes) == 0 {
 		return nil, ErrInvalidInput
 	}
+	// 超過 sae.Limits 的 envelope 不解析也不雜湊
+	if err := sae.Limits.CheckSize(len(saeBytes)); err != nil {
+		return nil, err
+	}
+
+	// Compressed transport form hashes as its canonical form
+	saeBytes, err := sae.Decompress(saeBytes)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+	if err := sae.Limits.CheckSize(len(saeBytes)); err != nil {
+		return nil, err
+	}
 
 	// Parse SAE from bytes
 	var s sae.Envelope
 	if err := json.Unmarshal(saeBytes, &s); err != nil {
 		return nil, ErrInvalidInput
 	}
+	if err := sae.Limits.CheckSDTO(s.SDTO); err != nil {
+		return nil, err
+	}
 
 	// Verify prevSAI matches
 	if !bytesEqual(prevSAI, expectedPrevSAI) {
 		return nil, ErrInvalidPrevSAI
 	}
 
+	// In-band binding (optional) must agree with the out-of-band prevSAI
+	if s.PrevSAI != "" && s.PrevSAI != hex.EncodeToString(prevSAI) {
+		return nil, ErrInvalidPrevSAI
+	}
+
 	// Verify SDTO against schema
-	if err := sdto.ValidateData(s.SDTO, schema); err != nil {
+	if err := validatePayload(&s, schema, cfg); err != nil {
 		return nil, err
 	}
 
@@ -104,7 +155,7 @@ func VerifyAction(
 	if len(clientProvidedSAI) != SAISize {
 		return nil, ErrInvalidInput
 	}
-    // Verify SAI
+	// Verify SAI
 	computedSAI, err := ComputeSAI(prevSAI, saeBytes)
 	if err != nil {
 		return nil, err
@@ -115,6 +166,27 @@ func VerifyAction(
 	return &s, nil
 }
 
+// validatePayload 驗證 sdto；加密的 envelope 需有解密金鑰，解密後驗證
+func validatePayload(s *sae.Envelope, schema map[string]sdto.FieldSpec, cfg config) error {
+	if s.Encrypted == nil {
+		return sdto.ValidateData(s.SDTO, schema, sdto.WithLogger(cfg.logger))
+	}
+	if s.SDTO != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, sae.ErrMixedPayload)
+	}
+	if cfg.decryptionKey == nil {
+		return ErrEncrypted
+	}
+	plain, err := sae.Decrypt(s, cfg.decryptionKey)
+	if err != nil {
+		return err
+	}
+	if err := sae.Limits.CheckSDTO(plain.SDTO); err != nil {
+		return err
+	}
+	return sdto.ValidateData(plain.SDTO, schema, sdto.WithLogger(cfg.logger))
+}
+
 func bytesEqual(a, b []byte) bool {
 	if len(a) != len(b) {
 		return false
@@ -4,7 +4,10 @@ package vax
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
 
 	"vax/pkg/vax/jcs"
@@ -108,12 +111,32 @@ func TestComputeSAI(t *testing.T) {
 	})
 }
 
+func TestComputeSAIFromDigest(t *testing.T) {
+	prevSAI := bytes.Repeat([]byte{0x11}, SAISize)
+	saeData := []byte(`{"action":"test","value":42}`)
+
+	t.Run("matches ComputeSAI", func(t *testing.T) {
+		want, _ := ComputeSAI(prevSAI, saeData)
+		digest := sha256.Sum256(saeData)
+		got, err := ComputeSAIFromDigest(prevSAI, digest[:])
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("got %x, %v; want %x", got, err, want)
+		}
+	})
+
+	t.Run("error: invalid digest length", func(t *testing.T) {
+		if _, err := ComputeSAIFromDigest(prevSAI, saeData); err != ErrInvalidInput {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	})
+}
+
 func TestVerifyAction(t *testing.T) {
 	// Setup schema
 	builder := sdto.NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
 	builder.SetActionNumberRange("amount", "0", "1000")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	// Helper to build SAE bytes
 	buildSAEBytes := func(s *sae.Envelope) []byte {
@@ -296,6 +319,61 @@ func TestVerifyAction(t *testing.T) {
 			t.Errorf("expected ErrInvalidInput, got %v", err)
 		}
 	})
+
+	t.Run("error: envelope beyond sae.Limits", func(t *testing.T) {
+		old := sae.Limits
+		sae.Limits = sae.EnvelopeLimits{MaxBytes: 64, MaxFields: 2}
+		t.Cleanup(func() { sae.Limits = old })
+		clientSAI := make([]byte, SAISize)
+		big := []byte(`{"action_type":"transfer","sdto":{"amount":1},"timestamp":1,"nonce":"` + strings.Repeat("n", 64) + `"}`)
+		if _, err := VerifyAction(make([]byte, SAISize), make([]byte, SAISize), big, clientSAI, schema); !errors.Is(err, sae.ErrEnvelopeTooLarge) {
+			t.Errorf("expected ErrEnvelopeTooLarge, got %v", err)
+		}
+		wide := []byte(`{"action_type":"t","sdto":{"a":[1,2]},"timestamp":1}`)
+		if _, err := VerifyAction(make([]byte, SAISize), make([]byte, SAISize), wide, clientSAI, schema); !errors.Is(err, sae.ErrTooManyFields) {
+			t.Errorf("expected ErrTooManyFields, got %v", err)
+		}
+	})
+
+	t.Run("encrypted envelope", func(t *testing.T) {
+		recipient, _ := sae.GenerateEncryptionKey()
+		prevSAI := bytes.Repeat([]byte{0xAA}, SAISize)
+		encrypt := func(payload map[string]any) ([]byte, []byte) {
+			env, err := sae.Encrypt(&sae.Envelope{ActionType: "transfer", Timestamp: 1234567890, SDTO: payload}, recipient.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := buildSAEBytes(env)
+			sai, _ := ComputeSAI(prevSAI, b)
+			return b, sai
+		}
+		valid, validSAI := encrypt(map[string]any{"name": "alice", "amount": 500})
+		invalid, invalidSAI := encrypt(map[string]any{"name": "alice", "amount": 5000})
+
+		env, err := VerifyAction(prevSAI, prevSAI, valid, validSAI, schema, WithDecryptionKey(recipient))
+		if err != nil || env.Encrypted == nil || env.SDTO != nil {
+			t.Fatalf("with key: %+v, %v", env, err)
+		}
+		if _, err := VerifyAction(prevSAI, prevSAI, invalid, invalidSAI, schema, WithDecryptionKey(recipient)); err == nil {
+			t.Error("with key: out-of-range payload accepted")
+		}
+
+		// 預設拒絕：沒有金鑰就無法驗證 schema
+		if _, err := VerifyAction(prevSAI, prevSAI, invalid, invalidSAI, schema); !errors.Is(err, ErrEncrypted) {
+			t.Errorf("without key: expected ErrEncrypted, got %v", err)
+		}
+
+		other, _ := sae.GenerateEncryptionKey()
+		if _, err := VerifyAction(prevSAI, prevSAI, valid, validSAI, schema, WithDecryptionKey(other)); !errors.Is(err, sae.ErrDecrypt) {
+			t.Errorf("wrong key: %v", err)
+		}
+
+		mixed := bytes.Replace(valid, []byte(`"sdto":null`), []byte(`"sdto":{"amount":1,"name":"bob"}`), 1)
+		mixedSAI, _ := ComputeSAI(prevSAI, mixed)
+		if _, err := VerifyAction(prevSAI, prevSAI, mixed, mixedSAI, schema, WithDecryptionKey(recipient)); !errors.Is(err, sae.ErrMixedPayload) || !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("sdto and encrypted: %v", err)
+		}
+	})
 }
 
 func TestChainSimulation(t *testing.T) {
@@ -354,7 +432,7 @@ func BenchmarkVerifyAction(b *testing.B) {
 	builder := sdto.NewSchemaBuilder()
 	builder.SetActionStringLength("name", "1", "50")
 	builder.SetActionNumberRange("amount", "0", "1000")
-	schema := builder.BuildSchema()
+	schema := builder.MustBuildSchema()
 
 	testSAE := &sae.Envelope{
 		ActionType: "transfer",
@@ -0,0 +1,248 @@
+// Package vectors generates the cross-language known-answer file: VAX-JCS
+// cases, genesis SAI and SAI answers, and signed SAE examples together with
+// their keys. Implementations in other languages check themselves against
+// the file instead of copying expected hashes out of Go test logs.
+//
+// Every value is derived from fixed inputs (the genesis salt and actor of
+// the C test suite, a fixed Ed25519 seed), so Generate always produces the
+// same bytes. gi has no known answers: the C library draws it at random and
+// this module does not compute it.
+package vectors
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+// Version is the format version of File.
+const Version = 1
+
+// File is the vector file Write emits.
+type File struct {
+	Version int             `json:"version"`
+	JCS     []JCSVector     `json:"jcs"`
+	Invalid []JCSVector     `json:"jcs_invalid"` // valid JSON that VAX-JCS rejects
+	Genesis []GenesisVector `json:"genesis_sai"`
+	SAI     []SAIVector     `json:"sai"`
+	SAE     []SAEVector     `json:"sae"`
+}
+
+// JCSVector is one canonicalization case, in the layout of the repository's
+// test-vectors.json: Expected is the VAX-JCS form of Input.
+type JCSVector struct {
+	Name     string          `json:"name"`
+	Input    json.RawMessage `json:"input"`
+	Expected string          `json:"expected,omitempty"`
+}
+
+// GenesisVector is a ComputeGenesisSAI known answer.
+type GenesisVector struct {
+	Name        string `json:"name"`
+	ActorID     string `json:"actor_id"`
+	GenesisSalt string `json:"genesis_salt"` // hex
+	Expected    string `json:"expected"`     // hex
+}
+
+// SAIVector is a ComputeSAI known answer; SAESHA256 is the intermediate
+// digest, for implementations that hash the SAE separately.
+type SAIVector struct {
+	Name      string `json:"name"`
+	PrevSAI   string `json:"prev_sai"` // hex
+	SAE       string `json:"sae"`
+	SAESHA256 string `json:"sae_sha256"` // hex
+	Expected  string `json:"expected"`   // hex
+}
+
+// SAEVector is a signed envelope with the key that signed it. Unsigned is
+// the canonical envelope without its signature, SigningInput (hex) the exact
+// message signed (SigningContext || 0x00 || Unsigned), and Signed the
+// canonical signed envelope. Chained examples also carry the SAI of Signed.
+type SAEVector struct {
+	Name         string  `json:"name"`
+	PrivateKey   sae.JWK `json:"private_key"`
+	PublicKey    sae.JWK `json:"public_key"`
+	Unsigned     string  `json:"unsigned"`
+	SigningInput string  `json:"signing_input"`
+	Signed       string  `json:"signed"`
+	PrevSAI      string  `json:"prev_sai,omitempty"` // hex
+	SAI          string  `json:"sai,omitempty"`      // hex
+}
+
+// Fixed inputs, matching the C test suite (c/test/test_sai.c).
+var (
+	genesisActor = "user123:device456"
+	genesisSalt  = []byte{
+		0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xa8,
+		0xa9, 0xaa, 0xab, 0xac, 0xad, 0xae, 0xaf, 0xb0,
+	}
+)
+
+// jcsCases 的 input 以原始字面量保存，數字格式才能被測到
+var jcsCases = []struct{ name, input string }{
+	{"basic types", `{"b": 2, "a": 1, "c": null, "d": true}`},
+	{"unicode string", `"你好世界"`},
+	{"emoji", `"😀🎉"`},
+	{"nested objects and arrays", `{"z": [3, {"y": 1, "x": [true, false]}], "a": {}}`},
+	{"key order by UTF-16 code units", `{"€": 1, "😀": 2, "a": 3, "B": 4}`},
+	{"decimal numbers", `[1.0, -0, 0.1, -1.50, 0.000001, 123456789012]`},
+	{"string escapes", `"tab\t newline\n quote\" backslash\\ slash/ control\u001f"`},
+	{"empty containers", `{"a": [], "b": {}, "c": ""}`},
+}
+
+// jcsInvalid 為合法 JSON，但 VAX-JCS 必須拒絕的輸入
+var jcsInvalid = []struct{ name, input string }{
+	{"exponent", `{"a": 1e3}`},
+	{"signed exponent", `[-1.5E+2]`},
+	{"negative exponent", `[1e-7]`},
+}
+
+// Generate builds the vector file.
+func Generate() (*File, error) {
+	f := &File{Version: Version}
+
+	for _, c := range jcsCases {
+		out, err := jcs.CanonicalizeJSON([]byte(c.input))
+		if err != nil {
+			return nil, fmt.Errorf("vectors: jcs %q: %w", c.name, err)
+		}
+		f.JCS = append(f.JCS, JCSVector{Name: c.name, Input: json.RawMessage(c.input), Expected: string(out)})
+	}
+	for _, c := range jcsInvalid {
+		if _, err := jcs.CanonicalizeJSON([]byte(c.input)); err == nil {
+			return nil, fmt.Errorf("vectors: jcs %q: accepted", c.name)
+		}
+		f.Invalid = append(f.Invalid, JCSVector{Name: c.name, Input: json.RawMessage(c.input)})
+	}
+
+	genesis, err := vax.ComputeGenesisSAI(genesisActor, genesisSalt)
+	if err != nil {
+		return nil, err
+	}
+	f.Genesis = append(f.Genesis, GenesisVector{Name: "C test suite actor", ActorID: genesisActor,
+		GenesisSalt: hex.EncodeToString(genesisSalt), Expected: hex.EncodeToString(genesis)})
+	empty, err := vax.ComputeGenesisSAI("", make([]byte, vax.GenesisSaltSize))
+	if err != nil {
+		return nil, err
+	}
+	f.Genesis = append(f.Genesis, GenesisVector{Name: "empty actor, zero salt",
+		GenesisSalt: hex.EncodeToString(make([]byte, vax.GenesisSaltSize)), Expected: hex.EncodeToString(empty)})
+
+	for _, c := range []struct {
+		name    string
+		prevSAI []byte
+		sae     string
+	}{
+		{"0x11 prev SAI", bytes.Repeat([]byte{0x11}, vax.SAISize), `{"action":"test","value":42}`},
+		{"zero prev SAI", make([]byte, vax.SAISize), `{"test":1}`},
+		{"genesis prev SAI", genesis, `{"action_type":"noop","sdto":{},"timestamp":0}`},
+	} {
+		v, err := saiVector(c.name, c.prevSAI, []byte(c.sae))
+		if err != nil {
+			return nil, err
+		}
+		f.SAI = append(f.SAI, v)
+	}
+
+	priv := ed25519.NewKeyFromSeed(seed())
+	at := sae.WithTimestamp(time.UnixMilli(1700000000000))
+	unchained := sae.NewEnvelope("transfer", map[string]any{"amount": 100, "to": "bob"}, at,
+		sae.WithNonce("000102030405060708090a0b0c0d0e0f"))
+	v, err := saeVector("ed25519, unchained", unchained, priv, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.SAE = append(f.SAE, v)
+
+	prev := genesis
+	for counter := uint64(1); counter <= 2; counter++ {
+		e := sae.NewEnvelope("transfer", map[string]any{"amount": counter * 10, "memo": "café ☕"}, at,
+			sae.WithChain(counter, prev), sae.WithMetadata(genesisActor, "", ""))
+		v, err := saeVector(fmt.Sprintf("ed25519, chained counter %d", counter), e, priv, prev)
+		if err != nil {
+			return nil, err
+		}
+		f.SAE = append(f.SAE, v)
+		prev, _ = hex.DecodeString(v.SAI)
+	}
+	return f, nil
+}
+
+// Write generates the vector file and returns it as indented JSON.
+func Write() ([]byte, error) {
+	f, err := Generate()
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func saiVector(name string, prevSAI, saeBytes []byte) (SAIVector, error) {
+	sai, err := vax.ComputeSAI(prevSAI, saeBytes)
+	if err != nil {
+		return SAIVector{}, fmt.Errorf("vectors: sai %q: %w", name, err)
+	}
+	digest := sha256.Sum256(saeBytes)
+	return SAIVector{Name: name, PrevSAI: hex.EncodeToString(prevSAI), SAE: string(saeBytes),
+		SAESHA256: hex.EncodeToString(digest[:]), Expected: hex.EncodeToString(sai)}, nil
+}
+
+// saeVector 以 priv 簽署 e；prevSAI 不為 nil 時一併計算 SAI
+func saeVector(name string, e *sae.Envelope, priv ed25519.PrivateKey, prevSAI []byte) (SAEVector, error) {
+	const kid = "vectors-ed25519"
+	e.Kid = kid
+	if err := e.Sign(priv); err != nil {
+		return SAEVector{}, err
+	}
+	unsigned, err := e.UnsignedBytes()
+	if err != nil {
+		return SAEVector{}, err
+	}
+	input, err := e.SigningBytes()
+	if err != nil {
+		return SAEVector{}, err
+	}
+	signed, err := jcs.Marshal(e)
+	if err != nil {
+		return SAEVector{}, err
+	}
+	privJWK, err := sae.PrivateJWK(priv, kid)
+	if err != nil {
+		return SAEVector{}, err
+	}
+	pubJWK, err := sae.PublicJWK(priv.Public(), kid)
+	if err != nil {
+		return SAEVector{}, err
+	}
+	v := SAEVector{Name: name, PrivateKey: privJWK, PublicKey: pubJWK, Unsigned: string(unsigned),
+		SigningInput: hex.EncodeToString(input), Signed: string(signed)}
+	if prevSAI != nil {
+		sai, err := vax.ComputeSAI(prevSAI, signed)
+		if err != nil {
+			return SAEVector{}, err
+		}
+		v.PrevSAI, v.SAI = hex.EncodeToString(prevSAI), hex.EncodeToString(sai)
+	}
+	return v, nil
+}
+
+// seed 為 0x01..0x20，與 C test suite 的 32-byte 測試輸入相同
+func seed() []byte {
+	b := make([]byte, ed25519.SeedSize)
+	for i := range b {
+		b[i] = byte(i + 1)
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package vectors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vax/pkg/vax"
+	"vax/pkg/vax/jcs"
+	"vax/pkg/vax/sae"
+)
+
+func TestGenerate(t *testing.T) {
+	f, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("genesis matches the C test suite", func(t *testing.T) {
+		if got := f.Genesis[0].Expected; got != "afc50728cd79e805a8ae06875a1ddf78ca11b0d56ec300b160fb71f50ce658c3" {
+			t.Errorf("genesis SAI = %s", got)
+		}
+	})
+
+	t.Run("JCS cases", func(t *testing.T) {
+		for _, v := range f.JCS {
+			if !jcs.IsCanonical([]byte(v.Expected)) {
+				t.Errorf("%s: expected %q is not canonical", v.Name, v.Expected)
+			}
+		}
+		if len(f.Invalid) == 0 {
+			t.Error("no invalid cases")
+		}
+	})
+
+	t.Run("signed SAEs verify and chain", func(t *testing.T) {
+		prev := f.Genesis[0].Expected
+		for _, v := range f.SAE {
+			pub, err := v.PublicKey.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := sae.Parse([]byte(v.Signed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Verify(pub); err != nil {
+				t.Errorf("%s: %v", v.Name, err)
+			}
+			input, _ := hex.DecodeString(v.SigningInput)
+			if !bytes.HasSuffix(input, []byte(v.Unsigned)) {
+				t.Errorf("%s: signing input does not end with the unsigned envelope", v.Name)
+			}
+			if v.SAI == "" {
+				continue
+			}
+			if v.PrevSAI != prev {
+				t.Errorf("%s: prev_sai %s, want %s", v.Name, v.PrevSAI, prev)
+			}
+			p, _ := hex.DecodeString(v.PrevSAI)
+			if sai, _ := vax.ComputeSAI(p, []byte(v.Signed)); hex.EncodeToString(sai) != v.SAI {
+				t.Errorf("%s: SAI mismatch", v.Name)
+			}
+			prev = v.SAI
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		a, _ := Write()
+		b, _ := Write()
+		if !bytes.Equal(a, b) {
+			t.Error("Write is not deterministic")
+		}
+	})
+}
+
+// 根目錄的 vectors.json 必須與產生器同步
+func TestCommittedFile(t *testing.T) {
+	path := filepath.Join("..", "..", "..", "..", "vectors.json")
+	have, err := os.ReadFile(path)
+	if err != nil {
+		t.Skipf("Skipping: cannot read vectors.json: %v", err)
+	}
+	want, err := Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Error("vectors.json is stale: run `go run ./cmd/vax vectors -out ../vectors.json`")
+	}
+}